@@ -64,6 +64,10 @@ type PostIPAMParams struct {
 
 	/*Family*/
 	Family *string
+	/*Owner*/
+	Owner *string
+	/*Pod*/
+	Pod *string
 
 	timeout    time.Duration
 	Context    context.Context
@@ -114,6 +118,28 @@ func (o *PostIPAMParams) SetFamily(family *string) {
 	o.Family = family
 }
 
+// WithOwner adds the owner to the post IP a m params
+func (o *PostIPAMParams) WithOwner(owner *string) *PostIPAMParams {
+	o.SetOwner(owner)
+	return o
+}
+
+// SetOwner adds the owner to the post IP a m params
+func (o *PostIPAMParams) SetOwner(owner *string) {
+	o.Owner = owner
+}
+
+// WithPod adds the pod to the post IP a m params
+func (o *PostIPAMParams) WithPod(pod *string) *PostIPAMParams {
+	o.SetPod(pod)
+	return o
+}
+
+// SetPod adds the pod to the post IP a m params
+func (o *PostIPAMParams) SetPod(pod *string) {
+	o.Pod = pod
+}
+
 // WriteToRequest writes these params to a swagger request
 func (o *PostIPAMParams) WriteToRequest(r runtime.ClientRequest, reg strfmt.Registry) error {
 
@@ -138,6 +164,38 @@ func (o *PostIPAMParams) WriteToRequest(r runtime.ClientRequest, reg strfmt.Regi
 
 	}
 
+	if o.Owner != nil {
+
+		// query param owner
+		var qrOwner string
+		if o.Owner != nil {
+			qrOwner = *o.Owner
+		}
+		qOwner := qrOwner
+		if qOwner != "" {
+			if err := r.SetQueryParam("owner", qOwner); err != nil {
+				return err
+			}
+		}
+
+	}
+
+	if o.Pod != nil {
+
+		// query param pod
+		var qrPod string
+		if o.Pod != nil {
+			qrPod = *o.Pod
+		}
+		qPod := qrPod
+		if qPod != "" {
+			if err := r.SetQueryParam("pod", qPod); err != nil {
+				return err
+			}
+		}
+
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
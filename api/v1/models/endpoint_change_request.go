@@ -21,6 +21,11 @@ type EndpointChangeRequest struct {
 	// addressing
 	Addressing *AddressPair `json:"addressing,omitempty"`
 
+	// Whether the interface and addresses of the endpoint are managed by
+	// another CNI plugin Cilium is chained to
+	//
+	Chained bool `json:"chained,omitempty"`
+
 	// ID assigned by container runtime
 	ContainerID string `json:"container-id,omitempty"`
 
@@ -65,6 +70,8 @@ type EndpointChangeRequest struct {
 
 /* polymorph EndpointChangeRequest addressing false */
 
+/* polymorph EndpointChangeRequest chained false */
+
 /* polymorph EndpointChangeRequest container-id false */
 
 /* polymorph EndpointChangeRequest container-name false */
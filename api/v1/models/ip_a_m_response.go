@@ -25,12 +25,24 @@ type IPAMResponse struct {
 	// host addressing
 	// Required: true
 	HostAddressing *NodeAddressing `json:"host-addressing"`
+
+	// Name of the IPAM pool the addresses have been allocated from
+	Pool string `json:"pool,omitempty"`
+
+	// Prefixes to route via the host. A default route via the host is
+	// used if empty.
+	//
+	Routes []string `json:"routes"`
 }
 
 /* polymorph IPAMResponse address false */
 
 /* polymorph IPAMResponse host-addressing false */
 
+/* polymorph IPAMResponse pool false */
+
+/* polymorph IPAMResponse routes false */
+
 // Validate validates this IP a m response
 func (m *IPAMResponse) Validate(formats strfmt.Registry) error {
 	var res []error
@@ -45,6 +57,11 @@ func (m *IPAMResponse) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateRoutes(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -89,6 +106,15 @@ func (m *IPAMResponse) validateHostAddressing(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *IPAMResponse) validateRoutes(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Routes) { // not required
+		return nil
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *IPAMResponse) MarshalBinary() ([]byte, error) {
 	if m == nil {
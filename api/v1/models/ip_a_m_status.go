@@ -17,17 +17,27 @@ import (
 
 type IPAMStatus struct {
 
+	// Map of allocated IP addresses to the owner of the allocation
+	Allocations map[string]string `json:"allocations,omitempty"`
+
 	// ipv4
 	IPV4 []string `json:"ipv4"`
 
 	// ipv6
 	IPV6 []string `json:"ipv6"`
+
+	// Allocated IP addresses whose owner could not be found after a restart
+	Leaked []string `json:"leaked"`
 }
 
+/* polymorph IPAMStatus allocations false */
+
 /* polymorph IPAMStatus ipv4 false */
 
 /* polymorph IPAMStatus ipv6 false */
 
+/* polymorph IPAMStatus leaked false */
+
 // Validate validates this IP a m status
 func (m *IPAMStatus) Validate(formats strfmt.Registry) error {
 	var res []error
@@ -42,6 +52,11 @@ func (m *IPAMStatus) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateLeaked(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -66,6 +81,15 @@ func (m *IPAMStatus) validateIPV6(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *IPAMStatus) validateLeaked(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Leaked) { // not required
+		return nil
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *IPAMStatus) MarshalBinary() ([]byte, error) {
 	if m == nil {
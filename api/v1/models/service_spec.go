@@ -160,12 +160,22 @@ type ServiceSpecFlags struct {
 
 	// Perform direct server return
 	DirectServerReturn bool `json:"direct-server-return,omitempty"`
+
+	// Keep sending connections of a client to the same backend
+	SessionAffinity bool `json:"session-affinity,omitempty"`
+
+	// Session affinity timeout in seconds
+	SessionAffinityTimeout int64 `json:"session-affinity-timeout,omitempty"`
 }
 
 /* polymorph ServiceSpecFlags active-frontend false */
 
 /* polymorph ServiceSpecFlags direct-server-return false */
 
+/* polymorph ServiceSpecFlags session-affinity false */
+
+/* polymorph ServiceSpecFlags session-affinity-timeout false */
+
 // Validate validates this service spec flags
 func (m *ServiceSpecFlags) Validate(formats strfmt.Registry) error {
 	var res []error
@@ -29,6 +29,9 @@ type StatusResponse struct {
 	// Status of all endpoint controllers
 	Controllers ControllerStatuses `json:"controllers"`
 
+	// Status of transparent encryption
+	Encryption *EncryptionStatus `json:"encryption,omitempty"`
+
 	// Status of IP address management
 	IPAM *IPAMStatus `json:"ipam,omitempty"`
 
@@ -53,6 +56,8 @@ type StatusResponse struct {
 
 /* polymorph StatusResponse controllers false */
 
+/* polymorph StatusResponse encryption false */
+
 /* polymorph StatusResponse ipam false */
 
 /* polymorph StatusResponse kubernetes false */
@@ -82,6 +87,11 @@ func (m *StatusResponse) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateEncryption(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateIPAM(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -170,6 +180,25 @@ func (m *StatusResponse) validateContainerRuntime(formats strfmt.Registry) error
 	return nil
 }
 
+func (m *StatusResponse) validateEncryption(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Encryption) { // not required
+		return nil
+	}
+
+	if m.Encryption != nil {
+
+		if err := m.Encryption.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("encryption")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *StatusResponse) validateIPAM(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.IPAM) { // not required
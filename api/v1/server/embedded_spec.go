@@ -561,6 +561,12 @@ func init() {
         "parameters": [
           {
             "$ref": "#/parameters/ipam-family"
+          },
+          {
+            "$ref": "#/parameters/ipam-owner"
+          },
+          {
+            "$ref": "#/parameters/ipam-pod"
           }
         ],
         "responses": {
@@ -589,6 +595,9 @@ func init() {
         "parameters": [
           {
             "$ref": "#/parameters/ipam-ip"
+          },
+          {
+            "$ref": "#/parameters/ipam-owner"
           }
         ],
         "responses": {
@@ -1104,6 +1113,19 @@ func init() {
           "type": "integer",
           "format": "uint16"
         },
+        "state": {
+          "description": "State of the backend. Terminating backends are no longer selected\nfor new connections but keep serving established connections until\nthey are drained.\n",
+          "type": "string",
+          "enum": [
+            "active",
+            "terminating"
+          ]
+        },
+        "terminating-since": {
+          "description": "Time at which the backend started terminating",
+          "type": "string",
+          "format": "date-time"
+        },
         "weight": {
           "description": "Weight for Round Robin",
           "type": "integer",
@@ -1388,6 +1410,10 @@ func init() {
         "addressing": {
           "$ref": "#/definitions/AddressPair"
         },
+        "chained": {
+          "description": "Whether the interface and addresses of the endpoint are managed by\nanother CNI plugin Cilium is chained to\n",
+          "type": "boolean"
+        },
         "container-id": {
           "description": "ID assigned by container runtime",
           "type": "string"
@@ -1704,6 +1730,36 @@ func init() {
         }
       }
     },
+    "EncryptionStatus": {
+      "description": "Status of transparent encryption",
+      "type": "object",
+      "properties": {
+        "keys": {
+          "description": "Number of keys accepted to decrypt traffic",
+          "type": "integer"
+        },
+        "mode": {
+          "description": "Encryption mode in use",
+          "type": "string",
+          "enum": [
+            "Disabled",
+            "IPsec"
+          ]
+        },
+        "msg": {
+          "description": "Human readable status/error/warning message",
+          "type": "string"
+        },
+        "peers": {
+          "description": "Number of remote nodes traffic is encrypted to",
+          "type": "integer"
+        },
+        "spi": {
+          "description": "SPI of the key used to encrypt traffic",
+          "type": "integer"
+        }
+      }
+    },
     "EndpointStatusChange": {
       "description": "Indication of a change of status",
       "type": "object",
@@ -1776,12 +1832,30 @@ func init() {
         },
         "host-addressing": {
           "$ref": "#/definitions/NodeAddressing"
+        },
+        "pool": {
+          "description": "Name of the IPAM pool the addresses have been allocated from",
+          "type": "string"
+        },
+        "routes": {
+          "description": "Prefixes to route via the host. A default route via the host is\nused if empty.\n",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
         }
       }
     },
     "IPAMStatus": {
       "description": "Status of IP address management",
       "properties": {
+        "allocations": {
+          "description": "Map of allocated IP addresses to the owner of the allocation",
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
         "ipv4": {
           "type": "array",
           "items": {
@@ -1793,6 +1867,13 @@ func init() {
           "items": {
             "type": "string"
           }
+        },
+        "leaked": {
+          "description": "Allocated IP addresses whose owner could not be found after a restart",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
         }
       }
     },
@@ -2249,6 +2330,14 @@ func init() {
             "direct-server-return": {
               "description": "Perform direct server return",
               "type": "boolean"
+            },
+            "session-affinity": {
+              "description": "Keep sending connections of a client to the same backend",
+              "type": "boolean"
+            },
+            "session-affinity-timeout": {
+              "description": "Session affinity timeout in seconds",
+              "type": "integer"
             }
           }
         },
@@ -2311,6 +2400,10 @@ func init() {
           "description": "Status of all endpoint controllers",
           "$ref": "#/definitions/ControllerStatuses"
         },
+        "encryption": {
+          "description": "Status of transparent encryption",
+          "$ref": "#/definitions/EncryptionStatus"
+        },
         "ipam": {
           "description": "Status of IP address management",
           "$ref": "#/definitions/IPAMStatus"
@@ -2412,6 +2505,18 @@ func init() {
       "in": "path",
       "required": true
     },
+    "ipam-owner": {
+      "type": "string",
+      "description": "Owner of the allocation, e.g. a container ID",
+      "name": "owner",
+      "in": "query"
+    },
+    "ipam-pod": {
+      "type": "string",
+      "description": "Kubernetes pod the addresses are allocated for in the form\nnamespace/name. The addresses are allocated from the IPAM pool\nselected by the pool annotation of the pod.\n",
+      "name": "pod",
+      "in": "query"
+    },
     "labels": {
       "description": "List of labels\n",
       "name": "labels",
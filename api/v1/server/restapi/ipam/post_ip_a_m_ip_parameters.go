@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/go-openapi/errors"
+	"github.com/go-openapi/runtime"
 	"github.com/go-openapi/runtime/middleware"
 
 	strfmt "github.com/go-openapi/strfmt"
@@ -35,6 +36,10 @@ type PostIPAMIPParams struct {
 	  In: path
 	*/
 	IP string
+	/*
+	  In: query
+	*/
+	Owner *string
 }
 
 // BindRequest both binds and validates a request, it assumes that complex things implement a Validatable(strfmt.Registry) error interface
@@ -43,11 +48,18 @@ func (o *PostIPAMIPParams) BindRequest(r *http.Request, route *middleware.Matche
 	var res []error
 	o.HTTPRequest = r
 
+	qs := runtime.Values(r.URL.Query())
+
 	rIP, rhkIP, _ := route.Params.GetOK("ip")
 	if err := o.bindIP(rIP, rhkIP, route.Formats); err != nil {
 		res = append(res, err)
 	}
 
+	qOwner, qhkOwner, _ := qs.GetOK("owner")
+	if err := o.bindOwner(qOwner, qhkOwner, route.Formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -64,3 +76,17 @@ func (o *PostIPAMIPParams) bindIP(rawData []string, hasKey bool, formats strfmt.
 
 	return nil
 }
+
+func (o *PostIPAMIPParams) bindOwner(rawData []string, hasKey bool, formats strfmt.Registry) error {
+	var raw string
+	if len(rawData) > 0 {
+		raw = rawData[len(rawData)-1]
+	}
+	if raw == "" { // empty values pass all other validations
+		return nil
+	}
+
+	o.Owner = &raw
+
+	return nil
+}
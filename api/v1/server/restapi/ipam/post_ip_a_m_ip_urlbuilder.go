@@ -16,6 +16,8 @@ import (
 type PostIPAMIPURL struct {
 	IP string
 
+	Owner *string
+
 	_basePath string
 	// avoid unkeyed usage
 	_ struct{}
@@ -54,6 +56,18 @@ func (o *PostIPAMIPURL) Build() (*url.URL, error) {
 	}
 	result.Path = golangswaggerpaths.Join(_basePath, _path)
 
+	qs := make(url.Values)
+
+	var owner string
+	if o.Owner != nil {
+		owner = *o.Owner
+	}
+	if owner != "" {
+		qs.Set("owner", owner)
+	}
+
+	result.RawQuery = qs.Encode()
+
 	return &result, nil
 }
 
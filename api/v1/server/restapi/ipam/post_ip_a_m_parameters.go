@@ -36,6 +36,14 @@ type PostIPAMParams struct {
 	  In: query
 	*/
 	Family *string
+	/*
+	  In: query
+	*/
+	Owner *string
+	/*
+	  In: query
+	*/
+	Pod *string
 }
 
 // BindRequest both binds and validates a request, it assumes that complex things implement a Validatable(strfmt.Registry) error interface
@@ -51,6 +59,16 @@ func (o *PostIPAMParams) BindRequest(r *http.Request, route *middleware.MatchedR
 		res = append(res, err)
 	}
 
+	qOwner, qhkOwner, _ := qs.GetOK("owner")
+	if err := o.bindOwner(qOwner, qhkOwner, route.Formats); err != nil {
+		res = append(res, err)
+	}
+
+	qPod, qhkPod, _ := qs.GetOK("pod")
+	if err := o.bindPod(qPod, qhkPod, route.Formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -83,3 +101,31 @@ func (o *PostIPAMParams) validateFamily(formats strfmt.Registry) error {
 
 	return nil
 }
+
+func (o *PostIPAMParams) bindOwner(rawData []string, hasKey bool, formats strfmt.Registry) error {
+	var raw string
+	if len(rawData) > 0 {
+		raw = rawData[len(rawData)-1]
+	}
+	if raw == "" { // empty values pass all other validations
+		return nil
+	}
+
+	o.Owner = &raw
+
+	return nil
+}
+
+func (o *PostIPAMParams) bindPod(rawData []string, hasKey bool, formats strfmt.Registry) error {
+	var raw string
+	if len(rawData) > 0 {
+		raw = rawData[len(rawData)-1]
+	}
+	if raw == "" { // empty values pass all other validations
+		return nil
+	}
+
+	o.Pod = &raw
+
+	return nil
+}
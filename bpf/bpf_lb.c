@@ -59,6 +59,7 @@ static inline int handle_ipv6(struct __sk_buff *skb)
 {
 	void *data, *data_end;
 	struct lb6_key key = {};
+	struct ipv6_ct_tuple tuple = {};
 	struct lb6_service *svc;
 	struct ipv6hdr *ip6;
 	struct csum_offset csum_off = {};
@@ -74,6 +75,8 @@ static inline int handle_ipv6(struct __sk_buff *skb)
 
 	nexthdr = ip6->nexthdr;
 	ipv6_addr_copy(&key.address, (union v6addr *) &ip6->daddr);
+	ipv6_addr_copy(&tuple.daddr, (union v6addr *) &ip6->daddr);
+	ipv6_addr_copy(&tuple.saddr, (union v6addr *) &ip6->saddr);
 	l3_off = ETH_HLEN;
 	hdrlen = ipv6_hdrlen(skb, ETH_HLEN, &nexthdr);
 	if (hdrlen < 0)
@@ -99,7 +102,12 @@ static inline int handle_ipv6(struct __sk_buff *skb)
 		return TC_ACT_OK;
 	}
 
-	slave = lb6_select_slave(skb, &key, svc->count, svc->weight);
+	ret = lb_extract_tuple_ports(skb, nexthdr, l4_off, &tuple.dport);
+	if (IS_ERR(ret))
+		return ret;
+
+	tuple.nexthdr = nexthdr;
+	slave = lb6_select_slave(skb, &key, &tuple, svc->count, svc->weight);
 	if (!(svc = lb6_lookup_slave(skb, &key, slave)))
 		return DROP_NO_SERVICE;
 
@@ -121,6 +129,7 @@ static inline int handle_ipv4(struct __sk_buff *skb)
 	void *data;
 	void *data_end;
 	struct lb4_key key = {};
+	struct ipv4_ct_tuple tuple = {};
 	struct lb4_service *svc;
 	struct iphdr *ip;
 	struct csum_offset csum_off = {};
@@ -136,6 +145,8 @@ static inline int handle_ipv4(struct __sk_buff *skb)
 
 	nexthdr = ip->protocol;
 	key.address = ip->daddr;
+	tuple.daddr = ip->daddr;
+	tuple.saddr = ip->saddr;
 	l3_off = ETH_HLEN;
 	l4_off = ETH_HLEN + ipv4_hdrlen(ip);
 	csum_l4_offset_and_flags(nexthdr, &csum_off);
@@ -157,7 +168,12 @@ static inline int handle_ipv4(struct __sk_buff *skb)
 		return TC_ACT_OK;
 	}
 
-	slave = lb4_select_slave(skb, &key, svc->count, svc->weight);
+	ret = lb_extract_tuple_ports(skb, nexthdr, l4_off, &tuple.dport);
+	if (IS_ERR(ret))
+		return ret;
+
+	tuple.nexthdr = nexthdr;
+	slave = lb4_select_slave(skb, &key, &tuple, svc->count, svc->weight);
 	if (!(svc = lb4_lookup_slave(skb, &key, slave)))
 		return DROP_NO_SERVICE;
 
@@ -185,9 +185,15 @@ struct policy_key {
 			pad:7;
 };
 
+/* Flags of struct policy_entry */
+#define POLICY_FLAG_DENY	1
+
 struct policy_entry {
 	__be16		proxy_port;
-	__u16		pad[3];
+	__u8		flags;
+	__u8		pad0;
+	__u16		pad1;
+	__u16		pad2;
 	__u64		packets;
 	__u64		bytes;
 };
@@ -212,6 +218,7 @@ enum {
 	CILIUM_NOTIFY_DBG_MSG,
 	CILIUM_NOTIFY_DBG_CAPTURE,
 	CILIUM_NOTIFY_TRACE,
+	CILIUM_NOTIFY_POLICY_AUDIT,
 };
 
 #define NOTIFY_COMMON_HDR \
@@ -267,6 +274,7 @@ enum {
 #define DROP_NO_TUNNEL_ENDPOINT -160
 #define DROP_PROXYMAP_CREATE_FAILED	-161
 #define DROP_POLICY_CIDR		-162
+#define DROP_POLICY_DENY	-163
 
 /* Cilium metrics reason for forwarding packet.
  * If reason > 0 then this is a drop reason and value corresponds to -(DROP_*)
@@ -405,6 +413,9 @@ struct ct_entry {
 	__u32 last_rx_report;
 };
 
+/* Slave is terminating and must not be selected for new connections */
+#define LB_SLAVE_F_TERMINATING	1
+
 struct lb6_key {
         union v6addr address;
         __be16 dport;		/* L4 port filter, if unset, all ports apply */
@@ -412,9 +423,15 @@ struct lb6_key {
 } __attribute__((packed));
 
 struct lb6_service {
-	union v6addr target;
+	union {
+		union v6addr target;
+		__u32 affinity_timeout;	/* Master only, ClientIP affinity in seconds */
+	};
 	__be16 port;
-	__u16 count;
+	union {
+		__u16 count;	/* Master only */
+		__u16 flags;	/* Slaves only, LB_SLAVE_F_* */
+	};
 	__u16 rev_nat_index;
 	__u16 weight;
 } __attribute__((packed));
@@ -431,9 +448,15 @@ struct lb4_key {
 } __attribute__((packed));
 
 struct lb4_service {
-	__be32 target;
+	union {
+		__be32 target;
+		__u32 affinity_timeout;	/* Master only, ClientIP affinity in seconds */
+	};
 	__be16 port;
-	__u16 count;
+	union {
+		__u16 count;	/* Master only */
+		__u16 flags;	/* Slaves only, LB_SLAVE_F_* */
+	};
 	__u16 rev_nat_index;
 	__u16 weight;
 } __attribute__((packed));
@@ -449,6 +472,49 @@ struct lb_sequence {
 	__u16 idx[LB_RR_MAX_SEQ];
 };
 
+/* Entry of a Maglev lookup table, keyed by lb4_key/lb6_key with the slave
+ * set to the table index (LB_MAGLEV_TABLE_SIZE generated by daemon in
+ * node_config.h) */
+struct lb_maglev_entry {
+	__u16 slave;
+};
+
+/* Entry of the list of active slaves of a service with terminating slaves,
+ * keyed by lb4_key/lb6_key with the slave set to the list index starting
+ * with 1. Index 0 holds the number of active slaves. */
+struct lb_active_entry {
+	__u16 slave;
+};
+
+struct lb6_affinity_key {
+	union v6addr client_ip;
+	__u16 rev_nat_index;	/* Identifies the service */
+	__u16 pad;
+} __attribute__((packed));
+
+struct lb4_affinity_key {
+	__be32 client_ip;
+	__u16 rev_nat_index;	/* Identifies the service */
+	__u16 pad;
+} __attribute__((packed));
+
+/* The backend is identified by its address and port, the slave only
+ * locates it in the service. The affinity is void if the slave has been
+ * reassigned to another backend in the meantime. */
+struct lb6_affinity_val {
+	union v6addr target;
+	__be16 port;
+	__u16 slave;
+	__u32 last_used;	/* bpf_ktime_get_sec() of the last new connection */
+};
+
+struct lb4_affinity_val {
+	__be32 target;
+	__be16 port;
+	__u16 slave;
+	__u32 last_used;	/* bpf_ktime_get_sec() of the last new connection */
+};
+
 struct ct_state {
 	__u16 rev_nat_index;
 	__u16 loopback:1,
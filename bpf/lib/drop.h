@@ -22,8 +22,10 @@
  * int send_drop_notify(skb, src, dst, dst_id, ifindex, reason, exitcode,
                         __u8 direction)
  * int send_drop_notify_error(skb, error, exitcode, __u8 direction)
+ * void send_policy_audit_notify(skb, src, dst, dst_id, ifindex, reason)
  *
- * If DROP_NOTIFY is not defined, the API will be compiled in as a NOP.
+ * If DROP_NOTIFY is not defined, send_drop_notify() and
+ * send_drop_notify_error() will be compiled in as a NOP.
  */
 
 #ifndef __LIB_DROP__
@@ -35,8 +37,6 @@
 #include "utils.h"
 #include "metrics.h"
 
-#ifdef DROP_NOTIFY
-
 struct drop_notify {
 	NOTIFY_COMMON_HDR
 	__u32		len_orig;
@@ -47,6 +47,8 @@ struct drop_notify {
 	__u32		ifindex;
 };
 
+#ifdef DROP_NOTIFY
+
 __section_tail(CILIUM_MAP_CALLS, CILIUM_CALL_DROP_NOTIFY) int __send_drop_notify(struct __sk_buff *skb)
 {
 	uint64_t skb_len = (uint64_t)skb->len, cap_len = min((uint64_t)TRACE_PAYLOAD_LEN, (uint64_t)skb_len);
@@ -120,6 +122,44 @@ static inline int send_drop_notify(struct __sk_buff *skb, __u32 src, __u32 dst,
 
 #endif
 
+/**
+ * send_policy_audit_notify
+ * @skb:	socket buffer
+ * @src:	source identity
+ * @dst:	destination identity
+ * @dst_id:	designated destination endpoint ID
+ * @ifindex:	designated destination ifindex
+ * @reason:	Reason the packet would have been dropped
+ *
+ * Generate a drop notification of type CILIUM_NOTIFY_POLICY_AUDIT for a
+ * packet which was denied by policy but is let through because the endpoint
+ * runs in policy audit mode. Unlike send_drop_notify(), this is not a
+ * terminal function, and the notification is sent regardless of DROP_NOTIFY
+ * as it is the only trace of the policy violation.
+ */
+static inline void send_policy_audit_notify(struct __sk_buff *skb, __u32 src,
+					    __u32 dst, __u32 dst_id,
+					    __u32 ifindex, int reason)
+{
+	uint64_t skb_len = (uint64_t)skb->len, cap_len = min((uint64_t)TRACE_PAYLOAD_LEN, (uint64_t)skb_len);
+	struct drop_notify msg = {
+		.type = CILIUM_NOTIFY_POLICY_AUDIT,
+		.subtype = reason < 0 ? -reason : reason,
+		.source = EVENT_SOURCE,
+		.hash = get_hash_recalc(skb),
+		.len_orig = skb_len,
+		.len_cap = cap_len,
+		.src_label = src,
+		.dst_label = dst,
+		.dst_id = dst_id,
+		.ifindex = ifindex,
+	};
+
+	skb_event_output(skb, &cilium_events,
+			 (cap_len << 32) | BPF_F_CURRENT_CPU,
+			 &msg, sizeof(msg));
+}
+
 static inline int send_drop_notify_error(struct __sk_buff *skb, int error,
                                          int exitcode, __u8 direction)
 {
@@ -35,6 +35,14 @@
 
 #define CILIUM_LB_MAP_MAX_FE		256
 
+/* Entries of clients which went away are evicted once the affinity maps
+ * are full. */
+#ifdef HAVE_LRU_MAP_TYPE
+#define LB_AFFINITY_MAP_TYPE BPF_MAP_TYPE_LRU_HASH
+#else
+#define LB_AFFINITY_MAP_TYPE BPF_MAP_TYPE_HASH
+#endif
+
 struct bpf_elf_map __section_maps cilium_lb6_reverse_nat = {
 	.type		= BPF_MAP_TYPE_HASH,
 	.size_key	= sizeof(__u16),
@@ -59,6 +67,33 @@ struct bpf_elf_map __section_maps cilium_lb6_rr_seq = {
 	.max_elem       = CILIUM_LB_MAP_MAX_FE,
 };
 
+struct bpf_elf_map __section_maps cilium_lb6_affinity = {
+	.type		= LB_AFFINITY_MAP_TYPE,
+	.size_key	= sizeof(struct lb6_affinity_key),
+	.size_value	= sizeof(struct lb6_affinity_val),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+};
+
+struct bpf_elf_map __section_maps cilium_lb6_active = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb6_key),
+	.size_value	= sizeof(struct lb_active_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+};
+
+#ifdef LB_MAGLEV
+struct bpf_elf_map __section_maps cilium_lb6_maglev = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb6_key),
+	.size_value	= sizeof(struct lb_maglev_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAGLEV_MAP_MAX_ENTRIES,
+	.flags		= BPF_F_NO_PREALLOC,
+};
+#endif
+
 struct bpf_elf_map __section_maps cilium_lb4_reverse_nat = {
 	.type		= BPF_MAP_TYPE_HASH,
 	.size_key	= sizeof(__u16),
@@ -82,6 +117,33 @@ struct bpf_elf_map __section_maps cilium_lb4_rr_seq = {
 	.pinning        = PIN_GLOBAL_NS,
 	.max_elem       = CILIUM_LB_MAP_MAX_FE,
 };
+
+struct bpf_elf_map __section_maps cilium_lb4_affinity = {
+	.type		= LB_AFFINITY_MAP_TYPE,
+	.size_key	= sizeof(struct lb4_affinity_key),
+	.size_value	= sizeof(struct lb4_affinity_val),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+};
+
+struct bpf_elf_map __section_maps cilium_lb4_active = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb4_key),
+	.size_value	= sizeof(struct lb_active_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+};
+
+#ifdef LB_MAGLEV
+struct bpf_elf_map __section_maps cilium_lb4_maglev = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb4_key),
+	.size_value	= sizeof(struct lb_maglev_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAGLEV_MAP_MAX_ENTRIES,
+	.flags		= BPF_F_NO_PREALLOC,
+};
+#endif
 #define REV_NAT_F_TUPLE_SADDR 1
 #ifdef LB_DEBUG
 #define cilium_dbg_lb cilium_dbg
@@ -107,25 +169,107 @@ static inline int lb_next_rr(struct __sk_buff *skb,
 }
 #endif
 
-static inline __u32 lb_enforce_rehash(struct __sk_buff *skb)
+#ifdef LB_MAGLEV
+/* Looks up the slave in the Maglev lookup table of the service. Returns 0 if
+ * the table has no valid entry for the hash. */
+static inline int lb_maglev_slave(void *map, void *key, __u16 count)
 {
-#ifdef HAVE_SET_HASH_INVALID
-	set_hash_invalid(skb);
-#else
-	/* Ugly workaround for 4.8 kernel where we don't have this function. */
-	__u32 tmp;
+	struct lb_maglev_entry *entry;
 
-	skb_load_bytes(skb,  0, &tmp, sizeof(tmp));
-	skb_store_bytes(skb, 0, &tmp, sizeof(tmp), BPF_F_INVALIDATE_HASH);
+	entry = map_lookup_elem(map, key);
+	if (entry && entry->slave && entry->slave <= count)
+		return entry->slave;
+
+	return 0;
+}
 #endif
-	return get_hash_recalc(skb);
+
+/* Selects a slave from the list of active slaves of the service, which is
+ * maintained while the service has terminating slaves. key_slave points to
+ * the slave of the key. Returns 0 if the service has no such list. */
+static inline int lb_active_slave(void *map, void *key, __u16 *key_slave,
+				  __u32 hash, __u16 count)
+{
+	struct lb_active_entry *entry;
+
+	*key_slave = 0;
+	entry = map_lookup_elem(map, key);
+	if (!entry || !entry->slave)
+		return 0;
+
+	*key_slave = (hash % entry->slave) + 1;
+	entry = map_lookup_elem(map, key);
+	if (entry && entry->slave && entry->slave <= count)
+		return entry->slave;
+
+	return 0;
+}
+
+#define lb_rol32(word, shift) (((word) << (shift)) | ((word) >> (32 - (shift))))
+
+/* Mixes a word into the flow hash, following the MurmurHash3 block step. */
+static inline __u32 lb_hash_add(__u32 hash, __u32 word)
+{
+	word *= 0xcc9e2d51;
+	word = lb_rol32(word, 15);
+	word *= 0x1b873593;
+
+	hash ^= word;
+	hash = lb_rol32(hash, 13);
+
+	return hash * 5 + 0xe6546b64;
+}
+
+static inline __u32 lb_hash_final(__u32 hash)
+{
+	hash ^= hash >> 16;
+	hash *= 0x85ebca6b;
+	hash ^= hash >> 13;
+	hash *= 0xc2b2ae35;
+	hash ^= hash >> 16;
+
+	return hash;
+}
+
+/* Returns the hash of the addresses, ports and protocol of the flow. Unlike
+ * the skb hash it is not seeded per host, so all nodes select the same slave
+ * for a flow, e.g. from the same Maglev lookup table entry. */
+static inline __u32 lb6_tuple_hash(struct ipv6_ct_tuple *tuple)
+{
+	__u32 hash = 0;
+
+	hash = lb_hash_add(hash, tuple->daddr.p1);
+	hash = lb_hash_add(hash, tuple->daddr.p2);
+	hash = lb_hash_add(hash, tuple->daddr.p3);
+	hash = lb_hash_add(hash, tuple->daddr.p4);
+	hash = lb_hash_add(hash, tuple->saddr.p1);
+	hash = lb_hash_add(hash, tuple->saddr.p2);
+	hash = lb_hash_add(hash, tuple->saddr.p3);
+	hash = lb_hash_add(hash, tuple->saddr.p4);
+	hash = lb_hash_add(hash, ((__u32) tuple->dport << 16) | tuple->sport);
+	hash = lb_hash_add(hash, tuple->nexthdr);
+
+	return lb_hash_final(hash);
+}
+
+static inline __u32 lb4_tuple_hash(struct ipv4_ct_tuple *tuple)
+{
+	__u32 hash = 0;
+
+	hash = lb_hash_add(hash, tuple->daddr);
+	hash = lb_hash_add(hash, tuple->saddr);
+	hash = lb_hash_add(hash, ((__u32) tuple->dport << 16) | tuple->sport);
+	hash = lb_hash_add(hash, tuple->nexthdr);
+
+	return lb_hash_final(hash);
 }
 
 static inline int lb6_select_slave(struct __sk_buff *skb,
 				   struct lb6_key *key,
+				   struct ipv6_ct_tuple *tuple,
 				   __u16 count, __u16 weight)
 {
-	__u32 hash = lb_enforce_rehash(skb);
+	__u32 hash = lb6_tuple_hash(tuple);
 	int slave = 0;
 
 /* Disabled for now since on older kernels dynamic map access
@@ -146,6 +290,18 @@ static inline int lb6_select_slave(struct __sk_buff *skb,
 	}
 #endif
 
+#ifdef LB_MAGLEV
+	{
+		struct lb6_key maglev_key = {
+			.slave = hash % LB_MAGLEV_TABLE_SIZE,
+			.dport = key->dport,
+		};
+
+		ipv6_addr_copy(&maglev_key.address, &key->address);
+		slave = lb_maglev_slave(&cilium_lb6_maglev, &maglev_key, count);
+	}
+#endif
+
 	if (slave == 0) {
 		/* Slave 0 is reserved for the master slot */
 		slave = (hash % count) + 1;
@@ -157,9 +313,10 @@ static inline int lb6_select_slave(struct __sk_buff *skb,
 
 static inline int lb4_select_slave(struct __sk_buff *skb,
 				   struct lb4_key *key,
+				   struct ipv4_ct_tuple *tuple,
 				   __u16 count, __u16 weight)
 {
-	__u32 hash = lb_enforce_rehash(skb);
+	__u32 hash = lb4_tuple_hash(tuple);
 	int slave = 0;
 
 /* Disabled for now since on older kernels dynamic map access
@@ -180,6 +337,18 @@ static inline int lb4_select_slave(struct __sk_buff *skb,
 	}
 #endif
 
+#ifdef LB_MAGLEV
+	{
+		struct lb4_key maglev_key = {
+			.address = key->address,
+			.dport = key->dport,
+			.slave = hash % LB_MAGLEV_TABLE_SIZE,
+		};
+
+		slave = lb_maglev_slave(&cilium_lb4_maglev, &maglev_key, count);
+	}
+#endif
+
 	if (slave == 0) {
 		/* Slave 0 is reserved for the master slot */
 		slave = (hash % count) + 1;
@@ -215,6 +384,23 @@ static inline int __inline__ extract_l4_port(struct __sk_buff *skb, __u8 nexthdr
 	return 0;
 }
 
+/* Loads the ports of the packet into the ports of a CT tuple in the same
+ * order as ct_lookup4() and ct_lookup6(), so that the flow hashes the same
+ * when the tuple is not filled in by a CT lookup. */
+static inline int __inline__ lb_extract_tuple_ports(struct __sk_buff *skb, __u8 nexthdr,
+						    int l4_off, __be16 *ports)
+{
+	switch (nexthdr) {
+	case IPPROTO_TCP:
+	case IPPROTO_UDP:
+		if (skb_load_bytes(skb, l4_off, ports, 4) < 0)
+			return DROP_CT_INVALID_HDR;
+		break;
+	}
+
+	return 0;
+}
+
 static inline int __inline__ reverse_map_l4_port(struct __sk_buff *skb, __u8 nexthdr,
 						 __be16 port, int l4_off,
 						 struct csum_offset *csum_off)
@@ -395,6 +581,79 @@ static inline struct lb6_service *lb6_lookup_slave(struct __sk_buff *skb,
 	return NULL;
 }
 
+/* Looks up the slave a client was last assigned to for a service with
+ * ClientIP session affinity. Returns 0 if there is none, if the affinity
+ * timed out or if the slave no longer holds the backend of the client. */
+static inline int lb6_affinity_slave(struct __sk_buff *skb, struct lb6_key *key,
+				     struct lb6_affinity_key *affinity_key,
+				     __u32 timeout, __u16 count)
+{
+	struct lb6_affinity_val *val;
+	struct lb6_service *slave_svc;
+
+	val = map_lookup_elem(&cilium_lb6_affinity, affinity_key);
+	if (!val || !val->slave || val->slave > count ||
+	    val->last_used + timeout < bpf_ktime_get_sec())
+		return 0;
+
+	slave_svc = lb6_lookup_slave(skb, key, val->slave);
+	if (!slave_svc || slave_svc->port != val->port ||
+	    ipv6_addrcmp(&slave_svc->target, &val->target))
+		return 0;
+
+	return val->slave;
+}
+
+static inline void lb6_affinity_update(struct lb6_affinity_key *affinity_key,
+				       __u16 slave, struct lb6_service *slave_svc)
+{
+	struct lb6_affinity_val val = {
+		.port = slave_svc->port,
+		.slave = slave,
+		.last_used = bpf_ktime_get_sec(),
+	};
+
+	ipv6_addr_copy(&val.target, &slave_svc->target);
+	map_update_elem(&cilium_lb6_affinity, affinity_key, &val, 0);
+}
+
+/* Selects the slave of a new connection. The preferred slave, e.g. the one
+ * the client has affinity to, is used if set and not terminating. Otherwise
+ * the slave selected by lb6_select_slave() is used, or one of the active
+ * slaves if it is terminating. */
+static inline int lb6_select_active_slave(struct __sk_buff *skb,
+					  struct lb6_key *key,
+					  struct ipv6_ct_tuple *tuple, __u16 count,
+					  __u16 weight, __u16 preferred)
+{
+	struct lb6_service *slave_svc;
+	int slave = preferred;
+
+	if (slave) {
+		slave_svc = lb6_lookup_slave(skb, key, slave);
+		if (slave_svc && !(slave_svc->flags & LB_SLAVE_F_TERMINATING))
+			return slave;
+	}
+
+	slave = lb6_select_slave(skb, key, tuple, count, weight);
+	slave_svc = lb6_lookup_slave(skb, key, slave);
+	if (slave_svc && (slave_svc->flags & LB_SLAVE_F_TERMINATING)) {
+		struct lb6_key active_key = {
+			.dport = key->dport,
+		};
+		int active;
+
+		ipv6_addr_copy(&active_key.address, &key->address);
+		active = lb_active_slave(&cilium_lb6_active, &active_key,
+					 &active_key.slave,
+					 lb6_tuple_hash(tuple), count);
+		if (active)
+			slave = active;
+	}
+
+	return slave;
+}
+
 static inline int __inline__ lb6_xlate(struct __sk_buff *skb, union v6addr *new_dst, __u8 nexthdr,
 				       int l3_off, int l4_off, struct csum_offset *csum_off,
 				       struct lb6_key *key, struct lb6_service *svc)
@@ -431,12 +690,24 @@ static inline int __inline__ lb6_local(void *map, struct __sk_buff *skb, int l3_
 	__u32 monitor; // Deliberately ignored; regular CT will determine monitoring.
 	union v6addr *addr;
 	__u8 flags = tuple->flags;
+	__u32 affinity_timeout = svc->affinity_timeout, update_affinity = 0;
+	struct lb6_affinity_key affinity_key = {
+		.rev_nat_index = svc->rev_nat_index,
+	};
 	int ret;
 
+	ipv6_addr_copy(&affinity_key.client_ip, &tuple->saddr);
+
 	ret = ct_lookup6(map, tuple, skb, l4_off, CT_SERVICE, state, &monitor);
 	switch(ret) {
 	case CT_NEW:
-		state->slave = lb6_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = 0;
+		if (affinity_timeout)
+			state->slave = lb6_affinity_slave(skb, key, &affinity_key,
+							  affinity_timeout, svc->count);
+		state->slave = lb6_select_active_slave(skb, key, tuple, svc->count,
+							svc->weight, state->slave);
+		update_affinity = affinity_timeout;
 		ret = ct_create6(map, tuple, skb, CT_SERVICE, state);
 		/* Fail closed, if the conntrack entry create fails drop
 		 * service lookup.
@@ -464,8 +735,18 @@ static inline int __inline__ lb6_local(void *map, struct __sk_buff *skb, int l3_
 			tuple->flags = flags;
 			return DROP_NO_SERVICE;
 		}
-		state->slave = lb6_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = lb6_select_active_slave(skb, key, tuple, svc->count,
+							svc->weight, 0);
 		ct_update6_slave(map, tuple, state);
+		if (affinity_timeout) {
+			struct lb6_service *slave_svc;
+
+			slave_svc = lb6_lookup_slave(skb, key, state->slave);
+			if (slave_svc)
+				lb6_affinity_update(&affinity_key, state->slave, slave_svc);
+		}
+	} else if (update_affinity) {
+		lb6_affinity_update(&affinity_key, state->slave, svc);
 	}
 
 	/* Restore flags so that SERVICE flag is only used in used when the
@@ -650,6 +931,79 @@ static inline struct lb4_service *lb4_lookup_slave(struct __sk_buff *skb,
 	return NULL;
 }
 
+/* Looks up the slave a client was last assigned to for a service with
+ * ClientIP session affinity. Returns 0 if there is none, if the affinity
+ * timed out or if the slave no longer holds the backend of the client. */
+static inline int lb4_affinity_slave(struct __sk_buff *skb, struct lb4_key *key,
+				     struct lb4_affinity_key *affinity_key,
+				     __u32 timeout, __u16 count)
+{
+	struct lb4_affinity_val *val;
+	struct lb4_service *slave_svc;
+
+	val = map_lookup_elem(&cilium_lb4_affinity, affinity_key);
+	if (!val || !val->slave || val->slave > count ||
+	    val->last_used + timeout < bpf_ktime_get_sec())
+		return 0;
+
+	slave_svc = lb4_lookup_slave(skb, key, val->slave);
+	if (!slave_svc || slave_svc->target != val->target ||
+	    slave_svc->port != val->port)
+		return 0;
+
+	return val->slave;
+}
+
+static inline void lb4_affinity_update(struct lb4_affinity_key *affinity_key,
+				       __u16 slave, struct lb4_service *slave_svc)
+{
+	struct lb4_affinity_val val = {
+		.target = slave_svc->target,
+		.port = slave_svc->port,
+		.slave = slave,
+		.last_used = bpf_ktime_get_sec(),
+	};
+
+	map_update_elem(&cilium_lb4_affinity, affinity_key, &val, 0);
+}
+
+/* Selects the slave of a new connection. The preferred slave, e.g. the one
+ * the client has affinity to, is used if set and not terminating. Otherwise
+ * the slave selected by lb4_select_slave() is used, or one of the active
+ * slaves if it is terminating. */
+static inline int lb4_select_active_slave(struct __sk_buff *skb,
+					  struct lb4_key *key,
+					  struct ipv4_ct_tuple *tuple, __u16 count,
+					  __u16 weight, __u16 preferred)
+{
+	struct lb4_service *slave_svc;
+	int slave = preferred;
+
+	if (slave) {
+		slave_svc = lb4_lookup_slave(skb, key, slave);
+		if (slave_svc && !(slave_svc->flags & LB_SLAVE_F_TERMINATING))
+			return slave;
+	}
+
+	slave = lb4_select_slave(skb, key, tuple, count, weight);
+	slave_svc = lb4_lookup_slave(skb, key, slave);
+	if (slave_svc && (slave_svc->flags & LB_SLAVE_F_TERMINATING)) {
+		struct lb4_key active_key = {
+			.address = key->address,
+			.dport = key->dport,
+		};
+		int active;
+
+		active = lb_active_slave(&cilium_lb4_active, &active_key,
+					 &active_key.slave,
+					 lb4_tuple_hash(tuple), count);
+		if (active)
+			slave = active;
+	}
+
+	return slave;
+}
+
 static inline int __inline__
 lb4_xlate(struct __sk_buff *skb, __be32 *new_daddr, __be32 *new_saddr,
 	  __be32 *old_saddr, __u8 nexthdr, int l3_off, int l4_off,
@@ -706,12 +1060,23 @@ static inline int __inline__ lb4_local(void *map, struct __sk_buff *skb,
 	__u32 monitor; // Deliberately ignored; regular CT will determine monitoring.
 	__be32 new_saddr = 0, new_daddr;
 	__u8 flags = tuple->flags;
+	__u32 affinity_timeout = svc->affinity_timeout, update_affinity = 0;
+	struct lb4_affinity_key affinity_key = {
+		.client_ip = saddr,
+		.rev_nat_index = svc->rev_nat_index,
+	};
 	int ret;
 
 	ret = ct_lookup4(map, tuple, skb, l4_off, CT_SERVICE, state, &monitor);
 	switch(ret) {
 	case CT_NEW:
-		state->slave = lb4_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = 0;
+		if (affinity_timeout)
+			state->slave = lb4_affinity_slave(skb, key, &affinity_key,
+							  affinity_timeout, svc->count);
+		state->slave = lb4_select_active_slave(skb, key, tuple, svc->count,
+							svc->weight, state->slave);
+		update_affinity = affinity_timeout;
 		ret = ct_create4(map, tuple, skb, CT_SERVICE, state);
 		/* Fail closed, if the conntrack entry create fails drop
 		 * service lookup.
@@ -739,8 +1104,18 @@ static inline int __inline__ lb4_local(void *map, struct __sk_buff *skb,
 			tuple->flags = flags;
 			return DROP_NO_SERVICE;
 		}
-		state->slave = lb4_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = lb4_select_active_slave(skb, key, tuple, svc->count,
+							svc->weight, 0);
 		ct_update4_slave(map, tuple, state);
+		if (affinity_timeout) {
+			struct lb4_service *slave_svc;
+
+			slave_svc = lb4_lookup_slave(skb, key, state->slave);
+			if (slave_svc)
+				lb4_affinity_update(&affinity_key, state->slave, slave_svc);
+		}
+	} else if (update_affinity) {
+		lb4_affinity_update(&affinity_key, state->slave, svc);
 	}
 
 	/* Restore flags so that SERVICE flag is only used in used when the
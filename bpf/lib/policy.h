@@ -71,7 +71,9 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 		}
 	}
 
-	/* If L4 policy check misses, fall back to L3. */
+	/* If L4 policy check misses, fall back to L3. Deny entries for
+	 * specific ports are always installed for the exact identity, so
+	 * they have been found above and take precedence over L3 allows. */
 	key.dport = 0;
 	key.protocol = 0;
 	policy = map_lookup_elem(map, &key);
@@ -79,6 +81,8 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 		/* FIXME: Use per cpu counters */
 		__sync_fetch_and_add(&policy->packets, 1);
 		__sync_fetch_and_add(&policy->bytes, skb->len);
+		if (unlikely(policy->flags & POLICY_FLAG_DENY))
+			return DROP_POLICY_DENY;
 		return TC_ACT_OK;
 	}
 
@@ -103,6 +107,8 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 	return DROP_POLICY;
 get_proxy_port:
 	if (likely(policy)) {
+		if (unlikely(policy->flags & POLICY_FLAG_DENY))
+			return DROP_POLICY_DENY;
 		return policy->proxy_port;
 	}
 allow:
@@ -138,8 +144,15 @@ policy_can_access_ingress(struct __sk_buff *skb, __u32 src_identity,
 
 	cilium_dbg(skb, DBG_POLICY_DENIED, src_identity, SECLABEL);
 
-#ifndef IGNORE_DROP
-	return DROP_POLICY;
+	if (ret != DROP_POLICY_DENY)
+		ret = DROP_POLICY;
+
+#if defined POLICY_AUDIT_MODE
+	/* Report the verdict but let the packet pass */
+	send_policy_audit_notify(skb, src_identity, SECLABEL, 0, 0, ret);
+	return TC_ACT_OK;
+#elif !defined IGNORE_DROP
+	return ret;
 #else
 	return TC_ACT_OK;
 #endif
@@ -156,10 +169,19 @@ policy_can_egress(struct __sk_buff *skb, __u32 identity, __u16 dport, __u8 proto
 		return ret;
 
 	cilium_dbg(skb, DBG_POLICY_DENIED, SECLABEL, identity);
-#ifndef IGNORE_DROP
-	return DROP_POLICY;
-#endif
+
+	if (ret != DROP_POLICY_DENY)
+		ret = DROP_POLICY;
+
+#if defined POLICY_AUDIT_MODE
+	/* Report the verdict but let the packet pass */
+	send_policy_audit_notify(skb, SECLABEL, identity, 0, 0, ret);
 	return TC_ACT_OK;
+#elif !defined IGNORE_DROP
+	return ret;
+#else
+	return TC_ACT_OK;
+#endif
 }
 
 static inline int policy_can_egress6(struct __sk_buff *skb,
@@ -58,6 +58,9 @@
 #define LB_REDIRECT 1
 #define LB_DST_MAC { .addr = { 0xce, 0x72, 0xa7, 0x03, 0x88, 0x58 } }
 #define CILIUM_LB_MAP_MAX_ENTRIES	65536
+#define LB_MAGLEV_TABLE_SIZE 251
+#define CILIUM_LB_MAGLEV_MAP_MAX_ENTRIES 1028096
+#define LB_MAGLEV
 #define PROXY_MAP_SIZE 524288
 #define POLICY_MAP_SIZE 16384
 #define IPCACHE_MAP_SIZE 512000
@@ -133,7 +133,9 @@ func formatMap(w io.Writer, statsMap []policymap.PolicyEntryDump) {
 			port = fmt.Sprintf("%d/%s", dport, proto.String())
 		}
 		proxyPort := "NONE"
-		if stat.ProxyPort != 0 {
+		if stat.IsDeny() {
+			proxyPort = "DENY"
+		} else if stat.ProxyPort != 0 {
 			proxyPort = strconv.FormatUint(uint64(byteorder.NetworkToHost(stat.ProxyPort).(uint16)), 10)
 		}
 		if printIDs {
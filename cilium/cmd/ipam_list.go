@@ -0,0 +1,111 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/models"
+	pkg "github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/command"
+
+	"github.com/spf13/cobra"
+)
+
+var listLeakedOnly bool
+
+// ipamListCmd represents the ipam_list command
+var ipamListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List allocated IP addresses and their owner",
+	Run: func(cmd *cobra.Command, args []string) {
+		status := getIPAMStatus()
+
+		if command.OutputJSON() {
+			if err := command.PrintOutput(status); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "IP\tOwner\tState")
+		for _, ip := range ipamAddresses(status, listLeakedOnly) {
+			state := "allocated"
+			if isLeaked(status, ip) {
+				state = "leaked"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ip, status.Allocations[ip], state)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamListCmd)
+	ipamListCmd.Flags().BoolVar(&listLeakedOnly, "leaked", false, "Only list leaked IP addresses")
+	command.AddJSONOutput(ipamListCmd)
+}
+
+func getIPAMStatus() *models.IPAMStatus {
+	resp, err := client.Daemon.GetHealthz(nil)
+	if err != nil {
+		Fatalf("Cannot get IPAM status: %s", pkg.Hint(err))
+	}
+
+	if resp.Payload.IPAM == nil {
+		return &models.IPAMStatus{}
+	}
+	return resp.Payload.IPAM
+}
+
+func isLeaked(status *models.IPAMStatus, ip string) bool {
+	for _, leaked := range status.Leaked {
+		if leaked == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// ipamAddresses returns the sorted list of allocated addresses
+func ipamAddresses(status *models.IPAMStatus, leakedOnly bool) []string {
+	var addresses []string
+	if leakedOnly {
+		addresses = append(addresses, status.Leaked...)
+	} else {
+		addresses = append(addresses, status.IPV4...)
+		addresses = append(addresses, status.IPV6...)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		a, b := net.ParseIP(addresses[i]), net.ParseIP(addresses[j])
+		if a == nil || b == nil {
+			return addresses[i] < addresses[j]
+		}
+		if len(a.To4()) != len(b.To4()) {
+			// IPv4 before IPv6
+			return a.To4() != nil
+		}
+		return bytes.Compare(a, b) < 0
+	})
+
+	return addresses
+}
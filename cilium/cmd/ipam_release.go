@@ -0,0 +1,49 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var releaseLeaked bool
+
+// ipamReleaseCmd represents the ipam_release command
+var ipamReleaseCmd = &cobra.Command{
+	Use:     "release [<ip>...]",
+	Short:   "Release allocated IP addresses",
+	Example: "cilium ipam release 10.11.0.36, cilium ipam release --leaked",
+	Run: func(cmd *cobra.Command, args []string) {
+		addresses := args
+		if releaseLeaked {
+			addresses = append(addresses, getIPAMStatus().Leaked...)
+		} else if len(addresses) == 0 {
+			Usagef(cmd, "Missing IP address argument")
+		}
+
+		for _, ip := range addresses {
+			if err := client.IPAMReleaseIP(ip); err != nil {
+				Fatalf("Cannot release IP address %s: %s", ip, err)
+			}
+			fmt.Printf("Released IP address %s\n", ip)
+		}
+	},
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamReleaseCmd)
+	ipamReleaseCmd.Flags().BoolVar(&releaseLeaked, "leaked", false, "Release all leaked IP addresses")
+}
@@ -162,14 +162,14 @@ func match(messageType int, src uint16, dst uint16) bool {
 	return true
 }
 
-// dropEvents prints out all the received drop notifications.
+// dropEvents prints out all the received drop and policy audit notifications.
 func dropEvents(prefix string, data []byte) {
 	dn := monitor.DropNotify{}
 
 	if err := binary.Read(bytes.NewReader(data), byteorder.Native, &dn); err != nil {
 		fmt.Printf("Error while parsing drop notification message: %s\n", err)
 	}
-	if match(monitor.MessageTypeDrop, dn.Source, uint16(dn.DstID)) {
+	if match(int(dn.Type), dn.Source, uint16(dn.DstID)) {
 		switch verbosity {
 		case INFO:
 			dn.DumpInfo(data)
@@ -285,7 +285,7 @@ func receiveEvent(data []byte, cpu int) {
 	messageType := data[0]
 
 	switch messageType {
-	case monitor.MessageTypeDrop:
+	case monitor.MessageTypeDrop, monitor.MessageTypePolicyAudit:
 		dropEvents(prefix, data)
 	case monitor.MessageTypeDebug:
 		debugEvents(prefix, data)
@@ -19,6 +19,7 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/command"
 	"github.com/cilium/cilium/pkg/loadbalancer"
 
@@ -49,6 +50,8 @@ var serviceGetCmd = &cobra.Command{
 		for _, be := range svc.Status.Realized.BackendAddresses {
 			if bea, err := loadbalancer.NewL3n4AddrFromBackendModel(be); err != nil {
 				slice = append(slice, fmt.Sprintf("invalid backend: %+v", be))
+			} else if be.State == models.BackendAddressStateTerminating {
+				slice = append(slice, fmt.Sprintf("%s [terminating since %s]", bea.String(), be.TerminatingSince))
 			} else {
 				slice = append(slice, bea.String())
 			}
@@ -43,6 +43,7 @@ import (
 	"github.com/cilium/cilium/pkg/controller"
 	"github.com/cilium/cilium/pkg/counter"
 	bpfIPCache "github.com/cilium/cilium/pkg/datapath/ipcache"
+	"github.com/cilium/cilium/pkg/datapath/ipsec"
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/endpoint"
 	"github.com/cilium/cilium/pkg/endpointmanager"
@@ -583,7 +584,7 @@ func (d *Daemon) installIptablesRules() error {
 		return err
 	}
 
-	if masquerade {
+	if option.Config.Masquerade {
 		ingressSnatSrcAddrExclusion := node.GetHostMasqueradeIPv4().String()
 		if option.Config.Tunnel == option.TunnelDisabled {
 			ingressSnatSrcAddrExclusion = node.GetIPv4ClusterRange().String()
@@ -651,6 +652,10 @@ func (d *Daemon) installIptablesRules() error {
 		}
 	}
 
+	if err := installIPAMPoolRules(); err != nil {
+		return err
+	}
+
 	for _, c := range ciliumChains {
 		if err := c.installFeeder(); err != nil {
 			return fmt.Errorf("cannot install feeder rule %s: %s", c.feederArgs, err)
@@ -660,6 +665,58 @@ func (d *Daemon) installIptablesRules() error {
 	return nil
 }
 
+// installIPAMPoolRules accepts forwarded traffic from and to the IPv4 CIDRs
+// of all named IPAM pools and masquerades traffic from the pools which have
+// masquerading enabled in the same way as traffic from the allocation range
+// of the node.
+func installIPAMPoolRules() error {
+	egressSnatDstAddrExclusion := node.GetIPv4AllocRange().String()
+	if option.Config.Tunnel == option.TunnelDisabled {
+		egressSnatDstAddrExclusion = node.GetIPv4ClusterRange().String()
+	}
+
+	for _, pool := range ipam.GetPools() {
+		if pool.IPv4CIDR == nil {
+			continue
+		}
+		cidr := pool.IPv4CIDR.String()
+
+		if err := runProg("iptables", []string{
+			"-A", ciliumForwardChain,
+			"-d", cidr,
+			"-o", "cilium_host",
+			"-m", "comment", "--comment", "cilium: any->pool " + pool.Name + " on cilium_host forward accept",
+			"-j", "ACCEPT"}, false); err != nil {
+			return err
+		}
+
+		if err := runProg("iptables", []string{
+			"-A", ciliumForwardChain,
+			"-s", cidr,
+			"-m", "comment", "--comment", "cilium: pool " + pool.Name + "->any forward accept",
+			"-j", "ACCEPT"}, false); err != nil {
+			return err
+		}
+
+		if !pool.Masquerade {
+			continue
+		}
+
+		if err := runProg("iptables", []string{
+			"-t", "nat",
+			"-A", ciliumPostNatChain,
+			"-s", cidr,
+			"!", "-d", egressSnatDstAddrExclusion,
+			"!", "-o", "cilium_+",
+			"-m", "comment", "--comment", "cilium masquerade pool " + pool.Name,
+			"-j", "MASQUERADE"}, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetCompilationLock returns the mutex responsible for synchronizing compilation
 // of BPF programs.
 func (d *Daemon) GetCompilationLock() *lock.RWMutex {
@@ -833,6 +890,15 @@ func (d *Daemon) init() error {
 		if _, err := lbmap.RRSeq6Map.OpenOrCreate(); err != nil {
 			return err
 		}
+		if _, err := lbmap.Maglev6Map.OpenOrCreate(); err != nil {
+			return err
+		}
+		if _, err := lbmap.Active6Map.OpenOrCreate(); err != nil {
+			return err
+		}
+		if _, err := lbmap.Affinity6Map.OpenOrCreate(); err != nil {
+			return err
+		}
 		if !option.Config.IPv4Disabled {
 			if _, err := lbmap.Service4Map.OpenOrCreate(); err != nil {
 				return err
@@ -843,7 +909,24 @@ func (d *Daemon) init() error {
 			if _, err := lbmap.RRSeq4Map.OpenOrCreate(); err != nil {
 				return err
 			}
+			if _, err := lbmap.Maglev4Map.OpenOrCreate(); err != nil {
+				return err
+			}
+			if _, err := lbmap.Active4Map.OpenOrCreate(); err != nil {
+				return err
+			}
+			if _, err := lbmap.Affinity4Map.OpenOrCreate(); err != nil {
+				return err
+			}
 		}
+
+		// Start the controller which removes terminating service backends
+		// once they no longer serve any connection.
+		controller.NewManager().UpdateController("lb-backend-drain",
+			controller.ControllerParams{
+				DoFunc:      d.drainTerminatingBackends,
+				RunInterval: 10 * time.Second,
+			})
 		// Clean all lb entries
 		if !option.Config.RestoreState {
 			log.Debug("cleaning up all BPF LB maps")
@@ -860,6 +943,15 @@ func (d *Daemon) init() error {
 			if err := lbmap.RRSeq6Map.DeleteAll(); err != nil {
 				return err
 			}
+			if err := lbmap.Maglev6Map.DeleteAll(); err != nil {
+				return err
+			}
+			if err := lbmap.Active6Map.DeleteAll(); err != nil {
+				return err
+			}
+			if err := lbmap.Affinity6Map.DeleteAll(); err != nil {
+				return err
+			}
 
 			if !option.Config.IPv4Disabled {
 				if err := lbmap.Service4Map.DeleteAll(); err != nil {
@@ -868,6 +960,15 @@ func (d *Daemon) init() error {
 				if err := lbmap.RRSeq4Map.DeleteAll(); err != nil {
 					return err
 				}
+				if err := lbmap.Maglev4Map.DeleteAll(); err != nil {
+					return err
+				}
+				if err := lbmap.Active4Map.DeleteAll(); err != nil {
+					return err
+				}
+				if err := lbmap.Affinity4Map.DeleteAll(); err != nil {
+					return err
+				}
 			}
 
 			// If we are not restoring state, all endpoints can be
@@ -940,6 +1041,11 @@ func createNodeConfigHeaderfile() error {
 	fmt.Fprintf(fw, "#define INIT_ID %d\n", identity.GetReservedID(labels.IDNameInit))
 	fmt.Fprintf(fw, "#define LB_RR_MAX_SEQ %d\n", lbmap.MaxSeq)
 	fmt.Fprintf(fw, "#define CILIUM_LB_MAP_MAX_ENTRIES %d\n", lbmap.MaxEntries)
+	fmt.Fprintf(fw, "#define LB_MAGLEV_TABLE_SIZE %d\n", loadbalancer.MaglevTableSize)
+	fmt.Fprintf(fw, "#define CILIUM_LB_MAGLEV_MAP_MAX_ENTRIES %d\n", lbmap.MaxMaglevEntries)
+	if option.Config.EnableLBMaglev {
+		fmt.Fprintf(fw, "#define LB_MAGLEV\n")
+	}
 	fmt.Fprintf(fw, "#define TUNNEL_ENDPOINT_MAP_SIZE %d\n", tunnel.MaxEntries)
 	fmt.Fprintf(fw, "#define PROXY_MAP_SIZE %d\n", proxymap.MaxEntries)
 	fmt.Fprintf(fw, "#define ENDPOINTS_MAP_SIZE %d\n", lxcmap.MaxEntries)
@@ -1083,6 +1189,7 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 	}
 
 	ctmap.InitMapInfo(option.Config.CTMapEntriesGlobalTCP, option.Config.CTMapEntriesGlobalAny)
+	lbmap.InitAffinityMaps()
 
 	if err := workloads.Setup(option.Config.Workloads, map[string]string{}); err != nil {
 		return nil, nil, fmt.Errorf("unable to setup workload: %s", err)
@@ -1202,7 +1309,9 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 
 	// Set up ipam conf after init() because we might be running d.conf.KVStoreIPv4Registration
 	log.Info("Initializing IPAM")
-	ipam.Init()
+	if err := ipam.Init(); err != nil {
+		log.WithError(err).Fatal("IPAM init failed")
+	}
 
 	// restore endpoints before any IPs are allocated to avoid eventual IP
 	// conflicts later on, otherwise any IP conflict will result in the
@@ -1235,6 +1344,8 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 		log.WithError(err).Fatal("IPAM init failed")
 	}
 
+	d.reconcileIPAM()
+
 	log.Info("Validating configured node address ranges")
 	if err := node.ValidatePostInit(); err != nil {
 		log.WithError(err).Fatal("postinit failed")
@@ -1266,7 +1377,7 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 
 	if !option.Config.IPv4Disabled {
 		// Allocate IPv4 service loopback IP
-		loopbackIPv4, _, err := ipam.AllocateNext("ipv4")
+		loopbackIPv4, _, err := ipam.AllocateNext("ipv4", ipam.OwnerLoopback)
 		if err != nil {
 			return nil, restoredEndpoints, fmt.Errorf("Unable to reserve IPv4 loopback address: %s", err)
 		}
@@ -1274,6 +1385,27 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 		log.Infof("  Loopback IPv4: %s", node.GetIPv4Loopback().String())
 	}
 
+	if option.Config.EnableIPSec {
+		if err := ipsec.LoadKeysFromFile(option.Config.IPSecKeyFile); err != nil {
+			log.WithError(err).Fatal("Unable to load IPsec keys")
+		}
+
+		// Periodically re-read the keys to pick up rotated keys
+		controller.NewManager().UpdateController("ipsec-key-reload",
+			controller.ControllerParams{
+				DoFunc: func() error {
+					return ipsec.LoadKeysFromFile(option.Config.IPSecKeyFile)
+				},
+				RunInterval: 10 * time.Second,
+			})
+	} else if !option.Config.DryMode {
+		// Remove the encryption set up by a previous run with IPsec
+		// enabled
+		if err := ipsec.DeleteAll(); err != nil {
+			log.WithError(err).Warn("Unable to remove IPsec configuration")
+		}
+	}
+
 	if err := node.ConfigureLocalNode(); err != nil {
 		log.WithError(err).Fatal("Unable to initialize local node")
 	}
@@ -1319,8 +1451,19 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 	if err := fqdn.ConfigFromResolvConf(); err != nil {
 		return nil, nil, err
 	}
+	// When DNS data is only learned from the DNS proxy it is refreshed with
+	// every lookup made by an endpoint, so a lower bound is enough.
+	minTTL := toFQDNsMinTTL
+	if minTTL == 0 {
+		if toFQDNsEnablePoller {
+			minTTL = defaults.ToFQDNsMinTTL
+		} else {
+			minTTL = defaults.ToFQDNsMinTTLProxy
+		}
+	}
 	d.dnsPoller = fqdn.NewDNSPoller(fqdn.DNSPollerConfig{
-		MinTTL:         toFQDNsMinTTL,
+		MinTTL:         minTTL,
+		DisablePolling: !toFQDNsEnablePoller,
 		LookupDNSNames: fqdn.DNSLookupDefaultResolver,
 		AddGeneratedRules: func(generatedRules []*policyApi.Rule) error {
 			// Insert the new rules into the policy repository. We need them to
@@ -1331,6 +1474,10 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 		}})
 	fqdn.StartDNSPoller(d.dnsPoller)
 
+	if err := d.l7Proxy.StartDNSProxy(uint16(toFQDNsProxyPort), d.notifyOnDNSMsg); err != nil {
+		return nil, nil, fmt.Errorf("unable to start DNS proxy: %s", err)
+	}
+
 	return &d, restoredEndpoints, nil
 }
 
@@ -25,6 +25,7 @@ import (
 	. "github.com/cilium/cilium/api/v1/server/restapi/endpoint"
 	"github.com/cilium/cilium/pkg/api"
 	"github.com/cilium/cilium/pkg/completion"
+	"github.com/cilium/cilium/pkg/datapath/loader"
 	"github.com/cilium/cilium/pkg/endpoint"
 	endpointid "github.com/cilium/cilium/pkg/endpoint/id"
 	"github.com/cilium/cilium/pkg/endpointmanager"
@@ -204,13 +205,34 @@ func (h *putEndpointID) Handle(params PutEndpointIDParams) middleware.Responder
 	} else if n != epTemplate.ID {
 		return api.New(PutEndpointIDInvalidCode,
 			"ID parameter does not match ID in endpoint parameter")
-	} else if epTemplate.ID == 0 {
+	} else if epTemplate.ID == 0 && !epTemplate.Chained {
 		return api.New(PutEndpointIDInvalidCode,
 			"endpoint ID cannot be 0")
 	}
 
+	// The addresses of chained endpoints are not allocated by IPAM, so
+	// their endpoint ID cannot be derived from the IPv6 address. The ID
+	// is allocated if none is given, otherwise it is reserved.
+	if epTemplate.Chained {
+		owner := epTemplate.ContainerID
+		if epTemplate.ID == 0 {
+			id, err := ipam.AllocateEndpointID(owner)
+			if err != nil {
+				return api.Error(PutEndpointIDFailedCode, err)
+			}
+			epTemplate.ID = int64(id)
+			params.ID = endpointid.NewCiliumID(epTemplate.ID)
+		} else if err := ipam.ReserveEndpointID(uint16(epTemplate.ID), owner); err != nil {
+			return api.Error(PutEndpointIDExistsCode, err)
+		}
+		logger = logger.WithField(logfields.EndpointID, epTemplate.ID)
+	}
+
 	code, err := h.d.createEndpoint(epTemplate, params.ID, params.Endpoint.Labels)
 	if err != nil {
+		if epTemplate.Chained {
+			ipam.ReleaseEndpointID(uint16(epTemplate.ID))
+		}
 		logger.WithError(err).Error("Endpoint cannot be created")
 		return api.Error(code, err)
 	}
@@ -499,9 +521,19 @@ func (d *Daemon) deleteEndpointQuiet(ep *endpoint.Endpoint, releaseIP bool) []er
 		if errs := ep.DeleteMapsLocked(); errs != nil {
 			errors = append(errors, errs...)
 		}
+
+		// The interface of a chained endpoint belongs to the plugin
+		// Cilium is chained to and outlives the endpoint
+		if ep.Chained {
+			if err := loader.RemoveDatapath(ep.IfName); err != nil {
+				errors = append(errors, fmt.Errorf("unable to detach BPF program from %s: %s", ep.IfName, err))
+			}
+		}
 	}
 
-	if releaseIP {
+	if releaseIP && ep.Chained {
+		ipam.ReleaseEndpointID(ep.ID)
+	} else if releaseIP {
 		if !option.Config.IPv4Disabled {
 			if err := ipam.ReleaseIP(ep.IPv4.IP()); err != nil {
 				errors = append(errors, fmt.Errorf("unable to release ipv4 address: %s", err))
@@ -34,7 +34,7 @@ import (
 )
 
 func getEPTemplate(c *C) *models.EndpointChangeRequest {
-	ip4, ip6, err := ipam.AllocateNext("")
+	ip4, ip6, err := ipam.AllocateNext("", "test")
 	c.Assert(err, Equals, nil)
 	c.Assert(ip4, Not(IsNil))
 	c.Assert(ip6, Not(IsNil))
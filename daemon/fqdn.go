@@ -0,0 +1,108 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/cilium/cilium/pkg/defaults"
+	"github.com/cilium/cilium/pkg/endpoint"
+	"github.com/cilium/cilium/pkg/endpointmanager"
+	"github.com/cilium/cilium/pkg/fqdn"
+	"github.com/cilium/cilium/pkg/fqdn/dnsproxy"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+)
+
+// notifyOnDNSMsg is called by the DNS proxy for every DNS response before it
+// is returned to the endpoint. The DNS data in successful responses is fed
+// into the DNS poller, which updates the rules generated from ToFQDNs rules.
+// The response is held back until the endpoint that made the request has the
+// resulting policy in place. An error is returned if the policy could not be
+// updated or was not in place within defaults.ToFQDNsProxyResponseTimeout, so
+// that the response is not released to the endpoint.
+func (d *Daemon) notifyOnDNSMsg(lookupTime time.Time, srcAddr string, srcIdentity uint32, protocol, dstAddr string, msg *dns.Msg) error {
+	if !msg.Response || msg.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+
+	qname, responseIPs, TTL, err := dnsproxy.ExtractMsgDetails(msg)
+	if err != nil {
+		return err
+	}
+
+	scopedLog := log.WithFields(logrus.Fields{
+		logfields.DNSName:  qname,
+		logfields.IPAddrs:  responseIPs,
+		logfields.Identity: srcIdentity,
+	})
+	scopedLog.Debug("Received DNS response from DNS proxy")
+
+	if len(responseIPs) == 0 {
+		return nil
+	}
+
+	err = d.dnsPoller.UpdateGenerateDNS(lookupTime, map[string]*fqdn.DNSIPRecords{
+		qname: {
+			IPs: responseIPs,
+			TTL: int(TTL),
+		}})
+	if err != nil {
+		scopedLog.WithError(err).Error("Cannot update policy from DNS response")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.ToFQDNsProxyResponseTimeout)
+	defer cancel()
+	if err := d.waitForPolicyAt(ctx, srcAddr, d.policy.GetRevision()); err != nil {
+		scopedLog.WithError(err).Warning("Timeout waiting for policy update, not releasing DNS response")
+		return err
+	}
+
+	return nil
+}
+
+// waitForPolicyAt waits until the endpoint with the address in srcAddr has
+// realized the policy revision rev. When the endpoint cannot be found by its
+// IP, all endpoints are waited for.
+func (d *Daemon) waitForPolicyAt(ctx context.Context, srcAddr string, rev uint64) error {
+	host, _, err := net.SplitHostPort(srcAddr)
+	if err != nil {
+		host = srcAddr
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		var ep *endpoint.Endpoint
+		if ip.To4() != nil {
+			ep = endpointmanager.LookupIPv4(ip.String())
+		} else {
+			ep = endpointmanager.LookupIPv6(ip.String())
+		}
+		if ep != nil {
+			select {
+			case <-ep.WaitForPolicyRevision(ctx, rev):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return endpointmanager.WaitForEndpointsAtPolicyRev(ctx, rev)
+}
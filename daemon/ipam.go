@@ -15,15 +15,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cilium/cilium/api/v1/models"
 	ipamapi "github.com/cilium/cilium/api/v1/server/restapi/ipam"
+	"github.com/cilium/cilium/pkg/annotation"
 	"github.com/cilium/cilium/pkg/api"
+	"github.com/cilium/cilium/pkg/endpoint"
+	endpointid "github.com/cilium/cilium/pkg/endpoint/id"
 	"github.com/cilium/cilium/pkg/ipam"
+	"github.com/cilium/cilium/pkg/k8s"
+	"github.com/cilium/cilium/pkg/workloads"
 
 	"github.com/go-openapi/runtime/middleware"
 	"github.com/go-openapi/swag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type postIPAM struct {
@@ -42,11 +51,21 @@ func (h *postIPAM) Handle(params ipamapi.PostIPAMParams) middleware.Responder {
 		Address:        &models.AddressPair{},
 	}
 
-	ipv4, ipv6, err := ipam.AllocateNext(strings.ToLower(swag.StringValue(params.Family)))
+	pool, err := podIPAMPool(swag.StringValue(params.Pod))
 	if err != nil {
 		return api.Error(ipamapi.PostIPAMFailureCode, err)
 	}
 
+	ipv4, ipv6, err := ipam.AllocateNextFromPool(pool, strings.ToLower(swag.StringValue(params.Family)), swag.StringValue(params.Owner))
+	if err != nil {
+		return api.Error(ipamapi.PostIPAMFailureCode, err)
+	}
+
+	resp.Pool = pool
+	for _, route := range ipam.GetPoolRoutes(pool) {
+		resp.Routes = append(resp.Routes, route.String())
+	}
+
 	if ipv4 != nil {
 		resp.Address.IPV4 = ipv4.String()
 	}
@@ -58,6 +77,27 @@ func (h *postIPAM) Handle(params ipamapi.PostIPAMParams) middleware.Responder {
 	return ipamapi.NewPostIPAMCreated().WithPayload(resp)
 }
 
+// podIPAMPool returns the IPAM pool selected by the pool annotation of the pod
+// given as namespace/name. The default pool is used if pod is empty, the pod
+// is not annotated or Kubernetes is disabled.
+func podIPAMPool(pod string) (string, error) {
+	if pod == "" || !k8s.IsEnabled() {
+		return "", nil
+	}
+
+	vals := strings.SplitN(pod, "/", 2)
+	if len(vals) != 2 {
+		return "", fmt.Errorf("invalid pod %q: expecting \"<namespace>/<name>\"", pod)
+	}
+
+	p, err := k8s.Client().CoreV1().Pods(vals[0]).Get(vals[1], metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve pod %s: %s", pod, err)
+	}
+
+	return p.GetAnnotations()[annotation.IPAMPool], nil
+}
+
 type postIPAMIP struct{}
 
 // NewPostIPAMIPHandler creates a new postIPAM from the daemon.
@@ -67,7 +107,7 @@ func NewPostIPAMIPHandler(d *Daemon) ipamapi.PostIPAMIPHandler {
 
 // Handle incoming requests address allocation requests for the daemon.
 func (h *postIPAMIP) Handle(params ipamapi.PostIPAMIPParams) middleware.Responder {
-	if err := ipam.AllocateIPString(params.IP); err != nil {
+	if err := ipam.AllocateIPString(params.IP, swag.StringValue(params.Owner)); err != nil {
 		return api.Error(ipamapi.PostIPAMIPFailureCode, err)
 	}
 
@@ -93,8 +133,46 @@ func (h *deleteIPAMIP) Handle(params ipamapi.DeleteIPAMIPParams) middleware.Resp
 // reserved IPv4 and IPv6 addresses.
 func (d *Daemon) DumpIPAM() *models.IPAMStatus {
 	allocv4, allocv6 := ipam.Dump()
+	allocations, leaked := ipam.DumpAllocations()
 	return &models.IPAMStatus{
-		IPV4: allocv4,
-		IPV6: allocv6,
+		IPV4:        allocv4,
+		IPV6:        allocv6,
+		Allocations: allocations,
+		Leaked:      leaked,
 	}
 }
+
+// endpointIPAMOwner returns the owner of the IPAM allocations of an endpoint
+func endpointIPAMOwner(ep *endpoint.Endpoint) string {
+	if ep.ContainerID != "" {
+		return ep.ContainerID
+	}
+	return endpointid.NewCiliumID(int64(ep.ID))
+}
+
+// reconcileIPAM reconciles the IPAM allocations restored from the previous
+// run which have not been claimed by a restored endpoint with the workloads
+// running in the container runtime. Allocations made by the CNI plugin for a
+// container which has not become an endpoint yet are kept, all others are
+// marked as leaked.
+func (d *Daemon) reconcileIPAM() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ids, err := workloads.RunningWorkloadIDs(ctx)
+	if err != nil {
+		log.WithError(err).Warning("Unable to list running workloads, keeping all restored IPAM allocations")
+		ipam.ReconcileRestored(nil)
+		return
+	}
+
+	running := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		running[id] = struct{}{}
+	}
+
+	ipam.ReconcileRestored(func(owner string) bool {
+		_, ok := running[owner]
+		return ok
+	})
+}
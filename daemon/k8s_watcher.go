@@ -433,6 +433,17 @@ func (d *Daemon) EnableK8sWatcher(reSyncPeriod time.Duration) error {
 		d.k8sAPIGroups.addAPI(k8sAPIGroupNetworkingV1Core)
 	}
 
+	// Node ports are programmed on the node addresses known when the
+	// services are received, follow later changes of the addresses.
+	d.loadBalancer.K8sMU.Lock()
+	d.loadBalancer.K8sNodeAddresses = node.GetLocalAddresses()
+	d.loadBalancer.K8sMU.Unlock()
+	k8sCM.UpdateController("k8s-svc-node-addresses",
+		controller.ControllerParams{
+			DoFunc:      d.syncK8sSVCNodeAddresses,
+			RunInterval: time.Minute,
+		})
+
 	svcController := k8sUtils.ControllerFactory(
 		k8s.Client().CoreV1().RESTClient(),
 		&v1.Service{},
@@ -806,9 +817,9 @@ func parseSvcV1(svc *v1.Service) *loadbalancer.K8sServiceInfo {
 		headless = true
 	}
 	newSI := loadbalancer.NewK8sServiceInfo(clusterIP, headless, svc.Labels, svc.Spec.Selector)
+	k8s.ParseServiceExposure(svc, newSI)
+	k8s.ParseServiceSessionAffinity(svc, newSI)
 
-	// FIXME: Add support for
-	//  - NodePort
 	for _, port := range svc.Spec.Ports {
 		p, err := loadbalancer.NewFEPort(loadbalancer.L4Type(port.Protocol), uint16(port.Port))
 		if err != nil {
@@ -840,6 +851,8 @@ func (d *Daemon) addK8sServiceV1(svc *v1.Service) error {
 		if oldSI.Equals(newSI) {
 			return nil
 		}
+		nodeAddrs := d.loadBalancer.K8sNodeAddresses
+		d.delStaleK8sSVCFrontends(svcns, oldSI, newSI, nodeAddrs, nodeAddrs)
 	}
 
 	d.loadBalancer.K8sServices[svcns] = newSI
@@ -1191,9 +1204,113 @@ func (d *Daemon) delK8sSVCs(svc loadbalancer.K8sServiceNamespace, svcInfo *loadb
 			scopedLog.Debugf("# cilium lb delete-rev-nat %d", svcPort.ID)
 		}
 	}
+
+	for fePortName := range svcInfo.Ports {
+		for _, fe := range svcInfo.ExternalFrontends(fePortName, d.loadBalancer.K8sNodeAddresses) {
+			if err := d.delK8sSVCFrontend(fe); err != nil {
+				scopedLog.WithError(err).WithField(logfields.Object, logfields.Repr(fe)).
+					Warn("Error deleting service frontend")
+			}
+		}
+	}
 	return nil
 }
 
+// delStaleK8sSVCFrontends removes the node port and external IP frontends of
+// oldSvcInfo on oldNodeAddrs which are no longer part of newSvcInfo on
+// newNodeAddrs from the BPF maps.
+func (d *Daemon) delStaleK8sSVCFrontends(svc loadbalancer.K8sServiceNamespace, oldSvcInfo, newSvcInfo *loadbalancer.K8sServiceInfo, oldNodeAddrs, newNodeAddrs []net.IP) {
+	if oldSvcInfo.IsHeadless {
+		return
+	}
+
+	newFrontends := map[string]struct{}{}
+	for fePortName := range newSvcInfo.Ports {
+		for _, fe := range newSvcInfo.ExternalFrontends(fePortName, newNodeAddrs) {
+			newFrontends[fe.StringWithProtocol()] = struct{}{}
+		}
+	}
+
+	for fePortName := range oldSvcInfo.Ports {
+		for _, fe := range oldSvcInfo.ExternalFrontends(fePortName, oldNodeAddrs) {
+			if _, ok := newFrontends[fe.StringWithProtocol()]; ok {
+				continue
+			}
+			if err := d.delK8sSVCFrontend(fe); err != nil {
+				log.WithError(err).WithFields(logrus.Fields{
+					logfields.K8sSvcName:   svc.ServiceName,
+					logfields.K8sNamespace: svc.Namespace,
+					logfields.Object:       logfields.Repr(fe),
+				}).Warn("Error deleting stale service frontend")
+			}
+		}
+	}
+}
+
+// syncK8sSVCNodeAddresses moves the node port frontends of all k8s services
+// to the current local node addresses if they changed since the frontends
+// were programmed.
+func (d *Daemon) syncK8sSVCNodeAddresses() error {
+	nodeAddrs := node.GetLocalAddresses()
+
+	d.loadBalancer.K8sMU.Lock()
+	defer d.loadBalancer.K8sMU.Unlock()
+
+	oldNodeAddrs := d.loadBalancer.K8sNodeAddresses
+	if reflect.DeepEqual(oldNodeAddrs, nodeAddrs) {
+		return nil
+	}
+
+	log.WithFields(logrus.Fields{
+		"oldAddresses": oldNodeAddrs,
+		"newAddresses": nodeAddrs,
+	}).Info("Local node addresses changed, updating node port frontends of services")
+
+	for svcns, svcInfo := range d.loadBalancer.K8sServices {
+		d.delStaleK8sSVCFrontends(svcns, svcInfo, svcInfo, oldNodeAddrs, nodeAddrs)
+	}
+	d.loadBalancer.K8sNodeAddresses = nodeAddrs
+
+	for svcns := range d.loadBalancer.K8sServices {
+		svcns := svcns
+		// Errors are logged by syncLB, continue with the other services
+		d.syncLB(&svcns, nil, nil)
+	}
+	return nil
+}
+
+// addK8sSVCFrontend programs the given node port or external IP frontend of a
+// k8s service with the given backends into the BPF maps.
+func (d *Daemon) addK8sSVCFrontend(fe *loadbalancer.L3n4Addr, bes []loadbalancer.LBBackEnd, affinity loadbalancer.SessionAffinity) error {
+	feAddrID, err := service.AcquireID(*fe, 0)
+	if err != nil {
+		return fmt.Errorf("unable to get a new service ID: %s", err)
+	}
+	if _, err := d.svcAdd(*feAddrID, bes, affinity, true); err != nil {
+		return fmt.Errorf("unable to insert service in LB map: %s", err)
+	}
+	return nil
+}
+
+// delK8sSVCFrontend removes the given node port or external IP frontend of a
+// k8s service from the BPF maps and releases its service ID.
+func (d *Daemon) delK8sSVCFrontend(fe *loadbalancer.L3n4Addr) error {
+	d.loadBalancer.BPFMapMU.RLock()
+	svc, ok := d.loadBalancer.SVCMap[fe.SHA256Sum()]
+	d.loadBalancer.BPFMapMU.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if err := d.svcDeleteByFrontend(fe); err != nil {
+		return err
+	}
+	if err := service.DeleteID(uint32(svc.FE.ID)); err != nil {
+		log.WithError(err).WithField(logfields.ServiceID, svc.FE.ID).Warn("Error while cleaning service ID")
+	}
+	return d.RevNATDelete(svc.FE.ID)
+}
+
 func (d *Daemon) addK8sSVCs(svc loadbalancer.K8sServiceNamespace, svcInfo *loadbalancer.K8sServiceInfo, se *loadbalancer.K8sServiceEndpoint) error {
 	// If east-west load balancing is disabled, we should not sync(add or delete)
 	// K8s service to a cilium service.
@@ -1275,9 +1392,18 @@ func (d *Daemon) addK8sSVCs(svc loadbalancer.K8sServiceNamespace, svcInfo *loadb
 			}).Error("Error while creating a New L3n4AddrID. Ignoring service...")
 			continue
 		}
-		if _, err := d.svcAdd(*fe, besValues, true); err != nil {
+		if _, err := d.svcAdd(*fe, besValues, svcInfo.SessionAffinity, true); err != nil {
 			scopedLog.WithError(err).Error("Error while inserting service in LB map")
 		}
+
+		// The external frontends are only load balanced for connections
+		// from local endpoints, external clients still rely on kube-proxy.
+		for _, extFE := range svcInfo.ExternalFrontends(fePortName, d.loadBalancer.K8sNodeAddresses) {
+			if err := d.addK8sSVCFrontend(extFE, besValues, svcInfo.SessionAffinity); err != nil {
+				scopedLog.WithError(err).WithField(logfields.Object, logfields.Repr(extFE)).
+					Error("Error while inserting service frontend in LB map")
+			}
+		}
 	}
 	return nil
 }
@@ -16,9 +16,11 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/cilium/cilium/api/v1/server/restapi/service"
 	"github.com/cilium/cilium/pkg/api"
+	"github.com/cilium/cilium/pkg/endpointmanager"
 	"github.com/cilium/cilium/pkg/loadbalancer"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/maps/lbmap"
@@ -31,11 +33,12 @@ import (
 
 // addSVC2BPFMap adds the given bpf service to the bpf maps. If addRevNAT is set, adds the
 // RevNAT value (feCilium.L3n4Addr) to the lb's RevNAT map for the given feCilium.ID.
+// A non-zero affinityTimeoutSec enables session affinity for the service.
 func (d *Daemon) addSVC2BPFMap(feCilium loadbalancer.L3n4AddrID, feBPF lbmap.ServiceKey,
-	besBPF []lbmap.ServiceValue, addRevNAT bool) error {
+	besBPF []lbmap.ServiceValue, affinityTimeoutSec uint32, addRevNAT bool) error {
 	log.WithField(logfields.ServiceName, feCilium.String()).Debug("adding service to BPF maps")
 
-	if err := lbmap.UpdateService(feBPF, besBPF, addRevNAT, int(feCilium.ID)); err != nil {
+	if err := lbmap.UpdateService(feBPF, besBPF, addRevNAT, int(feCilium.ID), affinityTimeoutSec); err != nil {
 		if addRevNAT {
 			delete(d.loadBalancer.RevNATMap, feCilium.ID)
 		}
@@ -54,7 +57,7 @@ func (d *Daemon) addSVC2BPFMap(feCilium loadbalancer.L3n4AddrID, feBPF lbmap.Ser
 // returned to the caller.
 //
 // Returns true if service was created.
-func (d *Daemon) SVCAdd(feL3n4Addr loadbalancer.L3n4AddrID, be []loadbalancer.LBBackEnd, addRevNAT bool) (bool, error) {
+func (d *Daemon) SVCAdd(feL3n4Addr loadbalancer.L3n4AddrID, be []loadbalancer.LBBackEnd, affinity loadbalancer.SessionAffinity, addRevNAT bool) (bool, error) {
 	log.WithField(logfields.ServiceID, feL3n4Addr.String()).Debug("adding service")
 	if feL3n4Addr.ID == 0 {
 		return false, fmt.Errorf("invalid service ID 0")
@@ -82,22 +85,39 @@ func (d *Daemon) SVCAdd(feL3n4Addr loadbalancer.L3n4AddrID, be []loadbalancer.LB
 		return false, fmt.Errorf("service ID %d is already registered to L3n4Addr %s, please choose a different ID", feL3n4Addr.ID, feAddr.String())
 	}
 
-	return d.svcAdd(feL3n4Addr, be, addRevNAT)
+	return d.svcAdd(feL3n4Addr, be, affinity, addRevNAT)
 }
 
-// svcAdd adds a service from the given feL3n4Addr (frontend) and LBBackEnd (backends).
+// svcAdd adds a service from the given feL3n4Addr (frontend) and LBBackEnd (backends)
+// with the given session affinity.
 // If addRevNAT is set, the RevNAT entry is also created for this particular service.
 // If any of the backend addresses set in bes have a different L3 address type than the
 // one set in fe, it returns an error without modifying the bpf LB map. If any backend
 // entry fails while updating the LB map, the frontend won't be inserted in the LB map
 // therefore there won't be any traffic going to the given backends.
+// Backends of an existing service which are not part of bes are kept as
+// terminating backends until their connections are drained.
 // All of the backends added will be DeepCopied to the internal load balancer map.
-func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.LBBackEnd, addRevNAT bool) (bool, error) {
+func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.LBBackEnd, affinity loadbalancer.SessionAffinity, addRevNAT bool) (bool, error) {
 	log.WithFields(logrus.Fields{
 		logfields.ServiceID: feL3n4Addr.String(),
 		logfields.Object:    logfields.Repr(bes),
 	}).Debug("adding service")
 
+	d.loadBalancer.BPFMapMU.Lock()
+	defer d.loadBalancer.BPFMapMU.Unlock()
+
+	if oldSvc, ok := d.loadBalancer.SVCMap[feL3n4Addr.L3n4Addr.SHA256Sum()]; ok {
+		bes = loadbalancer.AddTerminatingBackends(oldSvc.BES, bes, time.Now())
+	}
+
+	return d.svcAddLocked(feL3n4Addr, bes, affinity, addRevNAT)
+}
+
+// svcAddLocked adds a service with exactly the given backends, see svcAdd().
+//
+// Must be called with d.loadBalancer.BPFMapMU locked.
+func (d *Daemon) svcAddLocked(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.LBBackEnd, affinity loadbalancer.SessionAffinity, addRevNAT bool) (bool, error) {
 	// Move the slice to the loadbalancer map which has a mutex. If we don't
 	// copy the slice we might risk changing memory that should be locked.
 	beCpy := []loadbalancer.LBBackEnd{}
@@ -106,9 +126,10 @@ func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.L
 	}
 
 	svc := loadbalancer.LBSVC{
-		FE:     feL3n4Addr,
-		BES:    beCpy,
-		Sha256: feL3n4Addr.L3n4Addr.SHA256Sum(),
+		FE:              feL3n4Addr,
+		BES:             beCpy,
+		Sha256:          feL3n4Addr.L3n4Addr.SHA256Sum(),
+		SessionAffinity: affinity,
 	}
 
 	fe, besValues, err := lbmap.LBSVC2ServiceKeynValue(svc)
@@ -117,10 +138,7 @@ func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.L
 		return false, err
 	}
 
-	d.loadBalancer.BPFMapMU.Lock()
-	defer d.loadBalancer.BPFMapMU.Unlock()
-
-	err = d.addSVC2BPFMap(feL3n4Addr, fe, besValues, addRevNAT)
+	err = d.addSVC2BPFMap(feL3n4Addr, fe, besValues, affinity.TimeoutSec(), addRevNAT)
 	if err != nil {
 		return false, err
 	}
@@ -128,6 +146,100 @@ func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.L
 	return d.loadBalancer.AddService(svc), nil
 }
 
+// drainTerminatingBackends removes the terminating backends of all services
+// which are no longer referenced by any connection tracking entry or which
+// have been terminating for longer than the configured drain timeout.
+func (d *Daemon) drainTerminatingBackends() error {
+	// Scanning the CT maps is expensive, so it is done without holding
+	// BPFMapMU. The slaves of the terminating backends are collected
+	// beforehand and the services are re-validated afterwards.
+	type drainCandidate struct {
+		sha256 string
+		fe     loadbalancer.L3n4Addr
+		slaves map[string][]int
+		inUse  map[uint16]struct{}
+	}
+	candidates := []*drainCandidate{}
+
+	d.loadBalancer.BPFMapMU.RLock()
+	for sha256, svc := range d.loadBalancer.SVCMap {
+		c := &drainCandidate{
+			sha256: sha256,
+			fe:     *svc.FE.L3n4Addr.DeepCopy(),
+			slaves: map[string][]int{},
+		}
+		fe, besValues, err := lbmap.LBSVC2ServiceKeynValue(svc)
+		if err != nil {
+			continue
+		}
+		for i, be := range svc.BES {
+			if be.IsTerminating() {
+				c.slaves[be.L3n4Addr.String()] = lbmap.GetBackendSlaves(fe, besValues[i])
+			}
+		}
+		if len(c.slaves) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	d.loadBalancer.BPFMapMU.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	for _, c := range candidates {
+		c.inUse = endpointmanager.ServiceSlavesInUse(c.fe.IP, c.fe.Port)
+	}
+
+	d.loadBalancer.BPFMapMU.Lock()
+	defer d.loadBalancer.BPFMapMU.Unlock()
+
+	for _, c := range candidates {
+		svc, ok := d.loadBalancer.SVCMap[c.sha256]
+		if !ok {
+			continue
+		}
+
+		bes := make([]loadbalancer.LBBackEnd, 0, len(svc.BES))
+		for _, be := range svc.BES {
+			slaves, ok := c.slaves[be.L3n4Addr.String()]
+			if !be.IsTerminating() || !ok {
+				bes = append(bes, be)
+				continue
+			}
+
+			drained := true
+			for _, slave := range slaves {
+				if _, ok := c.inUse[uint16(slave)]; ok {
+					drained = false
+					break
+				}
+			}
+			if !drained && time.Since(be.TerminatingSince) < option.Config.LBBackendDrainTimeout {
+				bes = append(bes, be)
+				continue
+			}
+
+			log.WithFields(logrus.Fields{
+				logfields.ServiceName: svc.FE.String(),
+				"backend":             be.L3n4Addr.String(),
+				"drained":             drained,
+			}).Debug("removing terminating backend from service")
+		}
+
+		if len(bes) == len(svc.BES) {
+			continue
+		}
+
+		if _, err := d.svcAddLocked(svc.FE, bes, svc.SessionAffinity, false); err != nil {
+			log.WithError(err).WithField(logfields.ServiceName, svc.FE.String()).
+				Warn("Unable to remove terminating backends from service")
+		}
+	}
+
+	return nil
+}
+
 type putServiceID struct {
 	d *Daemon
 }
@@ -159,15 +271,20 @@ func (h *putServiceID) Handle(params PutServiceIDParams) middleware.Responder {
 	}
 
 	revnat := false
+	affinity := loadbalancer.SessionAffinity{}
 	if params.Config.Flags != nil {
 		revnat = params.Config.Flags.DirectServerReturn
+		if params.Config.Flags.SessionAffinity {
+			affinity.Enabled = true
+			affinity.Timeout = uint32(params.Config.Flags.SessionAffinityTimeout)
+		}
 	}
 
 	// FIXME
 	// Add flag to indicate whether service should be registered in
 	// global key value store
 
-	if created, err := h.d.SVCAdd(frontend, backends, revnat); err != nil {
+	if created, err := h.d.SVCAdd(frontend, backends, affinity, revnat); err != nil {
 		return api.Error(PutServiceIDFailureCode, err)
 	} else if created {
 		return NewPutServiceIDCreated()
@@ -484,7 +601,7 @@ func (d *Daemon) SyncLBMap() error {
 				" This entry will be removed from the bpf's LB map.", svc.FE.String(), svc.BES, err)
 		}
 
-		err = d.addSVC2BPFMap(svc.FE, fe, besValues, false)
+		err = d.addSVC2BPFMap(svc.FE, fe, besValues, svc.SessionAffinity.TimeoutSec(), false)
 		if err != nil {
 			return fmt.Errorf("Unable to add service FE: %s: %s."+
 				" This entry will be removed from the bpf's LB map.", svc.FE.String(), err)
@@ -628,6 +745,14 @@ func (d *Daemon) syncLBMapsWithK8s() error {
 			log.WithFields(logrus.Fields{logfields.ServiceID: frontendPort.ID,
 				logfields.L3n4Addr: address}).Debug("adding service to set of services to check against service map BPF contents")
 		}
+
+		// Node port and external IP frontends
+		for frontendPortName := range k8sServiceInfo.Ports {
+			for _, address := range k8sServiceInfo.ExternalFrontends(frontendPortName, d.loadBalancer.K8sNodeAddresses) {
+				k8sServicesFrontendAddresses[address.StringWithProtocol()] = struct{}{}
+				log.WithField(logfields.L3n4Addr, address).Debug("adding service frontend to set of services to check against service map BPF contents")
+			}
+		}
 	}
 
 	log.Debugf("dumping BPF service maps to userspace")
@@ -55,6 +55,7 @@ import (
 	"github.com/cilium/cilium/pkg/pidfile"
 	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/pprof"
+	"github.com/cilium/cilium/pkg/proxy/logger"
 	"github.com/cilium/cilium/pkg/service"
 	"github.com/cilium/cilium/pkg/version"
 	"github.com/cilium/cilium/pkg/versioncheck"
@@ -109,7 +110,6 @@ var (
 	loggers               []string
 	logstashAddr          string
 	logstashProbeTimer    uint32
-	masquerade            bool
 	nat46prefix           string
 	prometheusServeAddr   string
 	socketPath            string
@@ -123,6 +123,8 @@ var (
 	v6ServicePrefix       string
 	validLabels           []string
 	toFQDNsMinTTL         int
+	toFQDNsProxyPort      int
+	toFQDNsEnablePoller   bool
 )
 
 var (
@@ -382,6 +384,10 @@ func init() {
 		false, "Disable east-west K8s load balancing by cilium")
 	flags.StringVarP(&dockerEndpoint,
 		"docker", "e", workloads.GetRuntimeDefaultOpt(workloads.Docker, "endpoint"), "Path to docker runtime socket (DEPRECATED: use container-runtime-endpoint instead)")
+	flags.BoolVar(&option.Config.EnableIPSec,
+		option.EnableIPSecName, false, "Enable IPsec encryption of the traffic between nodes")
+	flags.BoolVar(&option.Config.EnableLBMaglev,
+		option.EnableLBMaglevName, false, "Enable Maglev consistent hashing for the selection of service backends")
 	flags.String("enable-policy", option.DefaultEnforcement, "Enable policy enforcement")
 	flags.BoolVar(&enableTracing,
 		"enable-tracing", false, "Enable tracing while determining policy (debugging)")
@@ -394,6 +400,8 @@ func init() {
 	viper.BindEnv("disable-envoy-version-check", "CILIUM_DISABLE_ENVOY_BUILD")
 	flags.Var(option.NewNamedMapOptions("fixed-identity-mapping", &fixedIdentity, fixedIdentityValidator),
 		"fixed-identity-mapping", "Key-value for the fixed identity mapping which allows to use reserved label for fixed identities")
+	flags.StringVar(&option.Config.IPSecKeyFile,
+		option.IPSecKeyFileName, "", "Path to the file holding the IPsec keys, one key per line, the first key is used to encrypt")
 	flags.IntVar(&v4ClusterCidrMaskSize,
 		"ipv4-cluster-cidr-mask-size", 8, "Mask size for the cluster wide CIDR")
 	flags.StringVar(&v4Prefix,
@@ -429,6 +437,8 @@ func init() {
 		"labels", []string{}, "List of label prefixes used to determine identity of an endpoint")
 	flags.StringVar(&option.Config.LBInterface,
 		"lb", "", "Enables load balancer mode where load balancer bpf program is attached to the given interface")
+	flags.DurationVar(&option.Config.LBBackendDrainTimeout,
+		option.LBBackendDrainTimeoutName, defaults.LBBackendDrainTimeout, "Maximum time a removed service backend keeps serving established connections")
 	flags.StringVar(&option.Config.LibDir,
 		"lib-dir", defaults.LibraryPath, "Directory path to store runtime build environment")
 	flags.StringSliceVar(&loggers,
@@ -444,7 +454,7 @@ func init() {
 	flags.Bool(option.LogSystemLoadConfigName, false, "Enable periodic logging of system load")
 	flags.StringVar(&nat46prefix,
 		"nat46-range", defaults.DefaultNAT46Prefix, "IPv6 prefix to map IPv4 addresses to")
-	flags.BoolVar(&masquerade,
+	flags.BoolVar(&option.Config.Masquerade,
 		"masquerade", true, "Masquerade packets from endpoints leaving the host")
 	flags.String(option.MonitorAggregationName, "None",
 		"Level of monitor aggregation for traces from the datapath")
@@ -471,6 +481,9 @@ func init() {
 		"state-dir", defaults.RuntimePath, "Directory path to store runtime state")
 	flags.StringP(option.TunnelName, "t", option.TunnelVXLAN, fmt.Sprintf("Tunnel mode {%s}", option.GetTunnelModes()))
 	viper.BindEnv(option.TunnelName, option.TunnelNameEnv)
+	flags.String(option.IPAMName, option.IPAMHostScope, fmt.Sprintf("IPAM mode {%s}", option.GetIPAMModes()))
+	flags.Var(option.NewNamedMapOptions(option.IPAMPoolName, &option.Config.IPAMPools, ipam.ValidatePoolOption),
+		option.IPAMPoolName, `Named IPAM pool selectable with the "ipam.cilium.io/pool" pod annotation, e.g. external=cidr=192.0.2.0/26,route=198.51.100.0/24,masquerade=false`)
 	flags.IntVar(&tracePayloadLen,
 		"trace-payloadlen", 128, "Length of payload to capture when tracing")
 	flags.Bool(
@@ -490,6 +503,8 @@ func init() {
 		"prometheus-serve-addr", "", "IP:Port on which to serve prometheus metrics (pass \":Port\" to bind on all interfaces, \"\" is off)")
 	viper.BindEnv("prometheus-serve-addr", "CILIUM_PROMETHEUS_SERVE_ADDR")
 	viper.BindEnv("prometheus-serve-addr-deprecated", "PROMETHEUS_SERVE_ADDR")
+	flags.StringSliceVar(&option.Config.ProxyMetricsLabels,
+		option.ProxyMetricsLabelsName, metrics.ProxyL7Labels, "Labels of the L7 proxy request metrics to keep, leaving out labels reduces the cardinality of the metrics")
 
 	flags.Int(option.CTMapEntriesGlobalTCPName, option.CTMapEntriesGlobalTCPDefault, "Maximum number of entries in TCP CT table")
 	viper.BindEnv(option.CTMapEntriesGlobalTCPName, option.CTMapEntriesGlobalTCPNameEnv)
@@ -501,7 +516,13 @@ func init() {
 	flags.MarkHidden("cmdref")
 
 	flags.IntVar(&toFQDNsMinTTL,
-		"tofqdns-min-ttl", defaults.ToFQDNsMinTTL, "The minimum time, in seconds, to use DNS data for toFQDNs policies.")
+		"tofqdns-min-ttl", 0, fmt.Sprintf("The minimum time, in seconds, to use DNS data for toFQDNs policies. (default %d when --tofqdns-enable-poller, %d otherwise)", defaults.ToFQDNsMinTTL, defaults.ToFQDNsMinTTLProxy))
+
+	flags.IntVar(&toFQDNsProxyPort,
+		"tofqdns-proxy-port", defaults.ToFQDNsProxyPortAutoAllocate, "Global port on which the in-agent DNS proxy should listen. Default 0 allocates a port from the proxy port range.")
+
+	flags.BoolVar(&toFQDNsEnablePoller,
+		"tofqdns-enable-poller", defaults.ToFQDNsEnablePoller, "Enable proactive polling of DNS names in toFQDNs.matchName rules.")
 
 	viper.BindPFlags(flags)
 }
@@ -674,6 +695,10 @@ func initEnv(cmd *cobra.Command) {
 			option.ModePreFilterNative, option.ModePreFilterGeneric)
 	}
 
+	if option.Config.EnableIPSec && option.Config.IPSecKeyFile == "" {
+		log.Fatalf("--%s requires --%s", option.EnableIPSecName, option.IPSecKeyFileName)
+	}
+
 	scopedLog = log.WithField(logfields.Path, socketPath)
 	socketDir := path.Dir(socketPath)
 	if err := os.MkdirAll(socketDir, defaults.RuntimePathRights); err != nil {
@@ -911,7 +936,7 @@ func runDaemon() {
 
 	// Allocate health endpoint IPs after restoring state
 	log.Info("Building health endpoint")
-	health4, health6, err := ipam.AllocateNext("")
+	health4, health6, err := ipam.AllocateNext("", ipam.OwnerHealth)
 	if err != nil {
 		log.WithError(err).Fatal("IPAM allocation failed. For more detail, see https://cilium.link/ipam-range-full")
 	}
@@ -960,6 +985,9 @@ func runDaemon() {
 		promAddr = viper.GetString("prometheus-serve-addr-deprecated")
 	}
 	if promAddr != "" {
+		if err := logger.SetMetricsLabels(option.Config.ProxyMetricsLabels); err != nil {
+			log.WithError(err).Fatal("Invalid L7 proxy metrics labels")
+		}
 		log.Infof("Serving prometheus metrics on %s", promAddr)
 		if err := metrics.Enable(promAddr); err != nil {
 			log.WithError(err).Fatal("Error while starting metrics")
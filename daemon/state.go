@@ -269,7 +269,19 @@ func (d *Daemon) regenerateRestoredEndpoints(state *endpointRestoreState) {
 }
 
 func (d *Daemon) allocateIPsLocked(ep *endpoint.Endpoint) error {
-	err := ipam.AllocateIP(ep.IPv6.IP())
+	owner := endpointIPAMOwner(ep)
+
+	// The addresses of chained endpoints are allocated by the plugin
+	// Cilium is chained to and are not part of the IPAM ranges, only the
+	// endpoint ID allocated by the agent is reserved again
+	if ep.Chained {
+		if err := ipam.ReserveEndpointID(ep.ID, owner); err != nil {
+			return fmt.Errorf("unable to reserve endpoint ID: %s", err)
+		}
+		return nil
+	}
+
+	err := ipam.AllocateIP(ep.IPv6.IP(), owner)
 	if err != nil {
 		// TODO if allocation failed reallocate a new IP address and setup veth
 		// pair accordingly
@@ -284,7 +296,7 @@ func (d *Daemon) allocateIPsLocked(ep *endpoint.Endpoint) error {
 
 	if !option.Config.IPv4Disabled {
 		if ep.IPv4 != nil {
-			if err = ipam.AllocateIP(ep.IPv4.IP()); err != nil {
+			if err = ipam.AllocateIP(ep.IPv4.IP(), owner); err != nil {
 				return fmt.Errorf("unable to reallocate IPv4 address: %s", err)
 			}
 		}
@@ -26,6 +26,7 @@ import (
 	"github.com/cilium/cilium/pkg/completion"
 	e "github.com/cilium/cilium/pkg/endpoint"
 	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/ipam"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/mac"
@@ -193,3 +194,44 @@ func (ds *DaemonSuite) TestReadEPsFromDirNames(c *C) {
 		}
 	}
 }
+
+func (ds *DaemonSuite) TestRestoreChainedEndpoint(c *C) {
+	tmpDir, err := ioutil.TempDir("", "cilium-tests")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(tmpDir)
+
+	oldStateDir := option.Config.StateDir
+	option.Config.StateDir = tmpDir
+	defer func() {
+		option.Config.StateDir = oldStateDir
+	}()
+
+	// The addresses of chained endpoints are allocated by another plugin
+	// and are not part of the IPAM ranges of the agent
+	ep := endpointCreator(260, identity.NumericIdentity(256))
+	ep.IPv4 = addressing.DeriveCiliumIPv4(net.ParseIP("198.51.100.10"))
+	ep.IPv6 = addressing.DeriveCiliumIPv6(net.ParseIP("2001:db8::10"))
+	ep.Chained = true
+
+	epsWanted := []*e.Endpoint{ep}
+	epsNames, err := ds.generateEPs(tmpDir, epsWanted, map[uint16]*e.Endpoint{ep.ID: ep})
+	c.Assert(err, IsNil)
+
+	eps := readEPsFromDirNames(tmpDir, epsNames)
+	c.Assert(len(eps), Equals, 1)
+	restored := eps[ep.ID]
+	c.Assert(restored, Not(IsNil))
+	c.Assert(restored.Chained, Equals, true)
+
+	restored.UnconditionalLock()
+	defer restored.Unlock()
+	c.Assert(ds.d.allocateIPsLocked(restored), IsNil)
+
+	// The endpoint ID allocated by the agent is reserved again
+	c.Assert(ipam.ReserveEndpointID(ep.ID, "other"), Not(IsNil))
+	ipam.ReleaseEndpointID(ep.ID)
+
+	// Without the chained flag, the addresses cannot be allocated again
+	restored.Chained = false
+	c.Assert(ds.d.allocateIPsLocked(restored), Not(IsNil))
+}
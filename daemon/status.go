@@ -21,6 +21,7 @@ import (
 	"github.com/cilium/cilium/api/v1/models"
 	. "github.com/cilium/cilium/api/v1/server/restapi/daemon"
 	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/datapath/ipsec"
 	"github.com/cilium/cilium/pkg/k8s"
 	"github.com/cilium/cilium/pkg/kvstore"
 	"github.com/cilium/cilium/pkg/node"
@@ -170,5 +171,11 @@ func (d *Daemon) getStatus() models.StatusResponse {
 		sr.Proxy = d.l7Proxy.GetStatusModel()
 	}
 
+	if option.Config.EnableIPSec {
+		sr.Encryption = ipsec.GetStatusModel()
+	} else {
+		sr.Encryption = &models.EncryptionStatus{Mode: models.EncryptionStatusModeDisabled}
+	}
+
 	return sr
 }
@@ -135,15 +135,18 @@ void AccessLog::Entry::InitFromRequest(
 void AccessLog::Entry::UpdateFromResponse(
     const Http::HeaderMap &headers, const RequestInfo::RequestInfo &info) {
   auto time = info.startTime();
+  ::cilium::HttpLogEntry* http_entry = entry.mutable_http();
+
   if (info.lastUpstreamRxByteReceived()) {
     time += info.lastUpstreamRxByteReceived().value();
+    http_entry->set_duration(std::chrono::duration_cast<std::chrono::nanoseconds>(
+                                 info.lastUpstreamRxByteReceived().value())
+                                 .count());
   }
   entry.set_timestamp(std::chrono::duration_cast<std::chrono::nanoseconds>(
                           time.time_since_epoch())
                           .count());
 
-  ::cilium::HttpLogEntry* http_entry = entry.mutable_http();
-
   if (info.responseCode()) {
     http_entry->set_status(info.responseCode().value());
   } else {
@@ -177,6 +177,10 @@ resources:
         - headers: [ { name: ':authority', exact_match: 'allowedHOST' } ]
         - headers: [ { name: ':authority', regex_match: '.*REGEX.*' } ]
         - headers: [ { name: ':method', exact_match: 'PUT' }, { name: ':path', exact_match: '/public/opinions' } ]
+        - headers: [ { name: ':path', exact_match: '/no-secret' }, { name: 'x-secret', present_match: true, invert_match: true } ]
+        - headers: [ { name: ':path', exact_match: '/not-admin' }, { name: 'x-user', exact_match: 'admin', invert_match: true } ]
+        - headers: [ { name: ':path', exact_match: '/identity' } ]
+          headers_to_add: [ { key: 'x-source-identity', value: '%SOURCE_IDENTITY%' } ]
     - remote_policies: [ 2 ]
       http_rules:
         http_rules:
@@ -772,6 +776,45 @@ TEST_P(CiliumIntegrationTest, AcceptedMethod) {
   Accepted({{":method", "PUT"}, {":path", "/public/opinions"}, {":authority", "host"}});
 }
 
+TEST_P(CiliumIntegrationTest, AcceptedInvertedPresentHeader) {
+  Accepted({{":method", "GET"}, {":path", "/no-secret"}, {":authority", "host"}});
+}
+
+TEST_P(CiliumIntegrationTest, DeniedInvertedPresentHeader) {
+  Denied({{":method", "GET"}, {":path", "/no-secret"}, {":authority", "host"},
+          {"x-secret", "anything"}});
+}
+
+TEST_P(CiliumIntegrationTest, AcceptedInvertedHeaderValue) {
+  Accepted({{":method", "GET"}, {":path", "/not-admin"}, {":authority", "host"},
+            {"x-user", "guest"}});
+}
+
+TEST_P(CiliumIntegrationTest, DeniedInvertedHeaderValue) {
+  Denied({{":method", "GET"}, {":path", "/not-admin"}, {":authority", "host"},
+          {"x-user", "admin"}});
+}
+
+TEST_P(CiliumIntegrationTest, DeniedInvertedHeaderValueAbsent) {
+  // Inverted value matches require the header to be present
+  Denied({{":method", "GET"}, {":path", "/not-admin"}, {":authority", "host"}});
+}
+
+TEST_P(CiliumIntegrationTest, AcceptedAddedHeader) {
+  Accepted({{":method", "GET"}, {":path", "/identity"}, {":authority", "host"},
+            {"x-source-identity", "forged"}});
+  const Http::HeaderEntry* entry = upstream_request_->headers().get(Http::LowerCaseString("x-source-identity"));
+  ASSERT_NE(nullptr, entry);
+  EXPECT_STRNE("forged", entry->value().c_str());
+}
+
+TEST_P(CiliumIntegrationTest, AcceptedForgedAddedHeaderRemoved) {
+  // Allowed by a rule not adding the header, which another rule of the port adds
+  Accepted({{":method", "GET"}, {":path", "/allowed"}, {":authority", "host"},
+            {"x-source-identity", "forged"}});
+  EXPECT_EQ(nullptr, upstream_request_->headers().get(Http::LowerCaseString("x-source-identity")));
+}
+
 TEST_P(CiliumIntegrationTest, L3DeniedPath) {
   Denied({{":method", "GET"}, {":path", "/only-2-allowed"}, {":authority", "host"}});
 }
@@ -9,6 +9,7 @@
 #include "common/buffer/buffer_impl.h"
 #include "common/common/enum_to_int.h"
 #include "common/config/utility.h"
+#include "common/grpc/common.h"
 #include "common/http/header_map_impl.h"
 
 #include "cilium_network_policy.h"
@@ -129,6 +130,7 @@ Http::FilterHeadersStatus AccessFilter::decodeHeaders(Http::HeaderMap& headers,
   const auto& conn = callbacks_->connection();
   bool ingress = false;
   bool allowed = false;
+  bool rate_limited = false;
   if (config_->npmap_ && conn) {
     const auto& options_ = conn->socketOptions();
     if (options_) {
@@ -143,15 +145,17 @@ Http::FilterHeadersStatus AccessFilter::decodeHeaders(Http::HeaderMap& headers,
 	  }
 	  if (ingress) {
 	    allowed = config_->npmap_->Allowed(config_->policy_name_, ingress, option->port_,
-					       option->identity_, headers);
+					       option->identity_, option->identity_, headers,
+					       rate_limited);
 	  } else {
 	    allowed = config_->npmap_->Allowed(config_->policy_name_, ingress, option->port_,
-					       option->destination_identity_, headers);
+					       option->destination_identity_, option->identity_, headers,
+					       rate_limited);
 	  }
 	  ENVOY_LOG(debug, "Cilium L7: {} ({}->{}) policy lookup for endpoint {}: {}",
 		    ingress ? "Ingress" : "Egress",
 		    option->identity_, option->destination_identity_,
-		    config_->policy_name_, allowed ? "ALLOW" : "DENY");
+		    config_->policy_name_, allowed ? "ALLOW" : rate_limited ? "RATE LIMITED" : "DENY");
 	  break;
 	}
       }
@@ -168,12 +172,42 @@ Http::FilterHeadersStatus AccessFilter::decodeHeaders(Http::HeaderMap& headers,
   // Fill in the log entry
   log_entry_.InitFromRequest(config_->policy_name_, ingress, callbacks_->connection(),
                              headers, callbacks_->requestInfo());
+  if (rate_limited) {
+    rate_limited_ = true;
+    config_->stats_.access_rate_limited_.inc();
+
+    if (Grpc::Common::hasGrpcContentType(headers)) {
+      // Return a trailers-only gRPC response with the RESOURCE_EXHAUSTED status
+      Http::HeaderMapPtr response_headers{new Http::HeaderMapImpl{
+	  {Http::Headers::get().Status, std::to_string(enumToInt(Http::Code::OK))},
+	  {Http::Headers::get().ContentType, Http::Headers::get().ContentTypeValues.Grpc},
+	  {Http::Headers::get().GrpcStatus,
+	   std::to_string(enumToInt(Grpc::Status::GrpcStatus::ResourceExhausted))},
+	  {Http::Headers::get().GrpcMessage, "Rate limit exceeded"}}};
+      callbacks_->encodeHeaders(std::move(response_headers), true);
+    } else {
+      // Return a 429 response
+      callbacks_->sendLocalReply(Http::Code::TooManyRequests, "Rate limit exceeded\r\n", nullptr);
+    }
+    return Http::FilterHeadersStatus::StopIteration;
+  }
   if (!allowed) {
     denied_ = true;
     config_->stats_.access_denied_.inc();
 
-    // Return a 403 response
-    callbacks_->sendLocalReply(Http::Code::Forbidden, config_->denied_403_body_, nullptr);
+    if (Grpc::Common::hasGrpcContentType(headers)) {
+      // Return a trailers-only gRPC response with the PERMISSION_DENIED status
+      Http::HeaderMapPtr response_headers{new Http::HeaderMapImpl{
+	  {Http::Headers::get().Status, std::to_string(enumToInt(Http::Code::OK))},
+	  {Http::Headers::get().ContentType, Http::Headers::get().ContentTypeValues.Grpc},
+	  {Http::Headers::get().GrpcStatus,
+	   std::to_string(enumToInt(Grpc::Status::GrpcStatus::PermissionDenied))},
+	  {Http::Headers::get().GrpcMessage, "Access denied"}}};
+      callbacks_->encodeHeaders(std::move(response_headers), true);
+    } else {
+      // Return a 403 response
+      callbacks_->sendLocalReply(Http::Code::Forbidden, config_->denied_403_body_, nullptr);
+    }
     return Http::FilterHeadersStatus::StopIteration;
   }
 
@@ -184,7 +218,8 @@ Http::FilterHeadersStatus AccessFilter::decodeHeaders(Http::HeaderMap& headers,
 Http::FilterHeadersStatus AccessFilter::encodeHeaders(Http::HeaderMap &headers,
                                                       bool) {
   log_entry_.UpdateFromResponse(headers, callbacks_->requestInfo());
-  config_->Log(log_entry_, denied_ ? ::cilium::EntryType::Denied
+  config_->Log(log_entry_, rate_limited_ ? ::cilium::EntryType::RateLimited
+                         : denied_ ? ::cilium::EntryType::Denied
                                    : ::cilium::EntryType::Response);
   return Http::FilterHeadersStatus::Continue;
 }
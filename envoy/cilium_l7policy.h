@@ -23,6 +23,7 @@ namespace Cilium {
 // clang-format off
 #define ALL_CILIUM_STATS(COUNTER)                                                                  \
   COUNTER(access_denied)                                                                           \
+  COUNTER(access_rate_limited)                                                                     \
 // clang-format on
 
 /**
@@ -65,7 +66,7 @@ typedef std::shared_ptr<Config> ConfigSharedPtr;
 class AccessFilter : public Http::StreamFilter,
                      Logger::Loggable<Logger::Id::filter> {
 public:
-  AccessFilter(ConfigSharedPtr& config) : config_(config), denied_(false) {}
+  AccessFilter(ConfigSharedPtr& config) : config_(config), denied_(false), rate_limited_(false) {}
 
   // Http::StreamFilterBase
   void onDestroy() override;
@@ -100,6 +101,7 @@ private:
   Http::StreamDecoderFilterCallbacks* callbacks_;
 
   bool denied_;
+  bool rate_limited_;
   AccessLog::Entry log_entry_;
 };
 
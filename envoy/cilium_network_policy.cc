@@ -63,7 +63,7 @@ void NetworkPolicyMap::onConfigUpdate(const ResourceVector& resources, const std
     }
 
     // May throw
-    to_be_added->emplace_back(std::make_shared<PolicyInstance>(new_hash, config));
+    to_be_added->emplace_back(std::make_shared<PolicyInstance>(new_hash, config, old_policy));
   }
 
   // Collect a shared vector of policy names to be removed
@@ -1,5 +1,8 @@
 #pragma once
 
+#include <chrono>
+#include <mutex>
+
 #include "envoy/local_info/local_info.h"
 #include "envoy/upstream/cluster_manager.h"
 #include "envoy/event/dispatcher.h"
@@ -39,17 +42,111 @@ public:
   
   class PolicyInstance {
   public:
-    PolicyInstance(uint64_t hash, const cilium::NetworkPolicy& proto)
-        : hash_(hash), policy_proto_(proto), ingress_(policy_proto_.ingress_per_port_policies()),
-          egress_(policy_proto_.egress_per_port_policies()) {}
+    // The rate limiters of the rules of 'old_policy', if any, are taken over
+    // by the rules of the new policy which are unchanged.
+    PolicyInstance(uint64_t hash, const cilium::NetworkPolicy& proto,
+		   const std::shared_ptr<const PolicyInstance>& old_policy)
+        : hash_(hash), policy_proto_(proto),
+          rate_limiters_(old_policy ? &old_policy->rate_limiters_ : nullptr),
+          ingress_(policy_proto_.ingress_per_port_policies(), rate_limiters_, "ingress"),
+          egress_(policy_proto_.egress_per_port_policies(), rate_limiters_, "egress") {
+      rate_limiters_.previous_ = nullptr;
+    }
 
     uint64_t hash_;
     const cilium::NetworkPolicy policy_proto_;
 
   protected:
+    // Token bucket rate limiter shared by all worker threads. Each source
+    // security identity has a bucket of its own.
+    class RateLimiter {
+    public:
+      RateLimiter(const cilium::HttpRateLimit& limit)
+	: rate_(limit.requests_per_second()),
+	  burst_(limit.burst() > 0 ? limit.burst() : limit.requests_per_second()),
+	  idle_timeout_(std::max(burst_ / rate_, 1.0)),
+	  last_expiry_(std::chrono::steady_clock::now()) {}
+
+      // Returns 'true' if a request from the given source is within the limit.
+      bool Consume(uint64_t source_identity) {
+	auto now = std::chrono::steady_clock::now();
+	std::lock_guard<std::mutex> lock(mutex_);
+	ExpireIdleBuckets(now);
+	auto it = buckets_.find(source_identity);
+	if (it == buckets_.end()) {
+	  it = buckets_.emplace(source_identity, Bucket{burst_, now}).first;
+	} else {
+	  std::chrono::duration<double> elapsed = now - it->second.last_;
+	  it->second.tokens_ = std::min(burst_, it->second.tokens_ + elapsed.count() * rate_);
+	  it->second.last_ = now;
+	}
+	if (it->second.tokens_ < 1.0) {
+	  return false;
+	}
+	it->second.tokens_ -= 1.0;
+	return true;
+      }
+
+    private:
+      // Buckets which have been idle long enough to be refilled completely
+      // are removed, a new bucket starts full.
+      void ExpireIdleBuckets(std::chrono::steady_clock::time_point now) {
+	if (now - last_expiry_ < idle_timeout_) {
+	  return;
+	}
+	last_expiry_ = now;
+	for (auto it = buckets_.begin(); it != buckets_.end();) {
+	  if (now - it->second.last_ >= idle_timeout_) {
+	    it = buckets_.erase(it);
+	  } else {
+	    ++it;
+	  }
+	}
+      }
+
+      struct Bucket {
+	double tokens_;
+	std::chrono::steady_clock::time_point last_;
+      };
+
+      const double rate_;
+      const double burst_;
+      const std::chrono::duration<double> idle_timeout_;
+      std::mutex mutex_;
+      std::chrono::steady_clock::time_point last_expiry_;
+      std::unordered_map<uint64_t, Bucket> buckets_;
+    };
+
+    // Rate limiters of a policy, keyed by the rule they belong to.
+    class RateLimiters {
+    public:
+      RateLimiters(const RateLimiters* previous) : previous_(previous) {}
+
+      // Returns the rate limiter of the rule with the given key, which is
+      // taken from the previous version of the policy if the rule was
+      // present there.
+      std::shared_ptr<RateLimiter> Get(const std::string& key, const cilium::HttpRateLimit& limit) {
+	auto& limiter = limiters_[key];
+	if (!limiter && previous_) {
+	  auto it = previous_->limiters_.find(key);
+	  if (it != previous_->limiters_.end()) {
+	    limiter = it->second;
+	  }
+	}
+	if (!limiter) {
+	  limiter = std::make_shared<RateLimiter>(limit);
+	}
+	return limiter;
+      }
+
+      std::unordered_map<std::string, std::shared_ptr<RateLimiter>> limiters_;
+      const RateLimiters* previous_; // Only set while the policy is created.
+    };
+
     class HttpNetworkPolicyRule : public Logger::Loggable<Logger::Id::config> {
     public:
-      HttpNetworkPolicyRule(const cilium::HttpNetworkPolicyRule& rule) {
+      HttpNetworkPolicyRule(const cilium::HttpNetworkPolicyRule& rule, RateLimiters& rate_limiters,
+			    const std::string& key_prefix) {
 	ENVOY_LOG(trace, "Cilium L7 HttpNetworkPolicyRule():");
 	for (const auto& header: rule.headers()) {
 	  headers_.emplace_back(header);
@@ -63,31 +160,98 @@ public:
 		    : header_data.header_match_type_ == Http::HeaderUtility::HeaderMatchType::Regex
 		    ? "<REGEX>" : "<UNKNOWN>");
 	}
+	for (const auto& header: rule.headers_to_add()) {
+	  ENVOY_LOG(trace, "Cilium L7 HttpNetworkPolicyRule(): Adding header {}={}",
+		    header.key(), header.value());
+	  headers_to_add_.emplace_back(Http::LowerCaseString(header.key()), header.value());
+	}
+	for (const auto& name: rule.headers_to_remove()) {
+	  ENVOY_LOG(trace, "Cilium L7 HttpNetworkPolicyRule(): Removing header {}", name);
+	  headers_to_remove_.emplace_back(name);
+	}
+	if (rule.has_rate_limit()) {
+	  ENVOY_LOG(trace, "Cilium L7 HttpNetworkPolicyRule(): Rate limit {}/s, burst {}",
+		    rule.rate_limit().requests_per_second(), rule.rate_limit().burst());
+	  rate_limiter_ = rate_limiters.Get(key_prefix + rule.SerializeAsString(), rule.rate_limit());
+	}
       }
 
       bool Matches(const Envoy::Http::HeaderMap& headers) const {
 	// Empty set matches any headers.
-	return Envoy::Http::HeaderUtility::matchHeaders(headers, headers_);
+	for (const auto& header_data: headers_) {
+	  if (!MatchesHeader(headers, header_data)) {
+	    return false;
+	  }
+	}
+	return true;
+      }
+
+      // Envoy fails the match of an absent header before applying
+      // 'invert_match', so that an inverted presence match would never
+      // match. An inverted presence match requires the header to be absent.
+      static bool MatchesHeader(const Envoy::Http::HeaderMap& headers,
+				const Envoy::Http::HeaderUtility::HeaderData& header_data) {
+	if (header_data.header_match_type_ == Http::HeaderUtility::HeaderMatchType::Present &&
+	    header_data.invert_match_) {
+	  return headers.get(header_data.name_) == nullptr;
+	}
+	return Envoy::Http::HeaderUtility::matchHeaders(headers, header_data);
+      }
+
+      // Returns 'true' if the request of the source is within the rate limit
+      // of the rule, if any.
+      bool WithinRateLimit(uint64_t source_identity) const {
+	return !rate_limiter_ || rate_limiter_->Consume(source_identity);
+      }
+
+      // Apply the header removals of the rule to an allowed request.
+      void RemoveHeaders(Envoy::Http::HeaderMap& headers) const {
+	for (const auto& name: headers_to_remove_) {
+	  headers.remove(name);
+	}
+      }
+
+      // Apply the header additions of the rule to an allowed request.
+      void AddHeaders(Envoy::Http::HeaderMap& headers, uint64_t source_identity) const {
+	for (const auto& header: headers_to_add_) {
+	  headers.remove(header.first);
+	  if (header.second == "%SOURCE_IDENTITY%") {
+	    headers.addCopy(header.first, source_identity);
+	  } else {
+	    headers.addCopy(header.first, header.second);
+	  }
+	}
       }
 
       std::vector<Envoy::Http::HeaderUtility::HeaderData> headers_; // Allowed if empty.
+      std::vector<std::pair<Http::LowerCaseString, std::string>> headers_to_add_;
+      std::vector<Http::LowerCaseString> headers_to_remove_;
+      // Shared by the copies of the rule, so that the limit applies to all
+      // worker threads, and by the same rule in later versions of the policy.
+      std::shared_ptr<RateLimiter> rate_limiter_;
     };
     
     class PortNetworkPolicyRule : public Logger::Loggable<Logger::Id::config> {
     public:
-      PortNetworkPolicyRule(const cilium::PortNetworkPolicyRule& rules) {
+      PortNetworkPolicyRule(const cilium::PortNetworkPolicyRule& rules, RateLimiters& rate_limiters,
+			    const std::string& key_prefix) {
+	std::string key = key_prefix;
 	for (const auto& remote: rules.remote_policies()) {
 	  ENVOY_LOG(trace, "Cilium L7 PortNetworkPolicyRule(): Allowing remote {}", remote);
 	  allowed_remotes_.emplace(remote);
+	  key += fmt::format("{},", remote);
 	}
+	key += "/";
 	if (rules.has_http_rules()) {
 	  for (const auto& http_rule: rules.http_rules().http_rules()) {
-	    http_rules_.emplace_back(http_rule);
+	    http_rules_.emplace_back(http_rule, rate_limiters, key);
 	  }
 	}
       }
 
-      bool Matches(uint64_t remote_id, const Envoy::Http::HeaderMap& headers) const {
+      // All matching HTTP rules are appended to 'matched'.
+      bool Matches(uint64_t remote_id, const Envoy::Http::HeaderMap& headers,
+		   std::vector<const HttpNetworkPolicyRule*>& matched) const {
 	// Remote ID must match if we have any.
 	if (allowed_remotes_.size() > 0) {
 	  auto search = allowed_remotes_.find(remote_id);
@@ -96,12 +260,14 @@ public:
 	  }
 	}
 	if (http_rules_.size() > 0) {
+	  bool allowed = false;
 	  for (const auto& rule: http_rules_) {
 	    if (rule.Matches(headers)) {
-	      return true;
+	      matched.push_back(&rule);
+	      allowed = true;
 	    }
 	  }
-	  return false;
+	  return allowed;
 	}
 	// Empty set matches any payload
 	return true;
@@ -113,7 +279,8 @@ public:
 
     class PortNetworkPolicyRules : public Logger::Loggable<Logger::Id::config> {
     public:
-      PortNetworkPolicyRules(const google::protobuf::RepeatedPtrField<cilium::PortNetworkPolicyRule>& rules) : have_http_rules_(false) {
+      PortNetworkPolicyRules(const google::protobuf::RepeatedPtrField<cilium::PortNetworkPolicyRule>& rules,
+			     RateLimiters& rate_limiters, const std::string& key_prefix) : have_http_rules_(false) {
 	if (rules.size() == 0) {
 	    ENVOY_LOG(trace, "Cilium L7 PortNetworkPolicyRules(): No rules, will allow everything.");
 	}
@@ -121,11 +288,25 @@ public:
 	  if (it.has_http_rules()) {
 	    have_http_rules_ = true;
 	  }
-	  rules_.emplace_back(PortNetworkPolicyRule(it));
+	  rules_.emplace_back(PortNetworkPolicyRule(it, rate_limiters, key_prefix));
+	  for (const auto& http_rule: rules_.back().http_rules_) {
+	    for (const auto& header: http_rule.headers_to_add_) {
+	      added_headers_.push_back(header.first);
+	    }
+	  }
 	}
       }
 
-      bool Matches(uint64_t remote_id, const Envoy::Http::HeaderMap& headers) const {
+      // Removes the headers added by any of the rules, so that a request
+      // allowed by a rule not adding them cannot pass forged values on.
+      void RemoveAddedHeaders(Envoy::Http::HeaderMap& headers) const {
+	for (const auto& name: added_headers_) {
+	  headers.remove(name);
+	}
+      }
+
+      bool Matches(uint64_t remote_id, const Envoy::Http::HeaderMap& headers,
+		   std::vector<const HttpNetworkPolicyRule*>& matched) const {
 	if (!have_http_rules_) {
 	  // If there are no L7 rules, host proxy will not create a proxy redirect at all,
 	  // whereby the decicion made by the bpf datapath is final. Emulate the same behavior
@@ -137,27 +318,31 @@ public:
 	if (rules_.size() == 0) {
 	  return true;
 	}
+	bool allowed = false;
 	for (const auto& rule: rules_) {
-	  if (rule.Matches(remote_id, headers)) {
-	    return true;
+	  if (rule.Matches(remote_id, headers, matched)) {
+	    allowed = true;
 	  }
 	}
-	return false;
+	return allowed;
       }
 
       std::vector<PortNetworkPolicyRule> rules_; // Allowed if empty.
+      std::vector<Http::LowerCaseString> added_headers_;
       bool have_http_rules_;
     };
     
     class PortNetworkPolicy : public Logger::Loggable<Logger::Id::config> {
     public:
-      PortNetworkPolicy(const google::protobuf::RepeatedPtrField<cilium::PortNetworkPolicy>& rules) {
+      PortNetworkPolicy(const google::protobuf::RepeatedPtrField<cilium::PortNetworkPolicy>& rules,
+			RateLimiters& rate_limiters, const std::string& direction) {
 	for (const auto& it: rules) {
 	  // Only TCP supported for HTTP
 	  if (it.protocol() == envoy::api::v2::core::SocketAddress::TCP) {
 	    // Port may be zero, which matches any port.
 	    ENVOY_LOG(trace, "Cilium L7 PortNetworkPolicy(): installing TCP policy for port {}", it.port());
-	    if (!rules_.emplace(it.port(), PortNetworkPolicyRules(it.rules())).second) {
+	    if (!rules_.emplace(it.port(), PortNetworkPolicyRules(it.rules(), rate_limiters,
+								  fmt::format("{}/{}/", direction, it.port()))).second) {
 	      throw EnvoyException("PortNetworkPolicy: Duplicate port number");
 	    }
 	  } else {
@@ -166,23 +351,28 @@ public:
 	}
       }
 
-      bool Matches(uint32_t port, uint64_t remote_id, const Envoy::Http::HeaderMap& headers) const {
+      bool Matches(uint32_t port, uint64_t remote_id, const Envoy::Http::HeaderMap& headers,
+		   std::vector<const HttpNetworkPolicyRule*>& matched) const {
 	bool found_port_rule = false;
+	bool allowed = false;
 	auto it = rules_.find(port);
 	if (it != rules_.end()) {
-	  if (it->second.Matches(remote_id, headers)) {
-	    return true;
+	  if (it->second.Matches(remote_id, headers, matched)) {
+	    allowed = true;
 	  }
 	  found_port_rule = true;
 	}
 	// Check for any rules that wildcard the port
 	it = rules_.find(0);
 	if (it != rules_.end()) {
-	  if (it->second.Matches(remote_id, headers)) {
-	    return true;
+	  if (it->second.Matches(remote_id, headers, matched)) {
+	    allowed = true;
 	  }
 	  found_port_rule = true;
 	}
+	if (allowed) {
+	  return true;
+	}
 
 	// No policy for the port was found. Cilium always creates a policy for redirects it
 	// creates, so the host proxy never gets here. Sidecar gets all the traffic, which we need
@@ -191,18 +381,67 @@ public:
 	return found_port_rule ? false : true;
       }
 
+      // Removes the headers added by any rule of the port from a request.
+      void RemoveAddedHeaders(uint32_t port, Envoy::Http::HeaderMap& headers) const {
+	auto it = rules_.find(port);
+	if (it != rules_.end()) {
+	  it->second.RemoveAddedHeaders(headers);
+	}
+	it = rules_.find(0);
+	if (it != rules_.end()) {
+	  it->second.RemoveAddedHeaders(headers);
+	}
+      }
+
       std::unordered_map<uint32_t, PortNetworkPolicyRules> rules_;
     };
 
   public:
-    bool Allowed(bool ingress, uint32_t port, uint64_t remote_id,
-		 const Envoy::Http::HeaderMap& headers) const {
-      return ingress
-	? ingress_.Matches(port, remote_id, headers)
-	: egress_.Matches(port, remote_id, headers);
+    // Headers of allowed requests are modified by the header actions of all
+    // matching rules, after removing the headers added by any rule of the
+    // port. Requests exceeding the rate limits of all matching rules are not
+    // allowed, and 'rate_limited' is set to 'true'.
+    bool Allowed(bool ingress, uint32_t port, uint64_t remote_id, uint64_t source_identity,
+		 Envoy::Http::HeaderMap& headers, bool& rate_limited) const {
+      const PortNetworkPolicy& policy = ingress ? ingress_ : egress_;
+      std::vector<const HttpNetworkPolicyRule*> matched;
+      rate_limited = false;
+      if (!policy.Matches(port, remote_id, headers, matched)) {
+	return false;
+      }
+      if (!matched.empty() && !WithinRateLimit(matched, source_identity)) {
+	rate_limited = true;
+	return false;
+      }
+      policy.RemoveAddedHeaders(port, headers);
+      for (const auto* rule: matched) {
+	rule->RemoveHeaders(headers);
+      }
+      for (const auto* rule: matched) {
+	rule->AddHeaders(headers, source_identity);
+      }
+      return true;
     }
 
   private:
+    // A request is within the rate limit if any of the matching rules allows
+    // it. Tokens are only consumed if all matching rules are rate limited.
+    static bool WithinRateLimit(const std::vector<const HttpNetworkPolicyRule*>& matched,
+				uint64_t source_identity) {
+      for (const auto* rule: matched) {
+	if (!rule->rate_limiter_) {
+	  return true;
+	}
+      }
+      for (const auto* rule: matched) {
+	if (rule->WithinRateLimit(source_identity)) {
+	  return true;
+	}
+      }
+      return false;
+    }
+
+    RateLimiters rate_limiters_;
     const PortNetworkPolicy ingress_;
     const PortNetworkPolicy egress_;
   };
@@ -221,7 +460,8 @@ public:
   }
 
   bool Allowed(const std::string& endpoint_policy_name, bool ingress, uint32_t port, uint64_t remote_id,
-	       const Envoy::Http::HeaderMap& headers) const {
+	       uint64_t source_identity, Envoy::Http::HeaderMap& headers, bool& rate_limited) const {
+    rate_limited = false;
     ENVOY_LOG(trace, "Cilium L7 NetworkPolicyMap::Allowed(): {} policy lookup for endpoint {}, port {}, remote_id: {}", ingress ? "Ingress" : "Egress", endpoint_policy_name, port, remote_id);
     if (tls_->get().get() == nullptr) {
       ENVOY_LOG(warn, "Cilium L7 NetworkPolicyMap::Allowed(): NULL TLS object!");
@@ -233,7 +473,7 @@ public:
       ENVOY_LOG(trace, "Cilium L7 NetworkPolicyMap::Allowed(): No policy found for endpoint {}", endpoint_policy_name);
       return false;
     }
-    return it->second->Allowed(ingress, port, remote_id, headers);
+    return it->second->Allowed(ingress, port, remote_id, source_identity, headers, rate_limited);
   }
 
   // Config::SubscriptionCallbacks
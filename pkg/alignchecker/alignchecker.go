@@ -109,6 +109,30 @@ var cToGO = map[reflect.Type]valueToCheck{
 		sizeOfC:  C.sizeof_struct_lb6_service,
 		goStruct: reflect.TypeOf(lbmap.Service6Value{}),
 	},
+	reflect.TypeOf(C.struct_lb_maglev_entry{}): {
+		sizeOfC:  C.sizeof_struct_lb_maglev_entry,
+		goStruct: reflect.TypeOf(lbmap.MaglevValue{}),
+	},
+	reflect.TypeOf(C.struct_lb_active_entry{}): {
+		sizeOfC:  C.sizeof_struct_lb_active_entry,
+		goStruct: reflect.TypeOf(lbmap.ActiveValue{}),
+	},
+	reflect.TypeOf(C.struct_lb4_affinity_key{}): {
+		sizeOfC:  C.sizeof_struct_lb4_affinity_key,
+		goStruct: reflect.TypeOf(lbmap.Affinity4Key{}),
+	},
+	reflect.TypeOf(C.struct_lb6_affinity_key{}): {
+		sizeOfC:  C.sizeof_struct_lb6_affinity_key,
+		goStruct: reflect.TypeOf(lbmap.Affinity6Key{}),
+	},
+	reflect.TypeOf(C.struct_lb4_affinity_val{}): {
+		sizeOfC:  C.sizeof_struct_lb4_affinity_val,
+		goStruct: reflect.TypeOf(lbmap.Affinity4Value{}),
+	},
+	reflect.TypeOf(C.struct_lb6_affinity_val{}): {
+		sizeOfC:  C.sizeof_struct_lb6_affinity_val,
+		goStruct: reflect.TypeOf(lbmap.Affinity6Value{}),
+	},
 	reflect.TypeOf(C.struct_endpoint_key{}): {
 		sizeOfC:  C.sizeof_struct_endpoint_key,
 		goStruct: reflect.TypeOf(bpf.EndpointKey{}),
@@ -37,4 +37,8 @@ const (
 	// CiliumHostIP is the annotation name used to store the IPv4 address
 	// of the cilium host interface in the node's annotations.
 	CiliumHostIP = "io.cilium.network.ipv4-cilium-host"
+
+	// IPAMPool is the annotation name used to select the named IPAM pool
+	// the addresses of a pod are allocated from.
+	IPAMPool = "ipam.cilium.io/pool"
 )
@@ -299,4 +299,16 @@ func FormatStatusResponse(w io.Writer, sr *models.StatusResponse, allAddresses,
 	} else {
 		fmt.Fprintf(w, "Proxy Status:\tNo managed proxy redirect\n")
 	}
+
+	if enc := sr.Encryption; enc != nil {
+		if enc.Mode == models.EncryptionStatusModeIPsec {
+			fmt.Fprintf(w, "Encryption:\tIPsec, SPI %d, %d keys, %d nodes", enc.Spi, enc.Keys, enc.Peers)
+		} else {
+			fmt.Fprintf(w, "Encryption:\t%s", enc.Mode)
+		}
+		if enc.Msg != "" {
+			fmt.Fprintf(w, "\t%s", enc.Msg)
+		}
+		fmt.Fprintf(w, "\n")
+	}
 }
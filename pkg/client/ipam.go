@@ -25,14 +25,24 @@ const (
 	AddressFamilyIPv4 = "ipv4"
 )
 
-// IPAMAllocate allocates an IP address out of address family specific pool.
-func (c *Client) IPAMAllocate(family string) (*models.IPAMResponse, error) {
+// IPAMAllocate allocates an IP address out of address family specific pool
+// on behalf of owner. If pod is set to the namespace/name of a Kubernetes pod,
+// the address is allocated from the IPAM pool selected by the pod.
+func (c *Client) IPAMAllocate(family, owner, pod string) (*models.IPAMResponse, error) {
 	params := ipam.NewPostIPAMParams().WithTimeout(api.ClientTimeout)
 
 	if family != "" {
 		params.SetFamily(&family)
 	}
 
+	if owner != "" {
+		params.SetOwner(&owner)
+	}
+
+	if pod != "" {
+		params.SetPod(&pod)
+	}
+
 	resp, err := c.IPAM.PostIPAM(params)
 	if err != nil {
 		return nil, Hint(err)
@@ -40,9 +50,13 @@ func (c *Client) IPAMAllocate(family string) (*models.IPAMResponse, error) {
 	return resp.Payload, nil
 }
 
-// IPAMAllocateIP tries to allocate a particular IP address.
-func (c *Client) IPAMAllocateIP(ip string) error {
+// IPAMAllocateIP tries to allocate a particular IP address on behalf of owner.
+func (c *Client) IPAMAllocateIP(ip, owner string) error {
 	params := ipam.NewPostIPAMIPParams().WithIP(ip).WithTimeout(api.ClientTimeout)
+
+	if owner != "" {
+		params.SetOwner(&owner)
+	}
 	_, err := c.IPAM.PostIPAMIP(params)
 	return Hint(err)
 }
@@ -0,0 +1,418 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// reqID identifies the XFRM states and policies installed by Cilium
+const reqID = 0xc1
+
+// Peer describes the traffic exchanged with a remote node which is encrypted
+type Peer struct {
+	// LocalIP and RemoteIP are the IPs of the local and of the remote node
+	// the encrypted packets are exchanged between
+	LocalIP  net.IP
+	RemoteIP net.IP
+
+	// LocalCIDR and RemoteCIDR are the endpoint CIDRs of the local and of
+	// the remote node. If set, the traffic between them is encrypted in
+	// tunnel mode.
+	LocalCIDR  *net.IPNet
+	RemoteCIDR *net.IPNet
+
+	// EncapPort is the UDP port of the overlay network. If LocalCIDR and
+	// RemoteCIDR are unset, the overlay traffic between LocalIP and
+	// RemoteIP is encrypted in transport mode instead.
+	EncapPort int
+}
+
+func cidrEquals(a, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// Equals returns true if both peers select the same traffic
+func (p *Peer) Equals(o *Peer) bool {
+	return p.LocalIP.Equal(o.LocalIP) && p.RemoteIP.Equal(o.RemoteIP) &&
+		cidrEquals(p.LocalCIDR, o.LocalCIDR) && cidrEquals(p.RemoteCIDR, o.RemoteCIDR) &&
+		p.EncapPort == o.EncapPort
+}
+
+func (p *Peer) mode() netlink.Mode {
+	if p.LocalCIDR != nil && p.RemoteCIDR != nil {
+		return netlink.XFRM_MODE_TUNNEL
+	}
+	return netlink.XFRM_MODE_TRANSPORT
+}
+
+func hostNet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+func anyIP(ip net.IP) net.IP {
+	if ip.To4() != nil {
+		return net.IPv4zero
+	}
+	return net.IPv6zero
+}
+
+// outState returns the XFRM state encrypting the traffic sent to p with key
+func outState(p *Peer, key *Key) *netlink.XfrmState {
+	return &netlink.XfrmState{
+		Src:   p.LocalIP,
+		Dst:   p.RemoteIP,
+		Proto: netlink.XFRM_PROTO_ESP,
+		Mode:  p.mode(),
+		Spi:   key.Spi,
+		Reqid: reqID,
+		Aead:  key.Aead,
+		Auth:  key.Auth,
+		Crypt: key.Crypt,
+	}
+}
+
+// inState returns the XFRM state decrypting the traffic received from p with
+// key. As the kernel identifies inbound states by their destination and SPI
+// only, the state is shared by all peers with the same local IP.
+func inState(p *Peer, key *Key) *netlink.XfrmState {
+	return &netlink.XfrmState{
+		Src:   anyIP(p.LocalIP),
+		Dst:   p.LocalIP,
+		Proto: netlink.XFRM_PROTO_ESP,
+		Mode:  p.mode(),
+		Spi:   key.Spi,
+		Reqid: reqID,
+		Aead:  key.Aead,
+		Auth:  key.Auth,
+		Crypt: key.Crypt,
+	}
+}
+
+// policies returns the XFRM policies selecting the traffic exchanged with p.
+// The outbound policy encrypts the traffic with the key identified by spi,
+// the inbound policies accept any key.
+func policies(p *Peer, spi int) []*netlink.XfrmPolicy {
+	outTmpl := []netlink.XfrmPolicyTmpl{{
+		Src:   p.LocalIP,
+		Dst:   p.RemoteIP,
+		Proto: netlink.XFRM_PROTO_ESP,
+		Mode:  p.mode(),
+		Spi:   spi,
+		Reqid: reqID,
+	}}
+	inTmpl := []netlink.XfrmPolicyTmpl{{
+		Src:   anyIP(p.LocalIP),
+		Dst:   p.LocalIP,
+		Proto: netlink.XFRM_PROTO_ESP,
+		Mode:  p.mode(),
+		Reqid: reqID,
+	}}
+
+	if p.mode() == netlink.XFRM_MODE_TUNNEL {
+		return []*netlink.XfrmPolicy{
+			{Src: p.LocalCIDR, Dst: p.RemoteCIDR, Dir: netlink.XFRM_DIR_OUT, Tmpls: outTmpl},
+			{Src: p.RemoteCIDR, Dst: p.LocalCIDR, Dir: netlink.XFRM_DIR_IN, Tmpls: inTmpl},
+			{Src: p.RemoteCIDR, Dst: p.LocalCIDR, Dir: netlink.XFRM_DIR_FWD, Tmpls: inTmpl},
+		}
+	}
+
+	local, remote := hostNet(p.LocalIP), hostNet(p.RemoteIP)
+	udp := netlink.Proto(syscall.IPPROTO_UDP)
+	return []*netlink.XfrmPolicy{
+		{Src: local, Dst: remote, Proto: udp, DstPort: p.EncapPort, Dir: netlink.XFRM_DIR_OUT, Tmpls: outTmpl},
+		{Src: remote, Dst: local, Proto: udp, DstPort: p.EncapPort, Dir: netlink.XFRM_DIR_IN, Tmpls: inTmpl},
+	}
+}
+
+// policyID returns a string identifying the selector of policy
+func policyID(policy *netlink.XfrmPolicy) string {
+	return fmt.Sprintf("%s %s %s %d %s", policy.Src, policy.Dst, policy.Proto, policy.DstPort, policy.Dir)
+}
+
+func replaceState(state *netlink.XfrmState) error {
+	err := netlink.XfrmStateAdd(state)
+	if os.IsExist(err) {
+		err = netlink.XfrmStateUpdate(state)
+	}
+	return err
+}
+
+// installPeer installs the XFRM states of all keys and the XFRM policies
+// for p. The first key is used to encrypt.
+func installPeer(p *Peer, keys []*Key) error {
+	for _, key := range keys {
+		if err := replaceState(inState(p, key)); err != nil {
+			return fmt.Errorf("unable to install inbound XFRM state with SPI %d: %s", key.Spi, err)
+		}
+		if err := replaceState(outState(p, key)); err != nil {
+			return fmt.Errorf("unable to install outbound XFRM state with SPI %d to %s: %s", key.Spi, p.RemoteIP, err)
+		}
+	}
+
+	for _, policy := range policies(p, keys[0].Spi) {
+		if err := netlink.XfrmPolicyUpdate(policy); err != nil {
+			return fmt.Errorf("unable to install XFRM policy %s: %s", policy, err)
+		}
+	}
+	return nil
+}
+
+// uninstallPeer removes the XFRM policies and the outbound XFRM states of
+// all keys for p, except for those which are also required by keep. The
+// inbound XFRM states are shared with other peers and left in place.
+func uninstallPeer(p *Peer, keys []*Key, keep *Peer) {
+	keepPolicies := map[string]struct{}{}
+	if keep != nil {
+		for _, policy := range policies(keep, 0) {
+			keepPolicies[policyID(policy)] = struct{}{}
+		}
+	}
+
+	for _, policy := range policies(p, 0) {
+		if _, ok := keepPolicies[policyID(policy)]; ok {
+			continue
+		}
+		if err := netlink.XfrmPolicyDel(policy); err != nil {
+			log.WithError(err).WithField("policy", policy).Debug("Unable to delete XFRM policy")
+		}
+	}
+
+	if keep != nil && keep.LocalIP.Equal(p.LocalIP) && keep.RemoteIP.Equal(p.RemoteIP) &&
+		keep.mode() == p.mode() {
+		return
+	}
+	for _, key := range keys {
+		deleteState(outState(p, key))
+	}
+}
+
+func deleteState(state *netlink.XfrmState) {
+	if err := netlink.XfrmStateDel(state); err != nil {
+		log.WithError(err).WithFields(logrus.Fields{
+			"spi": state.Spi,
+			"dst": state.Dst,
+		}).Debug("Unable to delete XFRM state")
+	}
+}
+
+type ipsecConfig struct {
+	lock.Mutex
+
+	// keys are the keys accepted to decrypt, the first key is used to
+	// encrypt
+	keys []*Key
+
+	// peers are all peers the traffic is encrypted with, by name
+	peers map[string]*Peer
+
+	// err is the last error encountered while installing the XFRM states
+	// and policies
+	err error
+}
+
+var config = &ipsecConfig{
+	peers: map[string]*Peer{},
+}
+
+// UpsertPeer encrypts the traffic exchanged with the peer of the given name.
+// The XFRM states and policies of a previous peer of the same name which
+// are no longer required are removed.
+func UpsertPeer(name string, p *Peer) error {
+	config.Lock()
+	defer config.Unlock()
+
+	oldPeer, ok := config.peers[name]
+	config.peers[name] = p
+
+	// Peers are installed once the keys are known
+	if len(config.keys) == 0 || (ok && oldPeer.Equals(p) && config.err == nil) {
+		return nil
+	}
+
+	config.err = installPeer(p, config.keys)
+	if ok {
+		uninstallPeer(oldPeer, config.keys, p)
+	}
+	return config.err
+}
+
+// DeletePeer stops encrypting the traffic exchanged with the peer of the
+// given name.
+func DeletePeer(name string) {
+	config.Lock()
+	defer config.Unlock()
+
+	p, ok := config.peers[name]
+	if !ok {
+		return
+	}
+	delete(config.peers, name)
+
+	if len(config.keys) > 0 {
+		uninstallPeer(p, config.keys, nil)
+	}
+}
+
+func findKey(keys []*Key, spi int) *Key {
+	for _, key := range keys {
+		if key.Spi == spi {
+			return key
+		}
+	}
+	return nil
+}
+
+// SetKeys replaces the keys used for all peers. All keys are accepted to
+// decrypt, the first key is used to encrypt. The XFRM states of keys which
+// are no longer present are removed once all peers use the new keys.
+//
+// The key material of an SPI cannot be changed in place, keys with a known
+// SPI but a different key material are briefly removed before they are
+// installed again.
+func SetKeys(keys []*Key) error {
+	config.Lock()
+	defer config.Unlock()
+
+	if len(keys) == len(config.keys) {
+		changed := false
+		for i := range keys {
+			if !keys[i].Equals(config.keys[i]) {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return nil
+		}
+	}
+
+	removed := []*Key{}
+	for _, oldKey := range config.keys {
+		newKey := findKey(keys, oldKey.Spi)
+		switch {
+		case newKey == nil:
+			removed = append(removed, oldKey)
+		case !newKey.Equals(oldKey):
+			log.WithField("spi", oldKey.Spi).Warn("Key material of IPsec key changed without changing the SPI, connectivity is disrupted")
+			for _, p := range config.peers {
+				deleteState(inState(p, oldKey))
+				deleteState(outState(p, oldKey))
+			}
+		}
+	}
+
+	config.keys = keys
+	config.err = nil
+	for name, p := range config.peers {
+		if err := installPeer(p, keys); err != nil {
+			log.WithError(err).WithField("peer", name).Warn("Unable to install IPsec keys")
+			config.err = err
+		}
+	}
+
+	for _, key := range removed {
+		for _, p := range config.peers {
+			deleteState(inState(p, key))
+			deleteState(outState(p, key))
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"spi":  keys[0].Spi,
+		"keys": len(keys),
+	}).Info("IPsec keys updated")
+
+	return config.err
+}
+
+// LoadKeysFromFile reads the keys stored in the file at path and uses them
+// for all peers, see SetKeys()
+func LoadKeysFromFile(path string) error {
+	keys, err := LoadKeys(path)
+	if err != nil {
+		return err
+	}
+	return SetKeys(keys)
+}
+
+// DeleteAll removes all XFRM states and policies installed by Cilium, e.g.
+// by a previous run with IPsec enabled.
+func DeleteAll() error {
+	policies, err := netlink.XfrmPolicyList(netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("unable to list XFRM policies: %s", err)
+	}
+	for i := range policies {
+		policy := &policies[i]
+		if len(policy.Tmpls) == 0 || policy.Tmpls[0].Reqid != reqID {
+			continue
+		}
+		if err := netlink.XfrmPolicyDel(policy); err != nil {
+			return fmt.Errorf("unable to delete XFRM policy %s: %s", policy, err)
+		}
+	}
+
+	states, err := netlink.XfrmStateList(netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("unable to list XFRM states: %s", err)
+	}
+	for i := range states {
+		state := &states[i]
+		if state.Reqid != reqID {
+			continue
+		}
+		if err := netlink.XfrmStateDel(state); err != nil {
+			return fmt.Errorf("unable to delete XFRM state %s: %s", state, err)
+		}
+	}
+	return nil
+}
+
+// GetStatusModel returns the status of the IPsec encryption
+func GetStatusModel() *models.EncryptionStatus {
+	config.Lock()
+	defer config.Unlock()
+
+	status := &models.EncryptionStatus{
+		Mode:  models.EncryptionStatusModeIPsec,
+		Keys:  int64(len(config.keys)),
+		Peers: int64(len(config.peers)),
+	}
+	if len(config.keys) > 0 {
+		status.Spi = int64(config.keys[0].Spi)
+	} else {
+		status.Msg = "No keys loaded"
+	}
+	if config.err != nil {
+		status.Msg = config.err.Error()
+	}
+	return status
+}
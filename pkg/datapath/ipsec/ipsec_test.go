@@ -0,0 +1,138 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type IPsecSuite struct{}
+
+var _ = Suite(&IPsecSuite{})
+
+func (s *IPsecSuite) TestParseKeys(c *C) {
+	keys, err := ParseKeys(strings.NewReader(`
+# active key
+3 rfc4106(gcm(aes)) 0x0102030405060708090a0b0c0d0e0f1011121314 128
+2 hmac(sha256) 0102030405060708090a0b0c0d0e0f10 cbc(aes) 0102030405060708090a0b0c0d0e0f10
+`))
+	c.Assert(err, IsNil)
+	c.Assert(len(keys), Equals, 2)
+
+	c.Assert(keys[0].Spi, Equals, 3)
+	c.Assert(keys[0].Aead, Not(IsNil))
+	c.Assert(keys[0].Aead.Name, Equals, "rfc4106(gcm(aes))")
+	c.Assert(len(keys[0].Aead.Key), Equals, 20)
+	c.Assert(keys[0].Aead.ICVLen, Equals, 128)
+	c.Assert(keys[0].Auth, IsNil)
+	c.Assert(keys[0].Crypt, IsNil)
+
+	c.Assert(keys[1].Spi, Equals, 2)
+	c.Assert(keys[1].Aead, IsNil)
+	c.Assert(keys[1].Auth.Name, Equals, "hmac(sha256)")
+	c.Assert(keys[1].Auth.TruncateLen, Equals, 128)
+	c.Assert(keys[1].Crypt.Name, Equals, "cbc(aes)")
+	c.Assert(len(keys[1].Crypt.Key), Equals, 16)
+
+	c.Assert(keys[0].Equals(keys[0]), Equals, true)
+	c.Assert(keys[0].Equals(keys[1]), Equals, false)
+
+	invalid := []string{
+		"",
+		"# no keys",
+		"3 rfc4106(gcm(aes)) 0102",
+		"0 rfc4106(gcm(aes)) 0102 128",
+		"3 rfc4106(gcm(aes)) xyz 128",
+		"3 rfc4106(gcm(aes)) 0102 abc",
+		"3 hmac(foo) 0102 cbc(aes) 0102",
+		"3 rfc4106(gcm(aes)) 0102 128\n3 rfc4106(gcm(aes)) 0304 128",
+	}
+	for _, input := range invalid {
+		_, err := ParseKeys(strings.NewReader(input))
+		c.Assert(err, Not(IsNil), Commentf("input %q", input))
+	}
+}
+
+func (s *IPsecSuite) TestPolicies(c *C) {
+	_, localCIDR, _ := net.ParseCIDR("10.1.0.0/16")
+	_, remoteCIDR, _ := net.ParseCIDR("10.2.0.0/16")
+	peer := &Peer{
+		LocalIP:    net.ParseIP("192.168.0.1"),
+		RemoteIP:   net.ParseIP("192.168.0.2"),
+		LocalCIDR:  localCIDR,
+		RemoteCIDR: remoteCIDR,
+	}
+
+	// Direct routing, the endpoint traffic is encrypted in tunnel mode
+	pols := policies(peer, 3)
+	c.Assert(len(pols), Equals, 3)
+	c.Assert(pols[0].Dir, Equals, netlink.XFRM_DIR_OUT)
+	c.Assert(pols[0].Src, Equals, localCIDR)
+	c.Assert(pols[0].Dst, Equals, remoteCIDR)
+	c.Assert(pols[0].Tmpls[0].Mode, Equals, netlink.XFRM_MODE_TUNNEL)
+	c.Assert(pols[0].Tmpls[0].Spi, Equals, 3)
+	c.Assert(pols[0].Tmpls[0].Dst.Equal(peer.RemoteIP), Equals, true)
+	for _, policy := range pols[1:] {
+		c.Assert(policy.Src, Equals, remoteCIDR)
+		c.Assert(policy.Dst, Equals, localCIDR)
+		c.Assert(policy.Tmpls[0].Spi, Equals, 0)
+		c.Assert(policy.Tmpls[0].Src.Equal(net.IPv4zero), Equals, true)
+	}
+	c.Assert(pols[1].Dir, Equals, netlink.XFRM_DIR_IN)
+	c.Assert(pols[2].Dir, Equals, netlink.XFRM_DIR_FWD)
+
+	// Overlay, the overlay traffic is encrypted in transport mode
+	peer = &Peer{
+		LocalIP:   net.ParseIP("192.168.0.1"),
+		RemoteIP:  net.ParseIP("192.168.0.2"),
+		EncapPort: 8472,
+	}
+	pols = policies(peer, 3)
+	c.Assert(len(pols), Equals, 2)
+	c.Assert(pols[0].Dir, Equals, netlink.XFRM_DIR_OUT)
+	c.Assert(pols[0].Src.String(), Equals, "192.168.0.1/32")
+	c.Assert(pols[0].Dst.String(), Equals, "192.168.0.2/32")
+	c.Assert(pols[1].Dir, Equals, netlink.XFRM_DIR_IN)
+	c.Assert(pols[1].Src.String(), Equals, "192.168.0.2/32")
+	c.Assert(pols[1].Dst.String(), Equals, "192.168.0.1/32")
+	for _, policy := range pols {
+		c.Assert(policy.Proto, Equals, netlink.Proto(syscall.IPPROTO_UDP))
+		c.Assert(policy.DstPort, Equals, 8472)
+		c.Assert(policy.Tmpls[0].Mode, Equals, netlink.XFRM_MODE_TRANSPORT)
+	}
+}
+
+func (s *IPsecSuite) TestPeerEquals(c *C) {
+	_, cidr, _ := net.ParseCIDR("10.1.0.0/16")
+	p1 := &Peer{LocalIP: net.ParseIP("192.168.0.1"), RemoteIP: net.ParseIP("192.168.0.2"), RemoteCIDR: cidr}
+	p2 := &Peer{LocalIP: net.ParseIP("192.168.0.1").To4(), RemoteIP: net.ParseIP("192.168.0.2"), RemoteCIDR: cidr}
+	c.Assert(p1.Equals(p2), Equals, true)
+
+	p2.EncapPort = 8472
+	c.Assert(p1.Equals(p2), Equals, false)
+
+	p2.EncapPort = 0
+	p2.RemoteCIDR = nil
+	c.Assert(p1.Equals(p2), Equals, false)
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Key is a key used to encrypt and decrypt the traffic between nodes
+type Key struct {
+	// Spi is the security parameter index identifying the key in ESP
+	// packets
+	Spi int
+
+	// Aead is the algorithm for authenticated encryption, if set Auth and
+	// Crypt are unset
+	Aead *netlink.XfrmStateAlgo
+
+	// Auth is the algorithm used for authentication
+	Auth *netlink.XfrmStateAlgo
+
+	// Crypt is the algorithm used for encryption
+	Crypt *netlink.XfrmStateAlgo
+}
+
+// Equals returns true if both keys are the same
+func (k *Key) Equals(o *Key) bool {
+	return k.Spi == o.Spi &&
+		algoEquals(k.Aead, o.Aead) &&
+		algoEquals(k.Auth, o.Auth) &&
+		algoEquals(k.Crypt, o.Crypt)
+}
+
+func algoEquals(a, b *netlink.XfrmStateAlgo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name && string(a.Key) == string(b.Key) &&
+		a.TruncateLen == b.TruncateLen && a.ICVLen == b.ICVLen
+}
+
+// authTruncateLen is the truncation length in bits of the ICV of the
+// supported authentication algorithms, as defined by the RFC of each algorithm
+var authTruncateLen = map[string]int{
+	"hmac(md5)":    96,
+	"hmac(sha1)":   96,
+	"hmac(sha256)": 128,
+	"hmac(sha384)": 192,
+	"hmac(sha512)": 256,
+}
+
+func decodeKey(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// parseKey parses a single key in one of the following formats:
+//
+//	<spi> <aead-algo> <aead-key> <icv-len>
+//	<spi> <auth-algo> <auth-key> <crypt-algo> <crypt-key>
+func parseKey(line string) (*Key, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 && len(fields) != 5 {
+		return nil, fmt.Errorf("expected 4 or 5 fields, got %d", len(fields))
+	}
+
+	spi, err := strconv.ParseUint(fields[0], 0, 32)
+	if err != nil || spi == 0 {
+		return nil, fmt.Errorf("invalid SPI %q, must be a non-zero 32 bit integer", fields[0])
+	}
+	key := &Key{Spi: int(spi)}
+
+	algoKey, err := decodeKey(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key for %s: %s", fields[1], err)
+	}
+
+	if len(fields) == 4 {
+		icvLen, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ICV length %q: %s", fields[3], err)
+		}
+		key.Aead = &netlink.XfrmStateAlgo{Name: fields[1], Key: algoKey, ICVLen: icvLen}
+		return key, nil
+	}
+
+	truncateLen, ok := authTruncateLen[fields[1]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported authentication algorithm %s", fields[1])
+	}
+	cryptKey, err := decodeKey(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key for %s: %s", fields[3], err)
+	}
+	key.Auth = &netlink.XfrmStateAlgo{Name: fields[1], Key: algoKey, TruncateLen: truncateLen}
+	key.Crypt = &netlink.XfrmStateAlgo{Name: fields[3], Key: cryptKey}
+	return key, nil
+}
+
+// ParseKeys parses the keys in r, one key per line. Empty lines and lines
+// starting with '#' are ignored. The SPI of each key must be unique.
+func ParseKeys(r io.Reader) ([]*Key, error) {
+	keys := []*Key{}
+	spis := map[int]struct{}{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := parseKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		if _, ok := spis[key.Spi]; ok {
+			return nil, fmt.Errorf("line %d: duplicate SPI %d", n, key.Spi)
+		}
+		spis[key.Spi] = struct{}{}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found")
+	}
+	return keys, nil
+}
+
+// LoadKeys parses the keys stored in the file at path, see ParseKeys()
+func LoadKeys(path string) ([]*Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys, err := ParseKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse IPsec keys in %s: %s", path, err)
+	}
+	return keys, nil
+}
@@ -130,3 +130,12 @@ func ReloadDatapath(ctx context.Context, ep endpoint) error {
 	}
 	return reloadDatapath(ctx, ep, &dirs)
 }
+
+// RemoveDatapath removes the BPF datapath program from the interface ifName.
+//
+// Interfaces created by Cilium are removed together with the endpoint, this
+// is only required for interfaces which outlive the endpoint such as the
+// interfaces of another CNI plugin Cilium is chained to.
+func RemoveDatapath(ifName string) error {
+	return removeDatapath(ifName)
+}
@@ -17,11 +17,13 @@ package loader
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/cilium/cilium/pkg/bpf"
 	"github.com/cilium/cilium/pkg/command/exec"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -95,3 +97,32 @@ func replaceDatapath(ctx context.Context, ifName string, objPath string, progSec
 
 	return nil
 }
+
+// removeDatapath removes the BPF program attached by replaceDatapath from
+// the ingress of ifName. The clsact qdisc is left in place as it may be
+// shared with filters of other users of the interface. Nothing is done if
+// the interface no longer exists.
+func removeDatapath(ifName string) error {
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Handle:    1,
+			Priority:  1,
+			Protocol:  unix.ETH_P_ALL,
+		},
+	}
+	if err := netlink.FilterDel(filter); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to remove tc filter: %s", err)
+	}
+
+	return nil
+}
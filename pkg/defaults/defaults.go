@@ -15,6 +15,8 @@
 package defaults
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -85,4 +87,32 @@ const (
 
 	// ToFQDNsMinTTL is the default lower bound for TTLs used with ToFQDNs rules.
 	ToFQDNsMinTTL = 365 * 86400 // 1 year in seconds
+
+	// ToFQDNsMinTTLProxy is the default lower bound for TTLs used with
+	// ToFQDNs rules when DNS data is only learned from the DNS proxy.
+	ToFQDNsMinTTLProxy = 3600 // 1 hour in seconds
+
+	// ToFQDNsEnablePoller is the default for whether the agent polls DNS
+	// names referenced by ToFQDNs rules.
+	ToFQDNsEnablePoller = true
+
+	// ToFQDNsProxyPortAutoAllocate is the default port of the DNS proxy, 0
+	// allocates a port from the proxy port range.
+	ToFQDNsProxyPortAutoAllocate = 0
+
+	// ToFQDNsProxyResponseTimeout is the maximum time a DNS response is held
+	// back by the DNS proxy while the policy update it triggers is applied.
+	// If the update is not applied in time, the request is answered with
+	// SERVFAIL instead.
+	ToFQDNsProxyResponseTimeout = 2 * time.Second
+
+	// LBBackendDrainTimeout is the default maximum time a terminating
+	// service backend keeps serving existing connections
+	LBBackendDrainTimeout = 5 * time.Minute
+
+	// TunnelPortVXLAN is the UDP port of the VXLAN overlay network
+	TunnelPortVXLAN = 8472
+
+	// TunnelPortGeneve is the UDP port of the Geneve overlay network
+	TunnelPortGeneve = 6081
 )
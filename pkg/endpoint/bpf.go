@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
@@ -150,7 +151,14 @@ func (e *Endpoint) writeHeaderfile(prefix string, owner Owner) error {
 	fw.WriteString(" */\n\n")
 
 	fw.WriteString(common.FmtDefineAddress("LXC_MAC", e.LXCMAC))
-	fw.WriteString(common.FmtDefineComma("LXC_IP", e.IPv6))
+	// Endpoints without an IPv6 address, e.g. chained endpoints of an
+	// IPv4-only plugin, use the unspecified address which the datapath
+	// never accepts as source address
+	if e.IPv6 != nil {
+		fw.WriteString(common.FmtDefineComma("LXC_IP", e.IPv6))
+	} else {
+		fw.WriteString(common.FmtDefineComma("LXC_IP", net.IPv6zero))
+	}
 	if e.IPv4 != nil {
 		fmt.Fprintf(fw, "#define LXC_IPV4 %#x\n", byteorder.HostSliceToNetwork(e.IPv4, reflect.Uint32))
 	}
@@ -292,6 +300,10 @@ func (e *Endpoint) addNewRedirectsFromMap(owner Owner, m policy.L4PolicyMap, des
 			}
 			keysFromFilter := e.convertL4FilterToPolicyMapKeys(&l4, direction)
 			for _, keyFromFilter := range keysFromFilter {
+				// Deny entries take precedence over redirects.
+				if entry, ok := e.desiredMapState[keyFromFilter]; ok && entry.IsDeny {
+					continue
+				}
 				e.desiredMapState[keyFromFilter] = PolicyMapStateEntry{ProxyPort: redirectPort}
 			}
 		}
@@ -37,44 +37,75 @@ func IPv4Gateway(addr *models.NodeAddressing) string {
 
 // IPv6Routes returns IPv6 routes to be installed in endpoint's networking namespace.
 func IPv6Routes(addr *models.NodeAddressing, linkMTU int) ([]route.Route, error) {
+	return IPv6PoolRoutes(addr, linkMTU, nil)
+}
+
+// IPv4Routes returns IPv4 routes to be installed in endpoint's networking namespace.
+func IPv4Routes(addr *models.NodeAddressing, linkMTU int) ([]route.Route, error) {
+	return IPv4PoolRoutes(addr, linkMTU, nil)
+}
+
+// IPv6PoolRoutes returns IPv6 routes to be installed in the networking
+// namespace of an endpoint with an address of an IPAM pool. The IPv6 prefixes
+// of the pool routes are routed via the host instead of a default route. A
+// default route is used if the pool has no IPv6 routes.
+func IPv6PoolRoutes(addr *models.NodeAddressing, linkMTU int, prefixes []string) ([]route.Route, error) {
 	ip := net.ParseIP(addr.IPV6.IP)
 	if ip == nil {
 		return []route.Route{}, fmt.Errorf("Invalid IP address: %s", addr.IPV6.IP)
 	}
-	return []route.Route{
-		{
-			Prefix: net.IPNet{
-				IP:   ip,
-				Mask: defaults.ContainerIPv6Mask,
-			},
-		},
-		{
-			Prefix:  defaults.IPv6DefaultRoute,
-			Nexthop: &ip,
-			MTU:     linkMTU,
-		},
-	}, nil
+	return hostRoutes(ip, defaults.ContainerIPv6Mask, defaults.IPv6DefaultRoute, linkMTU, prefixes)
 }
 
-// IPv4Routes returns IPv4 routes to be installed in endpoint's networking namespace.
-func IPv4Routes(addr *models.NodeAddressing, linkMTU int) ([]route.Route, error) {
+// IPv4PoolRoutes returns IPv4 routes to be installed in the networking
+// namespace of an endpoint with an address of an IPAM pool. The IPv4 prefixes
+// of the pool routes are routed via the host instead of a default route. A
+// default route is used if the pool has no IPv4 routes.
+func IPv4PoolRoutes(addr *models.NodeAddressing, linkMTU int, prefixes []string) ([]route.Route, error) {
 	ip := net.ParseIP(addr.IPV4.IP)
 	if ip == nil {
 		return []route.Route{}, fmt.Errorf("Invalid IP address: %s", addr.IPV4.IP)
 	}
-	return []route.Route{
+	return hostRoutes(ip, defaults.ContainerIPv4Mask, defaults.IPv4DefaultRoute, linkMTU, prefixes)
+}
+
+// hostRoutes returns a route to the host IP ip and routes via ip to all
+// prefixes of the address family of ip, or to defaultRoute if there are none
+func hostRoutes(ip net.IP, mask net.IPMask, defaultRoute net.IPNet, linkMTU int, prefixes []string) ([]route.Route, error) {
+	routes := []route.Route{
 		{
 			Prefix: net.IPNet{
 				IP:   ip,
-				Mask: defaults.ContainerIPv4Mask,
+				Mask: mask,
 			},
 		},
-		{
-			Prefix:  defaults.IPv4DefaultRoute,
+	}
+
+	isIPv4 := ip.To4() != nil
+	for _, p := range prefixes {
+		_, prefix, err := net.ParseCIDR(p)
+		if err != nil {
+			return []route.Route{}, fmt.Errorf("Invalid route prefix: %s", p)
+		}
+		if (prefix.IP.To4() != nil) != isIPv4 {
+			continue
+		}
+		routes = append(routes, route.Route{
+			Prefix:  *prefix,
 			Nexthop: &ip,
 			MTU:     linkMTU,
-		},
-	}, nil
+		})
+	}
+
+	if len(routes) == 1 {
+		routes = append(routes, route.Route{
+			Prefix:  defaultRoute,
+			Nexthop: &ip,
+			MTU:     linkMTU,
+		})
+	}
+
+	return routes, nil
 }
 
 // SufficientAddressing returns an error if the provided NodeAddressing does
@@ -272,6 +272,10 @@ type PolicyMapStateEntry struct {
 	// If 0 (default), there is no proxy redirection for the corresponding
 	// PolicyKey.
 	ProxyPort uint16
+
+	// IsDeny is true if the traffic matching the corresponding PolicyKey
+	// is denied. Deny entries never have a ProxyPort.
+	IsDeny bool
 }
 
 // Endpoint represents a container or similar which can be individually
@@ -316,6 +320,11 @@ type Endpoint struct {
 	// IfIndex is the interface index of the host face interface (veth pair)
 	IfIndex int
 
+	// Chained is true if the interface and addresses of the endpoint are
+	// managed by another CNI plugin Cilium is chained to. The addresses of
+	// a chained endpoint are not allocated by IPAM.
+	Chained bool
+
 	// OpLabels is the endpoint's label configuration
 	//
 	// FIXME: Rename this field to Labels
@@ -716,6 +725,7 @@ func NewEndpointFromChangeModel(base *models.EndpointChangeRequest) (*Endpoint,
 		DockerEndpointID: base.DockerEndpointID,
 		IfName:           base.InterfaceName,
 		IfIndex:          int(base.InterfaceIndex),
+		Chained:          base.Chained,
 		OpLabels: pkgLabels.OpLabels{
 			Custom:                pkgLabels.Labels{},
 			Disabled:              pkgLabels.Labels{},
@@ -1296,8 +1306,8 @@ func (e *Endpoint) Allows(id identityPkg.NumericIdentity) bool {
 		TrafficDirection: policymap.Ingress.Uint8(),
 	}
 
-	_, ok := e.desiredMapState[keyToLookup]
-	return ok
+	entry, ok := e.desiredMapState[keyToLookup]
+	return ok && !entry.IsDeny
 }
 
 // String returns endpoint on a JSON format.
@@ -1487,14 +1497,15 @@ func (e *Endpoint) RemoveFromGlobalPolicyMap() error {
 // GetBPFKeys returns all keys which should represent this endpoint in the BPF
 // endpoints map
 func (e *Endpoint) GetBPFKeys() []*lxcmap.EndpointKey {
-	key := lxcmap.NewEndpointKey(e.IPv6.IP())
-
+	keys := []*lxcmap.EndpointKey{}
+	if e.IPv6 != nil {
+		keys = append(keys, lxcmap.NewEndpointKey(e.IPv6.IP()))
+	}
 	if e.IPv4 != nil {
-		key4 := lxcmap.NewEndpointKey(e.IPv4.IP())
-		return []*lxcmap.EndpointKey{key, key4}
+		keys = append(keys, lxcmap.NewEndpointKey(e.IPv4.IP()))
 	}
 
-	return []*lxcmap.EndpointKey{key}
+	return keys
 }
 
 // GetBPFValue returns the value which should represent this endpoint in the
@@ -2220,6 +2231,9 @@ func (e *Endpoint) UpdateProxyStatistics(l7Protocol string, port uint16, ingress
 	case accesslog.VerdictDenied:
 		stats.Denied++
 		metrics.ProxyDenied.Inc()
+	case accesslog.VerdictRateLimited:
+		stats.Denied++
+		metrics.ProxyRateLimited.Inc()
 	case accesslog.VerdictError:
 		stats.Error++
 		metrics.ProxyParseErrors.Inc()
@@ -2643,7 +2657,12 @@ func (e *Endpoint) syncPolicyMap() error {
 
 	for keyToAdd, entry := range e.desiredMapState {
 		if oldEntry, ok := e.realizedMapState[keyToAdd]; !ok || oldEntry != entry {
-			err := e.PolicyMap.AllowKey(keyToAdd, entry.ProxyPort)
+			var err error
+			if entry.IsDeny {
+				err = e.PolicyMap.DenyKey(keyToAdd)
+			} else {
+				err = e.PolicyMap.AllowKey(keyToAdd, entry.ProxyPort)
+			}
 			if err != nil {
 				e.getLogger().WithError(err).Errorf("Failed to add PolicyMap key %s %d", keyToAdd.String(), entry.ProxyPort)
 				errors = append(errors, err)
@@ -27,6 +27,7 @@ import (
 	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
 	pkgLabels "github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/maps/lxcmap"
 	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/policy/api"
 
@@ -57,6 +58,23 @@ func (s *EndpointSuite) TestEndpointID(c *C) {
 	c.Assert(bytes.Compare(e.IPv4, IPv4Addr) == 0, Equals, true)
 }
 
+func (s *EndpointSuite) TestGetBPFKeys(c *C) {
+	e := Endpoint{
+		IPv6: IPv6Addr,
+		IPv4: IPv4Addr,
+	}
+	c.Assert(e.GetBPFKeys(), DeepEquals, []*lxcmap.EndpointKey{
+		lxcmap.NewEndpointKey(IPv6Addr.IP()),
+		lxcmap.NewEndpointKey(IPv4Addr.IP()),
+	})
+
+	// Chained endpoints may have no IPv6 address
+	e.IPv6 = nil
+	c.Assert(e.GetBPFKeys(), DeepEquals, []*lxcmap.EndpointKey{
+		lxcmap.NewEndpointKey(IPv4Addr.IP()),
+	})
+}
+
 func (s *EndpointSuite) TestOrderEndpointAsc(c *C) {
 	eps := []*models.Endpoint{
 		{ID: 5},
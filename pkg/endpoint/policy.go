@@ -221,6 +221,7 @@ func getLabelsMap() (*identityPkg.IdentityCache, error) {
 // Must be called with global endpoint.Mutex held.
 func (e *Endpoint) resolveL4Policy(repo *policy.Repository) (policyChanged bool, err error) {
 	var newL4IngressPolicy, newL4EgressPolicy *policy.L4PolicyMap
+	var newL4IngressDenyPolicy, newL4EgressDenyPolicy *policy.L4PolicyMap
 
 	ingressCtx := policy.SearchContext{
 		To: e.SecurityIdentity.LabelArray,
@@ -240,11 +241,13 @@ func (e *Endpoint) resolveL4Policy(repo *policy.Repository) (policyChanged bool,
 	// to check if policy applies.
 	if !e.ingressPolicyEnabled {
 		newL4IngressPolicy = &policy.L4PolicyMap{}
+		newL4IngressDenyPolicy = &policy.L4PolicyMap{}
 	} else {
 		newL4IngressPolicy, err = repo.ResolveL4IngressPolicy(&ingressCtx)
 		if err != nil {
 			return
 		}
+		newL4IngressDenyPolicy = repo.ResolveL4IngressDenyPolicy(&ingressCtx)
 	}
 
 	// egressPolicy encodes whether any rules select this endpoint at all on
@@ -252,15 +255,19 @@ func (e *Endpoint) resolveL4Policy(repo *policy.Repository) (policyChanged bool,
 	// to check if policy applies.
 	if !e.egressPolicyEnabled {
 		newL4EgressPolicy = &policy.L4PolicyMap{}
+		newL4EgressDenyPolicy = &policy.L4PolicyMap{}
 	} else {
 		newL4EgressPolicy, err = repo.ResolveL4EgressPolicy(&egressCtx)
 		if err != nil {
 			return
 		}
+		newL4EgressDenyPolicy = repo.ResolveL4EgressDenyPolicy(&egressCtx)
 	}
 
 	newL4Policy := &policy.L4Policy{Ingress: *newL4IngressPolicy,
-		Egress: *newL4EgressPolicy}
+		Egress:      *newL4EgressPolicy,
+		IngressDeny: *newL4IngressDenyPolicy,
+		EgressDeny:  *newL4EgressDenyPolicy}
 
 	if !reflect.DeepEqual(e.DesiredL4Policy, newL4Policy) {
 		policyChanged = true
@@ -276,9 +283,41 @@ func (e *Endpoint) computeDesiredPolicyMapState(repo *policy.Repository) {
 	e.determineAllowLocalhost(desiredPolicyKeys)
 	e.determineAllowFromWorld(desiredPolicyKeys)
 	e.computeDesiredL3PolicyMapEntries(repo, desiredPolicyKeys)
+	e.computeDesiredDenyPolicyMapEntries(desiredPolicyKeys)
 	e.desiredMapState = desiredPolicyKeys
 }
 
+// computeDesiredDenyPolicyMapEntries inserts the deny entries of the desired
+// L4 policy into desiredPolicyKeys, replacing any allow entries for the same
+// keys. A deny filter for port 0 denies all traffic with the selected
+// identities, so all allow entries for those identities are replaced by a
+// single L3 deny entry.
+//
+// This must be run after all allow entries have been computed.
+func (e *Endpoint) computeDesiredDenyPolicyMapEntries(desiredPolicyKeys PolicyMapState) {
+	if e.DesiredL4Policy == nil {
+		return
+	}
+
+	denyFilters := func(filters policy.L4PolicyMap, direction policymap.TrafficDirection) {
+		for _, filter := range filters {
+			for _, key := range e.convertL4FilterToPolicyMapKeys(&filter, direction) {
+				if key.DestPort == 0 {
+					for k := range desiredPolicyKeys {
+						if k.Identity == key.Identity && k.TrafficDirection == key.TrafficDirection {
+							delete(desiredPolicyKeys, k)
+						}
+					}
+				}
+				desiredPolicyKeys[key] = PolicyMapStateEntry{IsDeny: true}
+			}
+		}
+	}
+
+	denyFilters(e.DesiredL4Policy.IngressDeny, policymap.Ingress)
+	denyFilters(e.DesiredL4Policy.EgressDeny, policymap.Egress)
+}
+
 // determineAllowLocalhost determines whether endpoint should be allowed to
 // communicate with the localhost. It inserts the PolicyKey corresponding to
 // the localhost in the desiredPolicyKeys if the endpoint is allowed to
@@ -1013,8 +1052,12 @@ func (e *Endpoint) SetIdentity(identity *identityPkg.Identity) {
 
 	// Whenever the identity is updated, propagate change to key-value store
 	// of IP to identity mapping.
-	e.runIPIdentitySync(e.IPv4)
-	e.runIPIdentitySync(e.IPv6)
+	if e.IPv4 != nil {
+		e.runIPIdentitySync(e.IPv4)
+	}
+	if e.IPv6 != nil {
+		e.runIPIdentitySync(e.IPv6)
+	}
 
 	e.getLogger().WithFields(logrus.Fields{
 		logfields.Identity:       identity.StringID(),
@@ -0,0 +1,74 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	identityPkg "github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/maps/policymap"
+	"github.com/cilium/cilium/pkg/policy"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *EndpointSuite) TestComputeDesiredDenyPolicyMapEntries(c *C) {
+	fooID := identityPkg.NumericIdentity(1000)
+	barID := identityPkg.NumericIdentity(1001)
+	identityCache := identityPkg.IdentityCache{
+		fooID: labels.ParseSelectLabelArray("foo"),
+		barID: labels.ParseSelectLabelArray("bar"),
+	}
+
+	selFoo := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	selBar := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	e := &Endpoint{
+		prevIdentityCache: &identityCache,
+		DesiredL4Policy: &policy.L4Policy{
+			IngressDeny: policy.L4PolicyMap{
+				"80/TCP": policy.CreateL4DenyFilter(api.EndpointSelectorSlice{selFoo},
+					api.PortProtocol{Port: "80"}, api.ProtoTCP, nil, true),
+				"0/ANY": policy.CreateL4DenyFilter(api.EndpointSelectorSlice{selBar},
+					api.PortProtocol{Port: "0"}, api.ProtoAny, nil, true),
+			},
+		},
+	}
+
+	fooL3Key := policymap.PolicyKey{Identity: fooID.Uint32(), TrafficDirection: policymap.Ingress.Uint8()}
+	fooL4Key := policymap.PolicyKey{Identity: fooID.Uint32(), DestPort: 80, Nexthdr: 6, TrafficDirection: policymap.Ingress.Uint8()}
+	barL3Key := policymap.PolicyKey{Identity: barID.Uint32(), TrafficDirection: policymap.Ingress.Uint8()}
+	barL4Key := policymap.PolicyKey{Identity: barID.Uint32(), DestPort: 443, Nexthdr: 6, TrafficDirection: policymap.Ingress.Uint8()}
+	barEgressKey := policymap.PolicyKey{Identity: barID.Uint32(), TrafficDirection: policymap.Egress.Uint8()}
+
+	keys := PolicyMapState{
+		fooL3Key:     {},
+		fooL4Key:     {ProxyPort: 4242},
+		barL3Key:     {},
+		barL4Key:     {},
+		barEgressKey: {},
+	}
+	e.computeDesiredDenyPolicyMapEntries(keys)
+
+	c.Assert(keys, DeepEquals, PolicyMapState{
+		// The L3 allow for foo remains, the deny on port 80 overrides
+		// the redirect for the same key.
+		fooL3Key: {},
+		fooL4Key: {IsDeny: true},
+		// All ingress entries for bar are replaced by a single L3 deny.
+		barL3Key:     {IsDeny: true},
+		barEgressKey: {},
+	})
+}
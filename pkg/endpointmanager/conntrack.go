@@ -16,6 +16,7 @@ package endpointmanager
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/cilium/cilium/pkg/endpoint"
@@ -71,6 +72,41 @@ func runGC(e *endpoint.Endpoint, ipv4, ipv6 bool, filter *ctmap.GCFilter) {
 	}
 }
 
+// ServiceSlavesInUse returns the slaves of the service with the given
+// frontend which are referenced by unexpired entries of the global CT maps or
+// of the local CT maps of any endpoint.
+func ServiceSlavesInUse(frontendIP net.IP, frontendPort uint16) map[uint16]struct{} {
+	ipv4 := frontendIP.To4() != nil
+	maps := ctmap.GlobalMaps(ipv4, !ipv4)
+	for _, e := range GetEndpoints() {
+		if e.ConntrackLocal() {
+			maps = append(maps, ctmap.LocalMaps(e, ipv4, !ipv4)...)
+		}
+	}
+
+	slaves := map[uint16]struct{}{}
+	for _, m := range maps {
+		path, err := m.Path()
+		if err == nil {
+			err = m.Open()
+		}
+		if err != nil {
+			log.WithError(err).WithField(logfields.Path, path).Debug("Unable to open map")
+			continue
+		}
+
+		inUse, err := ctmap.ServiceSlavesInUse(m, frontendIP, frontendPort)
+		m.Close()
+		if err != nil {
+			log.WithError(err).WithField(logfields.Path, path).Warn("Unable to dump CT map")
+		}
+		for slave := range inUse {
+			slaves[slave] = struct{}{}
+		}
+	}
+	return slaves
+}
+
 func createGCFilter(initialScan bool, restoredEndpoints []*endpoint.Endpoint) *ctmap.GCFilter {
 	filter := &ctmap.GCFilter{
 		RemoveExpired: true,
@@ -139,6 +139,14 @@ func LookupIPv4(ipv4 string) *endpoint.Endpoint {
 	return ep
 }
 
+// LookupIPv6 looks up endpoint by IPv6 address
+func LookupIPv6(ipv6 string) *endpoint.Endpoint {
+	mutex.RLock()
+	ep := lookupIPv6(ipv6)
+	mutex.RUnlock()
+	return ep
+}
+
 // LookupPodName looks up endpoint by namespace + pod name
 func LookupPodName(name string) *endpoint.Endpoint {
 	mutex.RLock()
@@ -175,6 +183,10 @@ func Remove(ep *endpoint.Endpoint) {
 		delete(endpointsAux, endpointid.NewID(endpointid.IPv4Prefix, ep.IPv4.String()))
 	}
 
+	if ep.IPv6.String() != "" {
+		delete(endpointsAux, endpointid.NewID(endpointid.IPv6Prefix, ep.IPv6.String()))
+	}
+
 	if ep.ContainerName != "" {
 		delete(endpointsAux, endpointid.NewID(endpointid.ContainerNamePrefix, ep.ContainerName))
 	}
@@ -228,6 +240,13 @@ func lookupIPv4(ipv4 string) *endpoint.Endpoint {
 	return nil
 }
 
+func lookupIPv6(ipv6 string) *endpoint.Endpoint {
+	if ep, ok := endpointsAux[endpointid.NewID(endpointid.IPv6Prefix, ipv6)]; ok {
+		return ep
+	}
+	return nil
+}
+
 func lookupDockerID(id string) *endpoint.Endpoint {
 	if ep, ok := endpointsAux[endpointid.NewID(endpointid.ContainerIdPrefix, id)]; ok {
 		return ep
@@ -254,6 +273,10 @@ func updateReferences(ep *endpoint.Endpoint) {
 		endpointsAux[endpointid.NewID(endpointid.IPv4Prefix, ep.IPv4.String())] = ep
 	}
 
+	if ep.IPv6.String() != "" {
+		endpointsAux[endpointid.NewID(endpointid.IPv6Prefix, ep.IPv6.String())] = ep
+	}
+
 	if ep.ContainerName != "" {
 		endpointsAux[endpointid.NewID(endpointid.ContainerNamePrefix, ep.ContainerName)] = ep
 	}
@@ -128,18 +128,69 @@ func (s *accessLogServer) accessLogger(conn *net.UnixConn) {
 	}
 }
 
+// gRPC status codes logged for failed gRPC calls
+const (
+	grpcStatusUnknown           = 2
+	grpcStatusPermissionDenied  = 7
+	grpcStatusResourceExhausted = 8
+	grpcStatusUnimplemented     = 12
+	grpcStatusInternal          = 13
+	grpcStatusUnavailable       = 14
+	grpcStatusUnauthenticated   = 16
+)
+
+// setGRPCFields fills in the gRPC service, method and status of the record if
+// the request is a gRPC call. Denied calls are answered with the
+// PERMISSION_DENIED status and rate limited calls with the RESOURCE_EXHAUSTED
+// status, the status of other failed calls is derived from the HTTP status code
+// as specified by the gRPC protocol.
+func setGRPCFields(record *accesslog.LogRecordHTTP, verdict accesslog.FlowVerdict) {
+	if record.URL == nil || !strings.HasPrefix(record.Headers.Get("Content-Type"), "application/grpc") {
+		return
+	}
+
+	// The path of gRPC calls is "/<service>/<method>"
+	parts := strings.Split(record.URL.Path, "/")
+	if len(parts) != 3 || parts[0] != "" {
+		return
+	}
+	record.GRPCService, record.GRPCMethod = parts[1], parts[2]
+
+	switch {
+	case verdict == accesslog.VerdictDenied:
+		record.GRPCStatus = grpcStatusPermissionDenied
+	case verdict == accesslog.VerdictRateLimited:
+		record.GRPCStatus = grpcStatusResourceExhausted
+	case record.Code == 0 || record.Code == 200:
+	case record.Code == 400:
+		record.GRPCStatus = grpcStatusInternal
+	case record.Code == 401:
+		record.GRPCStatus = grpcStatusUnauthenticated
+	case record.Code == 403:
+		record.GRPCStatus = grpcStatusPermissionDenied
+	case record.Code == 404:
+		record.GRPCStatus = grpcStatusUnimplemented
+	case record.Code == 429, record.Code == 502, record.Code == 503, record.Code == 504:
+		record.GRPCStatus = grpcStatusUnavailable
+	default:
+		record.GRPCStatus = grpcStatusUnknown
+	}
+}
+
 func (s *accessLogServer) logRecord(localEndpoint logger.EndpointUpdater, pblog *cilium.LogEntry) {
 	// TODO: Support Kafka.
 
 	var l7tags logger.LogTag
 	if http := pblog.GetHttp(); http != nil {
-		l7tags = logger.LogTags.HTTP(&accesslog.LogRecordHTTP{
+		record := &accesslog.LogRecordHTTP{
 			Method:   http.Method,
 			Code:     int(http.Status),
 			URL:      http.ParseURL(),
 			Protocol: http.GetProtocol(),
 			Headers:  http.GetNetHttpHeaders(),
-		})
+		}
+		setGRPCFields(record, pblog.GetVerdict())
+		l7tags = logger.LogTags.HTTP(record)
 	} else if l7 := pblog.GetGenericL7(); l7 != nil {
 		l7tags = logger.LogTags.L7(&accesslog.LogRecordL7{
 			Proto:  l7.GetProto(),
@@ -164,6 +215,9 @@ func (s *accessLogServer) logRecord(localEndpoint logger.EndpointUpdater, pblog
 			DstIPPort:   pblog.DestinationAddress,
 			SrcIdentity: pblog.SourceSecurityId,
 		}), l7tags)
+	if duration := pblog.GetHttp().GetDuration(); duration > 0 {
+		r.ApplyTags(logger.LogTags.Duration(time.Duration(duration)))
+	}
 
 	r.Log()
 
@@ -15,7 +15,11 @@
 package envoy
 
 import (
+	"net/http"
+	"net/url"
+
 	"github.com/cilium/cilium/pkg/envoy/cilium"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
 
 	. "gopkg.in/check.v1"
 )
@@ -38,3 +42,40 @@ func (k *AccessLogServerSuite) TestParseURL(c *C) {
 		c.Assert(u.Path, Equals, "/foo")
 	}
 }
+
+func (k *AccessLogServerSuite) TestSetGRPCFields(c *C) {
+	grpcHeaders := http.Header{"Content-Type": []string{"application/grpc+proto"}}
+	record := &accesslog.LogRecordHTTP{
+		Code:    200,
+		URL:     &url.URL{Path: "/helloworld.Greeter/SayHello"},
+		Headers: grpcHeaders,
+	}
+	setGRPCFields(record, accesslog.VerdictForwarded)
+	c.Assert(record.GRPCService, Equals, "helloworld.Greeter")
+	c.Assert(record.GRPCMethod, Equals, "SayHello")
+	c.Assert(record.GRPCStatus, Equals, 0)
+
+	setGRPCFields(record, accesslog.VerdictDenied)
+	c.Assert(record.GRPCStatus, Equals, grpcStatusPermissionDenied)
+
+	setGRPCFields(record, accesslog.VerdictRateLimited)
+	c.Assert(record.GRPCStatus, Equals, grpcStatusResourceExhausted)
+
+	record = &accesslog.LogRecordHTTP{
+		Code:    503,
+		URL:     &url.URL{Path: "/helloworld.Greeter/SayHello"},
+		Headers: grpcHeaders,
+	}
+	setGRPCFields(record, accesslog.VerdictForwarded)
+	c.Assert(record.GRPCStatus, Equals, grpcStatusUnavailable)
+
+	// Requests other than gRPC calls are left untouched
+	record = &accesslog.LogRecordHTTP{
+		Code:    200,
+		URL:     &url.URL{Path: "/helloworld.Greeter/SayHello"},
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+	}
+	setGRPCFields(record, accesslog.VerdictDenied)
+	c.Assert(record.GRPCService, Equals, "")
+	c.Assert(record.GRPCStatus, Equals, 0)
+}
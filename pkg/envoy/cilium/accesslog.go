@@ -124,7 +124,7 @@ func (m *LogEntry) GetFlowType() accesslog.FlowType {
 
 	if m != nil {
 		switch m.EntryType {
-		case EntryType_Denied:
+		case EntryType_Denied, EntryType_RateLimited:
 			result = accesslog.TypeRequest
 		case EntryType_Request:
 			result = accesslog.TypeRequest
@@ -145,6 +145,8 @@ func (m *LogEntry) GetVerdict() accesslog.FlowVerdict {
 		switch m.EntryType {
 		case EntryType_Denied:
 			result = accesslog.VerdictDenied
+		case EntryType_RateLimited:
+			result = accesslog.VerdictRateLimited
 		}
 	}
 
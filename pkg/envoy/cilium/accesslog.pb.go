@@ -51,21 +51,24 @@ func (HttpProtocol) EnumDescriptor() ([]byte, []int) {
 type EntryType int32
 
 const (
-	EntryType_Request  EntryType = 0
-	EntryType_Response EntryType = 1
-	EntryType_Denied   EntryType = 2
+	EntryType_Request     EntryType = 0
+	EntryType_Response    EntryType = 1
+	EntryType_Denied      EntryType = 2
+	EntryType_RateLimited EntryType = 3
 )
 
 var EntryType_name = map[int32]string{
 	0: "Request",
 	1: "Response",
 	2: "Denied",
+	3: "RateLimited",
 }
 
 var EntryType_value = map[string]int32{
-	"Request":  0,
-	"Response": 1,
-	"Denied":   2,
+	"Request":     0,
+	"Response":    1,
+	"Denied":      2,
+	"RateLimited": 3,
 }
 
 func (x EntryType) String() string {
@@ -133,7 +136,10 @@ type HttpLogEntry struct {
 	// Request headers not included above
 	Headers []*KeyValue `protobuf:"bytes,6,rep,name=headers,proto3" json:"headers,omitempty"`
 	// Response info
-	Status               uint32   `protobuf:"varint,7,opt,name=status,proto3" json:"status,omitempty"`
+	Status uint32 `protobuf:"varint,7,opt,name=status,proto3" json:"status,omitempty"`
+	// Nanoseconds from the start of the request until
+	// the response was received, zero for request
+	Duration             uint64   `protobuf:"varint,8,opt,name=duration,proto3" json:"duration,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -213,6 +219,13 @@ func (m *HttpLogEntry) GetStatus() uint32 {
 	return 0
 }
 
+func (m *HttpLogEntry) GetDuration() uint64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
 type L7LogEntry struct {
 	Proto                string            `protobuf:"bytes,1,opt,name=proto,proto3" json:"proto,omitempty"`
 	Fields               map[string]string `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
@@ -568,47 +581,49 @@ func init() {
 func init() { proto.RegisterFile("cilium/accesslog.proto", fileDescriptor_f29d2fd7c3943de2) }
 
 var fileDescriptor_f29d2fd7c3943de2 = []byte{
-	// 668 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xdb, 0x6e, 0xd3, 0x40,
-	0x10, 0x8d, 0xdd, 0xdc, 0x3c, 0xb9, 0xd4, 0x2c, 0x25, 0x58, 0x11, 0x97, 0x28, 0x12, 0x28, 0x8a,
-	0x50, 0xda, 0xa6, 0x52, 0x43, 0x91, 0x78, 0xa0, 0x02, 0x94, 0x42, 0x85, 0xaa, 0xa5, 0xe2, 0xd5,
-	0x32, 0xf6, 0x24, 0x59, 0xd5, 0xb1, 0x8d, 0x77, 0x8d, 0x94, 0x0f, 0xe1, 0x13, 0x11, 0xbf, 0x81,
-	0xbc, 0xbb, 0x76, 0xd2, 0x02, 0x12, 0x6f, 0x33, 0x67, 0xce, 0x99, 0xf1, 0xec, 0xee, 0x31, 0xf4,
-	0x7c, 0x16, 0xb2, 0x6c, 0x7d, 0xe8, 0xf9, 0x3e, 0x72, 0x1e, 0xc6, 0xcb, 0x49, 0x92, 0xc6, 0x22,
-	0x26, 0x75, 0x85, 0x0f, 0xa7, 0xd0, 0xfc, 0x88, 0x9b, 0x2f, 0x5e, 0x98, 0x21, 0xb1, 0x61, 0xef,
-	0x06, 0x37, 0x8e, 0x31, 0x30, 0x46, 0x16, 0xcd, 0x43, 0x72, 0x00, 0xb5, 0xef, 0x79, 0xc9, 0x31,
-	0x25, 0xa6, 0x92, 0xe1, 0x4f, 0x03, 0xda, 0x73, 0x21, 0x92, 0xcb, 0x78, 0xf9, 0x2e, 0x12, 0xe9,
-	0x86, 0x9c, 0x41, 0x67, 0x25, 0x44, 0xe2, 0xca, 0xd6, 0x7e, 0x1c, 0xca, 0x16, 0xdd, 0xe9, 0xc1,
-	0x44, 0x0d, 0x99, 0xe4, 0xe4, 0x2b, 0x5d, 0xa3, 0xed, 0xd5, 0x4e, 0x46, 0x7a, 0x50, 0xe7, 0xfe,
-	0x0a, 0xd7, 0xc5, 0x08, 0x9d, 0x11, 0x02, 0xd5, 0x55, 0xcc, 0x85, 0xb3, 0x27, 0x51, 0x19, 0xe7,
-	0x58, 0xe2, 0x89, 0x95, 0x53, 0x55, 0x58, 0x1e, 0xe7, 0xfa, 0x35, 0x8a, 0x55, 0x1c, 0x38, 0x35,
-	0xa5, 0x57, 0x19, 0x19, 0x43, 0x63, 0x85, 0x5e, 0x80, 0x29, 0x77, 0xea, 0x83, 0xbd, 0x51, 0x6b,
-	0x6a, 0x17, 0x1f, 0x53, 0xac, 0x4b, 0x0b, 0x82, 0xfc, 0x06, 0xe1, 0x89, 0x8c, 0x3b, 0x8d, 0x81,
-	0x31, 0xea, 0x50, 0x9d, 0x0d, 0x7f, 0x18, 0x00, 0x97, 0xb3, 0x72, 0xcb, 0x03, 0xa8, 0xc9, 0x05,
-	0xf5, 0x01, 0xa9, 0x84, 0x9c, 0x42, 0x7d, 0xc1, 0x30, 0x0c, 0xb8, 0x63, 0xca, 0x39, 0x4f, 0x8a,
-	0x39, 0x5b, 0xe5, 0xe4, 0xbd, 0x24, 0xc8, 0x98, 0x6a, 0x76, 0xff, 0x0c, 0x5a, 0x3b, 0xf0, 0xff,
-	0x9e, 0xfd, 0x2b, 0xf3, 0xa5, 0x31, 0xfc, 0x55, 0x83, 0x66, 0xf9, 0x55, 0x8f, 0xc0, 0x12, 0x6c,
-	0x8d, 0x5c, 0x78, 0xeb, 0x44, 0xca, 0xab, 0x74, 0x0b, 0x90, 0xc7, 0x00, 0x8c, 0xbb, 0x2c, 0x5a,
-	0xa6, 0xc8, 0xb9, 0xb3, 0x3f, 0x30, 0x46, 0x4d, 0x6a, 0x31, 0x7e, 0xa1, 0x00, 0x72, 0x04, 0x80,
-	0x79, 0x17, 0x57, 0x6c, 0x12, 0x94, 0x67, 0xdd, 0x9d, 0xde, 0x2b, 0x16, 0x90, 0xfd, 0xaf, 0x37,
-	0x09, 0x52, 0x0b, 0x8b, 0x90, 0x3c, 0x85, 0x56, 0x12, 0x87, 0xcc, 0xdf, 0xb8, 0x91, 0xb7, 0x46,
-	0x7d, 0x15, 0xa0, 0xa0, 0x4f, 0xde, 0x1a, 0xc9, 0x73, 0xd8, 0x57, 0x7a, 0x37, 0xcd, 0x42, 0x74,
-	0x53, 0x5c, 0xe8, 0x9b, 0xe9, 0x28, 0x98, 0x66, 0x21, 0x52, 0x5c, 0x90, 0x17, 0x40, 0x78, 0x9c,
-	0xa5, 0x3e, 0xba, 0x1c, 0xfd, 0x2c, 0x65, 0x62, 0xe3, 0xb2, 0xc0, 0xa9, 0xcb, 0x0b, 0xb0, 0x55,
-	0xe5, 0xb3, 0x2e, 0x5c, 0x04, 0xe4, 0x14, 0x1e, 0x06, 0xc8, 0x05, 0x8b, 0x3c, 0xc1, 0xe2, 0xe8,
-	0x96, 0xc4, 0x96, 0x92, 0x07, 0x3b, 0xe5, 0x1d, 0xdd, 0x33, 0xe8, 0xea, 0x29, 0x5e, 0x10, 0xc8,
-	0x33, 0x68, 0xa8, 0x8f, 0x51, 0xe8, 0x1b, 0x05, 0x92, 0x43, 0xb8, 0xbf, 0xdb, 0xbe, 0xe0, 0x36,
-	0x25, 0x97, 0xec, 0x94, 0x0a, 0xc1, 0x18, 0xaa, 0xf9, 0x33, 0x76, 0x82, 0x81, 0x31, 0x6a, 0xdd,
-	0x7e, 0xe8, 0xc5, 0xcd, 0xcc, 0x2b, 0x54, 0x72, 0xc8, 0x09, 0xc0, 0x12, 0x23, 0x4c, 0x99, 0xef,
-	0x86, 0x33, 0x67, 0x21, 0x15, 0xe4, 0xcf, 0x57, 0x32, 0xaf, 0x50, 0x4b, 0xf3, 0x2e, 0x67, 0xe4,
-	0xf5, 0x5d, 0x4b, 0x99, 0xff, 0xb6, 0xd4, 0xb9, 0xe9, 0x18, 0x77, 0x6c, 0xd5, 0x2f, 0x6d, 0x65,
-	0xe5, 0x3b, 0x48, 0x46, 0x61, 0xad, 0x9e, 0xb6, 0x16, 0x94, 0x15, 0x65, 0xaf, 0x9e, 0xb6, 0x57,
-	0x6b, 0x8b, 0x4b, 0x8b, 0xf5, 0x4b, 0x8b, 0xb5, 0xb7, 0xbd, 0xb4, 0xcd, 0xfa, 0xa5, 0x75, 0x3a,
-	0xf9, 0x35, 0xe8, 0x39, 0x12, 0x21, 0x93, 0xad, 0x05, 0xbb, 0x7f, 0xb7, 0xa0, 0xa4, 0x17, 0xa4,
-	0xf3, 0x2a, 0x98, 0xe1, 0xec, 0x43, 0xb5, 0x89, 0xf6, 0x82, 0xd6, 0x6e, 0xbc, 0xc5, 0x8d, 0x37,
-	0x3e, 0x56, 0x3f, 0x9a, 0x72, 0x2d, 0x80, 0xfa, 0xfc, 0xfa, 0xfa, 0xea, 0xf8, 0xc8, 0xae, 0x94,
-	0xf1, 0xb1, 0x6d, 0x10, 0x0b, 0x6a, 0x79, 0x3c, 0xb5, 0xcd, 0xf1, 0x14, 0xac, 0xf2, 0xe1, 0x92,
-	0x16, 0x34, 0x28, 0x7e, 0xcb, 0x90, 0x0b, 0xbb, 0x42, 0xda, 0xd0, 0xa4, 0xc8, 0x93, 0x38, 0xe2,
-	0x68, 0x1b, 0xb9, 0xfc, 0x2d, 0x46, 0x0c, 0x03, 0xdb, 0xfc, 0x5a, 0x97, 0xa7, 0x7c, 0xf2, 0x3b,
-	0x00, 0x00, 0xff, 0xff, 0x0d, 0x1d, 0x5e, 0x96, 0x2d, 0x05, 0x00, 0x00,
+	// 693 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x5b, 0x6f, 0xd3, 0x4c,
+	0x10, 0x8d, 0x9d, 0x9b, 0x3d, 0xb9, 0xd4, 0xdf, 0x7e, 0xa5, 0x58, 0x15, 0x97, 0x28, 0x12, 0x28,
+	0x8a, 0x50, 0xda, 0xa6, 0x52, 0x43, 0x91, 0x78, 0xa0, 0x5c, 0x94, 0x42, 0x84, 0xaa, 0xa5, 0xe2,
+	0xd5, 0x32, 0xf6, 0x24, 0x59, 0xd5, 0x37, 0xbc, 0x6b, 0xa4, 0x3c, 0xf2, 0x23, 0xf8, 0x8d, 0xfc,
+	0x0d, 0xe4, 0x5d, 0xdb, 0x49, 0x0b, 0x48, 0xbc, 0xcd, 0x9c, 0x39, 0x67, 0x76, 0x76, 0xc7, 0xc7,
+	0x70, 0xe0, 0xb1, 0x80, 0x65, 0xe1, 0x91, 0xeb, 0x79, 0xc8, 0x79, 0x10, 0xaf, 0x26, 0x49, 0x1a,
+	0x8b, 0x98, 0xb4, 0x14, 0x3e, 0x9c, 0x82, 0xf1, 0x01, 0x37, 0x9f, 0xdd, 0x20, 0x43, 0x62, 0x41,
+	0xfd, 0x06, 0x37, 0xb6, 0x36, 0xd0, 0x46, 0x26, 0xcd, 0x43, 0xb2, 0x0f, 0xcd, 0x6f, 0x79, 0xc9,
+	0xd6, 0x25, 0xa6, 0x92, 0xe1, 0x77, 0x1d, 0xba, 0x73, 0x21, 0x92, 0x45, 0xbc, 0x7a, 0x1b, 0x89,
+	0x74, 0x43, 0xce, 0xa1, 0xb7, 0x16, 0x22, 0x71, 0x64, 0x6b, 0x2f, 0x0e, 0x64, 0x8b, 0xfe, 0x74,
+	0x7f, 0xa2, 0x0e, 0x99, 0xe4, 0xe4, 0xab, 0xa2, 0x46, 0xbb, 0xeb, 0x9d, 0x8c, 0x1c, 0x40, 0x8b,
+	0x7b, 0x6b, 0x0c, 0xcb, 0x23, 0x8a, 0x8c, 0x10, 0x68, 0xac, 0x63, 0x2e, 0xec, 0xba, 0x44, 0x65,
+	0x9c, 0x63, 0x89, 0x2b, 0xd6, 0x76, 0x43, 0x61, 0x79, 0x9c, 0xeb, 0x43, 0x14, 0xeb, 0xd8, 0xb7,
+	0x9b, 0x4a, 0xaf, 0x32, 0x32, 0x86, 0xf6, 0x1a, 0x5d, 0x1f, 0x53, 0x6e, 0xb7, 0x06, 0xf5, 0x51,
+	0x67, 0x6a, 0x95, 0xc3, 0x94, 0xd7, 0xa5, 0x25, 0x41, 0xce, 0x20, 0x5c, 0x91, 0x71, 0xbb, 0x3d,
+	0xd0, 0x46, 0x3d, 0x5a, 0x64, 0xe4, 0x10, 0x0c, 0x3f, 0x4b, 0x5d, 0xc1, 0xe2, 0xc8, 0x36, 0x06,
+	0xda, 0xa8, 0x41, 0xab, 0x7c, 0xf8, 0x43, 0x03, 0x58, 0xcc, 0xaa, 0x17, 0xd8, 0x87, 0xa6, 0xbc,
+	0x7c, 0xf1, 0x78, 0x2a, 0x21, 0x67, 0xd0, 0x5a, 0x32, 0x0c, 0x7c, 0x6e, 0xeb, 0x72, 0x86, 0x47,
+	0xe5, 0x0c, 0x5b, 0xe5, 0xe4, 0x9d, 0x24, 0xc8, 0x98, 0x16, 0xec, 0xc3, 0x73, 0xe8, 0xec, 0xc0,
+	0xff, 0xba, 0x97, 0x17, 0xfa, 0x73, 0x6d, 0xf8, 0xb3, 0x09, 0x46, 0x35, 0xd5, 0x03, 0x30, 0x05,
+	0x0b, 0x91, 0x0b, 0x37, 0x4c, 0xa4, 0xbc, 0x41, 0xb7, 0x00, 0x79, 0x08, 0xc0, 0xb8, 0xc3, 0xa2,
+	0x55, 0x8a, 0x9c, 0xdb, 0x7b, 0x03, 0x6d, 0x64, 0x50, 0x93, 0xf1, 0x4b, 0x05, 0x90, 0x63, 0x00,
+	0xcc, 0xbb, 0x38, 0x62, 0x93, 0xa0, 0xdc, 0x43, 0x7f, 0xfa, 0x5f, 0x79, 0x01, 0xd9, 0xff, 0x7a,
+	0x93, 0x20, 0x35, 0xb1, 0x0c, 0xc9, 0x63, 0xe8, 0x24, 0x71, 0xc0, 0xbc, 0x8d, 0x13, 0xb9, 0x21,
+	0x16, 0x6b, 0x02, 0x05, 0x7d, 0x74, 0x43, 0x24, 0x4f, 0x61, 0x4f, 0xe9, 0x9d, 0x34, 0x0b, 0xd0,
+	0x49, 0x71, 0x59, 0x6c, 0xad, 0xa7, 0x60, 0x9a, 0x05, 0x48, 0x71, 0x49, 0x9e, 0x01, 0xe1, 0x71,
+	0x96, 0x7a, 0xe8, 0x70, 0xf4, 0xb2, 0x94, 0x89, 0x8d, 0xc3, 0x7c, 0xbb, 0x25, 0x97, 0x63, 0xa9,
+	0xca, 0xa7, 0xa2, 0x70, 0xe9, 0x93, 0x33, 0xb8, 0xef, 0x23, 0x17, 0x2c, 0x92, 0x9b, 0xb9, 0x25,
+	0xb1, 0xa4, 0xe4, 0xde, 0x4e, 0x79, 0x47, 0xf7, 0x04, 0xfa, 0xc5, 0x29, 0xae, 0xef, 0xcb, 0x37,
+	0x68, 0xab, 0x61, 0x14, 0xfa, 0x4a, 0x81, 0xe4, 0x08, 0xfe, 0xdf, 0x6d, 0x5f, 0x72, 0x0d, 0xc9,
+	0x25, 0x3b, 0xa5, 0x52, 0x30, 0x86, 0x46, 0xfe, 0x89, 0xdb, 0xfe, 0x40, 0x1b, 0x75, 0x6e, 0x9b,
+	0xa0, 0xdc, 0xcc, 0xbc, 0x46, 0x25, 0x87, 0x9c, 0x02, 0xac, 0x30, 0xc2, 0x94, 0x79, 0x4e, 0x30,
+	0xb3, 0x97, 0x52, 0x41, 0x7e, 0xff, 0x4a, 0xe6, 0x35, 0x6a, 0x16, 0xbc, 0xc5, 0x8c, 0xbc, 0xbc,
+	0x6b, 0x37, 0xfd, 0xef, 0x76, 0xbb, 0xd0, 0x6d, 0xed, 0x8e, 0xe5, 0x0e, 0x2b, 0xcb, 0x99, 0xf9,
+	0x1d, 0x24, 0xa3, 0x40, 0xc8, 0x41, 0x61, 0x3b, 0xa8, 0x2a, 0x32, 0xcf, 0x71, 0x69, 0xbd, 0xce,
+	0x16, 0xcf, 0xf3, 0xbc, 0x57, 0x61, 0xbf, 0xee, 0xb6, 0x97, 0x42, 0xe4, 0x39, 0xca, 0x56, 0xbd,
+	0x7c, 0x0d, 0xc5, 0x39, 0x12, 0x21, 0x93, 0xad, 0x3d, 0xfb, 0x7f, 0xb6, 0xa7, 0xa4, 0x97, 0xa4,
+	0x8b, 0x06, 0xe8, 0xc1, 0xec, 0x7d, 0xc3, 0x40, 0x6b, 0x49, 0x9b, 0x37, 0xee, 0xf2, 0xc6, 0x1d,
+	0x9f, 0xa8, 0x9f, 0x50, 0x75, 0x2d, 0x80, 0xd6, 0xfc, 0xfa, 0xfa, 0xea, 0xe4, 0xd8, 0xaa, 0x55,
+	0xf1, 0x89, 0xa5, 0x11, 0x13, 0x9a, 0x79, 0x3c, 0xb5, 0xf4, 0xf1, 0x6b, 0x30, 0xab, 0x0f, 0x97,
+	0x74, 0xa0, 0x4d, 0xf1, 0x6b, 0x86, 0x5c, 0x58, 0x35, 0xd2, 0x05, 0x83, 0x22, 0x4f, 0xe2, 0x88,
+	0xa3, 0xa5, 0xe5, 0xf2, 0x37, 0x18, 0x31, 0xf4, 0x2d, 0x9d, 0xec, 0x41, 0x87, 0xba, 0x02, 0x17,
+	0x2c, 0x64, 0x02, 0x7d, 0xab, 0xfe, 0xa5, 0x25, 0x9f, 0xfd, 0xf4, 0xd7, 0x00, 0x1f, 0x16, 0x53,
+	0x46, 0x5a, 0x05, 0x00, 0x00,
 }
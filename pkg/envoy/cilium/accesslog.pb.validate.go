@@ -114,6 +114,8 @@ func (m *HttpLogEntry) Validate() error {
 
 	// no validation rules for Status
 
+	// no validation rules for Duration
+
 	return nil
 }
 
@@ -441,10 +441,26 @@ type HttpNetworkPolicyRule struct {
 	// * *:authority*: Also maps to the HTTP 1.1 *Host* header.
 	//
 	// Optional. If empty, matches any HTTP request.
-	Headers              []*route.HeaderMatcher `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+	Headers []*route.HeaderMatcher `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
+	// Headers added to requests allowed by this rule, replacing any headers of the
+	// same name present in the request. The value "%SOURCE_IDENTITY%" is replaced
+	// with the numeric security identity of the source of the request.
+	//
+	// Optional.
+	HeadersToAdd []*core.HeaderValue `protobuf:"bytes,2,rep,name=headers_to_add,json=headersToAdd,proto3" json:"headers_to_add,omitempty"`
+	// Names of headers removed from requests allowed by this rule.
+	//
+	// Optional.
+	HeadersToRemove []string `protobuf:"bytes,3,rep,name=headers_to_remove,json=headersToRemove,proto3" json:"headers_to_remove,omitempty"`
+	// Rate limit applied to requests allowed by this rule. Requests exceeding the
+	// limit are rejected with a 429 response. The state of the limit is kept
+	// across policy updates which do not modify the rule.
+	//
+	// Optional. If not set, requests are not rate limited.
+	RateLimit            *HttpRateLimit `protobuf:"bytes,4,opt,name=rate_limit,json=rateLimit,proto3" json:"rate_limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *HttpNetworkPolicyRule) Reset()         { *m = HttpNetworkPolicyRule{} }
@@ -479,6 +495,80 @@ func (m *HttpNetworkPolicyRule) GetHeaders() []*route.HeaderMatcher {
 	return nil
 }
 
+func (m *HttpNetworkPolicyRule) GetHeadersToAdd() []*core.HeaderValue {
+	if m != nil {
+		return m.HeadersToAdd
+	}
+	return nil
+}
+
+func (m *HttpNetworkPolicyRule) GetHeadersToRemove() []string {
+	if m != nil {
+		return m.HeadersToRemove
+	}
+	return nil
+}
+
+func (m *HttpNetworkPolicyRule) GetRateLimit() *HttpRateLimit {
+	if m != nil {
+		return m.RateLimit
+	}
+	return nil
+}
+
+// A token bucket rate limit of HTTP requests, applied separately to each source
+// security identity.
+type HttpRateLimit struct {
+	// The number of requests per second allowed on average.
+	// Required, must be greater than zero.
+	RequestsPerSecond uint32 `protobuf:"varint,1,opt,name=requests_per_second,json=requestsPerSecond,proto3" json:"requests_per_second,omitempty"`
+	// The maximum number of requests allowed in a burst.
+	// Optional. If zero, defaults to requests_per_second.
+	Burst                uint32   `protobuf:"varint,2,opt,name=burst,proto3" json:"burst,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HttpRateLimit) Reset()         { *m = HttpRateLimit{} }
+func (m *HttpRateLimit) String() string { return proto.CompactTextString(m) }
+func (*HttpRateLimit) ProtoMessage()    {}
+func (*HttpRateLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_282feee65b187334, []int{5}
+}
+
+func (m *HttpRateLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HttpRateLimit.Unmarshal(m, b)
+}
+func (m *HttpRateLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HttpRateLimit.Marshal(b, m, deterministic)
+}
+func (m *HttpRateLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HttpRateLimit.Merge(m, src)
+}
+func (m *HttpRateLimit) XXX_Size() int {
+	return xxx_messageInfo_HttpRateLimit.Size(m)
+}
+func (m *HttpRateLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_HttpRateLimit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HttpRateLimit proto.InternalMessageInfo
+
+func (m *HttpRateLimit) GetRequestsPerSecond() uint32 {
+	if m != nil {
+		return m.RequestsPerSecond
+	}
+	return 0
+}
+
+func (m *HttpRateLimit) GetBurst() uint32 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
 // A set of network policy rules that match Kafka requests.
 type KafkaNetworkPolicyRules struct {
 	// The set of Kafka network policy rules.
@@ -494,7 +584,7 @@ func (m *KafkaNetworkPolicyRules) Reset()         { *m = KafkaNetworkPolicyRules
 func (m *KafkaNetworkPolicyRules) String() string { return proto.CompactTextString(m) }
 func (*KafkaNetworkPolicyRules) ProtoMessage()    {}
 func (*KafkaNetworkPolicyRules) Descriptor() ([]byte, []int) {
-	return fileDescriptor_282feee65b187334, []int{5}
+	return fileDescriptor_282feee65b187334, []int{6}
 }
 
 func (m *KafkaNetworkPolicyRules) XXX_Unmarshal(b []byte) error {
@@ -551,7 +641,7 @@ func (m *KafkaNetworkPolicyRule) Reset()         { *m = KafkaNetworkPolicyRule{}
 func (m *KafkaNetworkPolicyRule) String() string { return proto.CompactTextString(m) }
 func (*KafkaNetworkPolicyRule) ProtoMessage()    {}
 func (*KafkaNetworkPolicyRule) Descriptor() ([]byte, []int) {
-	return fileDescriptor_282feee65b187334, []int{6}
+	return fileDescriptor_282feee65b187334, []int{7}
 }
 
 func (m *KafkaNetworkPolicyRule) XXX_Unmarshal(b []byte) error {
@@ -615,7 +705,7 @@ func (m *L7NetworkPolicyRules) Reset()         { *m = L7NetworkPolicyRules{} }
 func (m *L7NetworkPolicyRules) String() string { return proto.CompactTextString(m) }
 func (*L7NetworkPolicyRules) ProtoMessage()    {}
 func (*L7NetworkPolicyRules) Descriptor() ([]byte, []int) {
-	return fileDescriptor_282feee65b187334, []int{7}
+	return fileDescriptor_282feee65b187334, []int{8}
 }
 
 func (m *L7NetworkPolicyRules) XXX_Unmarshal(b []byte) error {
@@ -658,7 +748,7 @@ func (m *L7NetworkPolicyRule) Reset()         { *m = L7NetworkPolicyRule{} }
 func (m *L7NetworkPolicyRule) String() string { return proto.CompactTextString(m) }
 func (*L7NetworkPolicyRule) ProtoMessage()    {}
 func (*L7NetworkPolicyRule) Descriptor() ([]byte, []int) {
-	return fileDescriptor_282feee65b187334, []int{8}
+	return fileDescriptor_282feee65b187334, []int{9}
 }
 
 func (m *L7NetworkPolicyRule) XXX_Unmarshal(b []byte) error {
@@ -692,6 +782,7 @@ func init() {
 	proto.RegisterType((*PortNetworkPolicyRule)(nil), "cilium.PortNetworkPolicyRule")
 	proto.RegisterType((*HttpNetworkPolicyRules)(nil), "cilium.HttpNetworkPolicyRules")
 	proto.RegisterType((*HttpNetworkPolicyRule)(nil), "cilium.HttpNetworkPolicyRule")
+	proto.RegisterType((*HttpRateLimit)(nil), "cilium.HttpRateLimit")
 	proto.RegisterType((*KafkaNetworkPolicyRules)(nil), "cilium.KafkaNetworkPolicyRules")
 	proto.RegisterType((*KafkaNetworkPolicyRule)(nil), "cilium.KafkaNetworkPolicyRule")
 	proto.RegisterType((*L7NetworkPolicyRules)(nil), "cilium.L7NetworkPolicyRules")
@@ -840,57 +931,65 @@ var _NetworkPolicyDiscoveryService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("cilium/npds.proto", fileDescriptor_282feee65b187334) }
 
 var fileDescriptor_282feee65b187334 = []byte{
-	// 828 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0x41, 0x6f, 0xe3, 0x44,
-	0x14, 0xde, 0x49, 0x9c, 0xb4, 0x79, 0xd1, 0x2e, 0x74, 0xb6, 0x49, 0xdd, 0xb0, 0x4d, 0x83, 0x01,
-	0x29, 0x5b, 0xa9, 0xce, 0x2a, 0x3d, 0x84, 0x96, 0x03, 0xda, 0x88, 0x45, 0x45, 0x05, 0x14, 0xb9,
-	0x2b, 0x0e, 0x8b, 0xd8, 0x68, 0xd6, 0x7e, 0x6d, 0x47, 0x71, 0x3d, 0x66, 0x3c, 0x09, 0x0a, 0xc7,
-	0x15, 0x17, 0xae, 0xf0, 0x3b, 0x90, 0x38, 0x73, 0xda, 0xff, 0xc0, 0x5f, 0x80, 0x03, 0xbf, 0xa2,
-	0xc8, 0x33, 0x76, 0xb6, 0x56, 0xdd, 0x72, 0xe1, 0x62, 0x8d, 0xe7, 0x7d, 0xdf, 0x37, 0xef, 0x7d,
-	0xf3, 0x9e, 0x0d, 0x1b, 0x3e, 0x0f, 0xf9, 0xfc, 0x72, 0x10, 0xc5, 0x41, 0xe2, 0xc6, 0x52, 0x28,
-	0x41, 0xeb, 0x66, 0xab, 0xb3, 0x8b, 0xd1, 0x42, 0x2c, 0x07, 0x2c, 0xe6, 0x83, 0xc5, 0x70, 0xe0,
-	0x0b, 0x89, 0x03, 0x16, 0x04, 0x12, 0x93, 0x0c, 0xd8, 0x79, 0x54, 0x00, 0x04, 0x3c, 0xf1, 0xc5,
-	0x02, 0xe5, 0x32, 0x8b, 0x76, 0x0b, 0x51, 0x29, 0xe6, 0x0a, 0xcd, 0x33, 0x67, 0x9f, 0x0b, 0x71,
-	0x1e, 0xa2, 0x06, 0xb0, 0x28, 0x12, 0x8a, 0x29, 0x2e, 0xa2, 0x5c, 0x7b, 0x6b, 0xc1, 0x42, 0x1e,
-	0x30, 0x85, 0x83, 0x7c, 0x61, 0x02, 0xce, 0xdf, 0x04, 0xee, 0x7f, 0x8d, 0xea, 0x07, 0x21, 0x67,
-	0x13, 0x11, 0x72, 0x7f, 0x49, 0x29, 0x58, 0x11, 0xbb, 0x44, 0x9b, 0xf4, 0x48, 0xbf, 0xe1, 0xe9,
-	0x35, 0x6d, 0x43, 0x3d, 0xd6, 0x51, 0xbb, 0xd2, 0x23, 0x7d, 0xcb, 0xcb, 0xde, 0xe8, 0x73, 0xd8,
-	0xe6, 0xd1, 0x79, 0x5a, 0xc3, 0x34, 0x46, 0x39, 0x8d, 0x85, 0x54, 0x53, 0x1d, 0xe2, 0x98, 0xd8,
-	0xd5, 0x5e, 0xb5, 0xdf, 0x1c, 0x6e, 0xbb, 0xa6, 0x7e, 0x77, 0x22, 0xa4, 0x2a, 0x9c, 0xe4, 0xb5,
-	0x33, 0xee, 0x04, 0x65, 0x1a, 0x9c, 0x64, 0x44, 0xea, 0x81, 0x8d, 0xb7, 0x89, 0x5a, 0xff, 0x25,
-	0xda, 0xc2, 0x32, 0x4d, 0xe7, 0x77, 0x02, 0x1b, 0x37, 0xc0, 0x74, 0x17, 0xac, 0x54, 0x5e, 0xd7,
-	0x7a, 0x7f, 0xdc, 0xfc, 0xe3, 0x9f, 0x37, 0xd5, 0xfa, 0x9e, 0x65, 0x5f, 0x5d, 0x55, 0x3d, 0x1d,
-	0xa0, 0xcf, 0x60, 0x5d, 0xfb, 0xe4, 0x8b, 0x50, 0x97, 0xfe, 0x60, 0xf8, 0xd8, 0xd5, 0x17, 0xe1,
-	0xb2, 0x98, 0xbb, 0x8b, 0xa1, 0x9b, 0xde, 0xa3, 0x7b, 0x2a, 0xfc, 0x19, 0xaa, 0xa7, 0xd9, 0x6d,
-	0x4e, 0x32, 0x82, 0xb7, 0xa2, 0xd2, 0x03, 0xa8, 0xc9, 0x79, 0xb8, 0xf2, 0x64, 0xe7, 0xf6, 0xf4,
-	0xe7, 0x21, 0x7a, 0x06, 0xeb, 0xfc, 0x56, 0x81, 0x56, 0x29, 0x80, 0x1e, 0xc0, 0x3b, 0x12, 0x2f,
-	0x85, 0xc2, 0xb7, 0xbe, 0x90, 0x5e, 0xb5, 0x6f, 0x8d, 0x21, 0xad, 0xa0, 0xf6, 0x0b, 0xa9, 0xd8,
-	0xc4, 0x7b, 0x60, 0x20, 0x2b, 0x57, 0xb7, 0x61, 0x3d, 0x1c, 0x4d, 0x75, 0x4a, 0xba, 0x94, 0x86,
-	0xb7, 0x16, 0x8e, 0x74, 0xae, 0xf4, 0x53, 0x80, 0x0b, 0xa5, 0xe2, 0xa9, 0xc9, 0x31, 0xe8, 0x91,
-	0x7e, 0x73, 0xd8, 0xcd, 0x73, 0x3c, 0x56, 0x2a, 0xbe, 0x91, 0x42, 0x72, 0x7c, 0xcf, 0x6b, 0xa4,
-	0x1c, 0xfd, 0x42, 0xc7, 0xd0, 0x9c, 0xb1, 0xb3, 0x19, 0xcb, 0x14, 0x50, 0x2b, 0xec, 0xe6, 0x0a,
-	0x27, 0x69, 0xa8, 0x54, 0x02, 0x34, 0xcb, 0x68, 0x1c, 0xea, 0xfc, 0x8c, 0xc0, 0x99, 0x16, 0x78,
-	0x94, 0x0b, 0x7c, 0x39, 0x2a, 0x65, 0xaf, 0x85, 0x23, 0xbd, 0x1c, 0x5b, 0x50, 0x09, 0x47, 0xce,
-	0x2b, 0x68, 0x97, 0xe7, 0x4a, 0x8f, 0x0b, 0xf5, 0x91, 0xe2, 0x1d, 0x94, 0x72, 0xde, 0x3a, 0xb9,
-	0x4e, 0xae, 0x15, 0xea, 0x3c, 0x87, 0x56, 0x29, 0x9e, 0x7e, 0x02, 0x6b, 0x17, 0xc8, 0x02, 0x94,
-	0xb9, 0xfe, 0xfb, 0xc5, 0x3e, 0x31, 0xa3, 0x7a, 0xac, 0x21, 0x5f, 0x31, 0xe5, 0x5f, 0xa0, 0xf4,
-	0x72, 0x86, 0x73, 0x06, 0x5b, 0xb7, 0x78, 0x44, 0x4f, 0x8a, 0xce, 0x1a, 0xed, 0xee, 0xdd, 0xce,
-	0x16, 0x92, 0xbf, 0x66, 0xb1, 0xf3, 0x86, 0x40, 0xbb, 0x9c, 0x42, 0xb7, 0x60, 0x8d, 0xc5, 0x7c,
-	0x3a, 0xc3, 0xa5, 0x1e, 0x86, 0x9a, 0x57, 0x67, 0x31, 0x3f, 0xc1, 0x74, 0x44, 0x9a, 0x69, 0x60,
-	0x81, 0x32, 0xe1, 0x22, 0xd2, 0x9d, 0x53, 0xf3, 0x80, 0xc5, 0xfc, 0x1b, 0xb3, 0x93, 0xf6, 0xb6,
-	0x12, 0x31, 0xf7, 0xed, 0x6a, 0xda, 0x54, 0xe3, 0x9d, 0xf4, 0x6c, 0x5b, 0xb6, 0xed, 0x2b, 0x32,
-	0xdc, 0x78, 0xf9, 0x2d, 0xdb, 0xff, 0xf1, 0xe9, 0xfe, 0x8b, 0x27, 0xfb, 0x87, 0xee, 0x74, 0xff,
-	0xbb, 0xbd, 0x0f, 0x3d, 0x83, 0xa5, 0x23, 0x68, 0xf8, 0x21, 0xc7, 0x48, 0x4d, 0x79, 0x60, 0x5b,
-	0x9a, 0xd8, 0x49, 0x89, 0x2d, 0xf9, 0xb0, 0x8c, 0xb5, 0x6e, 0xc0, 0x5f, 0x04, 0xce, 0x0b, 0xd8,
-	0x2c, 0xeb, 0x06, 0x3a, 0xbe, 0xd6, 0x3d, 0xc6, 0xa4, 0xf7, 0xee, 0xe8, 0x9e, 0x82, 0x43, 0x79,
-	0x1b, 0x39, 0x3f, 0x13, 0x78, 0x58, 0x02, 0xa6, 0x87, 0x60, 0xa5, 0xc2, 0x99, 0xee, 0x47, 0x77,
-	0xe8, 0xba, 0xe9, 0xe3, 0x59, 0xa4, 0xe4, 0xd2, 0xd3, 0x94, 0xce, 0x08, 0x1a, 0xab, 0x2d, 0xfa,
-	0x2e, 0x54, 0x73, 0x7f, 0x1b, 0x5e, 0xba, 0xa4, 0x9b, 0x50, 0x5b, 0xb0, 0x70, 0x8e, 0xd9, 0x40,
-	0x9a, 0x97, 0xa3, 0xca, 0xc7, 0x64, 0xf8, 0x53, 0x05, 0x76, 0x0a, 0xf2, 0x9f, 0xe5, 0xff, 0x83,
-	0x53, 0x94, 0x0b, 0xee, 0x23, 0x7d, 0x09, 0xad, 0x53, 0x25, 0x91, 0x5d, 0x5e, 0x87, 0xa5, 0x83,
-	0xde, 0x2d, 0x76, 0xde, 0x8a, 0xe8, 0xe1, 0xf7, 0x73, 0x4c, 0x54, 0x67, 0xf7, 0xd6, 0x78, 0x12,
-	0x8b, 0x28, 0x41, 0xe7, 0x5e, 0x9f, 0x3c, 0x21, 0xf4, 0x35, 0x81, 0xcd, 0xcf, 0x51, 0xf9, 0x17,
-	0xff, 0xbb, 0xfe, 0xe3, 0xd7, 0x7f, 0xfe, 0xf5, 0x6b, 0xe5, 0x03, 0xa7, 0x5b, 0xf8, 0xcf, 0x1d,
-	0x45, 0xe6, 0x9c, 0xd5, 0x37, 0xed, 0x88, 0xec, 0xbd, 0xaa, 0xeb, 0xef, 0xd5, 0xc1, 0xbf, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0x9f, 0x7b, 0xa5, 0xa3, 0x58, 0x07, 0x00, 0x00,
+	// 954 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0x4f, 0x4f, 0x24, 0xc5,
+	0x1b, 0xa6, 0x66, 0x86, 0x3f, 0xf3, 0xf2, 0x83, 0xfd, 0x51, 0x30, 0xd0, 0xe0, 0x02, 0x63, 0xab,
+	0xc9, 0x2c, 0x09, 0x33, 0x9b, 0xc1, 0x64, 0x04, 0x0f, 0x86, 0xc9, 0xae, 0xc1, 0xb0, 0x9a, 0x49,
+	0xb1, 0xd9, 0xc3, 0x1a, 0xb7, 0x2d, 0xba, 0x5f, 0xa0, 0x43, 0xd3, 0xd5, 0x56, 0xd7, 0x8c, 0xc1,
+	0xe3, 0xc6, 0x8b, 0x57, 0xfd, 0x1c, 0x26, 0x9e, 0x3d, 0xed, 0x77, 0xf0, 0x2b, 0xe8, 0xc1, 0xef,
+	0x60, 0x82, 0xa9, 0xaa, 0xee, 0xd9, 0x69, 0x69, 0xf0, 0xe2, 0xa5, 0x53, 0x55, 0xef, 0xf3, 0x3c,
+	0xf5, 0xfe, 0xad, 0x86, 0x25, 0x3f, 0x8c, 0xc2, 0xe1, 0x55, 0x27, 0x4e, 0x82, 0xb4, 0x9d, 0x48,
+	0xa1, 0x04, 0x9d, 0xb1, 0x47, 0x1b, 0xdb, 0x18, 0x8f, 0xc4, 0x75, 0x87, 0x27, 0x61, 0x67, 0xd4,
+	0xed, 0xf8, 0x42, 0x62, 0x87, 0x07, 0x81, 0xc4, 0x34, 0x03, 0x6e, 0x3c, 0xbc, 0x0d, 0x38, 0xe5,
+	0x29, 0x96, 0x5a, 0x83, 0x30, 0xf5, 0xc5, 0x08, 0xe5, 0x75, 0x66, 0xdd, 0x2a, 0x58, 0xa5, 0x18,
+	0x2a, 0xb4, 0xdf, 0x9c, 0x7d, 0x2e, 0xc4, 0x79, 0x84, 0x06, 0xc0, 0xe3, 0x58, 0x28, 0xae, 0x42,
+	0x11, 0xe7, 0x37, 0xaf, 0x8d, 0x78, 0x14, 0x06, 0x5c, 0x61, 0x27, 0x5f, 0x58, 0x83, 0xfb, 0x07,
+	0x81, 0x85, 0x2f, 0x50, 0x7d, 0x2b, 0xe4, 0xe5, 0x40, 0x44, 0xa1, 0x7f, 0x4d, 0x29, 0xd4, 0x62,
+	0x7e, 0x85, 0x0e, 0x69, 0x92, 0x56, 0x9d, 0x99, 0x35, 0x5d, 0x85, 0x99, 0xc4, 0x58, 0x9d, 0x4a,
+	0x93, 0xb4, 0x6a, 0x2c, 0xdb, 0xd1, 0xe7, 0xb0, 0x1e, 0xc6, 0xe7, 0x3a, 0x42, 0x2f, 0x41, 0xe9,
+	0x25, 0x42, 0x2a, 0xcf, 0x98, 0x42, 0x4c, 0x9d, 0x6a, 0xb3, 0xda, 0x9a, 0xef, 0xae, 0xb7, 0x6d,
+	0x76, 0xda, 0x03, 0x21, 0x55, 0xe1, 0x26, 0xb6, 0x9a, 0x71, 0x07, 0x28, 0xb5, 0x71, 0x90, 0x11,
+	0x29, 0x03, 0x07, 0xef, 0x12, 0xad, 0xfd, 0x9b, 0x68, 0x03, 0xcb, 0x34, 0xdd, 0x5f, 0x08, 0x2c,
+	0xdd, 0x02, 0xd3, 0x6d, 0xa8, 0x69, 0x79, 0x13, 0xeb, 0x42, 0x7f, 0xfe, 0xd7, 0x3f, 0xdf, 0x54,
+	0x67, 0x76, 0x6a, 0xce, 0xcd, 0x4d, 0x95, 0x19, 0x03, 0x7d, 0x0a, 0x73, 0x26, 0x4f, 0xbe, 0x88,
+	0x4c, 0xe8, 0x8b, 0xdd, 0x47, 0x6d, 0x53, 0x88, 0x36, 0x4f, 0xc2, 0xf6, 0xa8, 0xdb, 0xd6, 0x45,
+	0x6c, 0x9f, 0x08, 0xff, 0x12, 0xd5, 0x61, 0x56, 0xeb, 0x41, 0x46, 0x60, 0x63, 0x2a, 0xdd, 0x83,
+	0x69, 0x39, 0x8c, 0xc6, 0x39, 0xd9, 0xbc, 0xdb, 0xfd, 0x61, 0x84, 0xcc, 0x62, 0xdd, 0x9f, 0x2b,
+	0xd0, 0x28, 0x05, 0xd0, 0x3d, 0x78, 0x20, 0xf1, 0x4a, 0x28, 0x7c, 0x9b, 0x17, 0xd2, 0xac, 0xb6,
+	0x6a, 0x7d, 0xd0, 0x11, 0x4c, 0xff, 0x48, 0x2a, 0x0e, 0x61, 0x8b, 0x16, 0x32, 0xce, 0xea, 0x3a,
+	0xcc, 0x45, 0x3d, 0xcf, 0xb8, 0x64, 0x42, 0xa9, 0xb3, 0xd9, 0xa8, 0x67, 0x7c, 0xa5, 0x9f, 0x00,
+	0x5c, 0x28, 0x95, 0x78, 0xd6, 0xc7, 0xa0, 0x49, 0x5a, 0xf3, 0xdd, 0xad, 0xdc, 0xc7, 0x23, 0xa5,
+	0x92, 0x5b, 0x2e, 0xa4, 0x47, 0x53, 0xac, 0xae, 0x39, 0x66, 0x43, 0xfb, 0x30, 0x7f, 0xc9, 0xcf,
+	0x2e, 0x79, 0xa6, 0x80, 0x46, 0x61, 0x3b, 0x57, 0x38, 0xd6, 0xa6, 0x52, 0x09, 0x30, 0x2c, 0xab,
+	0xb1, 0x6f, 0xfc, 0xb3, 0x02, 0x67, 0x46, 0xe0, 0x61, 0x2e, 0xf0, 0xac, 0x57, 0xca, 0x9e, 0x8d,
+	0x7a, 0x66, 0xd9, 0xaf, 0x41, 0x25, 0xea, 0xb9, 0xa7, 0xb0, 0x5a, 0xee, 0x2b, 0x3d, 0x2a, 0xc4,
+	0x47, 0x8a, 0x35, 0x28, 0xe5, 0xbc, 0xcd, 0xe4, 0x1c, 0x99, 0x08, 0xd4, 0xfd, 0x8b, 0x40, 0xa3,
+	0x94, 0x40, 0x3f, 0x86, 0xd9, 0x0b, 0xe4, 0x01, 0xca, 0xfc, 0x82, 0x77, 0x8b, 0x8d, 0x62, 0x67,
+	0xf5, 0xc8, 0x40, 0x3e, 0xe7, 0xca, 0xbf, 0x40, 0xc9, 0x72, 0x06, 0x7d, 0x02, 0x8b, 0xd9, 0xd2,
+	0x53, 0xc2, 0xe3, 0x41, 0xe0, 0x54, 0x8c, 0xc6, 0x56, 0x49, 0xb3, 0x59, 0x89, 0x17, 0x3c, 0x1a,
+	0x22, 0xfb, 0x5f, 0xc6, 0x7a, 0x2e, 0x0e, 0x83, 0x80, 0xee, 0xc0, 0xd2, 0x84, 0x8a, 0x2e, 0xff,
+	0x08, 0x4d, 0xc7, 0xd5, 0xd9, 0x83, 0x31, 0x90, 0x99, 0x63, 0xfa, 0x21, 0x80, 0xe4, 0x0a, 0xbd,
+	0x28, 0xbc, 0x0a, 0x95, 0x53, 0x33, 0xf9, 0x6e, 0x4c, 0xa6, 0x84, 0x71, 0x85, 0xcf, 0xb4, 0x91,
+	0xd5, 0x65, 0xbe, 0x74, 0xbf, 0x86, 0x85, 0x82, 0x8d, 0xee, 0xc3, 0xb2, 0xc4, 0x6f, 0x86, 0x98,
+	0x2a, 0x3b, 0xac, 0x29, 0xfa, 0x22, 0x0e, 0xb2, 0x79, 0xaa, 0xeb, 0x1c, 0xd6, 0x76, 0x2a, 0xcd,
+	0x29, 0xb6, 0x94, 0xa3, 0x06, 0x28, 0x4f, 0x0c, 0x86, 0xae, 0xc0, 0xf4, 0xe9, 0x50, 0xa6, 0xca,
+	0x34, 0xe3, 0x02, 0xb3, 0x1b, 0xf7, 0x0c, 0xd6, 0xee, 0x68, 0x17, 0x7a, 0x5c, 0x6c, 0x32, 0x92,
+	0x65, 0xe8, 0xde, 0x26, 0x2b, 0xd4, 0x71, 0xa2, 0xdb, 0xdc, 0x37, 0x04, 0x56, 0xcb, 0x29, 0x74,
+	0x0d, 0x66, 0x79, 0x12, 0x7a, 0x97, 0x78, 0x6d, 0xe2, 0x98, 0x66, 0x33, 0x3c, 0x09, 0x8f, 0x51,
+	0xbf, 0x16, 0xf3, 0xda, 0x30, 0x42, 0x99, 0x86, 0x22, 0x36, 0x7e, 0x4f, 0x33, 0xe0, 0x49, 0xf8,
+	0xc2, 0x9e, 0xe8, 0x31, 0x57, 0x22, 0x09, 0x7d, 0xa7, 0xaa, 0xe7, 0xab, 0xbf, 0xa9, 0xef, 0x76,
+	0xe4, 0xaa, 0x73, 0x43, 0xba, 0x4b, 0xaf, 0xbe, 0xe4, 0xbb, 0xdf, 0x1d, 0xee, 0xbe, 0x7c, 0xbc,
+	0xbb, 0xdf, 0xf6, 0x76, 0xbf, 0xda, 0x79, 0x9f, 0x59, 0x2c, 0xed, 0x41, 0xdd, 0x8f, 0x42, 0x8c,
+	0x95, 0x17, 0x06, 0xa6, 0x10, 0xf5, 0xfe, 0x86, 0x26, 0x36, 0xe4, 0x72, 0x19, 0x6b, 0xce, 0x82,
+	0x3f, 0x0b, 0xdc, 0x97, 0xb0, 0x52, 0x36, 0x18, 0xb4, 0x3f, 0x31, 0x48, 0x36, 0x49, 0xef, 0xdc,
+	0x33, 0x48, 0x85, 0x0c, 0xe5, 0x13, 0xe5, 0xfe, 0x40, 0x60, 0xb9, 0x04, 0x4c, 0xf7, 0xa1, 0xa6,
+	0x85, 0x33, 0xdd, 0x0f, 0xee, 0xd1, 0x6d, 0xeb, 0xcf, 0xd3, 0x58, 0xc9, 0x6b, 0x66, 0x28, 0x1b,
+	0x3d, 0xa8, 0x8f, 0x8f, 0xe8, 0xff, 0xa1, 0x9a, 0xe7, 0xb7, 0xce, 0xf4, 0x52, 0xb7, 0xc3, 0x48,
+	0xf7, 0x74, 0xf6, 0x36, 0xd9, 0xcd, 0x41, 0xe5, 0x23, 0xd2, 0xfd, 0xbe, 0x02, 0x9b, 0x05, 0xf9,
+	0x27, 0xf9, 0xaf, 0xf1, 0x04, 0xe5, 0x28, 0xf4, 0x91, 0xbe, 0x82, 0xc6, 0x89, 0x92, 0xc8, 0xaf,
+	0x26, 0x61, 0xfa, 0xcd, 0xfb, 0xc7, 0xfc, 0x8c, 0x89, 0xcc, 0x76, 0xe3, 0xc6, 0xf6, 0x9d, 0xf6,
+	0x34, 0x11, 0x71, 0x8a, 0xee, 0x54, 0x8b, 0x3c, 0x26, 0xf4, 0x35, 0x81, 0x95, 0x4f, 0x51, 0xf9,
+	0x17, 0xff, 0xb9, 0xfe, 0xa3, 0xd7, 0xbf, 0xfd, 0xfe, 0x53, 0xe5, 0x3d, 0x77, 0xab, 0xf0, 0xcb,
+	0x3f, 0x88, 0xed, 0x3d, 0xe3, 0xe7, 0xfd, 0x80, 0xec, 0x9c, 0xce, 0x98, 0xa7, 0x7b, 0xef, 0xef,
+	0x01, 0x00, 0x80, 0xa9, 0x5e, 0x63, 0x81, 0x08, 0x00, 0x00,
 }
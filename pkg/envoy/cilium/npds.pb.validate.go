@@ -364,6 +364,31 @@ func (m *HttpNetworkPolicyRule) Validate() error {
 
 	}
 
+	for idx, item := range m.GetHeadersToAdd() {
+		_, _ = idx, item
+
+		if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return HttpNetworkPolicyRuleValidationError{
+					Field:  fmt.Sprintf("HeadersToAdd[%v]", idx),
+					Reason: "embedded message failed validation",
+					Cause:  err,
+				}
+			}
+		}
+
+	}
+
+	if v, ok := interface{}(m.GetRateLimit()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return HttpNetworkPolicyRuleValidationError{
+				Field:  "RateLimit",
+				Reason: "embedded message failed validation",
+				Cause:  err,
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -398,6 +423,57 @@ func (e HttpNetworkPolicyRuleValidationError) Error() string {
 
 var _ error = HttpNetworkPolicyRuleValidationError{}
 
+// Validate checks the field values on HttpRateLimit with the rules defined in
+// the proto definition for this message. If any rules are violated, an error
+// is returned.
+func (m *HttpRateLimit) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	if m.GetRequestsPerSecond() <= 0 {
+		return HttpRateLimitValidationError{
+			Field:  "RequestsPerSecond",
+			Reason: "value must be greater than 0",
+		}
+	}
+
+	// no validation rules for Burst
+
+	return nil
+}
+
+// HttpRateLimitValidationError is the validation error returned by
+// HttpRateLimit.Validate if the designated constraints aren't met.
+type HttpRateLimitValidationError struct {
+	Field  string
+	Reason string
+	Cause  error
+	Key    bool
+}
+
+// Error satisfies the builtin error interface
+func (e HttpRateLimitValidationError) Error() string {
+	cause := ""
+	if e.Cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.Cause)
+	}
+
+	key := ""
+	if e.Key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sHttpRateLimit.%s: %s%s",
+		key,
+		e.Field,
+		e.Reason,
+		cause)
+}
+
+var _ error = HttpRateLimitValidationError{}
+
 // Validate checks the field values on KafkaNetworkPolicyRules with the rules
 // defined in the proto definition for this message. If any rules are
 // violated, an error is returned.
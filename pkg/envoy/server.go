@@ -325,6 +325,10 @@ func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatch
 	if h.Host != "" {
 		cnt++
 	}
+	if h.GRPC != nil {
+		cnt += 2
+	}
+	cnt += len(h.HeaderMatches)
 
 	headers = make([]*envoy_api_v2_route.HeaderMatcher, 0, cnt)
 	if h.Path != "" {
@@ -349,6 +353,29 @@ func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatch
 		}
 		ruleRef += `HostRegexp("` + h.Host + `")`
 	}
+
+	if h.GRPC != nil {
+		// gRPC calls are matched on the path consisting of the service
+		// and method, and on the gRPC content type, which may carry a
+		// suffix for the message encoding, e.g. "application/grpc+proto"
+		var pathMatch envoy_api_v2_route.HeaderMatcher
+		if h.GRPC.Method != "" {
+			pathMatch = envoy_api_v2_route.HeaderMatcher{Name: ":path",
+				HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: h.GRPC.Path()}}
+		} else {
+			pathMatch = envoy_api_v2_route.HeaderMatcher{Name: ":path",
+				HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: h.GRPC.Path()}}
+		}
+		headers = append(headers, &pathMatch, &envoy_api_v2_route.HeaderMatcher{Name: "content-type",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"}})
+		if ruleRef != "" {
+			ruleRef += " && "
+		}
+		ruleRef += `GRPCService("` + h.GRPC.Service + `")`
+		if h.GRPC.Method != "" {
+			ruleRef += ` && GRPCMethod("` + h.GRPC.Method + `")`
+		}
+	}
 	for _, hdr := range h.Headers {
 		strs := strings.SplitN(hdr, " ", 2)
 		if ruleRef != "" {
@@ -370,6 +397,34 @@ func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatch
 		}
 		ruleRef += `")`
 	}
+	for _, m := range h.HeaderMatches {
+		matcher := &envoy_api_v2_route.HeaderMatcher{Name: m.Name, InvertMatch: m.Invert}
+		switch {
+		case m.Value != "":
+			matcher.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: m.Value}
+		case m.ValueRegex != "":
+			matcher.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: m.ValueRegex}
+		default:
+			// An inverted presence match is implemented by the
+			// Cilium HTTP filter, it matches absent headers.
+			matcher.HeaderMatchSpecifier = &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true}
+		}
+		headers = append(headers, matcher)
+		if ruleRef != "" {
+			ruleRef += " && "
+		}
+		if m.Invert {
+			ruleRef += "!"
+		}
+		switch {
+		case m.Value != "":
+			ruleRef += `Header("` + m.Name + `","` + m.Value + `")`
+		case m.ValueRegex != "":
+			ruleRef += `HeaderRegexp("` + m.Name + `","` + m.ValueRegex + `")`
+		default:
+			ruleRef += `Header("` + m.Name + `")`
+		}
+	}
 	if len(headers) == 0 {
 		headers = nil
 	} else {
@@ -378,6 +433,22 @@ func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatch
 	return
 }
 
+// getHTTPHeaderActions returns the headers to be added to and removed from
+// requests allowed by the HTTP rule.
+func getHTTPHeaderActions(h *api.PortRuleHTTP) (headersToAdd []*envoy_api_v2_core.HeaderValue, headersToRemove []string) {
+	if len(h.AddHeaders) > 0 {
+		headersToAdd = make([]*envoy_api_v2_core.HeaderValue, 0, len(h.AddHeaders))
+		for _, hdr := range h.AddHeaders {
+			headersToAdd = append(headersToAdd, &envoy_api_v2_core.HeaderValue{Key: hdr.Name, Value: hdr.Value})
+		}
+	}
+	if len(h.RemoveHeaders) > 0 {
+		headersToRemove = make([]string, len(h.RemoveHeaders))
+		copy(headersToRemove, h.RemoveHeaders)
+	}
+	return
+}
+
 func createBootstrap(filePath string, name, cluster, version string, xdsSock, envoyClusterName string, adminPath string) {
 	bs := &envoy_config_bootstrap_v2.Bootstrap{
 		Node: &envoy_api_v2_core.Node{Id: name, Cluster: cluster, Metadata: nil, Locality: nil, BuildVersion: version},
@@ -476,7 +547,19 @@ func getPortNetworkPolicyRule(sel api.EndpointSelector, l7Parser policy.L7Parser
 			httpRules := make([]*cilium.HttpNetworkPolicyRule, 0, len(l7Rules.HTTP))
 			for _, l7 := range l7Rules.HTTP {
 				headers, _ := getHTTPRule(&l7)
-				httpRules = append(httpRules, &cilium.HttpNetworkPolicyRule{Headers: headers})
+				headersToAdd, headersToRemove := getHTTPHeaderActions(&l7)
+				httpRule := &cilium.HttpNetworkPolicyRule{
+					Headers:         headers,
+					HeadersToAdd:    headersToAdd,
+					HeadersToRemove: headersToRemove,
+				}
+				if l7.RateLimit != nil {
+					httpRule.RateLimit = &cilium.HttpRateLimit{
+						RequestsPerSecond: l7.RateLimit.RequestsPerSecond,
+						Burst:             l7.RateLimit.Burst,
+					}
+				}
+				httpRules = append(httpRules, httpRule)
 			}
 			SortHTTPNetworkPolicyRules(httpRules)
 			r.L7 = &cilium.PortNetworkPolicyRule_HttpRules{
@@ -488,6 +571,9 @@ func getPortNetworkPolicyRule(sel api.EndpointSelector, l7Parser policy.L7Parser
 	case policy.ParserTypeKafka:
 		// TODO: Support Kafka. For now, just ignore any Kafka L7 rule.
 
+	case policy.ParserTypeDNS:
+		// DNS is handled by the DNS proxy in the agent, not by Envoy.
+
 	default:
 		// Assume unknown parser types use a Key-Value Pair policy
 		if len(l7Rules.L7) > 0 {
@@ -328,6 +328,140 @@ func (s *ServerSuite) TestGetHTTPRule(c *C) {
 	c.Assert(obtained, checker.DeepEquals, ExpectedHeaders1)
 }
 
+func (s *ServerSuite) TestGetHTTPRuleGRPC(c *C) {
+	obtained, ruleRef := getHTTPRule(&api.PortRuleHTTP{
+		GRPC: &api.PortRuleGRPC{Service: "helloworld.Greeter", Method: "SayHello"},
+	})
+	c.Assert(obtained, checker.DeepEquals, []*envoy_api_v2_route.HeaderMatcher{
+		{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "/helloworld.Greeter/SayHello"},
+		},
+		{
+			Name:                 "content-type",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"},
+		},
+	})
+	c.Assert(ruleRef, Equals, `GRPCService("helloworld.Greeter") && GRPCMethod("SayHello")`)
+
+	// All methods of the service
+	obtained, _ = getHTTPRule(&api.PortRuleHTTP{
+		Host: "greeter",
+		GRPC: &api.PortRuleGRPC{Service: "helloworld.Greeter"},
+	})
+	c.Assert(obtained, checker.DeepEquals, []*envoy_api_v2_route.HeaderMatcher{
+		{
+			Name:                 ":authority",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: "greeter"},
+		},
+		{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/helloworld.Greeter/"},
+		},
+		{
+			Name:                 "content-type",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"},
+		},
+	})
+}
+
+func (s *ServerSuite) TestGetHTTPRuleHeaderMatches(c *C) {
+	obtained, ruleRef := getHTTPRule(&api.PortRuleHTTP{
+		HeaderMatches: []api.HeaderMatch{
+			{Name: "x-debug", Invert: true},
+			{Name: "x-api-version", ValueRegex: "v[23]"},
+			{Name: "x-tenant", Value: "test", Invert: true},
+		},
+	})
+	c.Assert(obtained, checker.DeepEquals, []*envoy_api_v2_route.HeaderMatcher{
+		{
+			Name:                 "x-api-version",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: "v[23]"},
+		},
+		{
+			Name:                 "x-debug",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PresentMatch{PresentMatch: true},
+			InvertMatch:          true,
+		},
+		{
+			Name:                 "x-tenant",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "test"},
+			InvertMatch:          true,
+		},
+	})
+	c.Assert(ruleRef, Equals, `!Header("x-debug") && HeaderRegexp("x-api-version","v[23]") && !Header("x-tenant","test")`)
+}
+
+func (s *ServerSuite) TestGetPortNetworkPolicyRuleHeaderActions(c *C) {
+	l7Rules := api.L7Rules{HTTP: []api.PortRuleHTTP{
+		{
+			Path:          "/public",
+			AddHeaders:    []api.HTTPHeader{{Name: "x-source-identity", Value: api.SourceIdentityPlaceholder}},
+			RemoveHeaders: []string{"authorization"},
+		},
+		{
+			Path:       "/public",
+			AddHeaders: []api.HTTPHeader{{Name: "x-public", Value: "true"}},
+		},
+	}}
+	obtained := getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeHTTP, l7Rules,
+		IdentityCache, DeniedIdentitiesNone)
+
+	headers := []*envoy_api_v2_route.HeaderMatcher{
+		{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: "/public"},
+		},
+	}
+	c.Assert(obtained, checker.DeepEquals, &cilium.PortNetworkPolicyRule{
+		RemotePolicies: []uint64{1001, 1002},
+		L7: &cilium.PortNetworkPolicyRule_HttpRules{
+			HttpRules: &cilium.HttpNetworkPolicyRules{
+				HttpRules: []*cilium.HttpNetworkPolicyRule{
+					{
+						Headers:      headers,
+						HeadersToAdd: []*envoy_api_v2_core.HeaderValue{{Key: "x-public", Value: "true"}},
+					},
+					{
+						Headers:         headers,
+						HeadersToAdd:    []*envoy_api_v2_core.HeaderValue{{Key: "x-source-identity", Value: "%SOURCE_IDENTITY%"}},
+						HeadersToRemove: []string{"authorization"},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (s *ServerSuite) TestGetPortNetworkPolicyRuleRateLimit(c *C) {
+	l7Rules := api.L7Rules{HTTP: []api.PortRuleHTTP{
+		{Path: "/public", RateLimit: &api.HTTPRateLimit{RequestsPerSecond: 10, Burst: 20}},
+		{Path: "/public", RateLimit: &api.HTTPRateLimit{RequestsPerSecond: 10}},
+		{Path: "/public"},
+	}}
+	obtained := getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeHTTP, l7Rules,
+		IdentityCache, DeniedIdentitiesNone)
+
+	headers := []*envoy_api_v2_route.HeaderMatcher{
+		{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_RegexMatch{RegexMatch: "/public"},
+		},
+	}
+	c.Assert(obtained, checker.DeepEquals, &cilium.PortNetworkPolicyRule{
+		RemotePolicies: []uint64{1001, 1002},
+		L7: &cilium.PortNetworkPolicyRule_HttpRules{
+			HttpRules: &cilium.HttpNetworkPolicyRules{
+				HttpRules: []*cilium.HttpNetworkPolicyRule{
+					{Headers: headers},
+					{Headers: headers, RateLimit: &cilium.HttpRateLimit{RequestsPerSecond: 10}},
+					{Headers: headers, RateLimit: &cilium.HttpRateLimit{RequestsPerSecond: 10, Burst: 20}},
+				},
+			},
+		},
+	})
+}
+
 func (s *ServerSuite) TestGetPortNetworkPolicyRule(c *C) {
 	obtained := getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeHTTP, L7Rules1,
 		IdentityCache, DeniedIdentitiesNone)
@@ -179,6 +179,62 @@ func HTTPNetworkPolicyRuleLess(r1, r2 *cilium.HttpNetworkPolicyRule) bool {
 		}
 	}
 
+	add1, add2 := r1.HeadersToAdd, r2.HeadersToAdd
+	switch {
+	case len(add1) < len(add2):
+		return true
+	case len(add1) > len(add2):
+		return false
+	}
+	// Headers are added in order, so the slices are not sorted.
+	for idx := range add1 {
+		switch {
+		case add1[idx].Key < add2[idx].Key:
+			return true
+		case add1[idx].Key > add2[idx].Key:
+			return false
+		case add1[idx].Value < add2[idx].Value:
+			return true
+		case add1[idx].Value > add2[idx].Value:
+			return false
+		}
+	}
+
+	remove1, remove2 := r1.HeadersToRemove, r2.HeadersToRemove
+	switch {
+	case len(remove1) < len(remove2):
+		return true
+	case len(remove1) > len(remove2):
+		return false
+	}
+	for idx := range remove1 {
+		switch {
+		case remove1[idx] < remove2[idx]:
+			return true
+		case remove1[idx] > remove2[idx]:
+			return false
+		}
+	}
+
+	limit1, limit2 := r1.RateLimit, r2.RateLimit
+	switch {
+	case limit1 == nil && limit2 != nil:
+		return true
+	case limit1 != nil && limit2 == nil:
+		return false
+	case limit1 != nil && limit2 != nil:
+		switch {
+		case limit1.RequestsPerSecond < limit2.RequestsPerSecond:
+			return true
+		case limit1.RequestsPerSecond > limit2.RequestsPerSecond:
+			return false
+		case limit1.Burst < limit2.Burst:
+			return true
+		case limit1.Burst > limit2.Burst:
+			return false
+		}
+	}
+
 	// Elements are equal.
 	return false
 }
@@ -17,6 +17,7 @@ package fqdn
 import (
 	"bytes"
 	"net"
+	"regexp"
 	"sort"
 	"time"
 
@@ -147,6 +148,31 @@ func (c *DNSCache) Lookup(name string) (ips []net.IP) {
 	return c.lookupByTime(time.Now(), name)
 }
 
+// LookupByRegexp returns all non-expired cache entries that match re as a map
+// of name -> IPs. The IPs for each name are returned sorted.
+func (c *DNSCache) LookupByRegexp(re *regexp.Regexp) (matches map[string][]net.IP) {
+	return c.lookupByRegexpByTime(time.Now(), re)
+}
+
+// lookupByRegexpByTime takes a timestamp for expiration comparisions, and is
+// only intended for testing.
+func (c *DNSCache) lookupByRegexpByTime(now time.Time, re *regexp.Regexp) (matches map[string][]net.IP) {
+	matches = make(map[string][]net.IP)
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for name, entries := range c.forward {
+		if re.MatchString(name) {
+			if ips := entries.getIPs(now); len(ips) > 0 {
+				matches[name] = ips
+			}
+		}
+	}
+
+	return matches
+}
+
 // lookupByTime takes a timestamp for expiration comparisions, and is only
 // intended for testing.
 func (c *DNSCache) lookupByTime(now time.Time, name string) (ips []net.IP) {
@@ -158,6 +184,32 @@ func (c *DNSCache) lookupByTime(now time.Time, name string) (ips []net.IP) {
 	return entries.getIPs(now)
 }
 
+// GC removes expired entries from the cache. It returns the names whose set of
+// valid IPs changed as a result. Names with no remaining entries are removed.
+func (c *DNSCache) GC() (affectedNames []string) {
+	return c.gcByTime(time.Now())
+}
+
+// gcByTime takes a timestamp for expiration comparisions, and is only
+// intended for testing.
+func (c *DNSCache) gcByTime(now time.Time) (affectedNames []string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for name, entries := range c.forward {
+		countBefore := len(entries)
+		c.removeExpired(entries, now)
+		if len(entries) != countBefore {
+			affectedNames = append(affectedNames, name)
+		}
+		if len(entries) == 0 {
+			delete(c.forward, name)
+		}
+	}
+
+	return affectedNames
+}
+
 // updateWithEntry adds a mapping for every IP found in `entry` to `ipEntries`
 // (which maps IP -> cacheEntry). It will replace existing IP->old mappings in
 // `entries` if the current entry expires sooner (or has already expired).
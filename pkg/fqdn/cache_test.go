@@ -18,9 +18,12 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"regexp"
 	"sort"
 	"time"
 
+	"github.com/cilium/cilium/pkg/checker"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -173,6 +176,47 @@ func makeEntries(now time.Time, live, redundant, expired int) (entries []*cacheE
 	return entries
 }
 
+// TestLookupByRegexp tests that names matching a regexp are returned with
+// their unexpired IPs, and that expired names are omitted.
+func (ds *DNSCacheTestSuite) TestLookupByRegexp(c *C) {
+	now := time.Now()
+	cache := NewDNSCache()
+	cache.Update(now, "www.cilium.io.", []net.IP{net.ParseIP("1.1.1.1")}, 2)
+	cache.Update(now, "api.cilium.io.", []net.IP{net.ParseIP("2.2.2.2")}, 4)
+	cache.Update(now, "github.com.", []net.IP{net.ParseIP("3.3.3.3")}, 4)
+
+	re := regexp.MustCompile(`^[-a-zA-Z0-9_]*[.]cilium[.]io[.]$`)
+
+	matches := cache.lookupByRegexpByTime(now.Add(time.Second), re)
+	c.Assert(len(matches), Equals, 2, Commentf("Incorrect number of names matched"))
+	c.Assert(matches["www.cilium.io."][0].String(), Equals, "1.1.1.1", Commentf("Incorrect IP returned"))
+	c.Assert(matches["api.cilium.io."][0].String(), Equals, "2.2.2.2", Commentf("Incorrect IP returned"))
+
+	matches = cache.lookupByRegexpByTime(now.Add(3*time.Second), re)
+	c.Assert(len(matches), Equals, 1, Commentf("Expired name was matched"))
+	c.Assert(matches["api.cilium.io."][0].String(), Equals, "2.2.2.2", Commentf("Incorrect IP returned"))
+}
+
+// TestGC tests that GC removes expired entries, and reports the names whose
+// IPs changed.
+func (ds *DNSCacheTestSuite) TestGC(c *C) {
+	now := time.Now()
+	cache := NewDNSCache()
+	cache.Update(now, "a.cilium.io.", []net.IP{net.ParseIP("1.1.1.1")}, 2)
+	cache.Update(now, "b.cilium.io.", []net.IP{net.ParseIP("2.2.2.2")}, 2)
+	cache.Update(now, "b.cilium.io.", []net.IP{net.ParseIP("3.3.3.3")}, 4)
+	cache.Update(now, "c.cilium.io.", []net.IP{net.ParseIP("4.4.4.4")}, 4)
+
+	affected := cache.gcByTime(now.Add(time.Second))
+	c.Assert(len(affected), Equals, 0, Commentf("Names affected by GC before any expiration"))
+
+	affected = cache.gcByTime(now.Add(3 * time.Second))
+	sort.Strings(affected)
+	c.Assert(affected, checker.DeepEquals, []string{"a.cilium.io.", "b.cilium.io."})
+	c.Assert(len(cache.forward), Equals, 2, Commentf("Name without entries not removed"))
+	c.Assert(len(cache.lookupByTime(now.Add(3*time.Second), "b.cilium.io.")), Equals, 1)
+}
+
 // Note: each "op" works on size things
 func (ds *DNSCacheTestSuite) BenchmarkGetIPs(c *C) {
 	c.StopTimer()
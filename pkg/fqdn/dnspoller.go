@@ -16,10 +16,12 @@ package fqdn
 
 import (
 	"net"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/policy/api"
@@ -99,6 +101,16 @@ type DNSPoller struct {
 	// The UUID -> rule mapping is allRules below.
 	sourceRules map[string]map[string]struct{}
 
+	// sourcePatterns maps sanitized matchPatterns to a set of rule UUIDs that
+	// depend on that pattern. Patterns are never polled. Instead, rules that
+	// depend on a pattern are regenerated whenever the IPs of a name matching
+	// the pattern change.
+	sourcePatterns map[string]map[string]struct{}
+
+	// patternMatchers maps sanitized matchPatterns in sourcePatterns to their
+	// compiled regexp.
+	patternMatchers map[string]*regexp.Regexp
+
 	// allRules is the global source of truth for rules we are managing. It maps
 	// UUID to the rule copy.
 	allRules map[string]*api.Rule
@@ -130,6 +142,11 @@ type DNSPollerConfig struct {
 	// AddGeneratedRules is a callback  to emit generated rules.
 	// When set to nil, it is a no-op.
 	AddGeneratedRules func([]*api.Rule) error
+
+	// DisablePolling stops the poller from running DNS lookups. DNS data is
+	// then only inserted via UpdateGenerateDNS, e.g. from the DNS proxy.
+	// Expired DNS data is still removed periodically.
+	DisablePolling bool
 }
 
 // NewDNSPoller creates an initialized DNSPoller. It does not start the controller (use .Start)
@@ -151,11 +168,13 @@ func NewDNSPoller(config DNSPollerConfig) *DNSPoller {
 	}
 
 	return &DNSPoller{
-		config:      config,
-		IPs:         make(map[string][]net.IP),
-		sourceRules: make(map[string]map[string]struct{}),
-		allRules:    make(map[string]*api.Rule),
-		cache:       config.Cache,
+		config:          config,
+		IPs:             make(map[string][]net.IP),
+		sourceRules:     make(map[string]map[string]struct{}),
+		sourcePatterns:  make(map[string]map[string]struct{}),
+		patternMatchers: make(map[string]*regexp.Regexp),
+		allRules:        make(map[string]*api.Rule),
+		cache:           config.Cache,
 	}
 }
 
@@ -189,7 +208,7 @@ perRule:
 		// function is called. This avoids accumulating generated toCIDRSet entries.
 		stripToCIDRSet(sourceRule)
 		// update IPs in this rule, best effort from the cache
-		injectToCIDRSetRules(sourceRule, poller.IPs)
+		injectToCIDRSetRules(sourceRule, poller.cache, poller.IPs)
 	}
 }
 
@@ -259,25 +278,51 @@ func (poller *DNSPoller) StopPollForDNSName(sourceRules []*api.Rule) {
 // The general steps are:
 // 1- take a snapshot of DNS names to lookup from poller, into dnsNamesToPoll
 // 2- Do a DNS lookup for each DNS name (map key) in poller via LookupDNSNames
-// 3- Update IPs for each dnsName in poller. If the IPs have changed for the
+// 3- Remove expired DNS data, and store which rules relied on it
+// 4- Update IPs for each dnsName in poller. If the IPs have changed for the
 // name, store which rules must be updated in rulesToUpdate. This is a set and
 // is deduped
-// 4- For each rule in rulesToUpdate, generate a new policy rule with IPs
-// 5- If we have any rules to update, emit them with AddGeneratedRules
+// 5- For each rule in rulesToUpdate, generate a new policy rule with IPs
+// 6- If we have any rules to update, emit them with AddGeneratedRules
+// Steps 1 and 2 are skipped when DNSPollerConfig.DisablePolling is set.
 func (poller *DNSPoller) LookupUpdateDNS() error {
-	// Collect the DNS names that need lookups. This avoids locking
-	// poller during lookups.
-	dnsNamesToPoll := poller.GetDNSNames()
-
-	// lookup the DNS names. Names with failures will not be updated (and we
-	// will use the most recent data below)
+	var updatedDNSIPs map[string]*DNSIPRecords
 	lookupTime := time.Now()
-	updatedDNSIPs, errorDNSNames := poller.config.LookupDNSNames(dnsNamesToPoll)
-	for dnsName, err := range errorDNSNames {
-		log.WithError(err).WithField("matchName", dnsName).
-			Warn("Cannot resolve FQDN. Traffic egressing to this destination may be incorrectly dropped due to stale data.")
+
+	if !poller.config.DisablePolling {
+		// Collect the DNS names that need lookups. This avoids locking
+		// poller during lookups.
+		dnsNamesToPoll := poller.GetDNSNames()
+
+		// lookup the DNS names. Names with failures will not be updated (and we
+		// will use the most recent data below)
+		var errorDNSNames map[string]error
+		updatedDNSIPs, errorDNSNames = poller.config.LookupDNSNames(dnsNamesToPoll)
+		for dnsName, err := range errorDNSNames {
+			log.WithError(err).WithField("matchName", dnsName).
+				Warn("Cannot resolve FQDN. Traffic egressing to this destination may be incorrectly dropped due to stale data.")
+		}
 	}
 
+	// Rules relying on expired DNS data are regenerated along with the ones
+	// affected by the lookups above
+	expiredUUIDs := poller.removeExpiredDNS()
+
+	return poller.updateGenerateDNS(lookupTime, updatedDNSIPs, expiredUUIDs)
+}
+
+// UpdateGenerateDNS inserts DNS data that was not looked up by the poller,
+// e.g. DNS responses seen by the DNS proxy, and emits regenerated policy rules
+// for the rules affected by it. Any name may be inserted, not only names the
+// poller polls for, as the data may match a matchPattern.
+// It returns once AddGeneratedRules returns.
+func (poller *DNSPoller) UpdateGenerateDNS(lookupTime time.Time, updatedDNSIPs map[string]*DNSIPRecords) error {
+	return poller.updateGenerateDNS(lookupTime, updatedDNSIPs, nil)
+}
+
+// updateGenerateDNS inserts updatedDNSIPs and emits regenerated rules for all
+// rules affected by the new data, as well as the rules in extraUUIDs.
+func (poller *DNSPoller) updateGenerateDNS(lookupTime time.Time, updatedDNSIPs map[string]*DNSIPRecords, extraUUIDs []string) error {
 	// Update IPs in poller
 	uuidsToUpdate, updatedDNSNames := poller.UpdateDNSIPs(lookupTime, updatedDNSIPs)
 	for dnsName, IPs := range updatedDNSNames {
@@ -287,6 +332,7 @@ func (poller *DNSPoller) LookupUpdateDNS() error {
 			"uuidsToUpdate": uuidsToUpdate,
 		}).Debug("Updated FQDN with new IPs")
 	}
+	uuidsToUpdate = mergeUUIDs(uuidsToUpdate, extraUUIDs)
 
 	// Generate a new rule for each sourceRule that needs an update.
 	rulesToUpdate, notFoundUUIDs := poller.GetRulesByUUID(uuidsToUpdate)
@@ -346,9 +392,7 @@ perDNSName:
 
 		// accumulate the rules affected by new IPs, that we need to update with
 		// CIDR rules
-		for uuid := range poller.sourceRules[dnsName] {
-			affectedRulesSet[uuid] = struct{}{}
-		}
+		poller.collectRulesForName(dnsName, affectedRulesSet)
 	}
 
 	// Convert the set to a list
@@ -359,6 +403,44 @@ perDNSName:
 	return affectedRules, updatedNames
 }
 
+// removeExpiredDNS removes expired DNS data from the cache and returns the
+// UUIDs of the rules that relied on it.
+func (poller *DNSPoller) removeExpiredDNS() (affectedRules []string) {
+	affectedRulesSet := make(map[string]struct{})
+
+	poller.Lock()
+	defer poller.Unlock()
+
+	for _, dnsName := range poller.cache.GC() {
+		if _, polled := poller.IPs[dnsName]; polled {
+			poller.IPs[dnsName] = poller.cache.Lookup(dnsName)
+		}
+		poller.collectRulesForName(dnsName, affectedRulesSet)
+	}
+
+	for uuid := range affectedRulesSet {
+		affectedRules = append(affectedRules, uuid)
+	}
+
+	return affectedRules
+}
+
+// collectRulesForName adds the UUIDs of all rules that select dnsName, via a
+// matchName or a matchPattern, to rulesSet.
+func (poller *DNSPoller) collectRulesForName(dnsName string, rulesSet map[string]struct{}) {
+	for uuid := range poller.sourceRules[dnsName] {
+		rulesSet[uuid] = struct{}{}
+	}
+
+	for pattern, matcher := range poller.patternMatchers {
+		if matcher.MatchString(dnsName) {
+			for uuid := range poller.sourcePatterns[pattern] {
+				rulesSet[uuid] = struct{}{}
+			}
+		}
+	}
+}
+
 // GetRulesByUUID returns the sourceRule copies of inserted rules. These are
 // the source of truth when generating rules with update IPs.
 // sourceRules is the list of *api.Rule objects that were found (i.e. currently
@@ -396,7 +478,7 @@ func (poller *DNSPoller) GenerateRulesFromSources(sourceRules []*api.Rule) (gene
 
 	for _, sourceRule := range sourceRules {
 		newRule := sourceRule.DeepCopy()
-		namesMissingIPs := injectToCIDRSetRules(newRule, poller.IPs)
+		namesMissingIPs := injectToCIDRSetRules(newRule, poller.cache, poller.IPs)
 		for _, missing := range namesMissingIPs {
 			namesMissingMap[missing] = struct{}{}
 		}
@@ -421,11 +503,17 @@ func (poller *DNSPoller) addRule(uuid string, sourceRule *api.Rule) (newDNSNames
 	// possible names to stop polling for in namesToStopPolling. As we add names
 	// from the new rule below, these are cleared.
 	namesToStopPolling := make(map[string]struct{})
+	patternsToRemove := make(map[string]struct{})
 	if oldRule, exists := poller.allRules[uuid]; exists {
 		for _, egressRule := range oldRule.Egress {
 			for _, ToFQDN := range egressRule.ToFQDNs {
-				matchName := dns.Fqdn(ToFQDN.MatchName)
-				namesToStopPolling[matchName] = struct{}{}
+				if ToFQDN.MatchName != "" {
+					matchName := dns.Fqdn(ToFQDN.MatchName)
+					namesToStopPolling[matchName] = struct{}{}
+				}
+				if ToFQDN.MatchPattern != "" {
+					patternsToRemove[matchpattern.Sanitize(ToFQDN.MatchPattern)] = struct{}{}
+				}
 			}
 		}
 	}
@@ -436,6 +524,15 @@ func (poller *DNSPoller) addRule(uuid string, sourceRule *api.Rule) (newDNSNames
 	// Add a dnsname -> rule reference
 	for _, egressRule := range sourceRule.Egress {
 		for _, ToFQDN := range egressRule.ToFQDNs {
+			if ToFQDN.MatchPattern != "" {
+				pattern := matchpattern.Sanitize(ToFQDN.MatchPattern)
+				delete(patternsToRemove, pattern)
+				poller.addPattern(pattern, uuid)
+			}
+
+			if ToFQDN.MatchName == "" {
+				continue
+			}
 			dnsName := dns.Fqdn(ToFQDN.MatchName)
 
 			delete(namesToStopPolling, dnsName)
@@ -461,6 +558,11 @@ func (poller *DNSPoller) addRule(uuid string, sourceRule *api.Rule) (newDNSNames
 		}
 	}
 
+	// Remove references to the uuid from patterns no longer in the rule
+	for pattern := range patternsToRemove {
+		poller.removeFromPattern(pattern, uuid)
+	}
+
 	return newDNSNames, oldDNSNames
 }
 
@@ -475,6 +577,13 @@ func (poller *DNSPoller) removeRule(uuid string, sourceRule *api.Rule) (noLonger
 	// Delete dnsname -> rule references
 	for _, egressRule := range sourceRule.Egress {
 		for _, ToFQDN := range egressRule.ToFQDNs {
+			if ToFQDN.MatchPattern != "" {
+				poller.removeFromPattern(matchpattern.Sanitize(ToFQDN.MatchPattern), uuid)
+			}
+
+			if ToFQDN.MatchName == "" {
+				continue
+			}
 			dnsName := dns.Fqdn(ToFQDN.MatchName)
 
 			if shouldStopPolling := poller.removeFromDNSName(dnsName, uuid); shouldStopPolling {
@@ -509,6 +618,35 @@ func (poller *DNSPoller) removeFromDNSName(dnsName, uuid string) (shouldStopPoll
 	return shouldStopPolling
 }
 
+// addPattern adds uuid as a dependent of the sanitized pattern, compiling the
+// pattern when it is seen for the first time. Invalid patterns are rejected
+// during rule validation, but are logged and ignored here as well.
+func (poller *DNSPoller) addPattern(pattern, uuid string) {
+	if _, exists := poller.sourcePatterns[pattern]; !exists {
+		matcher, err := matchpattern.Validate(pattern)
+		if err != nil {
+			log.WithError(err).WithField("matchPattern", pattern).
+				Warn("Ignoring invalid ToFQDN matchPattern")
+			return
+		}
+		poller.sourcePatterns[pattern] = make(map[string]struct{})
+		poller.patternMatchers[pattern] = matcher
+	}
+
+	poller.sourcePatterns[pattern][uuid] = struct{}{}
+}
+
+// removeFromPattern removes the uuid from the set of dependents of the
+// sanitized pattern. The pattern is forgotten once no rules depend on it.
+func (poller *DNSPoller) removeFromPattern(pattern, uuid string) {
+	delete(poller.sourcePatterns[pattern], uuid)
+
+	if len(poller.sourcePatterns[pattern]) == 0 {
+		delete(poller.sourcePatterns, pattern)
+		delete(poller.patternMatchers, pattern)
+	}
+}
+
 // ensureExists ensures that we have allocated objects for dnsName, and creates
 // them if needed.
 func (poller *DNSPoller) ensureExists(dnsName string) (exists bool) {
@@ -522,10 +660,13 @@ func (poller *DNSPoller) ensureExists(dnsName string) (exists bool) {
 }
 
 // updateIPsName will update the IPs for dnsName. It always retains a copy of
-// newIPs.
+// newIPs in the cache, and in poller.IPs if dnsName is polled.
 // updated is true when the new IPs differ from the old IPs
 func (poller *DNSPoller) updateIPsForName(lookupTime time.Time, dnsName string, newIPs []net.IP, ttl int) (updated bool) {
-	oldIPs := poller.IPs[dnsName]
+	oldIPs, polled := poller.IPs[dnsName]
+	if !polled {
+		oldIPs = poller.cache.Lookup(dnsName)
+	}
 
 	if poller.config.MinTTL > ttl {
 		ttl = poller.config.MinTTL
@@ -536,7 +677,11 @@ func (poller *DNSPoller) updateIPsForName(lookupTime time.Time, dnsName string,
 	sortedNewIPs := poller.cache.Lookup(dnsName) // DNSCache returns IPs sorted
 
 	// store the new IPs, sorted (to help with the updated determination below)
-	poller.IPs[dnsName] = sortedNewIPs
+	// Names that are not polled are only kept in the cache, inserting them
+	// into poller.IPs would start polling them.
+	if polled {
+		poller.IPs[dnsName] = sortedNewIPs
+	}
 
 	return !sortedIPsAreEqual(sortedNewIPs, oldIPs)
 }
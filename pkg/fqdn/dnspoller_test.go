@@ -19,7 +19,9 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
+	"github.com/cilium/cilium/pkg/checker"
 	"github.com/cilium/cilium/pkg/policy/api"
 	"github.com/miekg/dns"
 
@@ -52,6 +54,25 @@ func makeRule(key string, dnsNames ...string) *api.Rule {
 	return mustParseRule(rule)
 }
 
+func makePatternRule(key, pattern string) *api.Rule {
+	rule := fmt.Sprintf(`{
+	"labels": [{ "key": "%s" }],
+  "endpointSelector": {
+    "matchLabels": {
+      "class": "xwing"
+    }
+  },
+  "egress": [
+    {
+      "toFQDNs": [
+        { "matchPattern": "%s" }
+      ]
+    }
+  ]
+}`, key, pattern)
+	return mustParseRule(rule)
+}
+
 func parseRule(rule string) (parsedRule *api.Rule, err error) {
 	if err := json.Unmarshal([]byte(rule), &parsedRule); err != nil {
 		return nil, err
@@ -550,3 +571,109 @@ func (ds *FQDNTestSuite) TestDNSPollerUpdatesOnReplace(c *C) {
 	c.Assert(len(rules[0].Egress), Equals, 1, Commentf("Incorrect number of generated egress rules for testCase with single cached ToFQDNs DNS entry"))
 	c.Assert(len(rules[0].Egress[0].ToCIDRSet), Equals, 0, Commentf("Generated CIDR count is not the same as ToFQDNs DNS entries in cache"))
 }
+
+// TestDNSPollerMatchPattern tests that rules with a matchPattern are never
+// polled, but are regenerated with the IPs of every polled name they match.
+func (ds *FQDNTestSuite) TestDNSPollerMatchPattern(c *C) {
+	var (
+		lookups        = make(map[string]int)
+		generatedRules = make([]*api.Rule, 0)
+
+		poller = NewDNSPoller(DNSPollerConfig{
+			MinTTL: 1,
+			Cache:  NewDNSCache(),
+
+			LookupDNSNames: func(dnsNames []string) (DNSIPs map[string]*DNSIPRecords, errorDNSNames map[string]error) {
+				return lookupDNSNames(ipLookups, lookups, dnsNames)
+			},
+
+			AddGeneratedRules: func(rules []*api.Rule) error {
+				generatedRules = append(generatedRules, rules...)
+				return nil
+			},
+		})
+	)
+
+	patternRule := makePatternRule("patternRule", "*.io")
+
+	rulesToAdd := []*api.Rule{rule3.DeepCopy(), patternRule}
+	poller.MarkToFQDNRules(rulesToAdd)
+	poller.StartPollForDNSName(rulesToAdd)
+
+	err := poller.LookupUpdateDNS()
+	c.Assert(err, IsNil, Commentf("Error generating IP CIDR rules"))
+	c.Assert(len(lookups), Equals, 2, Commentf("matchPattern should not be polled"))
+	c.Assert(len(generatedRules), Equals, 2, Commentf("Incorrect number of generated rules"))
+
+	for _, rule := range generatedRules {
+		if rule.Egress[0].ToFQDNs[0].MatchPattern == "" {
+			continue
+		}
+		cidrs := rule.Egress[0].ToCIDRSet
+		c.Assert(len(cidrs), Equals, 2, Commentf("Incorrect number of CIDRs generated for matchPattern"))
+		c.Assert(cidrs[0].Cidr, Equals, api.CIDR("172.217.18.174/32"), Commentf("Incorrect IP CIDR generated"))
+		c.Assert(cidrs[1].Cidr, Equals, api.CIDR("2a00:1450:4001:811::200e/128"), Commentf("Incorrect IP CIDR generated"))
+	}
+
+	// A pattern rule inserted later picks up IPs already in the cache
+	lateRule := makePatternRule("lateRule", "*.io")
+	poller.MarkToFQDNRules([]*api.Rule{lateRule})
+	c.Assert(len(lateRule.Egress[0].ToCIDRSet), Equals, 2, Commentf("matchPattern did not use cached IPs on insert"))
+
+	// Removing the rule with the pattern stops updates for it
+	poller.StopPollForDNSName([]*api.Rule{patternRule})
+	c.Assert(len(poller.sourcePatterns), Equals, 0, Commentf("matchPattern not removed with its rule"))
+}
+
+// TestDNSPollerUpdateGenerateDNS tests that DNS data inserted from outside the
+// poller regenerates the rules that select it, without polling for new names.
+func (ds *FQDNTestSuite) TestDNSPollerUpdateGenerateDNS(c *C) {
+	var (
+		lookups        = make(map[string]int)
+		generatedRules = make([]*api.Rule, 0)
+
+		poller = NewDNSPoller(DNSPollerConfig{
+			MinTTL:         1,
+			Cache:          NewDNSCache(),
+			DisablePolling: true,
+
+			LookupDNSNames: func(dnsNames []string) (DNSIPs map[string]*DNSIPRecords, errorDNSNames map[string]error) {
+				return lookupDNSNames(ipLookups, lookups, dnsNames)
+			},
+
+			AddGeneratedRules: func(rules []*api.Rule) error {
+				generatedRules = append(generatedRules, rules...)
+				return nil
+			},
+		})
+	)
+
+	rulesToAdd := []*api.Rule{rule4.DeepCopy(), makePatternRule("patternRule", "*.cilium.io")}
+	poller.MarkToFQDNRules(rulesToAdd)
+	poller.StartPollForDNSName(rulesToAdd)
+
+	// A name matching only the pattern updates the pattern rule, and is not
+	// polled
+	err := poller.UpdateGenerateDNS(time.Now(), map[string]*DNSIPRecords{
+		dns.Fqdn("www.cilium.io"): {TTL: 60, IPs: []net.IP{net.ParseIP("1.1.1.1")}},
+	})
+	c.Assert(err, IsNil, Commentf("Error generating IP CIDR rules"))
+	c.Assert(len(generatedRules), Equals, 1, Commentf("Incorrect number of generated rules"))
+	c.Assert(generatedRules[0].Egress[0].ToFQDNs[0].MatchPattern, Equals, "*.cilium.io")
+	c.Assert(generatedRules[0].Egress[0].ToCIDRSet[0].Cidr, Equals, api.CIDR("1.1.1.1/32"), Commentf("Incorrect IP CIDR generated"))
+	c.Assert(poller.GetDNSNames(), checker.DeepEquals, []string{dns.Fqdn("github.com")}, Commentf("Name selected by a pattern should not be polled"))
+
+	// A matchName target is updated from the inserted data
+	generatedRules = nil
+	err = poller.UpdateGenerateDNS(time.Now(), map[string]*DNSIPRecords{
+		dns.Fqdn("github.com"): {TTL: 60, IPs: []net.IP{net.ParseIP("2.2.2.2")}},
+	})
+	c.Assert(err, IsNil, Commentf("Error generating IP CIDR rules"))
+	c.Assert(len(generatedRules), Equals, 1, Commentf("Incorrect number of generated rules"))
+	c.Assert(generatedRules[0].Egress[0].ToCIDRSet[0].Cidr, Equals, api.CIDR("2.2.2.2/32"), Commentf("Incorrect IP CIDR generated"))
+
+	// Polling is disabled
+	err = poller.LookupUpdateDNS()
+	c.Assert(err, IsNil)
+	c.Assert(len(lookups), Equals, 0, Commentf("DNS lookups were run with polling disabled"))
+}
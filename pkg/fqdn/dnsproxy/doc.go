@@ -0,0 +1,20 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnsproxy implements a transparent DNS proxy. DNS requests
+// redirected to it are forwarded to their original destination, and the
+// responses are passed to a callback before being returned to the client.
+// This allows ToFQDN policy to be updated with the exact data seen by the
+// endpoint before the endpoint can act on it.
+package dnsproxy
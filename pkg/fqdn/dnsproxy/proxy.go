@@ -0,0 +1,279 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsproxy
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ProxyForwardTimeout is the maximum time to wait for the original
+	// destination of a DNS request to respond.
+	ProxyForwardTimeout = 10 * time.Second
+)
+
+// LookupOriginalDstFunc returns the original destination of a request that
+// was redirected to the proxy, as an "IP:port" string, and the security
+// identity of the source endpoint. remoteAddr is the source address of the
+// redirected request and protocol is either "udp" or "tcp".
+type LookupOriginalDstFunc func(remoteAddr net.Addr, protocol string) (srcIdentity uint32, dstAddr string, err error)
+
+// DialOriginalDstFunc opens a connection to the original destination of a
+// request. srcIdentity is the security identity of the source endpoint, and
+// can be used to mark the connection.
+type DialOriginalDstFunc func(srcIdentity uint32, protocol, dstAddr string) (net.Conn, error)
+
+// CheckAllowedFunc decides whether a request from srcAddr, with the security
+// identity srcIdentity, to dstAddr over protocol may be forwarded. Requests
+// that are not allowed are answered with REFUSED.
+type CheckAllowedFunc func(srcAddr string, srcIdentity uint32, protocol, dstAddr string, request *dns.Msg) (allowed bool, err error)
+
+// NotifyOnDNSMsgFunc is called with each DNS response received from an
+// original destination, before the response is returned to the endpoint.
+// The response is held until the function returns. If it returns an error,
+// the response is dropped and the request is answered with SERVFAIL.
+type NotifyOnDNSMsgFunc func(lookupTime time.Time, srcAddr string, srcIdentity uint32, protocol, dstAddr string, msg *dns.Msg) error
+
+// DNSProxy is a transparent DNS proxy. It forwards each request allowed by
+// CheckAllowed to the original destination of the request, and calls
+// NotifyOnDNSMsg with the response before returning it.
+type DNSProxy struct {
+	// BindAddr is the address the proxy listens on for both UDP and TCP
+	BindAddr string
+
+	// BindPort is the port in BindAddr. It is set to the allocated port when
+	// the proxy was started with port 0.
+	BindPort uint16
+
+	// LookupOriginalDst finds the original destination of a redirected
+	// request.
+	LookupOriginalDst LookupOriginalDstFunc
+
+	// DialOriginalDst connects to the original destination of a request.
+	DialOriginalDst DialOriginalDstFunc
+
+	// CheckAllowed enforces policy on each request.
+	CheckAllowed CheckAllowedFunc
+
+	// NotifyOnDNSMsg is called with each DNS response before it is returned
+	// to the endpoint.
+	NotifyOnDNSMsg NotifyOnDNSMsgFunc
+
+	// UDPServer and TCPServer are the miekg/dns servers handling requests
+	UDPServer, TCPServer *dns.Server
+}
+
+// StartDNSProxy starts a proxy listening on address:port for both UDP and
+// TCP. When port is 0 a port is allocated, and is available in
+// DNSProxy.BindPort. The listening sockets are opened before this function
+// returns, so the proxy never refuses requests once it has been started.
+func StartDNSProxy(address string, port uint16, lookupOriginalDst LookupOriginalDstFunc,
+	dialOriginalDst DialOriginalDstFunc, checkAllowed CheckAllowedFunc, notifyFunc NotifyOnDNSMsgFunc) (*DNSProxy, error) {
+	if lookupOriginalDst == nil || dialOriginalDst == nil || checkAllowed == nil || notifyFunc == nil {
+		return nil, fmt.Errorf("DNS proxy must have lookupOriginalDst, dialOriginalDst, checkAllowed and notifyFunc set")
+	}
+
+	p := &DNSProxy{
+		BindAddr:          address,
+		LookupOriginalDst: lookupOriginalDst,
+		DialOriginalDst:   dialOriginalDst,
+		CheckAllowed:      checkAllowed,
+		NotifyOnDNSMsg:    notifyFunc,
+	}
+
+	// Bind TCP first, to allocate a port when port is 0, then use the same
+	// port for UDP.
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(int(port))))
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on TCP port %d: %s", port, err)
+	}
+	p.BindPort = uint16(listener.Addr().(*net.TCPAddr).Port)
+
+	conn, err := net.ListenPacket("udp", net.JoinHostPort(address, strconv.Itoa(int(p.BindPort))))
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("unable to listen on UDP port %d: %s", p.BindPort, err)
+	}
+
+	p.TCPServer = &dns.Server{Listener: listener, Handler: p}
+	p.UDPServer = &dns.Server{PacketConn: conn, Handler: p}
+
+	for _, s := range []*dns.Server{p.UDPServer, p.TCPServer} {
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				log.WithError(err).Error("DNS proxy server stopped")
+			}
+		}(s)
+	}
+
+	log.WithFields(logrus.Fields{
+		"address":      address,
+		logfields.Port: p.BindPort,
+	}).Info("Started DNS proxy")
+
+	return p, nil
+}
+
+// Stop shuts down both the UDP and TCP servers of the proxy.
+func (p *DNSProxy) Stop() {
+	for _, s := range []*dns.Server{p.UDPServer, p.TCPServer} {
+		if err := s.Shutdown(); err != nil {
+			log.WithError(err).Debug("Error while stopping DNS proxy server")
+		}
+	}
+}
+
+// ServeDNS handles a single DNS request. It implements dns.Handler.
+// Allowed requests are forwarded to their original destination and the
+// response is passed to NotifyOnDNSMsg before being written back to the
+// client. Denied requests are answered with REFUSED, and requests that cannot
+// be forwarded, or whose response NotifyOnDNSMsg fails to act upon, with
+// SERVFAIL.
+func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
+	protocol := w.RemoteAddr().Network()
+	scopedLog := log.WithFields(logrus.Fields{
+		"srcAddr":  w.RemoteAddr().String(),
+		"protocol": protocol,
+		"dnsID":    request.Id,
+	})
+	if len(request.Question) > 0 {
+		scopedLog = scopedLog.WithField(logfields.DNSName, request.Question[0].Name)
+	}
+	scopedLog.Debug("Handling DNS request")
+
+	srcIdentity, dstAddr, err := p.LookupOriginalDst(w.RemoteAddr(), protocol)
+	if err != nil {
+		scopedLog.WithError(err).Error("Cannot find original destination of DNS request")
+		p.sendRcode(scopedLog, w, request, dns.RcodeServerFailure)
+		return
+	}
+	scopedLog = scopedLog.WithField("dstAddr", dstAddr)
+
+	allowed, err := p.CheckAllowed(w.RemoteAddr().String(), srcIdentity, protocol, dstAddr, request)
+	if err != nil {
+		scopedLog.WithError(err).Error("Cannot check whether DNS request is allowed")
+		p.sendRcode(scopedLog, w, request, dns.RcodeServerFailure)
+		return
+	}
+	if !allowed {
+		scopedLog.Debug("Rejecting DNS request denied by policy")
+		p.sendRcode(scopedLog, w, request, dns.RcodeRefused)
+		return
+	}
+
+	response, err := p.forward(srcIdentity, protocol, dstAddr, request)
+	if err != nil {
+		scopedLog.WithError(err).Warn("Cannot forward DNS request to original destination")
+		p.sendRcode(scopedLog, w, request, dns.RcodeServerFailure)
+		return
+	}
+
+	// Hold the response until the data in it has been acted upon, otherwise
+	// the endpoint may attempt to connect before policy allows it.
+	if err := p.NotifyOnDNSMsg(time.Now(), w.RemoteAddr().String(), srcIdentity, protocol, dstAddr, response); err != nil {
+		scopedLog.WithError(err).Warn("Error while processing DNS response, returning SERVFAIL")
+		p.sendRcode(scopedLog, w, request, dns.RcodeServerFailure)
+		return
+	}
+
+	if err := w.WriteMsg(response); err != nil {
+		scopedLog.WithError(err).Error("Cannot write DNS response to client")
+	}
+}
+
+// forward sends request to dstAddr over protocol and returns the response.
+func (p *DNSProxy) forward(srcIdentity uint32, protocol, dstAddr string, request *dns.Msg) (*dns.Msg, error) {
+	conn, err := p.DialOriginalDst(srcIdentity, protocol, dstAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(ProxyForwardTimeout)); err != nil {
+		return nil, err
+	}
+
+	co := &dns.Conn{Conn: conn}
+	if protocol == "udp" {
+		co.UDPSize = dns.MaxMsgSize
+	}
+	if err := co.WriteMsg(request); err != nil {
+		return nil, err
+	}
+
+	response, err := co.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Id != request.Id {
+		return nil, fmt.Errorf("mismatched DNS response ID %d, expected %d", response.Id, request.Id)
+	}
+
+	return response, nil
+}
+
+// sendRcode answers request with an empty response with rcode set.
+func (p *DNSProxy) sendRcode(scopedLog *logrus.Entry, w dns.ResponseWriter, request *dns.Msg, rcode int) {
+	response := &dns.Msg{}
+	response.SetRcode(request, rcode)
+	if err := w.WriteMsg(response); err != nil {
+		scopedLog.WithError(err).Errorf("Cannot write %s response to client", dns.RcodeToString[rcode])
+	}
+}
+
+// ExtractMsgDetails returns the queried name, the A and AAAA IPs in the
+// answer section and the lowest TTL of all answers, including CNAMEs. The
+// IPs of CNAME targets are attributed to the queried name, as that is the
+// name the client requested.
+func ExtractMsgDetails(msg *dns.Msg) (qname string, responseIPs []net.IP, TTL uint32, err error) {
+	if len(msg.Question) == 0 {
+		return "", nil, 0, fmt.Errorf("invalid DNS message, no question section")
+	}
+	qname = msg.Question[0].Name
+
+	TTL = math.MaxUint32
+	for _, answer := range msg.Answer {
+		switch answer := answer.(type) {
+		case *dns.A:
+			responseIPs = append(responseIPs, answer.A)
+		case *dns.AAAA:
+			responseIPs = append(responseIPs, answer.AAAA)
+		case *dns.CNAME:
+		default:
+			// Other records do not affect the IPs of the name
+			continue
+		}
+
+		if answer.Header().Ttl < TTL {
+			TTL = answer.Header().Ttl
+		}
+	}
+
+	if TTL == math.MaxUint32 {
+		TTL = 0
+	}
+
+	return qname, responseIPs, TTL, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsproxy
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/pkg/lock"
+
+	"github.com/miekg/dns"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type DNSProxyTestSuite struct {
+	upstream   *dns.Server
+	proxy      *DNSProxy
+	proxyAddr  string
+	mutex      lock.Mutex
+	notified   []*dns.Msg
+	lookupFail bool
+	deny       bool
+	notifyFail bool
+}
+
+var _ = Suite(&DNSProxyTestSuite{})
+
+// upstreamHandler answers every A request with 1.1.1.1, behind a CNAME
+func upstreamHandler(w dns.ResponseWriter, request *dns.Msg) {
+	response := &dns.Msg{}
+	response.SetReply(request)
+	name := request.Question[0].Name
+	cname, _ := dns.NewRR(fmt.Sprintf("%s 60 IN CNAME target.cilium.test.", name))
+	a, _ := dns.NewRR("target.cilium.test. 30 IN A 1.1.1.1")
+	response.Answer = append(response.Answer, cname, a)
+	w.WriteMsg(response)
+}
+
+func (s *DNSProxyTestSuite) SetUpSuite(c *C) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	started := make(chan struct{})
+	s.upstream = &dns.Server{
+		PacketConn:        conn,
+		Handler:           dns.HandlerFunc(upstreamHandler),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go s.upstream.ActivateAndServe()
+	<-started
+	upstreamAddr := conn.LocalAddr().String()
+
+	s.proxy, err = StartDNSProxy("127.0.0.1", 0,
+		func(remoteAddr net.Addr, protocol string) (uint32, string, error) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			if s.lookupFail {
+				return 0, "", fmt.Errorf("no proxymap entry")
+			}
+			return 1234, upstreamAddr, nil
+		},
+		func(srcIdentity uint32, protocol, dstAddr string) (net.Conn, error) {
+			return net.Dial(protocol, dstAddr)
+		},
+		func(srcAddr string, srcIdentity uint32, protocol, dstAddr string, request *dns.Msg) (bool, error) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			return !s.deny, nil
+		},
+		func(lookupTime time.Time, srcAddr string, srcIdentity uint32, protocol, dstAddr string, msg *dns.Msg) error {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.notified = append(s.notified, msg)
+			if s.notifyFail {
+				return fmt.Errorf("policy update timed out")
+			}
+			return nil
+		})
+	c.Assert(err, IsNil)
+	c.Assert(s.proxy.BindPort, Not(Equals), uint16(0))
+	s.proxyAddr = fmt.Sprintf("127.0.0.1:%d", s.proxy.BindPort)
+}
+
+func (s *DNSProxyTestSuite) TearDownSuite(c *C) {
+	s.proxy.Stop()
+	s.upstream.Shutdown()
+}
+
+func (s *DNSProxyTestSuite) SetUpTest(c *C) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.notified = nil
+	s.lookupFail = false
+	s.deny = false
+	s.notifyFail = false
+}
+
+func (s *DNSProxyTestSuite) TestForwardAndNotify(c *C) {
+	request := &dns.Msg{}
+	request.SetQuestion("www.cilium.test.", dns.TypeA)
+
+	response, _, err := (&dns.Client{Net: "udp"}).Exchange(request, s.proxyAddr)
+	c.Assert(err, IsNil)
+	c.Assert(response.Rcode, Equals, dns.RcodeSuccess)
+	c.Assert(len(response.Answer), Equals, 2)
+
+	// The response was passed to the callback before it was returned
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.Assert(len(s.notified), Equals, 1)
+	c.Assert(s.notified[0].Id, Equals, request.Id)
+}
+
+func (s *DNSProxyTestSuite) TestServerFailureOnMissingDst(c *C) {
+	s.mutex.Lock()
+	s.lookupFail = true
+	s.mutex.Unlock()
+
+	request := &dns.Msg{}
+	request.SetQuestion("www.cilium.test.", dns.TypeA)
+
+	response, _, err := (&dns.Client{Net: "udp"}).Exchange(request, s.proxyAddr)
+	c.Assert(err, IsNil)
+	c.Assert(response.Rcode, Equals, dns.RcodeServerFailure)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.Assert(len(s.notified), Equals, 0)
+}
+
+func (s *DNSProxyTestSuite) TestRefusedWhenDenied(c *C) {
+	s.mutex.Lock()
+	s.deny = true
+	s.mutex.Unlock()
+
+	request := &dns.Msg{}
+	request.SetQuestion("www.cilium.test.", dns.TypeA)
+
+	response, _, err := (&dns.Client{Net: "udp"}).Exchange(request, s.proxyAddr)
+	c.Assert(err, IsNil)
+	c.Assert(response.Rcode, Equals, dns.RcodeRefused)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.Assert(len(s.notified), Equals, 0)
+}
+
+func (s *DNSProxyTestSuite) TestServerFailureOnNotifyError(c *C) {
+	s.mutex.Lock()
+	s.notifyFail = true
+	s.mutex.Unlock()
+
+	request := &dns.Msg{}
+	request.SetQuestion("www.cilium.test.", dns.TypeA)
+
+	// The response is not released if its data could not be acted upon
+	response, _, err := (&dns.Client{Net: "udp"}).Exchange(request, s.proxyAddr)
+	c.Assert(err, IsNil)
+	c.Assert(response.Rcode, Equals, dns.RcodeServerFailure)
+	c.Assert(len(response.Answer), Equals, 0)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c.Assert(len(s.notified), Equals, 1)
+}
+
+func (s *DNSProxyTestSuite) TestExtractMsgDetails(c *C) {
+	msg := &dns.Msg{}
+	msg.SetQuestion("www.cilium.test.", dns.TypeA)
+	cname, _ := dns.NewRR("www.cilium.test. 60 IN CNAME target.cilium.test.")
+	a, _ := dns.NewRR("target.cilium.test. 30 IN A 1.1.1.1")
+	aaaa, _ := dns.NewRR("target.cilium.test. 45 IN AAAA ::1")
+	msg.Answer = append(msg.Answer, cname, a, aaaa)
+
+	qname, IPs, TTL, err := ExtractMsgDetails(msg)
+	c.Assert(err, IsNil)
+	c.Assert(qname, Equals, "www.cilium.test.")
+	c.Assert(len(IPs), Equals, 2)
+	c.Assert(IPs[0].String(), Equals, "1.1.1.1")
+	c.Assert(IPs[1].String(), Equals, "::1")
+	c.Assert(TTL, Equals, uint32(30))
+
+	_, _, _, err = ExtractMsgDetails(&dns.Msg{})
+	c.Assert(err, Not(IsNil))
+}
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/policy/api"
 	"github.com/miekg/dns"
@@ -55,10 +56,12 @@ func generateUUIDLabel(lbls labels.LabelArray) (id *labels.Label) {
 }
 
 // injectToCIDRSetRules adds a ToCIDRSets section to the rule with all ToFQDN
-// targets resolved to IPs from dnsNames.
+// targets resolved to IPs. matchName targets are resolved from dnsNames, and
+// matchPattern targets from every name in cache that matches the pattern.
 // Pre-existing rules in ToCIDRSet are preserved.
 // Note: matchNames in rules are made into FQDNs
-func injectToCIDRSetRules(rule *api.Rule, dnsNames map[string][]net.IP) (namesMissingIPs []string) {
+// Note: matchPatterns that match no names are not reported in namesMissingIPs
+func injectToCIDRSetRules(rule *api.Rule, cache *DNSCache, dnsNames map[string][]net.IP) (namesMissingIPs []string) {
 	missing := make(map[string]struct{}) // a set to dedup missing dnsNames
 
 	// Add CIDR rules
@@ -68,6 +71,13 @@ func injectToCIDRSetRules(rule *api.Rule, dnsNames map[string][]net.IP) (namesMi
 
 		// Generate CIDR rules for each FQDN
 		for _, ToFQDN := range egressRule.ToFQDNs {
+			if ToFQDN.MatchPattern != "" {
+				egressRule.ToCIDRSet = append(egressRule.ToCIDRSet, ipsToRules(lookupPatternIPs(cache, ToFQDN.MatchPattern))...)
+			}
+
+			if ToFQDN.MatchName == "" {
+				continue
+			}
 			dnsName := dns.Fqdn(ToFQDN.MatchName)
 			IPs, present := dnsNames[dnsName]
 			if !present {
@@ -85,6 +95,21 @@ func injectToCIDRSetRules(rule *api.Rule, dnsNames map[string][]net.IP) (namesMi
 	return namesMissingIPs
 }
 
+// lookupPatternIPs returns the sorted, unique, IPs in cache for all names that
+// match pattern.
+func lookupPatternIPs(cache *DNSCache, pattern string) (IPs []net.IP) {
+	matcher, err := matchpattern.Validate(pattern)
+	if err != nil {
+		return nil
+	}
+
+	for _, nameIPs := range cache.LookupByRegexp(matcher) {
+		IPs = append(IPs, nameIPs...)
+	}
+
+	return keepUniqueIPs(IPs)
+}
+
 // stripeToCIDRSet ensures no ToCIDRSet is nil when ToFQDNs is non-nil
 func stripToCIDRSet(rule *api.Rule) {
 	for i := range rule.Egress {
@@ -123,6 +148,19 @@ func hasToFQDN(rule *api.Rule) bool {
 	return false
 }
 
+// mergeUUIDs returns the deduplicated union of the UUIDs in a and b.
+func mergeUUIDs(a, b []string) (merged []string) {
+	set := make(map[string]struct{}, len(a)+len(b))
+	for _, uuid := range append(a, b...) {
+		if _, seen := set[uuid]; !seen {
+			set[uuid] = struct{}{}
+			merged = append(merged, uuid)
+		}
+	}
+
+	return merged
+}
+
 // sortedIPsAreEqual compares two lists of sorted IPs. If any differ it returns
 // false.
 func sortedIPsAreEqual(a, b []net.IP) bool {
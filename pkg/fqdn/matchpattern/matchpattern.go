@@ -0,0 +1,83 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package matchpattern implements the glob-style wildcard patterns used by
+// the matchPattern field of toFQDNs selectors.
+package matchpattern
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// allowedDNSCharsREGroup is the regexp group of characters a "*" in a pattern
+// may expand to. It excludes "." so that a wildcard never spans more than one
+// DNS label.
+const allowedDNSCharsREGroup = "[-a-zA-Z0-9_]"
+
+// allowedPatternChars matches patterns consisting only of the characters
+// allowed in a matchPattern. Any other character could change the meaning of
+// the regexp the pattern is converted to.
+var allowedPatternChars = regexp.MustCompile("^[-a-zA-Z0-9_.*]+$")
+
+// Validate ensures that pattern is a parseable matchPattern. It returns the
+// regexp generated when validating. The regexp matches case insensitively, as
+// DNS names do.
+func Validate(pattern string) (matcher *regexp.Regexp, err error) {
+	pattern = strings.TrimSpace(pattern)
+	pattern = strings.ToLower(pattern)
+
+	if pattern == "" {
+		return nil, errors.New("empty matchPattern")
+	}
+
+	if !allowedPatternChars.MatchString(pattern) {
+		return nil, errors.New(`only alphanumeric ASCII characters, the hyphen "-", underscore "_", "." and "*" are allowed in a matchPattern`)
+	}
+
+	return regexp.Compile("(?i)" + ToRegexp(pattern))
+}
+
+// Sanitize canonicalizes a pattern for use in rules and caches. It lowercases
+// the pattern and makes it fully qualified, i.e. it ends with a ".".
+func Sanitize(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	pattern = strings.ToLower(pattern)
+
+	if pattern == "*" {
+		return pattern
+	}
+
+	return dns.Fqdn(pattern)
+}
+
+// ToRegexp converts a matchPattern to an anchored regexp string. Each "*"
+// matches zero or more valid DNS characters within a single label, and "."
+// matches a literal dot. The special pattern "*" matches all names.
+// Note: No validation is done here, use Validate for that.
+func ToRegexp(pattern string) string {
+	pattern = Sanitize(pattern)
+
+	if pattern == "*" {
+		return "(^(" + allowedDNSCharsREGroup + "+[.])+$)|(^[.]$)"
+	}
+
+	pattern = strings.Replace(pattern, ".", "[.]", -1)
+	pattern = strings.Replace(pattern, "*", allowedDNSCharsREGroup+"*", -1)
+
+	return "^" + pattern + "$"
+}
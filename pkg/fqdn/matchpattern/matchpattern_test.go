@@ -0,0 +1,107 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package matchpattern
+
+import (
+	"regexp"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type MatchPatternTestSuite struct{}
+
+var _ = Suite(&MatchPatternTestSuite{})
+
+func (ts *MatchPatternTestSuite) TestMatchPatternREConversion(c *C) {
+	for source, target := range map[string]string{
+		"cilium.io.":   "^cilium[.]io[.]$",
+		"*.cilium.io.": "^[-a-zA-Z0-9_]*[.]cilium[.]io[.]$",
+		"*":            "(^([-a-zA-Z0-9_]+[.])+$)|(^[.]$)",
+		".":            "^[.]$",
+		"Cilium.IO":    "^cilium[.]io[.]$",
+	} {
+		reStr := ToRegexp(source)
+		_, err := regexp.Compile(reStr)
+		c.Assert(err, IsNil, Commentf("Regexp generated from pattern %s is not valid", source))
+		c.Assert(reStr, Equals, target, Commentf("Regexp generated from pattern %s isn't expected", source))
+	}
+}
+
+func (ts *MatchPatternTestSuite) TestMatchPatternMatching(c *C) {
+	for _, testCase := range []struct {
+		pattern string
+		accept  []string
+		reject  []string
+	}{
+		{
+			pattern: "*",
+			accept:  []string{".", "io.", "cilium.io.", "svc.cluster.local."},
+			reject:  []string{"", "cilium.io"},
+		},
+		{
+			pattern: "cilium.io",
+			accept:  []string{"cilium.io."},
+			reject:  []string{"", "cilium.io", "www.cilium.io.", "ciliumio."},
+		},
+		{
+			pattern: "*.cilium.io",
+			accept:  []string{"www.cilium.io.", "api.cilium.io.", ".cilium.io.", "WWW.Cilium.IO."},
+			reject:  []string{"", "cilium.io.", "a.b.cilium.io.", "wwwcilium.io."},
+		},
+		{
+			pattern: "*.s3.amazonaws.com",
+			accept:  []string{"my-bucket.s3.amazonaws.com."},
+			reject:  []string{"s3.amazonaws.com.", "my.bucket.s3.amazonaws.com."},
+		},
+		{
+			pattern: "api*.cilium.io",
+			accept:  []string{"api.cilium.io.", "api2.cilium.io.", "api_v2.cilium.io."},
+			reject:  []string{"www.cilium.io.", "xapi.cilium.io."},
+		},
+	} {
+		matcher, err := Validate(testCase.pattern)
+		c.Assert(err, IsNil, Commentf("Pattern %s should be valid", testCase.pattern))
+		for _, accept := range testCase.accept {
+			c.Assert(matcher.MatchString(accept), Equals, true, Commentf("Pattern %s should match %s", testCase.pattern, accept))
+		}
+		for _, reject := range testCase.reject {
+			c.Assert(matcher.MatchString(reject), Equals, false, Commentf("Pattern %s should not match %s", testCase.pattern, reject))
+		}
+	}
+}
+
+func (ts *MatchPatternTestSuite) TestMatchPatternValidation(c *C) {
+	for _, pattern := range []string{
+		"",
+		"  ",
+		"cilium.io|evil.com",
+		"(cilium).io",
+		"[a-z].cilium.io",
+		"cilium+.io",
+		"^cilium.io$",
+		"cilium io",
+		"cilium.io/path",
+		"cilium@io",
+		"ciliüm.io",
+	} {
+		_, err := Validate(pattern)
+		c.Assert(err, Not(IsNil), Commentf("Pattern %q should be rejected", pattern))
+	}
+}
@@ -17,12 +17,10 @@ package ipam
 import (
 	"errors"
 	"fmt"
-	"math/big"
 	"net"
+	"sort"
 
-	"github.com/cilium/cilium/pkg/node"
-
-	k8sAPI "k8s.io/kubernetes/pkg/apis/core"
+	"github.com/cilium/cilium/common/addressing"
 )
 
 // Error definitions
@@ -34,51 +32,81 @@ var (
 	ErrIPv6Disabled = errors.New("IPv6 allocation disabled")
 )
 
-// AllocateIP allocates a IP address.
-func AllocateIP(ip net.IP) error {
-	ipamConf.allocatorMutex.Lock()
-	defer ipamConf.allocatorMutex.Unlock()
+// allocatorFor returns the allocator of the pool containing ip or the
+// allocator of the default pool responsible for the address family of ip
+func (c *Config) allocatorFor(ip net.IP) (Allocator, error) {
+	if allocator := c.poolAllocatorFor(ip); allocator != nil {
+		return allocator, nil
+	}
 
 	if ip.To4() != nil {
-		if ipamConf.IPv4Allocator == nil {
-			return ErrIPv4Disabled
+		if c.IPv4Allocator == nil {
+			return nil, ErrIPv4Disabled
 		}
+		return c.IPv4Allocator, nil
+	}
 
-		if err := ipamConf.IPv4Allocator.Allocate(ip); err != nil {
-			return err
-		}
-	} else {
-		if ipamConf.IPv6Allocator == nil {
-			return ErrIPv6Disabled
-		}
+	if c.IPv6Allocator == nil {
+		return nil, ErrIPv6Disabled
+	}
+	return c.IPv6Allocator, nil
+}
 
-		if err := ipamConf.IPv6Allocator.Allocate(ip); err != nil {
-			return err
-		}
+// AllocateIP allocates a IP address on behalf of owner. An address restored
+// from the checkpoint of a previous run is handed over to the first owner
+// allocating it.
+func AllocateIP(ip net.IP, owner string) error {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	allocator, err := ipamConf.allocatorFor(ip)
+	if err != nil {
+		return err
+	}
+
+	if err := ipamConf.allocate(allocator, ip, owner); err != nil {
+		return err
 	}
 
+	ipamConf.checkpoint()
 	return nil
 }
 
 // AllocateIPString is identical to AllocateIP but takes a string
-func AllocateIPString(ipAddr string) error {
+func AllocateIPString(ipAddr, owner string) error {
 	ip := net.ParseIP(ipAddr)
 	if ip == nil {
 		return fmt.Errorf("Invalid IP address: %s", ipAddr)
 	}
 
-	return AllocateIP(ip)
+	return AllocateIP(ip, owner)
 }
 
 // AllocateNext allocates the next available IPv4 and IPv6 address out of the
-// configured address pool. If family is set to "ipv4" or "ipv6", then
-// allocation is limited to the specified address family. If the pool has been
-// drained of addresses, an error will be returned.
-func AllocateNext(family string) (net.IP, net.IP, error) {
+// default address pool on behalf of owner. If family is set to "ipv4" or
+// "ipv6", then allocation is limited to the specified address family. If the
+// pool has been drained of addresses, an error will be returned.
+func AllocateNext(family, owner string) (net.IP, net.IP, error) {
+	return AllocateNextFromPool("", family, owner)
+}
+
+// AllocateNextFromPool is identical to AllocateNext but allocates out of the
+// named pool. Addresses of a family the pool has no addresses for are
+// allocated out of the default pool. The default pool is used if pool is
+// empty.
+func AllocateNextFromPool(pool, family, owner string) (net.IP, net.IP, error) {
 	var ipv4, ipv6 net.IP
 
-	if (family == "ipv6" || family == "") && ipamConf.IPv6Allocator != nil {
-		ipConf, err := ipamConf.IPv6Allocator.AllocateNext()
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	ipv4Allocator, ipv6Allocator, err := ipamConf.allocatorsForPool(pool)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if (family == "ipv6" || family == "") && ipv6Allocator != nil {
+		ipConf, err := ipamConf.allocateNextIPv6(ipv6Allocator)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -86,41 +114,141 @@ func AllocateNext(family string) (net.IP, net.IP, error) {
 		ipv6 = ipConf
 	}
 
-	if (family == "ipv4" || family == "") && ipamConf.IPv4Allocator != nil {
-		ipConf, err := ipamConf.IPv4Allocator.AllocateNext()
+	if (family == "ipv4" || family == "") && ipv4Allocator != nil {
+		ipConf, err := ipv4Allocator.AllocateNext()
 		if err != nil {
+			if ipv6 != nil {
+				ipv6Allocator.Release(ipv6)
+			}
 			return nil, nil, err
 		}
 
 		ipv4 = ipConf
 	}
 
+	for _, ip := range []net.IP{ipv4, ipv6} {
+		if ip != nil {
+			ipamConf.owner[ip.String()] = owner
+		}
+	}
+
+	ipamConf.checkpoint()
 	return ipv4, ipv6, nil
 }
 
-// ReleaseIP release a IP address.
-func ReleaseIP(ip net.IP) error {
-	ipamConf.allocatorMutex.Lock()
-	defer ipamConf.allocatorMutex.Unlock()
+// usedEndpointIDs returns the set of endpoint IDs of all allocated IPv6
+// addresses and of all endpoint IDs allocated with AllocateEndpointID or
+// ReserveEndpointID. Must be called with c.allocatorMutex held.
+func (c *Config) usedEndpointIDs() map[uint16]struct{} {
+	used := map[uint16]struct{}{}
+	for id := range c.endpointIDs {
+		used[id] = struct{}{}
+	}
+	for addr := range c.owner {
+		ip := net.ParseIP(addr)
+		if ip != nil && ip.To4() == nil {
+			used[addressing.CiliumIPv6(ip).EndpointID()] = struct{}{}
+		}
+	}
+	return used
+}
 
-	if ip.To4() != nil {
-		if ipamConf.IPv4Allocator == nil {
-			return ErrIPv4Disabled
+// allocateNextIPv6 allocates the next available IPv6 address of allocator
+// whose endpoint ID is not 0 and not used by any other allocated address.
+// The endpoint ID is derived from the last 16 bits of the IPv6 address, so
+// addresses of different CIDRs, e.g. of a pool with several IPv6 CIDRs, can
+// map to the same endpoint ID. Must be called with c.allocatorMutex held.
+func (c *Config) allocateNextIPv6(allocator Allocator) (net.IP, error) {
+	used := c.usedEndpointIDs()
+
+	// Addresses with a conflicting endpoint ID are kept allocated until
+	// an address has been found so that they are not returned again
+	var skipped []net.IP
+	defer func() {
+		for _, ip := range skipped {
+			allocator.Release(ip)
 		}
+	}()
 
-		if err := ipamConf.IPv4Allocator.Release(ip); err != nil {
-			return err
+	for {
+		ip, err := allocator.AllocateNext()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		if ipamConf.IPv6Allocator == nil {
-			return ErrIPv6Disabled
+
+		id := addressing.CiliumIPv6(ip).EndpointID()
+		if _, ok := used[id]; !ok && id != 0 {
+			return ip, nil
 		}
+		skipped = append(skipped, ip)
+	}
+}
 
-		if err := ipamConf.IPv6Allocator.Release(ip); err != nil {
-			return err
+// AllocateEndpointID allocates an endpoint ID on behalf of owner which is
+// not used by any other endpoint. It is used for endpoints whose IPv6
+// address, if any, has not been allocated by IPAM, e.g. for endpoints of
+// another CNI plugin Cilium is chained to, and therefore cannot determine
+// the endpoint ID.
+func AllocateEndpointID(owner string) (uint16, error) {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	used := ipamConf.usedEndpointIDs()
+	for i := 0; i < 1<<16; i++ {
+		id := ipamConf.nextEndpointID
+		ipamConf.nextEndpointID++
+		if _, ok := used[id]; !ok && id != 0 {
+			ipamConf.endpointIDs[id] = owner
+			return id, nil
 		}
 	}
 
+	return 0, fmt.Errorf("no endpoint ID available")
+}
+
+// ReserveEndpointID reserves the endpoint ID id on behalf of owner, e.g.
+// when restoring an endpoint whose ID has been allocated with
+// AllocateEndpointID. An error is returned if the ID is already in use.
+func ReserveEndpointID(id uint16, owner string) error {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	if id == 0 {
+		return fmt.Errorf("endpoint ID cannot be 0")
+	}
+	if _, ok := ipamConf.usedEndpointIDs()[id]; ok {
+		return fmt.Errorf("endpoint ID %d is already in use", id)
+	}
+
+	ipamConf.endpointIDs[id] = owner
+	return nil
+}
+
+// ReleaseEndpointID releases an endpoint ID allocated with
+// AllocateEndpointID or ReserveEndpointID
+func ReleaseEndpointID(id uint16) {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	delete(ipamConf.endpointIDs, id)
+}
+
+// ReleaseIP release a IP address.
+func ReleaseIP(ip net.IP) error {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	allocator, err := ipamConf.allocatorFor(ip)
+	if err != nil {
+		return err
+	}
+
+	if err := allocator.Release(ip); err != nil {
+		return err
+	}
+
+	ipamConf.forget(ip)
+	ipamConf.checkpoint()
 	return nil
 }
 
@@ -134,32 +262,55 @@ func ReleaseIPString(ipAddr string) error {
 	return ReleaseIP(ip)
 }
 
-// Dump dumps the list of allocated IP addresses
+// Dump dumps the list of allocated IP addresses of all pools
 func Dump() ([]string, []string) {
 	ipamConf.allocatorMutex.RLock()
 	defer ipamConf.allocatorMutex.RUnlock()
 
+	ipv4Allocators := []Allocator{ipamConf.IPv4Allocator}
+	ipv6Allocators := []Allocator{ipamConf.IPv6Allocator}
+	for _, p := range ipamConf.sortedPools() {
+		ipv4Allocators = append(ipv4Allocators, p.IPv4Allocator)
+		ipv6Allocators = append(ipv6Allocators, p.IPv6Allocator)
+	}
+
 	allocv4 := []string{}
-	ralv4 := k8sAPI.RangeAllocation{}
-	ipamConf.IPv4Allocator.Snapshot(&ralv4)
-	origIP := big.NewInt(0).SetBytes(node.GetIPv4AllocRange().IP)
-	v4Bits := big.NewInt(0).SetBytes(ralv4.Data)
-	for i := 0; i < v4Bits.BitLen(); i++ {
-		if v4Bits.Bit(i) != 0 {
-			allocv4 = append(allocv4, net.IP(big.NewInt(0).Add(origIP, big.NewInt(int64(uint(i+1)))).Bytes()).String())
+	for _, allocator := range ipv4Allocators {
+		if allocator != nil {
+			allocator.ForEach(func(ip net.IP) {
+				allocv4 = append(allocv4, ip.String())
+			})
 		}
 	}
 
 	allocv6 := []string{}
-	ralv6 := k8sAPI.RangeAllocation{}
-	ipamConf.IPv6Allocator.Snapshot(&ralv6)
-	origIP = big.NewInt(0).SetBytes(node.GetIPv6AllocRange().IP)
-	v6Bits := big.NewInt(0).SetBytes(ralv6.Data)
-	for i := 0; i < v6Bits.BitLen(); i++ {
-		if v6Bits.Bit(i) != 0 {
-			allocv6 = append(allocv6, net.IP(big.NewInt(0).Add(origIP, big.NewInt(int64(uint(i+1)))).Bytes()).String())
+	for _, allocator := range ipv6Allocators {
+		if allocator != nil {
+			allocator.ForEach(func(ip net.IP) {
+				allocv6 = append(allocv6, ip.String())
+			})
 		}
 	}
 
 	return allocv4, allocv6
 }
+
+// DumpAllocations returns the owner of each allocated IP address and the list
+// of allocated IP addresses whose owner could not be found after a restart
+func DumpAllocations() (map[string]string, []string) {
+	ipamConf.allocatorMutex.RLock()
+	defer ipamConf.allocatorMutex.RUnlock()
+
+	owners := make(map[string]string, len(ipamConf.owner))
+	for ip, owner := range ipamConf.owner {
+		owners[ip] = owner
+	}
+
+	leaked := make([]string, 0, len(ipamConf.leaked))
+	for ip := range ipamConf.leaked {
+		leaked = append(leaked, ip)
+	}
+	sort.Strings(leaked)
+
+	return owners, leaked
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// checkpointFile is the name of the file in the state directory
+	// holding the checkpoint of all allocations
+	checkpointFile = "ipam.json"
+
+	fieldOwner = "owner"
+)
+
+// Owners of allocations made by the agent itself. These allocations are
+// repeated on every start of the agent and are therefore not checkpointed.
+const (
+	// OwnerNode is the owner of the node addresses
+	OwnerNode = "node"
+
+	// OwnerRouter is the owner of the router addresses
+	OwnerRouter = "router"
+
+	// OwnerLoopback is the owner of the service loopback address
+	OwnerLoopback = "loopback"
+
+	// OwnerHealth is the owner of the health endpoint addresses
+	OwnerHealth = "health"
+
+	// OwnerLocalRoute is the owner of addresses reserved because of
+	// conflicting local routes
+	OwnerLocalRoute = "local-route"
+)
+
+func isInternalOwner(owner string) bool {
+	switch owner {
+	case OwnerNode, OwnerRouter, OwnerLoopback, OwnerHealth, OwnerLocalRoute:
+		return true
+	}
+	return false
+}
+
+// checkpointState is the representation of all allocations written to the
+// checkpoint file
+type checkpointState struct {
+	// Allocations maps each allocated IP to the owner of the allocation
+	Allocations map[string]string `json:"allocations"`
+}
+
+// allocate allocates ip in allocator on behalf of owner. An address restored
+// from the checkpoint is claimed instead of being allocated again. Must be
+// called with c.allocatorMutex held.
+func (c *Config) allocate(allocator Allocator, ip net.IP, owner string) error {
+	addr := ip.String()
+	if _, ok := c.restored[addr]; ok {
+		delete(c.restored, addr)
+	} else if err := allocator.Allocate(ip); err != nil {
+		return err
+	}
+
+	c.owner[addr] = owner
+	return nil
+}
+
+// forget removes all state associated with a released ip. Must be called
+// with c.allocatorMutex held.
+func (c *Config) forget(ip net.IP) {
+	addr := ip.String()
+	delete(c.owner, addr)
+	delete(c.restored, addr)
+	delete(c.leaked, addr)
+}
+
+// checkpoint writes all allocations, except the ones made by the agent
+// itself, to the checkpoint file. Must be called with c.allocatorMutex held.
+func (c *Config) checkpoint() {
+	if c.statePath == "" {
+		return
+	}
+
+	state := checkpointState{Allocations: map[string]string{}}
+	for addr, owner := range c.owner {
+		if !isInternalOwner(owner) {
+			state.Allocations[addr] = owner
+		}
+	}
+
+	scopedLog := log.WithField(logfields.Path, c.statePath)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		scopedLog.WithError(err).Warning("Unable to marshal IPAM checkpoint")
+		return
+	}
+
+	// Write to a temporary file first so that the checkpoint is never
+	// left truncated
+	tmpPath := c.statePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		scopedLog.WithError(err).Warning("Unable to write IPAM checkpoint")
+		return
+	}
+
+	if err := os.Rename(tmpPath, c.statePath); err != nil {
+		scopedLog.WithError(err).Warning("Unable to write IPAM checkpoint")
+	}
+}
+
+// restore allocates all addresses found in the checkpoint written by a
+// previous run so that they are not handed out to anybody else before their
+// owner has claimed them again.
+func (c *Config) restore() {
+	if c.statePath == "" {
+		return
+	}
+
+	scopedLog := log.WithField(logfields.Path, c.statePath)
+
+	data, err := ioutil.ReadFile(c.statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			scopedLog.WithError(err).Warning("Unable to read IPAM checkpoint")
+		}
+		return
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		scopedLog.WithError(err).Warning("Unable to parse IPAM checkpoint")
+		return
+	}
+
+	c.allocatorMutex.Lock()
+	defer c.allocatorMutex.Unlock()
+
+	for addr, owner := range state.Allocations {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+
+		allocator, err := c.allocatorFor(ip)
+		if err == nil {
+			err = allocator.Allocate(ip)
+		}
+		if err != nil {
+			scopedLog.WithError(err).WithFields(logrus.Fields{
+				logfields.IPAddr: addr,
+				fieldOwner:       owner,
+			}).Warning("Unable to restore IPAM allocation")
+			continue
+		}
+
+		c.owner[addr] = owner
+		c.restored[addr] = struct{}{}
+	}
+
+	scopedLog.WithField("count", len(c.restored)).Info("Restored IPAM allocations")
+}
+
+// ReconcileRestored completes the restoration of the allocations of the
+// previous run and must be called after all endpoints have been restored.
+// Restored allocations which have not been claimed yet are kept if isRunning
+// reports their owner to be running and are marked as leaked otherwise.
+// Leaked allocations are not released automatically. If isRunning is nil,
+// all restored allocations are kept.
+func ReconcileRestored(isRunning func(owner string) bool) {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	for addr := range ipamConf.restored {
+		owner := ipamConf.owner[addr]
+		if isRunning != nil && !isRunning(owner) {
+			log.WithFields(logrus.Fields{
+				logfields.IPAddr: addr,
+				fieldOwner:       owner,
+			}).Warning("Owner of restored IPAM allocation not found, marking address as leaked")
+			ipamConf.leaked[addr] = struct{}{}
+		}
+	}
+
+	ipamConf.restored = map[string]struct{}{}
+}
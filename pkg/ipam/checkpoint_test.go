@@ -0,0 +1,128 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
+)
+
+func newTestConfig(statePath string) *Config {
+	_, v4, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6, _ := net.ParseCIDR("f00d::/112")
+
+	return &Config{
+		IPv4Allocator: ipallocator.NewCIDRRange(v4),
+		IPv6Allocator: ipallocator.NewCIDRRange(v6),
+		Pools:         map[string]*Pool{},
+		owner:         map[string]string{},
+		restored:      map[string]struct{}{},
+		leaked:        map[string]struct{}{},
+		endpointIDs:   map[uint16]string{},
+		statePath:     statePath,
+	}
+}
+
+func readCheckpoint(c *C, path string) map[string]string {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+
+	var state checkpointState
+	c.Assert(json.Unmarshal(data, &state), IsNil)
+	return state.Allocations
+}
+
+func (s *IPAMSuite) TestCheckpointRestore(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	path := filepath.Join(c.MkDir(), checkpointFile)
+	ipamConf = newTestConfig(path)
+
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.10"), "endpoint"), IsNil)
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.11"), "pending"), IsNil)
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.12"), "gone"), IsNil)
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.13"), OwnerHealth), IsNil)
+	ipv4, ipv6, err := AllocateNext("", "released")
+	c.Assert(err, IsNil)
+	c.Assert(ReleaseIP(ipv4), IsNil)
+	c.Assert(ReleaseIP(ipv6), IsNil)
+
+	// Allocations of the agent itself are not checkpointed
+	c.Assert(readCheckpoint(c, path), DeepEquals, map[string]string{
+		"10.0.0.10": "endpoint",
+		"10.0.0.11": "pending",
+		"10.0.0.12": "gone",
+	})
+
+	// Simulate a restart
+	ipamConf = newTestConfig(path)
+	ipamConf.restore()
+
+	// Restored addresses are not handed out again
+	c.Assert(ipamConf.IPv4Allocator.Has(net.ParseIP("10.0.0.12")), Equals, true)
+	c.Assert(ipamConf.IPv4Allocator.Has(net.ParseIP("10.0.0.13")), Equals, false)
+
+	// A restored address can be claimed once
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.10"), "endpoint"), IsNil)
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.10"), "endpoint"), Not(IsNil))
+
+	ReconcileRestored(func(owner string) bool {
+		return owner == "pending"
+	})
+
+	owners, leaked := DumpAllocations()
+	c.Assert(owners, DeepEquals, map[string]string{
+		"10.0.0.10": "endpoint",
+		"10.0.0.11": "pending",
+		"10.0.0.12": "gone",
+	})
+	c.Assert(leaked, DeepEquals, []string{"10.0.0.12"})
+
+	// Leaked addresses remain allocated until released
+	c.Assert(AllocateIP(net.ParseIP("10.0.0.12"), "other"), Not(IsNil))
+	c.Assert(ReleaseIP(net.ParseIP("10.0.0.12")), IsNil)
+
+	_, leaked = DumpAllocations()
+	c.Assert(leaked, DeepEquals, []string{})
+	c.Assert(readCheckpoint(c, path), DeepEquals, map[string]string{
+		"10.0.0.10": "endpoint",
+		"10.0.0.11": "pending",
+	})
+}
+
+func (s *IPAMSuite) TestReconcileRestoredUnknownRuntime(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	path := filepath.Join(c.MkDir(), checkpointFile)
+	ipamConf = newTestConfig(path)
+	c.Assert(AllocateIP(net.ParseIP("f00d::10"), "unknown"), IsNil)
+
+	ipamConf = newTestConfig(path)
+	ipamConf.restore()
+
+	// Without a workload runtime, all restored allocations are kept
+	ReconcileRestored(nil)
+
+	owners, leaked := DumpAllocations()
+	c.Assert(owners, DeepEquals, map[string]string{"f00d::10": "unknown"})
+	c.Assert(leaked, DeepEquals, []string{})
+	c.Assert(AllocateIP(net.ParseIP("f00d::10"), "unknown"), Not(IsNil))
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/k8s"
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
+	ciliumclientv2 "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned/typed/cilium.io/v2"
+	informersv2 "github.com/cilium/cilium/pkg/k8s/client/informers/externalversions/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/versioncheck"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	fieldPool = "pool"
+	fieldCIDR = "cidr"
+
+	// crdStatusSyncInterval is the interval in which the usage of the
+	// pools is published into the CiliumNode resource
+	crdStatusSyncInterval = 10 * time.Second
+
+	// crdWaitLogInterval is the interval in which the agent logs that it
+	// is still waiting for the pools of the node to be declared
+	crdWaitLogInterval = time.Minute
+)
+
+var (
+	// ciliumNodeStatusVerConstr is the minimal version of Kubernetes
+	// supporting the status subresource of custom resources
+	ciliumNodeStatusVerConstr = versioncheck.MustCompile(">= 1.11.0")
+)
+
+// nodeStore keeps the pools of the CRD allocators in sync with the
+// CiliumNode resource of the local node and publishes the usage of the
+// pools into the status of the resource
+type nodeStore struct {
+	client   ciliumclientv2.CiliumV2Interface
+	nodeName string
+
+	// allocators are the allocators managed by the store
+	allocators []*crdAllocator
+
+	// required are the allocators which must have capacity before
+	// the store is ready
+	required []*crdAllocator
+
+	// statusSubresource is true if the status of the resource must be
+	// updated via the status subresource
+	statusSubresource bool
+
+	// mutex protects ownNode
+	mutex   lock.RWMutex
+	ownNode *ciliumv2.CiliumNode
+
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	controllers *controller.Manager
+}
+
+// startCRDAllocation starts watching the CiliumNode resource of the node
+// and blocks until all required allocators have at least one CIDR to
+// allocate from.
+func startCRDAllocation(nodeName string, allocators, required []*crdAllocator) (*nodeStore, error) {
+	restConfig, err := k8s.CreateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create k8s client configuration: %s", err)
+	}
+
+	apiextensionsclientset, err := apiextensionsclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create k8s CRD client: %s", err)
+	}
+
+	if err := ciliumv2.CreateCustomResourceDefinitions(apiextensionsclientset); err != nil {
+		return nil, fmt.Errorf("unable to create custom resource definitions: %s", err)
+	}
+
+	ciliumClient, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cilium k8s client: %s", err)
+	}
+
+	k8sServerVer, err := k8s.GetServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve kubernetes server version: %s", err)
+	}
+
+	s := &nodeStore{
+		client:            ciliumClient.CiliumV2(),
+		nodeName:          nodeName,
+		allocators:        allocators,
+		required:          required,
+		statusSubresource: ciliumNodeStatusVerConstr.Check(k8sServerVer),
+		ready:             make(chan struct{}),
+		controllers:       controller.NewManager(),
+	}
+
+	if err := s.ensureNode(); err != nil {
+		return nil, err
+	}
+
+	informer := informersv2.NewFilteredCiliumNodeInformer(ciliumClient, 0, cache.Indexers{},
+		func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if n, ok := obj.(*ciliumv2.CiliumNode); ok {
+				s.updateNode(n)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if n, ok := newObj.(*ciliumv2.CiliumNode); ok {
+				s.updateNode(n)
+			}
+		},
+		DeleteFunc: func(_ interface{}) {
+			log.WithField(logfields.NodeName, nodeName).
+				Warning("CiliumNode resource has been deleted, keeping current IPAM pools")
+		},
+	})
+	go informer.Run(wait.NeverStop)
+
+	scopedLog := log.WithField(logfields.NodeName, nodeName)
+	scopedLog.Info("Waiting for IPAM pools in CiliumNode resource")
+	for {
+		select {
+		case <-s.ready:
+			scopedLog.Info("IPAM pools of CiliumNode resource are available")
+			s.controllers.UpdateController("ipam-crd-status-sync",
+				controller.ControllerParams{
+					DoFunc:      s.syncStatus,
+					RunInterval: crdStatusSyncInterval,
+				},
+			)
+			return s, nil
+		case <-time.After(crdWaitLogInterval):
+			scopedLog.Warning("Still waiting for IPAM pools in CiliumNode resource")
+		}
+	}
+}
+
+// ensureNode creates the CiliumNode resource of the node if it does not
+// exist yet so that an external controller can declare its pools
+func (s *nodeStore) ensureNode() error {
+	_, err := s.client.CiliumNodes().Get(s.nodeName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		node := &ciliumv2.CiliumNode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: s.nodeName,
+			},
+		}
+		_, err = s.client.CiliumNodes().Create(node)
+		if errors.IsAlreadyExists(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create CiliumNode resource %s: %s", s.nodeName, err)
+	}
+
+	return nil
+}
+
+// updateNode updates the pools of all allocators with the spec of the
+// CiliumNode resource
+func (s *nodeStore) updateNode(node *ciliumv2.CiliumNode) {
+	s.mutex.Lock()
+	s.ownNode = node
+	s.mutex.Unlock()
+
+	for _, a := range s.allocators {
+		a.updatePools(node.Spec.IPAM.Pools, node.Status.IPAM.Pools)
+	}
+
+	for _, a := range s.required {
+		if !a.hasCapacity() {
+			return
+		}
+	}
+
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// finishRestore marks the restoration of endpoints as complete for all
+// allocators
+func (s *nodeStore) finishRestore() {
+	for _, a := range s.allocators {
+		a.finishRestore()
+	}
+}
+
+// status returns the usage of all pools of the allocators
+func (s *nodeStore) status() map[string]ciliumv2.IPAMPoolStatus {
+	status := map[string]ciliumv2.IPAMPoolStatus{}
+	for _, a := range s.allocators {
+		a.addStatus(status)
+	}
+
+	if len(status) == 0 {
+		return nil
+	}
+	return status
+}
+
+// syncStatus publishes the usage of the pools into the status of the
+// CiliumNode resource if it changed
+func (s *nodeStore) syncStatus() error {
+	s.mutex.RLock()
+	node := s.ownNode
+	s.mutex.RUnlock()
+
+	if node == nil {
+		return nil
+	}
+
+	status := s.status()
+	if reflect.DeepEqual(node.Status.IPAM.Pools, status) {
+		return nil
+	}
+
+	node = node.DeepCopy()
+	node.Status.IPAM.Pools = status
+
+	var err error
+	if s.statusSubresource {
+		_, err = s.client.CiliumNodes().UpdateStatus(node)
+	} else {
+		_, err = s.client.CiliumNodes().Update(node)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update status of CiliumNode resource %s: %s", s.nodeName, err)
+	}
+
+	return nil
+}
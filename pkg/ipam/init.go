@@ -17,12 +17,16 @@ package ipam
 import (
 	"fmt"
 	"net"
+	"path/filepath"
+	"sort"
 
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/ip"
+	"github.com/cilium/cilium/pkg/k8s"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/option"
 
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/plugins/ipam/host-local/backend/allocator"
@@ -34,6 +38,10 @@ import (
 var (
 	log      = logging.DefaultLogger.WithField(logfields.LogSubsys, "ipam")
 	ipamConf *Config
+
+	// crdStore is the store of the CiliumNode resource of the node when
+	// running in CRD mode
+	crdStore *nodeStore
 )
 
 type ErrAllocation error
@@ -83,9 +91,11 @@ func reserveLocalRoutes(ipam *Config) {
 				logfields.V4Prefix: allocRange,
 			}).Info("Marking local route as no-alloc in node allocation prefix")
 
+			ipam.allocatorMutex.Lock()
 			for ip := r.Dst.IP.Mask(r.Dst.Mask); r.Dst.Contains(ip); nextIP(ip) {
-				ipam.IPv4Allocator.Allocate(ip)
+				ipam.allocate(ipam.IPv4Allocator, ip, OwnerLocalRoute)
 			}
+			ipam.allocatorMutex.Unlock()
 		}
 	}
 }
@@ -96,8 +106,12 @@ func ReserveLocalRoutes() {
 	reserveLocalRoutes(ipamConf)
 }
 
-// Init initializes the IPAM package
-func Init() {
+// Init initializes the IPAM package and restores the allocations of the
+// previous run from the checkpoint in the state directory. In hostscope
+// mode, the named pools given with --ipam-pool are configured in addition to
+// the allocation ranges of the node. In CRD mode, it blocks until the pools
+// of the node have been declared in its CiliumNode resource.
+func Init() error {
 	ipamSubnets := net.IPNet{
 		IP:   node.GetIPv6Router(),
 		Mask: defaults.StateIPv6Mask,
@@ -119,13 +133,21 @@ func Init() {
 				},
 			},
 		},
-		IPv6Allocator: ipallocator.NewCIDRRange(node.GetIPv6AllocRange()),
+		Pools:    map[string]*Pool{},
+		owner:    map[string]string{},
+		restored: map[string]struct{}{},
+		leaked:   map[string]struct{}{},
+
+		endpointIDs: map[uint16]string{},
+	}
+
+	if option.Config.StateDir != "" {
+		ipamConf.statePath = filepath.Join(option.Config.StateDir, checkpointFile)
 	}
 
 	// Since docker doesn't support IPv6 only and there's always an IPv4
 	// address we can set up ipam for IPv4. More info:
 	// https://github.com/docker/libnetwork/pull/826
-	ipamConf.IPv4Allocator = ipallocator.NewCIDRRange(node.GetIPv4AllocRange())
 	ipamConf.IPAMConfig.Routes = append(ipamConf.IPAMConfig.Routes,
 		// IPv4
 		cniTypes.Route{
@@ -135,19 +157,90 @@ func Init() {
 			Dst: defaults.IPv4DefaultRoute,
 			GW:  node.GetInternalIPv4(),
 		})
+
+	switch option.Config.IPAM {
+	case option.IPAMCRD:
+		if !k8s.IsEnabled() {
+			return fmt.Errorf("IPAM mode %s requires Kubernetes", option.IPAMCRD)
+		}
+
+		ipv4Allocator := newCRDAllocator("ipv4")
+		ipv6Allocator := newCRDAllocator("ipv6")
+		ipamConf.IPv4Allocator = ipv4Allocator
+		ipamConf.IPv6Allocator = ipv6Allocator
+
+		allocators := []*crdAllocator{ipv4Allocator, ipv6Allocator}
+		required := []*crdAllocator{ipv6Allocator}
+		if !option.Config.IPv4Disabled {
+			required = append(required, ipv4Allocator)
+		}
+
+		store, err := startCRDAllocation(node.GetName(), allocators, required)
+		if err != nil {
+			return err
+		}
+		crdStore = store
+
+		if len(option.Config.IPAMPools) > 0 {
+			return fmt.Errorf("IPAM pools cannot be configured with --%s in IPAM mode %s, declare them in the CiliumNode resource instead",
+				option.IPAMPoolName, option.IPAMCRD)
+		}
+
+	default:
+		ipamConf.IPv6Allocator = ipallocator.NewCIDRRange(node.GetIPv6AllocRange())
+		ipamConf.IPv4Allocator = ipallocator.NewCIDRRange(node.GetIPv4AllocRange())
+
+		names := make([]string, 0, len(option.Config.IPAMPools))
+		for name := range option.Config.IPAMPools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			cfg, err := ParsePoolConfig(name, option.Config.IPAMPools[name], option.Config.Masquerade)
+			if err != nil {
+				return err
+			}
+			if err := ipamConf.addPool(cfg, node.GetIPv4AllocRange(), node.GetIPv6AllocRange()); err != nil {
+				return err
+			}
+		}
+	}
+
+	ipamConf.restore()
+
+	return nil
+}
+
+// reserveNodeIP reserves an address used by the node itself. In CRD mode,
+// the node addresses are usually not part of any pool and do not need to be
+// reserved. Must be called with ipamConf.allocatorMutex held.
+func reserveNodeIP(allocator Allocator, ip net.IP) error {
+	if a, ok := allocator.(*crdAllocator); ok && !a.contains(ip) {
+		return nil
+	}
+
+	return ipamConf.allocate(allocator, ip, OwnerRouter)
 }
 
 // AllocateInternalIPs allocates all non endpoint IPs in the CIDR required for
 // operation. This mustbe called *after* endpoints have been restored to avoid
 // allocation conflicts
 func AllocateInternalIPs() error {
+	if crdStore != nil {
+		crdStore.finishRestore()
+	}
+
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
 	// Reserve the IPv4 router IP if it is part of the IPv4
 	// allocation range to ensure that we do not hand out the
 	// router IP to a container.
 	allocRange := node.GetIPv4AllocRange()
 	nodeIP := node.GetExternalIPv4()
 	if allocRange.Contains(nodeIP) {
-		err := ipamConf.IPv4Allocator.Allocate(nodeIP)
+		err := ipamConf.allocate(ipamConf.IPv4Allocator, nodeIP, OwnerNode)
 		if err != nil {
 			log.WithError(err).WithField(logfields.IPAddr, nodeIP).Debug("Unable to reserve IPv4 router address")
 		}
@@ -157,7 +250,7 @@ func AllocateInternalIPs() error {
 	if internalIP == nil {
 		internalIP = ip.GetNextIP(node.GetIPv4AllocRange().IP)
 	}
-	err := ipamConf.IPv4Allocator.Allocate(internalIP)
+	err := reserveNodeIP(ipamConf.IPv4Allocator, internalIP)
 	if err != nil {
 		// If the allocation fails here it is likely that, in a kubernetes
 		// environment, cilium was not able to retrieve the node's pod-cidr
@@ -180,7 +273,7 @@ func AllocateInternalIPs() error {
 	allocRange = node.GetIPv6AllocRange()
 	for _, ip6 := range []net.IP{node.GetIPv6()} {
 		if allocRange.Contains(ip6) {
-			err := ipamConf.IPv6Allocator.Allocate(ip6)
+			err := ipamConf.allocate(ipamConf.IPv6Allocator, ip6, OwnerNode)
 			if err != nil {
 				log.WithError(err).WithField(logfields.IPAddr, ip6).Debug("Unable to reserve IPv6 address")
 			}
@@ -192,7 +285,7 @@ func AllocateInternalIPs() error {
 		routerIP = ip.GetNextIP(node.GetIPv6AllocRange().IP)
 	}
 	if !routerIP.Equal(node.GetIPv6()) {
-		err = ipamConf.IPv6Allocator.Allocate(routerIP)
+		err = reserveNodeIP(ipamConf.IPv6Allocator, routerIP)
 		if err != nil {
 			return ErrAllocation(fmt.Errorf("Unable to allocate internal IPv6 router IP %s: %s.",
 				routerIP, err))
@@ -15,12 +15,14 @@
 package ipam
 
 import (
+	"net"
 	"testing"
 
 	"github.com/cilium/cilium/common/addressing"
 	"github.com/cilium/cilium/pkg/node"
 
 	. "gopkg.in/check.v1"
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
 )
 
 func Test(t *testing.T) {
@@ -63,3 +65,41 @@ func (s *IPAMSuite) TestLock(c *C) {
 	err = ipamConf.IPv4Allocator.Release(epipv4.IP())
 	c.Assert(err, IsNil)
 }
+
+func (s *IPAMSuite) TestEndpointIDs(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	ipamConf = newTestConfig("")
+	_, v6, _ := net.ParseCIDR("f00d::/125")
+	ipamConf.IPv6Allocator = ipallocator.NewCIDRRange(v6)
+	ipamConf.nextEndpointID = 1
+	c.Assert(AllocateIPString("f00d::2", "pod"), IsNil)
+
+	// Endpoint IDs of allocated IPv6 addresses are not handed out
+	id, err := AllocateEndpointID("chained")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint16(1))
+	id, err = AllocateEndpointID("chained")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint16(3))
+
+	c.Assert(ReserveEndpointID(0, "chained"), Not(IsNil))
+	c.Assert(ReserveEndpointID(2, "chained"), Not(IsNil))
+	c.Assert(ReserveEndpointID(3, "chained"), Not(IsNil))
+	c.Assert(ReserveEndpointID(4, "chained"), IsNil)
+
+	// IPv6 addresses whose endpoint ID is allocated are skipped, leaving
+	// f00d::5 and f00d::6
+	for i := 0; i < 2; i++ {
+		_, ipv6, err := AllocateNext("ipv6", "pod")
+		c.Assert(err, IsNil)
+		id := addressing.CiliumIPv6(ipv6).EndpointID()
+		c.Assert(id == 5 || id == 6, Equals, true)
+	}
+	_, _, err = AllocateNext("ipv6", "pod")
+	c.Assert(err, Not(IsNil))
+
+	ReleaseEndpointID(3)
+	c.Assert(ReserveEndpointID(3, "chained"), IsNil)
+}
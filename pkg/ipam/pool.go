@@ -0,0 +1,501 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
+)
+
+// poolRange is a CIDR of an allocation pool
+type poolRange struct {
+	*ipallocator.Range
+
+	// cidr is the CIDR the range allocates from
+	cidr *net.IPNet
+
+	// draining is true when the CIDR has been removed from the pool.
+	// No new addresses are allocated from a draining range, it is removed
+	// as soon as all of its addresses have been released.
+	draining bool
+}
+
+// pool is an allocation pool declared in the CiliumNode resource
+type pool struct {
+	name   string
+	ranges []*poolRange
+}
+
+// crdAllocator is an Allocator for a single address family which allocates
+// addresses from the pools declared in the CiliumNode resource of the node.
+// Addresses are allocated from the pools in the order they are declared in.
+type crdAllocator struct {
+	// family is the address family of the allocator, either "ipv4" or
+	// "ipv6"
+	family string
+
+	// mutex protects all fields below
+	mutex lock.RWMutex
+
+	pools []*pool
+
+	// spec is the pool specification of the CiliumNode resource. CIDRs
+	// which can't be used yet, e.g. because they overlap with a draining
+	// range, are added once the conflict is resolved.
+	spec []ciliumv2.IPAMPoolSpec
+
+	// restoring is true until the addresses of the restored endpoints
+	// have been allocated. Empty draining ranges are kept while
+	// restoring as they may still be claimed by a restored endpoint.
+	restoring bool
+}
+
+func newCRDAllocator(family string) *crdAllocator {
+	return &crdAllocator{
+		family:    family,
+		restoring: true,
+	}
+}
+
+// isFamily returns true if the CIDR is of the address family of the
+// allocator
+func (a *crdAllocator) isFamily(cidr *net.IPNet) bool {
+	if a.family == "ipv4" {
+		return cidr.IP.To4() != nil
+	}
+	return cidr.IP.To4() == nil
+}
+
+// updatePools updates the pools of the allocator to match spec. Ranges of
+// CIDRs still present in the spec keep their allocations, even if the CIDR
+// has moved to a different pool. Ranges of CIDRs removed from the spec which
+// still have addresses allocated are kept as draining. Draining CIDRs listed
+// in status, e.g. by a previous run of the agent, are restored as draining
+// ranges as well.
+//
+// CIDRs of the spec overlapping with a draining range are ignored until all
+// addresses of the draining range have been released, e.g. when a CIDR is
+// replaced by a larger one containing it.
+func (a *crdAllocator) updatePools(spec []ciliumv2.IPAMPoolSpec, status map[string]ciliumv2.IPAMPoolStatus) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.spec = spec
+	a.updatePoolsLocked(status)
+}
+
+// updatePoolsLocked updates the pools of the allocator to match a.spec. Must
+// be called with a.mutex held.
+func (a *crdAllocator) updatePoolsLocked(status map[string]ciliumv2.IPAMPoolStatus) {
+	type specCIDR struct {
+		pool string
+		cidr *net.IPNet
+	}
+
+	var specCIDRs []specCIDR
+	inSpec := map[string]struct{}{}
+	for _, ps := range a.spec {
+		for _, c := range ps.CIDRs {
+			_, cidr, err := net.ParseCIDR(c)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{
+					fieldPool: ps.Name,
+					fieldCIDR: c,
+				}).Warning("Ignoring invalid IPAM pool CIDR")
+				continue
+			}
+			if a.isFamily(cidr) {
+				specCIDRs = append(specCIDRs, specCIDR{pool: ps.Name, cidr: cidr})
+				inSpec[cidr.String()] = struct{}{}
+			}
+		}
+	}
+
+	existing := map[string]*poolRange{}
+	existingPool := map[string]string{}
+	for _, p := range a.pools {
+		for _, r := range p.ranges {
+			existing[r.cidr.String()] = r
+			existingPool[r.cidr.String()] = p.name
+		}
+	}
+
+	pools := []*pool{}
+	poolsByName := map[string]*pool{}
+	getPool := func(name string) *pool {
+		p, ok := poolsByName[name]
+		if !ok {
+			p = &pool{name: name}
+			poolsByName[name] = p
+			pools = append(pools, p)
+		}
+		return p
+	}
+
+	// Existing ranges which remain in use are claimed first so that no
+	// CIDR of the spec overlapping with them is added
+	inUse := []*poolRange{}
+	var draining []string
+	for _, c := range sortedKeys(existing) {
+		r := existing[c]
+		if _, ok := inSpec[c]; ok {
+			inUse = append(inUse, r)
+		} else if r.Used() > 0 || a.restoring {
+			inUse = append(inUse, r)
+			draining = append(draining, c)
+		} else {
+			log.WithFields(logrus.Fields{
+				fieldPool: existingPool[c],
+				fieldCIDR: c,
+			}).Info("Removing IPAM pool CIDR")
+		}
+	}
+
+	overlaps := func(cidr *net.IPNet) *poolRange {
+		for _, r := range inUse {
+			if r.cidr.String() != cidr.String() &&
+				(r.cidr.Contains(cidr.IP) || cidr.Contains(r.cidr.IP)) {
+				return r
+			}
+		}
+		return nil
+	}
+
+	added := map[string]struct{}{}
+	addRange := func(poolName string, cidr *net.IPNet, isDraining bool) {
+		scopedLog := log.WithFields(logrus.Fields{
+			fieldPool: poolName,
+			fieldCIDR: cidr.String(),
+		})
+
+		if _, ok := added[cidr.String()]; ok {
+			scopedLog.Warning("Ignoring duplicate IPAM pool CIDR")
+			return
+		}
+
+		r, ok := existing[cidr.String()]
+		if !ok {
+			if o := overlaps(cidr); o != nil {
+				scopedLog.WithField("overlaps", o.cidr.String()).
+					Warning("Ignoring IPAM pool CIDR overlapping with another CIDR")
+				return
+			}
+			r = &poolRange{
+				Range: ipallocator.NewCIDRRange(cidr),
+				cidr:  cidr,
+			}
+			inUse = append(inUse, r)
+			scopedLog.Info("Adding IPAM pool CIDR")
+		}
+		r.draining = isDraining
+
+		p := getPool(poolName)
+		p.ranges = append(p.ranges, r)
+		added[cidr.String()] = struct{}{}
+	}
+
+	for _, sc := range specCIDRs {
+		addRange(sc.pool, sc.cidr, false)
+	}
+
+	// Keep removed CIDRs with allocated addresses as draining ranges in
+	// their previous pool
+	for _, c := range draining {
+		addRange(existingPool[c], existing[c].cidr, true)
+	}
+
+	if a.restoring {
+		names := make([]string, 0, len(status))
+		for name := range status {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, c := range status[name].CIDRs {
+				_, cidr, err := net.ParseCIDR(c)
+				if err != nil || !a.isFamily(cidr) {
+					continue
+				}
+				if _, ok := added[cidr.String()]; ok {
+					continue
+				}
+				addRange(name, cidr, true)
+			}
+		}
+	}
+
+	a.pools = pools
+}
+
+func sortedKeys(m map[string]*poolRange) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// finishRestore marks the restoration of endpoints as complete and removes
+// all draining ranges without any allocated addresses
+func (a *crdAllocator) finishRestore() {
+	a.mutex.Lock()
+	a.restoring = false
+	pruned := false
+	for _, p := range a.pools {
+		if a.pruneRanges(p) {
+			pruned = true
+		}
+	}
+	if pruned {
+		a.updatePoolsLocked(nil)
+	}
+	a.mutex.Unlock()
+}
+
+// pruneRanges removes the draining ranges of the pool without any allocated
+// addresses. Returns true if any range has been removed. Must be called with
+// a.mutex held.
+func (a *crdAllocator) pruneRanges(p *pool) bool {
+	if a.restoring {
+		return false
+	}
+
+	pruned := false
+	ranges := p.ranges[:0]
+	for _, r := range p.ranges {
+		if r.draining && r.Used() == 0 {
+			log.WithFields(logrus.Fields{
+				fieldPool: p.name,
+				fieldCIDR: r.cidr.String(),
+			}).Info("Removing drained IPAM pool CIDR")
+			pruned = true
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	p.ranges = ranges
+	return pruned
+}
+
+// lookup returns the pool and range containing ip. Must be called with
+// a.mutex held.
+func (a *crdAllocator) lookup(ip net.IP) (*pool, *poolRange) {
+	for _, p := range a.pools {
+		for _, r := range p.ranges {
+			if r.cidr.Contains(ip) {
+				return p, r
+			}
+		}
+	}
+	return nil, nil
+}
+
+// contains returns true if ip is part of any pool of the allocator
+func (a *crdAllocator) contains(ip net.IP) bool {
+	a.mutex.RLock()
+	_, r := a.lookup(ip)
+	a.mutex.RUnlock()
+	return r != nil
+}
+
+// hasCapacity returns true if the allocator has at least one range which is
+// not draining
+func (a *crdAllocator) hasCapacity() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, p := range a.pools {
+		for _, r := range p.ranges {
+			if !r.draining {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allocate allocates a specific IP. The IP must be part of a pool.
+func (a *crdAllocator) Allocate(ip net.IP) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	_, r := a.lookup(ip)
+	if r == nil {
+		return fmt.Errorf("IP %s is not part of any IPAM pool", ip)
+	}
+
+	return r.Allocate(ip)
+}
+
+// hasPoolLocked returns true if the allocator has a pool called name. Must be
+// called with a.mutex held.
+func (a *crdAllocator) hasPoolLocked(name string) bool {
+	for _, p := range a.pools {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPool returns true if the allocator has a pool called name
+func (a *crdAllocator) hasPool(name string) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.hasPoolLocked(name)
+}
+
+// AllocateNext allocates the next available IP of the default pool. If no
+// default pool is declared, the IP is allocated from the first pool with
+// available addresses.
+func (a *crdAllocator) AllocateNext() (net.IP, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.hasPoolLocked(DefaultPool) {
+		return a.allocateNextLocked(DefaultPool)
+	}
+	return a.allocateNextLocked("")
+}
+
+// allocateNextFromPool allocates the next available IP of the pool name
+func (a *crdAllocator) allocateNextFromPool(name string) (net.IP, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.allocateNextLocked(name)
+}
+
+// allocateNextLocked allocates the next available IP of the pool name, or of
+// the first pool with available addresses if name is empty. Must be called
+// with a.mutex held.
+func (a *crdAllocator) allocateNextLocked(name string) (net.IP, error) {
+	for _, p := range a.pools {
+		if name != "" && p.name != name {
+			continue
+		}
+
+		for _, r := range p.ranges {
+			if r.draining {
+				continue
+			}
+
+			ip, err := r.AllocateNext()
+			switch err {
+			case nil:
+				return ip, nil
+			case ipallocator.ErrFull:
+				continue
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("no %s address available in IPAM pool %s", a.family, name)
+	}
+	return nil, fmt.Errorf("no %s address available in IPAM pools", a.family)
+}
+
+// Release releases a previously allocated IP. Releasing the last address of
+// a draining range removes the range.
+func (a *crdAllocator) Release(ip net.IP) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	p, r := a.lookup(ip)
+	if r == nil {
+		return nil
+	}
+
+	if err := r.Release(ip); err != nil {
+		return err
+	}
+
+	// CIDRs of the spec overlapping with the drained range can be used
+	// now
+	if r.draining && a.pruneRanges(p) {
+		a.updatePoolsLocked(nil)
+	}
+
+	return nil
+}
+
+// ForEach calls fn for each allocated IP
+func (a *crdAllocator) ForEach(fn func(net.IP)) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, p := range a.pools {
+		for _, r := range p.ranges {
+			r.ForEach(fn)
+		}
+	}
+}
+
+// Has returns true if the IP is allocated
+func (a *crdAllocator) Has(ip net.IP) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	_, r := a.lookup(ip)
+	return r != nil && r.Has(ip)
+}
+
+// addStatus adds the usage of the pools of the allocator to status. The usage
+// is reported separately for each address family.
+func (a *crdAllocator) addStatus(status map[string]ciliumv2.IPAMPoolStatus) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, p := range a.pools {
+		ps := status[p.name]
+		usage := &ciliumv2.IPAMPoolUsage{}
+		for _, r := range p.ranges {
+			ps.CIDRs = append(ps.CIDRs, r.cidr.String())
+			usage.Used += r.Used()
+			if !r.draining {
+				usage.Available += r.Free()
+			}
+		}
+		if a.family == "ipv4" {
+			ps.IPv4 = usage
+		} else {
+			ps.IPv6 = usage
+		}
+		status[p.name] = ps
+	}
+}
+
+// crdPoolAllocator is an Allocator allocating the next available IP from a
+// single pool of a crdAllocator
+type crdPoolAllocator struct {
+	*crdAllocator
+	name string
+}
+
+// AllocateNext allocates the next available IP of the pool
+func (a *crdPoolAllocator) AllocateNext() (net.IP, error) {
+	return a.allocateNextFromPool(a.name)
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/common/addressing"
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *IPAMSuite) TestCRDAllocatorAllocateNext(c *C) {
+	a := newCRDAllocator("ipv4")
+	c.Assert(a.hasCapacity(), Equals, false)
+
+	_, err := a.AllocateNext()
+	c.Assert(err, Not(IsNil))
+
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "default", CIDRs: []string{"10.0.0.0/30", "f00d::/120", "10.0.1.0/30"}},
+	}, nil)
+	c.Assert(a.hasCapacity(), Equals, true)
+
+	// A /30 holds two usable addresses, allocation continues in the
+	// second CIDR once the first one is full
+	for _, cidr := range []string{"10.0.0.0/30", "10.0.0.0/30", "10.0.1.0/30", "10.0.1.0/30"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		ip, err := a.AllocateNext()
+		c.Assert(err, IsNil)
+		c.Assert(ipNet.Contains(ip), Equals, true)
+	}
+
+	_, err = a.AllocateNext()
+	c.Assert(err, Not(IsNil))
+
+	c.Assert(a.Release(net.ParseIP("10.0.0.2")), IsNil)
+	c.Assert(a.Has(net.ParseIP("10.0.0.2")), Equals, false)
+	ip, err := a.AllocateNext()
+	c.Assert(err, IsNil)
+	c.Assert(ip.String(), Equals, "10.0.0.2")
+
+	c.Assert(a.Allocate(net.ParseIP("10.0.2.1")), Not(IsNil))
+	c.Assert(a.contains(net.ParseIP("10.0.2.1")), Equals, false)
+}
+
+func (s *IPAMSuite) TestCRDAllocatorUpdatePools(c *C) {
+	a := newCRDAllocator("ipv4")
+	a.finishRestore()
+
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "a", CIDRs: []string{"10.0.0.0/24"}},
+		{Name: "b", CIDRs: []string{"10.0.1.0/24", "10.0.0.0/25", "invalid"}},
+	}, nil)
+	c.Assert(a.Allocate(net.ParseIP("10.0.0.10")), IsNil)
+
+	status := map[string]ciliumv2.IPAMPoolStatus{}
+	a.addStatus(status)
+	c.Assert(status, DeepEquals, map[string]ciliumv2.IPAMPoolStatus{
+		"a": {CIDRs: []string{"10.0.0.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 1, Available: 253}},
+		"b": {CIDRs: []string{"10.0.1.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 0, Available: 254}},
+	})
+
+	// Moving a CIDR to another pool keeps its allocations
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "b", CIDRs: []string{"10.0.1.0/24", "10.0.0.0/24"}},
+	}, nil)
+	c.Assert(a.Has(net.ParseIP("10.0.0.10")), Equals, true)
+
+	// Removing a CIDR with allocations keeps it as draining until all
+	// of its addresses have been released
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "b", CIDRs: []string{"10.0.1.0/24"}},
+	}, nil)
+	c.Assert(a.Has(net.ParseIP("10.0.0.10")), Equals, true)
+
+	status = map[string]ciliumv2.IPAMPoolStatus{}
+	a.addStatus(status)
+	c.Assert(status, DeepEquals, map[string]ciliumv2.IPAMPoolStatus{
+		"b": {CIDRs: []string{"10.0.1.0/24", "10.0.0.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 1, Available: 254}},
+	})
+
+	// Draining CIDRs are not used for new allocations
+	for i := 0; i < 10; i++ {
+		ip, err := a.AllocateNext()
+		c.Assert(err, IsNil)
+		c.Assert(ip.Mask(net.CIDRMask(24, 32)).String(), Equals, "10.0.1.0")
+	}
+
+	c.Assert(a.Release(net.ParseIP("10.0.0.10")), IsNil)
+	c.Assert(a.contains(net.ParseIP("10.0.0.10")), Equals, false)
+
+	// Growing a CIDR with allocations keeps the old range until all of
+	// its addresses have been released, the larger CIDR is only used
+	// afterwards to avoid allocating addresses twice
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "c", CIDRs: []string{"10.16.0.0/26"}},
+	}, nil)
+	c.Assert(a.Allocate(net.ParseIP("10.16.0.1")), IsNil)
+
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "c", CIDRs: []string{"10.16.0.0/25"}},
+	}, nil)
+	c.Assert(a.Has(net.ParseIP("10.16.0.1")), Equals, true)
+	c.Assert(a.Allocate(net.ParseIP("10.16.0.1")), Not(IsNil))
+	_, err := a.AllocateNext()
+	c.Assert(err, Not(IsNil))
+
+	status = map[string]ciliumv2.IPAMPoolStatus{}
+	a.addStatus(status)
+	c.Assert(status, DeepEquals, map[string]ciliumv2.IPAMPoolStatus{
+		"b": {CIDRs: []string{"10.0.1.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 10, Available: 0}},
+		"c": {CIDRs: []string{"10.16.0.0/26"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 1, Available: 0}},
+	})
+
+	c.Assert(a.Release(net.ParseIP("10.16.0.1")), IsNil)
+	ip, err := a.AllocateNext()
+	c.Assert(err, IsNil)
+	c.Assert(ip.Mask(net.CIDRMask(25, 32)).String(), Equals, "10.16.0.0")
+
+	status = map[string]ciliumv2.IPAMPoolStatus{}
+	a.addStatus(status)
+	c.Assert(status, DeepEquals, map[string]ciliumv2.IPAMPoolStatus{
+		"b": {CIDRs: []string{"10.0.1.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 10, Available: 0}},
+		"c": {CIDRs: []string{"10.16.0.0/25"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 1, Available: 125}},
+	})
+}
+
+func (s *IPAMSuite) TestCRDAllocatorStatusPerFamily(c *C) {
+	spec := []ciliumv2.IPAMPoolSpec{
+		{Name: "default", CIDRs: []string{"10.0.0.0/24", "f00d::/112"}},
+	}
+	v4 := newCRDAllocator("ipv4")
+	v4.finishRestore()
+	v4.updatePools(spec, nil)
+	v6 := newCRDAllocator("ipv6")
+	v6.finishRestore()
+	v6.updatePools(spec, nil)
+	c.Assert(v6.Allocate(net.ParseIP("f00d::10")), IsNil)
+
+	status := map[string]ciliumv2.IPAMPoolStatus{}
+	v4.addStatus(status)
+	v6.addStatus(status)
+	c.Assert(status, DeepEquals, map[string]ciliumv2.IPAMPoolStatus{
+		"default": {
+			CIDRs: []string{"10.0.0.0/24", "f00d::/112"},
+			IPv4:  &ciliumv2.IPAMPoolUsage{Used: 0, Available: 254},
+			IPv6:  &ciliumv2.IPAMPoolUsage{Used: 1, Available: 65533},
+		},
+	})
+}
+
+func (s *IPAMSuite) TestCRDAllocatorEndpointIDs(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	a := newCRDAllocator("ipv6")
+	a.finishRestore()
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "default", CIDRs: []string{"f00d::/120", "f00e::/119"}},
+	}, nil)
+
+	ipamConf = newTestConfig("")
+	ipamConf.IPv6Allocator = a
+
+	// Once the first CIDR is exhausted, addresses of the second CIDR whose
+	// endpoint ID is used by an address of the first CIDR are skipped. The
+	// IDs 1-254 of the second CIDR are all taken, leaving 256 addresses.
+	ids := map[uint16]struct{}{}
+	for i := 0; i < 510; i++ {
+		_, ipv6, err := AllocateNext("ipv6", "pod")
+		c.Assert(err, IsNil)
+
+		id := addressing.CiliumIPv6(ipv6).EndpointID()
+		_, ok := ids[id]
+		c.Assert(ok, Equals, false, Commentf("duplicate endpoint ID %d of %s", id, ipv6))
+		c.Assert(id, Not(Equals), uint16(0))
+		ids[id] = struct{}{}
+	}
+
+	_, _, err := AllocateNext("ipv6", "pod")
+	c.Assert(err, Not(IsNil))
+
+	// Skipped addresses are released again
+	status := map[string]ciliumv2.IPAMPoolStatus{}
+	a.addStatus(status)
+	c.Assert(status["default"].IPv6.Used, Equals, 510)
+}
+
+func (s *IPAMSuite) TestCRDAllocatorRestore(c *C) {
+	a := newCRDAllocator("ipv4")
+
+	// Draining CIDRs of a previous run are restored from the status so
+	// that restored endpoints can claim their addresses
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "default", CIDRs: []string{"10.0.1.0/24"}},
+	}, map[string]ciliumv2.IPAMPoolStatus{
+		"default": {CIDRs: []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/24"}, IPv4: &ciliumv2.IPAMPoolUsage{Used: 1}},
+	})
+	c.Assert(a.Allocate(net.ParseIP("10.0.0.10")), IsNil)
+
+	for i := 0; i < 10; i++ {
+		ip, err := a.AllocateNext()
+		c.Assert(err, IsNil)
+		c.Assert(ip.Mask(net.CIDRMask(24, 32)).String(), Equals, "10.0.1.0")
+	}
+
+	// Unused draining CIDRs are removed once restoration is complete
+	a.finishRestore()
+	c.Assert(a.contains(net.ParseIP("10.0.0.10")), Equals, true)
+	c.Assert(a.contains(net.ParseIP("10.0.2.10")), Equals, false)
+}
+
+func (s *IPAMSuite) TestCRDAllocatorAllocateNextFromPool(c *C) {
+	a := newCRDAllocator("ipv4")
+	a.finishRestore()
+
+	a.updatePools([]ciliumv2.IPAMPoolSpec{
+		{Name: "external", CIDRs: []string{"192.0.2.0/30"}},
+		{Name: "default", CIDRs: []string{"10.0.0.0/24"}},
+	}, nil)
+	c.Assert(a.hasPool("external"), Equals, true)
+	c.Assert(a.hasPool("unknown"), Equals, false)
+
+	// Allocations without pool are served from the default pool even if
+	// another pool is declared first
+	_, defaultNet, _ := net.ParseCIDR("10.0.0.0/24")
+	ip, err := a.AllocateNext()
+	c.Assert(err, IsNil)
+	c.Assert(defaultNet.Contains(ip), Equals, true)
+
+	_, externalNet, _ := net.ParseCIDR("192.0.2.0/30")
+	external := &crdPoolAllocator{crdAllocator: a, name: "external"}
+	for i := 0; i < 2; i++ {
+		ip, err := external.AllocateNext()
+		c.Assert(err, IsNil)
+		c.Assert(externalNet.Contains(ip), Equals, true)
+	}
+
+	// A full pool does not fall back to other pools
+	_, err = external.AllocateNext()
+	c.Assert(err, Not(IsNil))
+}
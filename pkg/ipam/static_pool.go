@@ -0,0 +1,264 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/node"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
+)
+
+// DefaultPool is the name of the pool used for allocations which do not
+// request a specific pool. In CRD mode, allocations which do not request a
+// pool are served from any pool unless a pool with this name is declared.
+const DefaultPool = "default"
+
+// PoolConfig is the configuration of a named pool
+type PoolConfig struct {
+	// Name is the name of the pool as referenced by the pool annotation
+	Name string
+
+	// IPv4CIDR is the CIDR IPv4 addresses of the pool are allocated
+	// from. IPv4 addresses are allocated from the default pool if nil.
+	IPv4CIDR *net.IPNet
+
+	// IPv6CIDR is the CIDR IPv6 addresses of the pool are allocated
+	// from. IPv6 addresses are allocated from the default pool if nil.
+	IPv6CIDR *net.IPNet
+
+	// Routes are the prefixes routed via the host by endpoints with an
+	// address of the pool. A default route via the host is installed if
+	// empty.
+	Routes []*net.IPNet
+
+	// Masquerade is true if traffic from the pool leaving the node is
+	// masqueraded
+	Masquerade bool
+}
+
+// Pool is a named pool with an allocator for each address family it has a
+// CIDR for
+type Pool struct {
+	PoolConfig
+
+	IPv4Allocator Allocator
+	IPv6Allocator Allocator
+}
+
+// ParsePoolConfig parses the configuration of the pool name from a comma
+// separated list of key=value pairs, e.g.
+// "cidr=192.0.2.0/26,route=198.51.100.0/24,masquerade=false". The key cidr
+// may be given once per address family, route may be repeated. Masquerading
+// of the pool defaults to masquerade.
+func ParsePoolConfig(name, value string, masquerade bool) (*PoolConfig, error) {
+	if name == "" || name == DefaultPool {
+		return nil, fmt.Errorf("invalid pool name %q", name)
+	}
+
+	cfg := &PoolConfig{
+		Name:       name,
+		Masquerade: masquerade,
+	}
+
+	for _, kv := range strings.Split(value, ",") {
+		vals := strings.SplitN(kv, "=", 2)
+		if len(vals) != 2 {
+			return nil, fmt.Errorf("invalid option %q of pool %s: expecting \"<key>=<value>\"", kv, name)
+		}
+
+		switch vals[0] {
+		case "cidr":
+			_, cidr, err := net.ParseCIDR(vals[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR of pool %s: %s", name, err)
+			}
+			target := &cfg.IPv6CIDR
+			if cidr.IP.To4() != nil {
+				target = &cfg.IPv4CIDR
+			}
+			if *target != nil {
+				return nil, fmt.Errorf("pool %s has more than one CIDR of the same address family", name)
+			}
+			*target = cidr
+		case "route":
+			_, route, err := net.ParseCIDR(vals[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid route of pool %s: %s", name, err)
+			}
+			cfg.Routes = append(cfg.Routes, route)
+		case "masquerade":
+			m, err := strconv.ParseBool(vals[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid masquerade value of pool %s: %s", name, err)
+			}
+			cfg.Masquerade = m
+		default:
+			return nil, fmt.Errorf("unknown option %q of pool %s", vals[0], name)
+		}
+	}
+
+	if cfg.IPv4CIDR == nil && cfg.IPv6CIDR == nil {
+		return nil, fmt.Errorf("pool %s has no CIDR", name)
+	}
+
+	return cfg, nil
+}
+
+// ValidatePoolOption validates a pool given as "<name>=<configuration>" on
+// the command line
+func ValidatePoolOption(val string) (string, error) {
+	vals := strings.SplitN(val, "=", 2)
+	if len(vals) != 2 {
+		return "", fmt.Errorf(`invalid IPAM pool: expecting "<name>=<configuration>" got %q`, val)
+	}
+
+	if _, err := ParsePoolConfig(vals[0], vals[1], true); err != nil {
+		return "", err
+	}
+
+	return val, nil
+}
+
+func overlaps(a, b *net.IPNet) bool {
+	return a != nil && b != nil && (a.Contains(b.IP) || b.Contains(a.IP))
+}
+
+// addPool adds the pool cfg. The CIDRs of the pool may not overlap with the
+// reserved CIDRs, e.g. the allocation ranges of the node, or with any other
+// pool. Routes to the CIDRs of the pool are installed via cilium_host.
+func (c *Config) addPool(cfg *PoolConfig, reserved ...*net.IPNet) error {
+	cidrs := append([]*net.IPNet{}, reserved...)
+	for _, p := range c.Pools {
+		cidrs = append(cidrs, p.IPv4CIDR, p.IPv6CIDR)
+	}
+
+	for _, cidr := range []*net.IPNet{cfg.IPv4CIDR, cfg.IPv6CIDR} {
+		for _, other := range cidrs {
+			if overlaps(cidr, other) {
+				return fmt.Errorf("CIDR %s of pool %s overlaps with %s", cidr, cfg.Name, other)
+			}
+		}
+	}
+
+	p := &Pool{PoolConfig: *cfg}
+	if cfg.IPv4CIDR != nil {
+		p.IPv4Allocator = ipallocator.NewCIDRRange(cfg.IPv4CIDR)
+		node.AddAuxPrefix(cfg.IPv4CIDR)
+	}
+	if cfg.IPv6CIDR != nil {
+		p.IPv6Allocator = ipallocator.NewCIDRRange(cfg.IPv6CIDR)
+		node.AddAuxPrefix(cfg.IPv6CIDR)
+	}
+
+	log.WithFields(logrus.Fields{
+		fieldPool:    cfg.Name,
+		"ipv4-cidr":  cfg.IPv4CIDR,
+		"ipv6-cidr":  cfg.IPv6CIDR,
+		"masquerade": cfg.Masquerade,
+	}).Info("Adding IPAM pool")
+
+	c.Pools[cfg.Name] = p
+	return nil
+}
+
+// poolAllocatorFor returns the allocator of the pool containing ip or nil if
+// ip is not part of any pool
+func (c *Config) poolAllocatorFor(ip net.IP) Allocator {
+	for _, p := range c.Pools {
+		switch {
+		case p.IPv4CIDR != nil && p.IPv4CIDR.Contains(ip):
+			return p.IPv4Allocator
+		case p.IPv6CIDR != nil && p.IPv6CIDR.Contains(ip):
+			return p.IPv6Allocator
+		}
+	}
+	return nil
+}
+
+// allocatorsForPool returns the IPv4 and IPv6 allocator of the pool name.
+// The allocators of the default pool are returned if name is empty or
+// DefaultPool and for address families the pool has no addresses for.
+func (c *Config) allocatorsForPool(name string) (Allocator, Allocator, error) {
+	ipv4, ipv6 := c.IPv4Allocator, c.IPv6Allocator
+	if name == "" || name == DefaultPool {
+		return ipv4, ipv6, nil
+	}
+
+	if p, ok := c.Pools[name]; ok {
+		if p.IPv4Allocator != nil {
+			ipv4 = p.IPv4Allocator
+		}
+		if p.IPv6Allocator != nil {
+			ipv6 = p.IPv6Allocator
+		}
+		return ipv4, ipv6, nil
+	}
+
+	found := false
+	if a, ok := c.IPv4Allocator.(*crdAllocator); ok && a.hasPool(name) {
+		ipv4 = &crdPoolAllocator{crdAllocator: a, name: name}
+		found = true
+	}
+	if a, ok := c.IPv6Allocator.(*crdAllocator); ok && a.hasPool(name) {
+		ipv6 = &crdPoolAllocator{crdAllocator: a, name: name}
+		found = true
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("unknown IPAM pool %q", name)
+	}
+
+	return ipv4, ipv6, nil
+}
+
+// sortedPools returns the pools sorted by name
+func (c *Config) sortedPools() []*Pool {
+	pools := make([]*Pool, 0, len(c.Pools))
+	for _, p := range c.Pools {
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools
+}
+
+// GetPools returns the configuration of all pools sorted by name
+func GetPools() []PoolConfig {
+	ipamConf.allocatorMutex.RLock()
+	defer ipamConf.allocatorMutex.RUnlock()
+
+	pools := []PoolConfig{}
+	for _, p := range ipamConf.sortedPools() {
+		pools = append(pools, p.PoolConfig)
+	}
+	return pools
+}
+
+// GetPoolRoutes returns the routes of the pool name. Nil is returned if the
+// pool has no routes, in which case endpoints use a default route via the
+// host.
+func GetPoolRoutes(name string) []*net.IPNet {
+	ipamConf.allocatorMutex.RLock()
+	defer ipamConf.allocatorMutex.RUnlock()
+
+	if p, ok := ipamConf.Pools[name]; ok {
+		return p.Routes
+	}
+	return nil
+}
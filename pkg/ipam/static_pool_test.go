@@ -0,0 +1,143 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ipam
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/common/addressing"
+
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *IPAMSuite) TestParsePoolConfig(c *C) {
+	cfg, err := ParsePoolConfig("external", "cidr=192.0.2.0/26,cidr=2001:db8::/112,route=198.51.100.0/24", true)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.Name, Equals, "external")
+	c.Assert(cfg.IPv4CIDR.String(), Equals, "192.0.2.0/26")
+	c.Assert(cfg.IPv6CIDR.String(), Equals, "2001:db8::/112")
+	c.Assert(len(cfg.Routes), Equals, 1)
+	c.Assert(cfg.Routes[0].String(), Equals, "198.51.100.0/24")
+	c.Assert(cfg.Masquerade, Equals, true)
+
+	cfg, err = ParsePoolConfig("external", "cidr=192.0.2.0/26,masquerade=false", true)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.IPv6CIDR, IsNil)
+	c.Assert(cfg.Masquerade, Equals, false)
+
+	for _, tc := range []struct {
+		name, value string
+	}{
+		{"", "cidr=192.0.2.0/26"},
+		{DefaultPool, "cidr=192.0.2.0/26"},
+		{"external", ""},
+		{"external", "route=198.51.100.0/24"},
+		{"external", "cidr=192.0.2.0/26,cidr=192.0.2.64/26"},
+		{"external", "cidr=invalid"},
+		{"external", "cidr=192.0.2.0/26,route=invalid"},
+		{"external", "cidr=192.0.2.0/26,masquerade=maybe"},
+		{"external", "cidr=192.0.2.0/26,unknown=1"},
+	} {
+		_, err := ParsePoolConfig(tc.name, tc.value, true)
+		c.Assert(err, Not(IsNil), Commentf("%s=%s", tc.name, tc.value))
+	}
+
+	_, err = ValidatePoolOption("external=cidr=192.0.2.0/26")
+	c.Assert(err, IsNil)
+	_, err = ValidatePoolOption("external")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *IPAMSuite) TestAllocateNextFromPool(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	ipamConf = newTestConfig("")
+	_, v4, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6, _ := net.ParseCIDR("f00d::/112")
+
+	cfg, err := ParsePoolConfig("external", "cidr=192.0.2.0/30,route=198.51.100.0/24", true)
+	c.Assert(err, IsNil)
+	c.Assert(ipamConf.addPool(cfg, v4, v6), IsNil)
+
+	for _, value := range []string{"cidr=192.0.2.0/24", "cidr=10.0.0.128/25"} {
+		overlapping, err := ParsePoolConfig("overlapping", value, true)
+		c.Assert(err, IsNil)
+		c.Assert(ipamConf.addPool(overlapping, v4, v6), Not(IsNil))
+	}
+
+	// The pool has no IPv6 CIDR, IPv6 addresses are allocated from the
+	// default pool
+	ipv4, ipv6, err := AllocateNextFromPool("external", "", "pod")
+	c.Assert(err, IsNil)
+	c.Assert(cfg.IPv4CIDR.Contains(ipv4), Equals, true)
+	c.Assert(v6.Contains(ipv6), Equals, true)
+
+	ipv4Default, _, err := AllocateNext("ipv4", "other")
+	c.Assert(err, IsNil)
+	c.Assert(v4.Contains(ipv4Default), Equals, true)
+
+	_, _, err = AllocateNextFromPool("unknown", "", "pod")
+	c.Assert(err, Not(IsNil))
+
+	allocv4, _ := Dump()
+	c.Assert(len(allocv4), Equals, 2)
+
+	// Addresses of the pool are released into the pool
+	c.Assert(ReleaseIP(ipv4), IsNil)
+	c.Assert(ipamConf.Pools["external"].IPv4Allocator.Has(ipv4), Equals, false)
+	c.Assert(AllocateIP(ipv4, "pod"), IsNil)
+	c.Assert(ipamConf.Pools["external"].IPv4Allocator.Has(ipv4), Equals, true)
+
+	c.Assert(len(GetPoolRoutes("external")), Equals, 1)
+	c.Assert(GetPoolRoutes("unknown"), IsNil)
+}
+
+func (s *IPAMSuite) TestAllocateNextFromPoolEndpointIDs(c *C) {
+	oldConf := ipamConf
+	defer func() { ipamConf = oldConf }()
+
+	ipamConf = newTestConfig("")
+	_, v4, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6, _ := net.ParseCIDR("f00d::/120")
+	ipamConf.IPv6Allocator = ipallocator.NewCIDRRange(v6)
+
+	cfg, err := ParsePoolConfig("external", "cidr=192.0.2.0/22,cidr=2001:db8::/119", true)
+	c.Assert(err, IsNil)
+	c.Assert(ipamConf.addPool(cfg, v4, v6), IsNil)
+
+	ids := map[uint16]struct{}{}
+	for i := 0; i < 254; i++ {
+		_, ipv6, err := AllocateNext("ipv6", "pod")
+		c.Assert(err, IsNil)
+		ids[addressing.CiliumIPv6(ipv6).EndpointID()] = struct{}{}
+	}
+
+	// Addresses of the pool sharing their endpoint ID with an address of
+	// the node prefix are skipped
+	for i := 0; i < 256; i++ {
+		_, ipv6, err := AllocateNextFromPool("external", "ipv6", "pod")
+		c.Assert(err, IsNil)
+
+		id := addressing.CiliumIPv6(ipv6).EndpointID()
+		_, ok := ids[id]
+		c.Assert(ok, Equals, false, Commentf("duplicate endpoint ID %d of %s", id, ipv6))
+		ids[id] = struct{}{}
+	}
+
+	_, _, err = AllocateNextFromPool("external", "ipv6", "pod")
+	c.Assert(err, Not(IsNil))
+}
@@ -15,17 +15,64 @@
 package ipam
 
 import (
+	"net"
+
 	"github.com/cilium/cilium/pkg/lock"
 
 	"github.com/containernetworking/cni/plugins/ipam/host-local/backend/allocator"
-	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
 )
 
+// Allocator is the interface of an IP address allocator for a single address
+// family
+type Allocator interface {
+	// Allocate allocates a specific IP
+	Allocate(ip net.IP) error
+
+	// AllocateNext allocates the next available IP
+	AllocateNext() (net.IP, error)
+
+	// Release releases a previously allocated IP
+	Release(ip net.IP) error
+
+	// ForEach calls fn for each allocated IP
+	ForEach(fn func(net.IP))
+
+	// Has returns true if the IP is allocated
+	Has(ip net.IP) bool
+}
+
 // Config is the IPAM configuration used for a particular IPAM type.
 type Config struct {
 	IPAMConfig    allocator.IPAMConfig
-	IPv6Allocator *ipallocator.Range
-	IPv4Allocator *ipallocator.Range
+	IPv6Allocator Allocator
+	IPv4Allocator Allocator
+
+	// Pools are the named pools configured in addition to the default
+	// pool, indexed by name
+	Pools map[string]*Pool
+
+	// owner maps each allocated IP to the owner of the allocation
+	owner map[string]string
+
+	// restored is the set of allocations restored from the checkpoint
+	// which have not been claimed by their owner yet
+	restored map[string]struct{}
+
+	// leaked is the set of restored allocations whose owner could not be
+	// found
+	leaked map[string]struct{}
+
+	// endpointIDs maps the endpoint IDs allocated without an IPv6 address
+	// to derive them from, e.g. for chained endpoints, to their owner
+	endpointIDs map[uint16]string
+
+	// nextEndpointID is the first endpoint ID considered by the next call
+	// to AllocateEndpointID
+	nextEndpointID uint16
+
+	// statePath is the path of the checkpoint file. Checkpointing is
+	// disabled if empty.
+	statePath string
 
 	// mutex covers access to all members of this struct
 	allocatorMutex lock.RWMutex
@@ -125,6 +125,37 @@ func parseToCiliumIngressRule(namespace string, inRule, retRule *api.Rule) {
 			}
 		}
 	}
+
+	if inRule.IngressDeny != nil {
+		retRule.IngressDeny = make([]api.IngressDenyRule, len(inRule.IngressDeny))
+		for i, ing := range inRule.IngressDeny {
+			if ing.FromEndpoints != nil {
+				retRule.IngressDeny[i].FromEndpoints = make([]api.EndpointSelector, len(ing.FromEndpoints))
+				for j, ep := range ing.FromEndpoints {
+					retRule.IngressDeny[i].FromEndpoints[j] = getEndpointSelector(namespace, ep.LabelSelector, true, matchesInit)
+				}
+			}
+
+			if ing.ToPorts != nil {
+				retRule.IngressDeny[i].ToPorts = make([]api.PortDenyRule, len(ing.ToPorts))
+				copy(retRule.IngressDeny[i].ToPorts, ing.ToPorts)
+			}
+			if ing.FromCIDR != nil {
+				retRule.IngressDeny[i].FromCIDR = make([]api.CIDR, len(ing.FromCIDR))
+				copy(retRule.IngressDeny[i].FromCIDR, ing.FromCIDR)
+			}
+
+			if ing.FromCIDRSet != nil {
+				retRule.IngressDeny[i].FromCIDRSet = make([]api.CIDRRule, len(ing.FromCIDRSet))
+				copy(retRule.IngressDeny[i].FromCIDRSet, ing.FromCIDRSet)
+			}
+
+			if ing.FromEntities != nil {
+				retRule.IngressDeny[i].FromEntities = make([]api.Entity, len(ing.FromEntities))
+				copy(retRule.IngressDeny[i].FromEntities, ing.FromEntities)
+			}
+		}
+	}
 }
 
 func parseToCiliumEgressRule(namespace string, inRule, retRule *api.Rule) {
@@ -178,6 +209,37 @@ func parseToCiliumEgressRule(namespace string, inRule, retRule *api.Rule) {
 			}
 		}
 	}
+
+	if inRule.EgressDeny != nil {
+		retRule.EgressDeny = make([]api.EgressDenyRule, len(inRule.EgressDeny))
+		for i, egr := range inRule.EgressDeny {
+			if egr.ToEndpoints != nil {
+				retRule.EgressDeny[i].ToEndpoints = make([]api.EndpointSelector, len(egr.ToEndpoints))
+				for j, ep := range egr.ToEndpoints {
+					retRule.EgressDeny[i].ToEndpoints[j] = getEndpointSelector(namespace, ep.LabelSelector, true, matchesInit)
+				}
+			}
+
+			if egr.ToPorts != nil {
+				retRule.EgressDeny[i].ToPorts = make([]api.PortDenyRule, len(egr.ToPorts))
+				copy(retRule.EgressDeny[i].ToPorts, egr.ToPorts)
+			}
+			if egr.ToCIDR != nil {
+				retRule.EgressDeny[i].ToCIDR = make([]api.CIDR, len(egr.ToCIDR))
+				copy(retRule.EgressDeny[i].ToCIDR, egr.ToCIDR)
+			}
+
+			if egr.ToCIDRSet != nil {
+				retRule.EgressDeny[i].ToCIDRSet = make(api.CIDRRuleSlice, len(egr.ToCIDRSet))
+				copy(retRule.EgressDeny[i].ToCIDRSet, egr.ToCIDRSet)
+			}
+
+			if egr.ToEntities != nil {
+				retRule.EgressDeny[i].ToEntities = make([]api.Entity, len(egr.ToEntities))
+				copy(retRule.EgressDeny[i].ToEntities, egr.ToEntities)
+			}
+		}
+	}
 }
 
 // namespacesAreValid checks the set of namespaces from a rule returns true if
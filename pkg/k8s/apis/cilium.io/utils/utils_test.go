@@ -251,6 +251,83 @@ func Test_ParseToCiliumRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Deny rules are restricted to the namespace of the
+			// policy in the same way as allow rules.
+			name: "parse-deny-in-namespace",
+			args: args{
+				namespace: metav1.NamespaceDefault,
+				rule: &api.Rule{
+					EndpointSelector: api.NewESFromMatchRequirements(
+						map[string]string{
+							role: "backend",
+						},
+						nil,
+					),
+					IngressDeny: []api.IngressDenyRule{
+						{
+							FromEndpoints: []api.EndpointSelector{
+								api.NewESFromMatchRequirements(
+									map[string]string{
+										role: "frontend",
+									},
+									nil,
+								),
+							},
+						},
+					},
+					EgressDeny: []api.EgressDenyRule{
+						{
+							ToCIDR: []api.CIDR{"169.254.169.254/32"},
+						},
+					},
+				},
+			},
+			want: &api.Rule{
+				EndpointSelector: api.NewESFromMatchRequirements(
+					map[string]string{
+						role:      "backend",
+						namespace: "default",
+					},
+					nil,
+				),
+				IngressDeny: []api.IngressDenyRule{
+					{
+						FromEndpoints: []api.EndpointSelector{
+							api.NewESFromMatchRequirements(
+								map[string]string{
+									role:      "frontend",
+									namespace: "default",
+								},
+								nil,
+							),
+						},
+					},
+				},
+				EgressDeny: []api.EgressDenyRule{
+					{
+						ToCIDR: []api.CIDR{"169.254.169.254/32"},
+					},
+				},
+				Labels: labels.LabelArray{
+					{
+						Key:    "io.cilium.k8s.policy.name",
+						Value:  "parse-deny-in-namespace",
+						Source: labels.LabelSourceK8s,
+					},
+					{
+						Key:    "io.cilium.k8s.policy.namespace",
+						Value:  "default",
+						Source: labels.LabelSourceK8s,
+					},
+					{
+						Key:    "io.cilium.k8s.policy.derived-from",
+						Value:  "CiliumNetworkPolicy",
+						Source: labels.LabelSourceK8s,
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
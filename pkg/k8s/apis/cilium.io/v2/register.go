@@ -41,7 +41,7 @@ const (
 
 	// CustomResourceDefinitionSchemaVersion is semver-conformant version of CRD schema
 	// Used to determine if CRD needs to be updated in cluster
-	CustomResourceDefinitionSchemaVersion = "1.10"
+	CustomResourceDefinitionSchemaVersion = "1.17"
 
 	// CustomResourceDefinitionSchemaVersionKey is key to label which holds the CRD schema version
 	CustomResourceDefinitionSchemaVersionKey = "io.cilium.k8s.crd.schema.version"
@@ -96,6 +96,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&CiliumNetworkPolicy{},
 		&CiliumNetworkPolicyList{},
 		&CiliumEndpoint{},
+		&CiliumNode{},
+		&CiliumNodeList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -112,6 +114,10 @@ func CreateCustomResourceDefinitions(clientset apiextensionsclient.Interface) er
 		return err
 	}
 
+	if err := createCNCRD(clientset); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -242,6 +248,52 @@ func createCEPCRD(clientset apiextensionsclient.Interface) error {
 	return createUpdateCRD(clientset, "v2.CiliumEndpoint", res)
 }
 
+// createCNCRD creates and updates the CiliumNode CRD. It should be called
+// on agent startup but is idempotent and safe to call again.
+func createCNCRD(clientset apiextensionsclient.Interface) error {
+	var (
+		// CustomResourceDefinitionSingularName is the singular name of custom resource definition
+		CustomResourceDefinitionSingularName = "ciliumnode"
+
+		// CustomResourceDefinitionPluralName is the plural name of custom resource definition
+		CustomResourceDefinitionPluralName = "ciliumnodes"
+
+		// CustomResourceDefinitionShortNames are the abbreviated names to refer to this CRD's instances
+		CustomResourceDefinitionShortNames = []string{"cn", "ciliumn"}
+
+		// CustomResourceDefinitionKind is the Kind name of custom resource definition
+		CustomResourceDefinitionKind = "CiliumNode"
+
+		CRDName = CustomResourceDefinitionPluralName + "." + SchemeGroupVersion.Group
+	)
+
+	res := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CRDName,
+			Labels: map[string]string{
+				CustomResourceDefinitionSchemaVersionKey: CustomResourceDefinitionSchemaVersion,
+			},
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   SchemeGroupVersion.Group,
+			Version: SchemeGroupVersion.Version,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     CustomResourceDefinitionPluralName,
+				Singular:   CustomResourceDefinitionSingularName,
+				ShortNames: CustomResourceDefinitionShortNames,
+				Kind:       CustomResourceDefinitionKind,
+			},
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+			},
+			Scope:      apiextensionsv1beta1.ClusterScoped,
+			Validation: &cnCRV,
+		},
+	}
+
+	return createUpdateCRD(clientset, "v2.CiliumNode", res)
+}
+
 // createUpdateCRD ensures the CRD object is installed into the k8s cluster. It
 // will create or update the CRD and it's validation when needed
 func createUpdateCRD(clientset apiextensionsclient.Interface, CRDName string, crd *apiextensionsv1beta1.CustomResourceDefinition) error {
@@ -357,6 +409,10 @@ func getInt64(i int64) *int64 {
 	return &i
 }
 
+func getFloat64(f float64) *float64 {
+	return &f
+}
+
 var (
 	// cepCRV is a minimal validation for CEP objects. Since only the agent is
 	// creating them, it is better to be permissive and have some data, if buggy,
@@ -365,6 +421,50 @@ var (
 		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{},
 	}
 
+	cnCRV = apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
+			Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"ipam": {
+							Type: "object",
+							Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+								"pools": {
+									Description: "Pools is the list of allocation pools of the node",
+									Type:        "array",
+									Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+										Schema: &IPAMPool,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	IPAMPool = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "IPAMPoolSpec is an allocation pool of a node",
+		Type:        "object",
+		Required:    []string{"name", "cidrs"},
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"name": {
+				Description: "Name is the name of the pool",
+				Type:        "string",
+				MinLength:   getInt64(1),
+			},
+			"cidrs": {
+				Description: "CIDRs is the list of IPv4 and IPv6 CIDRs addresses are allocated from",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+		},
+	}
+
 	cnpCRV = apiextensionsv1beta1.CustomResourceValidation{
 		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
 			Properties: properties,
@@ -384,6 +484,7 @@ var (
 		"LabelSelectorRequirement": LabelSelectorRequirement,
 		"PortProtocol":             PortProtocol,
 		"PortRule":                 PortRule,
+		"PortRuleDNS":              PortRuleDNS,
 		"PortRuleHTTP":             PortRuleHTTP,
 		"PortRuleKafka":            PortRuleKafka,
 		"PortRuleL7":               PortRuleL7,
@@ -443,6 +544,61 @@ var (
 		},
 	}
 
+	EgressDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "EgressDenyRule contains all rule types which can be applied at egress " +
+			"to deny network traffic that originates inside the endpoint and exits the " +
+			"endpoint selected by the endpointSelector. Deny rules take precedence over " +
+			"any EgressRule allowing the same traffic.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"toCIDR": {
+				Description: "ToCIDR is a list of IP blocks which the endpoint subject to the " +
+					"rule is not allowed to initiate connections to.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+			"toCIDRSet": {
+				Description: "ToCIDRSet is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to initiate connections to, along with a list of " +
+					"subnets contained within their corresponding IP block which are not " +
+					"subject to the deny.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDRRule,
+				},
+			},
+			"toEntities": {
+				Description: "ToEntities is a list of special entities to which the endpoint " +
+					"subject to the rule is not allowed to initiate connections.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type: "string",
+					},
+				},
+			},
+			"toPorts": {
+				Description: "ToPorts is a list of destination ports identified by port number " +
+					"and protocol which the endpoint subject to the rule is not allowed to " +
+					"connect to.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortDenyRule,
+				},
+			},
+			"toEndpoints": {
+				Description: "ToEndpoints is a list of endpoints identified by an " +
+					"EndpointSelector to which the endpoint subject to the rule is not " +
+					"allowed to communicate.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EndpointSelector,
+				},
+			},
+		},
+	}
+
 	EgressRule = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "EgressRule contains all rule types which can be applied at egress, i.e. " +
 			"network traffic that originates inside the endpoint and exits the endpoint " +
@@ -542,6 +698,61 @@ var (
 
 	EndpointSelector = *LabelSelector.DeepCopy()
 
+	IngressDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "IngressDenyRule contains all rule types which can be applied at " +
+			"ingress to deny network traffic that originates outside of the endpoint and " +
+			"is entering the endpoint selected by the endpointSelector. Deny rules take " +
+			"precedence over any IngressRule allowing the same traffic.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"fromCIDR": {
+				Description: "FromCIDR is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to receive connections from.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+			"fromCIDRSet": {
+				Description: "FromCIDRSet is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to receive connections from, along with a list " +
+					"of subnets contained within their corresponding IP block which are not " +
+					"subject to the deny.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDRRule,
+				},
+			},
+			"fromEndpoints": {
+				Description: "FromEndpoints is a list of endpoints identified by an " +
+					"EndpointSelector which are not allowed to communicate with the endpoint " +
+					"subject to the rule.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EndpointSelector,
+				},
+			},
+			"fromEntities": {
+				Description: "FromEntities is a list of special entities which the endpoint " +
+					"subject to the rule is not allowed to receive connections from.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type: "string",
+					},
+				},
+			},
+			"toPorts": {
+				Description: "ToPorts is a list of destination ports identified by port number " +
+					"and protocol which the endpoint subject to the rule is not allowed to " +
+					"receive connections on.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortDenyRule,
+				},
+			},
+		},
+	}
+
 	IngressRule = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "IngressRule contains all rule types which can be applied at ingress, " +
 			"i.e. network traffic that originates outside of the endpoint and is entering " +
@@ -665,6 +876,13 @@ var (
 					Schema: &PortRuleKafka,
 				},
 			},
+			"dns": {
+				Description: "DNS-specific rules.",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortRuleDNS,
+				},
+			},
 			"l7proto": {
 				Description: "Parser type name that uses Key-Value pair rules.",
 				Type:        "string",
@@ -765,6 +983,20 @@ var (
 		Required: []string{"key", "operator"},
 	}
 
+	PortDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortDenyRule is a list of ports/protocol combinations which are " +
+			"denied. Layer 7 rules cannot be specified in deny rules.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"ports": {
+				Description: "Ports is a list of L4 port/protocol",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortProtocol,
+				},
+			},
+		},
+	}
+
 	PortProtocol = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "PortProtocol specifies an L4 port with an optional transport protocol",
 		Required: []string{
@@ -825,6 +1057,26 @@ var (
 		},
 	}
 
+	PortRuleDNS = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortRuleDNS is a list of allowed DNS lookups. Exactly one of " +
+			"matchName or matchPattern must be set.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"matchName": {
+				Description: "MatchName matches literal DNS names. A trailing \".\" is " +
+					"automatically added when missing.",
+				Type:    "string",
+				Pattern: `^([-a-zA-Z0-9_]+[.]?)+$`,
+			},
+			"matchPattern": {
+				Description: "MatchPattern allows using wildcards to match DNS names. " +
+					"The wildcard \"*\" matches 0 or more valid DNS characters within a " +
+					"single label.",
+				Type:    "string",
+				Pattern: `^(([-a-zA-Z0-9_*]+[.]?)+|[*])$`,
+			},
+		},
+	}
+
 	PortRuleHTTP = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "PortRuleHTTP is a list of HTTP protocol constraints. All fields are " +
 			"optional, if all fields are empty or missing, the rule does not have any effect." +
@@ -834,6 +1086,65 @@ var (
 			"characters disallowed from the conventional \"path\" part of a URL as defined by " +
 			"RFC 3986.",
 		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"addHeaders": {
+				Description: "AddHeaders is a list of HTTP headers which are added to requests " +
+					"allowed by this rule before they are forwarded. The value " +
+					"\"%SOURCE_IDENTITY%\" is replaced with the security identity of the " +
+					"source of the request. Headers added by any rule of the port are " +
+					"removed from allowed requests.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"name"},
+						Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+							"name": {
+								Description: "Name is the name of the HTTP header.",
+								Type:        "string",
+								Pattern:     `^[^:]`,
+							},
+							"value": {
+								Description: "Value is the value of the HTTP header.",
+								Type:        "string",
+							},
+						},
+					},
+				},
+			},
+			"headerMatches": {
+				Description: "HeaderMatches is a list of HTTP header value constraints which " +
+					"must all be satisfied by the request.\n\nIf omitted or empty, requests " +
+					"are allowed regardless of header values.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"name"},
+						Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+							"name": {
+								Description: "Name is the name of the HTTP header.",
+								Type:        "string",
+							},
+							"value": {
+								Description: "Value is the exact value the header must have. " +
+									"This field is incompatible with the ValueRegex field.",
+								Type: "string",
+							},
+							"valueRegex": {
+								Description: "ValueRegex is an ECMAScript regex matched " +
+									"against the whole value of the header. Only the syntax " +
+									"shared with RE2 may be used.",
+								Type: "string",
+							},
+							"invert": {
+								Description: "Invert inverts the match. If no value is given, " +
+									"the header must not be present in the request.",
+								Type: "boolean",
+							},
+						},
+					},
+				},
+			},
 			"headers": {
 				Description: "Headers is a list of HTTP headers which must be present in the " +
 					"request. If omitted or empty, requests are allowed regardless of headers " +
@@ -845,6 +1156,28 @@ var (
 					},
 				},
 			},
+			"grpc": {
+				Description: "GRPC restricts the request to calls of a gRPC service, and " +
+					"optionally of a single method of the service. This field is incompatible " +
+					"with the Path and Method fields.\n\nIf omitted, requests are allowed " +
+					"regardless of being gRPC calls.",
+				Type:     "object",
+				Required: []string{"service"},
+				Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+					"service": {
+						Description: "Service is the fully qualified name of the gRPC service, " +
+							"including the package, e.g. \"helloworld.Greeter\".",
+						Type:    "string",
+						Pattern: `^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`,
+					},
+					"method": {
+						Description: "Method is the name of the gRPC method of the service, " +
+							"e.g. \"SayHello\".\n\nIf omitted or empty, all methods of the " +
+							"service are allowed.",
+						Type: "string",
+					},
+				},
+			},
 			"host": {
 				Description: "Host is an extended POSIX regex matched against the host header " +
 					"of a request, e.g. \"foo.com\"\n\nIf omitted or empty, the value of the " +
@@ -865,6 +1198,39 @@ var (
 					"If omitted or empty, all paths are all allowed.",
 				Type: "string",
 			},
+			"rateLimit": {
+				Description: "RateLimit limits the rate of requests allowed by this rule from " +
+					"each source security identity. Requests exceeding the limit are rejected " +
+					"with a 429 response.\n\nIf omitted, requests are not rate limited.",
+				Type:     "object",
+				Required: []string{"requestsPerSecond"},
+				Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+					"requestsPerSecond": {
+						Description: "RequestsPerSecond is the number of requests per second " +
+							"allowed on average.",
+						Type:    "integer",
+						Minimum: getFloat64(1),
+					},
+					"burst": {
+						Description: "Burst is the maximum number of requests allowed in a burst " +
+							"above the average rate.\n\nIf omitted or zero, the burst size is " +
+							"RequestsPerSecond.",
+						Type:    "integer",
+						Minimum: getFloat64(0),
+					},
+				},
+			},
+			"removeHeaders": {
+				Description: "RemoveHeaders is a list of names of HTTP headers which are " +
+					"removed from requests allowed by this rule before they are forwarded.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type:    "string",
+						Pattern: `^[^:]`,
+					},
+				},
+			},
 		},
 	}
 
@@ -928,10 +1294,34 @@ var (
 					"a-z, A-Z, 0-9, -, . and _ Older Kafka versions had longer topic lengths " +
 					"of 255, but in Kafka 0.10 version the length was changed from 255 to 249. " +
 					"For compatibility reasons we are using 255\n\nIf omitted or empty, all " +
-					"topics are allowed.",
+					"topics are allowed.\n\nThis field is incompatible with the TopicPrefix " +
+					"and TopicRegex fields.",
+				Type:      "string",
+				MaxLength: getInt64(255),
+			},
+			"topicPrefix": {
+				Description: "TopicPrefix matches all topics starting with the prefix, e.g. " +
+					"\"tenant-a.\" matches all topics of a tenant following the \"tenant-a.*\" " +
+					"naming convention. The same rules apply as for Topic.\n\nIf omitted or " +
+					"empty, all topics are allowed.",
 				Type:      "string",
 				MaxLength: getInt64(255),
 			},
+			"topicRegex": {
+				Description: "TopicRegex is a regular expression matched against the topic " +
+					"names of the request, in the syntax of the Go regexp package. The same " +
+					"rules apply as for Topic. The expression is not anchored, use \"^\" and " +
+					"\"$\" to match complete topic names.\n\nIf omitted or empty, all topics " +
+					"are allowed.",
+				Type: "string",
+			},
+			"consumerGroup": {
+				Description: "ConsumerGroup is the consumer group contained in JoinGroup, " +
+					"SyncGroup, OffsetCommit and OffsetFetch requests.\n\nThis constraint is " +
+					"ignored for all other requests.\n\nIf omitted or empty, all consumer " +
+					"groups are allowed.",
+				Type: "string",
+			},
 		},
 	}
 
@@ -982,6 +1372,16 @@ var (
 					Schema: &EgressRule,
 				},
 			},
+			"egressDeny": {
+				Description: "EgressDeny is a list of EgressDenyRule which are enforced at " +
+					"egress. Any traffic matching one of the rules is denied, even if it is " +
+					"allowed by an Egress rule. If omitted or empty, this rule does not deny " +
+					"any traffic at egress.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EgressDenyRule,
+				},
+			},
 			"endpointSelector": EndpointSelector,
 			"ingress": {
 				Description: "Ingress is a list of IngressRule which are enforced at ingress. " +
@@ -991,6 +1391,16 @@ var (
 					Schema: &IngressRule,
 				},
 			},
+			"ingressDeny": {
+				Description: "IngressDeny is a list of IngressDenyRule which are enforced at " +
+					"ingress. Any traffic matching one of the rules is denied, even if it is " +
+					"allowed by an Ingress rule. If omitted or empty, this rule does not deny " +
+					"any traffic at ingress.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &IngressDenyRule,
+				},
+			},
 			"labels": {
 				Description: "Labels is a list of optional strings which can be used to " +
 					"re-identify the rule or to store metadata. It is possible to lookup or " +
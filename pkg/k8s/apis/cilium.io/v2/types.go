@@ -275,3 +275,97 @@ type CiliumEndpointList struct {
 	// Items is a list of CiliumEndpoint
 	Items []CiliumEndpoint `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumNode represents a node managed by Cilium. It holds the address
+// allocation pools of the node and the usage of these pools as reported by
+// the agent running on the node.
+// +k8s:openapi-gen=false
+type CiliumNode struct {
+	// +k8s:openapi-gen=false
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec is the desired configuration of the node
+	Spec NodeSpec `json:"spec"`
+
+	// Status is the state of the node as reported by the agent
+	// +optional
+	Status NodeStatus `json:"status"`
+}
+
+// NodeSpec is the desired configuration of a node
+type NodeSpec struct {
+	// IPAM is the address management configuration of the node
+	IPAM IPAMSpec `json:"ipam,omitempty"`
+}
+
+// IPAMSpec is the address management configuration of a node
+type IPAMSpec struct {
+	// Pools is the list of allocation pools of the node. Addresses are
+	// allocated from the pools in the order listed.
+	Pools []IPAMPoolSpec `json:"pools,omitempty"`
+}
+
+// IPAMPoolSpec is an allocation pool of a node
+type IPAMPoolSpec struct {
+	// Name is the name of the pool
+	Name string `json:"name"`
+
+	// CIDRs is the list of IPv4 and IPv6 CIDRs addresses are allocated
+	// from. When a CIDR is removed, no new addresses are allocated from it
+	// but addresses already allocated remain in use until released.
+	CIDRs []string `json:"cidrs"`
+}
+
+// NodeStatus is the state of a node as reported by the agent
+type NodeStatus struct {
+	// IPAM is the usage of the allocation pools of the node
+	IPAM IPAMStatus `json:"ipam,omitempty"`
+}
+
+// IPAMStatus is the usage of the allocation pools of a node
+type IPAMStatus struct {
+	// Pools is the usage of each allocation pool indexed by pool name
+	Pools map[string]IPAMPoolStatus `json:"pools,omitempty"`
+}
+
+// IPAMPoolStatus is the usage of an allocation pool
+type IPAMPoolStatus struct {
+	// CIDRs is the list of CIDRs of the pool in use by the agent. This
+	// includes CIDRs removed from the spec which still have addresses
+	// allocated.
+	CIDRs []string `json:"cidrs,omitempty"`
+
+	// IPv4 is the usage of the IPv4 CIDRs of the pool
+	IPv4 *IPAMPoolUsage `json:"ipv4,omitempty"`
+
+	// IPv6 is the usage of the IPv6 CIDRs of the pool
+	IPv6 *IPAMPoolUsage `json:"ipv6,omitempty"`
+}
+
+// IPAMPoolUsage is the usage of the CIDRs of a single address family of an
+// allocation pool
+type IPAMPoolUsage struct {
+	// Used is the number of addresses allocated
+	Used int `json:"used"`
+
+	// Available is the number of addresses which can still be allocated
+	Available int `json:"available"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumNodeList is a list of CiliumNode objects
+// +k8s:openapi-gen=false
+type CiliumNodeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items is a list of CiliumNode
+	Items []CiliumNode `json:"items"`
+}
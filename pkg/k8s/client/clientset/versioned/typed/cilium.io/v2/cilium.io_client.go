@@ -27,6 +27,7 @@ type CiliumV2Interface interface {
 	RESTClient() rest.Interface
 	CiliumEndpointsGetter
 	CiliumNetworkPoliciesGetter
+	CiliumNodesGetter
 }
 
 // CiliumV2Client is used to interact with features provided by the cilium.io group.
@@ -42,6 +43,10 @@ func (c *CiliumV2Client) CiliumNetworkPolicies(namespace string) CiliumNetworkPo
 	return newCiliumNetworkPolicies(c, namespace)
 }
 
+func (c *CiliumV2Client) CiliumNodes() CiliumNodeInterface {
+	return newCiliumNodes(c)
+}
+
 // NewForConfig creates a new CiliumV2Client for the given config.
 func NewForConfig(c *rest.Config) (*CiliumV2Client, error) {
 	config := *c
@@ -19,3 +19,5 @@ package v2
 type CiliumEndpointExpansion interface{}
 
 type CiliumNetworkPolicyExpansion interface{}
+
+type CiliumNodeExpansion interface{}
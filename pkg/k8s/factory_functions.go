@@ -426,6 +426,8 @@ func equalV1Services(o1, o2 interface{}) bool {
 		headless = true
 	}
 	si1 := loadbalancer.NewK8sServiceInfo(clusterIP, headless, svc1.Labels, svc1.Spec.Selector)
+	ParseServiceExposure(svc1, si1)
+	ParseServiceSessionAffinity(svc1, si1)
 
 	clusterIP = net.ParseIP(svc2.Spec.ClusterIP)
 	headless = false
@@ -433,6 +435,8 @@ func equalV1Services(o1, o2 interface{}) bool {
 		headless = true
 	}
 	si2 := loadbalancer.NewK8sServiceInfo(clusterIP, headless, svc2.Labels, svc2.Spec.Selector)
+	ParseServiceExposure(svc2, si2)
+	ParseServiceSessionAffinity(svc2, si2)
 
 	// Please write all the equalness logic inside the K8sServiceInfo.Equals()
 	// method.
@@ -0,0 +1,76 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/loadbalancer"
+
+	"k8s.io/api/core/v1"
+)
+
+// ParseServiceExposure sets the type, the node ports and the external IPs
+// of the given K8sServiceInfo according to the given k8s service. Load
+// balancer ingress IPs are treated as external IPs.
+func ParseServiceExposure(svc *v1.Service, si *loadbalancer.K8sServiceInfo) {
+	switch svc.Spec.Type {
+	case v1.ServiceTypeNodePort:
+		si.Type = loadbalancer.SVCTypeNodePort
+	case v1.ServiceTypeLoadBalancer:
+		si.Type = loadbalancer.SVCTypeLoadBalancer
+	default:
+		si.Type = loadbalancer.SVCTypeClusterIP
+	}
+
+	si.NodePorts = map[loadbalancer.FEPortName]uint16{}
+	if si.Type != loadbalancer.SVCTypeClusterIP {
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort != 0 {
+				si.NodePorts[loadbalancer.FEPortName(port.Name)] = uint16(port.NodePort)
+			}
+		}
+	}
+
+	si.ExternalIPs = nil
+	for _, externalIP := range svc.Spec.ExternalIPs {
+		if ip := net.ParseIP(externalIP); ip != nil {
+			si.ExternalIPs = append(si.ExternalIPs, ip)
+		}
+	}
+	if si.Type == loadbalancer.SVCTypeLoadBalancer {
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ip := net.ParseIP(ingress.IP); ip != nil {
+				si.ExternalIPs = append(si.ExternalIPs, ip)
+			}
+		}
+	}
+}
+
+// ParseServiceSessionAffinity sets the session affinity of the given
+// K8sServiceInfo according to the given k8s service. Only ClientIP affinity is
+// supported.
+func ParseServiceSessionAffinity(svc *v1.Service, si *loadbalancer.K8sServiceInfo) {
+	si.SessionAffinity = loadbalancer.SessionAffinity{}
+	if svc.Spec.SessionAffinity != v1.ServiceAffinityClientIP {
+		return
+	}
+
+	si.SessionAffinity.Enabled = true
+	if cfg := svc.Spec.SessionAffinityConfig; cfg != nil && cfg.ClientIP != nil &&
+		cfg.ClientIP.TimeoutSeconds != nil && *cfg.ClientIP.TimeoutSeconds > 0 {
+		si.SessionAffinity.Timeout = uint32(*cfg.ClientIP.TimeoutSeconds)
+	}
+}
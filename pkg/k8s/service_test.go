@@ -0,0 +1,88 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/loadbalancer"
+
+	. "gopkg.in/check.v1"
+	"k8s.io/api/core/v1"
+)
+
+func (s *K8sSuite) TestParseServiceExposure(c *C) {
+	svc := &v1.Service{
+		Spec: v1.ServiceSpec{
+			Type:      v1.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+			Ports: []v1.ServicePort{
+				{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080},
+			},
+			ExternalIPs: []string{"1.1.1.1", "invalid"},
+		},
+	}
+
+	si := loadbalancer.NewK8sServiceInfo(net.ParseIP("10.0.0.1"), false, nil, nil)
+	ParseServiceExposure(svc, si)
+	c.Assert(si.Type, Equals, loadbalancer.SVCTypeClusterIP)
+	c.Assert(si.NodePorts, DeepEquals, map[loadbalancer.FEPortName]uint16{})
+	c.Assert(si.ExternalIPs, DeepEquals, []net.IP{net.ParseIP("1.1.1.1")})
+
+	svc.Spec.Type = v1.ServiceTypeNodePort
+	ParseServiceExposure(svc, si)
+	c.Assert(si.Type, Equals, loadbalancer.SVCTypeNodePort)
+	c.Assert(si.NodePorts, DeepEquals, map[loadbalancer.FEPortName]uint16{"http": 30080})
+	c.Assert(si.ExternalIPs, DeepEquals, []net.IP{net.ParseIP("1.1.1.1")})
+
+	svc.Spec.Type = v1.ServiceTypeLoadBalancer
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{
+		{IP: "2.2.2.2"},
+		{Hostname: "lb.example.com"},
+	}
+	ParseServiceExposure(svc, si)
+	c.Assert(si.Type, Equals, loadbalancer.SVCTypeLoadBalancer)
+	c.Assert(si.NodePorts, DeepEquals, map[loadbalancer.FEPortName]uint16{"http": 30080})
+	c.Assert(si.ExternalIPs, DeepEquals, []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")})
+}
+
+func (s *K8sSuite) TestParseServiceSessionAffinity(c *C) {
+	svc := &v1.Service{
+		Spec: v1.ServiceSpec{
+			ClusterIP:       "10.0.0.1",
+			SessionAffinity: v1.ServiceAffinityNone,
+		},
+	}
+
+	si := loadbalancer.NewK8sServiceInfo(net.ParseIP("10.0.0.1"), false, nil, nil)
+	ParseServiceSessionAffinity(svc, si)
+	c.Assert(si.SessionAffinity, Equals, loadbalancer.SessionAffinity{})
+
+	svc.Spec.SessionAffinity = v1.ServiceAffinityClientIP
+	ParseServiceSessionAffinity(svc, si)
+	c.Assert(si.SessionAffinity, Equals, loadbalancer.SessionAffinity{Enabled: true})
+	c.Assert(si.SessionAffinity.TimeoutSec(), Equals, uint32(loadbalancer.DefaultSessionAffinityTimeout))
+
+	timeout := int32(60)
+	svc.Spec.SessionAffinityConfig = &v1.SessionAffinityConfig{
+		ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeout},
+	}
+	ParseServiceSessionAffinity(svc, si)
+	c.Assert(si.SessionAffinity, Equals, loadbalancer.SessionAffinity{Enabled: true, Timeout: 60})
+
+	svc.Spec.SessionAffinity = v1.ServiceAffinityNone
+	ParseServiceSessionAffinity(svc, si)
+	c.Assert(si.SessionAffinity, Equals, loadbalancer.SessionAffinity{})
+}
@@ -138,9 +138,12 @@ func (cc *CorrelationCache) HandleRequest(req *RequestMessage, finishFunc Finish
 		log.Warning("BUG: Overwriting Kafka request message in correlation cache")
 	}
 
+	now := time.Now()
+	req.forwarded = now
+
 	cc.cache[newCorrelationID] = &correlationEntry{
 		request:           req,
-		created:           time.Now(),
+		created:           now,
 		origCorrelationID: origCorrelationID,
 		finishFunc:        finishFunc,
 	}
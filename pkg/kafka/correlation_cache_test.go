@@ -44,6 +44,7 @@ func (k *kafkaTestSuite) TestCorrelation(c *C) {
 	// Verify that the correlation ID has been rewritten to the next
 	// sequence number (1)
 	c.Assert(request1.GetCorrelationID(), Equals, CorrelationID(1))
+	c.Assert(request1.GetForwardedTime().IsZero(), Equals, false)
 
 	// Successful correlation will remove the request from the cache so
 	// subsequent correlation will return nil
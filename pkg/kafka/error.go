@@ -51,4 +51,7 @@ var (
 	ErrGroupAuthorizationFailed                = 30
 	ErrClusterAuthorizationFailed              = 31
 	ErrInvalidTimeStamp                        = 32
+	ErrUnsupportedSaslMechanism                = 33
+	ErrIllegalSaslState                        = 34
+	ErrUnsupportedVersion                      = 35
 )
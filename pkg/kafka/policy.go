@@ -59,7 +59,7 @@ func matchNonTopicRequests(req *RequestMessage, rule api.PortRuleKafka) bool {
 	// 2. The parser could not parse further even if there was a topic present.
 	// For scenario 2, if topic is present, we need to return
 	// false since topic can never be associated with this request kind.
-	if rule.Topic != "" && isTopicAPIKey(req.kind) {
+	if rule.HasTopic() && isTopicAPIKey(req.kind) {
 		return false
 	}
 	// TODO add functionality for parsing clientID GH-3097
@@ -160,9 +160,14 @@ func (req *RequestMessage) ruleMatches(rule api.PortRuleKafka) bool {
 		return false
 	}
 
+	if rule.ConsumerGroup != "" && api.IsConsumerGroupAPIKey(req.kind) &&
+		rule.ConsumerGroup != req.consumerGroup {
+		return false
+	}
+
 	// If the rule contains no additional conditionals, it is not required
 	// to match into the request specific fields.
-	if rule.Topic == "" && rule.ClientID == "" {
+	if !rule.HasTopic() && rule.ClientID == "" {
 		return true
 	}
 
@@ -208,16 +213,25 @@ func (req *RequestMessage) MatchesRule(rules []api.PortRuleKafka) bool {
 	}
 
 	for _, rule := range rules {
-		if rule.Topic == "" || len(topics) == 0 {
+		if !rule.HasTopic() || len(topics) == 0 {
 			if req.ruleMatches(rule) {
 				return true
 			}
-		} else if reqTopicsMap[rule.Topic] {
-			if req.ruleMatches(rule) {
-				delete(reqTopicsMap, rule.Topic)
-				if len(reqTopicsMap) == 0 {
-					return true
-				}
+			continue
+		}
+
+		var matchedTopics []string
+		for topic := range reqTopicsMap {
+			if rule.MatchesTopic(topic) {
+				matchedTopics = append(matchedTopics, topic)
+			}
+		}
+		if len(matchedTopics) > 0 && req.ruleMatches(rule) {
+			for _, topic := range matchedTopics {
+				delete(reqTopicsMap, topic)
+			}
+			if len(reqTopicsMap) == 0 {
+				return true
 			}
 		}
 	}
@@ -15,6 +15,8 @@
 package kafka
 
 import (
+	"bytes"
+	"encoding/binary"
 	"testing"
 	"time"
 
@@ -125,3 +127,68 @@ func (k *kafkaTestSuite) TestUnknownRequest(c *C) {
 	reqMsg = RequestMessage{kind: 19}
 	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{rule1, rule2}), Equals, false)
 }
+
+func (k *kafkaTestSuite) TestTopicPrefixAndRegex(c *C) {
+	reqMsg := RequestMessage{
+		request: &proto.MetadataReq{
+			Topics: []string{"tenant-a.orders", "tenant-a.payments"},
+		},
+	}
+
+	prefix := api.PortRuleKafka{TopicPrefix: "tenant-a."}
+	c.Assert(prefix.Sanitize(), IsNil)
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{prefix}), Equals, true)
+
+	otherPrefix := api.PortRuleKafka{TopicPrefix: "tenant-b."}
+	c.Assert(otherPrefix.Sanitize(), IsNil)
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{otherPrefix}), Equals, false)
+
+	regex := api.PortRuleKafka{TopicRegex: "^tenant-a\\.pay"}
+	c.Assert(regex.Sanitize(), IsNil)
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{regex}), Equals, false)
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{regex, {Topic: "tenant-a.orders"}}), Equals, true)
+
+	c.Assert((&api.PortRuleKafka{TopicRegex: "("}).Sanitize(), Not(IsNil))
+	c.Assert((&api.PortRuleKafka{Topic: "a", TopicPrefix: "a"}).Sanitize(), Not(IsNil))
+}
+
+// joinGroupRequest returns a raw JoinGroup request of version 0
+func joinGroupRequest(clientID, groupID string) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, int32(0))                // size
+	binary.Write(&b, binary.BigEndian, int16(api.JoinGroupKey)) // API key
+	binary.Write(&b, binary.BigEndian, int16(0))                // API version
+	binary.Write(&b, binary.BigEndian, int32(1))                // correlation ID
+	for _, s := range []string{clientID, groupID} {
+		binary.Write(&b, binary.BigEndian, int16(len(s)))
+		b.WriteString(s)
+	}
+	binary.Write(&b, binary.BigEndian, int32(30000)) // session timeout
+	binary.Write(&b, binary.BigEndian, int16(0))     // member ID
+	binary.Write(&b, binary.BigEndian, int16(0))     // protocol type
+	binary.Write(&b, binary.BigEndian, int32(0))     // protocols
+	raw := b.Bytes()
+	binary.BigEndian.PutUint32(raw, uint32(len(raw)-4))
+	return raw
+}
+
+func (k *kafkaTestSuite) TestConsumerGroup(c *C) {
+	reqMsg, err := ReadRequest(bytes.NewReader(joinGroupRequest("client", "tenant-a-group")))
+	c.Assert(err, IsNil)
+	c.Assert(reqMsg.GetConsumerGroup(), Equals, "tenant-a-group")
+
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{{ConsumerGroup: "tenant-a-group"}}), Equals, true)
+	c.Assert(reqMsg.MatchesRule([]api.PortRuleKafka{{ConsumerGroup: "tenant-b-group"}}), Equals, false)
+
+	commit := RequestMessage{
+		kind:          api.OffsetCommitKey,
+		request:       &proto.OffsetCommitReq{ConsumerGroup: "tenant-b-group"},
+		consumerGroup: "tenant-b-group",
+	}
+	c.Assert(commit.MatchesRule([]api.PortRuleKafka{{ConsumerGroup: "tenant-a-group"}}), Equals, false)
+	c.Assert(commit.MatchesRule([]api.PortRuleKafka{{ConsumerGroup: "tenant-b-group"}}), Equals, true)
+
+	// The consumer group is ignored for requests not carrying one
+	produce := RequestMessage{kind: api.ProduceKey, request: &proto.ProduceReq{}}
+	c.Assert(produce.MatchesRule([]api.PortRuleKafka{{ConsumerGroup: "tenant-a-group"}}), Equals, true)
+}
@@ -0,0 +1,365 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/optiopay/kafka/proto"
+)
+
+// Versions of requests and responses starting to use a layout which is not
+// handled by the vendored Kafka codec
+const (
+	// produceRecordBatchVersion is the first version of Produce requests
+	// carrying record batches instead of message sets
+	produceRecordBatchVersion = 3
+
+	// produceLogStartOffsetVersion is the first version of Produce
+	// responses carrying the log start offset of each partition
+	produceLogStartOffsetVersion = 5
+
+	// produceRecordErrorsVersion is the first version of Produce responses
+	// carrying record errors and an error message for each partition
+	produceRecordErrorsVersion = 8
+
+	// fetchSessionVersion is the first version of Fetch requests and
+	// responses carrying a fetch session
+	fetchSessionVersion = 7
+
+	// fetchLeaderEpochVersion is the first version of Fetch requests
+	// carrying the current leader epoch of each partition
+	fetchLeaderEpochVersion = 9
+
+	// fetchRackVersion is the first version of Fetch requests carrying the
+	// rack ID of the client and responses carrying the preferred read
+	// replica of each partition
+	fetchRackVersion = 11
+
+	// metadataAuthorizedOperationsVersion is the first version of Metadata
+	// responses carrying the authorized operations
+	metadataAuthorizedOperationsVersion = 8
+)
+
+// errorCode returns the Kafka error code of err
+func errorCode(err error) int16 {
+	if err == nil {
+		return int16(ErrNone)
+	}
+	if kerr, ok := err.(*proto.KafkaError); ok {
+		return int16(kerr.Errno())
+	}
+	return int16(ErrUnknown)
+}
+
+// readProduceReq reads Produce requests of version produceRecordBatchVersion
+// and later, decoding the record batches of each partition
+func readProduceReq(r io.Reader) (*proto.ProduceReq, error) {
+	var req proto.ProduceReq
+	dec := proto.NewDecoder(r)
+
+	// total message size
+	_ = dec.DecodeInt32()
+	// api key
+	_ = dec.DecodeInt16()
+	req.Version = dec.DecodeInt16()
+	req.CorrelationID = dec.DecodeInt32()
+	req.ClientID = dec.DecodeString()
+	req.TransactionalID = dec.DecodeString()
+	req.RequiredAcks = dec.DecodeInt16()
+	req.Timeout = dec.DecodeDuration32()
+
+	numTopics, err := dec.DecodeArrayLen(false)
+	if err != nil {
+		return nil, err
+	}
+	req.Topics = make([]proto.ProduceReqTopic, numTopics)
+
+	for ti := range req.Topics {
+		topic := &req.Topics[ti]
+		topic.Name = dec.DecodeString()
+
+		numPartitions, err := dec.DecodeArrayLen(false)
+		if err != nil {
+			return nil, err
+		}
+		topic.Partitions = make([]proto.ProduceReqPartition, numPartitions)
+
+		for pi := range topic.Partitions {
+			part := &topic.Partitions[pi]
+			part.ID = dec.DecodeInt32()
+			records := dec.DecodeBytes()
+			if err := dec.Err(); err != nil {
+				return nil, err
+			}
+			if part.Messages, err = readRecordBatches(records); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := dec.Err(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// readFetchReq reads Fetch requests of version fetchSessionVersion and later
+func readFetchReq(r io.Reader) (*proto.FetchReq, error) {
+	var req proto.FetchReq
+	dec := proto.NewDecoder(r)
+
+	// total message size
+	_ = dec.DecodeInt32()
+	// api key
+	_ = dec.DecodeInt16()
+	req.Version = dec.DecodeInt16()
+	req.CorrelationID = dec.DecodeInt32()
+	req.ClientID = dec.DecodeString()
+
+	req.ReplicaID = dec.DecodeInt32()
+	req.MaxWaitTime = dec.DecodeDuration32()
+	req.MinBytes = dec.DecodeInt32()
+	req.MaxBytes = dec.DecodeInt32()
+	req.IsolationLevel = dec.DecodeInt8()
+	// session ID and epoch
+	_ = dec.DecodeInt32()
+	_ = dec.DecodeInt32()
+
+	numTopics, err := dec.DecodeArrayLen(false)
+	if err != nil {
+		return nil, err
+	}
+	req.Topics = make([]proto.FetchReqTopic, numTopics)
+
+	for ti := range req.Topics {
+		topic := &req.Topics[ti]
+		topic.Name = dec.DecodeString()
+
+		numPartitions, err := dec.DecodeArrayLen(false)
+		if err != nil {
+			return nil, err
+		}
+		topic.Partitions = make([]proto.FetchReqPartition, numPartitions)
+
+		for pi := range topic.Partitions {
+			part := &topic.Partitions[pi]
+			part.ID = dec.DecodeInt32()
+			if req.Version >= fetchLeaderEpochVersion {
+				// current leader epoch
+				_ = dec.DecodeInt32()
+			}
+			part.FetchOffset = dec.DecodeInt64()
+			part.LogStartOffset = dec.DecodeInt64()
+			part.MaxBytes = dec.DecodeInt32()
+		}
+	}
+
+	// Topics removed from the fetch session don't give access to any data
+	// and are thus skipped
+	numForgotten, err := dec.DecodeArrayLen(false)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numForgotten; i++ {
+		_ = dec.DecodeString()
+		numPartitions, err := dec.DecodeArrayLen(false)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < numPartitions; j++ {
+			_ = dec.DecodeInt32()
+		}
+	}
+
+	if req.Version >= fetchRackVersion {
+		// rack ID
+		_ = dec.DecodeString()
+	}
+
+	if err := dec.Err(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// responseEncoder encodes responses of versions not handled by the vendored
+// Kafka codec
+type responseEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *responseEncoder) int8(val int8) {
+	e.buf.WriteByte(byte(val))
+}
+
+func (e *responseEncoder) int16(val int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(val))
+	e.buf.Write(b[:])
+}
+
+func (e *responseEncoder) int32(val int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(val))
+	e.buf.Write(b[:])
+}
+
+func (e *responseEncoder) int64(val int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(val))
+	e.buf.Write(b[:])
+}
+
+func (e *responseEncoder) duration(val time.Duration) {
+	e.int32(int32(val / time.Millisecond))
+}
+
+func (e *responseEncoder) string(val string) {
+	e.int16(int16(len(val)))
+	e.buf.WriteString(val)
+}
+
+// arrayLen encodes the length of an array, or -1 for a null array
+func (e *responseEncoder) arrayLen(length int, null bool) {
+	if null {
+		e.int32(-1)
+	} else {
+		e.int32(int32(length))
+	}
+}
+
+// header starts the response with a placeholder for its size followed by the
+// correlation ID
+func (e *responseEncoder) header(correlationID int32) {
+	e.int32(0)
+	e.int32(correlationID)
+}
+
+// bytes returns the encoded response with its size filled in
+func (e *responseEncoder) bytes() []byte {
+	b := e.buf.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// produceRespBytes encodes resp as a Produce response of the given version
+func produceRespBytes(resp *proto.ProduceResp, version int16) []byte {
+	var e responseEncoder
+
+	e.header(resp.CorrelationID)
+	e.arrayLen(len(resp.Topics), false)
+	for _, topic := range resp.Topics {
+		e.string(topic.Name)
+		e.arrayLen(len(topic.Partitions), false)
+		for _, part := range topic.Partitions {
+			e.int32(part.ID)
+			e.int16(errorCode(part.Err))
+			e.int64(part.Offset)
+			e.int64(part.LogAppendTime)
+			if version >= produceLogStartOffsetVersion {
+				// log start offset
+				e.int64(-1)
+			}
+			if version >= produceRecordErrorsVersion {
+				// record errors and null error message
+				e.arrayLen(0, false)
+				e.int16(-1)
+			}
+		}
+	}
+	e.duration(resp.ThrottleTime)
+
+	return e.bytes()
+}
+
+// fetchRespBytes encodes resp as a Fetch response of the given version. The
+// partitions of the response must not carry any messages.
+func fetchRespBytes(resp *proto.FetchResp, version int16) []byte {
+	var e responseEncoder
+
+	e.header(resp.CorrelationID)
+	e.duration(resp.ThrottleTime)
+	// error code and session ID
+	e.int16(int16(ErrNone))
+	e.int32(0)
+	e.arrayLen(len(resp.Topics), false)
+	for _, topic := range resp.Topics {
+		e.string(topic.Name)
+		e.arrayLen(len(topic.Partitions), false)
+		for _, part := range topic.Partitions {
+			e.int32(part.ID)
+			e.int16(errorCode(part.Err))
+			e.int64(part.TipOffset)
+			e.int64(part.LastStableOffset)
+			e.int64(part.LogStartOffset)
+			e.arrayLen(len(part.AbortedTransactions), part.AbortedTransactions == nil)
+			for _, trans := range part.AbortedTransactions {
+				e.int64(trans.ProducerID)
+				e.int64(trans.FirstOffset)
+			}
+			if version >= fetchRackVersion {
+				// no preferred read replica
+				e.int32(-1)
+			}
+			// null records
+			e.int32(-1)
+		}
+	}
+
+	return e.bytes()
+}
+
+// metadataRespBytes encodes resp as a Metadata response of the given version
+func metadataRespBytes(resp *proto.MetadataResp, version int16) []byte {
+	var e responseEncoder
+
+	e.header(resp.CorrelationID)
+	e.duration(resp.ThrottleTime)
+	e.arrayLen(len(resp.Brokers), resp.Brokers == nil)
+	for _, broker := range resp.Brokers {
+		e.int32(broker.NodeID)
+		e.string(broker.Host)
+		e.int32(broker.Port)
+		e.string(broker.Rack)
+	}
+	e.string(resp.ClusterID)
+	e.int32(resp.ControllerID)
+	e.arrayLen(len(resp.Topics), resp.Topics == nil)
+	for _, topic := range resp.Topics {
+		e.int16(errorCode(topic.Err))
+		e.string(topic.Name)
+		if topic.IsInternal {
+			e.int8(1)
+		} else {
+			e.int8(0)
+		}
+		// The partitions of error responses are always empty
+		e.arrayLen(0, false)
+		if version >= metadataAuthorizedOperationsVersion {
+			// authorized operations not requested
+			e.int32(math.MinInt32)
+		}
+	}
+	if version >= metadataAuthorizedOperationsVersion {
+		e.int32(math.MinInt32)
+	}
+
+	return e.bytes()
+}
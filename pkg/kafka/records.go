@@ -0,0 +1,178 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/optiopay/kafka/proto"
+)
+
+const (
+	// recordBatchMagic is the magic byte of record batches, which replace
+	// message sets in Produce requests of version 3 and later
+	recordBatchMagic = 2
+
+	// recordBatchOverhead is the size of the record batch fields preceding
+	// the batch length and the records
+	recordBatchOverhead = 12
+
+	// recordBatchHeaderSize is the size of the record batch header
+	// following the batch length
+	recordBatchHeaderSize = 49
+
+	// recordBatchCompressionMask masks the compression codec in the record
+	// batch attributes
+	recordBatchCompressionMask = 0x07
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// readRecordBatches decodes the record batches in b. The records of
+// compressed batches are not decoded, the batches are only checked to be
+// well formed.
+func readRecordBatches(b []byte) ([]*proto.Message, error) {
+	var messages []*proto.Message
+
+	for len(b) > 0 {
+		if len(b) < recordBatchOverhead+recordBatchHeaderSize {
+			return nil, fmt.Errorf("record batch too short (%d bytes)", len(b))
+		}
+
+		baseOffset := int64(binary.BigEndian.Uint64(b))
+		length := int(int32(binary.BigEndian.Uint32(b[8:])))
+		if length < recordBatchHeaderSize || length > len(b)-recordBatchOverhead {
+			return nil, fmt.Errorf("invalid record batch length %d", length)
+		}
+		batch := b[recordBatchOverhead : recordBatchOverhead+length]
+		b = b[recordBatchOverhead+length:]
+
+		// The header consists of partition leader epoch, magic, CRC,
+		// attributes, last offset delta, first timestamp, max timestamp,
+		// producer ID, producer epoch, base sequence and number of records
+		if magic := int8(batch[4]); magic != recordBatchMagic {
+			return nil, fmt.Errorf("unsupported record batch magic %d", magic)
+		}
+		crc := binary.BigEndian.Uint32(batch[5:])
+		if crc != crc32.Checksum(batch[9:], crc32c) {
+			return nil, fmt.Errorf("record batch CRC mismatch")
+		}
+		attributes := binary.BigEndian.Uint16(batch[9:])
+		numRecords := int(int32(binary.BigEndian.Uint32(batch[45:])))
+
+		if attributes&recordBatchCompressionMask != 0 {
+			continue
+		}
+
+		records := batch[recordBatchHeaderSize:]
+		for i := 0; i < numRecords; i++ {
+			msg, rest, err := readRecord(records)
+			if err != nil {
+				return nil, err
+			}
+			msg.Offset += baseOffset
+			messages = append(messages, msg)
+			records = rest
+		}
+		if len(records) != 0 {
+			return nil, fmt.Errorf("unexpected data following %d records", numRecords)
+		}
+	}
+
+	return messages, nil
+}
+
+// readVarint reads a zigzag encoded variable length integer from b and
+// returns it together with the remainder of b
+func readVarint(b []byte) (int64, []byte, error) {
+	val, size := binary.Varint(b)
+	if size <= 0 {
+		return 0, nil, fmt.Errorf("invalid varint")
+	}
+	return val, b[size:], nil
+}
+
+// readVarBytes reads a byte array preceded by its varint length from b and
+// returns it together with the remainder of b. A negative length denotes a
+// null array.
+func readVarBytes(b []byte) ([]byte, []byte, error) {
+	length, b, err := readVarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length < 0 {
+		return nil, b, nil
+	}
+	if length > int64(len(b)) {
+		return nil, nil, fmt.Errorf("invalid length %d", length)
+	}
+	return b[:length], b[length:], nil
+}
+
+// readRecord reads a single record from the records of a batch. The offset
+// of the returned message is relative to the base offset of the batch.
+func readRecord(b []byte) (*proto.Message, []byte, error) {
+	length, b, err := readVarint(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length < 0 || length > int64(len(b)) {
+		return nil, nil, fmt.Errorf("invalid record length %d", length)
+	}
+	record, rest := b[:length], b[length:]
+
+	// attributes
+	if len(record) < 1 {
+		return nil, nil, fmt.Errorf("record too short")
+	}
+	record = record[1:]
+
+	// timestamp delta
+	if _, record, err = readVarint(record); err != nil {
+		return nil, nil, err
+	}
+
+	msg := &proto.Message{}
+	if msg.Offset, record, err = readVarint(record); err != nil {
+		return nil, nil, err
+	}
+	if msg.Key, record, err = readVarBytes(record); err != nil {
+		return nil, nil, err
+	}
+	if msg.Value, record, err = readVarBytes(record); err != nil {
+		return nil, nil, err
+	}
+
+	numHeaders, record, err := readVarint(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := int64(0); i < numHeaders; i++ {
+		// header key and value
+		if _, record, err = readVarBytes(record); err != nil {
+			return nil, nil, err
+		}
+		if _, record, err = readVarBytes(record); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(record) != 0 {
+		return nil, nil, fmt.Errorf("unexpected data following record headers")
+	}
+
+	return msg, rest, nil
+}
@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/cilium/cilium/pkg/flowdebug"
+	"github.com/cilium/cilium/pkg/policy/api"
 
 	"github.com/optiopay/kafka/proto"
 )
@@ -32,6 +34,13 @@ type RequestMessage struct {
 	version int16
 	rawMsg  []byte
 	request interface{}
+
+	// consumerGroup is the consumer group of requests carrying one
+	consumerGroup string
+
+	// forwarded is the time the request was added to the correlation
+	// cache before being forwarded to the broker
+	forwarded time.Time
 }
 
 // CorrelationID represents the correlation id as defined in the Kafka protocol
@@ -62,6 +71,12 @@ func (req *RequestMessage) GetCorrelationID() CorrelationID {
 	return CorrelationID(0)
 }
 
+// GetForwardedTime returns the time the request was forwarded to the broker,
+// or the zero time if the request has not been forwarded
+func (req *RequestMessage) GetForwardedTime() time.Time {
+	return req.forwarded
+}
+
 // SetCorrelationID modified the correlation ID of the Kafka request
 func (req *RequestMessage) SetCorrelationID(id CorrelationID) {
 	if len(req.rawMsg) >= 12 {
@@ -84,6 +99,33 @@ func (req *RequestMessage) String() string {
 		req.kind, req.version, len(req.rawMsg), string(b))
 }
 
+// GetConsumerGroup returns the consumer group of the Kafka request, or an
+// empty string if the request does not carry a consumer group
+func (req *RequestMessage) GetConsumerGroup() string {
+	return req.consumerGroup
+}
+
+// Versions of JoinGroup and SyncGroup requests starting to use the flexible
+// encoding, which readGroupID is not able to parse
+const (
+	joinGroupFlexibleVersion = 6
+	syncGroupFlexibleVersion = 4
+)
+
+// readGroupID reads the group ID which JoinGroup and SyncGroup requests
+// start with, following the request header
+func (req *RequestMessage) readGroupID() (string, error) {
+	// The request header consists of size, API key, API version,
+	// correlation ID and client ID
+	dec := proto.NewDecoder(bytes.NewReader(req.rawMsg[12:]))
+	dec.DecodeString()
+	groupID := dec.DecodeString()
+	if err := dec.Err(); err != nil {
+		return "", err
+	}
+	return groupID, nil
+}
+
 // GetTopics returns the Kafka request list of topics
 func (req *RequestMessage) GetTopics() []string {
 	if req.request == nil {
@@ -198,14 +240,28 @@ func ReadRequest(reader io.Reader) (*RequestMessage, error) {
 	}
 	req.version = req.extractVersion()
 
+	// Requests of unsupported versions are not decoded, they are answered
+	// with an UNSUPPORTED_VERSION error instead
+	if !req.IsSupportedVersion() {
+		return req, nil
+	}
+
 	var nilSlice []byte
 	buf := bytes.NewBuffer(append(nilSlice, req.rawMsg...))
 
 	switch req.kind {
 	case proto.ProduceReqKind:
-		req.request, err = proto.ReadProduceReq(buf)
+		if req.version >= produceRecordBatchVersion {
+			req.request, err = readProduceReq(buf)
+		} else {
+			req.request, err = proto.ReadProduceReq(buf)
+		}
 	case proto.FetchReqKind:
-		req.request, err = proto.ReadFetchReq(buf)
+		if req.version >= fetchSessionVersion {
+			req.request, err = readFetchReq(buf)
+		} else {
+			req.request, err = proto.ReadFetchReq(buf)
+		}
 	case proto.OffsetReqKind:
 		req.request, err = proto.ReadOffsetReq(buf)
 	case proto.MetadataReqKind:
@@ -213,9 +269,25 @@ func ReadRequest(reader io.Reader) (*RequestMessage, error) {
 	case proto.ConsumerMetadataReqKind:
 		req.request, err = proto.ReadConsumerMetadataReq(buf)
 	case proto.OffsetCommitReqKind:
-		req.request, err = proto.ReadOffsetCommitReq(buf)
+		var commit *proto.OffsetCommitReq
+		commit, err = proto.ReadOffsetCommitReq(buf)
+		if err == nil {
+			req.request, req.consumerGroup = commit, commit.ConsumerGroup
+		}
 	case proto.OffsetFetchReqKind:
-		req.request, err = proto.ReadOffsetFetchReq(buf)
+		var fetch *proto.OffsetFetchReq
+		fetch, err = proto.ReadOffsetFetchReq(buf)
+		if err == nil {
+			req.request, req.consumerGroup = fetch, fetch.ConsumerGroup
+		}
+	case api.JoinGroupKey, api.SyncgroupKey:
+		// The requests are not parsed any further, requests in the
+		// flexible encoding are left without consumer group and thus
+		// don't match rules on the consumer group
+		if (req.kind == api.JoinGroupKey && req.version < joinGroupFlexibleVersion) ||
+			(req.kind == api.SyncgroupKey && req.version < syncGroupFlexibleVersion) {
+			req.consumerGroup, err = req.readGroupID()
+		}
 	default:
 		log.WithField(fieldRequest, req.String()).Debugf("Unknown Kafka request API key: %d", req.kind)
 	}
@@ -102,8 +102,10 @@ func createProduceResponse(req *proto.ProduceReq, err error) (*ResponseMessage,
 		}
 	}
 
-	b, err := resp.Bytes(req.Version)
-	if err != nil {
+	var b []byte
+	if req.Version >= produceLogStartOffsetVersion {
+		b = produceRespBytes(resp, req.Version)
+	} else if b, err = resp.Bytes(req.Version); err != nil {
 		return nil, err
 	}
 
@@ -138,8 +140,10 @@ func createFetchResponse(req *proto.FetchReq, err error) (*ResponseMessage, erro
 		}
 	}
 
-	b, err := resp.Bytes(req.Version)
-	if err != nil {
+	var b []byte
+	if req.Version >= fetchSessionVersion {
+		b = fetchRespBytes(resp, req.Version)
+	} else if b, err = resp.Bytes(req.Version); err != nil {
 		return nil, err
 	}
 
@@ -208,8 +212,10 @@ func createMetadataResponse(req *proto.MetadataReq, err error) (*ResponseMessage
 		}
 	}
 
-	b, err := resp.Bytes(req.Version)
-	if err != nil {
+	var b []byte
+	if req.Version >= metadataAuthorizedOperationsVersion {
+		b = metadataRespBytes(resp, req.Version)
+	} else if b, err = resp.Bytes(req.Version); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,156 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+// apiVersionsFlexibleVersion is the first version of ApiVersions using the
+// flexible encoding in the response body
+const apiVersionsFlexibleVersion = 3
+
+// maxVersions maps the API keys of requests decoded by the proxy to the
+// highest version of the request the proxy is able to decode and to create
+// responses for. ApiVersions responses are limited to these versions so that
+// clients don't negotiate versions the proxy does not understand.
+var maxVersions = map[int16]int16{
+	api.ProduceKey:         8,
+	api.FetchKey:           11,
+	api.OffsetsKey:         2,
+	api.MetadataKey:        8,
+	api.OffsetCommitKey:    3,
+	api.OffsetFetchKey:     3,
+	api.FindCoordinatorKey: 1,
+	api.JoinGroupKey:       joinGroupFlexibleVersion - 1,
+	api.SyncgroupKey:       syncGroupFlexibleVersion - 1,
+	api.APIVersionsKey:     apiVersionsFlexibleVersion,
+}
+
+// IsSupportedVersion returns false if the request is of a version which the
+// proxy is not able to decode. Such requests must not be forwarded, they are
+// answered with CreateUnsupportedVersionResponse instead.
+func (req *RequestMessage) IsSupportedVersion() bool {
+	max, ok := maxVersions[req.kind]
+	return !ok || req.version <= max
+}
+
+// CreateUnsupportedVersionResponse creates a response with the
+// UNSUPPORTED_VERSION error code for a request of an unsupported version.
+//
+// Like Kafka brokers do, the ApiVersions request is answered with a version 0
+// response listing the supported ApiVersions versions, which lets the client
+// retry with a lower version. The layout of responses to other requests of
+// unknown versions is not known, in which case an error is returned.
+func (req *RequestMessage) CreateUnsupportedVersionResponse() (*ResponseMessage, error) {
+	if req.kind != api.APIVersionsKey {
+		return nil, fmt.Errorf("unable to create response for version %d of API key %d",
+			req.version, req.kind)
+	}
+
+	var e responseEncoder
+	e.header(int32(req.GetCorrelationID()))
+	e.int16(int16(ErrUnsupportedVersion))
+	e.arrayLen(1, false)
+	e.int16(api.APIVersionsKey)
+	e.int16(0)
+	e.int16(maxVersions[api.APIVersionsKey])
+
+	return &ResponseMessage{rawMsg: e.bytes()}, nil
+}
+
+// LimitAPIVersions rewrites an ApiVersions response to the request of the
+// given version so that the maximum version of each request decoded by the
+// proxy does not exceed the version supported by the proxy
+func (res *ResponseMessage) LimitAPIVersions(version int16) error {
+	// The ApiVersions response always uses the response header version 0,
+	// consisting of size and correlation ID
+	if len(res.rawMsg) < 10 {
+		return fmt.Errorf("unexpected end of ApiVersions response")
+	}
+	b := res.rawMsg[8:]
+
+	// Brokers answer ApiVersions requests of versions they don't support
+	// with a version 0 response
+	if int(int16(binary.BigEndian.Uint16(b))) == ErrUnsupportedVersion {
+		version = 0
+	}
+	b = b[2:]
+
+	flexible := version >= apiVersionsFlexibleVersion
+	var numKeys int
+	if flexible {
+		n, size := binary.Uvarint(b)
+		if size <= 0 {
+			return fmt.Errorf("invalid ApiVersions array length")
+		}
+		numKeys, b = int(n)-1, b[size:]
+	} else {
+		if len(b) < 4 {
+			return fmt.Errorf("unexpected end of ApiVersions response")
+		}
+		numKeys, b = int(int32(binary.BigEndian.Uint32(b))), b[4:]
+	}
+
+	for i := 0; i < numKeys; i++ {
+		// Each entry consists of API key, min version and max version
+		if len(b) < 6 {
+			return fmt.Errorf("unexpected end of ApiVersions response")
+		}
+		key := int16(binary.BigEndian.Uint16(b))
+		if max, ok := maxVersions[key]; ok && int16(binary.BigEndian.Uint16(b[4:])) > max {
+			binary.BigEndian.PutUint16(b[4:], uint16(max))
+		}
+		b = b[6:]
+
+		if flexible {
+			var err error
+			if b, err = skipTaggedFields(b); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipTaggedFields returns b following the tagged fields it starts with
+func skipTaggedFields(b []byte) ([]byte, error) {
+	numFields, size := binary.Uvarint(b)
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid number of tagged fields")
+	}
+	b = b[size:]
+
+	for i := uint64(0); i < numFields; i++ {
+		// tag
+		_, size = binary.Uvarint(b)
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid tag")
+		}
+		b = b[size:]
+
+		length, size := binary.Uvarint(b)
+		if size <= 0 || uint64(len(b)-size) < length {
+			return nil, fmt.Errorf("invalid tagged field length")
+		}
+		b = b[size+int(length):]
+	}
+
+	return b, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"github.com/optiopay/kafka/proto"
+	. "gopkg.in/check.v1"
+)
+
+// rawWriter builds raw Kafka messages in tests
+type rawWriter struct {
+	bytes.Buffer
+}
+
+func (w *rawWriter) put(vals ...interface{}) {
+	for _, val := range vals {
+		if s, ok := val.(string); ok {
+			binary.Write(w, binary.BigEndian, int16(len(s)))
+			w.WriteString(s)
+		} else {
+			binary.Write(w, binary.BigEndian, val)
+		}
+	}
+}
+
+func (w *rawWriter) varint(val int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutVarint(b[:], val)])
+}
+
+// message returns the written message with its size filled in
+func (w *rawWriter) message() []byte {
+	raw := w.Bytes()
+	binary.BigEndian.PutUint32(raw, uint32(len(raw)-4))
+	return raw
+}
+
+// recordBatch returns an uncompressed record batch with a record for each of
+// the values
+func recordBatch(baseOffset int64, values ...string) []byte {
+	var records rawWriter
+	for i, value := range values {
+		var record rawWriter
+		record.put(int8(0))     // attributes
+		record.varint(0)        // timestamp delta
+		record.varint(int64(i)) // offset delta
+		record.varint(-1)       // null key
+		record.varint(int64(len(value)))
+		record.WriteString(value)
+		record.varint(1) // headers
+		record.varint(1)
+		record.WriteString("h")
+		record.varint(-1)
+
+		records.varint(int64(record.Len()))
+		records.Write(record.Bytes())
+	}
+
+	var header rawWriter
+	header.put(int16(0))               // attributes
+	header.put(int32(len(values) - 1)) // last offset delta
+	header.put(int64(0), int64(0))     // timestamps
+	header.put(int64(-1), int16(-1))   // producer ID and epoch
+	header.put(int32(-1))              // base sequence
+	header.put(int32(len(values)))     // number of records
+	header.Write(records.Bytes())
+
+	var batch rawWriter
+	batch.put(baseOffset, int32(header.Len()+9))
+	batch.put(int32(0), int8(recordBatchMagic))
+	batch.put(crc32.Checksum(header.Bytes(), crc32.MakeTable(crc32.Castagnoli)))
+	batch.Write(header.Bytes())
+	return batch.Bytes()
+}
+
+// produceRequest returns a raw Produce request carrying records for a single
+// partition of topic
+func produceRequest(version int16, topic string, records []byte) []byte {
+	var w rawWriter
+	w.put(int32(0), int16(api.ProduceKey), version, int32(1), "client")
+	w.put(int16(-1))                 // null transactional ID
+	w.put(int16(1), int32(1000))     // acks and timeout
+	w.put(int32(1), topic, int32(1)) // topics and partitions
+	w.put(int32(0), int32(len(records)))
+	w.Write(records)
+	return w.message()
+}
+
+func (k *kafkaTestSuite) TestProduceRecordBatches(c *C) {
+	records := append(recordBatch(0, "foo", "bar"), recordBatch(2, "baz")...)
+	req, err := ReadRequest(bytes.NewReader(produceRequest(7, "orders", records)))
+	c.Assert(err, IsNil)
+	c.Assert(req.GetTopics(), DeepEquals, []string{"orders"})
+
+	produce := req.request.(*proto.ProduceReq)
+	c.Assert(len(produce.Topics[0].Partitions[0].Messages), Equals, 3)
+	for i, value := range []string{"foo", "bar", "baz"} {
+		msg := produce.Topics[0].Partitions[0].Messages[i]
+		c.Assert(msg.Offset, Equals, int64(i))
+		c.Assert(msg.Key, IsNil)
+		c.Assert(string(msg.Value), Equals, value)
+	}
+
+	resp, err := req.CreateResponse(proto.ErrTopicAuthorizationFailed)
+	c.Assert(err, IsNil)
+	raw := resp.GetRaw()
+	c.Assert(int(binary.BigEndian.Uint32(raw)), Equals, len(raw)-4)
+	// topics, name, partitions, partition, error, base offset, log append
+	// time, log start offset and throttle time
+	c.Assert(len(raw), Equals, 8+4+2+6+4+4+2+8+8+8+4)
+	c.Assert(binary.BigEndian.Uint16(raw[28:]), Equals, uint16(ErrTopicAuthorizationFailed))
+
+	// Corrupted record batches are rejected
+	records[len(records)-1] ^= 0xff
+	_, err = ReadRequest(bytes.NewReader(produceRequest(7, "orders", records)))
+	c.Assert(err, Not(IsNil))
+
+	// Record batches are required as of version 3
+	_, err = ReadRequest(bytes.NewReader(produceRequest(3, "orders", []byte("message set"))))
+	c.Assert(err, Not(IsNil))
+}
+
+func (k *kafkaTestSuite) TestFetchSession(c *C) {
+	var w rawWriter
+	w.put(int32(0), int16(api.FetchKey), int16(11), int32(1), "client")
+	w.put(int32(-1), int32(500), int32(1), int32(1<<20), int8(0))
+	w.put(int32(0), int32(0))                // session ID and epoch
+	w.put(int32(1), "orders", int32(1))      // topics and partitions
+	w.put(int32(0), int32(5))                // partition and leader epoch
+	w.put(int64(10), int64(0), int32(1<<20)) // offsets and max bytes
+	w.put(int32(1), "old", int32(1), int32(0))
+	w.put("rack")
+
+	req, err := ReadRequest(bytes.NewReader(w.message()))
+	c.Assert(err, IsNil)
+	c.Assert(req.GetTopics(), DeepEquals, []string{"orders"})
+	fetch := req.request.(*proto.FetchReq)
+	c.Assert(fetch.Topics[0].Partitions[0].FetchOffset, Equals, int64(10))
+
+	resp, err := req.CreateResponse(proto.ErrTopicAuthorizationFailed)
+	c.Assert(err, IsNil)
+	raw := resp.GetRaw()
+	c.Assert(int(binary.BigEndian.Uint32(raw)), Equals, len(raw)-4)
+	// throttle time, error code, session ID, topics, name, partitions,
+	// partition, error, offsets, aborted transactions, preferred read
+	// replica and records
+	c.Assert(len(raw), Equals, 8+4+2+4+4+8+4+4+2+24+4+4+4)
+}
+
+func (k *kafkaTestSuite) TestUnsupportedVersion(c *C) {
+	req, err := ReadRequest(bytes.NewReader(produceRequest(9, "orders", nil)))
+	c.Assert(err, IsNil)
+	c.Assert(req.IsSupportedVersion(), Equals, false)
+	_, err = req.CreateUnsupportedVersionResponse()
+	c.Assert(err, Not(IsNil))
+
+	// Requests not decoded by the proxy are supported in all versions
+	var w rawWriter
+	w.put(int32(0), int16(api.HeartbeatKey), int16(10), int32(1), "client")
+	req, err = ReadRequest(bytes.NewReader(w.message()))
+	c.Assert(err, IsNil)
+	c.Assert(req.IsSupportedVersion(), Equals, true)
+
+	w.Reset()
+	w.put(int32(0), int16(api.APIVersionsKey), int16(apiVersionsFlexibleVersion+1), int32(7), "client")
+	req, err = ReadRequest(bytes.NewReader(w.message()))
+	c.Assert(err, IsNil)
+	c.Assert(req.IsSupportedVersion(), Equals, false)
+
+	resp, err := req.CreateUnsupportedVersionResponse()
+	c.Assert(err, IsNil)
+	w.Reset()
+	w.put(int32(16), int32(7), int16(ErrUnsupportedVersion), int32(1))
+	w.put(int16(api.APIVersionsKey), int16(0), int16(apiVersionsFlexibleVersion))
+	c.Assert(resp.GetRaw(), DeepEquals, w.Bytes())
+}
+
+func (k *kafkaTestSuite) TestLimitAPIVersions(c *C) {
+	var w rawWriter
+	w.put(int32(0), int32(1), int16(0), int32(3))
+	w.put(int16(api.ProduceKey), int16(0), int16(9))
+	w.put(int16(api.HeartbeatKey), int16(0), int16(4))
+	w.put(int16(api.FetchKey), int16(0), int16(4))
+	w.put(int32(0)) // throttle time
+
+	resp := &ResponseMessage{rawMsg: w.message()}
+	c.Assert(resp.LimitAPIVersions(2), IsNil)
+	raw := resp.GetRaw()
+	c.Assert(binary.BigEndian.Uint16(raw[18:]), Equals, uint16(maxVersions[api.ProduceKey]))
+	c.Assert(binary.BigEndian.Uint16(raw[24:]), Equals, uint16(4))
+	c.Assert(binary.BigEndian.Uint16(raw[30:]), Equals, uint16(4))
+
+	// Flexible response with tagged fields
+	w.Reset()
+	w.put(int32(0), int32(1), int16(0), int8(3))
+	w.put(int16(api.FetchKey), int16(0), int16(12), int8(1), int8(0), int8(1), int8(42))
+	w.put(int16(api.MetadataKey), int16(0), int16(9), int8(0))
+	w.put(int32(0), int8(0))
+
+	resp = &ResponseMessage{rawMsg: w.message()}
+	c.Assert(resp.LimitAPIVersions(3), IsNil)
+	raw = resp.GetRaw()
+	c.Assert(binary.BigEndian.Uint16(raw[15:]), Equals, uint16(maxVersions[api.FetchKey]))
+	c.Assert(binary.BigEndian.Uint16(raw[25:]), Equals, uint16(maxVersions[api.MetadataKey]))
+
+	// Truncated responses are rejected
+	resp = &ResponseMessage{rawMsg: raw[:20]}
+	c.Assert(resp.LimitAPIVersions(3), Not(IsNil))
+}
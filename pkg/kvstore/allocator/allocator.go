@@ -27,6 +27,7 @@ import (
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/metrics"
 	"github.com/cilium/cilium/pkg/uuid"
 
 	"github.com/sirupsen/logrus"
@@ -694,6 +695,7 @@ func (a *Allocator) runGC() error {
 				scopedLog.WithError(err).Warning("Unable to delete unused allocator master key")
 			} else {
 				scopedLog.Info("Deleted unused allocator master key")
+				metrics.AllocatorGCReclaimedIDs.WithLabelValues(a.idPrefix).Inc()
 			}
 		}
 
@@ -734,10 +736,13 @@ func (a *Allocator) syncLocalKeys() error {
 func (a *Allocator) startGC() {
 	go func(a *Allocator) {
 		for {
+			status := metrics.LabelValueOutcomeSuccess
 			if err := a.runGC(); err != nil {
+				status = metrics.LabelValueOutcomeFail
 				log.WithError(err).WithFields(logrus.Fields{fieldPrefix: a.idPrefix}).
 					Warning("Unable to run allocator garbage collector")
 			}
+			metrics.AllocatorGCRuns.WithLabelValues(a.idPrefix, status).Inc()
 
 			select {
 			case <-a.stopGC:
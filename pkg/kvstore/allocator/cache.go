@@ -23,6 +23,7 @@ import (
 
 	"github.com/cilium/cilium/pkg/kvstore"
 	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/metrics"
 
 	"github.com/sirupsen/logrus"
 )
@@ -149,6 +150,7 @@ func (c *cache) start(a *Allocator) waitChan {
 					// nextCache is valid, point the live cache to it
 					c.cache = c.nextCache
 					c.keyCache = c.nextKeyCache
+					c.updateMetrics()
 					c.mutex.Unlock()
 					a.idPool.FinishRefresh()
 
@@ -212,6 +214,7 @@ func (c *cache) start(a *Allocator) waitChan {
 						delete(c.nextCache, id)
 						a.idPool.Insert(id)
 					}
+					c.updateMetrics()
 					c.mutex.Unlock()
 
 					if a.events != nil {
@@ -256,6 +259,13 @@ func (c *cache) stop() {
 	default:
 	}
 	c.stopWatchWg.Wait()
+	metrics.AllocatorIDs.DeleteLabelValues(c.prefix)
+}
+
+// updateMetrics reports the number of IDs in the live cache. It must be
+// called with c.mutex held.
+func (c *cache) updateMetrics() {
+	metrics.AllocatorIDs.WithLabelValues(c.prefix).Set(float64(len(c.cache)))
 }
 
 func (c *cache) get(key string) ID {
@@ -184,6 +184,9 @@ func newConsulClient(config *consulAPI.Config) (BackendOperations, error) {
 		controller.ControllerParams{
 			DoFunc: func() error {
 				_, _, err := c.Session().Renew(lease, nil)
+				if err != nil {
+					countLeaseRenewalFailure(consulName)
+				}
 				return err
 			},
 			RunInterval: KeepAliveInterval,
@@ -200,7 +203,9 @@ func (c *consulClient) LockPath(path string) (kvLocker, error) {
 	}
 
 	for retries := 0; retries < maxLockRetries; retries++ {
+		start := time.Now()
 		ch, err := lockKey.Lock(nil)
+		observeOperation(consulName, metricLockPath, start, err)
 		switch {
 		case err != nil:
 			return nil, err
@@ -428,6 +433,7 @@ func (c *consulClient) Watch(w *Watcher) {
 		qo.WaitIndex = nextIndex
 		pairs, q, err := c.KV().List(w.prefix, &qo)
 		if err != nil {
+			countOperationError(consulName, metricWatch)
 			sleepTime = 5 * time.Second
 			Trace("List of Watch failed", err, logrus.Fields{fieldPrefix: w.prefix, fieldWatcher: w.name})
 		}
@@ -506,25 +512,33 @@ func (c *consulClient) Status() (string, error) {
 }
 
 func (c *consulClient) DeletePrefix(path string) error {
+	start := time.Now()
 	_, err := c.Client.KV().DeleteTree(path, nil)
+	observeOperation(consulName, metricDeletePrefix, start, err)
 	return err
 }
 
 // Set sets value of key
 func (c *consulClient) Set(key string, value []byte) error {
+	start := time.Now()
 	_, err := c.KV().Put(&consulAPI.KVPair{Key: key, Value: value}, nil)
+	observeOperation(consulName, metricSet, start, err)
 	return err
 }
 
 // Delete deletes a key
 func (c *consulClient) Delete(key string) error {
+	start := time.Now()
 	_, err := c.KV().Delete(key, nil)
+	observeOperation(consulName, metricDelete, start, err)
 	return err
 }
 
 // Get returns value of key
 func (c *consulClient) Get(key string) ([]byte, error) {
+	start := time.Now()
 	pair, _, err := c.KV().Get(key, nil)
+	observeOperation(consulName, metricGet, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -536,7 +550,9 @@ func (c *consulClient) Get(key string) ([]byte, error) {
 
 // GetPrefix returns the first key which matches the prefix
 func (c *consulClient) GetPrefix(prefix string) ([]byte, error) {
+	start := time.Now()
 	pairs, _, err := c.KV().List(prefix, nil)
+	observeOperation(consulName, metricGetPrefix, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -556,7 +572,9 @@ func (c *consulClient) Update(key string, value []byte, lease bool) error {
 		k.Session = c.lease
 	}
 
+	start := time.Now()
 	_, err := c.KV().Put(k, nil)
+	observeOperation(consulName, metricUpdate, start, err)
 	return err
 }
 
@@ -572,7 +590,9 @@ func (c *consulClient) CreateOnly(key string, value []byte, lease bool) error {
 		k.Session = c.lease
 	}
 
+	start := time.Now()
 	success, _, err := c.KV().CAS(k, nil)
+	observeOperation(consulName, metricCreateOnly, start, err)
 	if err != nil {
 		return fmt.Errorf("unable to compare-and-swap: %s", err)
 	}
@@ -615,7 +635,9 @@ func (c *consulClient) CreateIfExists(condKey, key string, value []byte, lease b
 
 // ListPrefix returns a map of matching keys
 func (c *consulClient) ListPrefix(prefix string) (KeyValuePairs, error) {
+	start := time.Now()
 	pairs, _, err := c.KV().List(prefix, nil)
+	observeOperation(consulName, metricListPrefix, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +192,7 @@ func (e *etcdClient) renewSession() error {
 
 	newSession, err := concurrency.NewSession(e.client, concurrency.WithTTL(int(LeaseTTL.Seconds())))
 	if err != nil {
+		countLeaseRenewalFailure(EtcdBackendName)
 		return fmt.Errorf("Unable to renew etcd session: %s", err)
 	}
 
@@ -359,7 +360,9 @@ func (e *etcdClient) LockPath(path string) (kvLocker, error) {
 
 	ctx, cancel := ctx.WithTimeout(ctx.Background(), 1*time.Minute)
 	defer cancel()
+	start := time.Now()
 	err := mu.Lock(ctx)
+	observeOperation(EtcdBackendName, metricLockPath, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -537,7 +540,9 @@ func (e *etcdClient) GASNewL3n4AddrID(basePath string, baseID uint32, lAddrID *l
 }
 
 func (e *etcdClient) DeletePrefix(path string) error {
+	start := time.Now()
 	_, err := e.client.Delete(ctx.Background(), path, client.WithPrefix())
+	observeOperation(EtcdBackendName, metricDeletePrefix, start, err)
 	return err
 }
 
@@ -556,6 +561,7 @@ reList:
 		res, err := e.client.Get(ctx.Background(), w.prefix, client.WithPrefix(),
 			client.WithSerializable())
 		if err != nil {
+			countOperationError(EtcdBackendName, metricWatch)
 			scopedLog.WithError(err).Warn("Unable to list keys before starting watcher")
 			continue
 		}
@@ -625,6 +631,8 @@ reList:
 				scopedLog := scopedLog.WithField(fieldRev, r.Header.Revision)
 
 				if err := r.Err(); err != nil {
+					countOperationError(EtcdBackendName, metricWatch)
+
 					// We tried to watch on a compacted
 					// revision that may no longer exist,
 					// recreate the watcher and try to
@@ -732,7 +740,9 @@ func (e *etcdClient) Status() (string, error) {
 
 // Get returns value of key
 func (e *etcdClient) Get(key string) ([]byte, error) {
+	start := time.Now()
 	getR, err := e.client.Get(ctx.Background(), key)
+	observeOperation(EtcdBackendName, metricGet, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -745,7 +755,9 @@ func (e *etcdClient) Get(key string) ([]byte, error) {
 
 // GetPrefix returns the first key which matches the prefix
 func (e *etcdClient) GetPrefix(prefix string) ([]byte, error) {
+	start := time.Now()
 	getR, err := e.client.Get(ctx.Background(), prefix, client.WithPrefix())
+	observeOperation(EtcdBackendName, metricGetPrefix, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -758,13 +770,17 @@ func (e *etcdClient) GetPrefix(prefix string) ([]byte, error) {
 
 // Set sets value of key
 func (e *etcdClient) Set(key string, value []byte) error {
+	start := time.Now()
 	_, err := e.client.Put(ctx.Background(), key, string(value))
+	observeOperation(EtcdBackendName, metricSet, start, err)
 	return err
 }
 
 // Delete deletes a key
 func (e *etcdClient) Delete(key string) error {
+	start := time.Now()
 	_, err := e.client.Delete(ctx.Background(), key)
+	observeOperation(EtcdBackendName, metricDelete, start, err)
 	return err
 }
 
@@ -781,12 +797,14 @@ func (e *etcdClient) createOpPut(key string, value []byte, lease bool) *client.O
 // Update creates or updates a key
 func (e *etcdClient) Update(key string, value []byte, lease bool) error {
 	<-e.firstSession
+	start := time.Now()
+	var err error
 	if lease {
-		_, err := e.client.Put(ctx.Background(), key, string(value), client.WithLease(e.GetLeaseID()))
-		return err
+		_, err = e.client.Put(ctx.Background(), key, string(value), client.WithLease(e.GetLeaseID()))
+	} else {
+		_, err = e.client.Put(ctx.Background(), key, string(value))
 	}
-
-	_, err := e.client.Put(ctx.Background(), key, string(value))
+	observeOperation(EtcdBackendName, metricUpdate, start, err)
 	return err
 }
 
@@ -794,7 +812,9 @@ func (e *etcdClient) Update(key string, value []byte, lease bool) error {
 func (e *etcdClient) CreateOnly(key string, value []byte, lease bool) error {
 	req := e.createOpPut(key, value, lease)
 	cond := client.Compare(client.Version(key), "=", 0)
+	start := time.Now()
 	txnresp, err := e.client.Txn(ctx.TODO()).If(cond).Then(*req).Commit()
+	observeOperation(EtcdBackendName, metricCreateOnly, start, err)
 	if err != nil {
 		return err
 	}
@@ -810,7 +830,9 @@ func (e *etcdClient) CreateOnly(key string, value []byte, lease bool) error {
 func (e *etcdClient) CreateIfExists(condKey, key string, value []byte, lease bool) error {
 	req := e.createOpPut(key, value, lease)
 	cond := client.Compare(client.Version(condKey), "!=", 0)
+	start := time.Now()
 	txnresp, err := e.client.Txn(ctx.TODO()).If(cond).Then(*req).Commit()
+	observeOperation(EtcdBackendName, metricCreateIfExists, start, err)
 	if err != nil {
 		return err
 	}
@@ -843,7 +865,9 @@ func (e *etcdClient) CreateIfExists(condKey, key string, value []byte, lease boo
 
 // ListPrefix returns a map of matching keys
 func (e *etcdClient) ListPrefix(prefix string) (KeyValuePairs, error) {
+	start := time.Now()
 	getR, err := e.client.Get(ctx.Background(), prefix, client.WithPrefix())
+	observeOperation(EtcdBackendName, metricListPrefix, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,56 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package kvstore
+
+import (
+	"time"
+
+	"github.com/cilium/cilium/pkg/metrics"
+)
+
+// Names of the kvstore operations as reported in the operation label of the
+// kvstore metrics
+const (
+	metricGet            = "Get"
+	metricGetPrefix      = "GetPrefix"
+	metricSet            = "Set"
+	metricDelete         = "Delete"
+	metricDeletePrefix   = "DeletePrefix"
+	metricUpdate         = "Update"
+	metricCreateOnly     = "CreateOnly"
+	metricCreateIfExists = "CreateIfExists"
+	metricListPrefix     = "ListPrefix"
+	metricLockPath       = "LockPath"
+	metricWatch          = "Watch"
+)
+
+// observeOperation records the duration of a kvstore operation started at
+// start and counts it as failed if err is not nil
+func observeOperation(backend, operation string, start time.Time, err error) {
+	metrics.KVStoreOperationsDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		countOperationError(backend, operation)
+	}
+}
+
+// countOperationError counts a failed kvstore operation. It is used for
+// long-running operations such as watches for which a duration is of no use.
+func countOperationError(backend, operation string) {
+	metrics.KVStoreOperationErrors.WithLabelValues(backend, operation).Inc()
+}
+
+// countLeaseRenewalFailure counts a failed attempt to renew the kvstore lease
+func countLeaseRenewalFailure(backend string) {
+	metrics.KVStoreLeaseRenewalFailures.WithLabelValues(backend).Inc()
+}
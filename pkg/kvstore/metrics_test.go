@@ -0,0 +1,45 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package kvstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cilium/cilium/pkg/metrics"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *independentSuite) TestObserveOperation(c *C) {
+	errors := metrics.KVStoreOperationErrors.WithLabelValues("test", metricGet)
+	before := metrics.GetCounterValue(errors)
+
+	observeOperation("test", metricGet, time.Now(), nil)
+	c.Assert(metrics.GetCounterValue(errors), Equals, before)
+
+	observeOperation("test", metricGet, time.Now(), fmt.Errorf("failed"))
+	c.Assert(metrics.GetCounterValue(errors), Equals, before+1)
+
+	countOperationError("test", metricGet)
+	c.Assert(metrics.GetCounterValue(errors), Equals, before+2)
+}
+
+func (s *independentSuite) TestCountLeaseRenewalFailure(c *C) {
+	failures := metrics.KVStoreLeaseRenewalFailures.WithLabelValues("test")
+	before := metrics.GetCounterValue(failures)
+
+	countLeaseRenewalFailure("test")
+	c.Assert(metrics.GetCounterValue(failures), Equals, before+1)
+}
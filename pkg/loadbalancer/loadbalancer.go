@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/comparator"
@@ -28,6 +29,7 @@ import (
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/metrics"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/sirupsen/logrus"
 )
 
@@ -56,10 +58,73 @@ type FEPortName string
 // ServiceID is the service's ID.
 type ServiceID uint16
 
+// BackendState is the lifecycle state of a service backend.
+type BackendState uint8
+
+const (
+	// BackendStateActive backends are selected for new connections.
+	BackendStateActive BackendState = iota
+	// BackendStateTerminating backends have been removed from the
+	// service. They are no longer selected for new connections but keep
+	// serving the connections established to them until these are gone
+	// or the drain timeout passed.
+	BackendStateTerminating
+)
+
+func (s BackendState) String() string {
+	switch s {
+	case BackendStateActive:
+		return models.BackendAddressStateActive
+	case BackendStateTerminating:
+		return models.BackendAddressStateTerminating
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
+
 // LBBackEnd represents load balancer backend.
 type LBBackEnd struct {
 	L3n4Addr
 	Weight uint16
+
+	State BackendState
+	// TerminatingSince is the time at which the backend started
+	// terminating, only set for terminating backends.
+	TerminatingSince time.Time
+}
+
+// IsTerminating returns true if the backend is terminating.
+func (lbbe *LBBackEnd) IsTerminating() bool {
+	return lbbe.State == BackendStateTerminating
+}
+
+// AddTerminatingBackends returns the given backends of a service extended by
+// the previous backends of the service, oldBES, which are no longer part of
+// it. These are kept as terminating backends, terminating since now unless
+// they were already terminating before. Backends which are part of bes are active even
+// if they were terminating before.
+func AddTerminatingBackends(oldBES, bes []LBBackEnd, now time.Time) []LBBackEnd {
+	result := make([]LBBackEnd, 0, len(bes))
+	present := map[string]struct{}{}
+	for _, be := range bes {
+		be.State = BackendStateActive
+		be.TerminatingSince = time.Time{}
+		result = append(result, be)
+		present[be.L3n4Addr.String()] = struct{}{}
+	}
+
+	for _, be := range oldBES {
+		if _, ok := present[be.L3n4Addr.String()]; ok {
+			continue
+		}
+		if !be.IsTerminating() {
+			be.State = BackendStateTerminating
+			be.TerminatingSince = now
+		}
+		result = append(result, be)
+		present[be.L3n4Addr.String()] = struct{}{}
+	}
+
+	return result
 }
 
 func (lbbe *LBBackEnd) String() string {
@@ -71,6 +136,32 @@ type LBSVC struct {
 	Sha256 string
 	FE     L3n4AddrID
 	BES    []LBBackEnd
+
+	SessionAffinity SessionAffinity
+}
+
+// DefaultSessionAffinityTimeout is the session affinity timeout in seconds
+// used if none is configured, the same as the one of kube-proxy.
+const DefaultSessionAffinityTimeout = 10800
+
+// SessionAffinity is the ClientIP session affinity of a service. If enabled,
+// the connections of a client keep being sent to the same backend as long as
+// the client opened its last connection less than Timeout seconds ago.
+type SessionAffinity struct {
+	Enabled bool
+	Timeout uint32
+}
+
+// TimeoutSec returns the session affinity timeout in seconds, or 0 if session
+// affinity is disabled.
+func (a SessionAffinity) TimeoutSec() uint32 {
+	if !a.Enabled {
+		return 0
+	}
+	if a.Timeout == 0 {
+		return DefaultSessionAffinityTimeout
+	}
+	return a.Timeout
 }
 
 func (s *LBSVC) GetModel() *models.Service {
@@ -89,6 +180,13 @@ func (s *LBSVC) GetModel() *models.Service {
 		spec.BackendAddresses[i] = be.GetBackendModel()
 	}
 
+	if s.SessionAffinity.Enabled {
+		spec.Flags = &models.ServiceSpecFlags{
+			SessionAffinity:        true,
+			SessionAffinityTimeout: int64(s.SessionAffinity.TimeoutSec()),
+		}
+	}
+
 	return &models.Service{
 		Spec: spec,
 		Status: &models.ServiceStatus{
@@ -119,6 +217,9 @@ type LoadBalancer struct {
 	K8sServices  map[K8sServiceNamespace]*K8sServiceInfo
 	K8sEndpoints map[K8sServiceNamespace]*K8sServiceEndpoint
 	K8sIngress   map[K8sServiceNamespace]*K8sServiceInfo
+	// K8sNodeAddresses are the local node addresses the node ports of
+	// K8sServices are programmed on, protected by K8sMU
+	K8sNodeAddresses []net.IP
 }
 
 // AddService adds a service to list of loadbalancers and returns true if created.
@@ -183,6 +284,20 @@ type K8sServiceNamespace struct {
 	Namespace   string `json:"namespace,omitempty"`
 }
 
+// SVCType is the type of a k8s service.
+type SVCType string
+
+const (
+	// SVCTypeClusterIP is a service only reachable via its ClusterIP.
+	SVCTypeClusterIP = SVCType("ClusterIP")
+	// SVCTypeNodePort is a service which is additionally reachable on a
+	// node port on every node.
+	SVCTypeNodePort = SVCType("NodePort")
+	// SVCTypeLoadBalancer is a service which is additionally exposed via
+	// an external load balancer. It is also reachable on its node ports.
+	SVCTypeLoadBalancer = SVCType("LoadBalancer")
+)
+
 // K8sServiceInfo is an abstraction for a k8s service that is composed by the frontend IP
 // address (FEIP) and the map of the frontend ports (Ports).
 type K8sServiceInfo struct {
@@ -191,6 +306,18 @@ type K8sServiceInfo struct {
 	Ports      map[FEPortName]*FEPort
 	Labels     map[string]string
 	Selector   map[string]string
+
+	// Type is the type of the service.
+	Type SVCType
+	// NodePorts maps the frontend port names to the node port allocated
+	// for them. Only set for NodePort and LoadBalancer services.
+	NodePorts map[FEPortName]uint16
+	// ExternalIPs are the additional IPs, including the load balancer
+	// ingress IPs, on which the frontend ports are exposed.
+	ExternalIPs []net.IP
+
+	// SessionAffinity is the ClientIP session affinity of the service.
+	SessionAffinity SessionAffinity
 }
 
 // IsExternal returns true if the service is expected to serve out-of-cluster endpoints:
@@ -211,9 +338,26 @@ func (si *K8sServiceInfo) Equals(o *K8sServiceInfo) bool {
 	}
 	if si.IsHeadless == o.IsHeadless &&
 		si.FEIP.Equal(o.FEIP) &&
+		si.Type == o.Type &&
+		si.SessionAffinity == o.SessionAffinity &&
 		comparator.MapStringEquals(si.Labels, o.Labels) &&
 		comparator.MapStringEquals(si.Selector, o.Selector) {
 
+		if len(si.NodePorts) != len(o.NodePorts) ||
+			len(si.ExternalIPs) != len(o.ExternalIPs) {
+			return false
+		}
+		for portName, nodePort := range si.NodePorts {
+			if oNodePort, ok := o.NodePorts[portName]; !ok || nodePort != oNodePort {
+				return false
+			}
+		}
+		for i, externalIP := range si.ExternalIPs {
+			if !externalIP.Equal(o.ExternalIPs[i]) {
+				return false
+			}
+		}
+
 		if ((si.Ports == nil) != (o.Ports == nil)) ||
 			len(si.Ports) != len(o.Ports) {
 			return false
@@ -240,9 +384,46 @@ func NewK8sServiceInfo(ip net.IP, headless bool, labels map[string]string, selec
 		Ports:      map[FEPortName]*FEPort{},
 		Labels:     labels,
 		Selector:   selector,
+		Type:       SVCTypeClusterIP,
+		NodePorts:  map[FEPortName]uint16{},
 	}
 }
 
+// ExternalFrontends returns the frontend addresses, other than the one of the
+// ClusterIP, on which the frontend port with the given name is exposed. These
+// are the allocated node port on each of the given node addresses as well as
+// the frontend port on each of the external IPs. Addresses not of the same
+// family as the ClusterIP are skipped.
+func (si *K8sServiceInfo) ExternalFrontends(portName FEPortName, nodeAddrs []net.IP) []*L3n4Addr {
+	fePort, ok := si.Ports[portName]
+	if !ok {
+		return nil
+	}
+
+	isSvcIPv4 := si.FEIP.To4() != nil
+	frontends := []*L3n4Addr{}
+	add := func(ip net.IP, port uint16) {
+		if (ip.To4() != nil) != isSvcIPv4 {
+			return
+		}
+		frontends = append(frontends, &L3n4Addr{
+			IP:     ip,
+			L4Addr: L4Addr{Protocol: fePort.Protocol, Port: port},
+		})
+	}
+
+	if nodePort, ok := si.NodePorts[portName]; ok && nodePort != 0 {
+		for _, ip := range nodeAddrs {
+			add(ip, nodePort)
+		}
+	}
+	for _, ip := range si.ExternalIPs {
+		add(ip, fePort.Port)
+	}
+
+	return frontends
+}
+
 // K8sServiceEndpoint is an abstraction for the k8s endpoint object. Each service is
 // composed by a set of backend IPs (BEIPs) and a map of Ports (Ports). Each k8s endpoint
 // present in BEIPs share the same list of Ports open.
@@ -487,11 +668,16 @@ func (b *LBBackEnd) GetBackendModel() *models.BackendAddress {
 	}
 
 	ip := b.IP.String()
-	return &models.BackendAddress{
+	model := &models.BackendAddress{
 		IP:     &ip,
 		Port:   b.Port,
 		Weight: b.Weight,
+		State:  b.State.String(),
+	}
+	if b.IsTerminating() {
+		model.TerminatingSince = strfmt.DateTime(b.TerminatingSince)
 	}
+	return model
 }
 
 // String returns the L3n4Addr in the "IPv4:Port" format for IPv4 and
@@ -17,6 +17,7 @@ package loadbalancer
 import (
 	"net"
 	"testing"
+	"time"
 
 	"gopkg.in/check.v1"
 )
@@ -39,6 +40,66 @@ func (s *TypesSuite) TestIsK8ServiceExternal(c *check.C) {
 	c.Assert(si.IsExternal(), check.Equals, false)
 }
 
+func (s *TypesSuite) TestExternalFrontends(c *check.C) {
+	si := NewK8sServiceInfo(net.ParseIP("10.0.0.1"), false, nil, nil)
+	si.Type = SVCTypeNodePort
+	si.Ports["http"] = &FEPort{L4Addr: &L4Addr{Protocol: TCP, Port: 80}}
+	si.Ports["dns"] = &FEPort{L4Addr: &L4Addr{Protocol: UDP, Port: 53}}
+	si.NodePorts["http"] = 30080
+	si.ExternalIPs = []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("f00d::1")}
+
+	nodeAddrs := []net.IP{net.ParseIP("192.168.0.1"), net.ParseIP("f00d::2")}
+
+	c.Assert(si.ExternalFrontends("http", nodeAddrs), check.DeepEquals, []*L3n4Addr{
+		{IP: net.ParseIP("192.168.0.1"), L4Addr: L4Addr{Protocol: TCP, Port: 30080}},
+		{IP: net.ParseIP("1.1.1.1"), L4Addr: L4Addr{Protocol: TCP, Port: 80}},
+	})
+	c.Assert(si.ExternalFrontends("dns", nodeAddrs), check.DeepEquals, []*L3n4Addr{
+		{IP: net.ParseIP("1.1.1.1"), L4Addr: L4Addr{Protocol: UDP, Port: 53}},
+	})
+	c.Assert(si.ExternalFrontends("unknown", nodeAddrs), check.IsNil)
+}
+
+func (s *TypesSuite) TestSessionAffinityTimeoutSec(c *check.C) {
+	c.Assert(SessionAffinity{}.TimeoutSec(), check.Equals, uint32(0))
+	c.Assert(SessionAffinity{Timeout: 10}.TimeoutSec(), check.Equals, uint32(0))
+	c.Assert(SessionAffinity{Enabled: true}.TimeoutSec(), check.Equals, uint32(DefaultSessionAffinityTimeout))
+	c.Assert(SessionAffinity{Enabled: true, Timeout: 10}.TimeoutSec(), check.Equals, uint32(10))
+}
+
+func (s *TypesSuite) TestAddTerminatingBackends(c *check.C) {
+	be1 := LBBackEnd{L3n4Addr: L3n4Addr{IP: net.ParseIP("10.0.0.1"), L4Addr: L4Addr{Protocol: TCP, Port: 80}}}
+	be2 := LBBackEnd{L3n4Addr: L3n4Addr{IP: net.ParseIP("10.0.0.2"), L4Addr: L4Addr{Protocol: TCP, Port: 80}}}
+	be3 := LBBackEnd{L3n4Addr: L3n4Addr{IP: net.ParseIP("10.0.0.3"), L4Addr: L4Addr{Protocol: TCP, Port: 80}}}
+	t1 := time.Unix(1000, 0)
+	t2 := time.Unix(2000, 0)
+
+	// be2 was removed and becomes terminating
+	bes := AddTerminatingBackends([]LBBackEnd{be1, be2}, []LBBackEnd{be1, be3}, t1)
+	c.Assert(len(bes), check.Equals, 3)
+	c.Assert(bes[0].L3n4Addr.String(), check.Equals, be1.L3n4Addr.String())
+	c.Assert(bes[0].IsTerminating(), check.Equals, false)
+	c.Assert(bes[1].L3n4Addr.String(), check.Equals, be3.L3n4Addr.String())
+	c.Assert(bes[1].IsTerminating(), check.Equals, false)
+	c.Assert(bes[2].L3n4Addr.String(), check.Equals, be2.L3n4Addr.String())
+	c.Assert(bes[2].IsTerminating(), check.Equals, true)
+	c.Assert(bes[2].TerminatingSince, check.Equals, t1)
+
+	// be2 keeps the time it started terminating at
+	bes = AddTerminatingBackends(bes, []LBBackEnd{be1, be3}, t2)
+	c.Assert(len(bes), check.Equals, 3)
+	c.Assert(bes[2].IsTerminating(), check.Equals, true)
+	c.Assert(bes[2].TerminatingSince, check.Equals, t1)
+
+	// be2 is added back and becomes active again
+	bes = AddTerminatingBackends(bes, []LBBackEnd{be1, be2, be3}, t2)
+	c.Assert(len(bes), check.Equals, 3)
+	for _, be := range bes {
+		c.Assert(be.IsTerminating(), check.Equals, false)
+		c.Assert(be.TerminatingSince.IsZero(), check.Equals, true)
+	}
+}
+
 func TestL4Addr_Equals(t *testing.T) {
 	type args struct {
 		o *L4Addr
@@ -510,6 +571,66 @@ func TestK8sServiceInfo_Equals(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "different node ports",
+			fields: &K8sServiceInfo{
+				FEIP:      net.ParseIP("1.1.1.1"),
+				Type:      SVCTypeNodePort,
+				NodePorts: map[FEPortName]uint16{"foo": 30001},
+			},
+			args: args{
+				o: &K8sServiceInfo{
+					FEIP:      net.ParseIP("1.1.1.1"),
+					Type:      SVCTypeNodePort,
+					NodePorts: map[FEPortName]uint16{"foo": 30002},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "different type",
+			fields: &K8sServiceInfo{
+				FEIP:      net.ParseIP("1.1.1.1"),
+				Type:      SVCTypeNodePort,
+				NodePorts: map[FEPortName]uint16{"foo": 30001},
+			},
+			args: args{
+				o: &K8sServiceInfo{
+					FEIP:      net.ParseIP("1.1.1.1"),
+					Type:      SVCTypeLoadBalancer,
+					NodePorts: map[FEPortName]uint16{"foo": 30001},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "different external IPs",
+			fields: &K8sServiceInfo{
+				FEIP:        net.ParseIP("1.1.1.1"),
+				ExternalIPs: []net.IP{net.ParseIP("2.2.2.2")},
+			},
+			args: args{
+				o: &K8sServiceInfo{
+					FEIP:        net.ParseIP("1.1.1.1"),
+					ExternalIPs: []net.IP{net.ParseIP("3.3.3.3")},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "different session affinity timeout",
+			fields: &K8sServiceInfo{
+				FEIP:            net.ParseIP("1.1.1.1"),
+				SessionAffinity: SessionAffinity{Enabled: true, Timeout: 10},
+			},
+			args: args{
+				o: &K8sServiceInfo{
+					FEIP:            net.ParseIP("1.1.1.1"),
+					SessionAffinity: SessionAffinity{Enabled: true, Timeout: 20},
+				},
+			},
+			want: false,
+		},
 		{
 			name: "both nil",
 			args: args{},
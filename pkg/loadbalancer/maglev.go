@@ -0,0 +1,101 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+const (
+	// MaglevTableSize is the number of entries in the Maglev lookup table
+	// of a service. It must be a prime number and should be considerably
+	// larger than the number of backends of a service for an even
+	// distribution of flows.
+	MaglevTableSize = 251
+)
+
+// maglevPermutation returns the preference list of lookup table entries of
+// the backend with the given name, as described in the Maglev paper.
+func maglevPermutation(name string, m uint64) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	offset := h.Sum64() % m
+
+	h.Reset()
+	h.Write([]byte("maglev-skip:" + name))
+	skip := h.Sum64()%(m-1) + 1
+
+	perm := make([]uint64, m)
+	for j := uint64(0); j < m; j++ {
+		perm[j] = (offset + j*skip) % m
+	}
+	return perm
+}
+
+// GetMaglevTable returns a Maglev lookup table of size m for the backends
+// with the given names. Each entry of the table refers to the backend by its
+// name. The table only depends on the set of names, not on their order or
+// duplicates, so that all nodes compute the same table for a service. Adding
+// or removing a single backend only changes a minimal share of the table
+// entries.
+//
+// Returns nil if no backend names are given.
+func GetMaglevTable(backends []string, m uint64) []string {
+	if len(backends) == 0 || m < 2 {
+		return nil
+	}
+
+	names := make([]string, 0, len(backends))
+	seen := make(map[string]struct{}, len(backends))
+	for _, name := range backends {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	perms := make([][]uint64, len(names))
+	for i, name := range names {
+		perms[i] = maglevPermutation(name, m)
+	}
+
+	next := make([]uint64, len(names))
+	entries := make([]int, m)
+	for j := range entries {
+		entries[j] = -1
+	}
+
+	for filled := uint64(0); ; {
+		for i := range names {
+			c := perms[i][next[i]]
+			for entries[c] >= 0 {
+				next[i]++
+				c = perms[i][next[i]]
+			}
+			entries[c] = i
+			next[i]++
+			filled++
+			if filled == m {
+				table := make([]string, m)
+				for j, i := range entries {
+					table[j] = names[i]
+				}
+				return table
+			}
+		}
+	}
+}
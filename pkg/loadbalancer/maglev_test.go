@@ -0,0 +1,73 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancer
+
+import (
+	"fmt"
+
+	"gopkg.in/check.v1"
+)
+
+func maglevBackends(n int) []string {
+	backends := make([]string, n)
+	for i := range backends {
+		backends[i] = fmt.Sprintf("10.0.0.%d:80", i+1)
+	}
+	return backends
+}
+
+func (s *TypesSuite) TestGetMaglevTable(c *check.C) {
+	c.Assert(GetMaglevTable(nil, MaglevTableSize), check.IsNil)
+
+	backends := maglevBackends(5)
+	table := GetMaglevTable(backends, MaglevTableSize)
+	c.Assert(len(table), check.Equals, MaglevTableSize)
+
+	// All backends get an almost equal share of the table
+	count := map[string]int{}
+	for _, backend := range table {
+		count[backend]++
+	}
+	c.Assert(len(count), check.Equals, len(backends))
+	for _, n := range count {
+		c.Assert(n >= MaglevTableSize/len(backends), check.Equals, true)
+		c.Assert(n <= MaglevTableSize/len(backends)+1, check.Equals, true)
+	}
+
+	// Order and duplicates of the backends do not matter
+	reordered := []string{backends[3], backends[1], backends[4], backends[0], backends[2], backends[1]}
+	c.Assert(GetMaglevTable(reordered, MaglevTableSize), check.DeepEquals, table)
+}
+
+func (s *TypesSuite) TestGetMaglevTableMinimalDisruption(c *check.C) {
+	backends := maglevBackends(10)
+	table := GetMaglevTable(backends, MaglevTableSize)
+
+	// Removing a backend only remaps the entries of that backend and a
+	// small share of the other entries.
+	removed := backends[4]
+	newTable := GetMaglevTable(append(backends[:4:4], backends[5:]...), MaglevTableSize)
+	changed := 0
+	for i := range table {
+		if table[i] == removed {
+			c.Assert(newTable[i], check.Not(check.Equals), removed)
+			continue
+		}
+		if table[i] != newTable[i] {
+			changed++
+		}
+	}
+	c.Assert(changed < MaglevTableSize/10, check.Equals, true)
+}
@@ -84,6 +84,9 @@ const (
 	// IPAddr is an IPV4 or IPv6 address
 	IPAddr = "ipAddr"
 
+	// IPAddrs is a list of IPV4 or IPv6 addresses
+	IPAddrs = "ipAddrs"
+
 	// IPMask is an IPV4 or IPv6 address mask
 	IPMask = "ipMask"
 
@@ -25,6 +25,7 @@ import (
 	"unsafe"
 
 	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/byteorder"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/metrics"
@@ -352,6 +353,45 @@ func GC(m *Map, filter *GCFilter) int {
 	return doGC(m, filter)
 }
 
+// ServiceSlavesInUse returns the slaves of the service with the given frontend
+// which are referenced by the unexpired service entries of map m. The
+// specified map must be already opened using bpf.OpenMap(). As the frontend
+// may be either end of the tuple of a service entry depending on the direction
+// it was created in, both ends are matched.
+func ServiceSlavesInUse(m *Map, frontendIP net.IP, frontendPort uint16) (map[uint16]struct{}, error) {
+	t, _ := bpf.GetMtime()
+	now := uint32(t / 1000000000)
+	slaves := map[uint16]struct{}{}
+
+	matches := func(daddr, saddr net.IP, dport, sport uint16) bool {
+		return (daddr.Equal(frontendIP) || saddr.Equal(frontendIP)) &&
+			(byteorder.NetworkToHost(dport).(uint16) == frontendPort ||
+				byteorder.NetworkToHost(sport).(uint16) == frontendPort)
+	}
+
+	err := m.DumpWithCallback(func(key bpf.MapKey, value bpf.MapValue) {
+		entry := value.(*CtEntry)
+		if entry.Lifetime < now {
+			return
+		}
+
+		switch k := key.(type) {
+		case *CtKey4Global:
+			if k.Flags&TUPLE_F_SERVICE != 0 &&
+				matches(k.DestAddr.IP(), k.SourceAddr.IP(), k.DestPort, k.SourcePort) {
+				slaves[entry.Slave] = struct{}{}
+			}
+		case *CtKey6Global:
+			if k.Flags&TUPLE_F_SERVICE != 0 &&
+				matches(k.DestAddr.IP(), k.SourceAddr.IP(), k.DestPort, k.SourcePort) {
+				slaves[entry.Slave] = struct{}{}
+			}
+		}
+	})
+
+	return slaves, err
+}
+
 // Flush runs garbage collection for map m with the name mapType, deleting all
 // entries. The specified map must be already opened using bpf.OpenMap().
 func (m *Map) Flush() int {
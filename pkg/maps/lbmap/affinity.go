@@ -0,0 +1,185 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbmap
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/cilium/cilium/common/types"
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/byteorder"
+)
+
+var (
+	// Affinity4Map represents the BPF map holding the backend each IPv4
+	// client was last assigned to for services with session affinity.
+	Affinity4Map = bpf.NewMap("cilium_lb4_affinity",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Affinity4Key{})),
+		int(unsafe.Sizeof(Affinity4Value{})),
+		MaxEntries,
+		0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			aKey, aVal := Affinity4Key{}, Affinity4Value{}
+
+			if err := bpf.ConvertKeyValue(key, value, &aKey, &aVal); err != nil {
+				return nil, nil, err
+			}
+
+			return &aKey, &aVal, nil
+		})
+	// Affinity6Map represents the BPF map holding the backend each IPv6
+	// client was last assigned to for services with session affinity.
+	Affinity6Map = bpf.NewMap("cilium_lb6_affinity",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Affinity6Key{})),
+		int(unsafe.Sizeof(Affinity6Value{})),
+		MaxEntries,
+		0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			aKey, aVal := Affinity6Key{}, Affinity6Value{}
+
+			if err := bpf.ConvertKeyValue(key, value, &aKey, &aVal); err != nil {
+				return nil, nil, err
+			}
+
+			return &aKey, &aVal, nil
+		})
+)
+
+// InitAffinityMaps selects the map type of the session affinity maps. Entries
+// are evicted in LRU order if the kernel supports it, so that clients which
+// went away do not exhaust the maps. It must be called after the BPF feature
+// probes have been read and before the maps are opened.
+func InitAffinityMaps() {
+	Affinity4Map.MapType = bpf.GetLRUMapType()
+	Affinity6Map.MapType = bpf.GetLRUMapType()
+}
+
+// Affinity4Key must match 'struct lb4_affinity_key' in "bpf/lib/common.h".
+type Affinity4Key struct {
+	ClientIP types.IPv4
+	// RevNat is the reverse NAT ID of the service in network byte order
+	RevNat uint16
+	Pad    uint16
+}
+
+func (k *Affinity4Key) NewValue() bpf.MapValue    { return &Affinity4Value{} }
+func (k *Affinity4Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+func (k *Affinity4Key) String() string {
+	return fmt.Sprintf("%s (%d)", k.ClientIP, byteorder.NetworkToHost(k.RevNat))
+}
+
+// Affinity6Key must match 'struct lb6_affinity_key' in "bpf/lib/common.h".
+type Affinity6Key struct {
+	ClientIP types.IPv6
+	// RevNat is the reverse NAT ID of the service in network byte order
+	RevNat uint16
+	Pad    uint16
+}
+
+func (k *Affinity6Key) NewValue() bpf.MapValue    { return &Affinity6Value{} }
+func (k *Affinity6Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+func (k *Affinity6Key) String() string {
+	return fmt.Sprintf("[%s] (%d)", k.ClientIP, byteorder.NetworkToHost(k.RevNat))
+}
+
+// Affinity4Value must match 'struct lb4_affinity_val' in "bpf/lib/common.h".
+type Affinity4Value struct {
+	// Target and Port identify the backend the client is assigned to,
+	// Port is in network byte order
+	Target types.IPv4
+	Port   uint16
+	// Slave is the backend index the backend was last seen at, the
+	// affinity is ignored if the slave holds another backend
+	Slave uint16
+	// LastUsed is the time in seconds the client last opened a connection
+	// to the service
+	LastUsed uint32
+}
+
+func (v *Affinity4Value) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+func (v *Affinity4Value) String() string {
+	return fmt.Sprintf("%s:%d (%d) last_used=%d", v.Target,
+		byteorder.NetworkToHost(v.Port), v.Slave, v.LastUsed)
+}
+
+// Affinity6Value must match 'struct lb6_affinity_val' in "bpf/lib/common.h".
+type Affinity6Value struct {
+	// Target and Port identify the backend the client is assigned to,
+	// Port is in network byte order
+	Target types.IPv6
+	Port   uint16
+	// Slave is the backend index the backend was last seen at, the
+	// affinity is ignored if the slave holds another backend
+	Slave uint16
+	// LastUsed is the time in seconds the client last opened a connection
+	// to the service
+	LastUsed uint32
+}
+
+func (v *Affinity6Value) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+func (v *Affinity6Value) String() string {
+	return fmt.Sprintf("[%s]:%d (%d) last_used=%d", v.Target,
+		byteorder.NetworkToHost(v.Port), v.Slave, v.LastUsed)
+}
+
+// DeleteAffinity removes all session affinity entries of the service with the
+// given reverse NAT ID from cilium_lb6_affinity or cilium_lb4_affinity.
+func DeleteAffinity(revNATID uint16, isIPv6 bool) error {
+	var (
+		m     *bpf.Map
+		stale []bpf.MapKey
+	)
+
+	if isIPv6 {
+		m = Affinity6Map
+	} else {
+		m = Affinity4Map
+	}
+
+	if _, err := m.OpenOrCreate(); err != nil {
+		return err
+	}
+
+	id := byteorder.HostToNetwork(revNATID).(uint16)
+	err := m.DumpWithCallback(func(key bpf.MapKey, _ bpf.MapValue) {
+		switch k := key.(type) {
+		case *Affinity4Key:
+			if k.RevNat == id {
+				stale = append(stale, k)
+			}
+		case *Affinity6Key:
+			if k.RevNat == id {
+				stale = append(stale, k)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stale {
+		if err := m.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,6 +14,10 @@
 
 package lbmap
 
+import (
+	"sort"
+)
+
 type serviceValueMap map[string]ServiceValue
 
 type bpfBackend struct {
@@ -88,13 +92,18 @@ func (b *bpfService) deleteBackend(backend ServiceValue) {
 		}
 	}
 
-	// select the backend with the most duplicates that is not the backend
+	// select the backend with the most duplicates that is not the backend,
+	// preferring backends which are not terminating
 	var lowestCount int
 	var fillBackendID string
+	var fillTerminating bool
 	for backendID, count := range duplicateCount {
-		if lowestCount == 0 || count < lowestCount {
+		terminating := b.uniqueBackends[backendID].IsTerminating()
+		if fillBackendID == "" || (fillTerminating && !terminating) ||
+			(fillTerminating == terminating && count < lowestCount) {
 			lowestCount = count
 			fillBackendID = backendID
+			fillTerminating = terminating
 		}
 	}
 
@@ -119,6 +128,29 @@ func (b *bpfService) deleteBackend(backend ServiceValue) {
 	delete(b.uniqueBackends, idToRemove)
 }
 
+func (b *bpfService) updateBackend(backend ServiceValue) {
+	id := backend.String()
+	for _, slot := range b.backendsByMapIndex {
+		if slot.id == id {
+			slot.bpfValue = backend
+		}
+	}
+	b.uniqueBackends[id] = backend
+}
+
+// getBackendSlaves returns the map indices of all slots of the backend with
+// the given ID, including the holes filled in by the backend.
+func (b *bpfService) getBackendSlaves(id string) []int {
+	slaves := []int{}
+	for index, slot := range b.backendsByMapIndex {
+		if slot.id == id {
+			slaves = append(slaves, index)
+		}
+	}
+	sort.Ints(slaves)
+	return slaves
+}
+
 func (b *bpfService) getBackends() []ServiceValue {
 	backends := make([]ServiceValue, len(b.backendsByMapIndex))
 	for i := 1; i <= len(b.backendsByMapIndex); i++ {
@@ -168,10 +200,13 @@ func (l *lbmapCache) prepareUpdate(fe ServiceKey, backends []ServiceValue) *bpfS
 
 	// Step 2: Add all backends that don't exist yet. This will use up
 	// holes that have been created by deleteBackend() first before adding
-	// new slave slots.
+	// new slave slots. Backends which already exist are updated in all
+	// slots they occupy, e.g. as they started terminating.
 	for _, b := range backends {
 		if _, ok := bpfSvc.uniqueBackends[b.String()]; !ok {
 			bpfSvc.addBackend(b)
+		} else {
+			bpfSvc.updateBackend(b)
 		}
 	}
 
@@ -181,3 +216,14 @@ func (l *lbmapCache) prepareUpdate(fe ServiceKey, backends []ServiceValue) *bpfS
 func (l *lbmapCache) delete(fe ServiceKey) {
 	delete(l.entries, fe.String())
 }
+
+// GetBackendSlaves returns the slave indices the given backend of the service
+// with the given frontend occupies in the BPF service map. The caller must
+// serialize calls with UpdateService() and DeleteService().
+func GetBackendSlaves(fe ServiceKey, backend ServiceValue) []int {
+	bpfSvc, ok := cache.entries[fe.String()]
+	if !ok {
+		return nil
+	}
+	return bpfSvc.getBackendSlaves(backend.String())
+}
@@ -174,3 +174,37 @@ func (b *LBMapTestSuite) TestPrepareUpdate(c *C) {
 	backends = bpfSvc.getBackends()
 	c.Assert(len(backends), Equals, 0)
 }
+
+func (b *LBMapTestSuite) TestPrepareUpdateTerminating(c *C) {
+	cache := newLBMapCache()
+
+	ip := net.ParseIP("1.1.1.1")
+	c.Assert(ip, Not(IsNil))
+	frontend := NewService4Key(ip, 80, 0)
+
+	b1 := createBackend(c, "2.2.2.2", 80, 1)
+	b2 := createBackend(c, "3.3.3.3", 80, 1)
+	b3 := createBackend(c, "4.4.4.4", 80, 1)
+
+	cache.prepareUpdate(frontend, []ServiceValue{b1, b2, b3})
+
+	// b2 starts terminating, it keeps its slot
+	b2t := createBackend(c, "3.3.3.3", 80, 1)
+	b2t.SetTerminating(true)
+	c.Assert(b2t.IsTerminating(), Equals, true)
+	bpfSvc := cache.prepareUpdate(frontend, []ServiceValue{b1, b2t, b3})
+	c.Assert(bpfSvc.backendsByMapIndex[1].bpfValue, DeepEquals, b1)
+	c.Assert(bpfSvc.backendsByMapIndex[2].bpfValue, DeepEquals, b2t)
+	c.Assert(bpfSvc.backendsByMapIndex[3].bpfValue, DeepEquals, b3)
+	c.Assert(bpfSvc.getBackendSlaves(b2t.String()), DeepEquals, []int{2})
+
+	// the hole of b1 is filled in by b3 rather than the terminating b2
+	bpfSvc = cache.prepareUpdate(frontend, []ServiceValue{b2t, b3})
+	c.Assert(bpfSvc.backendsByMapIndex[1].bpfValue, DeepEquals, b3)
+	c.Assert(bpfSvc.backendsByMapIndex[1].isHole, Equals, true)
+	c.Assert(bpfSvc.getBackendSlaves(b3.String()), DeepEquals, []int{1, 3})
+
+	// b2 becomes active again
+	bpfSvc = cache.prepareUpdate(frontend, []ServiceValue{b2, b3})
+	c.Assert(bpfSvc.backendsByMapIndex[2].bpfValue.IsTerminating(), Equals, false)
+}
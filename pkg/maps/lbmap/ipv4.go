@@ -71,6 +71,41 @@ var (
 				return nil, nil, err
 			}
 
+			return svcKey.ToNetwork(), &svcVal, nil
+		}).WithCache()
+	// Active4Map represents the BPF map for the lists of active backends
+	// of IPv4 services with terminating backends. The slave of the key is
+	// the list index.
+	Active4Map = bpf.NewMap("cilium_lb4_active",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service4Key{})),
+		int(unsafe.Sizeof(ActiveValue{})),
+		MaxEntries,
+		0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service4Key{}, ActiveValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
+			return svcKey.ToNetwork(), &svcVal, nil
+		}).WithCache()
+	// Maglev4Map represents the BPF map for the Maglev lookup tables of
+	// the IPv4 load balancer. The slave of the key is the table index.
+	Maglev4Map = bpf.NewMap("cilium_lb4_maglev",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service4Key{})),
+		int(unsafe.Sizeof(MaglevValue{})),
+		MaxMaglevEntries,
+		bpf.BPF_F_NO_PREALLOC,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service4Key{}, MaglevValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
 			return svcKey.ToNetwork(), &svcVal, nil
 		}).WithCache()
 )
@@ -85,6 +120,8 @@ type Service4Key struct {
 func (k Service4Key) IsIPv6() bool               { return false }
 func (k Service4Key) Map() *bpf.Map              { return Service4Map }
 func (k Service4Key) RRMap() *bpf.Map            { return RRSeq4Map }
+func (k Service4Key) MaglevMap() *bpf.Map        { return Maglev4Map }
+func (k Service4Key) ActiveMap() *bpf.Map        { return Active4Map }
 func (k Service4Key) NewValue() bpf.MapValue     { return &Service4Value{} }
 func (k *Service4Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
 func (k *Service4Key) GetPort() uint16           { return k.Port }
@@ -136,9 +173,11 @@ func (k *Service4Key) RevNatValue() RevNatValue {
 type Service4Value struct {
 	Address types.IPv4
 	Port    uint16
-	Count   uint16
-	RevNat  uint16
-	Weight  uint16
+	// Count is the number of backends in the master entry and holds the
+	// flags of the backend in backend entries
+	Count  uint16
+	RevNat uint16
+	Weight uint16
 }
 
 func NewService4Value(count uint16, target net.IP, port uint16, revNat uint16, weight uint16) *Service4Value {
@@ -162,6 +201,28 @@ func (s *Service4Value) SetRevNat(id int)            { s.RevNat = uint16(id) }
 func (s *Service4Value) SetWeight(weight uint16)     { s.Weight = weight }
 func (s *Service4Value) GetWeight() uint16           { return s.Weight }
 
+func (s *Service4Value) SetTerminating(terminating bool) {
+	if terminating {
+		s.Count |= serviceFlagTerminating
+	} else {
+		s.Count &^= serviceFlagTerminating
+	}
+}
+
+func (s *Service4Value) IsTerminating() bool {
+	return s.Count&serviceFlagTerminating != 0
+}
+
+// SetAffinityTimeout stores the session affinity timeout in the otherwise
+// unused address of the master entry.
+func (s *Service4Value) SetAffinityTimeout(timeout uint32) {
+	*(*uint32)(unsafe.Pointer(&s.Address[0])) = timeout
+}
+
+func (s *Service4Value) GetAffinityTimeout() uint32 {
+	return *(*uint32)(unsafe.Pointer(&s.Address[0]))
+}
+
 func (s *Service4Value) SetAddress(ip net.IP) error {
 	ip4 := ip.To4()
 	if ip4 == nil {
@@ -74,6 +74,41 @@ var (
 				return nil, nil, err
 			}
 
+			return svcKey.ToNetwork(), &svcVal, nil
+		}).WithCache()
+	// Active6Map represents the BPF map for the lists of active backends
+	// of IPv6 services with terminating backends. The slave of the key is
+	// the list index.
+	Active6Map = bpf.NewMap("cilium_lb6_active",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service6Key{})),
+		int(unsafe.Sizeof(ActiveValue{})),
+		MaxEntries,
+		0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service6Key{}, ActiveValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
+			return svcKey.ToNetwork(), &svcVal, nil
+		}).WithCache()
+	// Maglev6Map represents the BPF map for the Maglev lookup tables of
+	// the IPv6 load balancer. The slave of the key is the table index.
+	Maglev6Map = bpf.NewMap("cilium_lb6_maglev",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service6Key{})),
+		int(unsafe.Sizeof(MaglevValue{})),
+		MaxMaglevEntries,
+		bpf.BPF_F_NO_PREALLOC,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service6Key{}, MaglevValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
 			return svcKey.ToNetwork(), &svcVal, nil
 		}).WithCache()
 )
@@ -99,6 +134,8 @@ func NewService6Key(ip net.IP, port uint16, slave uint16) *Service6Key {
 func (k Service6Key) IsIPv6() bool               { return true }
 func (k Service6Key) Map() *bpf.Map              { return Service6Map }
 func (k Service6Key) RRMap() *bpf.Map            { return RRSeq6Map }
+func (k Service6Key) MaglevMap() *bpf.Map        { return Maglev6Map }
+func (k Service6Key) ActiveMap() *bpf.Map        { return Active6Map }
 func (k Service6Key) NewValue() bpf.MapValue     { return &Service6Value{} }
 func (k *Service6Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
 func (k *Service6Key) GetPort() uint16           { return k.Port }
@@ -135,9 +172,11 @@ func (k *Service6Key) RevNatValue() RevNatValue {
 type Service6Value struct {
 	Address types.IPv6
 	Port    uint16
-	Count   uint16
-	RevNat  uint16
-	Weight  uint16
+	// Count is the number of backends in the master entry and holds the
+	// flags of the backend in backend entries
+	Count  uint16
+	RevNat uint16
+	Weight uint16
 }
 
 func NewService6Value(count uint16, target net.IP, port uint16, revNat uint16, weight uint16) *Service6Value {
@@ -162,6 +201,28 @@ func (s *Service6Value) RevNatKey() RevNatKey        { return &RevNat6Key{s.RevN
 func (s *Service6Value) SetWeight(weight uint16)     { s.Weight = weight }
 func (s *Service6Value) GetWeight() uint16           { return s.Weight }
 
+func (s *Service6Value) SetTerminating(terminating bool) {
+	if terminating {
+		s.Count |= serviceFlagTerminating
+	} else {
+		s.Count &^= serviceFlagTerminating
+	}
+}
+
+func (s *Service6Value) IsTerminating() bool {
+	return s.Count&serviceFlagTerminating != 0
+}
+
+// SetAffinityTimeout stores the session affinity timeout in the otherwise
+// unused address of the master entry.
+func (s *Service6Value) SetAffinityTimeout(timeout uint32) {
+	*(*uint32)(unsafe.Pointer(&s.Address[0])) = timeout
+}
+
+func (s *Service6Value) GetAffinityTimeout() uint32 {
+	return *(*uint32)(unsafe.Pointer(&s.Address[0]))
+}
+
 func (s *Service6Value) SetAddress(ip net.IP) error {
 	if ip.To4() != nil {
 		return fmt.Errorf("Not an IPv6 address")
@@ -17,6 +17,7 @@ package lbmap
 import (
 	"fmt"
 	"net"
+	"time"
 	"unsafe"
 
 	"github.com/cilium/cilium/pkg/bpf"
@@ -24,6 +25,7 @@ import (
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/option"
 
 	"github.com/sirupsen/logrus"
 )
@@ -37,9 +39,21 @@ var (
 )
 
 const (
+	// serviceFlagTerminating marks the entries of terminating backends, must
+	// match LB_SLAVE_F_TERMINATING in "bpf/lib/common.h".
+	serviceFlagTerminating = 1
+
 	// Maximum number of entries in each hashtable
 	MaxEntries   = 65536
 	maxFrontEnds = 256
+	// MaxMaglevServices is the maximum number of services with a Maglev
+	// lookup table, backend selection of further services falls back to
+	// hashing over the backends.
+	MaxMaglevServices = 4096
+	// MaxMaglevEntries is the maximum number of entries in each Maglev
+	// lookup table map, each service uses MaglevTableSize of them. It is
+	// used by daemon for generating bpf define CILIUM_LB_MAGLEV_MAP_MAX_ENTRIES.
+	MaxMaglevEntries = MaxMaglevServices * loadbalancer.MaglevTableSize
 	// MaxSeq is used by daemon for generating bpf define LB_RR_MAX_SEQ.
 	MaxSeq = 31
 )
@@ -63,6 +77,12 @@ type ServiceKey interface {
 	// Returns the BPF Weighted Round Robin map matching the key type
 	RRMap() *bpf.Map
 
+	// Returns the BPF Maglev lookup table map matching the key type
+	MaglevMap() *bpf.Map
+
+	// Returns the BPF map of active backend lists matching the key type
+	ActiveMap() *bpf.Map
+
 	// Returns a RevNatValue matching a ServiceKey
 	RevNatValue() RevNatValue
 
@@ -110,6 +130,19 @@ type ServiceValue interface {
 	// Get Weight
 	GetWeight() uint16
 
+	// Set session affinity timeout in seconds (master only, 0 disables
+	// session affinity)
+	SetAffinityTimeout(uint32)
+
+	// Get session affinity timeout in seconds
+	GetAffinityTimeout() uint32
+
+	// Set whether the backend is terminating (backends only)
+	SetTerminating(bool)
+
+	// Returns true if the backend is terminating
+	IsTerminating() bool
+
 	// ToNetwork converts fields to network byte order.
 	ToNetwork() ServiceValue
 
@@ -131,6 +164,31 @@ func (s *RRSeqValue) String() string {
 	return fmt.Sprintf("count=%d idx=%v", s.Count, s.Idx)
 }
 
+// MaglevValue must match 'struct lb_maglev_entry' in "bpf/lib/common.h".
+type MaglevValue struct {
+	// Slave is the backend index the table entry maps to
+	Slave uint16
+}
+
+func (m *MaglevValue) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(m) }
+
+func (m *MaglevValue) String() string {
+	return fmt.Sprintf("slave=%d", m.Slave)
+}
+
+// ActiveValue must match 'struct lb_active_entry' in "bpf/lib/common.h".
+type ActiveValue struct {
+	// Slave is the backend index the list entry maps to, or the number of
+	// entries in the list for the entry with index 0
+	Slave uint16
+}
+
+func (a *ActiveValue) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(a) }
+
+func (a *ActiveValue) String() string {
+	return fmt.Sprintf("slave=%d", a.Slave)
+}
+
 func updateService(key ServiceKey, value ServiceValue) error {
 	log.WithFields(logrus.Fields{
 		"frontend": key,
@@ -155,11 +213,29 @@ func DeleteService(key ServiceKey) error {
 }
 
 func deleteServiceLocked(key ServiceKey) error {
+	var revNATID uint16
+	if key.GetBackend() == 0 {
+		if svc, err := lookupService(key); err == nil {
+			revNATID = svc.RevNatKey().GetKey()
+		}
+	}
+
 	err := key.Map().Delete(key.ToNetwork())
 	if err != nil {
 		return err
 	}
+	if revNATID != 0 {
+		if err := DeleteAffinity(revNATID, key.IsIPv6()); err != nil {
+			return err
+		}
+	}
 	err = lookupAndDeleteServiceWeights(key)
+	if err == nil && option.Config.EnableLBMaglev && key.GetBackend() == 0 {
+		err = deleteMaglevTable(key)
+	}
+	if err == nil && key.GetBackend() == 0 {
+		err = deleteActiveList(key)
+	}
 	if err == nil {
 		cache.delete(key)
 	}
@@ -203,6 +279,140 @@ func lookupAndDeleteServiceWeights(key ServiceKey) error {
 	return key.RRMap().Delete(key.ToNetwork())
 }
 
+// updateMaglevTable computes the Maglev lookup table of the service with the
+// given backends, in the order of their slave slots, and writes it into
+// cilium_lb6_maglev or cilium_lb4_maglev.
+func updateMaglevTable(fe ServiceKey, backends []ServiceValue) error {
+	// Slots filling in for removed backends hold duplicates, always map
+	// to the first slot of each backend.
+	slaves := map[string]int{}
+	names := []string{}
+	for i, be := range backends {
+		if be.IsTerminating() {
+			// Terminating backends are not selected for new
+			// connections
+			continue
+		}
+		name := be.String()
+		if _, ok := slaves[name]; !ok {
+			slaves[name] = i + 1 // service count starts with 1
+			names = append(names, name)
+		}
+	}
+
+	table := loadbalancer.GetMaglevTable(names, loadbalancer.MaglevTableSize)
+	if table == nil {
+		return deleteMaglevTable(fe)
+	}
+
+	if _, err := fe.MaglevMap().OpenOrCreate(); err != nil {
+		return err
+	}
+
+	defer fe.SetBackend(0)
+	for i, name := range table {
+		fe.SetBackend(i)
+		value := &MaglevValue{Slave: uint16(slaves[name])}
+		if err := fe.MaglevMap().Update(fe.ToNetwork(), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteMaglevTable deletes the Maglev lookup table of the given service from
+// cilium_lb6_maglev or cilium_lb4_maglev.
+func deleteMaglevTable(fe ServiceKey) error {
+	defer fe.SetBackend(0)
+	for i := 0; i < loadbalancer.MaglevTableSize; i++ {
+		fe.SetBackend(i)
+		if _, err := fe.MaglevMap().Lookup(fe.ToNetwork()); err != nil {
+			// Ignore if entry is not found.
+			continue
+		}
+		if err := fe.MaglevMap().Delete(fe.ToNetwork()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateActiveList writes the slaves of the backends which are not
+// terminating into cilium_lb6_active or cilium_lb4_active while the service
+// has terminating backends. The datapath selects one of them for a new
+// connection if the backend selected by hashing is terminating.
+func updateActiveList(fe ServiceKey, backends []ServiceValue) error {
+	active := []uint16{}
+	for i, be := range backends {
+		if !be.IsTerminating() {
+			active = append(active, uint16(i+1)) // service count starts with 1
+		}
+	}
+	if len(active) == 0 || len(active) == len(backends) {
+		return deleteActiveList(fe)
+	}
+
+	if _, err := fe.ActiveMap().OpenOrCreate(); err != nil {
+		return err
+	}
+
+	existing := lookupActiveListLen(fe)
+
+	// Write the entries before the length so that the datapath never
+	// selects an index without an entry.
+	defer fe.SetBackend(0)
+	for i, slave := range active {
+		fe.SetBackend(i + 1)
+		if err := fe.ActiveMap().Update(fe.ToNetwork(), &ActiveValue{Slave: slave}); err != nil {
+			return err
+		}
+	}
+	fe.SetBackend(0)
+	if err := fe.ActiveMap().Update(fe.ToNetwork(), &ActiveValue{Slave: uint16(len(active))}); err != nil {
+		return err
+	}
+	for i := len(active) + 1; i <= existing; i++ {
+		fe.SetBackend(i)
+		if err := fe.ActiveMap().Delete(fe.ToNetwork()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteActiveList deletes the list of active backends of the given service
+// from cilium_lb6_active or cilium_lb4_active.
+func deleteActiveList(fe ServiceKey) error {
+	existing := lookupActiveListLen(fe)
+	if existing == 0 {
+		return nil
+	}
+
+	defer fe.SetBackend(0)
+	for i := existing; i >= 0; i-- {
+		fe.SetBackend(i)
+		if err := fe.ActiveMap().Delete(fe.ToNetwork()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupActiveListLen returns the number of entries in the list of active
+// backends of the given service, or 0 if it has none.
+func lookupActiveListLen(fe ServiceKey) int {
+	backend := fe.GetBackend()
+	defer fe.SetBackend(backend)
+
+	fe.SetBackend(0)
+	val, err := fe.ActiveMap().Lookup(fe.ToNetwork())
+	if err != nil {
+		// Ignore if entry is not found.
+		return 0
+	}
+	return int(val.(*ActiveValue).Slave)
+}
+
 type RevNatKey interface {
 	bpf.MapKey
 
@@ -338,17 +548,22 @@ func updateWrrSeq(fe ServiceKey, weights []uint16) error {
 	return updateServiceWeights(fe, svcRRSeq)
 }
 
-func updateMasterService(fe ServiceKey, nbackends int, nonZeroWeights uint16) error {
+func updateMasterService(fe ServiceKey, nbackends int, nonZeroWeights uint16, revNATID int, affinityTimeoutSec uint32) error {
 	fe.SetBackend(0)
 	zeroValue := fe.NewValue().(ServiceValue)
 	zeroValue.SetCount(nbackends)
 	zeroValue.SetWeight(nonZeroWeights)
+	// The datapath identifies the service of session affinity entries by
+	// the reverse NAT ID of the master.
+	zeroValue.SetRevNat(revNATID)
+	zeroValue.SetAffinityTimeout(affinityTimeoutSec)
 
 	return updateService(fe, zeroValue)
 }
 
-// UpdateService adds or updates the given service in the bpf maps
-func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNATID int) error {
+// UpdateService adds or updates the given service in the bpf maps. A non-zero
+// affinityTimeoutSec enables ClientIP session affinity for the service.
+func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNATID int, affinityTimeoutSec uint32) error {
 	var (
 		weights         []uint16
 		nNonZeroWeights uint16
@@ -406,7 +621,7 @@ func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNA
 		}()
 	}
 
-	err = updateMasterService(fe, len(besValues), nNonZeroWeights)
+	err = updateMasterService(fe, len(besValues), nNonZeroWeights, revNATID, affinityTimeoutSec)
 	if err != nil {
 		return fmt.Errorf("unable to update service %+v: %s", fe, err)
 	}
@@ -416,6 +631,18 @@ func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNA
 		return fmt.Errorf("unable to update service weights for %s with value %+v: %s", fe.String(), weights, err)
 	}
 
+	if option.Config.EnableLBMaglev {
+		err = updateMaglevTable(fe, besValues)
+		if err != nil {
+			return fmt.Errorf("unable to update Maglev lookup table for %s: %s", fe.String(), err)
+		}
+	}
+
+	err = updateActiveList(fe, besValues)
+	if err != nil {
+		return fmt.Errorf("unable to update active backends for %s: %s", fe.String(), err)
+	}
+
 	// Remove old backends that are no longer needed
 	for i := len(besValues) + 1; i <= existingCount; i++ {
 		fe.SetBackend(i)
@@ -456,6 +683,7 @@ func LBSVC2ServiceKeynValue(svc loadbalancer.LBSVC) (ServiceKey, []ServiceValue,
 		beValue.SetPort(be.Port)
 		beValue.SetRevNat(int(svc.FE.ID))
 		beValue.SetWeight(be.Weight)
+		beValue.SetTerminating(be.IsTerminating())
 
 		besValues = append(besValues, beValue)
 		log.WithFields(logrus.Fields{
@@ -537,6 +765,12 @@ func serviceKeynValue2FEnBE(svcKey ServiceKey, svcValue ServiceValue) (*loadbala
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create a new backend for %s:%d: %s", beIP, bePort, err)
 	}
+	if svcKey.GetBackend() != 0 && svcValue.IsTerminating() {
+		// The time the backend started terminating is not stored in
+		// the BPF maps, its drain timeout restarts.
+		beLBBackEnd.State = loadbalancer.BackendStateTerminating
+		beLBBackEnd.TerminatingSince = time.Now()
+	}
 
 	feL3n4AddrID := &loadbalancer.L3n4AddrID{
 		L3n4Addr: *feL3n4Addr,
@@ -607,14 +841,24 @@ func DumpServiceMapsToUserspace(includeMasterBackend, skipIPv4 bool) (loadbalanc
 	newSVCMap := loadbalancer.SVCMap{}
 	newSVCList := []*loadbalancer.LBSVC{}
 	errors := []error{}
+	// affinityTimeouts maps the frontend SHA of the services with session
+	// affinity to their timeout as stored in their master entry.
+	affinityTimeouts := map[string]uint32{}
 
 	parseSVCEntries := func(key bpf.MapKey, value bpf.MapValue) {
 		svcKey := key.(ServiceKey)
-		//It's the frontend service so we don't add this one
-		if svcKey.GetBackend() == 0 && !includeMasterBackend {
-			return
-		}
 		svcValue := value.(ServiceValue)
+		if svcKey.GetBackend() == 0 {
+			if timeout := svcValue.GetAffinityTimeout(); timeout != 0 {
+				if fe, err := serviceKey2L3n4Addr(svcKey); err == nil {
+					affinityTimeouts[fe.SHA256Sum()] = timeout
+				}
+			}
+			//It's the frontend service so we don't add this one
+			if !includeMasterBackend {
+				return
+			}
+		}
 
 		scopedLog := log.WithFields(logrus.Fields{
 			logfields.BPFMapKey:   svcKey,
@@ -648,6 +892,18 @@ func DumpServiceMapsToUserspace(includeMasterBackend, skipIPv4 bool) (loadbalanc
 		errors = append(errors, err)
 	}
 
+	for sha, timeout := range affinityTimeouts {
+		if svc, ok := newSVCMap[sha]; ok {
+			svc.SessionAffinity = loadbalancer.SessionAffinity{Enabled: true, Timeout: timeout}
+			newSVCMap[sha] = svc
+		}
+	}
+	for _, svc := range newSVCList {
+		if timeout, ok := affinityTimeouts[svc.Sha256]; ok {
+			svc.SessionAffinity = loadbalancer.SessionAffinity{Enabled: true, Timeout: timeout}
+		}
+	}
+
 	return newSVCMap, newSVCList, errors
 }
 
@@ -56,6 +56,9 @@ type PolicyMap struct {
 }
 
 func (pe *PolicyEntry) String() string {
+	if pe.IsDeny() {
+		return fmt.Sprintf("deny %d %d", pe.Packets, pe.Bytes)
+	}
 	return fmt.Sprintf("%d %d %d", pe.ProxyPort, pe.Packets, pe.Bytes)
 }
 
@@ -72,13 +75,29 @@ type PolicyKey struct {
 // match the layout of policy_entry in bpf/lib/common.h.
 type PolicyEntry struct {
 	ProxyPort uint16 // In network byte-order
-	Pad0      uint16
+	Flags     policyEntryFlags
+	Pad0      uint8
 	Pad1      uint16
 	Pad2      uint16
 	Packets   uint64
 	Bytes     uint64
 }
 
+// policyEntryFlags are the flags of a PolicyEntry. They must match the
+// POLICY_FLAG_* definitions in bpf/lib/common.h.
+type policyEntryFlags uint8
+
+const (
+	// policyFlagDeny marks an entry which denies the traffic matching its
+	// key, taking precedence over any entry allowing the same traffic.
+	policyFlagDeny policyEntryFlags = 1 << iota
+)
+
+// IsDeny returns true if the entry denies the traffic matching its key.
+func (pe *PolicyEntry) IsDeny() bool {
+	return pe.Flags&policyFlagDeny != 0
+}
+
 func (pe *PolicyEntry) Add(oPe PolicyEntry) {
 	pe.Packets += oPe.Packets
 	pe.Bytes += oPe.Bytes
@@ -174,6 +193,23 @@ func (pm *PolicyMap) Allow(id uint32, dport uint16, proto u8proto.U8proto, traff
 	return bpf.UpdateElement(pm.Fd, unsafe.Pointer(&key), unsafe.Pointer(&entry), 0)
 }
 
+// DenyKey pushes an entry into the PolicyMap which denies the traffic
+// matching the given PolicyKey k. Returns an error if the update of the
+// PolicyMap fails.
+func (pm *PolicyMap) DenyKey(k PolicyKey) error {
+	return pm.Deny(k.Identity, k.DestPort, u8proto.U8proto(k.Nexthdr), TrafficDirection(k.TrafficDirection))
+}
+
+// Deny pushes an entry into the PolicyMap to deny traffic in the given
+// `trafficDirection` for identity `id` with destination port `dport` over
+// protocol `proto`. A `dport` of 0 denies all ports. It is assumed that
+// `dport` is in host byte-order.
+func (pm *PolicyMap) Deny(id uint32, dport uint16, proto u8proto.U8proto, trafficDirection TrafficDirection) error {
+	key := PolicyKey{Identity: id, DestPort: byteorder.HostToNetwork(dport).(uint16), Nexthdr: uint8(proto), TrafficDirection: trafficDirection.Uint8()}
+	entry := PolicyEntry{Flags: policyFlagDeny}
+	return bpf.UpdateElement(pm.Fd, unsafe.Pointer(&key), unsafe.Pointer(&entry), 0)
+}
+
 // Exists determines whether PolicyMap currently contains an entry that
 // allows traffic in `trafficDirection` for identity `id` with destination port
 // `dport`over protocol `proto`. It is assumed that `dport` is in host byte-order.
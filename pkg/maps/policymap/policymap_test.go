@@ -16,6 +16,7 @@ package policymap
 
 import (
 	"testing"
+	"unsafe"
 
 	. "gopkg.in/check.v1"
 )
@@ -141,3 +142,16 @@ func (pm *PolicyMapTestSuite) TestPolicyEntriesDump_Less(c *C) {
 		c.Assert(got, Equals, tt.want, Commentf("Test Name: %s", tt.name))
 	}
 }
+
+func (pm *PolicyMapTestSuite) TestPolicyEntryDeny(c *C) {
+	// The deny flag must not change the size of the entry shared with
+	// the datapath.
+	c.Assert(unsafe.Sizeof(PolicyEntry{}), Equals, uintptr(24))
+
+	entry := PolicyEntry{ProxyPort: 8080}
+	c.Assert(entry.IsDeny(), Equals, false)
+
+	entry = PolicyEntry{Flags: policyFlagDeny}
+	c.Assert(entry.IsDeny(), Equals, true)
+	c.Assert(entry.String(), Equals, "deny 0 0")
+}
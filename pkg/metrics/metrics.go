@@ -59,6 +59,15 @@ var (
 	// the datapath. It is prepended to metric names and separated with a '_'.
 	Datapath = "datapath"
 
+	// KVStore is the subsystem to scope metrics related to the kvstore
+	// backends. It is prepended to metric names and separated with a '_'.
+	KVStore = "kvstore"
+
+	// Allocator is the subsystem to scope metrics related to the kvstore
+	// based ID allocator. It is prepended to metric names and separated
+	// with a '_'.
+	Allocator = "allocator"
+
 	// Labels
 
 	// LabelValueOutcomeSuccess is used as a successful outcome of an operation
@@ -117,6 +126,38 @@ var (
 	// started by cilium (Envoy, monitor, etc..)
 	LabelSubsystem = "subsystem"
 
+	// LabelDirection is the direction of traffic, ingress or egress
+	LabelDirection = "direction"
+
+	// LabelVerdict is the verdict on a flow, e.g. forwarded or denied
+	LabelVerdict = "verdict"
+
+	// LabelKafkaAPIKey is the API key of a Kafka request
+	LabelKafkaAPIKey = "kafka_api_key"
+
+	// LabelSourceIdentity is the security identity of the source of a flow
+	LabelSourceIdentity = "source_identity"
+
+	// LabelDestinationIdentity is the security identity of the destination
+	// of a flow
+	LabelDestinationIdentity = "destination_identity"
+
+	// LabelOperation is the kvstore operation performed, e.g. Get or LockPath
+	LabelOperation = "operation"
+
+	// LabelBackend is the name of the kvstore backend, e.g. etcd or consul
+	LabelBackend = "backend"
+
+	// LabelPrefix is the kvstore prefix an allocator manages IDs in
+	LabelPrefix = "prefix"
+
+	// ProxyL7Labels are the labels of the L7 proxy request metrics. Labels
+	// which are not needed are given an empty value to limit the
+	// cardinality of the metrics. The status label holds the HTTP status,
+	// Kafka error code or DNS response code.
+	ProxyL7Labels = []string{LabelProtocolL7, LabelDirection, LabelVerdict, LabelStatus,
+		LabelKafkaAPIKey, LabelSourceIdentity, LabelDestinationIdentity}
+
 	// Endpoint
 
 	// EndpointCount is a function used to collect this metric.
@@ -286,6 +327,30 @@ var (
 		Help:      "Number of total L7 denied requests/responses due to policy",
 	})
 
+	// ProxyRateLimited is a count of all requests rejected by the proxy
+	// for exceeding the rate limit of the policy
+	ProxyRateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "policy_l7_rate_limited_total",
+		Help:      "Number of total L7 requests rejected due to policy rate limits",
+	})
+
+	// ProxyL7Requests is a count of all completed requests handled by the
+	// proxy, labeled by ProxyL7Labels
+	ProxyL7Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "proxy_l7_requests_total",
+		Help:      "Number of total L7 requests handled by the proxy, labeled by protocol, direction, verdict, status and security identities",
+	}, ProxyL7Labels)
+
+	// ProxyL7RequestDuration is the time from forwarding a request until
+	// receiving the response, labeled by ProxyL7Labels
+	ProxyL7RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "proxy_l7_request_duration_seconds",
+		Help:      "Duration in seconds of L7 requests handled by the proxy, labeled by protocol, direction, verdict, status and security identities",
+	}, ProxyL7Labels)
+
 	// ProxyReceived is a count of all received requests by the proxy
 	ProxyReceived = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: Namespace,
@@ -360,6 +425,65 @@ var (
 			"labeled by datapath family and completion status",
 	}, []string{LabelDatapathFamily, LabelProtocol, LabelStatus})
 
+	// KVStore statistics
+
+	// KVStoreOperationsDuration is the duration of kvstore operations in
+	// seconds, labeled by backend and operation
+	KVStoreOperationsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: KVStore,
+		Name:      "operations_duration_seconds",
+		Help:      "Duration in seconds of kvstore operations labeled by backend and operation",
+	}, []string{LabelBackend, LabelOperation})
+
+	// KVStoreOperationErrors is the number of kvstore operations which
+	// failed, labeled by backend and operation
+	KVStoreOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: KVStore,
+		Name:      "operation_errors_total",
+		Help:      "Number of failed kvstore operations labeled by backend and operation",
+	}, []string{LabelBackend, LabelOperation})
+
+	// KVStoreLeaseRenewalFailures is the number of times the kvstore
+	// lease or session could not be renewed
+	KVStoreLeaseRenewalFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: KVStore,
+		Name:      "lease_renewal_failures_total",
+		Help:      "Number of times the kvstore lease could not be renewed labeled by backend",
+	}, []string{LabelBackend})
+
+	// Allocator statistics
+
+	// AllocatorGCRuns is the number of times the allocator garbage
+	// collector was run
+	AllocatorGCRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: Allocator,
+		Name:      "gc_runs_total",
+		Help: "Number of times that the allocator garbage collector was run " +
+			"labeled by prefix and completion status",
+	}, []string{LabelPrefix, LabelStatus})
+
+	// AllocatorGCReclaimedIDs is the number of unused IDs released by the
+	// allocator garbage collector
+	AllocatorGCReclaimedIDs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: Allocator,
+		Name:      "gc_reclaimed_ids_total",
+		Help:      "Number of unused IDs released by the allocator garbage collector labeled by prefix",
+	}, []string{LabelPrefix})
+
+	// AllocatorIDs is the number of IDs currently allocated in the kvstore,
+	// e.g. the number of security identities
+	AllocatorIDs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: Allocator,
+		Name:      "ids",
+		Help:      "Number of IDs allocated in the kvstore labeled by prefix",
+	}, []string{LabelPrefix})
+
 	// Services
 
 	// ServicesCount number of services
@@ -446,7 +570,10 @@ func init() {
 	MustRegister(ProxyParseErrors)
 	MustRegister(ProxyForwarded)
 	MustRegister(ProxyDenied)
+	MustRegister(ProxyRateLimited)
 	MustRegister(ProxyReceived)
+	MustRegister(ProxyL7Requests)
+	MustRegister(ProxyL7RequestDuration)
 
 	MustRegister(DropCount)
 	MustRegister(ForwardCount)
@@ -459,6 +586,14 @@ func init() {
 	MustRegister(ConntrackGCSize)
 	MustRegister(ConntrackGCDuration)
 
+	MustRegister(KVStoreOperationsDuration)
+	MustRegister(KVStoreOperationErrors)
+	MustRegister(KVStoreLeaseRenewalFailures)
+
+	MustRegister(AllocatorGCRuns)
+	MustRegister(AllocatorGCReclaimedIDs)
+	MustRegister(AllocatorIDs)
+
 	MustRegister(ServicesCount)
 
 	MustRegister(ErrorsWarnings)
@@ -17,6 +17,7 @@ package monitor
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -75,6 +76,7 @@ var errors = map[uint8]string{
 	160: "No tunnel/encapsulation endpoint (datapath BUG!)",
 	161: "Failed to insert into proxymap",
 	162: "Policy denied (CIDR)",
+	163: "Policy denied (deny rule)",
 }
 
 // DropReason prints the drop reason in a human readable string
@@ -85,17 +87,33 @@ func DropReason(reason uint8) string {
 	return fmt.Sprintf("%d", reason)
 }
 
+// IsAudit returns true if the notification was generated for a packet which
+// was denied by policy but let through because the endpoint is in policy
+// audit mode.
+func (n *DropNotify) IsAudit() bool {
+	return n.Type == MessageTypePolicyAudit
+}
+
+// typeName returns the name of the notification type as shown to the user
+func (n *DropNotify) typeName() string {
+	if n.IsAudit() {
+		return "audit"
+	}
+	return "drop"
+}
+
 // DumpInfo prints a summary of the drop messages.
 func (n *DropNotify) DumpInfo(data []byte) {
-	fmt.Printf("xx drop (%s) flow %#x to endpoint %d, identity %d->%d: %s\n",
-		DropReason(n.SubType), n.Hash, n.DstID, n.SrcLabel, n.DstLabel,
+	fmt.Printf("xx %s (%s) flow %#x to endpoint %d, identity %d->%d: %s\n",
+		n.typeName(), DropReason(n.SubType), n.Hash, n.DstID, n.SrcLabel, n.DstLabel,
 		GetConnectionSummary(data[DropNotifyLen:]))
 }
 
 // DumpVerbose prints the drop notification in human readable form
 func (n *DropNotify) DumpVerbose(dissect bool, data []byte, prefix string) {
-	fmt.Printf("%s MARK %#x FROM %d DROP: %d bytes, reason %s, to ifindex %s",
-		prefix, n.Hash, n.Source, n.OrigLen, DropReason(n.SubType), ifname(int(n.Ifindex)))
+	fmt.Printf("%s MARK %#x FROM %d %s: %d bytes, reason %s, to ifindex %s",
+		prefix, n.Hash, n.Source, strings.ToUpper(n.typeName()), n.OrigLen,
+		DropReason(n.SubType), ifname(int(n.Ifindex)))
 
 	if n.SrcLabel != 0 || n.DstLabel != 0 {
 		fmt.Printf(", identity %d->%d", n.SrcLabel, n.DstLabel)
@@ -152,7 +170,7 @@ type DropNotifyVerbose struct {
 //DropNotifyToVerbose creates verbose notification from DropNotify
 func DropNotifyToVerbose(n *DropNotify) DropNotifyVerbose {
 	return DropNotifyVerbose{
-		Type:     "drop",
+		Type:     n.typeName(),
 		Mark:     fmt.Sprintf("%#x", n.Hash),
 		Ifindex:  ifname(int(n.Ifindex)),
 		Reason:   DropReason(n.SubType),
@@ -0,0 +1,40 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *MonitorSuite) TestDropNotifyToVerbose(c *C) {
+	n := &DropNotify{
+		Type:     MessageTypeDrop,
+		SubType:  133,
+		SrcLabel: 100,
+		DstLabel: 200,
+	}
+	c.Assert(n.IsAudit(), Equals, false)
+	v := DropNotifyToVerbose(n)
+	c.Assert(v.Type, Equals, "drop")
+	c.Assert(v.Reason, Equals, "Policy denied (L3)")
+
+	n.Type = MessageTypePolicyAudit
+	c.Assert(n.IsAudit(), Equals, true)
+	v = DropNotifyToVerbose(n)
+	c.Assert(v.Type, Equals, "audit")
+	c.Assert(v.Reason, Equals, "Policy denied (L3)")
+	c.Assert(v.SrcLabel, Equals, uint32(100))
+	c.Assert(v.DstLabel, Equals, uint32(200))
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	"github.com/cilium/cilium/pkg/proxy/accesslog"
+
+	"github.com/miekg/dns"
 )
 
 // LogRecordNotify is a proxy access log notification
@@ -46,6 +48,10 @@ func (l *LogRecordNotify) l7Proto() string {
 		return "kafka"
 	}
 
+	if l.DNS != nil {
+		return "dns"
+	}
+
 	if l.L7 != nil {
 		return l.L7.Proto
 	}
@@ -67,11 +73,29 @@ func (l *LogRecordNotify) DumpInfo() {
 			url = http.URL.String()
 		}
 
-		fmt.Printf(" %s %s => %d\n", http.Method, url, http.Code)
+		if http.GRPCService != "" {
+			fmt.Printf(" %s %s grpc %s/%s => %d grpc-status %d\n", http.Method, url,
+				http.GRPCService, http.GRPCMethod, http.Code, http.GRPCStatus)
+		} else {
+			fmt.Printf(" %s %s => %d\n", http.Method, url, http.Code)
+		}
 	}
 
 	if kafka := l.Kafka; kafka != nil {
-		fmt.Printf(" %s topic %s => %d\n", kafka.APIKey, kafka.Topic.Topic, kafka.ErrorCode)
+		if kafka.ConsumerGroup != "" {
+			fmt.Printf(" %s topic %s group %s => %d\n", kafka.APIKey, kafka.Topic.Topic, kafka.ConsumerGroup, kafka.ErrorCode)
+		} else {
+			fmt.Printf(" %s topic %s => %d\n", kafka.APIKey, kafka.Topic.Topic, kafka.ErrorCode)
+		}
+	}
+
+	if dnsRecord := l.DNS; dnsRecord != nil {
+		qtypes := make([]string, 0, len(dnsRecord.QTypes))
+		for _, qtype := range dnsRecord.QTypes {
+			qtypes = append(qtypes, dns.TypeToString[qtype])
+		}
+		fmt.Printf(" query %v %s => %s %v TTL %d\n", qtypes, dnsRecord.Query,
+			dns.RcodeToString[dnsRecord.RCode], dnsRecord.IPs, dnsRecord.TTL)
 	}
 
 	if l7 := l.L7; l7 != nil {
@@ -120,6 +144,7 @@ type LogRecordNotifyVerbose struct {
 	Verdict          accesslog.FlowVerdict      `json:"verdict"`
 	HTTP             *accesslog.LogRecordHTTP   `json:"http,omitempty"`
 	Kafka            *accesslog.LogRecordKafka  `json:"kafka,omitempty"`
+	DNS              *accesslog.LogRecordDNS    `json:"dns,omitempty"`
 	L7               *accesslog.LogRecordL7     `json:"l7,omitempty"`
 }
 
@@ -139,6 +164,7 @@ func LogRecordNotifyToVerbose(n *LogRecordNotify) LogRecordNotifyVerbose {
 		Verdict:          n.Verdict,
 		HTTP:             n.HTTP,
 		Kafka:            n.Kafka,
+		DNS:              n.DNS,
 		L7:               n.L7,
 	}
 }
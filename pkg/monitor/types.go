@@ -31,6 +31,7 @@ const (
 	MessageTypeDebug
 	MessageTypeCapture
 	MessageTypeTrace
+	MessageTypePolicyAudit
 
 	// 129-255 are reserved for agent level events
 
@@ -47,6 +48,7 @@ var (
 		"debug":   MessageTypeDebug,
 		"capture": MessageTypeCapture,
 		"trace":   MessageTypeTrace,
+		"audit":   MessageTypePolicyAudit,
 		"l7":      MessageTypeAccessLog,
 		"agent":   MessageTypeAgent,
 	}
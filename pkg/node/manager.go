@@ -21,6 +21,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/cilium/cilium/pkg/datapath/ipsec"
 	routeUtils "github.com/cilium/cilium/pkg/datapath/route"
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/lock"
@@ -225,6 +226,76 @@ func updateTunnelMapping(n *Node, ip *net.IPNet) {
 	}
 }
 
+// ipsecPeerName returns the name of the IPsec peer of the node with the given
+// identity for the given address family
+func ipsecPeerName(ni Identity, ipv6 bool) string {
+	if ipv6 {
+		return ni.String() + "/ipv6"
+	}
+	return ni.String() + "/ipv4"
+}
+
+func tunnelPort() int {
+	if option.Config.Tunnel == option.TunnelGeneve {
+		return defaults.TunnelPortGeneve
+	}
+	return defaults.TunnelPortVXLAN
+}
+
+// ipsecPeer returns the IPsec peer encrypting the traffic of the given
+// address family exchanged with node n, or nil if there is no such traffic.
+func ipsecPeer(n *Node, ipv6 bool) *ipsec.Peer {
+	localIP, localCIDR, remoteCIDR := GetExternalIPv4(), GetIPv4AllocRange(), n.IPv4AllocCIDR
+	if ipv6 {
+		localIP, localCIDR, remoteCIDR = GetIPv6(), GetIPv6AllocRange(), n.IPv6AllocCIDR
+	}
+	remoteIP := n.GetNodeIP(ipv6)
+	if localIP == nil || remoteIP == nil {
+		return nil
+	}
+
+	if option.Config.Tunnel != option.TunnelDisabled {
+		// The overlay network carries the traffic of both address
+		// families between the IPv4 addresses of the nodes
+		if ipv6 {
+			return nil
+		}
+		return &ipsec.Peer{LocalIP: localIP, RemoteIP: remoteIP, EncapPort: tunnelPort()}
+	}
+
+	if localCIDR == nil || remoteCIDR == nil {
+		return nil
+	}
+	return &ipsec.Peer{
+		LocalIP:    localIP,
+		RemoteIP:   remoteIP,
+		LocalCIDR:  localCIDR,
+		RemoteCIDR: remoteCIDR,
+	}
+}
+
+// updateIPsecPeers encrypts the traffic exchanged with the remote node n
+func updateIPsecPeers(n *Node) {
+	for _, ipv6 := range []bool{false, true} {
+		name := ipsecPeerName(n.Identity(), ipv6)
+		peer := ipsecPeer(n, ipv6)
+		if peer == nil {
+			ipsec.DeletePeer(name)
+			continue
+		}
+		if err := ipsec.UpsertPeer(name, peer); err != nil {
+			n.getLogger().WithError(err).Warn("Unable to set up IPsec encryption for node")
+		}
+	}
+}
+
+// deleteIPsecPeers stops encrypting the traffic exchanged with the node with
+// the given identity
+func deleteIPsecPeers(ni Identity) {
+	ipsec.DeletePeer(ipsecPeerName(ni, false))
+	ipsec.DeletePeer(ipsecPeerName(ni, true))
+}
+
 // UpdateNode updates the new node in the nodes' map with the given identity.
 // When using DirectRoute RouteType the field ownAddr should contain the IPv6
 // address of the interface that can reach the other nodes.
@@ -280,6 +351,10 @@ func UpdateNode(n *Node, routesTypes RouteType, ownAddr net.IP) {
 		updateIPRoute(oldNode, n, ownAddr)
 	}
 
+	if option.Config.EnableIPSec && !n.IsLocal() {
+		updateIPsecPeers(n)
+	}
+
 	clusterConf.nodes[ni] = n
 	clusterConf.replaceHostRoutes()
 }
@@ -304,6 +379,9 @@ func DeleteNode(ni Identity, routesTypes RouteType) {
 		if (routesTypes & DirectRoute) != 0 {
 			deleteIPRoute(n)
 		}
+		if option.Config.EnableIPSec {
+			deleteIPsecPeers(ni)
+		}
 		delete(clusterConf.nodes, ni)
 		clusterConf.replaceHostRoutes()
 	}
@@ -447,6 +447,29 @@ func IsHostIPv6(ip net.IP) bool {
 	return ip.Equal(GetIPv6()) || ip.Equal(GetIPv6Router())
 }
 
+// GetLocalAddresses returns the IPv4 and IPv6 addresses of the local node,
+// without duplicates. The addresses of the cilium_host interface are not
+// included as they are not reachable from outside of the node.
+func GetLocalAddresses() []net.IP {
+	addrs := []net.IP{}
+	for _, ip := range []net.IP{GetExternalIPv4(), GetIPv6()} {
+		if ip == nil || ip.IsUnspecified() {
+			continue
+		}
+		duplicate := false
+		for _, addr := range addrs {
+			if addr.Equal(ip) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs
+}
+
 // GetNodeAddressing returns the NodeAddressing model for the local IPs.
 func GetNodeAddressing(enableIPv4 bool) *models.NodeAddressing {
 	return &models.NodeAddressing{
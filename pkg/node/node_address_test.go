@@ -130,3 +130,32 @@ func (s *NodeSuite) Test_getCiliumHostIPsFromFile(c *C) {
 		}
 	}
 }
+
+func (s *NodeSuite) TestGetLocalAddresses(c *C) {
+	oldExternal, oldInternal := GetExternalIPv4(), GetInternalIPv4()
+	oldIPv6, oldRouter := GetIPv6(), GetIPv6Router()
+	defer func() {
+		SetExternalIPv4(oldExternal)
+		SetInternalIPv4(oldInternal)
+		SetIPv6(oldIPv6)
+		SetIPv6Router(oldRouter)
+	}()
+
+	SetExternalIPv4(net.ParseIP("192.168.0.1"))
+	SetInternalIPv4(net.ParseIP("10.0.0.1"))
+	SetIPv6(net.ParseIP("f00d::1"))
+	SetIPv6Router(net.ParseIP("f00d::2"))
+
+	// The cilium_host addresses are not included
+	c.Assert(GetLocalAddresses(), DeepEquals, []net.IP{
+		net.ParseIP("192.168.0.1"),
+		net.ParseIP("f00d::1"),
+	})
+
+	SetExternalIPv4(net.ParseIP("f00d::1"))
+	c.Assert(GetLocalAddresses(), DeepEquals, []net.IP{net.ParseIP("f00d::1")})
+
+	SetExternalIPv4(net.ParseIP("192.168.0.1"))
+	SetIPv6(nil)
+	c.Assert(GetLocalAddresses(), DeepEquals, []net.IP{net.ParseIP("192.168.0.1")})
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/common"
@@ -61,6 +62,9 @@ const (
 	// AutoIPv6NodeRoutesName is the name of the AutoIPv6NodeRoutes option
 	AutoIPv6NodeRoutesName = "auto-ipv6-node-routes"
 
+	// ProxyMetricsLabelsName is the name of the ProxyMetricsLabels option
+	ProxyMetricsLabelsName = "proxy-metrics-labels"
+
 	// MTUName is the name of the MTU option
 	MTUName = "mtu"
 
@@ -128,6 +132,29 @@ const (
 	// LogSystemLoadConfigName is the name of the option to enable system
 	// load loggging
 	LogSystemLoadConfigName = "log-system-load"
+
+	// EnableLBMaglevName is the name of the option to enable Maglev
+	// consistent hashing for the selection of service backends
+	EnableLBMaglevName = "enable-lb-maglev"
+
+	// LBBackendDrainTimeoutName is the name of the option to set the
+	// maximum time a terminating service backend is drained for
+	LBBackendDrainTimeoutName = "lb-backend-drain-timeout"
+
+	// EnableIPSecName is the name of the option to enable IPsec
+	// encryption of the traffic between nodes
+	EnableIPSecName = "enable-ipsec"
+
+	// IPSecKeyFileName is the name of the option for the file holding
+	// the IPsec keys
+	IPSecKeyFileName = "ipsec-key-file"
+
+	// IPAMName is the name of the option to select the IPAM mode
+	IPAMName = "ipam"
+
+	// IPAMPoolName is the name of the option to configure a named IPAM
+	// pool
+	IPAMPoolName = "ipam-pool"
 )
 
 // Available option for daemonConfig.Tunnel
@@ -147,6 +174,22 @@ func GetTunnelModes() string {
 	return fmt.Sprintf("%s, %s, %s", TunnelVXLAN, TunnelGeneve, TunnelDisabled)
 }
 
+// Available option for daemonConfig.IPAM
+const (
+	// IPAMHostScope allocates addresses from the allocation prefix of
+	// the node
+	IPAMHostScope = "hostscope"
+
+	// IPAMCRD allocates addresses from the pools declared in the
+	// CiliumNode resource of the node
+	IPAMCRD = "crd"
+)
+
+// GetIPAMModes returns the list of all IPAM modes
+func GetIPAMModes() string {
+	return fmt.Sprintf("%s, %s", IPAMHostScope, IPAMCRD)
+}
+
 // daemonConfig is the configuration used by Daemon.
 type daemonConfig struct {
 	BpfDir          string     // BPF template files directory
@@ -164,6 +207,16 @@ type daemonConfig struct {
 
 	Tunnel string // Tunnel mode
 
+	IPAM string // IPAM mode
+
+	// IPAMPools maps the name of each named IPAM pool to its
+	// configuration, see ipam.ParsePoolConfig
+	IPAMPools map[string]string
+
+	// Masquerade enables masquerading of traffic from endpoints leaving
+	// the node
+	Masquerade bool
+
 	DryMode bool // Do not create BPF maps, devices, ..
 
 	// RestoreState enables restoring the state from previous running daemons.
@@ -184,6 +237,23 @@ type daemonConfig struct {
 	// host-sourced traffic, to provide compatibility with Cilium 1.0.
 	HostAllowsWorld bool
 
+	// EnableLBMaglev enables Maglev consistent hashing to select the
+	// backend of a service, so that changes to the backends of a service
+	// only remap a minimal share of flows.
+	EnableLBMaglev bool
+
+	// LBBackendDrainTimeout is the maximum time a backend removed from a
+	// service keeps serving the connections established to it.
+	LBBackendDrainTimeout time.Duration
+
+	// EnableIPSec enables the transparent IPsec encryption of the
+	// traffic between nodes.
+	EnableIPSec bool
+
+	// IPSecKeyFile is the file holding the IPsec keys, which is
+	// re-read periodically to pick up rotated keys.
+	IPSecKeyFile string
+
 	// StateDir is the directory where runtime state of endpoints is stored
 	StateDir string
 
@@ -202,6 +272,10 @@ type daemonConfig struct {
 	// AgentLabels contains additional labels to identify this agent in monitor events.
 	AgentLabels []string
 
+	// ProxyMetricsLabels is the list of labels kept in the L7 proxy
+	// request metrics
+	ProxyMetricsLabels []string
+
 	// IPv6ClusterAllocCIDR is the base CIDR used to allocate IPv6 node
 	// CIDRs if allocation is not performed by an orchestration system
 	IPv6ClusterAllocCIDR string
@@ -255,6 +329,7 @@ var (
 		IPv6ClusterAllocCIDR:     defaults.IPv6ClusterAllocCIDR,
 		IPv6ClusterAllocCIDRBase: defaults.IPv6ClusterAllocCIDRBase,
 		EnableHostIPRestore:      defaults.EnableHostIPRestore,
+		IPAMPools:                map[string]string{},
 	}
 )
 
@@ -333,6 +408,13 @@ func (c *daemonConfig) Validate() error {
 		return fmt.Errorf("invalid tunnel mode '%s', valid modes = {%s}", c.Tunnel, GetTunnelModes())
 	}
 
+	c.IPAM = viper.GetString(IPAMName)
+	switch c.IPAM {
+	case IPAMHostScope, IPAMCRD:
+	default:
+		return fmt.Errorf("invalid IPAM mode '%s', valid modes = {%s}", c.IPAM, GetIPAMModes())
+	}
+
 	c.ClusterName = viper.GetString(ClusterName)
 	c.ClusterID = viper.GetInt(ClusterIDName)
 	c.ClusterMeshConfig = viper.GetString(ClusterMeshConfigName)
@@ -35,6 +35,7 @@ var (
 		TraceNotify:         &specTraceNotify,
 		MonitorAggregation:  &specMonitorAggregation,
 		NAT46:               &specNAT46,
+		PolicyAuditMode:     &specPolicyAuditMode,
 	}
 )
 
@@ -29,6 +29,7 @@ const (
 	TraceNotify         = "TraceNotification"
 	MonitorAggregation  = "MonitorAggregationLevel"
 	NAT46               = "NAT46"
+	PolicyAuditMode     = "PolicyAuditMode"
 	AlwaysEnforce       = "always"
 	NeverEnforce        = "never"
 	DefaultEnforcement  = "default"
@@ -97,6 +98,11 @@ var (
 		},
 	}
 
+	specPolicyAuditMode = Option{
+		Define:      "POLICY_AUDIT_MODE",
+		Description: "Enable policy audit (non-drop) mode",
+	}
+
 	IngressSpecPolicy = Option{
 		Define:      "POLICY_INGRESS",
 		Description: "Enable ingress policy enforcement",
@@ -108,7 +108,8 @@ type EgressRule struct {
 	ToServices []Service `json:"toServices,omitempty"`
 
 	// ToFQDN allows whitelisting DNS names in place of IPs. The IPs that result
-	// from DNS resolution of `ToFQDN.MatchName`s are added to the same
+	// from DNS resolution of `ToFQDN.MatchName`s, as well as the IPs known for
+	// any name matching a `ToFQDN.MatchPattern`, are added to the same
 	// EgressRule object as ToCIDRSet entries, and behave accordingly. Any L4 and
 	// L7 rules within this EgressRule will also apply to these IPs.
 	// The DNS -> IP mapping is re-resolved periodically from within the
@@ -148,3 +149,63 @@ func (e *EgressRule) GetDestinationEndpointSelectors() EndpointSelectorSlice {
 func (e *EgressRule) IsLabelBased() bool {
 	return len(e.ToRequires)+len(e.ToCIDR)+len(e.ToCIDRSet)+len(e.ToServices) == 0
 }
+
+// EgressDenyRule contains all rule types which can be applied at egress to
+// deny network traffic that originates inside the endpoint and exits the
+// endpoint selected by the endpointSelector. Deny rules take precedence over
+// any EgressRule allowing the same traffic.
+//
+// - All members of this structure are optional. If omitted or empty, the
+//   member will have no effect on the rule.
+//
+// - For now, combining multiple To* L3 members in the same rule is not
+//   supported and such rules will be rejected.
+type EgressDenyRule struct {
+	// ToEndpoints is a list of endpoints identified by an EndpointSelector to
+	// which the endpoints subject to the rule are not allowed to communicate.
+	//
+	// +optional
+	ToEndpoints []EndpointSelector `json:"toEndpoints,omitempty"`
+
+	// ToPorts is a list of destination ports identified by port number and
+	// protocol which the endpoint subject to the rule is not allowed to
+	// connect to. If any To* L3 member is also set, only connections to the
+	// selected peers are denied.
+	//
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+
+	// ToCIDR is a list of IP blocks which the endpoint subject to the rule
+	// is not allowed to initiate connections to.
+	//
+	// Example:
+	// Any endpoint with the label "app=frontend" is not allowed to
+	// initiate connections to the cloud metadata service at
+	// 169.254.169.254/32.
+	//
+	// +optional
+	ToCIDR CIDRSlice `json:"toCIDR,omitempty"`
+
+	// ToCIDRSet is a list of IP blocks which the endpoint subject to the rule
+	// is not allowed to initiate connections to, along with a list of
+	// subnets contained within their corresponding IP block which are not
+	// subject to the deny.
+	//
+	// +optional
+	ToCIDRSet CIDRRuleSlice `json:"toCIDRSet,omitempty"`
+
+	// ToEntities is a list of special entities to which the endpoint subject
+	// to the rule is not allowed to initiate connections.
+	//
+	// +optional
+	ToEntities EntitySlice `json:"toEntities,omitempty"`
+}
+
+// GetDestinationEndpointSelectors returns a slice of endpoints selectors
+// covering all L3 destination selectors of the egress deny rule
+func (e *EgressDenyRule) GetDestinationEndpointSelectors() EndpointSelectorSlice {
+	res := append(EndpointSelectorSlice{}, e.ToEndpoints...)
+	res = append(res, e.ToEntities.GetAsEndpointSelectors()...)
+	res = append(res, e.ToCIDR.GetAsEndpointSelectors()...)
+	return append(res, e.ToCIDRSet.GetAsEndpointSelectors()...)
+}
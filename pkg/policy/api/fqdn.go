@@ -14,6 +14,27 @@
 
 package api
 
+// FQDNSelector selects DNS names, either literally via MatchName or with
+// wildcards via MatchPattern. Exactly one of the two must be set.
 type FQDNSelector struct {
+	// MatchName matches literal DNS names. A trailing "." is automatically
+	// added when missing.
+	//
+	// +optional
 	MatchName string `json:"matchName,omitempty"`
+
+	// MatchPattern allows using wildcards to match DNS names. Matching is
+	// case insensitive and a trailing "." is automatically added when
+	// missing. The wildcard "*" matches 0 or more valid DNS characters
+	// within a single label, e.g. "*.cilium.io" matches "www.cilium.io" but
+	// neither "cilium.io" nor "a.b.cilium.io". A lone "*" matches all names.
+	// Names matching a pattern are never polled, only IPs already seen for
+	// them are used.
+	//
+	// +optional
+	MatchPattern string `json:"matchPattern,omitempty"`
 }
+
+// PortRuleDNS is a list of allowed DNS lookups. Exactly one of MatchName or
+// MatchPattern must be set.
+type PortRuleDNS FQDNSelector
@@ -14,7 +14,11 @@
 
 package api
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 // PortRuleHTTP is a list of HTTP protocol constraints. All fields are
 // optional, if all fields are empty or missing, the rule does not have any
@@ -57,6 +61,224 @@ type PortRuleHTTP struct {
 	//
 	// +optional
 	Headers []string `json:"headers,omitempty"`
+
+	// GRPC restricts the request to calls of a gRPC service, and
+	// optionally of a single method of the service. This field is
+	// incompatible with the Path and Method fields.
+	//
+	// If omitted, requests are allowed regardless of being gRPC calls.
+	//
+	// +optional
+	GRPC *PortRuleGRPC `json:"grpc,omitempty"`
+
+	// HeaderMatches is a list of HTTP header value constraints which must
+	// all be satisfied by the request.
+	//
+	// If omitted or empty, requests are allowed regardless of header
+	// values.
+	//
+	// +optional
+	HeaderMatches []HeaderMatch `json:"headerMatches,omitempty"`
+
+	// AddHeaders is a list of HTTP headers which are added to requests
+	// allowed by this rule before they are forwarded. The value
+	// SourceIdentityPlaceholder is replaced with the security identity
+	// of the source of the request. Headers added by any rule of the port
+	// are removed from requests which are allowed, so that their values
+	// cannot be forged by the source.
+	//
+	// +optional
+	AddHeaders []HTTPHeader `json:"addHeaders,omitempty"`
+
+	// RemoveHeaders is a list of names of HTTP headers which are removed
+	// from requests allowed by this rule before they are forwarded.
+	// Headers are removed before the headers in AddHeaders are added.
+	//
+	// +optional
+	RemoveHeaders []string `json:"removeHeaders,omitempty"`
+
+	// RateLimit limits the rate of requests allowed by this rule from each
+	// source security identity. Requests exceeding the limit are rejected
+	// with a 429 response, unless they are allowed by another rule.
+	//
+	// If omitted, requests are not rate limited.
+	//
+	// +optional
+	RateLimit *HTTPRateLimit `json:"rateLimit,omitempty"`
+}
+
+// HTTPRateLimit is a token bucket rate limit of HTTP requests. The limit
+// applies to the requests of each source security identity separately.
+type HTTPRateLimit struct {
+	// RequestsPerSecond is the number of requests per second allowed on
+	// average.
+	RequestsPerSecond uint32 `json:"requestsPerSecond"`
+
+	// Burst is the maximum number of requests allowed in a burst above
+	// the average rate.
+	//
+	// If omitted or zero, the burst size is RequestsPerSecond.
+	//
+	// +optional
+	Burst uint32 `json:"burst,omitempty"`
+}
+
+// Sanitize ensures that the rate limit allows requests
+func (r *HTTPRateLimit) Sanitize() error {
+	if r.RequestsPerSecond == 0 {
+		return fmt.Errorf("rate limit must allow at least one request per second")
+	}
+	return nil
+}
+
+// SourceIdentityPlaceholder is replaced with the numeric security identity
+// of the source of the request in the values of added HTTP headers.
+const SourceIdentityPlaceholder = "%SOURCE_IDENTITY%"
+
+// HeaderMatch is a constraint on the value of an HTTP header.
+type HeaderMatch struct {
+	// Name is the name of the HTTP header.
+	Name string `json:"name"`
+
+	// Value is the exact value the header must have. This field is
+	// incompatible with the ValueRegex field.
+	//
+	// If both Value and ValueRegex are omitted, the header must be present
+	// with any value.
+	//
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ValueRegex is an ECMAScript regex matched against the whole value of
+	// the header. Only the syntax shared with RE2 may be used, i.e. no
+	// backreferences, lookaheads, flag groups or Unicode classes.
+	//
+	// +optional
+	ValueRegex string `json:"valueRegex,omitempty"`
+
+	// Invert inverts the match. If no value is given, the header must not
+	// be present in the request. Otherwise the header must be present with
+	// a value not matching Value or ValueRegex.
+	//
+	// +optional
+	Invert bool `json:"invert,omitempty"`
+}
+
+// Sanitize ensures that the header name is set, that at most one of the value
+// fields is set and that the value regex is a valid regular expression.
+func (m *HeaderMatch) Sanitize() error {
+	if m.Name == "" {
+		return fmt.Errorf("header match without a header name")
+	}
+	if m.Value != "" && m.ValueRegex != "" {
+		return fmt.Errorf("header match %q cannot have both value and valueRegex", m.Name)
+	}
+	if m.ValueRegex != "" {
+		if err := checkECMAScriptRegex(m.ValueRegex); err != nil {
+			return fmt.Errorf("header match %q: %s", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkECMAScriptRegex ensures that expr is a valid regular expression which
+// is interpreted the same by the ECMAScript engine of Envoy. RE2 rejects the
+// ECMAScript-only constructs, the RE2-only ones are rejected here.
+func checkECMAScriptRegex(expr string) error {
+	if _, err := regexp.Compile(expr); err != nil {
+		return err
+	}
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			if i+1 < len(expr) && strings.IndexByte("ACEPQpz", expr[i+1]) >= 0 {
+				return fmt.Errorf("escape sequence \\%c is not supported", expr[i+1])
+			}
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			// A leading ']' is a literal
+			if i+1 < len(expr) && expr[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(expr) && expr[i+1] == ']' {
+				i++
+			}
+		case c == '(' && i+1 < len(expr) && expr[i+1] == '?':
+			if i+2 >= len(expr) || expr[i+2] != ':' {
+				return fmt.Errorf("only non-capturing groups (?:...) are supported")
+			}
+		}
+	}
+	return nil
+}
+
+// HTTPHeader is an HTTP header name and value.
+type HTTPHeader struct {
+	// Name is the name of the HTTP header.
+	Name string `json:"name"`
+
+	// Value is the value of the HTTP header.
+	//
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// sanitizeHeaderName ensures that the header name can be modified by a rule.
+// Pseudo-headers such as ":path" cannot be added or removed.
+func sanitizeHeaderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty header name")
+	}
+	if strings.HasPrefix(name, ":") {
+		return fmt.Errorf("pseudo-header %q cannot be modified", name)
+	}
+	return nil
+}
+
+// PortRuleGRPC is a gRPC service and method constraint. gRPC calls are HTTP/2
+// POST requests to the path "/<service>/<method>" with a content type of
+// "application/grpc".
+type PortRuleGRPC struct {
+	// Service is the fully qualified name of the gRPC service, including
+	// the package, e.g. "helloworld.Greeter".
+	Service string `json:"service"`
+
+	// Method is the name of the gRPC method of the service, e.g.
+	// "SayHello".
+	//
+	// If omitted or empty, all methods of the service are allowed.
+	//
+	// +optional
+	Method string `json:"method,omitempty"`
+}
+
+var (
+	grpcServiceRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+	grpcMethodRegexp  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Path returns the HTTP path of calls matching the gRPC rule. If the rule
+// does not restrict the method, the returned path is the prefix of the paths
+// of all methods of the service.
+func (g *PortRuleGRPC) Path() string {
+	return "/" + g.Service + "/" + g.Method
+}
+
+// Sanitize ensures that the service and method are valid protobuf names
+func (g *PortRuleGRPC) Sanitize() error {
+	if !grpcServiceRegexp.MatchString(g.Service) {
+		return fmt.Errorf("invalid gRPC service name %q", g.Service)
+	}
+	if g.Method != "" && !grpcMethodRegexp.MatchString(g.Method) {
+		return fmt.Errorf("invalid gRPC method name %q", g.Method)
+	}
+	return nil
 }
 
 // Sanitize sanitizes HTTP rules. It ensures that the path and method fields
@@ -79,6 +301,39 @@ func (h *PortRuleHTTP) Sanitize() error {
 		}
 	}
 
+	if h.GRPC != nil {
+		if h.Path != "" || h.Method != "" {
+			return fmt.Errorf("gRPC rules cannot be combined with path or method")
+		}
+		if err := h.GRPC.Sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for i := range h.HeaderMatches {
+		if err := h.HeaderMatches[i].Sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for _, header := range h.AddHeaders {
+		if err := sanitizeHeaderName(header.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range h.RemoveHeaders {
+		if err := sanitizeHeaderName(name); err != nil {
+			return err
+		}
+	}
+
+	if h.RateLimit != nil {
+		if err := h.RateLimit.Sanitize(); err != nil {
+			return err
+		}
+	}
+
 	// Headers are not sanitized.
 	return nil
 }
@@ -120,3 +120,59 @@ func (i *IngressRule) GetSourceEndpointSelectors() EndpointSelectorSlice {
 func (i *IngressRule) IsLabelBased() bool {
 	return len(i.FromRequires)+len(i.FromCIDR)+len(i.FromCIDRSet) == 0
 }
+
+// IngressDenyRule contains all rule types which can be applied at ingress to
+// deny network traffic that originates outside of the endpoint and is
+// entering the endpoint selected by the endpointSelector. Deny rules take
+// precedence over any IngressRule allowing the same traffic.
+//
+// - All members of this structure are optional. If omitted or empty, the
+//   member will have no effect on the rule.
+//
+// - For now, combining multiple From* L3 members in the same rule is not
+//   supported and such rules will be rejected.
+type IngressDenyRule struct {
+	// FromEndpoints is a list of endpoints identified by an
+	// EndpointSelector which are not allowed to communicate with the
+	// endpoint subject to the rule.
+	//
+	// +optional
+	FromEndpoints []EndpointSelector `json:"fromEndpoints,omitempty"`
+
+	// ToPorts is a list of destination ports identified by port number and
+	// protocol which the endpoint subject to the rule is not allowed to
+	// receive connections on. If FromEndpoints or FromEntities is also
+	// set, only connections from the selected peers are denied.
+	//
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+
+	// FromCIDR is a list of IP blocks which the endpoint subject to the
+	// rule is not allowed to receive connections from.
+	//
+	// +optional
+	FromCIDR CIDRSlice `json:"fromCIDR,omitempty"`
+
+	// FromCIDRSet is a list of IP blocks which the endpoint subject to the
+	// rule is not allowed to receive connections from, along with a list of
+	// subnets contained within their corresponding IP block which are not
+	// subject to the deny.
+	//
+	// +optional
+	FromCIDRSet CIDRRuleSlice `json:"fromCIDRSet,omitempty"`
+
+	// FromEntities is a list of special entities which the endpoint subject
+	// to the rule is not allowed to receive connections from.
+	//
+	// +optional
+	FromEntities EntitySlice `json:"fromEntities,omitempty"`
+}
+
+// GetSourceEndpointSelectors returns a slice of endpoints selectors covering
+// all L3 source selectors of the ingress deny rule
+func (i *IngressDenyRule) GetSourceEndpointSelectors() EndpointSelectorSlice {
+	res := append(EndpointSelectorSlice{}, i.FromEndpoints...)
+	res = append(res, i.FromEntities.GetAsEndpointSelectors()...)
+	res = append(res, i.FromCIDR.GetAsEndpointSelectors()...)
+	return append(res, i.FromCIDRSet.GetAsEndpointSelectors()...)
+}
@@ -90,9 +90,41 @@ type PortRuleKafka struct {
 	//
 	// If omitted or empty, all topics are allowed.
 	//
+	// This field is incompatible with the TopicPrefix and TopicRegex
+	// fields.
+	//
 	// +optional
 	Topic string `json:"topic,omitempty"`
 
+	// TopicPrefix matches all topics starting with the prefix, e.g.
+	// "tenant-a." matches all topics of a tenant following the
+	// "tenant-a.*" naming convention. The same rules apply as for Topic.
+	//
+	// If omitted or empty, all topics are allowed.
+	//
+	// +optional
+	TopicPrefix string `json:"topicPrefix,omitempty"`
+
+	// TopicRegex is a regular expression matched against the topic names
+	// of the request, in the syntax of the Go regexp package. The same
+	// rules apply as for Topic. The expression is not anchored, use "^"
+	// and "$" to match complete topic names.
+	//
+	// If omitted or empty, all topics are allowed.
+	//
+	// +optional
+	TopicRegex string `json:"topicRegex,omitempty"`
+
+	// ConsumerGroup is the consumer group contained in JoinGroup,
+	// SyncGroup, OffsetCommit and OffsetFetch requests.
+	//
+	// This constraint is ignored for all other requests.
+	//
+	// If omitted or empty, all consumer groups are allowed.
+	//
+	// +optional
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+
 	// --------------------------------------------------------------------
 	// Private fields. These fields are used internally and are not exposed
 	// via the API.
@@ -104,6 +136,10 @@ type PortRuleKafka struct {
 
 	// apiVersionInt is the integer representation of APIVersion
 	apiVersionInt *int16
+
+	// topicRegex is the compiled TopicRegex. It is immutable and thus
+	// shared between copies of the rule.
+	topicRegex *regexp.Regexp
 }
 
 // List of Kafka apiKeys which have a topic in their
@@ -141,6 +177,16 @@ const (
 	APIVersionsKey = 18
 )
 
+// IsConsumerGroupAPIKey returns true if kind is an apiKey message type whose
+// request is matched against the ConsumerGroup of a rule
+func IsConsumerGroupAPIKey(kind int16) bool {
+	switch kind {
+	case JoinGroupKey, SyncgroupKey, OffsetCommitKey, OffsetFetchKey:
+		return true
+	}
+	return false
+}
+
 // List of Kafka Roles
 const (
 	ProduceRole = "produce"
@@ -260,6 +306,24 @@ func (kr *PortRuleKafka) CheckAPIKeyRole(kind int16) bool {
 	return false
 }
 
+// HasTopic returns true if the rule constrains the topics of a request
+func (kr *PortRuleKafka) HasTopic() bool {
+	return kr.Topic != "" || kr.TopicPrefix != "" || kr.TopicRegex != ""
+}
+
+// MatchesTopic returns true if the topic is allowed by the rule
+func (kr *PortRuleKafka) MatchesTopic(topic string) bool {
+	switch {
+	case kr.Topic != "":
+		return kr.Topic == topic
+	case kr.TopicPrefix != "":
+		return strings.HasPrefix(topic, kr.TopicPrefix)
+	case kr.TopicRegex != "":
+		return kr.topicRegex != nil && kr.topicRegex.MatchString(topic)
+	}
+	return true
+}
+
 // GetAPIVersion returns the APIVersion as integer or the bool set to true if
 // any API version is allowed
 func (kr *PortRuleKafka) GetAPIVersion() (int16, bool) {
@@ -29,6 +29,9 @@ import (
 //
 // Either ingress, egress, or both can be provided. If both ingress and egress
 // are omitted, the rule has no effect.
+//
+// The ingressDeny and egressDeny sections deny the matching traffic and take
+// precedence over the allow sections of all rules.
 type Rule struct {
 	// EndpointSelector selects all endpoints which should be subject to
 	// this rule. Cannot be empty.
@@ -46,6 +49,22 @@ type Rule struct {
 	// +optional
 	Egress []EgressRule `json:"egress,omitempty"`
 
+	// IngressDeny is a list of IngressDenyRule which are enforced at
+	// ingress. Any traffic matching one of the rules is denied, even if it
+	// is allowed by an Ingress rule of this or any other policy rule.
+	// If omitted or empty, this rule does not deny any traffic at ingress.
+	//
+	// +optional
+	IngressDeny []IngressDenyRule `json:"ingressDeny,omitempty"`
+
+	// EgressDeny is a list of EgressDenyRule which are enforced at egress.
+	// Any traffic matching one of the rules is denied, even if it is
+	// allowed by an Egress rule of this or any other policy rule.
+	// If omitted or empty, this rule does not deny any traffic at egress.
+	//
+	// +optional
+	EgressDeny []EgressDenyRule `json:"egressDeny,omitempty"`
+
 	// Labels is a list of optional strings which can be used to
 	// re-identify the rule or to store metadata. It is possible to lookup
 	// or delete strings based on labels. Labels are not required to be
@@ -17,9 +17,11 @@ package api
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
 	"github.com/cilium/cilium/pkg/labels"
 )
 
@@ -61,6 +63,70 @@ func (r Rule) Sanitize() error {
 		}
 	}
 
+	for i := range r.IngressDeny {
+		if err := r.IngressDeny[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for i := range r.EgressDeny {
+		if err := r.EgressDeny[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sanitizeL3Members returns an error if more than one of the L3 peer
+// selectors in l3Members is set, as combining them is not supported yet.
+func sanitizeL3Members(l3Members map[string]int) error {
+	for m1 := range l3Members {
+		for m2 := range l3Members {
+			if m2 != m1 && l3Members[m1] > 0 && l3Members[m2] > 0 {
+				return fmt.Errorf("Combining %s and %s is not supported yet", m1, m2)
+			}
+		}
+	}
+	return nil
+}
+
+// sanitizeCIDRs validates the CIDR prefixes of a rule and limits the number
+// of distinct prefix lengths used in the given direction.
+func sanitizeCIDRs(cidrs CIDRSlice, cidrSets CIDRRuleSlice, direction string) error {
+	prefixLengths := map[int]exists{}
+	for n := range cidrs {
+		prefixLength, err := cidrs[n].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+
+	for n := range cidrSets {
+		prefixLength, err := cidrSets[n].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+
+	// FIXME GH-1781 count coalesced CIDRs and restrict the number of
+	// prefix lengths based on the CIDRSet exclusions.
+	if l := len(prefixLengths); l > MaxCIDRPrefixLengths {
+		return fmt.Errorf("too many %s CIDR prefix lengths %d/%d", direction, l, MaxCIDRPrefixLengths)
+	}
+
+	return nil
+}
+
+func sanitizeEntities(entities EntitySlice) error {
+	for _, entity := range entities {
+		_, ok := EntitySelectorMapping[entity]
+		if !ok {
+			return fmt.Errorf("unsupported entity: %s", entity)
+		}
+	}
 	return nil
 }
 
@@ -77,12 +143,8 @@ func (i *IngressRule) sanitize() error {
 		"FromCIDRSet":   false,
 		"FromEntities":  true,
 	}
-	for m1 := range l3Members {
-		for m2 := range l3Members {
-			if m2 != m1 && l3Members[m1] > 0 && l3Members[m2] > 0 {
-				return fmt.Errorf("Combining %s and %s is not supported yet", m1, m2)
-			}
-		}
+	if err := sanitizeL3Members(l3Members); err != nil {
+		return err
 	}
 	for member := range l3Members {
 		if l3Members[member] > 0 && len(i.ToPorts) > 0 && !l3DependentL4Support[member] {
@@ -96,37 +158,11 @@ func (i *IngressRule) sanitize() error {
 		}
 	}
 
-	prefixLengths := map[int]exists{}
-	for n := range i.FromCIDR {
-		prefixLength, err := i.FromCIDR[n].sanitize()
-		if err != nil {
-			return err
-		}
-		prefixLengths[prefixLength] = exists{}
-	}
-
-	for n := range i.FromCIDRSet {
-		prefixLength, err := i.FromCIDRSet[n].sanitize()
-		if err != nil {
-			return err
-		}
-		prefixLengths[prefixLength] = exists{}
-	}
-
-	for _, fromEntity := range i.FromEntities {
-		_, ok := EntitySelectorMapping[fromEntity]
-		if !ok {
-			return fmt.Errorf("unsupported entity: %s", fromEntity)
-		}
-	}
-
-	// FIXME GH-1781 count coalesced CIDRs and restrict the number of
-	// prefix lengths based on the CIDRSet exclusions.
-	if l := len(prefixLengths); l > MaxCIDRPrefixLengths {
-		return fmt.Errorf("too many ingress CIDR prefix lengths %d/%d", l, MaxCIDRPrefixLengths)
+	if err := sanitizeCIDRs(i.FromCIDR, i.FromCIDRSet, "ingress"); err != nil {
+		return err
 	}
 
-	return nil
+	return sanitizeEntities(i.FromEntities)
 }
 
 func (e *EgressRule) sanitize() error {
@@ -146,12 +182,8 @@ func (e *EgressRule) sanitize() error {
 		"ToServices":  true,
 		"ToFQDNs":     true,
 	}
-	for m1 := range l3Members {
-		for m2 := range l3Members {
-			if m2 != m1 && l3Members[m1] > 0 && l3Members[m2] > 0 {
-				return fmt.Errorf("Combining %s and %s is not supported yet", m1, m2)
-			}
-		}
+	if err := sanitizeL3Members(l3Members); err != nil {
+		return err
 	}
 	for member := range l3Members {
 		if l3Members[member] > 0 && len(e.ToPorts) > 0 && !l3DependentL4Support[member] {
@@ -165,36 +197,69 @@ func (e *EgressRule) sanitize() error {
 		}
 	}
 
-	prefixLengths := map[int]exists{}
-	for i := range e.ToCIDR {
-		prefixLength, err := e.ToCIDR[i].sanitize()
-		if err != nil {
+	if err := sanitizeCIDRs(e.ToCIDR, e.ToCIDRSet, "egress"); err != nil {
+		return err
+	}
+
+	if err := sanitizeEntities(e.ToEntities); err != nil {
+		return err
+	}
+
+	for i := range e.ToFQDNs {
+		if err := e.ToFQDNs[i].sanitize(); err != nil {
 			return err
 		}
-		prefixLengths[prefixLength] = exists{}
 	}
-	for i := range e.ToCIDRSet {
-		prefixLength, err := e.ToCIDRSet[i].sanitize()
-		if err != nil {
+
+	return nil
+}
+
+func (i *IngressDenyRule) sanitize() error {
+	l3Members := map[string]int{
+		"FromEndpoints": len(i.FromEndpoints),
+		"FromCIDR":      len(i.FromCIDR),
+		"FromCIDRSet":   len(i.FromCIDRSet),
+		"FromEntities":  len(i.FromEntities),
+	}
+	if err := sanitizeL3Members(l3Members); err != nil {
+		return err
+	}
+
+	for n := range i.ToPorts {
+		if err := i.ToPorts[n].sanitize(); err != nil {
 			return err
 		}
-		prefixLengths[prefixLength] = exists{}
 	}
 
-	for _, toEntity := range e.ToEntities {
-		_, ok := EntitySelectorMapping[toEntity]
-		if !ok {
-			return fmt.Errorf("unsupported entity: %s", toEntity)
+	if err := sanitizeCIDRs(i.FromCIDR, i.FromCIDRSet, "ingress deny"); err != nil {
+		return err
+	}
+
+	return sanitizeEntities(i.FromEntities)
+}
+
+func (e *EgressDenyRule) sanitize() error {
+	l3Members := map[string]int{
+		"ToCIDR":      len(e.ToCIDR),
+		"ToCIDRSet":   len(e.ToCIDRSet),
+		"ToEndpoints": len(e.ToEndpoints),
+		"ToEntities":  len(e.ToEntities),
+	}
+	if err := sanitizeL3Members(l3Members); err != nil {
+		return err
+	}
+
+	for i := range e.ToPorts {
+		if err := e.ToPorts[i].sanitize(); err != nil {
+			return err
 		}
 	}
 
-	// FIXME GH-1781 count coalesced CIDRs and restrict the number of
-	// prefix lengths based on the CIDRSet exclusions.
-	if l := len(prefixLengths); l > MaxCIDRPrefixLengths {
-		return fmt.Errorf("too many egress CIDR prefix lengths %d/%d", l, MaxCIDRPrefixLengths)
+	if err := sanitizeCIDRs(e.ToCIDR, e.ToCIDRSet, "egress deny"); err != nil {
+		return err
 	}
 
-	return nil
+	return sanitizeEntities(e.ToEntities)
 }
 
 // Sanitize sanitizes Kafka rules
@@ -231,16 +296,33 @@ func (kr *PortRuleKafka) Sanitize() error {
 		kr.apiVersionInt = &n16
 	}
 
-	if len(kr.Topic) > 0 {
-		if len(kr.Topic) > KafkaMaxTopicLen {
-			return fmt.Errorf("kafka topic exceeds maximum len of %d",
-				KafkaMaxTopicLen)
+	nTopics := 0
+	for _, topic := range []string{kr.Topic, kr.TopicPrefix} {
+		if len(topic) > 0 {
+			nTopics++
+			if len(topic) > KafkaMaxTopicLen {
+				return fmt.Errorf("kafka topic exceeds maximum len of %d",
+					KafkaMaxTopicLen)
+			}
+			// This check allows suffix and prefix matching
+			// for topic.
+			if KafkaTopicValidChar.MatchString(topic) == false {
+				return fmt.Errorf("invalid Kafka Topic name \"%s\"", topic)
+			}
 		}
-		// This check allows suffix and prefix matching
-		// for topic.
-		if KafkaTopicValidChar.MatchString(kr.Topic) == false {
-			return fmt.Errorf("invalid Kafka Topic name \"%s\"", kr.Topic)
+	}
+
+	if len(kr.TopicRegex) > 0 {
+		nTopics++
+		re, err := regexp.Compile(kr.TopicRegex)
+		if err != nil {
+			return fmt.Errorf("invalid Kafka TopicRegex %q: %s", kr.TopicRegex, err)
 		}
+		kr.topicRegex = re
+	}
+
+	if nTopics > 1 {
+		return fmt.Errorf("only one of Topic, TopicPrefix and TopicRegex may be set")
 	}
 	return nil
 }
@@ -266,6 +348,15 @@ func (pr *L7Rules) sanitize() error {
 		}
 	}
 
+	if pr.DNS != nil {
+		nTypes++
+		for i := range pr.DNS {
+			if err := pr.DNS[i].Sanitize(); err != nil {
+				return err
+			}
+		}
+	}
+
 	if pr.L7 != nil && pr.L7Proto == "" {
 		return fmt.Errorf("'l7' may only be specified when a 'l7proto' is also specified")
 	}
@@ -284,15 +375,26 @@ func (pr *L7Rules) sanitize() error {
 	return nil
 }
 
-func (pr *PortRule) sanitize() error {
-	if len(pr.Ports) > maxPorts {
+// sanitizePorts validates the ports of a port rule.
+func sanitizePorts(ports []PortProtocol) error {
+	if len(ports) > maxPorts {
 		return fmt.Errorf("too many ports, the max is %d", maxPorts)
 	}
-	for i := range pr.Ports {
-		if err := pr.Ports[i].sanitize(); err != nil {
+	for i := range ports {
+		if err := ports[i].sanitize(); err != nil {
 			return err
 		}
-		if !pr.Rules.IsEmpty() && pr.Ports[i].Protocol != ProtoTCP {
+	}
+	return nil
+}
+
+func (pr *PortRule) sanitize() error {
+	if err := sanitizePorts(pr.Ports); err != nil {
+		return err
+	}
+	for i := range pr.Ports {
+		// DNS is also served over UDP, all other L7 protocols are TCP only
+		if !pr.Rules.IsEmpty() && pr.Rules.DNS == nil && pr.Ports[i].Protocol != ProtoTCP {
 			return fmt.Errorf("L7 rules can only apply exclusively to TCP, not %s", pr.Ports[i].Protocol)
 		}
 	}
@@ -306,6 +408,10 @@ func (pr *PortRule) sanitize() error {
 	return nil
 }
 
+func (pr *PortDenyRule) sanitize() error {
+	return sanitizePorts(pr.Ports)
+}
+
 func (pp *PortProtocol) sanitize() error {
 	if pp.Port == "" {
 		return fmt.Errorf("Port must be specified")
@@ -393,3 +499,29 @@ func (c *CIDRRule) sanitize() (prefixLength int, err error) {
 
 	return prefixLength, nil
 }
+
+// Sanitize checks that exactly one of MatchName or MatchPattern is set and
+// that MatchPattern is valid.
+func (r *PortRuleDNS) Sanitize() error {
+	return (*FQDNSelector)(r).sanitize()
+}
+
+// sanitize validates an FQDNSelector. Exactly one of MatchName and
+// MatchPattern must be set, and MatchPattern must be a valid pattern.
+func (s *FQDNSelector) sanitize() error {
+	if s.MatchName != "" && s.MatchPattern != "" {
+		return fmt.Errorf("only one of matchName or matchPattern may be set")
+	}
+
+	if s.MatchName == "" && s.MatchPattern == "" {
+		return fmt.Errorf("one of matchName or matchPattern must be set")
+	}
+
+	if s.MatchPattern != "" {
+		if _, err := matchpattern.Validate(s.MatchPattern); err != nil {
+			return fmt.Errorf("invalid matchPattern %q: %s", s.MatchPattern, err)
+		}
+	}
+
+	return nil
+}
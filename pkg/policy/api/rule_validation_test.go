@@ -197,6 +197,43 @@ func (s *PolicyAPITestSuite) TestHTTPRuleRegexes(c *C) {
 	c.Assert(err, Not(IsNil))
 }
 
+func (s *PolicyAPITestSuite) TestHTTPRuleGRPC(c *C) {
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld.Greeter"}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "Greeter", Method: "SayHello"}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "Greeter"}, Host: "greeter"}).Sanitize(), IsNil)
+
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld/Greeter"}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld..Greeter"}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "Greeter", Method: "Say.Hello"}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "Greeter"}, Path: "/"}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "Greeter"}, Method: "POST"}).Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestHTTPRuleHeaders(c *C) {
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-debug", Invert: true}}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: "v[0-9]+"}}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: `(?:v|version-)[(?\]]+\.\d`}}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{AddHeaders: []HTTPHeader{{Name: "x-id", Value: SourceIdentityPlaceholder}}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{RemoveHeaders: []string{"authorization"}}).Sanitize(), IsNil)
+
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Value: "foo"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: "v[0-9"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: "(?i)v1"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: `v\pN+`}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", ValueRegex: `v1\z`}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{HeaderMatches: []HeaderMatch{{Name: "x-version", Value: "v1", ValueRegex: "v1"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{AddHeaders: []HTTPHeader{{Value: "foo"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{AddHeaders: []HTTPHeader{{Name: ":path", Value: "/"}}}).Sanitize(), Not(IsNil))
+	c.Assert((&PortRuleHTTP{RemoveHeaders: []string{":authority"}}).Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestHTTPRuleRateLimit(c *C) {
+	c.Assert((&PortRuleHTTP{RateLimit: &HTTPRateLimit{RequestsPerSecond: 10}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{RateLimit: &HTTPRateLimit{RequestsPerSecond: 10, Burst: 50}}).Sanitize(), IsNil)
+	c.Assert((&PortRuleHTTP{RateLimit: &HTTPRateLimit{Burst: 50}}).Sanitize(), Not(IsNil))
+}
+
 // Test the validation of CIDR rule prefix definitions
 func (s *PolicyAPITestSuite) TestCIDRsanitize(c *C) {
 	// IPv4
@@ -270,6 +307,79 @@ func (s *PolicyAPITestSuite) TestToServicesSanitize(c *C) {
 
 }
 
+// TestToFQDNsSanitize tests that toFQDNs entries have exactly one of
+// matchName or matchPattern, and that patterns are valid.
+func (s *PolicyAPITestSuite) TestToFQDNsSanitize(c *C) {
+	for _, testCase := range []struct {
+		selector FQDNSelector
+		valid    bool
+	}{
+		{FQDNSelector{MatchName: "cilium.io"}, true},
+		{FQDNSelector{MatchPattern: "*.cilium.io"}, true},
+		{FQDNSelector{MatchPattern: "*"}, true},
+		{FQDNSelector{}, false},
+		{FQDNSelector{MatchName: "cilium.io", MatchPattern: "*.cilium.io"}, false},
+		{FQDNSelector{MatchPattern: "(cilium|github).io"}, false},
+	} {
+		rule := Rule{
+			EndpointSelector: WildcardEndpointSelector,
+			Egress:           []EgressRule{{ToFQDNs: []FQDNSelector{testCase.selector}}},
+		}
+		err := rule.Sanitize()
+		if testCase.valid {
+			c.Assert(err, IsNil, Commentf("Selector %+v should be valid", testCase.selector))
+		} else {
+			c.Assert(err, Not(IsNil), Commentf("Selector %+v should be invalid", testCase.selector))
+		}
+	}
+}
+
+// TestDNSRulesSanitize tests that DNS rules are valid for UDP and ANY, and
+// that each rule has exactly one of matchName or matchPattern.
+func (s *PolicyAPITestSuite) TestDNSRulesSanitize(c *C) {
+	for _, testCase := range []struct {
+		dnsRule PortRuleDNS
+		valid   bool
+	}{
+		{PortRuleDNS{MatchName: "cilium.io"}, true},
+		{PortRuleDNS{MatchPattern: "*.cilium.io"}, true},
+		{PortRuleDNS{}, false},
+		{PortRuleDNS{MatchName: "cilium.io", MatchPattern: "*.cilium.io"}, false},
+		{PortRuleDNS{MatchPattern: "cilium.io$"}, false},
+	} {
+		rule := Rule{
+			EndpointSelector: WildcardEndpointSelector,
+			Egress: []EgressRule{{
+				ToPorts: []PortRule{{
+					Ports: []PortProtocol{{Port: "53", Protocol: ProtoAny}},
+					Rules: &L7Rules{DNS: []PortRuleDNS{testCase.dnsRule}},
+				}},
+			}},
+		}
+		err := rule.Sanitize()
+		if testCase.valid {
+			c.Assert(err, IsNil, Commentf("DNS rule %+v should be valid", testCase.dnsRule))
+		} else {
+			c.Assert(err, Not(IsNil), Commentf("DNS rule %+v should be invalid", testCase.dnsRule))
+		}
+	}
+
+	// DNS rules cannot be mixed with other L7 rule types
+	invalidRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		Egress: []EgressRule{{
+			ToPorts: []PortRule{{
+				Ports: []PortProtocol{{Port: "53", Protocol: ProtoTCP}},
+				Rules: &L7Rules{
+					DNS:  []PortRuleDNS{{MatchName: "cilium.io"}},
+					HTTP: []PortRuleHTTP{{Method: "GET"}},
+				},
+			}},
+		}},
+	}
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+}
+
 // This test ensures that PortRules using key-value pairs do not have empty keys
 func (s *PolicyAPITestSuite) TestL7Rules(c *C) {
 
@@ -346,3 +456,47 @@ func (s *PolicyAPITestSuite) TestL7Rules(c *C) {
 	err = invalidL7Rule.Sanitize()
 	c.Assert(err, Not(IsNil))
 }
+
+func (s *PolicyAPITestSuite) TestDenyRulesSanitize(c *C) {
+	validDeny := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		IngressDeny: []IngressDenyRule{{
+			FromCIDR: []CIDR{"10.0.0.0/8"},
+			ToPorts: []PortDenyRule{{
+				Ports: []PortProtocol{{Port: "22"}},
+			}},
+		}},
+		EgressDeny: []EgressDenyRule{{
+			ToCIDR: []CIDR{"169.254.169.254/32"},
+		}},
+	}
+	c.Assert(validDeny.Sanitize(), IsNil)
+	c.Assert(validDeny.IngressDeny[0].ToPorts[0].Ports[0].Protocol, Equals, ProtoAny)
+
+	invalidPort := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		EgressDeny: []EgressDenyRule{{
+			ToPorts: []PortDenyRule{{
+				Ports: []PortProtocol{{Port: "0", Protocol: ProtoTCP}},
+			}},
+		}},
+	}
+	c.Assert(invalidPort.Sanitize(), Not(IsNil))
+
+	invalidL3 := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		IngressDeny: []IngressDenyRule{{
+			FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+			FromCIDR:      []CIDR{"10.0.0.0/8"},
+		}},
+	}
+	c.Assert(invalidL3.Sanitize(), Not(IsNil))
+
+	invalidEntity := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		EgressDeny: []EgressDenyRule{{
+			ToEntities: []Entity{"foo"},
+		}},
+	}
+	c.Assert(invalidEntity.Sanitize(), Not(IsNil))
+}
@@ -35,7 +35,20 @@ func (h *PortRuleHTTP) Equal(o PortRuleHTTP) bool {
 	if h.Path != o.Path ||
 		h.Method != o.Method ||
 		h.Host != o.Host ||
-		len(h.Headers) != len(o.Headers) {
+		len(h.Headers) != len(o.Headers) ||
+		len(h.HeaderMatches) != len(o.HeaderMatches) ||
+		len(h.AddHeaders) != len(o.AddHeaders) ||
+		len(h.RemoveHeaders) != len(o.RemoveHeaders) {
+		return false
+	}
+
+	if (h.GRPC == nil) != (o.GRPC == nil) ||
+		(h.GRPC != nil && *h.GRPC != *o.GRPC) {
+		return false
+	}
+
+	if (h.RateLimit == nil) != (o.RateLimit == nil) ||
+		(h.RateLimit != nil && *h.RateLimit != *o.RateLimit) {
 		return false
 	}
 
@@ -44,6 +57,21 @@ func (h *PortRuleHTTP) Equal(o PortRuleHTTP) bool {
 			return false
 		}
 	}
+	for i, value := range h.HeaderMatches {
+		if o.HeaderMatches[i] != value {
+			return false
+		}
+	}
+	for i, value := range h.AddHeaders {
+		if o.AddHeaders[i] != value {
+			return false
+		}
+	}
+	for i, value := range h.RemoveHeaders {
+		if o.RemoveHeaders[i] != value {
+			return false
+		}
+	}
 	return true
 }
 
@@ -61,7 +89,25 @@ func (k *PortRuleKafka) Exists(rules L7Rules) bool {
 // Equal returns true if both rules are equal
 func (k *PortRuleKafka) Equal(o PortRuleKafka) bool {
 	return k.APIVersion == o.APIVersion && k.APIKey == o.APIKey &&
-		k.Topic == o.Topic && k.ClientID == o.ClientID && k.Role == o.Role
+		k.Topic == o.Topic && k.TopicPrefix == o.TopicPrefix &&
+		k.TopicRegex == o.TopicRegex && k.ConsumerGroup == o.ConsumerGroup &&
+		k.ClientID == o.ClientID && k.Role == o.Role
+}
+
+// Exists returns true if the DNS rule already exists in the list of rules
+func (d *PortRuleDNS) Exists(rules L7Rules) bool {
+	for _, existingRule := range rules.DNS {
+		if d.Equal(existingRule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Equal returns true if both rules are equal
+func (d *PortRuleDNS) Equal(o PortRuleDNS) bool {
+	return d.MatchName == o.MatchName && d.MatchPattern == o.MatchPattern
 }
 
 // Exists returns true if the L7 rule already exists in the list of rules
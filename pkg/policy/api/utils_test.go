@@ -45,6 +45,30 @@ func (s *PolicyAPITestSuite) TestHTTPEqual(c *C) {
 	c.Assert(rule1.Exists(rules), Equals, true)
 	c.Assert(rule2.Exists(rules), Equals, true)
 	c.Assert(rule3.Exists(rules), Equals, false)
+
+	rule4 := PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld.Greeter"}}
+	rule5 := PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld.Greeter", Method: "SayHello"}}
+	c.Assert(rule4.Equal(PortRuleHTTP{GRPC: &PortRuleGRPC{Service: "helloworld.Greeter"}}), Equals, true)
+	c.Assert(rule4.Equal(rule5), Equals, false)
+	c.Assert(rule4.Equal(PortRuleHTTP{}), Equals, false)
+
+	rule6 := PortRuleHTTP{
+		HeaderMatches: []HeaderMatch{{Name: "x-tenant", Value: "test"}},
+		AddHeaders:    []HTTPHeader{{Name: "x-source-identity", Value: SourceIdentityPlaceholder}},
+		RemoveHeaders: []string{"authorization"},
+	}
+	rule7 := *rule6.DeepCopy()
+	c.Assert(rule6.Equal(rule7), Equals, true)
+	rule7.HeaderMatches[0].Invert = true
+	c.Assert(rule6.Equal(rule7), Equals, false)
+	c.Assert(rule6.Equal(PortRuleHTTP{HeaderMatches: rule6.HeaderMatches, AddHeaders: rule6.AddHeaders}), Equals, false)
+	c.Assert(rule6.Equal(PortRuleHTTP{HeaderMatches: rule6.HeaderMatches, RemoveHeaders: rule6.RemoveHeaders,
+		AddHeaders: []HTTPHeader{{Name: "x-source-identity"}}}), Equals, false)
+
+	rule8 := PortRuleHTTP{Path: "/", RateLimit: &HTTPRateLimit{RequestsPerSecond: 10}}
+	c.Assert(rule8.Equal(PortRuleHTTP{Path: "/", RateLimit: &HTTPRateLimit{RequestsPerSecond: 10}}), Equals, true)
+	c.Assert(rule8.Equal(PortRuleHTTP{Path: "/", RateLimit: &HTTPRateLimit{RequestsPerSecond: 10, Burst: 20}}), Equals, false)
+	c.Assert(rule8.Equal(PortRuleHTTP{Path: "/"}), Equals, false)
 }
 
 func (s *PolicyAPITestSuite) TestKafkaEqual(c *C) {
@@ -65,6 +89,24 @@ func (s *PolicyAPITestSuite) TestKafkaEqual(c *C) {
 	c.Assert(rule3.Exists(rules), Equals, false)
 }
 
+func (s *PolicyAPITestSuite) TestDNSEqual(c *C) {
+	rule1 := PortRuleDNS{MatchName: "cilium.io"}
+	rule2 := PortRuleDNS{MatchPattern: "*.cilium.io"}
+	rule3 := PortRuleDNS{MatchName: "github.com"}
+
+	c.Assert(rule1.Equal(rule1), Equals, true)
+	c.Assert(rule1.Equal(rule2), Equals, false)
+	c.Assert(rule1.Equal(rule3), Equals, false)
+
+	rules := L7Rules{
+		DNS: []PortRuleDNS{rule1, rule2},
+	}
+
+	c.Assert(rule1.Exists(rules), Equals, true)
+	c.Assert(rule2.Exists(rules), Equals, true)
+	c.Assert(rule3.Exists(rules), Equals, false)
+}
+
 func (s *PolicyAPITestSuite) TestL7Equal(c *C) {
 	rule1 := PortRuleL7{"Path": "/foo$", "Method": "GET"}
 	rule2 := PortRuleL7{"Path": "/bar$", "Method": "GET"}
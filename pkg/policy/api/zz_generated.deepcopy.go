@@ -86,6 +86,53 @@ func (in CIDRSlice) DeepCopy() CIDRSlice {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressDenyRule) DeepCopyInto(out *EgressDenyRule) {
+	*out = *in
+	if in.ToEndpoints != nil {
+		in, out := &in.ToEndpoints, &out.ToEndpoints
+		*out = make([]EndpointSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToCIDR != nil {
+		in, out := &in.ToCIDR, &out.ToCIDR
+		*out = make(CIDRSlice, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToCIDRSet != nil {
+		in, out := &in.ToCIDRSet, &out.ToCIDRSet
+		*out = make(CIDRRuleSlice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToEntities != nil {
+		in, out := &in.ToEntities, &out.ToEntities
+		*out = make(EntitySlice, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressDenyRule.
+func (in *EgressDenyRule) DeepCopy() *EgressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EgressRule) DeepCopyInto(out *EgressRule) {
 	*out = *in
@@ -250,6 +297,101 @@ func (in *FQDNSelector) DeepCopy() *FQDNSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHeader) DeepCopyInto(out *HTTPHeader) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHeader.
+func (in *HTTPHeader) DeepCopy() *HTTPHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRateLimit) DeepCopyInto(out *HTTPRateLimit) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRateLimit.
+func (in *HTTPRateLimit) DeepCopy() *HTTPRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderMatch) DeepCopyInto(out *HeaderMatch) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderMatch.
+func (in *HeaderMatch) DeepCopy() *HeaderMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDenyRule) DeepCopyInto(out *IngressDenyRule) {
+	*out = *in
+	if in.FromEndpoints != nil {
+		in, out := &in.FromEndpoints, &out.FromEndpoints
+		*out = make([]EndpointSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FromCIDR != nil {
+		in, out := &in.FromCIDR, &out.FromCIDR
+		*out = make(CIDRSlice, len(*in))
+		copy(*out, *in)
+	}
+	if in.FromCIDRSet != nil {
+		in, out := &in.FromCIDRSet, &out.FromCIDRSet
+		*out = make(CIDRRuleSlice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FromEntities != nil {
+		in, out := &in.FromEntities, &out.FromEntities
+		*out = make(EntitySlice, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDenyRule.
+func (in *IngressDenyRule) DeepCopy() *IngressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressRule) DeepCopyInto(out *IngressRule) {
 	*out = *in
@@ -374,6 +516,11 @@ func (in *L7Rules) DeepCopyInto(out *L7Rules) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = make([]PortRuleDNS, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -387,6 +534,27 @@ func (in *L7Rules) DeepCopy() *L7Rules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortDenyRule) DeepCopyInto(out *PortDenyRule) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]PortProtocol, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortDenyRule.
+func (in *PortDenyRule) DeepCopy() *PortDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PortDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortProtocol) DeepCopyInto(out *PortProtocol) {
 	*out = *in
@@ -433,6 +601,38 @@ func (in *PortRule) DeepCopy() *PortRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleDNS) DeepCopyInto(out *PortRuleDNS) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleDNS.
+func (in *PortRuleDNS) DeepCopy() *PortRuleDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleGRPC) DeepCopyInto(out *PortRuleGRPC) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleGRPC.
+func (in *PortRuleGRPC) DeepCopy() *PortRuleGRPC {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleGRPC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortRuleHTTP) DeepCopyInto(out *PortRuleHTTP) {
 	*out = *in
@@ -441,6 +641,31 @@ func (in *PortRuleHTTP) DeepCopyInto(out *PortRuleHTTP) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(PortRuleGRPC)
+		**out = **in
+	}
+	if in.HeaderMatches != nil {
+		in, out := &in.HeaderMatches, &out.HeaderMatches
+		*out = make([]HeaderMatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddHeaders != nil {
+		in, out := &in.AddHeaders, &out.AddHeaders
+		*out = make([]HTTPHeader, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemoveHeaders != nil {
+		in, out := &in.RemoveHeaders, &out.RemoveHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HTTPRateLimit)
+		**out = **in
+	}
 	return
 }
 
@@ -502,6 +727,20 @@ func (in *Rule) DeepCopyInto(out *Rule) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IngressDeny != nil {
+		in, out := &in.IngressDeny, &out.IngressDeny
+		*out = make([]IngressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EgressDeny != nil {
+		in, out := &in.EgressDeny, &out.EgressDeny
+		*out = make([]EgressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.Labels = in.Labels.DeepCopy()
 	return
 }
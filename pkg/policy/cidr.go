@@ -65,6 +65,22 @@ func GetCIDRPrefixes(rules api.Rules) []*net.IPNet {
 				res = append(res, GetPrefixesFromCIDRSet(er.ToCIDRSet)...)
 			}
 		}
+		for _, ir := range r.IngressDeny {
+			if len(ir.FromCIDR) > 0 {
+				res = append(res, getPrefixesFromCIDR(ir.FromCIDR)...)
+			}
+			if len(ir.FromCIDRSet) > 0 {
+				res = append(res, GetPrefixesFromCIDRSet(ir.FromCIDRSet)...)
+			}
+		}
+		for _, er := range r.EgressDeny {
+			if len(er.ToCIDR) > 0 {
+				res = append(res, getPrefixesFromCIDR(er.ToCIDR)...)
+			}
+			if len(er.ToCIDRSet) > 0 {
+				res = append(res, GetPrefixesFromCIDRSet(er.ToCIDRSet)...)
+			}
+		}
 	}
 	return res
 }
@@ -84,6 +84,9 @@ const (
 	ParserTypeHTTP L7ParserType = "http"
 	// ParserTypeKafka specifies a Kafka parser type
 	ParserTypeKafka L7ParserType = "kafka"
+	// ParserTypeDNS specifies a DNS parser type. DNS is handled by the DNS
+	// proxy in the agent, and may be redirected for both TCP and UDP.
+	ParserTypeDNS L7ParserType = "dns"
 )
 
 type L4Filter struct {
@@ -104,6 +107,10 @@ type L4Filter struct {
 	L7RulesPerEp L7DataMap `json:"l7-rules,omitempty"`
 	// Ingress is true if filter applies at ingress; false if it applies at egress.
 	Ingress bool `json:"-"`
+	// Deny is true if the filter denies the traffic it selects. Deny
+	// filters take precedence over allow filters and never redirect to
+	// an L7 proxy.
+	Deny bool `json:"deny,omitempty"`
 	// The rule labels of this Filter
 	DerivedFromRules labels.LabelArrayList `json:"-"`
 }
@@ -123,6 +130,7 @@ func (l7 L7DataMap) GetRelevantRules(identity *identity.Identity) api.L7Rules {
 			if selector.Matches(identity.Labels.LabelArray()) {
 				rules.HTTP = append(rules.HTTP, endpointRules.HTTP...)
 				rules.Kafka = append(rules.Kafka, endpointRules.Kafka...)
+				rules.DNS = append(rules.DNS, endpointRules.DNS...)
 				rules.L7Proto = endpointRules.L7Proto
 				rules.L7 = append(rules.L7, endpointRules.L7...)
 			}
@@ -133,6 +141,7 @@ func (l7 L7DataMap) GetRelevantRules(identity *identity.Identity) api.L7Rules {
 	if r, ok := l7[api.WildcardEndpointSelector]; ok {
 		rules.HTTP = append(rules.HTTP, r.HTTP...)
 		rules.Kafka = append(rules.Kafka, r.Kafka...)
+		rules.DNS = append(rules.DNS, r.DNS...)
 		rules.L7Proto = r.L7Proto // XXX
 		rules.L7 = append(rules.L7, r.L7...)
 	}
@@ -182,18 +191,28 @@ func CreateL4Filter(peerEndpoints api.EndpointSelectorSlice, rule api.PortRule,
 		Ingress:          ingress,
 	}
 
-	if protocol == api.ProtoTCP && rule.Rules != nil {
+	// Only DNS may be redirected to a proxy for protocols other than TCP
+	if rule.Rules != nil && (protocol == api.ProtoTCP || len(rule.Rules.DNS) > 0 || rule.Rules.L7Proto == ParserTypeDNS.String()) {
 		switch {
 		case len(rule.Rules.HTTP) > 0:
 			l4.L7Parser = ParserTypeHTTP
 		case len(rule.Rules.Kafka) > 0:
 			l4.L7Parser = ParserTypeKafka
+		case len(rule.Rules.DNS) > 0:
+			l4.L7Parser = ParserTypeDNS
 		case rule.Rules.L7Proto != "":
 			l4.L7Parser = (L7ParserType)(rule.Rules.L7Proto)
 		}
 		if !rule.Rules.IsEmpty() {
 			l4.L7RulesPerEp.addRulesForEndpoints(*rule.Rules, filterEndpoints)
 		}
+		// "l7proto: dns" without DNS rules only redirects to the DNS
+		// proxy, which then allows all names.
+		if l4.L7Parser == ParserTypeDNS && len(rule.Rules.DNS) == 0 {
+			l4.L7RulesPerEp.addRulesForEndpoints(api.L7Rules{
+				DNS: []api.PortRuleDNS{{MatchPattern: "*"}},
+			}, filterEndpoints)
+		}
 	}
 
 	return l4
@@ -232,6 +251,34 @@ func CreateL4EgressFilter(toEndpoints api.EndpointSelectorSlice, rule api.PortRu
 	return CreateL4Filter(toEndpoints, rule, port, protocol, ruleLabels, false)
 }
 
+// CreateL4DenyFilter creates a filter which denies traffic from or to the
+// specified endpoints on the given port/protocol, with reference to the
+// original rules that the filter is derived from. A port of 0 with protocol
+// ANY denies all traffic with the specified endpoints at L3.
+func CreateL4DenyFilter(peerEndpoints api.EndpointSelectorSlice, port api.PortProtocol,
+	protocol api.L4Proto, ruleLabels labels.LabelArray, ingress bool) L4Filter {
+
+	// already validated via PortDenyRule.sanitize()
+	p, _ := strconv.ParseUint(port.Port, 0, 16)
+	u8p, _ := u8proto.ParseProtocol(string(protocol))
+
+	filterEndpoints := peerEndpoints
+	if peerEndpoints.SelectsAllEndpoints() {
+		filterEndpoints = api.EndpointSelectorSlice{api.WildcardEndpointSelector}
+	}
+
+	return L4Filter{
+		Port:             int(p),
+		Protocol:         protocol,
+		U8Proto:          u8p,
+		L7RulesPerEp:     make(L7DataMap),
+		Endpoints:        filterEndpoints,
+		DerivedFromRules: labels.LabelArrayList{ruleLabels},
+		Ingress:          ingress,
+		Deny:             true,
+	}
+}
+
 // IsRedirect returns true if the L4 filter contains a port redirection
 func (l4 *L4Filter) IsRedirect() bool {
 	return l4.L7Parser != ParserTypeNone
@@ -334,19 +381,56 @@ func (l4 L4PolicyMap) containsAllL3L4(labels labels.LabelArray, ports []*models.
 	return api.Allowed
 }
 
+// deniesAnyL3L4 checks whether the deny filters in the L4PolicyMap deny
+// communication with the endpoint carrying `labels` on any of the L4 ports in
+// `ports`. Filters for port 0 deny all ports, including when `ports` is empty.
+// Returns api.Denied if a filter matches, api.Undecided otherwise.
+func (l4 L4PolicyMap) deniesAnyL3L4(labels labels.LabelArray, ports []*models.Port) api.Decision {
+	for _, filter := range l4 {
+		if !filter.matchesLabels(labels) {
+			continue
+		}
+		if filter.Port == 0 {
+			return api.Denied
+		}
+		for _, l4Ctx := range ports {
+			if int(l4Ctx.Port) != filter.Port {
+				continue
+			}
+			switch l4Ctx.Protocol {
+			case "", models.PortProtocolANY:
+				return api.Denied
+			default:
+				if l4Ctx.Protocol == string(filter.Protocol) {
+					return api.Denied
+				}
+			}
+		}
+	}
+	return api.Undecided
+}
+
 type L4Policy struct {
 	Ingress L4PolicyMap
 	Egress  L4PolicyMap
 
+	// IngressDeny and EgressDeny contain the filters derived from deny
+	// rules. Filters for port 0 deny all traffic with the selected
+	// endpoints at L3.
+	IngressDeny L4PolicyMap
+	EgressDeny  L4PolicyMap
+
 	// Revision is the repository revision used to generate this policy.
 	Revision uint64
 }
 
 func NewL4Policy() *L4Policy {
 	return &L4Policy{
-		Ingress:  L4PolicyMap{},
-		Egress:   L4PolicyMap{},
-		Revision: 0,
+		Ingress:     L4PolicyMap{},
+		Egress:      L4PolicyMap{},
+		IngressDeny: L4PolicyMap{},
+		EgressDeny:  L4PolicyMap{},
+		Revision:    0,
 	}
 }
 
@@ -362,6 +446,18 @@ func (l4 *L4PolicyMap) EgressCoversContext(ctx *SearchContext) api.Decision {
 	return l4.containsAllL3L4(ctx.To, ctx.DPorts)
 }
 
+// IngressDeniesContext checks if the receiver's ingress deny filters deny
+// any of the `dPorts` for the `labels` in ctx.
+func (l4 *L4PolicyMap) IngressDeniesContext(ctx *SearchContext) api.Decision {
+	return l4.deniesAnyL3L4(ctx.From, ctx.DPorts)
+}
+
+// EgressDeniesContext checks if the receiver's egress deny filters deny
+// any of the `dPorts` for the `labels` in ctx.
+func (l4 *L4PolicyMap) EgressDeniesContext(ctx *SearchContext) api.Decision {
+	return l4.deniesAnyL3L4(ctx.To, ctx.DPorts)
+}
+
 // HasRedirect returns true if the L4 policy contains at least one port redirection
 func (l4 *L4Policy) HasRedirect() bool {
 	return l4 != nil && (l4.Ingress.HasRedirect() || l4.Egress.HasRedirect())
@@ -386,6 +482,13 @@ func (l4 *L4Policy) GetModel() *models.L4Policy {
 		})
 	}
 
+	for _, v := range l4.IngressDeny {
+		ingress = append(ingress, &models.PolicyRule{
+			Rule:             v.MarshalIndent(),
+			DerivedFromRules: v.DerivedFromRules.GetModel(),
+		})
+	}
+
 	egress := []*models.PolicyRule{}
 	for _, v := range l4.Egress {
 		egress = append(egress, &models.PolicyRule{
@@ -393,6 +496,12 @@ func (l4 *L4Policy) GetModel() *models.L4Policy {
 			DerivedFromRules: v.DerivedFromRules.GetModel(),
 		})
 	}
+	for _, v := range l4.EgressDeny {
+		egress = append(egress, &models.PolicyRule{
+			Rule:             v.MarshalIndent(),
+			DerivedFromRules: v.DerivedFromRules.GetModel(),
+		})
+	}
 
 	return &models.L4Policy{
 		Ingress: ingress,
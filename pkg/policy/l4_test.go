@@ -83,6 +83,38 @@ func (s *PolicyTestSuite) TestCreateL4Filter(c *C) {
 	}
 }
 
+func (s *PolicyTestSuite) TestCreateL4FilterDNS(c *C) {
+	portrule := api.PortRule{
+		Ports: []api.PortProtocol{{Port: "53", Protocol: api.ProtoAny}},
+		Rules: &api.L7Rules{L7Proto: ParserTypeDNS.String()},
+	}
+	eps := []api.EndpointSelector{api.WildcardEndpointSelector}
+
+	// DNS is redirected for both TCP and UDP
+	for _, proto := range []api.L4Proto{api.ProtoTCP, api.ProtoUDP} {
+		tuple := api.PortProtocol{Port: "53", Protocol: proto}
+		filter := CreateL4EgressFilter(eps, portrule, tuple, proto, nil)
+		c.Assert(filter.L7Parser, Equals, ParserTypeDNS)
+		c.Assert(filter.IsRedirect(), Equals, true)
+		// "l7proto: dns" allows all names
+		c.Assert(filter.L7RulesPerEp[api.WildcardEndpointSelector].DNS, checker.DeepEquals,
+			[]api.PortRuleDNS{{MatchPattern: "*"}})
+	}
+
+	// DNS rules select the DNS parser
+	portrule.Rules = &api.L7Rules{DNS: []api.PortRuleDNS{{MatchName: "cilium.io"}}}
+	tuple := api.PortProtocol{Port: "53", Protocol: api.ProtoUDP}
+	filter := CreateL4EgressFilter(eps, portrule, tuple, tuple.Protocol, nil)
+	c.Assert(filter.L7Parser, Equals, ParserTypeDNS)
+	c.Assert(filter.L7RulesPerEp[api.WildcardEndpointSelector].DNS, checker.DeepEquals,
+		[]api.PortRuleDNS{{MatchName: "cilium.io"}})
+
+	// Other parsers are only redirected for TCP
+	portrule.Rules = &api.L7Rules{L7Proto: "testparser"}
+	filter = CreateL4EgressFilter(eps, portrule, tuple, tuple.Protocol, nil)
+	c.Assert(filter.IsRedirect(), Equals, false)
+}
+
 type SortablePolicyRules []*models.PolicyRule
 
 func (a SortablePolicyRules) Len() int           { return len(a) }
@@ -59,13 +59,18 @@ type traceState struct {
 	// unsatisfied
 	constrainedRules int
 
+	// deniedRules is the number of rules that have denied traffic
+	deniedRules int
+
 	// ruleID is the rule ID currently being evaluated
 	ruleID int
 }
 
 func (state *traceState) trace(p *Repository, ctx *SearchContext) {
 	ctx.PolicyTrace("%d/%d rules selected\n", state.selectedRules, len(p.rules))
-	if state.constrainedRules > 0 {
+	if state.deniedRules > 0 {
+		ctx.PolicyTrace("Found deny rule\n")
+	} else if state.constrainedRules > 0 {
 		ctx.PolicyTrace("Found unsatisfied FromRequires constraint\n")
 	} else if state.matchedRules > 0 {
 		ctx.PolicyTrace("Found allow rule\n")
@@ -150,6 +155,13 @@ func wildcardL3L4Rule(proto api.L4Proto, port int, endpoints api.EndpointSelecto
 					Kafka: []api.PortRuleKafka{rule},
 				}
 			}
+		case ParserTypeDNS:
+			// Wildcard at L7 all the endpoints allowed at L3 or L4.
+			for _, sel := range endpoints {
+				filter.L7RulesPerEp[sel] = api.L7Rules{
+					DNS: []api.PortRuleDNS{{MatchPattern: "*"}},
+				}
+			}
 		default:
 			// Wildcard at L7 all the endpoints allowed at L3 or L4.
 			for _, sel := range endpoints {
@@ -333,6 +345,32 @@ func (p *Repository) ResolveL4EgressPolicy(ctx *SearchContext) (*L4PolicyMap, er
 	return &result.Egress, nil
 }
 
+// ResolveL4IngressDenyPolicy resolves the ingress deny filters for a set of
+// endpoints by searching the policy repository for `IngressDenyRule` rules
+// that are attached to a `Rule` where the EndpointSelector matches `ctx.To`.
+// `ctx.From` takes no effect and is ignored in the search. Deny rules without
+// ports result in a filter for port 0 which denies all ports.
+func (p *Repository) ResolveL4IngressDenyPolicy(ctx *SearchContext) *L4PolicyMap {
+	result := NewL4Policy()
+	for _, r := range p.rules {
+		r.resolveL4IngressDenyPolicy(ctx, result)
+	}
+	return &result.IngressDeny
+}
+
+// ResolveL4EgressDenyPolicy resolves the egress deny filters for a set of
+// endpoints by searching the policy repository for `EgressDenyRule` rules
+// that are attached to a `Rule` where the EndpointSelector matches
+// `ctx.From`. `ctx.To` takes no effect and is ignored in the search. Deny
+// rules without ports result in a filter for port 0 which denies all ports.
+func (p *Repository) ResolveL4EgressDenyPolicy(ctx *SearchContext) *L4PolicyMap {
+	result := NewL4Policy()
+	for _, r := range p.rules {
+		r.resolveL4EgressDenyPolicy(ctx, result)
+	}
+	return &result.EgressDeny
+}
+
 // ResolveCIDRPolicy resolves the L3 policy for a set of endpoints by searching
 // the policy repository for `CIDR` rules that are attached to a `Rule`
 // where the EndpointSelector matches `ctx.To`. `ctx.From` takes no effect and
@@ -352,6 +390,26 @@ func (p *Repository) ResolveCIDRPolicy(ctx *SearchContext) *CIDRPolicy {
 	return result
 }
 
+func (p *Repository) deniesIngress(ctx *SearchContext) api.Decision {
+	denyPolicy := p.ResolveL4IngressDenyPolicy(ctx)
+	if len(*denyPolicy) == 0 {
+		return api.Undecided
+	}
+	verdict := denyPolicy.IngressDeniesContext(ctx)
+	ctx.PolicyTrace("Ingress deny verdict: %s", verdict.String())
+	return verdict
+}
+
+func (p *Repository) deniesEgress(ctx *SearchContext) api.Decision {
+	denyPolicy := p.ResolveL4EgressDenyPolicy(ctx)
+	if len(*denyPolicy) == 0 {
+		return api.Undecided
+	}
+	verdict := denyPolicy.EgressDeniesContext(ctx)
+	ctx.PolicyTrace("Egress deny verdict: %s", verdict.String())
+	return verdict
+}
+
 func (p *Repository) allowsL4Egress(ctx *SearchContext) api.Decision {
 	egressL4Policy, err := p.ResolveL4EgressPolicy(ctx)
 	if err != nil {
@@ -396,6 +454,11 @@ func (p *Repository) allowsL4Ingress(ctx *SearchContext) api.Decision {
 // be held.
 func (p *Repository) AllowsIngressRLocked(ctx *SearchContext) api.Decision {
 	ctx.PolicyTrace("Tracing %s\n", ctx.String())
+	// Deny rules take precedence over all allow rules.
+	if p.deniesIngress(ctx) == api.Denied {
+		return api.Denied
+	}
+
 	decision := p.CanReachIngressRLocked(ctx)
 	ctx.PolicyTrace("Label verdict: %s", decision.String())
 	if decision == api.Allowed {
@@ -421,6 +484,11 @@ func (p *Repository) AllowsIngressRLocked(ctx *SearchContext) api.Decision {
 // held.
 func (p *Repository) AllowsEgressRLocked(egressCtx *SearchContext) api.Decision {
 	egressCtx.PolicyTrace("Tracing %s\n", egressCtx.String())
+	// Deny rules take precedence over all allow rules.
+	if p.deniesEgress(egressCtx) == api.Denied {
+		return api.Denied
+	}
+
 	egressDecision := p.CanReachEgressRLocked(egressCtx)
 	egressCtx.PolicyTrace("Egress label verdict: %s", egressDecision.String())
 
@@ -620,6 +688,11 @@ func (p *Repository) GetJSON() string {
 // GetRulesMatching returns whether any of the rules in a repository contain a
 // rule with labels matching the labels in the provided LabelArray.
 //
+// Deny rules count as well: deny entries are enforced through the policy map
+// of the endpoint, which is only consulted when policy enforcement is enabled
+// for the direction. A rule with only deny sections therefore puts the
+// selected endpoints into default deny mode, like an allow rule does.
+//
 // Must be called with p.Mutex held
 func (p *Repository) GetRulesMatching(labels labels.LabelArray) (ingressMatch bool, egressMatch bool) {
 	ingressMatch = false
@@ -627,10 +700,10 @@ func (p *Repository) GetRulesMatching(labels labels.LabelArray) (ingressMatch bo
 	for _, r := range p.rules {
 		rulesMatch := r.EndpointSelector.Matches(labels)
 		if rulesMatch {
-			if len(r.Ingress) > 0 {
+			if len(r.Ingress) > 0 || len(r.IngressDeny) > 0 {
 				ingressMatch = true
 			}
-			if len(r.Egress) > 0 {
+			if len(r.Egress) > 0 || len(r.EgressDeny) > 0 {
 				egressMatch = true
 			}
 		}
@@ -1680,3 +1680,131 @@ Label verdict: undecided
 	repo.Mutex.RUnlock()
 	c.Assert(verdict, Equals, api.Allowed)
 }
+
+func (ds *PolicyTestSuite) TestDenyRulesIngress(c *C) {
+	repo := NewPolicyRepository()
+
+	selBar := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	selFoo := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	selBaz := api.NewESFromLabels(labels.ParseSelectLabel("baz"))
+	labelsDeny := labels.LabelArray{labels.ParseLabel("deny")}
+
+	// Allow foo=>bar and baz=>bar on all ports
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: selBar,
+		Ingress: []api.IngressRule{{
+			FromEndpoints: []api.EndpointSelector{selFoo, selBaz},
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	// Deny foo=>bar:80 and all of baz=>bar
+	_, err = repo.Add(api.Rule{
+		EndpointSelector: selBar,
+		IngressDeny: []api.IngressDenyRule{
+			{
+				FromEndpoints: []api.EndpointSelector{selFoo},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "80", Protocol: api.ProtoTCP}},
+				}},
+			},
+			{
+				FromEndpoints: []api.EndpointSelector{selBaz},
+			},
+		},
+		Labels: labelsDeny,
+	})
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	defer repo.Mutex.RUnlock()
+
+	ctx := buildSearchCtx("foo", "bar", 80)
+	c.Assert(repo.AllowsIngressRLocked(ctx), Equals, api.Denied)
+	ctx = buildSearchCtx("foo", "bar", 443)
+	c.Assert(repo.AllowsIngressRLocked(ctx), Equals, api.Allowed)
+	ctx = buildSearchCtx("foo", "bar", 0)
+	c.Assert(repo.AllowsIngressRLocked(ctx), Equals, api.Allowed)
+	c.Assert(repo.CanReachIngressRLocked(ctx), Equals, api.Allowed)
+
+	ctx = buildSearchCtx("baz", "bar", 443)
+	c.Assert(repo.AllowsIngressRLocked(ctx), Equals, api.Denied)
+	ctx = buildSearchCtx("baz", "bar", 0)
+	c.Assert(repo.CanReachIngressRLocked(ctx), Equals, api.Denied)
+
+	buffer := new(bytes.Buffer)
+	ctx.Logging = logging.NewLogBackend(buffer, "", 0)
+	c.Assert(repo.AllowsIngressRLocked(ctx), Equals, api.Denied)
+	c.Assert(buffer.String(), checker.DeepEquals, "Tracing "+ctx.String()+`
+* Rule {"matchLabels":{"any:bar":""}}: selected for ingress deny
+    Denies Ingress port [{80 TCP}] with endpoints [{"matchLabels":{"any:foo":""}}]
+    Denies Ingress all ports with endpoints [{"matchLabels":{"any:baz":""}}]
+Ingress deny verdict: denied
+`)
+
+	ctx = &SearchContext{To: labels.ParseSelectLabelArray("bar")}
+	expected := L4PolicyMap{
+		"80/TCP": {
+			Port:             80,
+			Protocol:         api.ProtoTCP,
+			U8Proto:          0x6,
+			Endpoints:        []api.EndpointSelector{selFoo},
+			L7RulesPerEp:     L7DataMap{},
+			Ingress:          true,
+			Deny:             true,
+			DerivedFromRules: labels.LabelArrayList{labelsDeny},
+		},
+		"0/ANY": {
+			Port:             0,
+			Protocol:         api.ProtoAny,
+			Endpoints:        []api.EndpointSelector{selBaz},
+			L7RulesPerEp:     L7DataMap{},
+			Ingress:          true,
+			Deny:             true,
+			DerivedFromRules: labels.LabelArrayList{labelsDeny},
+		},
+	}
+	c.Assert(*repo.ResolveL4IngressDenyPolicy(ctx), checker.DeepEquals, expected)
+}
+
+func (ds *PolicyTestSuite) TestDenyRulesEgress(c *C) {
+	repo := NewPolicyRepository()
+
+	selBar := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	selFoo := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+
+	// A deny-only rule enables policy enforcement at egress
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: selBar,
+		EgressDeny: []api.EgressDenyRule{{
+			ToEndpoints: []api.EndpointSelector{selFoo},
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	ingress, egress := repo.GetRulesMatching(labels.ParseSelectLabelArray("bar"))
+	c.Assert(ingress, Equals, false)
+	c.Assert(egress, Equals, true)
+	repo.Mutex.RUnlock()
+
+	// Allow all egress from bar, the deny still takes precedence
+	_, err = repo.Add(api.Rule{
+		EndpointSelector: selBar,
+		Egress: []api.EgressRule{{
+			ToEndpoints: []api.EndpointSelector{api.WildcardEndpointSelector},
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	defer repo.Mutex.RUnlock()
+
+	ctx := buildSearchCtx("bar", "foo", 0)
+	c.Assert(repo.CanReachEgressRLocked(ctx), Equals, api.Denied)
+	c.Assert(repo.AllowsEgressRLocked(ctx), Equals, api.Denied)
+
+	ctx = buildSearchCtx("bar", "baz", 0)
+	c.Assert(repo.CanReachEgressRLocked(ctx), Equals, api.Allowed)
+	c.Assert(repo.AllowsEgressRLocked(ctx), Equals, api.Allowed)
+}
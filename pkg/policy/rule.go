@@ -62,7 +62,7 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 		if ep, ok := existingFilter.L7RulesPerEp[hash]; ok {
 			switch {
 			case len(newL7Rules.HTTP) > 0:
-				if len(ep.Kafka) > 0 || ep.L7Proto != "" {
+				if len(ep.Kafka) > 0 || len(ep.DNS) > 0 || ep.L7Proto != "" {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -73,7 +73,7 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 					}
 				}
 			case len(newL7Rules.Kafka) > 0:
-				if len(ep.HTTP) > 0 || ep.L7Proto != "" {
+				if len(ep.HTTP) > 0 || len(ep.DNS) > 0 || ep.L7Proto != "" {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -83,8 +83,19 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 						ep.Kafka = append(ep.Kafka, newRule)
 					}
 				}
+			case len(newL7Rules.DNS) > 0:
+				if len(ep.HTTP) > 0 || len(ep.Kafka) > 0 || ep.L7Proto != "" {
+					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
+					return fmt.Errorf("Cannot merge conflicting L7 rule types")
+				}
+
+				for _, newRule := range newL7Rules.DNS {
+					if !newRule.Exists(ep) {
+						ep.DNS = append(ep.DNS, newRule)
+					}
+				}
 			case newL7Rules.L7Proto != "":
-				if len(ep.Kafka) > 0 || len(ep.HTTP) > 0 || (ep.L7Proto != "" && ep.L7Proto != newL7Rules.L7Proto) {
+				if len(ep.Kafka) > 0 || len(ep.HTTP) > 0 || len(ep.DNS) > 0 || (ep.L7Proto != "" && ep.L7Proto != newL7Rules.L7Proto) {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -183,6 +194,9 @@ func mergeL4Ingress(ctx *SearchContext, rule api.IngressRule, ruleLabels labels.
 			for _, l7 := range r.Rules.Kafka {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
+			for _, l7 := range r.Rules.DNS {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
 			for _, l7 := range r.Rules.L7 {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
@@ -357,6 +371,24 @@ func (r *rule) canReachIngress(ctx *SearchContext, state *traceState) api.Decisi
 	}
 
 	state.selectRule(ctx, r)
+
+	// Deny rules without L4 restrictions take precedence over all other
+	// ingress rules.
+	for _, r := range r.IngressDeny {
+		if len(r.ToPorts) > 0 {
+			continue
+		}
+		for _, sel := range r.GetSourceEndpointSelectors() {
+			ctx.PolicyTrace("    Denies from labels %+v", sel)
+			if sel.Matches(ctx.From) {
+				ctx.PolicyTrace("-     Found all required labels\n")
+				state.deniedRules++
+				return api.Denied
+			}
+			ctx.PolicyTrace("      Labels %v not found\n", ctx.From)
+		}
+	}
+
 	for _, r := range r.Ingress {
 		for _, sel := range r.FromRequires {
 			ctx.PolicyTrace("    Requires from labels %+v", sel)
@@ -405,6 +437,23 @@ func (r *rule) canReachEgress(ctx *SearchContext, state *traceState) api.Decisio
 
 	state.selectRule(ctx, r)
 
+	// Deny rules without L4 restrictions take precedence over all other
+	// egress rules.
+	for _, r := range r.EgressDeny {
+		if len(r.ToPorts) > 0 {
+			continue
+		}
+		for _, sel := range r.GetDestinationEndpointSelectors() {
+			ctx.PolicyTrace("    Denies to labels %+v", sel)
+			if sel.Matches(ctx.To) {
+				ctx.PolicyTrace("-     Found all required labels\n")
+				state.deniedRules++
+				return api.Denied
+			}
+			ctx.PolicyTrace("      Labels %v not found\n", ctx.To)
+		}
+	}
+
 	for _, r := range r.Egress {
 		for _, sel := range r.ToRequires {
 			ctx.PolicyTrace("    Requires from labels %+v", sel)
@@ -460,6 +509,9 @@ func mergeL4Egress(ctx *SearchContext, rule api.EgressRule, ruleLabels labels.La
 			for _, l7 := range r.Rules.Kafka {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
+			for _, l7 := range r.Rules.DNS {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
 			for _, l7 := range r.Rules.L7 {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
@@ -565,3 +617,91 @@ func (r *rule) resolveL4EgressPolicy(ctx *SearchContext, state *traceState, resu
 
 	return nil, nil
 }
+
+func mergeL4DenyPort(endpoints api.EndpointSelectorSlice, p api.PortProtocol,
+	proto api.L4Proto, ruleLabels labels.LabelArray, ingress bool, resMap L4PolicyMap) int {
+
+	filterToMerge := CreateL4DenyFilter(endpoints, p, proto, ruleLabels, ingress)
+	key := fmt.Sprintf("%d/%s", filterToMerge.Port, filterToMerge.Protocol)
+	existingFilter, ok := resMap[key]
+	if !ok {
+		resMap[key] = filterToMerge
+		return 1
+	}
+
+	if existingFilter.AllowsAllAtL3() || filterToMerge.AllowsAllAtL3() {
+		existingFilter.Endpoints = api.EndpointSelectorSlice{api.WildcardEndpointSelector}
+	} else {
+		existingFilter.Endpoints = append(existingFilter.Endpoints, filterToMerge.Endpoints...)
+	}
+	existingFilter.DerivedFromRules = append(existingFilter.DerivedFromRules, ruleLabels)
+	resMap[key] = existingFilter
+	return 1
+}
+
+// mergeL4Deny merges the deny rule for the given peer endpoints and ports
+// into resMap. A rule without ports denies all traffic with the peers at L3,
+// which is represented as a filter for port 0 and protocol ANY.
+func mergeL4Deny(ctx *SearchContext, endpoints api.EndpointSelectorSlice, toPorts []api.PortDenyRule,
+	ruleLabels labels.LabelArray, ingress bool, resMap L4PolicyMap) int {
+
+	dir := policymap.Egress
+	if ingress {
+		dir = policymap.Ingress
+	}
+
+	if len(toPorts) == 0 {
+		// A rule without L3 nor L4 members does not deny anything.
+		if len(endpoints) == 0 {
+			return 0
+		}
+		ctx.PolicyTrace("    Denies %s all ports with endpoints %v\n", dir, endpoints)
+		return mergeL4DenyPort(endpoints, api.PortProtocol{Port: "0"}, api.ProtoAny, ruleLabels, ingress, resMap)
+	}
+
+	found := 0
+	for _, r := range toPorts {
+		ctx.PolicyTrace("    Denies %s port %v with endpoints %v\n", dir, r.Ports, endpoints)
+		for _, p := range r.Ports {
+			if p.Protocol != api.ProtoAny {
+				found += mergeL4DenyPort(endpoints, p, p.Protocol, ruleLabels, ingress, resMap)
+			} else {
+				found += mergeL4DenyPort(endpoints, p, api.ProtoTCP, ruleLabels, ingress, resMap)
+				found += mergeL4DenyPort(endpoints, p, api.ProtoUDP, ruleLabels, ingress, resMap)
+			}
+		}
+	}
+	return found
+}
+
+// resolveL4IngressDenyPolicy merges the ingress deny rules of r into result
+// if r selects ctx.To. Returns the number of filters merged.
+func (r *rule) resolveL4IngressDenyPolicy(ctx *SearchContext, result *L4Policy) int {
+	if len(r.IngressDeny) == 0 || !r.EndpointSelector.Matches(ctx.To) {
+		return 0
+	}
+
+	ctx.PolicyTrace("* Rule %s: selected for ingress deny\n", r)
+	found := 0
+	for _, denyRule := range r.IngressDeny {
+		found += mergeL4Deny(ctx, denyRule.GetSourceEndpointSelectors(), denyRule.ToPorts,
+			r.Rule.Labels.DeepCopy(), true, result.IngressDeny)
+	}
+	return found
+}
+
+// resolveL4EgressDenyPolicy merges the egress deny rules of r into result
+// if r selects ctx.From. Returns the number of filters merged.
+func (r *rule) resolveL4EgressDenyPolicy(ctx *SearchContext, result *L4Policy) int {
+	if len(r.EgressDeny) == 0 || !r.EndpointSelector.Matches(ctx.From) {
+		return 0
+	}
+
+	ctx.PolicyTrace("* Rule %s: selected for egress deny\n", r)
+	found := 0
+	for _, denyRule := range r.EgressDeny {
+		found += mergeL4Deny(ctx, denyRule.GetDestinationEndpointSelectors(), denyRule.ToPorts,
+			r.Rule.Labels.DeepCopy(), false, result.EgressDeny)
+	}
+	return found
+}
@@ -15,6 +15,7 @@
 package accesslog
 
 import (
+	"net"
 	"net/http"
 	"net/url"
 )
@@ -45,6 +46,10 @@ const (
 
 	// VerdictError indicates that there was an error processing the flow
 	VerdictError = "Error"
+
+	// VerdictRateLimited indicates that the flow was allowed by policy but
+	// rejected for exceeding the rate limit of the policy
+	VerdictRateLimited = "RateLimited"
 )
 
 // ObservationPoint is the type used to describe point of observation
@@ -195,6 +200,9 @@ type LogRecord struct {
 	// Kafka contains information for Kafka request/responses
 	Kafka *LogRecordKafka `json:"Kafka,omitempty"`
 
+	// DNS contains information for DNS request/responses
+	DNS *LogRecordDNS `json:"DNS,omitempty"`
+
 	// L7 contains information about generic L7 protocols
 	L7 *LogRecordL7 `json:"L7,omitempty"`
 }
@@ -215,6 +223,19 @@ type LogRecordHTTP struct {
 
 	// Headers are all HTTP headers present in the request
 	Headers http.Header
+
+	// GRPCService is the gRPC service called by the request, if the
+	// request is a gRPC call
+	GRPCService string `json:"GRPCService,omitempty"`
+
+	// GRPCMethod is the gRPC method called by the request, if the request
+	// is a gRPC call
+	GRPCMethod string `json:"GRPCMethod,omitempty"`
+
+	// GRPCStatus is the gRPC status code of the call. The status of
+	// successful HTTP responses is carried in trailers which are not
+	// logged, so it is only set for failed calls.
+	GRPCStatus int `json:"GRPCStatus,omitempty"`
 }
 
 // KafkaTopic contains the topic for requests
@@ -242,6 +263,30 @@ type LogRecordKafka struct {
 	// Note that this string can be empty since not all messages use
 	// Topic. example: LeaveGroup, Heartbeat
 	Topic KafkaTopic
+
+	// ConsumerGroup of the request, only set for requests matched
+	// against the consumer group of policy rules, e.g. JoinGroup
+	ConsumerGroup string
+}
+
+// LogRecordDNS contains the DNS specific portion of a log record
+type LogRecordDNS struct {
+	// Query is the name in the original query
+	Query string `json:"Query,omitempty"`
+
+	// IPs are the IPs in the answer section of a response
+	IPs []net.IP `json:"IPs,omitempty"`
+
+	// TTL is the lowest TTL of the answers in a response
+	TTL uint32 `json:"TTL,omitempty"`
+
+	// RCode is the response code as defined in RFC 1035. Use the
+	// github.com/miekg/dns.RcodeToString map to retrieve its name.
+	RCode int `json:"RCode,omitempty"`
+
+	// QTypes are the question types of the DNS message. Use the
+	// github.com/miekg/dns.TypeToString map to retrieve their names.
+	QTypes []uint16 `json:"QTypes,omitempty"`
 }
 
 // LogRecordL7 contains the generic L7 portion of a log record
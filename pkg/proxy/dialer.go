@@ -40,8 +40,26 @@ func setKeepAlive(c net.Conn) error {
 	return nil
 }
 
+// ciliumDialer connects to address with a socket marked with identity.
+// network may be a TCP or a UDP network.
 func ciliumDialer(identity int, network, address string) (net.Conn, error) {
-	addr, err := net.ResolveTCPAddr(network, address)
+	var (
+		addr     *net.TCPAddr
+		sockType = syscall.SOCK_STREAM
+		err      error
+	)
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		var udpAddr *net.UDPAddr
+		udpAddr, err = net.ResolveUDPAddr(network, address)
+		if udpAddr != nil {
+			addr = &net.TCPAddr{IP: udpAddr.IP, Port: udpAddr.Port, Zone: udpAddr.Zone}
+		}
+		sockType = syscall.SOCK_DGRAM
+	default:
+		addr, err = net.ResolveTCPAddr(network, address)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable resolve address %s/%s: %s", network, address, err)
 	}
@@ -51,7 +69,7 @@ func ciliumDialer(identity int, network, address string) (net.Conn, error) {
 		family = syscall.AF_INET6
 	}
 
-	fd, err := syscall.Socket(family, syscall.SOCK_STREAM, 0)
+	fd, err := syscall.Socket(family, sockType, 0)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create socket: %s", err)
 	}
@@ -0,0 +1,332 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/pkg/completion"
+	"github.com/cilium/cilium/pkg/flowdebug"
+	"github.com/cilium/cilium/pkg/fqdn/dnsproxy"
+	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
+	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/ipcache"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/policy"
+	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
+	"github.com/cilium/cilium/pkg/proxy/logger"
+	"github.com/cilium/cilium/pkg/u8proto"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+)
+
+// dnsRedirect implements the Redirect interface for DNS. All DNS redirects
+// share the single DNS proxy of the agent, and therefore its port.
+type dnsRedirect struct {
+	redirect *Redirect
+
+	// allowed are the regexps of the DNS names that may be looked up,
+	// indexed by the selector of the DNS servers they apply to. Access is
+	// protected by redirect.mutex.
+	allowed map[api.EndpointSelector][]*regexp.Regexp
+}
+
+// StartDNSProxy starts the DNS proxy that all DNS redirects point to. When
+// port is 0, a port is allocated from the proxy port range. notifyFunc is
+// called with every DNS response before it is returned to the endpoint.
+func (p *Proxy) StartDNSProxy(port uint16, notifyFunc dnsproxy.NotifyOnDNSMsgFunc) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.dnsProxy != nil {
+		return fmt.Errorf("DNS proxy already started on port %d", p.dnsProxy.BindPort)
+	}
+
+	if port == 0 {
+		allocatedPort, err := p.allocatePort()
+		if err != nil {
+			return err
+		}
+		port = allocatedPort
+	}
+
+	notifyAndLog := func(lookupTime time.Time, srcAddr string, srcIdentity uint32, protocol, dstAddr string, msg *dns.Msg) error {
+		if dr, err := p.findDNSRedirect(srcAddr, protocol, dstAddr); err == nil {
+			record := dr.newLogRecord(accesslog.TypeResponse, srcAddr, srcIdentity, protocol, dstAddr, msg)
+			record.log(accesslog.VerdictForwarded, "")
+		}
+		return notifyFunc(lookupTime, srcAddr, srcIdentity, protocol, dstAddr, msg)
+	}
+
+	dnsProxy, err := dnsproxy.StartDNSProxy("", port, lookupDNSOriginalDst(port), dialDNSOriginalDst,
+		p.checkDNSAllowed, notifyAndLog)
+	if err != nil {
+		return err
+	}
+
+	p.allocatedPorts[dnsProxy.BindPort] = struct{}{}
+	p.dnsProxy = dnsProxy
+
+	return nil
+}
+
+// lookupDNSOriginalDst returns a function looking up the original destination
+// of DNS requests redirected to proxyPort.
+func lookupDNSOriginalDst(proxyPort uint16) dnsproxy.LookupOriginalDstFunc {
+	return func(remoteAddr net.Addr, protocol string) (uint32, string, error) {
+		nexthdr := u8proto.UDP
+		if protocol == "tcp" {
+			nexthdr = u8proto.TCP
+		}
+
+		return lookupNewDestProto(remoteAddr.String(), proxyPort, nexthdr)
+	}
+}
+
+// dialDNSOriginalDst connects to the original destination of a DNS request
+// with the socket marked as egress proxy traffic from srcIdentity.
+func dialDNSOriginalDst(srcIdentity uint32, protocol, dstAddr string) (net.Conn, error) {
+	return ciliumDialer(getMagicMark(false, int(srcIdentity)), protocol, dstAddr)
+}
+
+// findDNSRedirect returns the DNS redirect of the endpoint with the address
+// srcAddr for the protocol and destination port of a redirected request.
+func (p *Proxy) findDNSRedirect(srcAddr, protocol, dstAddr string) (*dnsRedirect, error) {
+	srcIP, _, err := net.SplitHostPort(srcAddr)
+	if err != nil {
+		return nil, err
+	}
+	_, dstPortStr, err := net.SplitHostPort(dstAddr)
+	if err != nil {
+		return nil, err
+	}
+	dstPort, err := strconv.ParseUint(dstPortStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the candidates first, the endpoint lock must not be taken
+	// while holding the proxy mutex.
+	candidates := []*Redirect{}
+	p.mutex.RLock()
+	for id, r := range p.redirects {
+		if r.parserType != policy.ParserTypeDNS || r.ingress {
+			continue
+		}
+		_, _, proto, port, err := policy.ParseProxyID(id)
+		if err != nil || uint64(port) != dstPort || !strings.EqualFold(proto, protocol) {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	p.mutex.RUnlock()
+
+	for _, r := range candidates {
+		r.localEndpoint.UnconditionalRLock()
+		matches := r.localEndpoint.GetIPv4Address() == srcIP || r.localEndpoint.GetIPv6Address() == srcIP
+		r.localEndpoint.RUnlock()
+
+		if dr, ok := r.implementation.(*dnsRedirect); matches && ok {
+			return dr, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no DNS redirect for %s to port %d/%s", srcIP, dstPort, protocol)
+}
+
+// checkDNSAllowed implements dnsproxy.CheckAllowedFunc. A request is allowed
+// when all names in it are allowed by the DNS rules that apply to the DNS
+// server in dstAddr.
+func (p *Proxy) checkDNSAllowed(srcAddr string, srcIdentity uint32, protocol, dstAddr string, request *dns.Msg) (bool, error) {
+	dr, err := p.findDNSRedirect(srcAddr, protocol, dstAddr)
+	if err != nil {
+		return false, err
+	}
+
+	record := dr.newLogRecord(accesslog.TypeRequest, srcAddr, srcIdentity, protocol, dstAddr, request)
+
+	dstIdentity := lookupDNSServerIdentity(dstAddr)
+	for _, question := range request.Question {
+		if !dr.isAllowed(dstIdentity, question.Name) {
+			flowdebug.Log(log.WithFields(logrus.Fields{
+				logfields.DNSName:  question.Name,
+				logfields.Identity: srcIdentity,
+			}), "DNS request is denied by policy")
+			record.log(accesslog.VerdictDenied, "DNS request is denied by policy")
+			return false, nil
+		}
+	}
+
+	record.log(accesslog.VerdictForwarded, "")
+	return true, nil
+}
+
+// lookupDNSServerIdentity returns the identity of the DNS server in dstAddr.
+// Addresses unknown to the ipcache are outside of the cluster.
+func lookupDNSServerIdentity(dstAddr string) *identity.Identity {
+	id := identity.ReservedIdentityWorld
+	if dstIP, _, err := net.SplitHostPort(dstAddr); err == nil {
+		if cached, exists := ipcache.IPIdentityCache.LookupByIP(dstIP); exists {
+			id = cached.ID
+		}
+	}
+
+	return identity.LookupIdentityByID(id)
+}
+
+// dnsRuleToRegexp returns the regexp matching the DNS names allowed by rule.
+func dnsRuleToRegexp(rule api.PortRuleDNS) (*regexp.Regexp, error) {
+	if rule.MatchPattern != "" {
+		return matchpattern.Validate(rule.MatchPattern)
+	}
+
+	return regexp.Compile("(?i)^" + regexp.QuoteMeta(dns.Fqdn(strings.TrimSpace(rule.MatchName))) + "$")
+}
+
+// createDNSRedirect creates a redirect to the DNS proxy. The redirect structure
+// passed in is safe to access for reading and writing.
+func createDNSRedirect(r *Redirect, dnsProxy *dnsproxy.DNSProxy) (RedirectImplementation, error) {
+	if dnsProxy == nil {
+		return nil, fmt.Errorf("%s: DNS proxy is not running, cannot add redirect", r.id)
+	}
+
+	if r.ingress {
+		return nil, fmt.Errorf("%s: DNS proxy only supports egress redirects", r.id)
+	}
+
+	r.ProxyPort = dnsProxy.BindPort
+
+	dr := &dnsRedirect{redirect: r}
+	if err := dr.UpdateRules(nil); err != nil {
+		return nil, err
+	}
+
+	return dr, nil
+}
+
+// UpdateRules compiles the DNS rules of the redirect. Redirect.mutex must be
+// held.
+func (dr *dnsRedirect) UpdateRules(wg *completion.WaitGroup) error {
+	allowed := make(map[api.EndpointSelector][]*regexp.Regexp, len(dr.redirect.rules))
+	for selector, rules := range dr.redirect.rules {
+		for _, rule := range rules.DNS {
+			re, err := dnsRuleToRegexp(rule)
+			if err != nil {
+				return fmt.Errorf("%s: invalid DNS rule %+v: %s", dr.redirect.id, rule, err)
+			}
+			allowed[selector] = append(allowed[selector], re)
+		}
+	}
+	dr.allowed = allowed
+
+	return nil
+}
+
+// Close is a no-op, the DNS proxy is shared by all DNS redirects and keeps
+// running.
+func (dr *dnsRedirect) Close(wg *completion.WaitGroup) {
+}
+
+// isAllowed returns whether name may be looked up via a DNS server with the
+// identity dstIdentity.
+func (dr *dnsRedirect) isAllowed(dstIdentity *identity.Identity, name string) bool {
+	var dstLabels labels.LabelArray
+	if dstIdentity != nil {
+		dstLabels = dstIdentity.Labels.LabelArray()
+	}
+
+	dr.redirect.mutex.RLock()
+	defer dr.redirect.mutex.RUnlock()
+
+	for selector, regexps := range dr.allowed {
+		if !selector.Matches(dstLabels) {
+			continue
+		}
+		for _, re := range regexps {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// dnsLogRecord wraps a logger.LogRecord so that we can define methods with a
+// receiver
+type dnsLogRecord struct {
+	*logger.LogRecord
+	localEndpoint logger.EndpointUpdater
+}
+
+// newLogRecord returns a log record for msg, the DNS request or response
+// proxied from srcAddr to dstAddr.
+func (dr *dnsRedirect) newLogRecord(t accesslog.FlowType, srcAddr string, srcIdentity uint32, protocol, dstAddr string, msg *dns.Msg) dnsLogRecord {
+	record := &accesslog.LogRecordDNS{
+		RCode: msg.Rcode,
+	}
+	for _, question := range msg.Question {
+		record.QTypes = append(record.QTypes, question.Qtype)
+	}
+	if len(msg.Question) > 0 {
+		record.Query = msg.Question[0].Name
+	}
+	if t == accesslog.TypeResponse {
+		if _, IPs, TTL, err := dnsproxy.ExtractMsgDetails(msg); err == nil {
+			record.IPs = IPs
+			record.TTL = TTL
+		}
+	}
+
+	lr := logger.NewLogRecord(DefaultEndpointInfoRegistry, dr.redirect.localEndpoint, t, false,
+		logger.LogTags.DNS(record),
+		logger.LogTags.Addressing(logger.AddressingInfo{
+			SrcIPPort:   srcAddr,
+			DstIPPort:   dstAddr,
+			SrcIdentity: srcIdentity,
+		}))
+	if protocol == "udp" {
+		lr.TransportProtocol = accesslog.TransportProtocol(u8proto.UDP)
+	}
+
+	return dnsLogRecord{
+		LogRecord:     lr,
+		localEndpoint: dr.redirect.localEndpoint,
+	}
+}
+
+// log logs the record with verdict and updates the proxy statistics of the
+// endpoint.
+func (l *dnsLogRecord) log(verdict accesslog.FlowVerdict, info string) {
+	l.ApplyTags(logger.LogTags.Verdict(verdict, info))
+	l.Log()
+
+	port := l.DestinationEndpoint.Port
+	if port == 0 {
+		// Something went wrong when identifying the endpoints.
+		// Ignore in order to avoid polluting the stats.
+		return
+	}
+	request := l.Type == accesslog.TypeRequest
+	l.localEndpoint.UpdateProxyStatistics("dns", port, false, request, l.Verdict)
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"github.com/cilium/cilium/pkg/fqdn/dnsproxy"
+	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/policy"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	. "gopkg.in/check.v1"
+)
+
+func (k *proxyTestSuite) TestDNSRedirectRules(c *C) {
+	dnsServerSelector := api.NewESFromLabels(labels.ParseSelectLabel("dns-server"))
+
+	r := newRedirect(localEndpointMock, policy.ProxyID(1000, false, "UDP", 53))
+	r.rules = policy.L7DataMap{
+		api.WildcardEndpointSelector: api.L7Rules{
+			DNS: []api.PortRuleDNS{{MatchName: "cilium.io"}},
+		},
+		dnsServerSelector: api.L7Rules{
+			DNS: []api.PortRuleDNS{{MatchPattern: "*.github.com"}},
+		},
+	}
+
+	_, err := createDNSRedirect(r, nil)
+	c.Assert(err, Not(IsNil))
+
+	redir, err := createDNSRedirect(r, &dnsproxy.DNSProxy{BindPort: 10053})
+	c.Assert(err, IsNil)
+	c.Assert(r.ProxyPort, Equals, uint16(10053))
+	dr := redir.(*dnsRedirect)
+
+	dnsServer := identity.NewIdentity(1234, labels.NewLabelsFromModel([]string{"dns-server"}))
+
+	// The wildcard rule applies to all DNS servers
+	c.Assert(dr.isAllowed(nil, "cilium.io."), Equals, true)
+	c.Assert(dr.isAllowed(dnsServer, "CILIUM.io."), Equals, true)
+	c.Assert(dr.isAllowed(nil, "www.cilium.io."), Equals, false)
+
+	// Patterns only apply to the selected DNS servers
+	c.Assert(dr.isAllowed(dnsServer, "api.github.com."), Equals, true)
+	c.Assert(dr.isAllowed(nil, "api.github.com."), Equals, false)
+	c.Assert(dr.isAllowed(dnsServer, "github.com."), Equals, false)
+
+	// Updated rules replace the previous ones
+	r.rules = policy.L7DataMap{
+		api.WildcardEndpointSelector: api.L7Rules{
+			DNS: []api.PortRuleDNS{{MatchPattern: "*"}},
+		},
+	}
+	c.Assert(dr.UpdateRules(nil), IsNil)
+	c.Assert(dr.isAllowed(nil, "www.cilium.io."), Equals, true)
+}
+
+func (k *proxyTestSuite) TestFindDNSRedirect(c *C) {
+	id := policy.ProxyID(1000, false, "UDP", 53)
+	r := newRedirect(localEndpointMock, id)
+	r.parserType = policy.ParserTypeDNS
+	redir, err := createDNSRedirect(r, &dnsproxy.DNSProxy{BindPort: 10053})
+	c.Assert(err, IsNil)
+	r.implementation = redir
+
+	p := &Proxy{redirects: map[string]*Redirect{id: r}}
+
+	dr, err := p.findDNSRedirect("10.0.0.1:34567", "udp", "8.8.8.8:53")
+	c.Assert(err, IsNil)
+	c.Assert(dr, Equals, redir)
+
+	dr, err = p.findDNSRedirect("[f00d::1]:34567", "udp", "[2001:4860:4860::8888]:53")
+	c.Assert(err, IsNil)
+	c.Assert(dr, Equals, redir)
+
+	// Other protocols, ports and endpoints have no redirect
+	_, err = p.findDNSRedirect("10.0.0.1:34567", "tcp", "8.8.8.8:53")
+	c.Assert(err, Not(IsNil))
+	_, err = p.findDNSRedirect("10.0.0.1:34567", "udp", "8.8.8.8:5353")
+	c.Assert(err, Not(IsNil))
+	_, err = p.findDNSRedirect("10.0.0.2:34567", "udp", "8.8.8.8:53")
+	c.Assert(err, Not(IsNil))
+}
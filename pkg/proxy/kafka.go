@@ -173,6 +173,7 @@ func (k *kafkaRedirect) newLogRecordFromRequest(req *kafka.RequestMessage) kafka
 				APIVersion:    req.GetVersion(),
 				APIKey:        apiKeyToString(req.GetAPIKey()),
 				CorrelationID: int32(req.GetCorrelationID()),
+				ConsumerGroup: req.GetConsumerGroup(),
 			})),
 		localEndpoint: k.redirect.localEndpoint,
 		topics:        req.GetTopics(),
@@ -193,6 +194,7 @@ func (k *kafkaRedirect) newLogRecordFromResponse(res *kafka.ResponseMessage, req
 	if req != nil {
 		lr.Kafka.APIVersion = req.GetVersion()
 		lr.Kafka.APIKey = apiKeyToString(req.GetAPIKey())
+		lr.Kafka.ConsumerGroup = req.GetConsumerGroup()
 		lr.topics = req.GetTopics()
 	}
 
@@ -209,6 +211,10 @@ func (l *kafkaLogRecord) log(verdict accesslog.FlowVerdict, code int, info strin
 		l.Kafka.Topic.Topic = t
 		l.Log()
 	}
+	// Requests without topics, e.g. JoinGroup, are logged once
+	if len(l.topics) == 0 && l.Kafka.ConsumerGroup != "" {
+		l.Log()
+	}
 
 	// Update stats for the endpoint.
 	// Count only one request.
@@ -242,6 +248,28 @@ func (k *kafkaRedirect) handleRequest(pair *connectionPair, req *kafka.RequestMe
 		SrcIdentity: remoteIdentity,
 	}))
 
+	if !req.IsSupportedVersion() {
+		flowdebug.Log(scopedLog, "Kafka request version is not supported")
+
+		resp, err := req.CreateUnsupportedVersionResponse()
+		if err != nil {
+			// Like Kafka brokers do, close the connection if the
+			// response layout of the request version is unknown
+			// so that the client does not wait for a response.
+			record.log(accesslog.VerdictDenied,
+				kafka.ErrUnsupportedVersion, fmt.Sprintf("Kafka request version is not supported: %s", err))
+			scopedLog.WithError(err).Debug("Closing Kafka connection on unsupported request version")
+			pair.Rx.Close()
+			return
+		}
+
+		record.log(accesslog.VerdictDenied,
+			kafka.ErrUnsupportedVersion, fmt.Sprint("Kafka request version is not supported"))
+
+		pair.Rx.Enqueue(resp.GetRaw())
+		return
+	}
+
 	if !k.canAccess(req, identity.NumericIdentity(remoteIdentity)) {
 		flowdebug.Log(scopedLog, "Kafka request is denied by policy")
 
@@ -390,12 +418,23 @@ func (k *kafkaRedirect) handleResponses(done <-chan struct{}, pair *connectionPa
 		//    correlation id as expected
 		req := correlationCache.CorrelateResponse(rsp)
 
+		// Keep clients from negotiating request versions which the
+		// proxy is not able to decode
+		if req != nil && req.GetAPIKey() == api.APIVersionsKey {
+			if err := rsp.LimitAPIVersions(req.GetVersion()); err != nil {
+				scopedLog.WithError(err).Warning("Unable to limit versions in Kafka ApiVersions response")
+			}
+		}
+
 		record := k.newLogRecordFromResponse(rsp, req)
 		record.ApplyTags(logger.LogTags.Addressing(logger.AddressingInfo{
 			SrcIPPort:   remoteAddr.String(),
 			DstIPPort:   origDstAddr,
 			SrcIdentity: remoteIdentity,
 		}))
+		if req != nil && !req.GetForwardedTime().IsZero() {
+			record.ApplyTags(logger.LogTags.Duration(time.Since(req.GetForwardedTime())))
+		}
 		record.log(accesslog.VerdictForwarded, kafka.ErrNone, "")
 
 		handler(pair, rsp)
@@ -15,7 +15,10 @@
 package proxy
 
 import (
-	"fmt"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -179,7 +182,7 @@ func (k *proxyTestSuite) TestKafkaRedirect(c *C) {
 		"address": server.Address(),
 	}).Debug("Started kafka server")
 
-	proxyAddress := fmt.Sprintf("%s:%d", proxyAddress, uint16(proxyPort))
+	proxyAddress := net.JoinHostPort(proxyAddress, strconv.Itoa(proxyPort))
 
 	kafkaRule1 := api.PortRuleKafka{APIKey: "metadata", APIVersion: "0"}
 	c.Assert(kafkaRule1.Sanitize(), IsNil)
@@ -257,6 +260,24 @@ func (k *proxyTestSuite) TestKafkaRedirect(c *C) {
 	_, err = producer.Produce("disallowedTopic", 0, messages...)
 	c.Assert(err, Equals, proto.ErrTopicAuthorizationFailed)
 
+	// send a Metadata request of a version the proxy cannot create a
+	// response for, the proxy closes the connection
+	conn, err := net.Dial("tcp", proxyAddress)
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint32(req[0:], 16)
+	binary.BigEndian.PutUint16(req[4:], uint16(proto.MetadataReqKind))
+	binary.BigEndian.PutUint16(req[6:], 99)
+	binary.BigEndian.PutUint32(req[8:], 1)
+	binary.BigEndian.PutUint16(req[12:], 6)
+	copy(req[14:], "client")
+	_, err = conn.Write(req)
+	c.Assert(err, IsNil)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	c.Assert(err, Equals, io.EOF)
+
 	log.Debug("Testing done, closing listen socket")
 	redir.Close(nil)
 
@@ -55,6 +55,14 @@ const (
 	FieldMessage  = "message"
 )
 
+// fields used for structured logging of DNS messages
+const (
+	FieldDNSQuery  = "dnsQuery"
+	FieldDNSIPs    = "dnsIPs"
+	FieldDNSTTL    = "dnsTTL"
+	FieldDNSQTypes = "dnsQTypes"
+)
+
 // fields used for structured logging of Kafka messages
 const (
 	FieldKafkaAPIKey        = "kafkaApiKey"
@@ -74,6 +82,15 @@ type LogRecord struct {
 	// either sent the request (for egress) or is receiving the request
 	// (for ingress)
 	localEndpointInfo *accesslog.EndpointInfo
+
+	// duration is the time from forwarding the request until receiving
+	// the response, only known for some responses
+	duration time.Duration
+
+	// metricsUpdated is true once the record has been accounted in the
+	// metrics, so that records logged multiple times, e.g. once per Kafka
+	// topic, are only counted once
+	metricsUpdated bool
 }
 
 // NewLogRecord creates a new log record and applies optional tags
@@ -218,6 +235,14 @@ func (logTags) Timestamp(ts time.Time) LogTag {
 	}
 }
 
+// Duration attaches the time from forwarding the request until receiving the
+// response to a response log record
+func (logTags) Duration(d time.Duration) LogTag {
+	return func(lr *LogRecord) {
+		lr.duration = d
+	}
+}
+
 // AddressingInfo is the information passed in via the Addressing() tag
 type AddressingInfo struct {
 	SrcIPPort   string
@@ -279,6 +304,13 @@ func (logTags) Kafka(k *accesslog.LogRecordKafka) LogTag {
 	}
 }
 
+// DNS attaches DNS information to the log record
+func (logTags) DNS(d *accesslog.LogRecordDNS) LogTag {
+	return func(lr *LogRecord) {
+		lr.DNS = d
+	}
+}
+
 // L7 attaches generic L7 information to the log record
 func (logTags) L7(h *accesslog.LogRecordL7) LogTag {
 	return func(lr *LogRecord) {
@@ -322,6 +354,16 @@ func (lr *LogRecord) getLogFields() *logrus.Entry {
 		})
 	}
 
+	if lr.DNS != nil {
+		fields = fields.WithFields(logrus.Fields{
+			FieldCode:      lr.DNS.RCode,
+			FieldDNSQuery:  lr.DNS.Query,
+			FieldDNSIPs:    lr.DNS.IPs,
+			FieldDNSTTL:    lr.DNS.TTL,
+			FieldDNSQTypes: lr.DNS.QTypes,
+		})
+	}
+
 	return fields
 }
 
@@ -338,6 +380,11 @@ func (lr *LogRecord) getRawLogMessage() []byte {
 func (lr *LogRecord) Log() {
 	flowdebug.Log(lr.getLogFields(), "Logging flow record")
 
+	if !lr.metricsUpdated {
+		lr.metricsUpdated = true
+		lr.updateMetrics()
+	}
+
 	// Lock while writing access log so we serialize writes as we may have
 	// to reopen the logfile and parallel writes could fail because of that
 	logMutex.Lock()
@@ -0,0 +1,149 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
+)
+
+var (
+	metricsMutex lock.RWMutex
+
+	// metricsLabels is the set of labels of metrics.ProxyL7Labels which
+	// are given a value, all other labels are left empty
+	metricsLabels = allMetricsLabels()
+)
+
+func allMetricsLabels() map[string]bool {
+	labels := make(map[string]bool, len(metrics.ProxyL7Labels))
+	for _, label := range metrics.ProxyL7Labels {
+		labels[label] = true
+	}
+	return labels
+}
+
+// SetMetricsLabels restricts the labels of the L7 proxy request metrics to
+// the given labels. Leaving out labels such as the security identities
+// reduces the cardinality of the metrics.
+func SetMetricsLabels(labels []string) error {
+	enabled := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		found := false
+		for _, l := range metrics.ProxyL7Labels {
+			if label == l {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown L7 proxy metrics label %q, supported labels are %s",
+				label, strings.Join(metrics.ProxyL7Labels, ","))
+		}
+		enabled[label] = true
+	}
+
+	metricsMutex.Lock()
+	metricsLabels = enabled
+	metricsMutex.Unlock()
+	return nil
+}
+
+// l7Proto returns the name of the L7 protocol of the record
+func (lr *LogRecord) l7Proto() string {
+	switch {
+	case lr.HTTP != nil:
+		return "http"
+	case lr.Kafka != nil:
+		return "kafka"
+	case lr.DNS != nil:
+		return "dns"
+	case lr.L7 != nil:
+		return lr.L7.Proto
+	}
+	return ""
+}
+
+// l7Status returns the status of a response, or the reason of denying a
+// request, as a label value
+func (lr *LogRecord) l7Status() string {
+	switch {
+	case lr.HTTP != nil:
+		if lr.HTTP.Code != 0 {
+			return strconv.Itoa(lr.HTTP.Code)
+		}
+	case lr.Kafka != nil:
+		return strconv.Itoa(lr.Kafka.ErrorCode)
+	case lr.DNS != nil:
+		return strconv.Itoa(lr.DNS.RCode)
+	}
+	return ""
+}
+
+// metricsLabelValues returns the values of metrics.ProxyL7Labels for the
+// record, leaving disabled labels empty
+func (lr *LogRecord) metricsLabelValues() []string {
+	metricsMutex.RLock()
+	defer metricsMutex.RUnlock()
+
+	values := make([]string, len(metrics.ProxyL7Labels))
+	for i, label := range metrics.ProxyL7Labels {
+		if !metricsLabels[label] {
+			continue
+		}
+		switch label {
+		case metrics.LabelProtocolL7:
+			values[i] = lr.l7Proto()
+		case metrics.LabelDirection:
+			values[i] = strings.ToLower(string(lr.ObservationPoint))
+		case metrics.LabelVerdict:
+			values[i] = strings.ToLower(string(lr.Verdict))
+		case metrics.LabelStatus:
+			values[i] = lr.l7Status()
+		case metrics.LabelKafkaAPIKey:
+			if lr.Kafka != nil {
+				values[i] = lr.Kafka.APIKey
+			}
+		case metrics.LabelSourceIdentity:
+			values[i] = strconv.FormatUint(lr.SourceEndpoint.Identity, 10)
+		case metrics.LabelDestinationIdentity:
+			values[i] = strconv.FormatUint(lr.DestinationEndpoint.Identity, 10)
+		}
+	}
+	return values
+}
+
+// updateMetrics accounts the record in the L7 proxy request metrics. Each
+// request is counted once, either on its response, or on the request if it is
+// not forwarded, e.g. when denied by policy.
+func (lr *LogRecord) updateMetrics() {
+	switch {
+	case lr.Type == accesslog.TypeResponse:
+	case lr.Type == accesslog.TypeRequest && lr.Verdict != accesslog.VerdictForwarded:
+	default:
+		return
+	}
+
+	values := lr.metricsLabelValues()
+	metrics.ProxyL7Requests.WithLabelValues(values...).Inc()
+	if lr.duration > 0 {
+		metrics.ProxyL7RequestDuration.WithLabelValues(values...).Observe(lr.duration.Seconds())
+	}
+}
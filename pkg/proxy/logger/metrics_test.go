@@ -0,0 +1,110 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type LoggerSuite struct{}
+
+var _ = Suite(&LoggerSuite{})
+
+func (s *LoggerSuite) TestMetricsLabelValues(c *C) {
+	lr := &LogRecord{
+		LogRecord: accesslog.LogRecord{
+			Type:                accesslog.TypeResponse,
+			ObservationPoint:    accesslog.Ingress,
+			Verdict:             accesslog.VerdictForwarded,
+			SourceEndpoint:      accesslog.EndpointInfo{Identity: 1001},
+			DestinationEndpoint: accesslog.EndpointInfo{Identity: 1002},
+			Kafka:               &accesslog.LogRecordKafka{APIKey: "produce", ErrorCode: 0},
+		},
+	}
+	c.Assert(lr.metricsLabelValues(), DeepEquals,
+		[]string{"kafka", "ingress", "forwarded", "0", "produce", "1001", "1002"})
+
+	err := SetMetricsLabels([]string{metrics.LabelProtocolL7, metrics.LabelVerdict, metrics.LabelStatus})
+	c.Assert(err, IsNil)
+	defer SetMetricsLabels(metrics.ProxyL7Labels)
+
+	lr.Kafka = nil
+	lr.HTTP = &accesslog.LogRecordHTTP{Code: 403}
+	lr.Verdict = accesslog.VerdictDenied
+	c.Assert(lr.metricsLabelValues(), DeepEquals,
+		[]string{"http", "", "denied", "403", "", "", ""})
+
+	c.Assert(SetMetricsLabels([]string{"path"}), Not(IsNil))
+}
+
+func (s *LoggerSuite) TestUpdateMetrics(c *C) {
+	lr := &LogRecord{
+		LogRecord: accesslog.LogRecord{
+			Type:                accesslog.TypeRequest,
+			ObservationPoint:    accesslog.Egress,
+			Verdict:             accesslog.VerdictForwarded,
+			SourceEndpoint:      accesslog.EndpointInfo{Identity: 2001},
+			DestinationEndpoint: accesslog.EndpointInfo{Identity: 2002},
+			HTTP:                &accesslog.LogRecordHTTP{},
+		},
+	}
+	counter := metrics.ProxyL7Requests.WithLabelValues("http", "egress", "forwarded", "200", "", "2001", "2002")
+
+	// Forwarded requests are counted on their response
+	lr.updateMetrics()
+	c.Assert(metrics.GetCounterValue(counter), Equals, float64(0))
+
+	lr.Type = accesslog.TypeResponse
+	lr.HTTP.Code = 200
+	lr.updateMetrics()
+	c.Assert(metrics.GetCounterValue(counter), Equals, float64(1))
+
+	// Denied requests are counted right away
+	lr.Type = accesslog.TypeRequest
+	lr.Verdict = accesslog.VerdictDenied
+	lr.HTTP.Code = 403
+	lr.updateMetrics()
+	c.Assert(metrics.GetCounterValue(metrics.ProxyL7Requests.WithLabelValues(
+		"http", "egress", "denied", "403", "", "2001", "2002")), Equals, float64(1))
+}
+
+func (s *LoggerSuite) TestLogUpdatesMetricsOnce(c *C) {
+	lr := &LogRecord{
+		LogRecord: accesslog.LogRecord{
+			Type:                accesslog.TypeRequest,
+			ObservationPoint:    accesslog.Ingress,
+			Verdict:             accesslog.VerdictDenied,
+			SourceEndpoint:      accesslog.EndpointInfo{Identity: 3001},
+			DestinationEndpoint: accesslog.EndpointInfo{Identity: 3002},
+			Kafka:               &accesslog.LogRecordKafka{APIKey: "produce"},
+		},
+	}
+	counter := metrics.ProxyL7Requests.WithLabelValues("kafka", "ingress", "denied", "0", "produce", "3001", "3002")
+
+	// Records logged once per Kafka topic are counted once
+	lr.Log()
+	lr.Log()
+	c.Assert(metrics.GetCounterValue(counter), Equals, float64(1))
+}
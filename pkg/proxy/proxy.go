@@ -23,6 +23,7 @@ import (
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/completion"
 	"github.com/cilium/cilium/pkg/envoy"
+	"github.com/cilium/cilium/pkg/fqdn/dnsproxy"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
@@ -80,6 +81,10 @@ type Proxy struct {
 	// the redirect identifier. Redirects may be implemented by different
 	// proxies.
 	redirects map[string]*Redirect
+
+	// dnsProxy is the DNS proxy shared by all DNS redirects. It is nil until
+	// StartDNSProxy is called.
+	dnsProxy *dnsproxy.DNSProxy
 }
 
 // StartProxySupport starts the servers to support L7 proxies: xDS GRPC server
@@ -206,6 +211,22 @@ create:
 	redir.parserType = l4.L7Parser
 	redir.updateRules(l4)
 
+	// All DNS redirects use the port of the already running DNS proxy
+	if l4.L7Parser == policy.ParserTypeDNS {
+		var err error
+		redir.implementation, err = createDNSRedirect(redir, p.dnsProxy)
+		if err != nil {
+			scopedLog.WithError(err).Error("Unable to create ", l4.L7Parser, " proxy")
+			return nil, err
+		}
+
+		scopedLog.WithField(logfields.Object, logfields.Repr(redir)).
+			Debug("Created new ", l4.L7Parser, " proxy instance")
+
+		p.redirects[id] = redir
+		return redir, nil
+	}
+
 retryCreatePort:
 	for nRetry := 0; ; nRetry++ {
 		to, err := p.allocatePort()
@@ -273,6 +294,12 @@ func (p *Proxy) removeRedirect(id string, r *Redirect, wg *completion.WaitGroup)
 
 	delete(p.redirects, id)
 
+	// The port of the DNS proxy is shared by all DNS redirects, it is never
+	// released
+	if r.parserType == policy.ParserTypeDNS {
+		return nil
+	}
+
 	// delay the release and reuse of the port number so it is guaranteed
 	// to be safe to listen on the port again
 	go func() {
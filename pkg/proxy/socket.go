@@ -27,6 +27,7 @@ import (
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/maps/proxymap"
+	"github.com/cilium/cilium/pkg/u8proto"
 
 	"github.com/sirupsen/logrus"
 )
@@ -433,7 +434,13 @@ func (p *connectionPair) close() {
 }
 
 func lookupNewDest(remoteAddr string, dport uint16) (uint32, string, error) {
-	key, err := createProxyMapKey(remoteAddr, dport)
+	return lookupNewDestProto(remoteAddr, dport, u8proto.TCP)
+}
+
+// lookupNewDestProto returns the source identity and the original destination
+// of a connection or datagram of protocol nexthdr redirected to dport.
+func lookupNewDestProto(remoteAddr string, dport uint16, nexthdr u8proto.U8proto) (uint32, string, error) {
+	key, err := createProxyMapKey(remoteAddr, dport, nexthdr)
 	if err != nil {
 		return 0, "", err
 	}
@@ -463,8 +470,11 @@ func setFdMark(fd, mark int) {
 }
 
 func setSocketMark(c net.Conn, mark int) {
-	if tc, ok := c.(*net.TCPConn); ok {
-		if f, err := tc.File(); err == nil {
+	// Both *net.TCPConn and *net.UDPConn provide the underlying file
+	if fc, ok := c.(interface {
+		File() (*os.File, error)
+	}); ok {
+		if f, err := fc.File(); err == nil {
 			defer f.Close()
 			setFdMark(int(f.Fd()), mark)
 		}
@@ -477,10 +487,10 @@ func getProxyMapKey(c net.Conn, proxyPort uint16) (proxymap.ProxyMapKey, error)
 		return nil, fmt.Errorf("RemoteAddr() returned nil")
 	}
 
-	return createProxyMapKey(addr.String(), proxyPort)
+	return createProxyMapKey(addr.String(), proxyPort, u8proto.TCP)
 }
 
-func createProxyMapKey(addr string, proxyPort uint16) (proxymap.ProxyMapKey, error) {
+func createProxyMapKey(addr string, proxyPort uint16, nexthdr u8proto.U8proto) (proxymap.ProxyMapKey, error) {
 	ip, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid remote address '%s': %s", addr, err)
@@ -500,7 +510,7 @@ func createProxyMapKey(addr string, proxyPort uint16) (proxymap.ProxyMapKey, err
 		key := proxymap.Proxy4Key{
 			SPort:   uint16(sport),
 			DPort:   proxyPort,
-			Nexthdr: 6,
+			Nexthdr: uint8(nexthdr),
 		}
 
 		copy(key.SAddr[:], pIP.To4())
@@ -510,7 +520,7 @@ func createProxyMapKey(addr string, proxyPort uint16) (proxymap.ProxyMapKey, err
 	key := proxymap.Proxy6Key{
 		SPort:   uint16(sport),
 		DPort:   proxyPort,
-		Nexthdr: 6,
+		Nexthdr: uint8(nexthdr),
 	}
 
 	copy(key.SAddr[:], pIP.To16())
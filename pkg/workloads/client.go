@@ -15,6 +15,9 @@
 package workloads
 
 import (
+	"context"
+	"errors"
+
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/endpoint"
 )
@@ -50,6 +53,15 @@ func IsRunning(ep *endpoint.Endpoint) bool {
 	return Client().IsRunning(ep)
 }
 
+// RunningWorkloadIDs returns the IDs of all workloads running in the workload
+// runtime. An error is returned if no runtime is available.
+func RunningWorkloadIDs(ctx context.Context) ([]string, error) {
+	if Client() == nil {
+		return nil, errors.New("no workload runtime available")
+	}
+	return Client().workloadIDsList(ctx)
+}
+
 // Status returns the status of the workload runtime
 func Status() *models.Status {
 	if Client() == nil {
@@ -336,7 +336,7 @@ func (c *criClient) IgnoreRunningWorkloads() {
 		if cIP == nil {
 			continue
 		}
-		if err := ipam.AllocateIP(cIP.IP()); err != nil {
+		if err := ipam.AllocateIP(cIP.IP(), pod.GetId()); err != nil {
 			continue
 		}
 		//TODO Release this address when the ignored container leaves
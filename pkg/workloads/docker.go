@@ -527,7 +527,7 @@ func (d *dockerClient) IgnoreRunningWorkloads() {
 		if cIP == nil {
 			continue
 		}
-		if err := ipam.AllocateIP(cIP.IP()); err != nil {
+		if err := ipam.AllocateIP(cIP.IP(), cont.ID); err != nil {
 			continue
 		}
 		// TODO Release this address when the ignored container leaves
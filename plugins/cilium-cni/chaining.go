@@ -0,0 +1,170 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	endpointid "github.com/cilium/cilium/pkg/endpoint/id"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/uuid"
+
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/containernetworking/cni/pkg/skel"
+	cniTypesVer "github.com/containernetworking/cni/pkg/types/current"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// chainedAddressing returns the addresses assigned to the container
+// interface ifName by the previous plugin in the chain. The first IPv4 and
+// the first IPv6 address are used, either of them may be missing.
+func chainedAddressing(prevResult *cniTypesVer.Result, ifName string) (*models.AddressPair, error) {
+	addr := &models.AddressPair{}
+	for _, ipConfig := range interfaceIPs(prevResult, ifName) {
+		ip := ipConfig.Address.IP
+		if ip.To4() != nil {
+			if addr.IPV4 == "" {
+				addr.IPV4 = ip.String()
+			}
+		} else if addr.IPV6 == "" {
+			addr.IPV6 = ip.String()
+		}
+	}
+
+	if addr.IPV4 == "" && addr.IPV6 == "" {
+		return nil, fmt.Errorf("prevResult does not provide any address for interface %q", ifName)
+	}
+
+	return addr, nil
+}
+
+// chainedInterfaces returns the container interface ifName and the host side
+// of its veth pair as created by the previous plugin in the chain. Must be
+// called in the host network namespace.
+func chainedInterfaces(netNs ns.NetNS, ifName string) (netlink.Link, netlink.Link, error) {
+	var containerLink netlink.Link
+
+	err := netNs.Do(func(_ ns.NetNS) error {
+		var err error
+		containerLink, err = netlink.LinkByName(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to lookup %q: %s", ifName, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if containerLink.Type() != "veth" {
+		return nil, nil, fmt.Errorf("interface %q is of type %s, chaining requires a veth",
+			ifName, containerLink.Type())
+	}
+
+	hostLink, err := netlink.LinkByIndex(containerLink.Attrs().ParentIndex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lookup host side of %q: %s", ifName, err)
+	}
+
+	if hostLink.Type() != "veth" || hostLink.Attrs().ParentIndex != containerLink.Attrs().Index {
+		return nil, nil, fmt.Errorf("interface %s is not the veth peer of %q",
+			hostLink.Attrs().Name, ifName)
+	}
+
+	return containerLink, hostLink, nil
+}
+
+// cmdAddChained creates an endpoint for the interface and addresses set up
+// by the previous plugin in the chain. No addresses are allocated and the
+// interface is not modified apart from attaching the BPF programs to its
+// host side.
+func cmdAddChained(args *skel.CmdArgs, n *netConf, cniVersion string) error {
+	logger := log.WithField("eventUUID", uuid.NewUUID())
+	logger.WithField("args", args).Debug("Processing chained CNI ADD request")
+
+	if n.PrevResult == nil {
+		return fmt.Errorf("chained mode requires the result of a previous plugin")
+	}
+
+	addr, err := chainedAddressing(n.PrevResult, args.IfName)
+	if err != nil {
+		return err
+	}
+
+	client, err := client.NewDefaultClient()
+	if err != nil {
+		return fmt.Errorf("unable to connect to Cilium daemon: %s", err)
+	}
+
+	netNs, err := ns.GetNS(args.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %s", args.Netns, err)
+	}
+	defer netNs.Close()
+
+	containerLink, hostLink, err := chainedInterfaces(netNs, args.IfName)
+	if err != nil {
+		return err
+	}
+
+	// The endpoint ID is allocated by the agent as the addresses have not
+	// been allocated by Cilium
+	ep := &models.EndpointChangeRequest{
+		ContainerID:       args.ContainerID,
+		Labels:            mesosLabels(n),
+		State:             models.EndpointStateWaitingForIdentity,
+		Addressing:        addr,
+		InterfaceName:     hostLink.Attrs().Name,
+		InterfaceIndex:    int64(hostLink.Attrs().Index),
+		Mac:               containerLink.Attrs().HardwareAddr.String(),
+		HostMac:           hostLink.Attrs().HardwareAddr.String(),
+		SyncBuildEndpoint: true,
+		Chained:           true,
+	}
+
+	if err = client.EndpointCreate(ep); err != nil {
+		logger.WithError(err).WithFields(logrus.Fields{
+			logfields.ContainerID: ep.ContainerID}).Warn("Unable to create endpoint")
+		return fmt.Errorf("Unable to create endpoint: %s", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		logfields.ContainerID: ep.ContainerID,
+		logfields.Interface:   ep.InterfaceName}).Debug("Endpoint successfully created on chained interface")
+	return printResult(n.PrevResult, cniVersion)
+}
+
+// cmdDelChained deletes the endpoint of a chained container. The addresses
+// and interfaces are owned by the previous plugin in the chain and are left
+// for it to release. The agent detaches the BPF programs from the host side
+// when deleting the endpoint.
+func cmdDelChained(args *skel.CmdArgs, client *client.Client) error {
+	log.WithField("args", args).Debug("Processing chained CNI DEL request")
+
+	id := endpointid.NewID(endpointid.ContainerIdPrefix, args.ContainerID)
+	if ep, err := client.EndpointGet(id); err != nil || ep == nil {
+		// Ignore endpoints not found
+		log.WithError(err).WithField(logfields.EndpointID, id).Debug("Agent is not aware of endpoint")
+		return nil
+	}
+
+	if err := client.EndpointDelete(id); err != nil {
+		log.WithError(err).Warn("Deletion of endpoint failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// +build privileged_tests
+
+package main
+
+import (
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/vishvananda/netlink"
+	. "gopkg.in/check.v1"
+)
+
+func (s *CNISuite) TestChainedInterfaces(c *C) {
+	netNs, err := ns.NewNS()
+	c.Assert(err, IsNil)
+	defer netNs.Close()
+
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: "cilium-test-h"},
+		PeerName:  "cilium-test-c",
+	}
+	c.Assert(netlink.LinkAdd(veth), IsNil)
+	defer netlink.LinkDel(veth)
+
+	peer, err := netlink.LinkByName("cilium-test-c")
+	c.Assert(err, IsNil)
+	c.Assert(netlink.LinkSetNsFd(peer, int(netNs.Fd())), IsNil)
+
+	containerLink, hostLink, err := chainedInterfaces(netNs, "cilium-test-c")
+	c.Assert(err, IsNil)
+	c.Assert(containerLink.Attrs().Name, Equals, "cilium-test-c")
+	c.Assert(hostLink.Attrs().Name, Equals, "cilium-test-h")
+
+	// Only veth pairs are supported
+	_, _, err = chainedInterfaces(netNs, "lo")
+	c.Assert(err, Not(IsNil))
+
+	_, _, err = chainedInterfaces(netNs, "missing0")
+	c.Assert(err, Not(IsNil))
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+
+	cniTypesVer "github.com/containernetworking/cni/pkg/types/current"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type CNISuite struct{}
+
+var _ = Suite(&CNISuite{})
+
+func ipConfig(iface int, addr string) *cniTypesVer.IPConfig {
+	ip, ipNet, _ := net.ParseCIDR(addr)
+	ipNet.IP = ip
+	version := "4"
+	if ip.To4() == nil {
+		version = "6"
+	}
+	return &cniTypesVer.IPConfig{
+		Version:   version,
+		Interface: iface,
+		Address:   *ipNet,
+	}
+}
+
+func (s *CNISuite) TestChainedAddressing(c *C) {
+	prevResult := &cniTypesVer.Result{
+		Interfaces: []*cniTypesVer.Interface{
+			{Name: "cni0"},
+			{Name: "veth1234"},
+			{Name: "eth0", Sandbox: "/var/run/netns/pod"},
+		},
+		IPs: []*cniTypesVer.IPConfig{
+			ipConfig(0, "10.22.0.1/16"),
+			ipConfig(2, "10.22.0.5/16"),
+			ipConfig(2, "10.22.0.6/16"),
+		},
+	}
+
+	// IPv4 only, as assigned by the bridge and ptp plugins by default
+	addr, err := chainedAddressing(prevResult, "eth0")
+	c.Assert(err, IsNil)
+	c.Assert(addr, DeepEquals, &models.AddressPair{IPV4: "10.22.0.5"})
+
+	prevResult.IPs = append(prevResult.IPs, ipConfig(2, "fd00:22::5/64"))
+	addr, err = chainedAddressing(prevResult, "eth0")
+	c.Assert(err, IsNil)
+	c.Assert(addr, DeepEquals, &models.AddressPair{IPV4: "10.22.0.5", IPV6: "fd00:22::5"})
+
+	// Addresses of host interfaces are ignored
+	_, err = chainedAddressing(prevResult, "cni0")
+	c.Assert(err, Not(IsNil))
+	_, err = chainedAddressing(prevResult, "eth1")
+	c.Assert(err, Not(IsNil))
+}
@@ -0,0 +1,352 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	endpointapi "github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	endpointid "github.com/cilium/cilium/pkg/endpoint/id"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	cniTypesVer "github.com/containernetworking/cni/pkg/types/current"
+	"github.com/vishvananda/netlink"
+)
+
+// Error codes returned by the CHECK command. Codes below 100 are defined by
+// the CNI spec, a container runtime is expected to tear down and recreate the
+// sandbox on any error.
+const (
+	errCodeUnknownContainer     uint = 3
+	errCodeInvalidEnvironment   uint = 4
+	errCodeDecodingFailure      uint = 6
+	errCodeInvalidNetworkConfig uint = 7
+	errCodeTryAgainLater        uint = 11
+
+	errCodeGeneric           uint = 100
+	errCodeInterfaceMismatch uint = 101
+	errCodeAddressMismatch   uint = 102
+	errCodeRouteMismatch     uint = 103
+	errCodeEndpointMismatch  uint = 104
+)
+
+func newCheckError(code uint, msg string, format string, args ...interface{}) *cniTypes.Error {
+	return &cniTypes.Error{
+		Code:    code,
+		Msg:     msg,
+		Details: fmt.Sprintf(format, args...),
+	}
+}
+
+// checkMain reads the arguments of the CHECK command from the environment
+// and stdin and runs cmdCheck
+func checkMain() *cniTypes.Error {
+	args := &skel.CmdArgs{
+		ContainerID: os.Getenv("CNI_CONTAINERID"),
+		Netns:       os.Getenv("CNI_NETNS"),
+		IfName:      os.Getenv("CNI_IFNAME"),
+		Args:        os.Getenv("CNI_ARGS"),
+		Path:        os.Getenv("CNI_PATH"),
+	}
+
+	missing := []string{}
+	for _, v := range []struct {
+		name string
+		val  string
+	}{
+		{"CNI_CONTAINERID", args.ContainerID},
+		{"CNI_NETNS", args.Netns},
+		{"CNI_IFNAME", args.IfName},
+		{"CNI_PATH", args.Path},
+	} {
+		if v.val == "" {
+			missing = append(missing, v.name)
+		}
+	}
+	if len(missing) > 0 {
+		return newCheckError(errCodeInvalidEnvironment, "required env variables missing",
+			"%s", strings.Join(missing, ", "))
+	}
+
+	stdinData, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return newCheckError(errCodeGeneric, "error reading from stdin", "%s", err)
+	}
+	args.StdinData = stdinData
+
+	if err := cmdCheck(args); err != nil {
+		if e, ok := err.(*cniTypes.Error); ok {
+			return e
+		}
+		return &cniTypes.Error{Code: errCodeGeneric, Msg: err.Error()}
+	}
+
+	return nil
+}
+
+// cmdCheck verifies that the networking of a container set up by ADD is
+// still in place: the veth pair, the addresses and routes of the container
+// interface as well as the endpoint in the agent and its identity.
+func cmdCheck(args *skel.CmdArgs) error {
+	log.WithField("args", args).Debug("Processing CNI CHECK request")
+
+	n, cniVersion, err := loadNetConf(args.StdinData)
+	if err != nil {
+		return newCheckError(errCodeDecodingFailure, "failed to load netconf", "%s", err)
+	}
+
+	if cniVersion != cniVersion040 {
+		return newCheckError(cniTypes.ErrIncompatibleCNIVersion, "incompatible CNI versions",
+			"config version %q does not support the CHECK command", cniVersion)
+	}
+
+	if n.PrevResult == nil {
+		return newCheckError(errCodeInvalidNetworkConfig, "invalid network config",
+			"prevResult is required by the CHECK command")
+	}
+
+	c, err := client.NewDefaultClient()
+	if err != nil {
+		return newCheckError(errCodeTryAgainLater, "unable to connect to Cilium daemon", "%s", err)
+	}
+
+	id := endpointid.NewID(endpointid.ContainerIdPrefix, args.ContainerID)
+	ep, err := c.EndpointGet(id)
+	if err != nil {
+		if _, ok := err.(*endpointapi.GetEndpointIDNotFound); ok {
+			return newCheckError(errCodeUnknownContainer, "endpoint not found",
+				"agent is not aware of endpoint %s", id)
+		}
+		return newCheckError(errCodeTryAgainLater, "unable to retrieve endpoint",
+			"%s", client.Hint(err))
+	}
+
+	ips := interfaceIPs(n.PrevResult, args.IfName)
+	if err := checkEndpoint(ep, ips); err != nil {
+		return err
+	}
+
+	if err := checkHostInterface(ep); err != nil {
+		return err
+	}
+
+	netNs, err := ns.GetNS(args.Netns)
+	if err != nil {
+		return newCheckError(errCodeUnknownContainer, "failed to open netns",
+			"netns %q: %s", args.Netns, err)
+	}
+	defer netNs.Close()
+
+	return netNs.Do(func(_ ns.NetNS) error {
+		if err := checkInterface(n.PrevResult, args.IfName, ips); err != nil {
+			return err
+		}
+		return checkRoutes(n.PrevResult.Routes)
+	})
+}
+
+// interfaceIPs returns the addresses of prevResult assigned to the container
+// interface ifName
+func interfaceIPs(prevResult *cniTypesVer.Result, ifName string) []*cniTypesVer.IPConfig {
+	ips := []*cniTypesVer.IPConfig{}
+	for _, ipConfig := range prevResult.IPs {
+		if ipConfig.Interface < 0 || ipConfig.Interface >= len(prevResult.Interfaces) {
+			continue
+		}
+		iface := prevResult.Interfaces[ipConfig.Interface]
+		if iface.Name == ifName && iface.Sandbox != "" {
+			ips = append(ips, ipConfig)
+		}
+	}
+	return ips
+}
+
+func sortedLabels(lbls models.Labels) []string {
+	sorted := append([]string{}, lbls...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// checkEndpoint verifies that the endpoint has a security identity matching
+// its labels and owns all addresses in ips
+func checkEndpoint(ep *models.Endpoint, ips []*cniTypesVer.IPConfig) error {
+	if ep == nil || ep.Status == nil {
+		return newCheckError(errCodeUnknownContainer, "endpoint not found",
+			"agent returned no endpoint status")
+	}
+
+	switch ep.Status.State {
+	case models.EndpointStateDisconnecting, models.EndpointStateDisconnected:
+		return newCheckError(errCodeEndpointMismatch, "endpoint is being deleted",
+			"endpoint %d is in state %s", ep.ID, ep.Status.State)
+	}
+
+	identity := ep.Status.Identity
+	if identity == nil || identity.ID == 0 {
+		return newCheckError(errCodeEndpointMismatch, "endpoint has no identity",
+			"endpoint %d has no security identity", ep.ID)
+	}
+
+	if ep.Status.Labels != nil {
+		expected := sortedLabels(ep.Status.Labels.SecurityRelevant)
+		actual := sortedLabels(identity.Labels)
+		if strings.Join(expected, ",") != strings.Join(actual, ",") {
+			return newCheckError(errCodeEndpointMismatch, "endpoint identity does not match its labels",
+				"identity %d of endpoint %d has labels %v, expected %v", identity.ID, ep.ID, actual, expected)
+		}
+	}
+
+	owned := map[string]struct{}{}
+	if ep.Status.Networking != nil {
+		for _, pair := range ep.Status.Networking.Addressing {
+			for _, addr := range []string{pair.IPV4, pair.IPV6} {
+				if ip := net.ParseIP(addr); ip != nil {
+					owned[ip.String()] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for _, ipConfig := range ips {
+		if _, ok := owned[ipConfig.Address.IP.String()]; !ok {
+			return newCheckError(errCodeAddressMismatch, "address not assigned to endpoint",
+				"address %s is not assigned to endpoint %d", ipConfig.Address.IP, ep.ID)
+		}
+	}
+
+	return nil
+}
+
+// checkHostInterface verifies that the host side of the veth pair of the
+// endpoint exists
+func checkHostInterface(ep *models.Endpoint) error {
+	if ep.Status.Networking == nil || ep.Status.Networking.InterfaceName == "" {
+		return newCheckError(errCodeInterfaceMismatch, "endpoint has no interface",
+			"endpoint %d has no host interface", ep.ID)
+	}
+
+	ifName := ep.Status.Networking.InterfaceName
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return newCheckError(errCodeInterfaceMismatch, "host interface not found",
+			"interface %s of endpoint %d: %s", ifName, ep.ID, err)
+	}
+
+	if mac := ep.Status.Networking.HostMac; mac != "" && link.Attrs().HardwareAddr.String() != mac {
+		return newCheckError(errCodeInterfaceMismatch, "host interface has unexpected MAC address",
+			"interface %s has MAC address %s, expected %s", ifName, link.Attrs().HardwareAddr, mac)
+	}
+
+	return nil
+}
+
+// checkInterface verifies that the container interface ifName is a veth
+// with the MAC address of prevResult and all addresses in ips. Must be
+// called in the network namespace of the container.
+func checkInterface(prevResult *cniTypesVer.Result, ifName string, ips []*cniTypesVer.IPConfig) error {
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return newCheckError(errCodeInterfaceMismatch, "container interface not found",
+			"interface %s: %s", ifName, err)
+	}
+
+	if link.Type() != "veth" {
+		return newCheckError(errCodeInterfaceMismatch, "container interface is not a veth",
+			"interface %s is of type %s", ifName, link.Type())
+	}
+
+	for _, iface := range prevResult.Interfaces {
+		if iface.Name == ifName && iface.Sandbox != "" && iface.Mac != "" &&
+			link.Attrs().HardwareAddr.String() != iface.Mac {
+			return newCheckError(errCodeInterfaceMismatch, "container interface has unexpected MAC address",
+				"interface %s has MAC address %s, expected %s", ifName, link.Attrs().HardwareAddr, iface.Mac)
+		}
+	}
+
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return newCheckError(errCodeGeneric, "unable to list addresses",
+			"interface %s: %s", ifName, err)
+	}
+
+	for _, ipConfig := range ips {
+		found := false
+		for _, addr := range addrs {
+			if addr.IP.Equal(ipConfig.Address.IP) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return newCheckError(errCodeAddressMismatch, "address not found on container interface",
+				"address %s not found on interface %s", ipConfig.Address.IP, ifName)
+		}
+	}
+
+	return nil
+}
+
+// routeDst returns the destination of r. Default routes are reported by
+// netlink without a destination.
+func routeDst(r netlink.Route, family int) string {
+	if r.Dst != nil {
+		return r.Dst.String()
+	}
+	if family == netlink.FAMILY_V4 {
+		return "0.0.0.0/0"
+	}
+	return "::/0"
+}
+
+// checkRoutes verifies that all routes of prevResult are installed. Must be
+// called in the network namespace of the container.
+func checkRoutes(routes []*cniTypes.Route) error {
+	for _, expected := range routes {
+		family := netlink.FAMILY_V6
+		if expected.Dst.IP.To4() != nil {
+			family = netlink.FAMILY_V4
+		}
+
+		installed, err := netlink.RouteList(nil, family)
+		if err != nil {
+			return newCheckError(errCodeGeneric, "unable to list routes", "%s", err)
+		}
+
+		found := false
+		for _, r := range installed {
+			if routeDst(r, family) == expected.Dst.String() &&
+				(expected.GW == nil || expected.GW.Equal(r.Gw)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.WithField("route", logfields.Repr(expected)).Debug("Route not found")
+			return newCheckError(errCodeRouteMismatch, "route not found",
+				"route to %s via %s not found", expected.Dst.String(), expected.GW)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/cilium/cilium/api/v1/models"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	cniTypesVer "github.com/containernetworking/cni/pkg/types/current"
+	. "gopkg.in/check.v1"
+)
+
+// checkErrorCode returns the code of the CNI error err, or 0 if err is nil
+func checkErrorCode(c *C, err error) uint {
+	if err == nil {
+		return 0
+	}
+	e, ok := err.(*cniTypes.Error)
+	c.Assert(ok, Equals, true, Commentf("unexpected error %s", err))
+	return e.Code
+}
+
+func testEndpoint() *models.Endpoint {
+	return &models.Endpoint{
+		ID: 1234,
+		Status: &models.EndpointStatus{
+			State: models.EndpointStateReady,
+			Identity: &models.Identity{
+				ID:     256,
+				Labels: models.Labels{"k8s:app=foo", "k8s:io.kubernetes.pod.namespace=default"},
+			},
+			Labels: &models.LabelConfigurationStatus{
+				SecurityRelevant: models.Labels{"k8s:io.kubernetes.pod.namespace=default", "k8s:app=foo"},
+			},
+			Networking: &models.EndpointNetworking{
+				Addressing: []*models.AddressPair{
+					{IPV4: "10.22.0.5", IPV6: "fd00:22::5"},
+				},
+			},
+		},
+	}
+}
+
+func (s *CNISuite) TestCheckEndpoint(c *C) {
+	ips := []*cniTypesVer.IPConfig{
+		ipConfig(2, "10.22.0.5/16"),
+		ipConfig(2, "fd00:22::5/64"),
+	}
+
+	c.Assert(checkErrorCode(c, checkEndpoint(testEndpoint(), ips)), Equals, uint(0))
+
+	c.Assert(checkErrorCode(c, checkEndpoint(nil, ips)), Equals, errCodeUnknownContainer)
+	c.Assert(checkErrorCode(c, checkEndpoint(&models.Endpoint{ID: 1234}, ips)), Equals, errCodeUnknownContainer)
+
+	ep := testEndpoint()
+	ep.Status.State = models.EndpointStateDisconnecting
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeEndpointMismatch)
+
+	ep = testEndpoint()
+	ep.Status.Identity = nil
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeEndpointMismatch)
+
+	ep = testEndpoint()
+	ep.Status.Identity.ID = 0
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeEndpointMismatch)
+
+	ep = testEndpoint()
+	ep.Status.Labels.SecurityRelevant = append(ep.Status.Labels.SecurityRelevant, "k8s:app=bar")
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeEndpointMismatch)
+
+	ep = testEndpoint()
+	ep.Status.Networking.Addressing[0].IPV6 = ""
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeAddressMismatch)
+
+	ep = testEndpoint()
+	ep.Status.Networking = nil
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, ips)), Equals, errCodeAddressMismatch)
+	c.Assert(checkErrorCode(c, checkEndpoint(ep, nil)), Equals, uint(0))
+}
+
+func (s *CNISuite) TestInterfaceIPs(c *C) {
+	prevResult := &cniTypesVer.Result{
+		Interfaces: []*cniTypesVer.Interface{
+			{Name: "lxc1234"},
+			{Name: "eth0", Sandbox: "/var/run/netns/pod"},
+			{Name: "eth1", Sandbox: "/var/run/netns/pod"},
+		},
+		IPs: []*cniTypesVer.IPConfig{
+			ipConfig(0, "10.22.0.1/32"),
+			ipConfig(1, "10.22.0.5/32"),
+			ipConfig(1, "fd00:22::5/128"),
+			ipConfig(2, "10.23.0.5/32"),
+			ipConfig(-1, "10.24.0.5/32"),
+			ipConfig(3, "10.25.0.5/32"),
+		},
+	}
+
+	c.Assert(interfaceIPs(prevResult, "eth0"), DeepEquals, []*cniTypesVer.IPConfig{
+		prevResult.IPs[1],
+		prevResult.IPs[2],
+	})
+	c.Assert(interfaceIPs(prevResult, "eth1"), DeepEquals, []*cniTypesVer.IPConfig{
+		prevResult.IPs[3],
+	})
+	// Addresses of host interfaces are ignored
+	c.Assert(interfaceIPs(prevResult, "lxc1234"), DeepEquals, []*cniTypesVer.IPConfig{})
+	c.Assert(interfaceIPs(prevResult, "eth2"), DeepEquals, []*cniTypesVer.IPConfig{})
+}
+
+const checkNetConf = `{
+	"cniVersion": "0.4.0",
+	"name": "cilium",
+	"type": "cilium-cni",
+	"prevResult": {
+		"cniVersion": "0.4.0",
+		"interfaces": [
+			{"name": "lxc1234"},
+			{"name": "eth0", "sandbox": "/var/run/netns/pod"}
+		],
+		"ips": [
+			{"version": "4", "interface": 1, "address": "10.22.0.5/32", "gateway": "10.22.0.1"},
+			{"version": "6", "interface": 1, "address": "fd00:22::5/128"}
+		],
+		"routes": [
+			{"dst": "0.0.0.0/0", "gw": "10.22.0.1"}
+		]
+	}
+}`
+
+func (s *CNISuite) TestPrevResultRoundTrip(c *C) {
+	n, cniVersion, err := loadNetConf([]byte(checkNetConf))
+	c.Assert(err, IsNil)
+	c.Assert(cniVersion, Equals, cniVersion040)
+	c.Assert(n.PrevResult, Not(IsNil))
+	c.Assert(n.PrevResult.Interfaces, HasLen, 2)
+	c.Assert(n.PrevResult.IPs, HasLen, 2)
+	c.Assert(n.PrevResult.IPs[0].Address.String(), Equals, "10.22.0.5/32")
+	c.Assert(n.PrevResult.IPs[0].Gateway.String(), Equals, "10.22.0.1")
+	c.Assert(n.PrevResult.IPs[1].Address.String(), Equals, "fd00:22::5/128")
+	c.Assert(n.PrevResult.Routes, HasLen, 1)
+
+	// Capture the result printed to stdout
+	r, w, err := os.Pipe()
+	c.Assert(err, IsNil)
+	stdout := os.Stdout
+	os.Stdout = w
+	err = printResult(n.PrevResult, cniVersion)
+	os.Stdout = stdout
+	w.Close()
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+
+	raw := map[string]interface{}{}
+	c.Assert(json.Unmarshal(data, &raw), IsNil)
+	c.Assert(raw["cniVersion"], Equals, cniVersion040)
+
+	res, err := parsePrevResult(raw)
+	c.Assert(err, IsNil)
+	c.Assert(res, DeepEquals, n.PrevResult)
+}
+
+func (s *CNISuite) TestCmdCheckInvalidConfig(c *C) {
+	args := &skel.CmdArgs{ContainerID: "foo", IfName: "eth0"}
+
+	args.StdinData = []byte(`{"cniVersion": "0.3.1", "name": "cilium", "type": "cilium-cni"}`)
+	c.Assert(checkErrorCode(c, cmdCheck(args)), Equals, cniTypes.ErrIncompatibleCNIVersion)
+
+	args.StdinData = []byte(`{"cniVersion": "0.4.0", "name": "cilium", "type": "cilium-cni"}`)
+	c.Assert(checkErrorCode(c, cmdCheck(args)), Equals, errCodeInvalidNetworkConfig)
+
+	args.StdinData = []byte(`{"cniVersion": "0.4.0", "prevResult": {"ips": "invalid"}}`)
+	c.Assert(checkErrorCode(c, cmdCheck(args)), Equals, errCodeDecodingFailure)
+}
@@ -62,12 +62,25 @@ type CmdState struct {
 	IP4routes []route.Route
 	Client    *client.Client
 	HostAddr  *models.NodeAddressing
+
+	// PoolRoutes are the prefixes to route via the host as requested by
+	// the IPAM pool the addresses have been allocated from
+	PoolRoutes []string
 }
 
 type netConf struct {
 	cniTypes.NetConf
 	MTU  int  `json:"mtu"`
 	Args Args `json:"args"`
+
+	// Chained enables policy enforcement on top of the interface and
+	// addresses set up by the previous plugin in a chain instead of
+	// creating a veth pair and allocating addresses
+	Chained bool `json:"chained,omitempty"`
+
+	// RawPrevResult is the result of the previous plugin in a chain
+	RawPrevResult map[string]interface{} `json:"prevResult,omitempty"`
+	PrevResult    *cniTypesVer.Result    `json:"-"`
 }
 
 // Args contains arbitrary information a scheduler
@@ -92,8 +105,51 @@ type NetworkInfo struct {
 	} `json:"labels,omitempty"`
 }
 
+// K8sArgs are the arguments passed by Kubernetes to the CNI plugin in
+// CNI_ARGS
+type K8sArgs struct {
+	cniTypes.CommonArgs
+	K8S_POD_NAME               cniTypes.UnmarshallableString
+	K8S_POD_NAMESPACE          cniTypes.UnmarshallableString
+	K8S_POD_INFRA_CONTAINER_ID cniTypes.UnmarshallableString
+}
+
+// podName returns the namespace/name of the Kubernetes pod the CNI plugin is
+// invoked for or an empty string if not invoked by Kubernetes
+func podName(args string) (string, error) {
+	k8sArgs := K8sArgs{}
+	if err := cniTypes.LoadArgs(args, &k8sArgs); err != nil {
+		return "", fmt.Errorf("unable to parse CNI arguments: %s", err)
+	}
+
+	if k8sArgs.K8S_POD_NAMESPACE == "" || k8sArgs.K8S_POD_NAME == "" {
+		return "", nil
+	}
+
+	return string(k8sArgs.K8S_POD_NAMESPACE) + "/" + string(k8sArgs.K8S_POD_NAME), nil
+}
+
+// cniVersion040 is the version of the CNI spec introducing the CHECK
+// command. The vendored CNI library predates it, the format of results is
+// unchanged from version 0.3.1.
+const cniVersion040 = "0.4.0"
+
+// pluginVersions are the versions of the CNI spec supported by the plugin
+var pluginVersions = version.PluginSupports("0.1.0", "0.2.0", "0.3.0", "0.3.1", cniVersion040)
+
 func main() {
-	skel.PluginMain(cmdAdd, cmdDel, version.All)
+	// The CHECK command is not dispatched by the vendored CNI library
+	if os.Getenv("CNI_COMMAND") == "CHECK" {
+		if e := checkMain(); e != nil {
+			if err := e.Print(); err != nil {
+				log.WithError(err).Warn("Unable to write error JSON to stdout")
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	skel.PluginMain(cmdAdd, cmdDel, pluginVersions)
 }
 
 func IPv6IsEnabled(ipam *models.IPAMResponse) bool {
@@ -125,9 +181,66 @@ func loadNetConf(bytes []byte) (*netConf, string, error) {
 	if err := json.Unmarshal(bytes, n); err != nil {
 		return nil, "", fmt.Errorf("failed to load netconf: %s", err)
 	}
+
+	if n.RawPrevResult != nil {
+		prevResult, err := parsePrevResult(n.RawPrevResult)
+		if err != nil {
+			return nil, "", err
+		}
+		n.PrevResult = prevResult
+	}
+
 	return n, n.CNIVersion, nil
 }
 
+// parsePrevResult parses the result of the previous plugin in a chain.
+// Chaining was introduced with version 0.3.0 of the CNI spec, all results of
+// later versions share the same format.
+func parsePrevResult(raw map[string]interface{}) (*cniTypesVer.Result, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal prevResult: %s", err)
+	}
+
+	res, err := cniTypesVer.NewResult(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prevResult: %s", err)
+	}
+
+	return cniTypesVer.GetResult(res)
+}
+
+// result040 is a result of version 0.4.0 of the CNI spec
+type result040 struct {
+	CNIVersion string `json:"cniVersion"`
+	*cniTypesVer.Result
+}
+
+// printResult prints res to stdout in the format of cniVersion
+func printResult(res *cniTypesVer.Result, cniVersion string) error {
+	if cniVersion != cniVersion040 {
+		return cniTypes.PrintResult(res, cniVersion)
+	}
+
+	data, err := json.MarshalIndent(&result040{CNIVersion: cniVersion, Result: res}, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// mesosLabels returns the labels passed by Mesos in the network configuration
+func mesosLabels(n *netConf) models.Labels {
+	addLabels := models.Labels{}
+
+	for _, label := range n.Args.Mesos.NetworkInfo.Labels.Labels {
+		addLabels = append(addLabels, fmt.Sprintf("%s:%s=%s", labels.LabelSourceMesos, label.Key, label.Value))
+	}
+
+	return addLabels
+}
+
 func removeIfFromNSIfExists(netNs ns.NetNS, ifName string) error {
 	return netNs.Do(func(_ ns.NetNS) error {
 		l, err := netlink.LinkByName(ifName)
@@ -249,7 +362,7 @@ func prepareIP(ipAddr string, isIPv6 bool, state *CmdState, mtu int) (*cniTypesV
 		if state.IP6, err = addressing.NewCiliumIPv6(ipAddr); err != nil {
 			return nil, nil, err
 		}
-		if state.IP6routes, err = connector.IPv6Routes(state.HostAddr, mtu); err != nil {
+		if state.IP6routes, err = connector.IPv6PoolRoutes(state.HostAddr, mtu, state.PoolRoutes); err != nil {
 			return nil, nil, err
 		}
 		routes = state.IP6routes
@@ -260,7 +373,7 @@ func prepareIP(ipAddr string, isIPv6 bool, state *CmdState, mtu int) (*cniTypesV
 		if state.IP4, err = addressing.NewCiliumIPv4(ipAddr); err != nil {
 			return nil, nil, err
 		}
-		if state.IP4routes, err = connector.IPv4Routes(state.HostAddr, mtu); err != nil {
+		if state.IP4routes, err = connector.IPv4PoolRoutes(state.HostAddr, mtu, state.PoolRoutes); err != nil {
 			return nil, nil, err
 		}
 		routes = state.IP4routes
@@ -299,6 +412,10 @@ func cmdAdd(args *skel.CmdArgs) error {
 		return err
 	}
 
+	if n.Chained {
+		return cmdAddChained(args, n, cniVersion)
+	}
+
 	client, err := client.NewDefaultClient()
 	if err != nil {
 		return fmt.Errorf("unable to connect to Cilium daemon: %s", err)
@@ -315,11 +432,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 			args.IfName, args.Netns, err)
 	}
 
-	addLabels := models.Labels{}
-
-	for _, label := range n.Args.Mesos.NetworkInfo.Labels.Labels {
-		addLabels = append(addLabels, fmt.Sprintf("%s:%s=%s", labels.LabelSourceMesos, label.Key, label.Value))
-	}
+	addLabels := mesosLabels(n)
 
 	configResult, err := client.ConfigGet()
 	if err != nil {
@@ -352,7 +465,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}()
 
 	if err = netlink.LinkSetNsFd(*peer, int(netNs.Fd())); err != nil {
-		return fmt.Errorf("unable to move veth pair %q to netns: %s", peer, err)
+		return fmt.Errorf("unable to move veth pair %q to netns: %s", (*peer).Attrs().Name, err)
 	}
 
 	err = netNs.Do(func(_ ns.NetNS) error {
@@ -366,7 +479,12 @@ func cmdAdd(args *skel.CmdArgs) error {
 		return err
 	}
 
-	ipam, err := client.IPAMAllocate("")
+	pod, err := podName(args.Args)
+	if err != nil {
+		return err
+	}
+
+	ipam, err := client.IPAMAllocate("", args.ContainerID, pod)
 	if err != nil {
 		return err
 	}
@@ -390,12 +508,18 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}
 
 	state := CmdState{
-		Endpoint: ep,
-		Client:   client,
-		HostAddr: ipam.HostAddressing,
+		Endpoint:   ep,
+		Client:     client,
+		HostAddr:   ipam.HostAddressing,
+		PoolRoutes: ipam.Routes,
 	}
 
+	// Pass on the results of previous plugins in a chain
 	res := &cniTypesVer.Result{}
+	if n.PrevResult != nil {
+		res = n.PrevResult
+	}
+	ifIndex := len(res.Interfaces)
 
 	if IPv6IsEnabled(ipam) {
 		ipConfig, routes, err := prepareIP(ep.Addressing.IPV6, true, &state, int(conf.RouteMTU))
@@ -403,6 +527,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 			return err
 		}
 		ep.ID = int64(state.IP6.EndpointID())
+		ipConfig.Interface = ifIndex
 		res.IPs = append(res.IPs, ipConfig)
 		res.Routes = append(res.Routes, routes...)
 	} else {
@@ -414,6 +539,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 		if err != nil {
 			return err
 		}
+		ipConfig.Interface = ifIndex
 		res.IPs = append(res.IPs, ipConfig)
 		res.Routes = append(res.Routes, routes...)
 	}
@@ -449,24 +575,38 @@ func cmdAdd(args *skel.CmdArgs) error {
 	logger.WithFields(logrus.Fields{
 		logfields.EndpointID:  ep.ID,
 		logfields.ContainerID: ep.ContainerID}).Debug("Endpoint successfully created")
-	return cniTypes.PrintResult(res, cniVersion)
+	return printResult(res, cniVersion)
 }
 
 func cmdDel(args *skel.CmdArgs) error {
 	log.WithField("args", args).Debug("Processing CNI DEL request")
 
+	// DEL must succeed on a best effort basis, a broken prevResult must
+	// not prevent the endpoint from being removed
+	n, _, err := loadNetConf(args.StdinData)
+	if err != nil {
+		log.WithError(err).Warn("Unable to load network configuration, ignoring prevResult")
+		n = &netConf{}
+	}
+
 	client, err := client.NewDefaultClient()
 	if err != nil {
 		return fmt.Errorf("unable to connect to Cilium daemon: %s", err)
 	}
 
+	if n.Chained {
+		return cmdDelChained(args, client)
+	}
+
 	id := endpointid.NewID(endpointid.ContainerIdPrefix, args.ContainerID)
 	if ep, err := client.EndpointGet(id); err != nil {
 		// Ignore endpoints not found
 		log.WithError(err).WithField(logfields.EndpointID, id).Debug("Agent is not aware of endpoint")
+		releasePrevResultIPs(client, args.ContainerID, n.PrevResult)
 		return nil
 	} else if ep == nil {
 		log.WithError(err).WithField(logfields.EndpointID, id).Debug("Agent is not aware of endpoint")
+		releasePrevResultIPs(client, args.ContainerID, n.PrevResult)
 		return nil
 	} else {
 		for _, address := range ep.Status.Networking.Addressing {
@@ -486,3 +626,29 @@ func cmdDel(args *skel.CmdArgs) error {
 
 	return removeIfFromNSIfExists(netNs, args.IfName)
 }
+
+// releasePrevResultIPs releases the addresses of prevResult which are still
+// allocated on behalf of the container. This covers containers for which no
+// endpoint has been created, e.g. because ADD failed half way, or for which
+// the endpoint has been lost by the agent.
+func releasePrevResultIPs(client *client.Client, containerID string, prevResult *cniTypesVer.Result) {
+	if prevResult == nil || len(prevResult.IPs) == 0 {
+		return
+	}
+
+	resp, err := client.Daemon.GetHealthz(nil)
+	if err != nil {
+		log.WithError(err).Warn("Unable to retrieve IPAM allocations")
+		return
+	}
+	if resp.Payload.IPAM == nil {
+		return
+	}
+
+	for _, ipConfig := range prevResult.IPs {
+		ip := ipConfig.Address.IP.String()
+		if resp.Payload.IPAM.Allocations[ip] == containerID {
+			releaseIP(client, ip)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 
 	"github.com/cilium/cilium/pkg/envoy/cilium"
+	"github.com/cilium/cilium/proxylib/memcached/meta"
 	"github.com/cilium/cilium/proxylib/proxylib"
 
 	log "github.com/sirupsen/logrus"
@@ -43,39 +44,49 @@ type BinaryMemcacheRule struct {
 	regex *regexp.Regexp
 }
 
-type binaryMemcacheMeta struct {
-	opCode byte
-	key    string
-}
-
 // Matches returns true if the BinaryMemcacheRule matches
 func (rule *BinaryMemcacheRule) Matches(data interface{}) bool {
 	log.Debugf("binarymemcache checking rule %v", *rule)
 
-	packetMeta, ok := data.(binaryMemcacheMeta)
+	packetMeta, ok := data.(meta.MemcacheMeta)
 	if !ok {
-		log.Warnf("BinaryMemcacheRule.Matches called with type other than binaryMemcacheMeta, failing to match")
+		log.Warnf("BinaryMemcacheRule.Matches called with type other than MemcacheMeta, failing to match")
 		return false
 	}
 
-	if !rule.matchOpcode(packetMeta.opCode) {
+	if !rule.matchOpcode(packetMeta.Opcode) {
 		return false
 	}
 
-	if rule.keyExact != "" {
-		return rule.keyExact == packetMeta.key
+	if rule.keyExact == "" && rule.keyPrefix == "" && rule.keyRegex == "" {
+		log.Debugf("No key rule specified, matching by opcode")
+		return true
 	}
 
-	if rule.keyPrefix != "" {
-		return strings.HasPrefix(packetMeta.key, rule.keyPrefix)
+	// Requests without keys are matched against the empty key, requests
+	// with multiple keys only match if all of the keys do
+	keys := packetMeta.Keys
+	if len(keys) == 0 {
+		keys = []string{""}
 	}
+	for _, key := range keys {
+		if !rule.matchKey(key) {
+			return false
+		}
+	}
+	return true
+}
 
-	if rule.keyRegex != "" {
-		return rule.regex.MatchString(packetMeta.key)
+func (rule *BinaryMemcacheRule) matchKey(key string) bool {
+	if rule.keyExact != "" {
+		return rule.keyExact == key
 	}
 
-	log.Debugf("No key rule specified, matching by opcode")
-	return true
+	if rule.keyPrefix != "" {
+		return strings.HasPrefix(key, rule.keyPrefix)
+	}
+
+	return rule.regex.MatchString(key)
 }
 
 func (rule *BinaryMemcacheRule) matchOpcode(oc byte) bool {
@@ -218,7 +229,7 @@ func (p *BinaryMemcacheParser) OnData(reply, endStream bool, dataBuffers [][]byt
 
 	p.requestCount++
 
-	matches := p.connection.Matches(binaryMemcacheMeta{opcode, key})
+	matches := p.connection.Matches(meta.MemcacheMeta{Opcode: opcode, Keys: []string{key}})
 	if matches {
 		p.connection.Log(cilium.EntryType_Request, logEntry)
 		return proxylib.PASS, int(bodyLength + headerSize)
@@ -15,8 +15,11 @@
 package binary
 
 import (
+	"regexp"
 	"testing"
 
+	"github.com/cilium/cilium/proxylib/memcached/meta"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -72,3 +75,21 @@ func (k *BinaryMemcachedTestSuite) TestMemcacheGetKey(c *C) {
 
 	c.Assert(key, Equals, "")
 }
+
+func (k *BinaryMemcachedTestSuite) TestMemcacheRuleMatchesKeys(c *C) {
+	rule := &BinaryMemcacheRule{opCode: "get", opCodes: MemcacheOpCodeMap["get"], keyPrefix: "app:"}
+
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 0, Keys: []string{"app:a"}}), Equals, true)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 0, Keys: []string{"app:a", "app:b"}}), Equals, true)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 0, Keys: []string{"app:a", "b"}}), Equals, false)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 0}), Equals, false)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 1, Keys: []string{"app:a"}}), Equals, false)
+
+	rule = &BinaryMemcacheRule{opCode: "readGroup", opCodes: MemcacheOpCodeMap["readGroup"], keyRegex: "^a.$"}
+	rule.regex = regexp.MustCompile(rule.keyRegex)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 12, Keys: []string{"ab", "ac"}}), Equals, true)
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 12, Keys: []string{"ab", "abc"}}), Equals, false)
+
+	rule = &BinaryMemcacheRule{opCode: "version", opCodes: MemcacheOpCodeMap["version"]}
+	c.Assert(rule.Matches(meta.MemcacheMeta{Opcode: 11}), Equals, true)
+}
@@ -0,0 +1,28 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+// MemcacheMeta holds the parts of a memcached request policy is enforced
+// on. It is shared by the binary and text protocol parsers so that the same
+// rules apply to both protocols.
+type MemcacheMeta struct {
+	// Command is the text protocol command, empty for binary requests
+	Command string
+	// Opcode is the binary protocol opcode of the request. Text commands
+	// are mapped to the opcode of the equivalent binary request.
+	Opcode byte
+	// Keys holds the keys of the request
+	Keys []string
+}
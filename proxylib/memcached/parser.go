@@ -0,0 +1,94 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memcached
+
+import (
+	"github.com/cilium/cilium/proxylib/memcached/binary"
+	"github.com/cilium/cilium/proxylib/memcached/text"
+	"github.com/cilium/cilium/proxylib/proxylib"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MemcacheParserFactory implements proxylib.ParserFactory
+type MemcacheParserFactory struct{}
+
+// Create creates memcached parser
+func (p *MemcacheParserFactory) Create(connection *proxylib.Connection) proxylib.Parser {
+	log.Infof("MemcacheParserFactory: Create: %v", connection)
+	return &MemcacheParser{connection: connection}
+}
+
+// compile time check for interface implementation
+var _ proxylib.ParserFactory = &MemcacheParserFactory{}
+
+var memcacheParserFactory *MemcacheParserFactory
+
+const (
+	parserName = "memcache"
+)
+
+func init() {
+	log.Info("init(): Registering memcacheParserFactory")
+	proxylib.RegisterParserFactory(parserName, memcacheParserFactory)
+	proxylib.RegisterL7RuleParser(parserName, binary.L7BinaryMemcacheRuleParser)
+}
+
+// MemcacheParser implements proxylib.Parser. It detects whether the client
+// speaks the binary or the text protocol from the first request and hands
+// the connection over to the parser of that protocol.
+type MemcacheParser struct {
+	connection *proxylib.Connection
+
+	parser proxylib.Parser
+}
+
+var _ proxylib.Parser = &MemcacheParser{}
+
+// requestMagic is the first byte of every binary request
+const requestMagic = 0x80
+
+// OnData parses memcached data
+func (p *MemcacheParser) OnData(reply, endStream bool, dataBuffers [][]byte, offset int) (proxylib.OpType, int) {
+	if p.parser == nil {
+		var first []byte
+		skip := offset
+		for _, buf := range dataBuffers {
+			if skip < len(buf) {
+				first = buf[skip:]
+				break
+			}
+			skip -= len(buf)
+		}
+		if len(first) == 0 {
+			return proxylib.NOP, 0
+		}
+
+		if reply {
+			log.Warnf("memcached server sent data before the first request")
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+
+		if first[0] == requestMagic {
+			log.Debugf("memcached client speaks the binary protocol")
+			p.parser = (&binary.BinaryMemcacheParserFactory{}).Create(p.connection)
+		} else {
+			log.Debugf("memcached client speaks the text protocol")
+			p.parser = (&text.TextMemcacheParserFactory{}).Create(p.connection)
+		}
+	}
+
+	return p.parser.OnData(reply, endStream, dataBuffers, offset)
+}
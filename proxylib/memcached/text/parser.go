@@ -0,0 +1,311 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/envoy/cilium"
+	"github.com/cilium/cilium/proxylib/memcached/binary"
+	"github.com/cilium/cilium/proxylib/memcached/meta"
+	"github.com/cilium/cilium/proxylib/proxylib"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TextMemcacheParserFactory implements proxylib.ParserFactory
+type TextMemcacheParserFactory struct{}
+
+// Create creates text memcached parser
+func (p *TextMemcacheParserFactory) Create(connection *proxylib.Connection) proxylib.Parser {
+	log.Infof("TextMemcacheParserFactory: Create: %v", connection)
+	return &TextMemcacheParser{connection: connection, injectQueue: make([]uint32, 0)}
+}
+
+// compile time check for interface implementation
+var _ proxylib.ParserFactory = &TextMemcacheParserFactory{}
+
+var textMemcacheParserFactory *TextMemcacheParserFactory
+
+const (
+	parserName = "textmemcache"
+)
+
+func init() {
+	log.Info("init(): Registering textMemcacheParserFactory")
+	proxylib.RegisterParserFactory(parserName, textMemcacheParserFactory)
+	proxylib.RegisterL7RuleParser(parserName, binary.L7BinaryMemcacheRuleParser)
+}
+
+// TextMemcacheParser implements proxylib.Parser
+type TextMemcacheParser struct {
+	connection *proxylib.Connection
+
+	// requestCount and replyCount only count the requests the server
+	// replies to, i.e., not quit and noreply requests
+	requestCount uint32
+	replyCount   uint32
+	// injectQueue holds the request numbers of the denied requests
+	// whose error reply has not been injected yet
+	injectQueue []uint32
+}
+
+var _ proxylib.Parser = &TextMemcacheParser{}
+
+// OnData parses text memcached data
+func (p *TextMemcacheParser) OnData(reply, endStream bool, dataBuffers [][]byte, offset int) (proxylib.OpType, int) {
+	log.Debugf("OnData with offset %d", offset)
+
+	if reply {
+		if p.injectFromQueue() {
+			return proxylib.INJECT, len(DeniedMsg)
+		}
+	}
+
+	//TODO don't copy data from buffers
+	data := (bytes.Join(dataBuffers, []byte{}))[offset:]
+	if len(data) == 0 {
+		return proxylib.NOP, 0
+	}
+
+	lineEnd := bytes.IndexByte(data, '\n')
+	if lineEnd < 0 {
+		log.Debugf("Did not receive a complete line, need more bytes")
+		return proxylib.MORE, 1
+	}
+	lineLength := lineEnd + 1
+
+	if reply {
+		return p.onReply(data, lineLength, strings.Fields(string(data[:lineEnd])))
+	}
+
+	fields, ok := splitCommandLine(data[:lineEnd])
+	if !ok {
+		log.Warnf("Command line contains control characters: %q", data[:lineEnd])
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+
+	if len(fields) == 0 {
+		// The server replies to empty lines with ERROR
+		p.requestCount++
+		return proxylib.PASS, lineLength
+	}
+
+	command := strings.ToLower(fields[0])
+	requestLength := lineLength
+	if dataField, ok := storageCommands[command]; ok {
+		if len(fields) <= dataField {
+			log.Warnf("Storage command without data length: %q", data[:lineEnd])
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		dataLength, err := strconv.ParseUint(fields[dataField], 10, 32)
+		if err != nil {
+			log.Warnf("Invalid data length in storage command: %q", data[:lineEnd])
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		// data block is terminated by CRLF
+		requestLength += int(dataLength) + 2
+		if requestLength > len(data) {
+			log.Debugf("Did not receive the data block, need %d more bytes", requestLength-len(data))
+			return proxylib.MORE, requestLength - len(data)
+		}
+	}
+
+	keys := getKeys(command, fields)
+	noReply := command == "quit" || (supportsNoReply(command) && fields[len(fields)-1] == "noreply")
+
+	logEntry := &cilium.LogEntry_GenericL7{
+		&cilium.L7LogEntry{
+			Proto: "textmemcached",
+			Fields: map[string]string{
+				"command": command,
+				"keys":    strings.Join(keys, " "),
+			},
+		},
+	}
+
+	if !noReply {
+		p.requestCount++
+	}
+
+	// Commands without a binary equivalent never match
+	opcode, ok := commandOpcodes[command]
+	if ok && p.connection.Matches(meta.MemcacheMeta{Command: command, Opcode: opcode, Keys: keys}) {
+		p.connection.Log(cilium.EntryType_Request, logEntry)
+		return proxylib.PASS, requestLength
+	}
+
+	if !noReply {
+		// This is done to ensure in-order replies
+		if p.requestCount == p.replyCount+1 {
+			p.injectDeniedMessage()
+		} else {
+			p.injectQueue = append(p.injectQueue, p.requestCount)
+		}
+	}
+
+	p.connection.Log(cilium.EntryType_Denied, logEntry)
+	return proxylib.DROP, requestLength
+}
+
+// splitCommandLine splits a command line into tokens the way memcached does,
+// on spaces only. Lines containing other control characters than the
+// terminating CR are rejected, as memcached would tokenize them differently
+// than the parser, e.g. a tab is not a separator but ends a number.
+func splitCommandLine(line []byte) ([]string, bool) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	for _, b := range line {
+		if b < ' ' || b == 0x7f {
+			return nil, false
+		}
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(string(line), " ") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields, true
+}
+
+// onReply passes a reply line. Retrieval and stats replies span multiple
+// lines, so replies are only counted on their final line.
+func (p *TextMemcacheParser) onReply(data []byte, lineLength int, fields []string) (proxylib.OpType, int) {
+	if len(fields) == 0 {
+		log.Warnf("Empty reply line")
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+
+	switch fields[0] {
+	case "VALUE":
+		// VALUE <key> <flags> <bytes> [<cas unique>]
+		if len(fields) < 4 {
+			log.Warnf("Invalid VALUE reply: %q", data[:lineLength])
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		dataLength, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			log.Warnf("Invalid data length in VALUE reply: %q", data[:lineLength])
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		replyLength := lineLength + int(dataLength) + 2
+		if replyLength > len(data) {
+			return proxylib.MORE, replyLength - len(data)
+		}
+		return proxylib.PASS, replyLength
+
+	case "STAT", "ITEM":
+		return proxylib.PASS, lineLength
+	}
+
+	log.Debugf("reply, passing %d bytes", lineLength)
+	p.replyCount++
+	return proxylib.PASS, lineLength
+}
+
+func (p *TextMemcacheParser) injectDeniedMessage() {
+	p.connection.Inject(true, DeniedMsg)
+	p.replyCount++
+}
+
+func (p *TextMemcacheParser) injectFromQueue() bool {
+	if len(p.injectQueue) > 0 {
+		if p.injectQueue[0] == p.replyCount+1 {
+			p.injectDeniedMessage()
+			p.injectQueue = p.injectQueue[1:]
+			return true
+		}
+	}
+	return false
+}
+
+func getKeys(command string, fields []string) []string {
+	switch command {
+	case "get", "gets":
+		return fields[1:]
+	case "gat", "gats":
+		// gat <exptime> <key>*
+		if len(fields) > 2 {
+			return fields[2:]
+		}
+		return nil
+	}
+	if _, ok := keyCommands[command]; ok && len(fields) > 1 {
+		return fields[1:2]
+	}
+	return nil
+}
+
+func supportsNoReply(command string) bool {
+	if _, ok := keyCommands[command]; ok {
+		return true
+	}
+	return command == "flush_all" || command == "verbosity"
+}
+
+// DeniedMsg is sent if policy denies the request. Exported for tests
+var DeniedMsg = []byte("CLIENT_ERROR access denied\r\n")
+
+// storageCommands maps storage commands to the index of their data length
+// field
+var storageCommands = map[string]int{
+	"set":     4,
+	"add":     4,
+	"replace": 4,
+	"append":  4,
+	"prepend": 4,
+	"cas":     4,
+}
+
+// keyCommands are the commands taking a single key as their first argument
+var keyCommands = map[string]struct{}{
+	"set":     {},
+	"add":     {},
+	"replace": {},
+	"append":  {},
+	"prepend": {},
+	"cas":     {},
+	"delete":  {},
+	"incr":    {},
+	"decr":    {},
+	"touch":   {},
+}
+
+// commandOpcodes maps text commands to the opcode of the equivalent binary
+// request, so that opcode groups in rules apply to both protocols
+var commandOpcodes = map[string]byte{
+	"get":       0,
+	"gets":      0,
+	"set":       1,
+	"cas":       1,
+	"add":       2,
+	"replace":   3,
+	"delete":    4,
+	"incr":      5,
+	"decr":      6,
+	"quit":      7,
+	"flush_all": 8,
+	"version":   11,
+	"append":    14,
+	"prepend":   15,
+	"stats":     16,
+	"verbosity": 27,
+	"touch":     28,
+	"gat":       29,
+	"gats":      29,
+}
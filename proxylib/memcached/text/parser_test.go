@@ -0,0 +1,80 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package text
+
+import (
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TextMemcachedTestSuite struct{}
+
+var _ = Suite(&TextMemcachedTestSuite{})
+
+func (k *TextMemcachedTestSuite) TestGetKeys(c *C) {
+	tests := []struct {
+		request string
+		keys    []string
+	}{
+		{"get a", []string{"a"}},
+		{"gets a b c", []string{"a", "b", "c"}},
+		{"gat 10 a b", []string{"a", "b"}},
+		{"gat 10", nil},
+		{"set a 0 0 5 noreply", []string{"a"}},
+		{"cas a 0 0 5 42", []string{"a"}},
+		{"incr a 1", []string{"a"}},
+		{"delete", nil},
+		{"stats items", nil},
+		{"flush_all", nil},
+	}
+	for _, t := range tests {
+		fields := strings.Fields(t.request)
+		c.Assert(getKeys(fields[0], fields), DeepEquals, t.keys, Commentf("%s", t.request))
+	}
+}
+
+func (k *TextMemcachedTestSuite) TestSupportsNoReply(c *C) {
+	c.Assert(supportsNoReply("set"), Equals, true)
+	c.Assert(supportsNoReply("delete"), Equals, true)
+	c.Assert(supportsNoReply("flush_all"), Equals, true)
+	c.Assert(supportsNoReply("get"), Equals, false)
+	c.Assert(supportsNoReply("stats"), Equals, false)
+}
+
+func (k *TextMemcachedTestSuite) TestSplitCommandLine(c *C) {
+	tests := []struct {
+		line   string
+		fields []string
+		ok     bool
+	}{
+		{"get a\r", []string{"get", "a"}, true},
+		{"set  a 0 0 5  noreply", []string{"set", "a", "0", "0", "5", "noreply"}, true},
+		{"", []string{}, true},
+		{"\r", []string{}, true},
+		// memcached does not split on tabs and parses "0\t100" as 0
+		{"set tenant-a:k 0 0\t100 5\r", nil, false},
+		{"get a\x00b", nil, false},
+		{"get a\rb", nil, false},
+	}
+	for _, t := range tests {
+		fields, ok := splitCommandLine([]byte(t.line))
+		c.Assert(ok, Equals, t.ok, Commentf("%q", t.line))
+		c.Assert(fields, DeepEquals, t.fields, Commentf("%q", t.line))
+	}
+}
@@ -0,0 +1,571 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/envoy/cilium"
+	"github.com/cilium/cilium/proxylib/proxylib"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//
+// PostgreSQL v3 wire protocol parser
+//
+// Spec: https://www.postgresql.org/docs/current/protocol.html
+//
+
+// The parser enforces policy on the database and user given in the startup
+// message, and on the statements of simple and extended queries. Statements
+// are classified by their action (select, insert, update, delete, ddl,
+// transaction or other), and by the tables they access. Table names are
+// matched as they are written in the statement, i.e., possibly schema
+// qualified. Statements accessing no tables only match on their action.
+//
+// Examples:
+// database = 'shop', query_action = 'select', query_table = '^(public\.)?orders$'
+// user = 'reporting', query_action = 'select'
+//
+// A query holding multiple statements is allowed only if all of them are.
+// Likewise, each query in the WITH clause of a statement is classified by
+// its own action and must be allowed along with the primary statement.
+// Queries which can't be parsed are denied.
+// SSL and GSSAPI encryption requests are declined so that the client
+// continues in the clear, where policy can be enforced.
+
+// PostgresRule matches against PostgreSQL startup messages and statements
+type PostgresRule struct {
+	database           string
+	user               string
+	queryAction        string
+	tableRegexCompiled *regexp.Regexp
+}
+
+type postgresMeta struct {
+	database string
+	user     string
+	// action is empty for the startup message
+	action string
+	tables []string
+}
+
+// Matches returns true if the PostgresRule matches
+func (rule *PostgresRule) Matches(data interface{}) bool {
+	meta, ok := data.(postgresMeta)
+	if !ok {
+		log.Warnf("PostgresRule.Matches called with type other than postgresMeta, failing to match")
+		return false
+	}
+	log.Debugf("PostgresRule: checking rule %v against %v", *rule, meta)
+
+	if rule.database != "" && rule.database != meta.database {
+		return false
+	}
+	if rule.user != "" && rule.user != meta.user {
+		return false
+	}
+	if meta.action == "" {
+		// startup message
+		return true
+	}
+	if rule.queryAction != "" && rule.queryAction != meta.action {
+		return false
+	}
+	if rule.tableRegexCompiled != nil {
+		for _, table := range meta.tables {
+			if !rule.tableRegexCompiled.MatchString(table) {
+				log.Debugf("PostgresRule: query_table mismatch '%v', '%s'", rule.tableRegexCompiled, table)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// queryActions are the valid values of query_action
+var queryActions = map[string]struct{}{
+	"select":      {},
+	"insert":      {},
+	"update":      {},
+	"delete":      {},
+	"ddl":         {},
+	"transaction": {},
+	"other":       {},
+}
+
+// L7PostgresRuleParser parses protobuf L7 rules to an array of PostgresRule
+// May panic
+func L7PostgresRuleParser(rule *cilium.PortNetworkPolicyRule) []proxylib.L7NetworkPolicyRule {
+	l7Rules := rule.GetL7Rules()
+	if l7Rules == nil {
+		proxylib.ParseError("Can't get L7 rules", rule)
+	}
+	var rules []proxylib.L7NetworkPolicyRule
+	for _, l7Rule := range l7Rules.GetL7Rules() {
+		var pr PostgresRule
+		for k, v := range l7Rule.Rule {
+			switch k {
+			case "database":
+				pr.database = v
+			case "user":
+				pr.user = v
+			case "query_action":
+				if _, ok := queryActions[v]; !ok && v != "" {
+					proxylib.ParseError(fmt.Sprintf("Invalid query_action: '%s'", v), rule)
+				}
+				pr.queryAction = v
+			case "query_table":
+				if v != "" {
+					pr.tableRegexCompiled = regexp.MustCompile(v)
+				}
+			default:
+				proxylib.ParseError(fmt.Sprintf("Unsupported key: %s", k), rule)
+			}
+		}
+		log.Debugf("Parsed PostgresRule: %v", pr)
+		rules = append(rules, &pr)
+	}
+	return rules
+}
+
+// PostgresParserFactory implements proxylib.ParserFactory
+type PostgresParserFactory struct{}
+
+// Create creates PostgreSQL parser
+func (p *PostgresParserFactory) Create(connection *proxylib.Connection) proxylib.Parser {
+	log.Infof("PostgresParserFactory: Create: %v", connection)
+	return &PostgresParser{
+		connection:      connection,
+		standardStrings: true,
+		txStatus:        'I',
+	}
+}
+
+// compile time check for interface implementation
+var _ proxylib.ParserFactory = &PostgresParserFactory{}
+
+var postgresParserFactory *PostgresParserFactory
+
+const (
+	parserName = "postgres"
+)
+
+func init() {
+	log.Info("init(): Registering postgresParserFactory")
+	proxylib.RegisterParserFactory(parserName, postgresParserFactory)
+	proxylib.RegisterL7RuleParser(parserName, L7PostgresRuleParser)
+}
+
+// PostgresParser implements proxylib.Parser
+type PostgresParser struct {
+	connection *proxylib.Connection
+
+	// started is set once the startup message has been seen
+	started bool
+	// startupDenied is set if policy denied the startup message, all
+	// further requests are dropped
+	startupDenied bool
+	database      string
+	user          string
+
+	// standardStrings tracks the standard_conforming_strings server
+	// parameter, which determines whether backslashes escape quotes in
+	// string literals
+	standardStrings bool
+	// txStatus is the transaction status of the last ReadyForQuery
+	txStatus byte
+
+	// requestCount counts the requests the server replies to with
+	// ReadyForQuery, replyCount counts the ReadyForQuery replies
+	requestCount uint32
+	replyCount   uint32
+	injectQueue  []queuedInject
+
+	// discarding is set after a Parse message has been denied. Like the
+	// server does after an error, extended query messages are then
+	// discarded until the next Sync.
+	discarding bool
+}
+
+var _ proxylib.Parser = &PostgresParser{}
+
+type queuedInject struct {
+	requestID uint32
+	// ready is set if the request was dropped, so that ReadyForQuery has
+	// to be injected along with the error. Otherwise the error is
+	// injected before the ReadyForQuery of the server.
+	ready bool
+}
+
+const (
+	// headerLen is the length of the message type and length fields
+	headerLen = 5
+	// maxStartupLen is the maximum length of a startup message accepted
+	// by the server
+	maxStartupLen = 10000
+	// maxMessageLen is the maximum length of a message accepted by the
+	// server
+	maxMessageLen = 0x3fffffff
+
+	protocolVersion3  = 196608
+	cancelRequestCode = 80877102
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+)
+
+// OnData parses PostgreSQL data
+func (p *PostgresParser) OnData(reply, endStream bool, dataBuffers [][]byte, offset int) (proxylib.OpType, int) {
+	log.Debugf("OnData with offset %d", offset)
+
+	//TODO don't copy data from buffers
+	data := (bytes.Join(dataBuffers, []byte{}))[offset:]
+
+	if reply {
+		if n := p.injectFromQueue(data); n > 0 {
+			return proxylib.INJECT, n
+		}
+	}
+
+	if len(data) == 0 {
+		return proxylib.NOP, 0
+	}
+
+	if reply {
+		return p.onReply(data)
+	}
+	if !p.started {
+		return p.onStartup(data)
+	}
+	return p.onRequest(data)
+}
+
+func (p *PostgresParser) onStartup(data []byte) (proxylib.OpType, int) {
+	if len(data) < 8 {
+		return proxylib.MORE, 8 - len(data)
+	}
+	length := int(binary.BigEndian.Uint32(data[0:4]))
+	if length < 8 || length > maxStartupLen {
+		log.Warnf("Invalid startup message length %d", length)
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+
+	switch binary.BigEndian.Uint32(data[4:8]) {
+	case sslRequestCode, gssEncRequestCode:
+		log.Debugf("Declining encryption request")
+		p.connection.Inject(true, []byte{'N'})
+		return proxylib.DROP, length
+
+	case cancelRequestCode:
+		return proxylib.PASS, length
+
+	case protocolVersion3:
+		if length > len(data) {
+			return proxylib.MORE, length - len(data)
+		}
+		params := strings.Split(string(data[8:length]), "\x00")
+		for i := 0; i+1 < len(params); i += 2 {
+			switch params[i] {
+			case "database":
+				p.database = params[i+1]
+			case "user":
+				p.user = params[i+1]
+			}
+		}
+		if p.database == "" {
+			p.database = p.user
+		}
+		p.started = true
+
+		logEntry := &cilium.LogEntry_GenericL7{
+			&cilium.L7LogEntry{
+				Proto: "postgres",
+				Fields: map[string]string{
+					"database": p.database,
+					"user":     p.user,
+				},
+			},
+		}
+		if p.connection.Matches(postgresMeta{database: p.database, user: p.user}) {
+			p.connection.Log(cilium.EntryType_Request, logEntry)
+			return proxylib.PASS, length
+		}
+		p.startupDenied = true
+		p.connection.Inject(true, StartupDeniedMsg)
+		p.connection.Log(cilium.EntryType_Denied, logEntry)
+		return proxylib.DROP, length
+	}
+
+	log.Warnf("Unsupported protocol version in startup message")
+	return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+}
+
+func (p *PostgresParser) onRequest(data []byte) (proxylib.OpType, int) {
+	if len(data) < headerLen {
+		return proxylib.MORE, headerLen - len(data)
+	}
+	msgType := data[0]
+	length := int(binary.BigEndian.Uint32(data[1:headerLen]))
+	if length < 4 || length > maxMessageLen {
+		log.Warnf("Invalid message length %d", length)
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+	msgLen := 1 + length
+
+	if p.startupDenied {
+		return proxylib.DROP, msgLen
+	}
+
+	switch msgType {
+	case 'Q':
+		// Query: query string
+		if msgLen > len(data) {
+			return proxylib.MORE, msgLen - len(data)
+		}
+		p.requestCount++
+		if p.checkStatements(cString(data[headerLen:msgLen])) {
+			return proxylib.PASS, msgLen
+		}
+		p.injectDenied()
+		return proxylib.DROP, msgLen
+
+	case 'P':
+		// Parse: statement name, query string, parameter types
+		if p.discarding {
+			return proxylib.DROP, msgLen
+		}
+		if msgLen > len(data) {
+			return proxylib.MORE, msgLen - len(data)
+		}
+		body := data[headerLen:msgLen]
+		name := cString(body)
+		if len(name) >= len(body) {
+			log.Warnf("Parse message without query string")
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		if p.checkStatements(cString(body[len(name)+1:])) {
+			return proxylib.PASS, msgLen
+		}
+		p.discarding = true
+		return proxylib.DROP, msgLen
+
+	case 'F':
+		// FunctionCall bypasses statement parsing, it only matches
+		// rules allowing other actions
+		p.requestCount++
+		meta := postgresMeta{database: p.database, user: p.user, action: "other"}
+		if p.check(meta) {
+			return proxylib.PASS, msgLen
+		}
+		p.injectDenied()
+		return proxylib.DROP, msgLen
+
+	case 'S':
+		// Sync
+		p.requestCount++
+		if p.discarding {
+			p.discarding = false
+			p.injectQueue = append(p.injectQueue, queuedInject{requestID: p.requestCount})
+		}
+		return proxylib.PASS, msgLen
+
+	case 'B', 'E', 'D', 'C', 'H':
+		// Bind, Execute, Describe, Close, Flush
+		if p.discarding {
+			return proxylib.DROP, msgLen
+		}
+	}
+
+	return proxylib.PASS, msgLen
+}
+
+func (p *PostgresParser) onReply(data []byte) (proxylib.OpType, int) {
+	if len(data) < headerLen {
+		return proxylib.MORE, headerLen - len(data)
+	}
+	msgType := data[0]
+	length := int(binary.BigEndian.Uint32(data[1:headerLen]))
+	if length < 4 || length > maxMessageLen {
+		log.Warnf("Invalid reply message length %d", length)
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+	msgLen := 1 + length
+
+	switch msgType {
+	case 'Z':
+		// ReadyForQuery: transaction status
+		if msgLen != headerLen+1 {
+			log.Warnf("Invalid ReadyForQuery message length %d", length)
+			return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+		}
+		if msgLen > len(data) {
+			return proxylib.MORE, msgLen - len(data)
+		}
+		p.txStatus = data[headerLen]
+		p.replyCount++
+
+	case 'S':
+		// ParameterStatus: name, value
+		if msgLen > len(data) {
+			return proxylib.MORE, msgLen - len(data)
+		}
+		params := strings.Split(string(data[headerLen:msgLen]), "\x00")
+		if len(params) >= 2 && params[0] == "standard_conforming_strings" {
+			p.standardStrings = params[1] == "on"
+		}
+	}
+
+	log.Debugf("reply, passing %d bytes", msgLen)
+	return proxylib.PASS, msgLen
+}
+
+// checkStatements returns true if policy allows all statements in query
+func (p *PostgresParser) checkStatements(query string) bool {
+	// Queries which can't be classified are denied
+	statements, ok := tokenize(query, p.standardStrings)
+	if !ok {
+		log.Debugf("Unable to tokenize query '%s'", query)
+		p.logVerdict(postgresMeta{database: p.database, user: p.user, action: "other"}, false)
+		return false
+	}
+
+	matches := true
+	for _, tokens := range statements {
+		ops, ok := parseStatement(tokens)
+		if !ok {
+			log.Debugf("Unable to parse statement '%s'", strings.Join(tokens, " "))
+			p.logVerdict(postgresMeta{database: p.database, user: p.user, action: "other"}, false)
+			return false
+		}
+		for _, op := range ops {
+			meta := postgresMeta{
+				database: p.database,
+				user:     p.user,
+				action:   op.action,
+				tables:   op.tables,
+			}
+			if !p.check(meta) {
+				matches = false
+			}
+		}
+	}
+	return matches
+}
+
+// check matches meta against policy and logs the result
+func (p *PostgresParser) check(meta postgresMeta) bool {
+	matches := p.connection.Matches(meta)
+	p.logVerdict(meta, matches)
+	return matches
+}
+
+// logVerdict logs the policy verdict for meta
+func (p *PostgresParser) logVerdict(meta postgresMeta, matches bool) {
+	entryType := cilium.EntryType_Request
+	if !matches {
+		entryType = cilium.EntryType_Denied
+	}
+	p.connection.Log(entryType,
+		&cilium.LogEntry_GenericL7{
+			&cilium.L7LogEntry{
+				Proto: "postgres",
+				Fields: map[string]string{
+					"database":     meta.database,
+					"user":         meta.user,
+					"query_action": meta.action,
+					"query_table":  strings.Join(meta.tables, ","),
+				},
+			},
+		})
+}
+
+// injectDenied injects the error and ReadyForQuery for a dropped request,
+// in order with the replies of the server
+func (p *PostgresParser) injectDenied() {
+	if p.requestCount == p.replyCount+1 {
+		p.injectDeniedMessage(true)
+	} else {
+		p.injectQueue = append(p.injectQueue, queuedInject{requestID: p.requestCount, ready: true})
+	}
+}
+
+func (p *PostgresParser) injectDeniedMessage(ready bool) int {
+	msg := DeniedMsg
+	if ready {
+		msg = append(append([]byte{}, DeniedMsg...), readyForQuery(p.txStatus)...)
+		p.replyCount++
+	}
+	p.connection.Inject(true, msg)
+	return len(msg)
+}
+
+// injectFromQueue injects the next queued error if it is due, and returns
+// the number of injected bytes
+func (p *PostgresParser) injectFromQueue(data []byte) int {
+	if len(p.injectQueue) == 0 || p.injectQueue[0].requestID != p.replyCount+1 {
+		return 0
+	}
+	ready := p.injectQueue[0].ready
+	if !ready && (len(data) == 0 || data[0] != 'Z') {
+		// wait for the ReadyForQuery of the server
+		return 0
+	}
+	p.injectQueue = p.injectQueue[1:]
+	return p.injectDeniedMessage(ready)
+}
+
+// cString returns the null terminated string at the start of data
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+func errorResponse(severity, code, message string) []byte {
+	msg := []byte{'E', 0, 0, 0, 0}
+	for _, field := range []struct {
+		fieldType byte
+		value     string
+	}{
+		{'S', severity},
+		{'V', severity},
+		{'C', code},
+		{'M', message},
+	} {
+		msg = append(msg, field.fieldType)
+		msg = append(msg, field.value...)
+		msg = append(msg, 0)
+	}
+	msg = append(msg, 0)
+	binary.BigEndian.PutUint32(msg[1:headerLen], uint32(len(msg)-1))
+	return msg
+}
+
+func readyForQuery(txStatus byte) []byte {
+	return []byte{'Z', 0, 0, 0, 5, txStatus}
+}
+
+// DeniedMsg is sent if policy denies a statement. Exported for tests
+var DeniedMsg = errorResponse("ERROR", "42501", "access denied by policy")
+
+// StartupDeniedMsg is sent if policy denies the database or user of the
+// startup message. Exported for tests
+var StartupDeniedMsg = errorResponse("FATAL", "28000", "access denied by policy")
@@ -0,0 +1,205 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"regexp"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type PostgresTestSuite struct{}
+
+var _ = Suite(&PostgresTestSuite{})
+
+func (s *PostgresTestSuite) TestTokenize(c *C) {
+	statements, ok := tokenize(`SELECT 'a;b' FROM "My""Table"; -- comment;
+		/* nested /* comment; */ */ select $$a;b$$, $tag$;$tag$, E'\';', $1`, true)
+	c.Assert(ok, Equals, true)
+	c.Assert(statements, DeepEquals, [][]string{
+		{"select", "'", "from", `"My"Table`},
+		{"select", "'", ",", "'", ",", "'", ",", "$1"},
+	})
+
+	statements, ok = tokenize(`select 'a\'; drop table b; '`, false)
+	c.Assert(ok, Equals, true)
+	c.Assert(statements, DeepEquals, [][]string{{"select", "'"}})
+
+	for _, query := range []string{
+		"select 'a",
+		`select "a`,
+		"select /* /* */",
+		"select $a$b",
+	} {
+		_, ok = tokenize(query, true)
+		c.Assert(ok, Equals, false, Commentf("%s", query))
+	}
+}
+
+func (s *PostgresTestSuite) TestParseStatement(c *C) {
+	tests := []struct {
+		query  string
+		action string
+		tables []string
+	}{
+		{"select 1", "select", []string{}},
+		{"select * from orders", "select", []string{"orders"}},
+		{"select * from public.orders o, items as i where o.id = i.id", "select", []string{"public.orders", "items"}},
+		{"select * from a join b on a.id = b.id left outer join c using (id)", "select", []string{"a", "b", "c"}},
+		{"select extract(year from ts) from a", "select", []string{"a"}},
+		{"select * from (select * from a) x where id in (select id from b)", "select", []string{"a", "b"}},
+		{"select * from generate_series(1, 3)", "select", []string{}},
+		{"SELECT * FROM (secret CROSS JOIN orders)", "select", []string{"secret", "orders"}},
+		{"select * from ((select * from a) x join (b natural join c) on x.id = b.id) as y", "select", []string{"b", "c", "a"}},
+		{"select * from orders where id in (table secret)", "select", []string{"orders", "secret"}},
+		{"select * from a union all table b", "select", []string{"a", "b"}},
+		{"select * from rows from (f(), g()) with ordinality as r (x, y), secret", "select", []string{"secret"}},
+		{"select * from a t tablesample system (10) repeatable (1), lateral f(t.x) as (y int)", "select", []string{"a"}},
+		{"select * from a join b on left(a.x, 1) = b.x join c using (id) where a.y is not distinct from b.y", "select", []string{"a", "b", "c"}},
+		{"table a", "select", []string{"a"}},
+		{"insert into a (id) table b", "insert", []string{"a", "b"}},
+		{"insert into a (id) values (1)", "insert", []string{"a"}},
+		{"insert into a select * from b", "insert", []string{"a", "b"}},
+		{"update only a set x = 1 from b where a.id = b.id", "update", []string{"a", "b"}},
+		{"delete from a using b where a.id = b.id", "delete", []string{"a", "b"}},
+		{"explain (analyze, verbose) delete from a", "delete", []string{"a"}},
+		{"explain analyze select * from a", "select", []string{"a"}},
+		{"prepare q (int) as update a set x = $1", "update", []string{"a"}},
+		{"copy a from stdin", "insert", []string{"a"}},
+		{"copy a (id) to stdout", "select", []string{"a"}},
+		{"copy (select * from a) to stdout", "select", []string{"a"}},
+		{"copy (delete from a returning *) to stdout", "delete", []string{"a"}},
+		{"create table if not exists a (id int default extract(year from now()))", "ddl", []string{"a"}},
+		{"create table a as select * from b", "ddl", []string{"a", "b"}},
+		{"create unique index i on only a (id)", "ddl", []string{"a"}},
+		{"alter table if exists a add column b int", "ddl", []string{"a"}},
+		{"drop table a, b cascade", "ddl", []string{"a", "b"}},
+		{"truncate table a", "ddl", []string{"a"}},
+		{"revoke select on a from b", "ddl", []string{}},
+		{"begin", "transaction", []string{}},
+		{"prepare transaction 'x'", "transaction", []string{}},
+		{"set search_path = a", "other", []string{}},
+		{"do $$ begin end $$", "other", []string{}},
+	}
+	for _, t := range tests {
+		statements, ok := tokenize(t.query, true)
+		c.Assert(ok, Equals, true)
+		c.Assert(statements, HasLen, 1)
+		ops, ok := parseStatement(statements[0])
+		c.Assert(ok, Equals, true, Commentf("%s", t.query))
+		c.Assert(ops, HasLen, 1, Commentf("%s", t.query))
+		c.Assert(ops[0].action, Equals, t.action, Commentf("%s", t.query))
+		tables := ops[0].tables
+		if tables == nil {
+			tables = []string{}
+		}
+		c.Assert(tables, DeepEquals, t.tables, Commentf("%s", t.query))
+	}
+
+	// Statements with FROM items which can't be parsed are rejected, as the
+	// tables they access are unknown
+	for _, query := range []string{
+		"select * from",
+		"select * from a join",
+		"select * from (a join b",
+		"select * from (a join b on a.id = b.id, c)",
+		"select * from a where id in (select id from)",
+		"select * from a tablesample system",
+		"select * from a)",
+		"update a set x = 1 from",
+		"drop table",
+	} {
+		statements, ok := tokenize(query, true)
+		c.Assert(ok, Equals, true)
+		_, ok = parseStatement(statements[0])
+		c.Assert(ok, Equals, false, Commentf("%s", query))
+	}
+}
+
+func (s *PostgresTestSuite) TestParseWithQueries(c *C) {
+	tests := []struct {
+		query string
+		ops   []operation
+	}{
+		{"with x as (select * from a) delete from b where id in (select id from x)", []operation{
+			{"select", []string{"a"}},
+			{"delete", []string{"b", "x"}},
+		}},
+		{"with d as (delete from orders returning *) select 1", []operation{
+			{"delete", []string{"orders"}},
+			{"select", []string{}},
+		}},
+		{"with recursive x (id) as not materialized (select 1), " +
+			"y as (update a set v = 1 from b returning *), " +
+			"z as (insert into c select * from y returning *) select * from x, z", []operation{
+			{"select", []string{}},
+			{"update", []string{"a", "b"}},
+			{"insert", []string{"c", "y"}},
+			{"select", []string{"x", "z"}},
+		}},
+		{"explain analyze with d as (delete from a returning *) select * from d", []operation{
+			{"delete", []string{"a"}},
+			{"select", []string{"d"}},
+		}},
+	}
+	for _, t := range tests {
+		statements, ok := tokenize(t.query, true)
+		c.Assert(ok, Equals, true)
+		c.Assert(statements, HasLen, 1)
+		ops, ok := parseStatement(statements[0])
+		c.Assert(ok, Equals, true, Commentf("%s", t.query))
+		for i := range ops {
+			if ops[i].tables == nil {
+				ops[i].tables = []string{}
+			}
+		}
+		c.Assert(ops, DeepEquals, t.ops, Commentf("%s", t.query))
+	}
+
+	for _, query := range []string{
+		"with select 1",
+		"with x (select 1) select * from x",
+		"with x as (select 1",
+		"with x as (select 1)",
+		"with x as select 1",
+	} {
+		statements, ok := tokenize(query, true)
+		c.Assert(ok, Equals, true)
+		_, ok = parseStatement(statements[0])
+		c.Assert(ok, Equals, false, Commentf("%s", query))
+	}
+}
+
+func (s *PostgresTestSuite) TestRuleMatches(c *C) {
+	rule := &PostgresRule{
+		database:           "shop",
+		queryAction:        "select",
+		tableRegexCompiled: regexp.MustCompile(`^(public\.)?orders$`),
+	}
+
+	c.Assert(rule.Matches(postgresMeta{database: "shop", user: "a"}), Equals, true)
+	c.Assert(rule.Matches(postgresMeta{database: "other", user: "a"}), Equals, false)
+	c.Assert(rule.Matches(postgresMeta{database: "shop", action: "select", tables: []string{"orders", "public.orders"}}), Equals, true)
+	c.Assert(rule.Matches(postgresMeta{database: "shop", action: "select", tables: []string{"orders", "items"}}), Equals, false)
+	c.Assert(rule.Matches(postgresMeta{database: "shop", action: "select"}), Equals, true)
+	c.Assert(rule.Matches(postgresMeta{database: "shop", action: "delete", tables: []string{"orders"}}), Equals, false)
+
+	rule = &PostgresRule{user: "admin"}
+	c.Assert(rule.Matches(postgresMeta{database: "shop", user: "admin", action: "ddl"}), Equals, true)
+	c.Assert(rule.Matches(postgresMeta{database: "shop", user: "a", action: "ddl"}), Equals, false)
+}
@@ -0,0 +1,781 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"strings"
+)
+
+// Tokens of quoted identifiers start with a double quote, string literals
+// are all represented by stringToken. Keywords and unquoted identifiers are
+// lower cased.
+const (
+	identQuote  = '"'
+	stringToken = "'"
+)
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// tokenize splits query into statements and these into tokens. Comments
+// are skipped. It returns false if query has unterminated quotes or
+// comments. If standardStrings is false, backslashes escape quotes in all
+// string literals, not only in escape string literals.
+func tokenize(query string, standardStrings bool) ([][]string, bool) {
+	var statements [][]string
+	var tokens []string
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+
+		case strings.HasPrefix(query[i:], "/*"):
+			// block comments nest
+			depth := 0
+			for i < len(query) {
+				if strings.HasPrefix(query[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(query[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			if depth > 0 {
+				return nil, false
+			}
+
+		case (c == 'e' || c == 'E') && i+1 < len(query) && query[i+1] == '\'':
+			n := stringLength(query[i+1:], true)
+			if n == 0 {
+				return nil, false
+			}
+			tokens = append(tokens, stringToken)
+			i += 1 + n
+
+		case c == '\'':
+			n := stringLength(query[i:], !standardStrings)
+			if n == 0 {
+				return nil, false
+			}
+			tokens = append(tokens, stringToken)
+			i += n
+
+		case c == '"':
+			var ident []byte
+			j := i + 1
+			for {
+				end := strings.IndexByte(query[j:], '"')
+				if end < 0 {
+					return nil, false
+				}
+				ident = append(ident, query[j:j+end]...)
+				j += end + 1
+				if j < len(query) && query[j] == '"' {
+					// escaped double quote
+					ident = append(ident, '"')
+					j++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, string(identQuote)+string(ident))
+			i = j
+
+		case c == '$' && i+1 < len(query) && !(query[i+1] >= '0' && query[i+1] <= '9'):
+			// dollar quoted string: $tag$...$tag$
+			end := strings.IndexByte(query[i+1:], '$')
+			if end < 0 {
+				return nil, false
+			}
+			tag := query[i : i+end+2]
+			for j := 1; j < len(tag)-1; j++ {
+				if !isWordChar(tag[j]) {
+					return nil, false
+				}
+			}
+			close := strings.Index(query[i+len(tag):], tag)
+			if close < 0 {
+				return nil, false
+			}
+			tokens = append(tokens, stringToken)
+			i += len(tag) + close + len(tag)
+
+		case c == ';':
+			if len(tokens) > 0 {
+				statements = append(statements, tokens)
+				tokens = nil
+			}
+			i++
+
+		case isWordChar(c) || c == '$':
+			j := i + 1
+			for j < len(query) && (isWordChar(query[j]) || query[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(query[i:j]))
+			i = j
+
+		default:
+			tokens = append(tokens, query[i:i+1])
+			i++
+		}
+	}
+	if len(tokens) > 0 {
+		statements = append(statements, tokens)
+	}
+	return statements, true
+}
+
+// stringLength returns the length of the string literal at the start of s
+// including its quotes, or 0 if it is not terminated
+func stringLength(s string, backslashEscapes bool) int {
+	for j := 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if backslashEscapes {
+				j++
+			}
+		case '\'':
+			if j+1 < len(s) && s[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return 0
+}
+
+// isName returns true if token may be part of a table name
+func isName(token string) bool {
+	if token == "" || token == stringToken {
+		return false
+	}
+	c := token[0]
+	return c == identQuote || c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z')
+}
+
+// aliasStopWords are the keywords which may follow a table name in a FROM
+// list, and which therefore are not an alias of the table
+var aliasStopWords = map[string]struct{}{
+	"cross": {}, "do": {}, "except": {}, "fetch": {}, "for": {}, "from": {},
+	"full": {}, "group": {}, "having": {}, "inner": {}, "intersect": {},
+	"into": {}, "join": {}, "left": {}, "limit": {}, "natural": {},
+	"offset": {}, "on": {}, "order": {}, "returning": {}, "right": {},
+	"select": {}, "set": {}, "tablesample": {}, "union": {}, "using": {},
+	"values": {}, "where": {}, "window": {}, "cascade": {}, "restrict": {},
+}
+
+// readName reads the possibly qualified name starting at tokens[i], and
+// returns it along with the index of the token following it
+func readName(tokens []string, i int) (string, int) {
+	var parts []string
+	for i < len(tokens) && isName(tokens[i]) {
+		parts = append(parts, strings.TrimPrefix(tokens[i], string(identQuote)))
+		i++
+		if i+1 < len(tokens) && tokens[i] == "." {
+			i++
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "."), i
+}
+
+// readTables reads the comma separated list of FROM items starting at
+// tokens[i], along with the items joined to them, and returns the tables in
+// them. The tables of subqueries are not returned, queryTables finds them.
+// It returns false if an item can't be parsed.
+func readTables(tokens []string, i int) ([]string, bool) {
+	var tables []string
+	for {
+		itemTables, next, ok := readJoinedItem(tokens, i)
+		if !ok {
+			return nil, false
+		}
+		tables = append(tables, itemTables...)
+		i = next
+		if i >= len(tokens) || tokens[i] != "," {
+			return tables, true
+		}
+		i++
+	}
+}
+
+// joinConditionEnd are the keywords which end the condition of a join
+var joinConditionEnd = map[string]struct{}{
+	",": {}, "cross": {}, "except": {}, "fetch": {}, "for": {}, "full": {},
+	"group": {}, "having": {}, "inner": {}, "intersect": {}, "join": {},
+	"left": {}, "limit": {}, "natural": {}, "offset": {}, "on": {},
+	"order": {}, "returning": {}, "right": {}, "union": {}, "where": {},
+	"window": {},
+}
+
+// readJoinedItem reads the FROM item starting at tokens[i] and the items
+// joined to it. It returns the tables in them and the index of the token
+// following them.
+//
+// from_item [ NATURAL ] join_type from_item [ ON condition | USING ( column [, ...] ) ] ...
+func readJoinedItem(tokens []string, i int) ([]string, int, bool) {
+	tables, i, ok := readFromItem(tokens, i)
+	if !ok {
+		return nil, i, false
+	}
+	for {
+		j := skip(tokens, i, "natural")
+		if j < len(tokens) {
+			switch tokens[j] {
+			case "cross", "inner":
+				j++
+			case "left", "right", "full":
+				j = skip(tokens, j+1, "outer")
+			}
+		}
+		if j >= len(tokens) || tokens[j] != "join" {
+			return tables, i, true
+		}
+
+		joined, next, ok := readFromItem(tokens, j+1)
+		if !ok {
+			return nil, next, false
+		}
+		tables = append(tables, joined...)
+		i = next
+
+		if i < len(tokens) && tokens[i] == "using" {
+			if i+1 >= len(tokens) || tokens[i+1] != "(" {
+				return nil, i, false
+			}
+			if i = closingParen(tokens, i+1); i < 0 {
+				return nil, i, false
+			}
+			i = skip(tokens, i+1, "as")
+			if i < len(tokens) && isName(tokens[i]) {
+				i++
+			}
+		} else if i < len(tokens) && tokens[i] == "on" {
+			i = expressionEnd(tokens, i+1, joinConditionEnd)
+		}
+	}
+}
+
+// readFromItem reads the FROM item starting at tokens[i], which is a table,
+// a function call, a subquery or a parenthesized join, along with its alias.
+// It returns the tables in the item and the index of the token following it.
+func readFromItem(tokens []string, i int) ([]string, int, bool) {
+	var tables []string
+	i = skip(tokens, i, "lateral", "only")
+	if i >= len(tokens) {
+		return nil, i, false
+	}
+
+	switch {
+	case tokens[i] == "(":
+		end := closingParen(tokens, i)
+		if end < 0 {
+			return nil, i, false
+		}
+		if !isSubquery(tokens, i) {
+			// ( from_item join_type from_item ... )
+			inner := tokens[i+1 : end]
+			joined, next, ok := readJoinedItem(inner, 0)
+			if !ok || next != len(inner) {
+				return nil, i, false
+			}
+			tables = joined
+		}
+		i = end + 1
+
+	case tokens[i] == "rows" && i+2 < len(tokens) && tokens[i+1] == "from" && tokens[i+2] == "(":
+		// ROWS FROM ( function_call [, ...] ) [ WITH ORDINALITY ]
+		end := closingParen(tokens, i+2)
+		if end < 0 {
+			return nil, i, false
+		}
+		i = skip(tokens, end+1, "with", "ordinality")
+
+	default:
+		name, next := readName(tokens, i)
+		if name == "" {
+			return nil, i, false
+		}
+		i = next
+		if i < len(tokens) && tokens[i] == "(" {
+			// function call, subqueries in its arguments are found by
+			// queryTables
+			end := closingParen(tokens, i)
+			if end < 0 {
+				return nil, i, false
+			}
+			i = skip(tokens, end+1, "with", "ordinality")
+		} else {
+			tables = append(tables, name)
+			i = skip(tokens, i, "*")
+		}
+	}
+
+	// [ AS ] alias [ ( column_alias [, ...] ) ]
+	if i < len(tokens) && tokens[i] == "as" {
+		i++
+		if i < len(tokens) && isName(tokens[i]) {
+			i++
+		}
+	} else if i < len(tokens) && isName(tokens[i]) {
+		if _, ok := aliasStopWords[tokens[i]]; !ok {
+			i++
+		}
+	}
+	if i < len(tokens) && tokens[i] == "(" {
+		if i = closingParen(tokens, i); i < 0 {
+			return nil, i, false
+		}
+		i++
+	}
+
+	// TABLESAMPLE sampling_method ( argument [, ...] ) [ REPEATABLE ( seed ) ]
+	if i < len(tokens) && tokens[i] == "tablesample" {
+		if _, next := readName(tokens, i+1); next < len(tokens) && tokens[next] == "(" {
+			i = closingParen(tokens, next)
+		} else {
+			i = -1
+		}
+		if i >= 0 && i+2 < len(tokens) && tokens[i+1] == "repeatable" && tokens[i+2] == "(" {
+			i = closingParen(tokens, i+2)
+		}
+		if i < 0 {
+			return nil, i, false
+		}
+		i++
+	}
+
+	return tables, i, true
+}
+
+// isSubquery returns true if the parenthesis at tokens[i] opens a subquery
+func isSubquery(tokens []string, i int) bool {
+	if i+1 >= len(tokens) {
+		return false
+	}
+	switch tokens[i+1] {
+	case "select", "with", "values", "table":
+		return true
+	}
+	return false
+}
+
+// expressionEnd returns the index of the first token at the top level of
+// the expression starting at tokens[i] which is one of words, or of the
+// parenthesis closing the one the expression is in. Words followed by a
+// parenthesis are function calls, e.g. "left(name, 1)", and are skipped.
+func expressionEnd(tokens []string, i int, words map[string]struct{}) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			if depth == 0 {
+				return i
+			}
+			depth--
+		default:
+			if _, ok := words[tokens[i]]; ok && depth == 0 &&
+				(i+1 >= len(tokens) || tokens[i+1] != "(") {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// skip returns the index of the first token at or after tokens[i] which is
+// not one of words, in order
+func skip(tokens []string, i int, words ...string) int {
+	for _, word := range words {
+		if i < len(tokens) && tokens[i] == word {
+			i++
+		}
+	}
+	return i
+}
+
+// indexAtTop returns the index of the first of words at the top level of
+// tokens, i.e., outside of parentheses, starting at tokens[i]
+func indexAtTop(tokens []string, i int, words ...string) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		default:
+			if depth == 0 {
+				for _, word := range words {
+					if tokens[i] == word {
+						return i
+					}
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// tableQueryPrefix are the tokens which may precede a TABLE query in
+// another query
+var tableQueryPrefix = map[string]struct{}{
+	"(": {}, "all": {}, "as": {}, "distinct": {}, "except": {},
+	"intersect": {}, "union": {},
+}
+
+// isFromClause returns false if the FROM keyword at tokens[i] does not
+// start a FROM clause, e.g. in "a IS DISTINCT FROM b" or "ROWS FROM (...)"
+func isFromClause(tokens []string, i int) bool {
+	if i > 0 && tokens[i-1] == "rows" {
+		return false
+	}
+	if i > 1 && tokens[i-1] == "distinct" && (tokens[i-2] == "is" || tokens[i-2] == "not") {
+		return false
+	}
+	return true
+}
+
+// queryTables returns the tables in the FROM and JOIN clauses and the TABLE
+// queries of the queries in tokens. Parentheses which do not hold a
+// subquery, e.g., the arguments of "extract(year from ...)", are skipped.
+// If topLevel is false, only subqueries are searched. The tables following
+// any of the extra words at the top level are returned as well. It returns
+// false if a FROM item can't be parsed or the parentheses are unbalanced, as
+// the tables accessed are unknown then.
+func queryTables(tokens []string, topLevel bool, extra ...string) ([]string, bool) {
+	var tables []string
+	inQuery := []bool{topLevel}
+	for i := 0; i < len(tokens); i++ {
+		read := false
+		switch tokens[i] {
+		case "(":
+			inQuery = append(inQuery, isSubquery(tokens, i))
+		case ")":
+			if len(inQuery) == 1 {
+				return nil, false
+			}
+			inQuery = inQuery[:len(inQuery)-1]
+		case "from", "join":
+			read = inQuery[len(inQuery)-1] && isFromClause(tokens, i)
+		case "table":
+			if inQuery[len(inQuery)-1] && i > 0 {
+				_, read = tableQueryPrefix[tokens[i-1]]
+			}
+		}
+		if len(inQuery) == 1 && topLevel {
+			for _, word := range extra {
+				if tokens[i] == word {
+					read = true
+				}
+			}
+		}
+		if read {
+			itemTables, ok := readTables(tokens, i+1)
+			if !ok {
+				return nil, false
+			}
+			tables = append(tables, itemTables...)
+		}
+	}
+	if len(inQuery) != 1 {
+		return nil, false
+	}
+	return tables, true
+}
+
+// ddlTables returns the table a data definition statement applies to, if
+// it applies to a table at all
+func ddlTables(tokens []string) ([]string, bool) {
+	switch tokens[0] {
+	case "create":
+		i := skip(tokens, 1, "or", "replace")
+		i = skip(tokens, i, "temp", "temporary", "unlogged", "global", "local")
+		i = skip(tokens, i, "unique")
+		// the name is followed by the column list, so it can't be
+		// read as a table list
+		var name string
+		if i < len(tokens) && tokens[i] == "table" {
+			name, _ = readName(tokens, skip(tokens, i+1, "if", "not", "exists"))
+		} else if i < len(tokens) && tokens[i] == "index" {
+			if on := indexAtTop(tokens, i, "on"); on >= 0 {
+				name, _ = readName(tokens, skip(tokens, on+1, "only"))
+			}
+		}
+		if name != "" {
+			return []string{name}, true
+		}
+	case "alter", "drop":
+		if len(tokens) > 1 && tokens[1] == "table" {
+			return readTables(tokens, skip(tokens, 2, "if", "exists"))
+		}
+	case "truncate":
+		return readTables(tokens, skip(tokens, 1, "table"))
+	case "comment":
+		if len(tokens) > 2 && tokens[1] == "on" && tokens[2] == "table" {
+			return readTables(tokens, 3)
+		}
+	}
+	return nil, true
+}
+
+// operation is an action along with the tables it accesses. A statement
+// with data modifying queries in its WITH clause consists of several
+// operations, each of which must be allowed by policy.
+type operation struct {
+	action string
+	tables []string
+}
+
+// parseStatement returns the operations of the statement in tokens. It
+// returns false if the statement is malformed.
+func parseStatement(tokens []string) ([]operation, bool) {
+	ops, ok := statementOperations(tokens)
+	if !ok {
+		return nil, false
+	}
+
+	// remove duplicates
+	for i := range ops {
+		seen := make(map[string]struct{}, len(ops[i].tables))
+		unique := ops[i].tables[:0]
+		for _, table := range ops[i].tables {
+			if _, ok := seen[table]; !ok {
+				seen[table] = struct{}{}
+				unique = append(unique, table)
+			}
+		}
+		ops[i].tables = unique
+	}
+	return ops, true
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// tokens[i], or -1 if it is not closed
+func closingParen(tokens []string, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// withQueries returns the tokens of the queries in the WITH clause at the
+// start of tokens and the index of the primary statement following it. The
+// index is -1 if the clause is malformed.
+//
+// WITH [ RECURSIVE ] name [ ( column [, ...] ) ] AS [ NOT ] [ MATERIALIZED ] ( query ) [, ...]
+func withQueries(tokens []string) ([][]string, int) {
+	var queries [][]string
+	i := skip(tokens, 1, "recursive")
+	for {
+		name, next := readName(tokens, i)
+		if name == "" {
+			return nil, -1
+		}
+		i = next
+		if i < len(tokens) && tokens[i] == "(" {
+			if i = closingParen(tokens, i); i < 0 {
+				return nil, -1
+			}
+			i++
+		}
+		if i >= len(tokens) || tokens[i] != "as" {
+			return nil, -1
+		}
+		i = skip(tokens, i+1, "not", "materialized")
+		if i >= len(tokens) || tokens[i] != "(" {
+			return nil, -1
+		}
+		end := closingParen(tokens, i)
+		if end < 0 {
+			return nil, -1
+		}
+		queries = append(queries, tokens[i+1:end])
+
+		// skip SEARCH and CYCLE clauses
+		i = indexAtTop(tokens, end+1, ",", "select", "values", "table", "insert", "update", "delete")
+		if i < 0 {
+			return nil, -1
+		}
+		if tokens[i] != "," {
+			return queries, i
+		}
+		i++
+	}
+}
+
+// statementOperations returns the operations of the statement in tokens,
+// following statements embedded in other statements
+func statementOperations(tokens []string) ([]operation, bool) {
+	if len(tokens) == 0 {
+		return []operation{{action: "other"}}, true
+	}
+
+	switch tokens[0] {
+	case "explain":
+		// EXPLAIN [ ( option [, ...] ) ] [ ANALYZE ] [ VERBOSE ] statement
+		i := 1
+		if i < len(tokens) && tokens[i] == "(" {
+			if i = closingParen(tokens, i); i < 0 {
+				return nil, false
+			}
+			i++
+		}
+		if i >= len(tokens) {
+			return []operation{{action: "other"}}, true
+		}
+		return statementOperations(tokens[skip(tokens, i, "analyze", "verbose"):])
+
+	case "prepare":
+		if len(tokens) > 1 && tokens[1] == "transaction" {
+			return []operation{{action: "transaction"}}, true
+		}
+		// PREPARE name [ ( data_type [, ...] ) ] AS statement
+		if as := indexAtTop(tokens, 1, "as"); as >= 0 {
+			return statementOperations(tokens[as+1:])
+		}
+		return []operation{{action: "other"}}, true
+
+	case "with":
+		// Each query in the WITH clause may modify data on its own
+		queries, i := withQueries(tokens)
+		if i < 0 {
+			return nil, false
+		}
+		var ops []operation
+		for _, query := range append(queries, tokens[i:]) {
+			queryOps, ok := statementOperations(query)
+			if !ok {
+				return nil, false
+			}
+			ops = append(ops, queryOps...)
+		}
+		return ops, true
+
+	case "copy":
+		// COPY ( query ) TO ..., the query may modify data if it has a
+		// RETURNING clause
+		if len(tokens) > 1 && tokens[1] == "(" {
+			end := closingParen(tokens, 1)
+			if end < 0 {
+				return nil, false
+			}
+			return statementOperations(tokens[2:end])
+		}
+	}
+
+	action, tables, ok := statementTables(tokens)
+	if !ok {
+		return nil, false
+	}
+	return []operation{{action: action, tables: tables}}, true
+}
+
+// statementTables returns the action of a statement which does not embed
+// other statements and the tables it accesses. It returns false if the
+// tables can't be determined.
+func statementTables(tokens []string) (string, []string, bool) {
+	switch tokens[0] {
+	case "select", "values":
+		tables, ok := queryTables(tokens, true)
+		return "select", tables, ok
+
+	case "table":
+		tables, ok := queryTables(tokens, true, "table")
+		return "select", tables, ok
+
+	case "insert":
+		var tables []string
+		if name, _ := readName(tokens, skip(tokens, 1, "into")); name != "" {
+			tables = append(tables, name)
+		}
+		// INSERT INTO table [ ( column [, ...] ) ] TABLE other
+		queried, ok := queryTables(tokens, true, "table")
+		return "insert", append(tables, queried...), ok
+
+	case "update":
+		tables, ok := readTables(tokens, 1)
+		if !ok {
+			return "", nil, false
+		}
+		queried, ok := queryTables(tokens, true)
+		return "update", append(tables, queried...), ok
+
+	case "delete":
+		tables, ok := queryTables(tokens, true, "using")
+		return "delete", tables, ok
+
+	case "copy":
+		// COPY table [ ( column_name [, ...] ) ] FROM | TO ...
+		name, _ := readName(tokens, 1)
+		if name == "" {
+			return "other", nil, true
+		}
+		if i := indexAtTop(tokens, 2, "from", "to"); i >= 0 && tokens[i] == "from" {
+			return "insert", []string{name}, true
+		}
+		return "select", []string{name}, true
+
+	case "create", "alter", "drop", "truncate", "comment", "grant", "revoke":
+		tables, ok := ddlTables(tokens)
+		if !ok {
+			return "", nil, false
+		}
+		// REVOKE ... FROM names roles, not tables
+		queried, ok := queryTables(tokens, tokens[0] != "revoke")
+		return "ddl", append(tables, queried...), ok
+
+	case "begin", "start", "commit", "end", "rollback", "abort", "savepoint", "release":
+		return "transaction", nil, true
+	}
+
+	return "other", nil, true
+}
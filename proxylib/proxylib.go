@@ -25,9 +25,13 @@ import (
 
 	"github.com/cilium/cilium/proxylib/accesslog"
 	_ "github.com/cilium/cilium/proxylib/cassandra"
+	_ "github.com/cilium/cilium/proxylib/memcached"
 	_ "github.com/cilium/cilium/proxylib/memcached/binary"
+	_ "github.com/cilium/cilium/proxylib/memcached/text"
 	"github.com/cilium/cilium/proxylib/npds"
 	. "github.com/cilium/cilium/proxylib/proxylib"
+	_ "github.com/cilium/cilium/proxylib/postgres"
+	_ "github.com/cilium/cilium/proxylib/redis"
 	_ "github.com/cilium/cilium/proxylib/testparsers"
 
 	"github.com/cilium/cilium/pkg/lock"
@@ -0,0 +1,214 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+
+	_ "gopkg.in/check.v1"
+
+	"github.com/cilium/cilium/proxylib/postgres"
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/test"
+)
+
+func pgStartup(params ...string) []byte {
+	msg := []byte{0, 0, 0, 0, 0, 3, 0, 0}
+	for _, param := range params {
+		msg = append(append(msg, param...), 0)
+	}
+	msg = append(msg, 0)
+	binary.BigEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	return msg
+}
+
+func pgMessage(msgType byte, fields ...string) []byte {
+	msg := []byte{msgType, 0, 0, 0, 0}
+	for _, field := range fields {
+		msg = append(append(msg, field...), 0)
+	}
+	binary.BigEndian.PutUint32(msg[1:5], uint32(len(msg)-1))
+	return msg
+}
+
+var pgSSLRequest = []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+var pgReadyForQuery = []byte{'Z', 0, 0, 0, 5, 'I'}
+
+const postgresPolicy = `
+		name: "pg1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 5432
+		  rules: <
+		    remote_policies: 1
+		    remote_policies: 3
+		    remote_policies: 4
+		    l7_proto: "postgres"
+		    l7_rules: <
+		      l7_rules: <
+		        rule: <
+		          key: "database"
+		          value: "shop"
+		        >
+		        rule: <
+		          key: "query_action"
+		          value: "select"
+		        >
+		        rule: <
+		          key: "query_table"
+		          value: "^(public\\.)?orders$"
+		        >
+		      >
+		    >
+		  >
+		>
+		`
+
+func TestPostgresOnDataReq(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{postgresPolicy})
+	buf := CheckOnNewConnection(t, mod, "postgres", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:5432", "pg1",
+		256, proxylib.OK, 1)
+
+	// Encryption is declined
+	CheckOnData(t, 1, false, false, &[][]byte{pgSSLRequest}, []ExpFilterOp{
+		{proxylib.DROP, len(pgSSLRequest)},
+	}, proxylib.OK, "N")
+
+	startup := pgStartup("user", "app", "database", "shop")
+	CheckOnData(t, 1, false, false, &[][]byte{startup[:6]}, []ExpFilterOp{
+		{proxylib.MORE, 2},
+	}, proxylib.OK, "")
+	CheckOnData(t, 1, false, false, &[][]byte{startup[:20]}, []ExpFilterOp{
+		{proxylib.MORE, len(startup) - 20},
+	}, proxylib.OK, "")
+	CheckOnData(t, 1, false, false, &[][]byte{startup[:10], startup[10:]}, []ExpFilterOp{
+		{proxylib.PASS, len(startup)},
+	}, proxylib.OK, "")
+
+	query := pgMessage('Q', "SELECT * FROM public.orders")
+	CheckOnData(t, 1, false, false, &[][]byte{query}, []ExpFilterOp{
+		{proxylib.PASS, len(query)},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, true, false, &[][]byte{pgReadyForQuery}, []ExpFilterOp{
+		{proxylib.PASS, len(pgReadyForQuery)},
+	}, proxylib.OK, "")
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestPostgresOnDataReqDrop(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{postgresPolicy})
+	buf := CheckOnNewConnection(t, mod, "postgres", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:5432", "pg1",
+		256, proxylib.OK, 1)
+
+	startup := pgStartup("user", "app", "database", "shop")
+	CheckOnData(t, 1, false, false, &[][]byte{startup}, []ExpFilterOp{
+		{proxylib.PASS, len(startup)},
+	}, proxylib.OK, "")
+
+	// All statements of a query must be allowed
+	query := pgMessage('Q', "SELECT * FROM orders; DELETE FROM orders")
+	CheckOnData(t, 1, false, false, &[][]byte{query}, []ExpFilterOp{
+		{proxylib.DROP, len(query)},
+	}, proxylib.OK, string(postgres.DeniedMsg)+string(pgReadyForQuery))
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestPostgresOnDataStartupDrop(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{postgresPolicy})
+	buf := CheckOnNewConnection(t, mod, "postgres", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:5432", "pg1",
+		256, proxylib.OK, 1)
+
+	// database defaults to the user name
+	startup := pgStartup("user", "app")
+	query := pgMessage('Q', "SELECT * FROM orders")
+	CheckOnData(t, 1, false, false, &[][]byte{startup, query}, []ExpFilterOp{
+		{proxylib.DROP, len(startup)}, {proxylib.DROP, len(query)},
+	}, proxylib.OK, string(postgres.StartupDeniedMsg))
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestPostgresOnDataExtendedDrop(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{postgresPolicy})
+	buf := CheckOnNewConnection(t, mod, "postgres", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:5432", "pg1",
+		256, proxylib.OK, 1)
+
+	startup := pgStartup("user", "app", "database", "shop")
+	CheckOnData(t, 1, false, false, &[][]byte{startup}, []ExpFilterOp{
+		{proxylib.PASS, len(startup)},
+	}, proxylib.OK, "")
+
+	// Messages following the denied Parse are discarded until Sync
+	parse := append(pgMessage('P', "", "UPDATE orders SET paid = true"), 0, 0)
+	parse[4] += 2
+	bind := pgMessage('B', "", "", "\x00\x00\x00\x00\x00\x00")
+	execute := pgMessage('E', "", "\x00\x00\x00")
+	sync := pgMessage('S')
+	CheckOnData(t, 1, false, false, &[][]byte{parse, bind, execute, sync}, []ExpFilterOp{
+		{proxylib.DROP, len(parse)}, {proxylib.DROP, len(bind)}, {proxylib.DROP, len(execute)},
+		{proxylib.PASS, len(sync)},
+	}, proxylib.OK, "")
+
+	// The error is injected before the ReadyForQuery of the server
+	CheckOnData(t, 1, true, false, &[][]byte{pgReadyForQuery}, []ExpFilterOp{
+		{proxylib.INJECT, len(postgres.DeniedMsg)}, {proxylib.PASS, len(pgReadyForQuery)},
+	}, proxylib.OK, string(postgres.DeniedMsg))
+
+	CheckClose(t, 1, buf, 1)
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	_ "gopkg.in/check.v1"
+
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/redis"
+	"github.com/cilium/cilium/proxylib/test"
+)
+
+var redisGetHello = []byte("*2\r\n$3\r\nGET\r\n$9\r\napp:Hello\r\n")
+var redisSetHello = []byte("*3\r\n$3\r\nSET\r\n$9\r\napp:Hello\r\n$5\r\nWorld\r\n")
+var redisGetWorldResp = []byte("$5\r\nWorld\r\n")
+
+const redisPolicy = `
+		name: "rd1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 6379
+		  rules: <
+		    remote_policies: 1
+		    remote_policies: 3
+		    remote_policies: 4
+		    l7_proto: "redis"
+		    l7_rules: <
+		      l7_rules: <
+		        rule: <
+		          key: "command"
+		          value: "get"
+		        >
+		        rule: <
+		          key: "keyPrefix"
+		          value: "app:"
+		        >
+		      >
+		      l7_rules: <
+		        rule: <
+		          key: "command"
+		          value: "config get"
+		        >
+		      >
+		    >
+		  >
+		>
+		`
+
+func TestRedisOnDataReq(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{redisPolicy})
+	buf := CheckOnNewConnection(t, mod, "redis", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:6379", "rd1",
+		30, proxylib.OK, 1)
+
+	configGet := []byte("CONFIG GET maxmemory\r\n")
+	CheckOnData(t, 1, false, false, &[][]byte{redisGetHello[:10], redisGetHello[10:], configGet}, []ExpFilterOp{
+		{proxylib.PASS, len(redisGetHello)}, {proxylib.PASS, len(configGet)},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, true, false, &[][]byte{redisGetWorldResp[:4]}, []ExpFilterOp{
+		{proxylib.MORE, 1},
+	}, proxylib.OK, "")
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestRedisOnDataReqDrop(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{redisPolicy})
+	buf := CheckOnNewConnection(t, mod, "redis", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:6379", "rd1",
+		30, proxylib.OK, 1)
+
+	CheckOnData(t, 1, false, false, &[][]byte{redisSetHello}, []ExpFilterOp{
+		{proxylib.DROP, len(redisSetHello)},
+	}, proxylib.OK, string(redis.DeniedMsg))
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestRedisOnDataReqDropPipelined(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{redisPolicy})
+	buf := CheckOnNewConnection(t, mod, "redis", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:6379", "rd1",
+		30, proxylib.OK, 1)
+
+	// The denied reply must not be injected before the reply to GET
+	CheckOnData(t, 1, false, false, &[][]byte{redisGetHello, redisSetHello}, []ExpFilterOp{
+		{proxylib.PASS, len(redisGetHello)}, {proxylib.DROP, len(redisSetHello)},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, true, false, &[][]byte{redisGetWorldResp}, []ExpFilterOp{
+		{proxylib.PASS, len(redisGetWorldResp)}, {proxylib.INJECT, len(redis.DeniedMsg)},
+	}, proxylib.OK, string(redis.DeniedMsg))
+
+	CheckClose(t, 1, buf, 1)
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	_ "gopkg.in/check.v1"
+
+	"github.com/cilium/cilium/proxylib/memcached/text"
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/test"
+)
+
+var textGetHello = []byte("get Hello\r\n")
+var textGetHelloResp = []byte("VALUE Hello 0 5\r\nWorld\r\nEND\r\n")
+var textSetHello = []byte("set Hello 0 0 5\r\nWorld\r\n")
+var textSetHelloNoReply = []byte("set Hello 0 0 5 noreply\r\nWorld\r\n")
+
+const textMemcachePolicy = `
+		name: "tm1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 11211
+		  rules: <
+		    remote_policies: 1
+		    remote_policies: 3
+		    remote_policies: 4
+		    l7_proto: "memcache"
+		    l7_rules: <
+		      l7_rules: <
+		        rule: <
+		          key: "keyPrefix"
+		          value: "Hell"
+		        >
+		        rule: <
+		          key: "opCode"
+		          value: "readGroup"
+		        >
+		      >
+		    >
+		  >
+		>
+		`
+
+func TestTextMemcacheOnDataReq(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{textMemcachePolicy})
+	buf := CheckOnNewConnection(t, mod, "memcache", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:11211", "tm1",
+		30, proxylib.OK, 1)
+
+	CheckOnData(t, 1, false, false, &[][]byte{textGetHello[:4], textGetHello[4:]}, []ExpFilterOp{
+		{proxylib.PASS, len(textGetHello)},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, true, false, &[][]byte{textGetHelloResp}, []ExpFilterOp{
+		{proxylib.PASS, 24}, {proxylib.PASS, 5},
+	}, proxylib.OK, "")
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestTextMemcacheOnDataReqDrop(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{textMemcachePolicy})
+	buf := CheckOnNewConnection(t, mod, "memcache", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:11211", "tm1",
+		30, proxylib.OK, 1)
+
+	CheckOnData(t, 1, false, false, &[][]byte{textSetHello[:20]}, []ExpFilterOp{
+		{proxylib.MORE, len(textSetHello) - 20},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, false, false, &[][]byte{textSetHello}, []ExpFilterOp{
+		{proxylib.DROP, len(textSetHello)},
+	}, proxylib.OK, string(text.DeniedMsg))
+
+	// Nothing is injected for noreply requests
+	CheckOnData(t, 1, false, false, &[][]byte{textSetHelloNoReply}, []ExpFilterOp{
+		{proxylib.DROP, len(textSetHelloNoReply)},
+	}, proxylib.OK, "")
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestTextMemcacheOnDataReqDropPipelined(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{textMemcachePolicy})
+	buf := CheckOnNewConnection(t, mod, "memcache", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:11211", "tm1",
+		30, proxylib.OK, 1)
+
+	// The denied reply must not be injected before the reply to get
+	CheckOnData(t, 1, false, false, &[][]byte{textGetHello, textSetHello}, []ExpFilterOp{
+		{proxylib.PASS, len(textGetHello)}, {proxylib.DROP, len(textSetHello)},
+	}, proxylib.OK, "")
+
+	CheckOnData(t, 1, true, false, &[][]byte{textGetHelloResp}, []ExpFilterOp{
+		{proxylib.PASS, 24}, {proxylib.PASS, 5}, {proxylib.INJECT, len(text.DeniedMsg)},
+	}, proxylib.OK, string(text.DeniedMsg))
+
+	CheckClose(t, 1, buf, 1)
+}
+
+func TestMemcacheOnDataReqBinary(t *testing.T) {
+	logServer := test.StartAccessLogServer(t, "access_log.sock", 10)
+	defer logServer.Close()
+
+	mod := OpenModule([][2]string{{"access-log-path", logServer.Path}}, true)
+	if mod == 0 {
+		t.Errorf("OpenModule() with access log path %s failed", logServer.Path)
+	} else {
+		defer CloseModule(mod)
+	}
+
+	insertPolicyText(t, mod, "1", []string{textMemcachePolicy})
+	buf := CheckOnNewConnection(t, mod, "memcache", 1, true, 1, 2, "1.1.1.1:34567", "2.2.2.2:11211", "tm1",
+		30, proxylib.OK, 1)
+
+	CheckOnData(t, 1, false, false, &[][]byte{getHello}, []ExpFilterOp{
+		{proxylib.PASS, len(getHello)}, {proxylib.MORE, 24},
+	}, proxylib.OK, "")
+
+	CheckClose(t, 1, buf, 1)
+}
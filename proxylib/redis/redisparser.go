@@ -0,0 +1,696 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/envoy/cilium"
+	"github.com/cilium/cilium/proxylib/proxylib"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RedisRule matches against Redis commands
+type RedisRule struct {
+	// command is the upper case command, optionally followed by a
+	// subcommand, e.g. "GET" or "CONFIG GET". Empty matches any command.
+	command []string
+
+	keyPrefix string
+}
+
+type redisMeta struct {
+	// args holds the command and its arguments
+	args []string
+}
+
+func (meta *redisMeta) command() string {
+	if len(meta.args) == 0 {
+		return ""
+	}
+	return strings.ToUpper(meta.args[0])
+}
+
+// Matches returns true if the RedisRule matches
+func (rule *RedisRule) Matches(data interface{}) bool {
+	log.Debugf("redis checking rule %v", *rule)
+
+	meta, ok := data.(redisMeta)
+	if !ok {
+		log.Warnf("RedisRule.Matches called with type other than redisMeta, failing to match")
+		return false
+	}
+
+	if len(rule.command) > len(meta.args) {
+		return false
+	}
+	for i, word := range rule.command {
+		if word != strings.ToUpper(meta.args[i]) {
+			return false
+		}
+	}
+
+	if rule.keyPrefix != "" {
+		spec, ok := commandKeys[meta.command()]
+		if !ok {
+			log.Debugf("Keys of redis command %s unknown, failing to match key prefix", meta.command())
+			return false
+		}
+		keys, ok := spec.keys(meta.args)
+		if !ok || len(keys) == 0 {
+			return false
+		}
+		for _, key := range keys {
+			if !strings.HasPrefix(key, rule.keyPrefix) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// L7RedisRuleParser parses protobuf L7 rules to and array of RedisRule
+// May panic
+func L7RedisRuleParser(rule *cilium.PortNetworkPolicyRule) []proxylib.L7NetworkPolicyRule {
+	l7Rules := rule.GetL7Rules()
+	if l7Rules == nil {
+		proxylib.ParseError("Can't get L7 rules", rule)
+	}
+	var rules []proxylib.L7NetworkPolicyRule
+	for _, l7Rule := range l7Rules.GetL7Rules() {
+		var rr RedisRule
+		for k, v := range l7Rule.Rule {
+			switch k {
+			case "command":
+				rr.command = strings.Fields(strings.ToUpper(v))
+			case "keyPrefix":
+				rr.keyPrefix = v
+			default:
+				proxylib.ParseError(fmt.Sprintf("Unsupported key: %s", k), rule)
+			}
+		}
+		if rr.keyPrefix != "" && len(rr.command) > 0 {
+			if _, ok := commandKeys[rr.command[0]]; !ok {
+				proxylib.ParseError(fmt.Sprintf("keyPrefix not supported for command %s", rr.command[0]), rule)
+			}
+		}
+		log.Debugf("Parsed RedisRule pair: %v", rr)
+		rules = append(rules, &rr)
+	}
+	return rules
+}
+
+// RedisParserFactory implements proxylib.ParserFactory
+type RedisParserFactory struct{}
+
+// Create creates Redis parser
+func (p *RedisParserFactory) Create(connection *proxylib.Connection) proxylib.Parser {
+	log.Infof("RedisParserFactory: Create: %v", connection)
+	return &RedisParser{connection: connection}
+}
+
+// compile time check for interface implementation
+var _ proxylib.ParserFactory = &RedisParserFactory{}
+
+var redisParserFactory *RedisParserFactory
+
+const (
+	parserName = "redis"
+)
+
+func init() {
+	log.Info("init(): Registering redisParserFactory")
+	proxylib.RegisterParserFactory(parserName, redisParserFactory)
+	proxylib.RegisterL7RuleParser(parserName, L7RedisRuleParser)
+}
+
+// RedisParser implements proxylib.Parser
+type RedisParser struct {
+	connection *proxylib.Connection
+
+	requestCount uint32
+	replyCount   uint32
+	// injectQueue holds the request numbers of the denied requests
+	// whose error reply has not been injected yet
+	injectQueue []uint32
+
+	// subscribed is set once the client subscribed to a channel. Replies
+	// are no longer paired with requests from then on, so that error
+	// replies are injected right away.
+	subscribed bool
+}
+
+var _ proxylib.Parser = &RedisParser{}
+
+// OnData parses Redis data
+func (p *RedisParser) OnData(reply, endStream bool, dataBuffers [][]byte, offset int) (proxylib.OpType, int) {
+	log.Debugf("OnData with offset %d", offset)
+
+	if reply {
+		if p.injectFromQueue() {
+			return proxylib.INJECT, len(DeniedMsg)
+		}
+	}
+
+	//TODO don't copy data from buffers
+	data := (bytes.Join(dataBuffers, []byte{}))[offset:]
+	if len(data) == 0 {
+		return proxylib.NOP, 0
+	}
+
+	var args []string
+	var n int
+	var err error
+	if reply {
+		n, err = parseValue(data, nil, 0)
+	} else {
+		n, args, err = parseRequest(data)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Unable to parse redis data")
+		return proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE)
+	}
+	if n == 0 {
+		log.Debugf("Did not receive a complete redis value, need more bytes")
+		return proxylib.MORE, 1
+	}
+
+	// we don't filter reply traffic
+	if reply {
+		log.Debugf("reply, passing %d bytes", n)
+		p.replyCount++
+		return proxylib.PASS, n
+	}
+
+	if len(args) == 0 {
+		// Empty inline commands are ignored by the server
+		return proxylib.PASS, n
+	}
+
+	meta := redisMeta{args: args}
+	logEntry := &cilium.LogEntry_GenericL7{
+		&cilium.L7LogEntry{
+			Proto: "redis",
+			Fields: map[string]string{
+				"command": meta.command(),
+			},
+		},
+	}
+	if spec, ok := commandKeys[meta.command()]; ok {
+		if keys, ok := spec.keys(args); ok {
+			logEntry.GenericL7.Fields["keys"] = strings.Join(keys, " ")
+		}
+	}
+
+	p.requestCount++
+
+	if p.connection.Matches(meta) {
+		switch meta.command() {
+		case "SUBSCRIBE", "PSUBSCRIBE":
+			p.subscribed = true
+		}
+		p.connection.Log(cilium.EntryType_Request, logEntry)
+		return proxylib.PASS, n
+	}
+
+	// This is done to ensure in-order replies
+	if p.subscribed || p.requestCount == p.replyCount+1 {
+		p.injectDeniedMessage()
+	} else {
+		p.injectQueue = append(p.injectQueue, p.requestCount)
+	}
+
+	p.connection.Log(cilium.EntryType_Denied, logEntry)
+	return proxylib.DROP, n
+}
+
+func (p *RedisParser) injectDeniedMessage() {
+	p.connection.Inject(true, DeniedMsg)
+	p.replyCount++
+}
+
+func (p *RedisParser) injectFromQueue() bool {
+	if len(p.injectQueue) > 0 {
+		if p.subscribed || p.injectQueue[0] == p.replyCount+1 {
+			p.injectDeniedMessage()
+			p.injectQueue = p.injectQueue[1:]
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedMsg is sent if policy denies the request. Exported for tests
+var DeniedMsg = []byte("-ERR access denied\r\n")
+
+const (
+	// maxDepth is the maximum nesting depth of arrays
+	maxDepth = 16
+
+	// maxBulkLength is the maximum length of a bulk string accepted by
+	// the Redis server
+	maxBulkLength = 512 * 1024 * 1024
+)
+
+// readLine returns the line at the start of data without the terminating
+// CRLF and the length of the line including it. The length is 0 if data
+// does not hold a complete line.
+func readLine(data []byte) ([]byte, int) {
+	i := bytes.Index(data, []byte("\r\n"))
+	if i < 0 {
+		return nil, 0
+	}
+	return data[:i], i + 2
+}
+
+// parseRequest parses the request at the start of data, either an array of
+// bulk strings or an inline command. It returns the length of the request
+// and its arguments. The length is 0 if data does not hold the complete
+// request.
+func parseRequest(data []byte) (int, []string, error) {
+	if data[0] != '*' {
+		// Inline command, only terminated by LF
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return 0, nil, nil
+		}
+		args, err := splitInlineArgs(bytes.TrimSuffix(data[:i], []byte{'\r'}))
+		return i + 1, args, err
+	}
+
+	args := []string{}
+	n, err := parseValue(data, &args, 0)
+	return n, args, err
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitToInt(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// splitInlineArgs splits an inline command into its arguments following the
+// rules of sdssplitargs() in Redis. Quotes may start anywhere within an
+// argument, double quoted strings support escape sequences, and a closing
+// quote must be followed by a space or the end of the line.
+func splitInlineArgs(line []byte) ([]string, error) {
+	args := []string{}
+	p := 0
+	for {
+		for p < len(line) && isSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, nil
+		}
+
+		inDoubleQuotes, inSingleQuotes, done := false, false, false
+		current := []byte{}
+		for !done {
+			switch {
+			case inDoubleQuotes:
+				switch {
+				case p == len(line):
+					return nil, fmt.Errorf("unbalanced quotes in inline command")
+				case line[p] == '\\' && p+3 < len(line) && line[p+1] == 'x' &&
+					isHexDigit(line[p+2]) && isHexDigit(line[p+3]):
+					current = append(current, hexDigitToInt(line[p+2])*16+hexDigitToInt(line[p+3]))
+					p += 3
+				case line[p] == '\\' && p+1 < len(line):
+					p++
+					switch line[p] {
+					case 'n':
+						current = append(current, '\n')
+					case 'r':
+						current = append(current, '\r')
+					case 't':
+						current = append(current, '\t')
+					case 'b':
+						current = append(current, '\b')
+					case 'a':
+						current = append(current, '\a')
+					default:
+						current = append(current, line[p])
+					}
+				case line[p] == '"':
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, fmt.Errorf("closing quote must be followed by a space")
+					}
+					done = true
+				default:
+					current = append(current, line[p])
+				}
+			case inSingleQuotes:
+				switch {
+				case p == len(line):
+					return nil, fmt.Errorf("unbalanced quotes in inline command")
+				case line[p] == '\\' && p+1 < len(line) && line[p+1] == '\'':
+					p++
+					current = append(current, '\'')
+				case line[p] == '\'':
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, fmt.Errorf("closing quote must be followed by a space")
+					}
+					done = true
+				default:
+					current = append(current, line[p])
+				}
+			default:
+				if p == len(line) {
+					done = true
+					break
+				}
+				switch line[p] {
+				case ' ', '\n', '\r', '\t', 0:
+					done = true
+				case '"':
+					inDoubleQuotes = true
+				case '\'':
+					inSingleQuotes = true
+				default:
+					current = append(current, line[p])
+				}
+			}
+			if p < len(line) {
+				p++
+			}
+		}
+		args = append(args, string(current))
+	}
+}
+
+// parseValue parses the RESP value at the start of data and returns its
+// length. The length is 0 if data does not hold the complete value. If args
+// is not nil, the value must be an array of bulk strings, which are appended
+// to args.
+func parseValue(data []byte, args *[]string, depth int) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	line, n := readLine(data)
+	if n == 0 {
+		return 0, nil
+	}
+	if args != nil && depth == 0 && data[0] != '*' {
+		return 0, fmt.Errorf("request is not an array")
+	}
+
+	switch data[0] {
+	case '+', '-', ':':
+		if args != nil {
+			return 0, fmt.Errorf("unexpected value type %q in request", data[0])
+		}
+		return n, nil
+
+	case '$':
+		length, err := strconv.Atoi(string(line[1:]))
+		if err != nil || length < -1 || length > maxBulkLength {
+			return 0, fmt.Errorf("invalid bulk string length %q", line[1:])
+		}
+		if length == -1 {
+			if args != nil {
+				return 0, fmt.Errorf("unexpected null bulk string in request")
+			}
+			return n, nil
+		}
+		if len(data) < n+length+2 {
+			return 0, nil
+		}
+		if data[n+length] != '\r' || data[n+length+1] != '\n' {
+			return 0, fmt.Errorf("bulk string not terminated by CRLF")
+		}
+		if args != nil {
+			*args = append(*args, string(data[n:n+length]))
+		}
+		return n + length + 2, nil
+
+	case '*':
+		count, err := strconv.Atoi(string(line[1:]))
+		if err != nil || count < -1 {
+			return 0, fmt.Errorf("invalid array length %q", line[1:])
+		}
+		if args != nil && depth > 0 {
+			return 0, fmt.Errorf("unexpected nested array in request")
+		}
+		if depth >= maxDepth {
+			return 0, fmt.Errorf("arrays nested too deep")
+		}
+		for i := 0; i < count; i++ {
+			m, err := parseValue(data[n:], args, depth+1)
+			if m == 0 || err != nil {
+				return 0, err
+			}
+			n += m
+		}
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("invalid value type %q", data[0])
+}
+
+// keySpec describes the position of the keys in the arguments of a
+// command, the same way as the Redis COMMAND command does.
+type keySpec struct {
+	// first and last are the indices of the first and of the last key,
+	// negative last indices count from the end. first is 0 if the
+	// command has no keys at fixed positions.
+	first int
+	last  int
+	// step is the distance between keys
+	step int
+	// numKeys, if non-zero, is the index of the argument holding the
+	// number of keys which follow it
+	numKeys int
+	// options, if non-zero, is the index of the first optional argument.
+	// The argument following a STORE or STOREDIST option is a key.
+	options int
+}
+
+// keys returns the keys in args, returns false if args are malformed
+func (s keySpec) keys(args []string) ([]string, bool) {
+	keys := []string{}
+	if s.first > 0 {
+		last := s.last
+		if last < 0 {
+			last += len(args)
+		}
+		for i := s.first; i <= last && i < len(args); i += s.step {
+			keys = append(keys, args[i])
+		}
+	}
+	if s.numKeys > 0 {
+		if s.numKeys >= len(args) {
+			return nil, false
+		}
+		n, err := strconv.Atoi(args[s.numKeys])
+		if err != nil || n < 0 || s.numKeys+n >= len(args) {
+			return nil, false
+		}
+		keys = append(keys, args[s.numKeys+1:s.numKeys+1+n]...)
+	}
+	if s.options > 0 {
+		for i := s.options; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "STORE", "STOREDIST":
+				if i+1 == len(args) {
+					return nil, false
+				}
+				i++
+				keys = append(keys, args[i])
+			}
+		}
+	}
+	return keys, true
+}
+
+var (
+	singleKey    = keySpec{first: 1, last: 1, step: 1}
+	allKeys      = keySpec{first: 1, last: -1, step: 1}
+	twoKeys      = keySpec{first: 1, last: 2, step: 1}
+	keyValuePair = keySpec{first: 1, last: -1, step: 2}
+)
+
+// commandKeys maps the commands a keyPrefix rule can apply to to the
+// position of their keys. SORT is omitted as its BY and GET patterns
+// access keys which are not known before the command is executed.
+var commandKeys = map[string]keySpec{
+	// Keys
+	"DEL":       allKeys,
+	"DUMP":      singleKey,
+	"EXISTS":    allKeys,
+	"EXPIRE":    singleKey,
+	"EXPIREAT":  singleKey,
+	"MOVE":      singleKey,
+	"PERSIST":   singleKey,
+	"PEXPIRE":   singleKey,
+	"PEXPIREAT": singleKey,
+	"PTTL":      singleKey,
+	"RENAME":    twoKeys,
+	"RENAMENX":  twoKeys,
+	"RESTORE":   singleKey,
+	"TOUCH":     allKeys,
+	"TTL":       singleKey,
+	"TYPE":      singleKey,
+	"UNLINK":    allKeys,
+	"WATCH":     allKeys,
+
+	// Strings
+	"APPEND":      singleKey,
+	"BITCOUNT":    singleKey,
+	"BITFIELD":    singleKey,
+	"BITOP":       keySpec{first: 2, last: -1, step: 1},
+	"BITPOS":      singleKey,
+	"DECR":        singleKey,
+	"DECRBY":      singleKey,
+	"GET":         singleKey,
+	"GETBIT":      singleKey,
+	"GETRANGE":    singleKey,
+	"GETSET":      singleKey,
+	"INCR":        singleKey,
+	"INCRBY":      singleKey,
+	"INCRBYFLOAT": singleKey,
+	"MGET":        allKeys,
+	"MSET":        keyValuePair,
+	"MSETNX":      keyValuePair,
+	"PSETEX":      singleKey,
+	"SET":         singleKey,
+	"SETBIT":      singleKey,
+	"SETEX":       singleKey,
+	"SETNX":       singleKey,
+	"SETRANGE":    singleKey,
+	"STRLEN":      singleKey,
+
+	// Hashes
+	"HDEL":         singleKey,
+	"HEXISTS":      singleKey,
+	"HGET":         singleKey,
+	"HGETALL":      singleKey,
+	"HINCRBY":      singleKey,
+	"HINCRBYFLOAT": singleKey,
+	"HKEYS":        singleKey,
+	"HLEN":         singleKey,
+	"HMGET":        singleKey,
+	"HMSET":        singleKey,
+	"HSCAN":        singleKey,
+	"HSET":         singleKey,
+	"HSETNX":       singleKey,
+	"HSTRLEN":      singleKey,
+	"HVALS":        singleKey,
+
+	// Lists
+	"BLPOP":      keySpec{first: 1, last: -2, step: 1},
+	"BRPOP":      keySpec{first: 1, last: -2, step: 1},
+	"BRPOPLPUSH": twoKeys,
+	"LINDEX":     singleKey,
+	"LINSERT":    singleKey,
+	"LLEN":       singleKey,
+	"LPOP":       singleKey,
+	"LPUSH":      singleKey,
+	"LPUSHX":     singleKey,
+	"LRANGE":     singleKey,
+	"LREM":       singleKey,
+	"LSET":       singleKey,
+	"LTRIM":      singleKey,
+	"RPOP":       singleKey,
+	"RPOPLPUSH":  twoKeys,
+	"RPUSH":      singleKey,
+	"RPUSHX":     singleKey,
+
+	// Sets
+	"SADD":        singleKey,
+	"SCARD":       singleKey,
+	"SDIFF":       allKeys,
+	"SDIFFSTORE":  allKeys,
+	"SINTER":      allKeys,
+	"SINTERSTORE": allKeys,
+	"SISMEMBER":   singleKey,
+	"SMEMBERS":    singleKey,
+	"SMOVE":       twoKeys,
+	"SPOP":        singleKey,
+	"SRANDMEMBER": singleKey,
+	"SREM":        singleKey,
+	"SSCAN":       singleKey,
+	"SUNION":      allKeys,
+	"SUNIONSTORE": allKeys,
+
+	// Sorted sets
+	"ZADD":             singleKey,
+	"ZCARD":            singleKey,
+	"ZCOUNT":           singleKey,
+	"ZINCRBY":          singleKey,
+	"ZINTERSTORE":      keySpec{first: 1, last: 1, step: 1, numKeys: 2},
+	"ZLEXCOUNT":        singleKey,
+	"ZRANGE":           singleKey,
+	"ZRANGEBYLEX":      singleKey,
+	"ZRANGEBYSCORE":    singleKey,
+	"ZRANK":            singleKey,
+	"ZREM":             singleKey,
+	"ZREMRANGEBYLEX":   singleKey,
+	"ZREMRANGEBYRANK":  singleKey,
+	"ZREMRANGEBYSCORE": singleKey,
+	"ZREVRANGE":        singleKey,
+	"ZREVRANGEBYLEX":   singleKey,
+	"ZREVRANGEBYSCORE": singleKey,
+	"ZREVRANK":         singleKey,
+	"ZSCAN":            singleKey,
+	"ZSCORE":           singleKey,
+	"ZUNIONSTORE":      keySpec{first: 1, last: 1, step: 1, numKeys: 2},
+
+	// HyperLogLog
+	"PFADD":   singleKey,
+	"PFCOUNT": allKeys,
+	"PFMERGE": allKeys,
+
+	// Geo
+	"GEOADD":            singleKey,
+	"GEODIST":           singleKey,
+	"GEOHASH":           singleKey,
+	"GEOPOS":            singleKey,
+	"GEORADIUS":         keySpec{first: 1, last: 1, step: 1, options: 6},
+	"GEORADIUSBYMEMBER": keySpec{first: 1, last: 1, step: 1, options: 5},
+
+	// Streams
+	"XADD":      singleKey,
+	"XDEL":      singleKey,
+	"XLEN":      singleKey,
+	"XRANGE":    singleKey,
+	"XREVRANGE": singleKey,
+	"XTRIM":     singleKey,
+
+	// Scripting
+	"EVAL":    keySpec{numKeys: 2},
+	"EVALSHA": keySpec{numKeys: 2},
+}
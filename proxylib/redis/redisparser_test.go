@@ -0,0 +1,166 @@
+// Copyright 2018 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type RedisTestSuite struct{}
+
+var _ = Suite(&RedisTestSuite{})
+
+func (s *RedisTestSuite) TestParseRequest(c *C) {
+	req := []byte("*3\r\n$3\r\nSET\r\n$5\r\nHello\r\n$5\r\nWorld\r\n")
+	n, args, err := parseRequest(req)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, len(req))
+	c.Assert(args, DeepEquals, []string{"SET", "Hello", "World"})
+
+	// Incomplete requests
+	for i := 1; i < len(req); i++ {
+		n, _, err = parseRequest(req[:i])
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, 0)
+	}
+
+	// Inline commands
+	n, args, err = parseRequest([]byte("get Hello\r\nGET"))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 11)
+	c.Assert(args, DeepEquals, []string{"get", "Hello"})
+
+	// Inline commands are split like Redis does, quotes may start
+	// anywhere within an argument
+	for _, t := range []struct {
+		req  string
+		args []string
+	}{
+		{"  get\tHello  \n", []string{"get", "Hello"}},
+		{"\r\n", []string{}},
+		{`MSET tenant-a:1 "v tenant-a:2" other:secret tenant-a:x` + "\r\n",
+			[]string{"MSET", "tenant-a:1", "v tenant-a:2", "other:secret", "tenant-a:x"}},
+		{`SET tenant-a:"x y" 1` + "\r\n", []string{"SET", "tenant-a:x y", "1"}},
+		{`SET "a\x41\n\"b" 'c\'d'` + "\r\n", []string{"SET", "aA\n\"b", "c'd"}},
+		{"get Hello\u00a0World\r\n", []string{"get", "Hello\u00a0World"}},
+	} {
+		_, args, err = parseRequest([]byte(t.req))
+		c.Assert(err, IsNil, Commentf("%q", t.req))
+		c.Assert(args, DeepEquals, t.args, Commentf("%q", t.req))
+	}
+
+	// Malformed requests
+	for _, req := range []string{
+		"*1\r\n:1\r\n",
+		"*1\r\n*1\r\n$3\r\nGET\r\n",
+		"*1\r\n$-1\r\n",
+		"*1\r\n$3\r\nGETX\r\n",
+		"*x\r\n",
+		"*1\r\n$x\r\n",
+		"SET \"a b\r\n",
+		"SET 'a\r\n",
+		"SET \"a\"b\r\n",
+		"SET 'a'b\r\n",
+	} {
+		_, _, err = parseRequest([]byte(req))
+		c.Assert(err, Not(IsNil), Commentf("%q", req))
+	}
+}
+
+func (s *RedisTestSuite) TestParseReply(c *C) {
+	for _, reply := range []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":1000\r\n",
+		"$5\r\nWorld\r\n",
+		"$-1\r\n",
+		"*-1\r\n",
+		"*0\r\n",
+		"*2\r\n*1\r\n:1\r\n$5\r\nWorld\r\n",
+	} {
+		n, err := parseValue([]byte(reply+"+OK\r\n"), nil, 0)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, len(reply), Commentf("%q", reply))
+
+		n, err = parseValue([]byte(reply[:len(reply)-1]), nil, 0)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, 0, Commentf("%q", reply))
+	}
+
+	_, err := parseValue([]byte("%1\r\n"), nil, 0)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *RedisTestSuite) TestKeys(c *C) {
+	tests := []struct {
+		args []string
+		keys []string
+	}{
+		{[]string{"GET", "a"}, []string{"a"}},
+		{[]string{"MGET", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{[]string{"MSET", "a", "1", "b", "2"}, []string{"a", "b"}},
+		{[]string{"BLPOP", "a", "b", "0"}, []string{"a", "b"}},
+		{[]string{"RENAME", "a", "b"}, []string{"a", "b"}},
+		{[]string{"EVAL", "return 1", "2", "a", "b", "c"}, []string{"a", "b"}},
+		{[]string{"EVAL", "return 1", "0"}, []string{}},
+		{[]string{"ZUNIONSTORE", "a", "2", "b", "c", "WEIGHTS", "1", "2"}, []string{"a", "b", "c"}},
+		{[]string{"GEORADIUS", "a", "15", "37", "200", "km", "WITHDIST"}, []string{"a"}},
+		{[]string{"GEORADIUS", "a", "15", "37", "200", "km", "store", "b"}, []string{"a", "b"}},
+		{[]string{"GEORADIUSBYMEMBER", "a", "store", "200", "km", "STOREDIST", "b"}, []string{"a", "b"}},
+	}
+	for _, t := range tests {
+		keys, ok := commandKeys[t.args[0]].keys(t.args)
+		c.Assert(ok, Equals, true)
+		c.Assert(keys, DeepEquals, t.keys, Commentf("%v", t.args))
+	}
+
+	_, ok := commandKeys["EVAL"].keys([]string{"EVAL", "return 1", "3", "a"})
+	c.Assert(ok, Equals, false)
+
+	_, ok = commandKeys["GEORADIUS"].keys([]string{"GEORADIUS", "a", "15", "37", "200", "km", "STORE"})
+	c.Assert(ok, Equals, false)
+
+	// The keys accessed by SORT are unknown
+	_, ok = commandKeys["SORT"]
+	c.Assert(ok, Equals, false)
+}
+
+func (s *RedisTestSuite) TestRuleMatches(c *C) {
+	get := RedisRule{command: []string{"GET"}}
+	c.Assert(get.Matches(redisMeta{args: []string{"get", "a"}}), Equals, true)
+	c.Assert(get.Matches(redisMeta{args: []string{"SET", "a", "1"}}), Equals, false)
+
+	configGet := RedisRule{command: []string{"CONFIG", "GET"}}
+	c.Assert(configGet.Matches(redisMeta{args: []string{"CONFIG", "get", "maxmemory"}}), Equals, true)
+	c.Assert(configGet.Matches(redisMeta{args: []string{"CONFIG", "SET", "maxmemory", "0"}}), Equals, false)
+	c.Assert(configGet.Matches(redisMeta{args: []string{"CONFIG"}}), Equals, false)
+
+	prefix := RedisRule{keyPrefix: "app:"}
+	c.Assert(prefix.Matches(redisMeta{args: []string{"SET", "app:a", "1"}}), Equals, true)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"MGET", "app:a", "app:b"}}), Equals, true)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"MGET", "app:a", "b"}}), Equals, false)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"EVAL", "return 1", "1", "app:a"}}), Equals, true)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"EVAL", "return 1", "0"}}), Equals, false)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"FLUSHALL"}}), Equals, false)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"GEORADIUS", "app:a", "15", "37", "200", "km", "STORE", "other:b"}}), Equals, false)
+	c.Assert(prefix.Matches(redisMeta{args: []string{"SORT", "app:a", "STORE", "other:b"}}), Equals, false)
+
+	any := RedisRule{}
+	c.Assert(any.Matches(redisMeta{args: []string{"FLUSHALL"}}), Equals, true)
+}
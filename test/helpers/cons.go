@@ -126,6 +126,7 @@ const (
 	OptionDropNotify          = "DropNotification"
 	OptionTraceNotify         = "TraceNotification"
 	OptionNAT46               = "NAT46"
+	OptionPolicyAuditMode     = "PolicyAuditMode"
 	OptionIngressPolicy       = "IngressPolicy"
 	OptionEgressPolicy        = "EgressPolicy"
 	OptionIngress             = "ingress"